@@ -536,3 +536,58 @@ func (s *Server) AdminTokenDelete(c *gin.Context) {
 	recordWalletTransaction(s, c.Request.Context(), "token_delete", id)
 	c.JSON(http.StatusOK, APIResponse{Success: true, Data: gin.H{"recorded": true, "type": "token_delete"}})
 }
+
+// AdminListMempool возвращает транзакции мемпула с положением в очереди отправителя и сроком жизни.
+// GET /api/v1/admin/mempool?sender=...
+func (s *Server) AdminListMempool(c *gin.Context) {
+	if !s.RequireAdmin(c) {
+		return
+	}
+	if s.mempool == nil {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{Success: false, Error: "Мемпул недоступен", Code: http.StatusServiceUnavailable})
+		return
+	}
+	sender := c.Query("sender")
+	list := []gin.H{}
+	for _, e := range s.mempool.Entries() {
+		if sender != "" && string(e.Tx.Sender) != sender {
+			continue
+		}
+		status := "pending"
+		if e.Queued {
+			status = "queued"
+		}
+		list = append(list, gin.H{
+			"hash":       e.Tx.Hash,
+			"sender":     e.Tx.Sender,
+			"recipient":  e.Tx.Recipient,
+			"nonce":      e.Tx.Nonce,
+			"gas_price":  e.Tx.EffectiveGasPrice().String(),
+			"gas_limit":  e.Tx.EffectiveGasLimit(),
+			"type":       e.Tx.Type,
+			"status":     status,
+			"added_at":   e.AddedAt,
+			"expires_at": e.ExpiresAt,
+		})
+	}
+	pending, queued := s.mempool.Stats()
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: gin.H{"list": list, "pending": pending, "queued": queued}})
+}
+
+// AdminEvictMempoolTx удаляет транзакцию из мемпула; в БД ей выставляется статус evicted.
+// DELETE /api/v1/admin/mempool/:hash, POST /api/v1/admin/mempool/:hash/evict
+func (s *Server) AdminEvictMempoolTx(c *gin.Context) {
+	if !s.RequireAdmin(c) {
+		return
+	}
+	if s.mempool == nil {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{Success: false, Error: "Мемпул недоступен", Code: http.StatusServiceUnavailable})
+		return
+	}
+	hash := strings.TrimPrefix(c.Param("hash"), "0x")
+	if _, ok := s.mempool.Remove(hash, core.TxStatusEvicted); !ok {
+		c.JSON(http.StatusNotFound, APIResponse{Success: false, Error: "Транзакция не найдена в мемпуле", Code: http.StatusNotFound})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: gin.H{"hash": hash, "status": core.TxStatusEvicted}})
+}
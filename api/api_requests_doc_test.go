@@ -4,10 +4,13 @@
 package api
 
 import (
+	"GND/consensus"
 	"GND/core"
+	"GND/core/proof"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -128,9 +131,9 @@ func TestDocURLs_TransactionHelpAndList(t *testing.T) {
 func TestDocURLs_Blocks(t *testing.T) {
 	s := setupServerForDocTest(t)
 
-	// block/latest, block/0, block/1 — без БД (nil pool) возможен 500; с БД — 200 с data
+	// block/latest, block/0, block/1, теги finalized/justified — без БД (nil pool) возможен 500; с БД — 200 с data
 	allowedStatuses := []int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError}
-	for _, path := range []string{"/api/v1/block/latest", "/api/v1/block/0", "/api/v1/block/1"} {
+	for _, path := range []string{"/api/v1/block/latest", "/api/v1/block/0", "/api/v1/block/1", "/api/v1/block/finalized", "/api/v1/block/justified"} {
 		req := httptest.NewRequest("GET", path, nil)
 		w := httptest.NewRecorder()
 		s.router.ServeHTTP(w, req)
@@ -155,6 +158,82 @@ func TestDocURLs_Blocks(t *testing.T) {
 	}
 }
 
+func TestDocURLs_ConsensusPoA(t *testing.T) {
+	s := setupServerForDocTest(t)
+
+	// без движка PoA (нода без ключа валидатора) — 503
+	for _, path := range []string{"/api/v1/consensus/validators", "/api/v1/consensus/history"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("GET %s без движка PoA: статус %d, ожидался 503", path, w.Code)
+		}
+	}
+
+	poa, err := consensus.NewPoA(&core.ConsensusPoaConfig{RoundDuration: "5s"}, nil, s.core.Genesis.Timestamp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poa.SetValidators([]consensus.PoAValidator{{Address: "GN_val1"}})
+	poa.Attach(s.core)
+	for _, path := range []string{"/api/v1/consensus/validators", "/api/v1/consensus/history?address=GN_val1"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		var resp APIResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: decode: %v", path, err)
+		}
+		if w.Code != http.StatusOK || !resp.Success || resp.Data == nil {
+			t.Errorf("GET %s: статус %d, ожидались success и data", path, w.Code)
+		}
+	}
+}
+
+func TestDocURLs_Finality(t *testing.T) {
+	s := setupServerForDocTest(t)
+
+	req := httptest.NewRequest("GET", "/api/v1/consensus/finality", nil)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	var resp struct {
+		Success bool                     `json:"success"`
+		Data    consensus.FinalityStatus `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || !resp.Success || resp.Data.FinalizedHash != s.core.Genesis.Hash {
+		t.Errorf("GET /api/v1/consensus/finality: статус %d, data %+v", w.Code, resp.Data)
+	}
+
+	// без движка консенсуса голоса не принимаются
+	req = httptest.NewRequest("POST", "/api/v1/consensus/vote", strings.NewReader(`{"type":"prevote","height":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("POST /api/v1/consensus/vote без слоя финальности: статус %d, ожидался 503", w.Code)
+	}
+}
+
+func TestDocURLs_Staking(t *testing.T) {
+	s := setupServerForDocTest(t)
+	for _, path := range []string{"/api/v1/staking/validators", "/api/v1/staking/GN_delegator"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		var resp APIResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: decode: %v", path, err)
+		}
+		if w.Code != http.StatusOK || !resp.Success || resp.Data == nil {
+			t.Errorf("GET %s: статус %d, ожидались success и data", path, w.Code)
+		}
+	}
+}
+
 func TestDocURLs_ContractGet(t *testing.T) {
 	s := setupServerForDocTest(t)
 	req := httptest.NewRequest("GET", "/api/v1/contract/GNDctTestAddress123", nil)
@@ -176,3 +255,41 @@ func TestDocURLs_TokenBalance(t *testing.T) {
 		t.Errorf("GET /api/v1/token/:address/balance/:owner: статус %d", w.Code)
 	}
 }
+
+func TestDocURLs_StateProofs(t *testing.T) {
+	s := setupServerForDocTest(t)
+	if err := s.core.ProduceNextBlock(s.core.Mempool, "test", 10); err != nil {
+		t.Fatal(err)
+	}
+	head, _ := s.core.LatestBlock()
+
+	req := httptest.NewRequest("GET", "/api/v1/state/account/GN_proof_account/proof?block=1", nil)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /state/account/:address/proof: статус %d, %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data core.AccountProof `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if err := proof.VerifyAccount(&resp.Data, head.Hash); err != nil {
+		t.Errorf("доказательство из API не проверяется: %v", err)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/state/account/GN_proof_account/proof?block=x":           http.StatusBadRequest,
+		"/api/v1/state/account/GN_proof_account/proof?block=7":           http.StatusNotFound,
+		"/api/v1/state/contract/GN_proof_contract/storage/0x01/proof":    http.StatusOK,
+		"/api/v1/state/contract/GN_proof_contract/storage/not-hex/proof": http.StatusBadRequest,
+		"/api/v1/transaction/unknown_tx/proof":                           http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s: статус %d, ожидался %d", path, w.Code, want)
+		}
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
+	"encoding/json"
 	"os"
+	"strings"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -39,6 +41,51 @@ func ValidateAPIKey(ctx context.Context, pool *pgxpool.Pool, key string) bool {
 	return err == nil && exists
 }
 
+// Права API-ключа на подпись транзакций кастодиальным кошельком (POST /api/v1/wallet/:address/sign-and-send):
+// "wallet:sign:<адрес>" — один кошелёк, "wallet:sign:*" — все кошельки signing_service.
+const (
+	PermWalletSignPrefix = "wallet:sign:"
+	PermWalletSignAll    = PermWalletSignPrefix + "*"
+)
+
+// APIKey — действующая запись api_keys (не отключена и не просрочена).
+type APIKey struct {
+	ID          int
+	Permissions []string
+}
+
+// LookupAPIKey находит действующий ключ из заголовка X-API-Key (по key_hash или legacy-полю key). nil — ключ не найден.
+func LookupAPIKey(ctx context.Context, pool *pgxpool.Pool, key string) *APIKey {
+	if key == "" || pool == nil {
+		return nil
+	}
+	var k APIKey
+	var permsRaw []byte
+	err := pool.QueryRow(ctx, `
+		SELECT id, permissions FROM public.api_keys
+		WHERE (key_hash = $1 OR key = $2) AND COALESCE(disabled, false) = false
+			AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY id LIMIT 1`, HashKey(key), key).Scan(&k.ID, &permsRaw)
+	if err != nil {
+		return nil
+	}
+	if len(permsRaw) > 0 {
+		_ = json.Unmarshal(permsRaw, &k.Permissions)
+	}
+	return &k
+}
+
+// CanSignFor сообщает, есть ли у ключа право подписывать транзакции кошелька address.
+func (k *APIKey) CanSignFor(address string) bool {
+	address = strings.TrimSpace(address)
+	for _, p := range k.Permissions {
+		if p == PermWalletSignAll || (address != "" && p == PermWalletSignPrefix+address) {
+			return true
+		}
+	}
+	return false
+}
+
 // HashKey возвращает SHA-256 хеш ключа в hex (для сохранения в api_keys.key_hash).
 func HashKey(key string) string {
 	h := sha256.Sum256([]byte(key))
@@ -0,0 +1,121 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// api/consensus.go — состояние консенсуса: предупреждения и баны валидаторов PoA, история нарушений, финальность и голоса, реестр стейкинга PoS.
+
+package api
+
+import (
+	"math/big"
+	"net/http"
+	"strings"
+
+	"GND/consensus"
+	"GND/core"
+
+	"github.com/gin-gonic/gin"
+)
+
+// poaEngine возвращает движок PoA цепи; при его отсутствии отвечает 503 и возвращает nil.
+func (s *Server) poaEngine(c *gin.Context) *consensus.PoA {
+	var poa *consensus.PoA
+	if s.core != nil {
+		poa, _ = s.core.Engine.(*consensus.PoA)
+	}
+	if poa == nil {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{Success: false, Error: "Движок PoA не подключён (нода без ключа валидатора)", Code: http.StatusServiceUnavailable})
+	}
+	return poa
+}
+
+// GetPoAValidators возвращает набор валидаторов PoA с предупреждениями и банами (сводно — ConsensusMetrics в /metrics).
+// GET /api/v1/consensus/validators
+func (s *Server) GetPoAValidators(c *gin.Context) {
+	poa := s.poaEngine(c)
+	if poa == nil {
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: poa.Statuses()})
+}
+
+// GetPoAHistory возвращает историю нарушений PoA от новых к старым. Query: address — фильтр по валидатору.
+// GET /api/v1/consensus/history
+func (s *Server) GetPoAHistory(c *gin.Context) {
+	poa := s.poaEngine(c)
+	if poa == nil {
+		return
+	}
+	events := poa.History(c.Query("address"))
+	if events == nil {
+		events = []consensus.PoAEvent{}
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: events})
+}
+
+// GetFinality возвращает вершину цепи, последние justified и finalized блоки и отставание финальности (finality_lag).
+// GET /api/v1/consensus/finality
+func (s *Server) GetFinality(c *gin.Context) {
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: consensus.FinalityStatusOf(s.core)})
+}
+
+// SubmitVote принимает подписанный голос валидатора (prevote/precommit) за блок.
+// POST /api/v1/consensus/vote
+func (s *Server) SubmitVote(c *gin.Context) {
+	if s.core.Finality == nil {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{Success: false, Error: "Слой финальности не подключён (нода без движка консенсуса)", Code: http.StatusServiceUnavailable})
+		return
+	}
+	var vote core.Vote
+	if err := c.ShouldBindJSON(&vote); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Неверный формат голоса: " + err.Error(), Code: http.StatusBadRequest})
+		return
+	}
+	if err := s.core.Finality.AddVote(&vote); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Голос отклонён: " + err.Error(), Code: http.StatusBadRequest})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: consensus.FinalityStatusOf(s.core)})
+}
+
+// stakingAccount — стейкинг адреса: валидатор (если зарегистрирован), делегирования, разблокировки и полученные награды.
+type stakingAccount struct {
+	Address     string               `json:"address"`
+	Validator   *core.StakeValidator `json:"validator,omitempty"`
+	Delegations []core.Delegation    `json:"delegations"`
+	Unbondings  []core.Unbonding     `json:"unbondings"`
+	Rewards     *big.Int             `json:"rewards"`
+}
+
+// GetStakingValidators возвращает валидаторов PoS из реестра стейкинга с собственным и делегированным стейком.
+// GET /api/v1/staking/validators
+func (s *Server) GetStakingValidators(c *gin.Context) {
+	validators := s.core.Stakes.Validators()
+	if validators == nil {
+		validators = []*core.StakeValidator{}
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: validators})
+}
+
+// GetStakingAccount возвращает стейкинг адреса: делегирования, ожидающие разблокировки и полученные награды.
+// GET /api/v1/staking/:address
+func (s *Server) GetStakingAccount(c *gin.Context) {
+	address := strings.TrimSpace(c.Param("address"))
+	if address == "" {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Укажите address", Code: http.StatusBadRequest})
+		return
+	}
+	account := stakingAccount{
+		Address:     address,
+		Delegations: s.core.Stakes.Delegations(address),
+		Unbondings:  s.core.Stakes.Unbondings(address),
+		Rewards:     s.core.Stakes.Rewards(address),
+	}
+	if v, ok := s.core.Stakes.Validator(address); ok {
+		account.Validator = v
+	}
+	if account.Delegations == nil {
+		account.Delegations = []core.Delegation{}
+	}
+	if account.Unbondings == nil {
+		account.Unbondings = []core.Unbonding{}
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: account})
+}
@@ -0,0 +1,1023 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// api/jsonrpc.go — JSON-RPC 2.0 в формате Ethereum (eth_*, net_*, web3_*, debug_trace*) для MetaMask, ethers.js, Hardhat, Foundry.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"GND/core"
+	"GND/types"
+	"GND/vm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Коды ошибок JSON-RPC 2.0 и Ethereum
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	rpcServerError    = -32000
+	rpcExecutionError = 3 // execution reverted (как в geth: data — return data revert)
+)
+
+const (
+	rpcMaxBatchSize  = 100     // максимум запросов в одном batch
+	rpcMaxBodySize   = 5 << 20 // максимальный размер тела запроса
+	rpcMaxLogsRange  = 10_000  // максимальный диапазон блоков для eth_getLogs
+	rpcClientVersion = "GND/1.0.0"
+)
+
+// rpcRequest — запрос JSON-RPC 2.0. Запрос без id — уведомление (ответ не отправляется).
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse — ответ JSON-RPC 2.0 (ровно одно из result/error).
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError — ошибка JSON-RPC.
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+func invalidParams(format string, args ...interface{}) *rpcError {
+	return &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// rpcMethod — обработчик метода: params — позиционные параметры запроса.
+type rpcMethod func(ctx context.Context, params []json.RawMessage) (interface{}, error)
+
+// EthRPC — сервер JSON-RPC 2.0 с пространствами имён eth, net, web3 и debug поверх core.Blockchain, core.Mempool и vm.EVM.
+type EthRPC struct {
+	bc      *core.Blockchain
+	mempool *core.Mempool
+	evm     *vm.EVM
+	chainID int64
+	methods map[string]rpcMethod
+}
+
+// NewEthRPC создаёт JSON-RPC сервер. chainID — chain_id из config.json (0 — vm.DefaultChainID).
+func NewEthRPC(bc *core.Blockchain, mempool *core.Mempool, evm *vm.EVM, chainID int64) *EthRPC {
+	if chainID == 0 {
+		chainID = vm.DefaultChainID
+	}
+	r := &EthRPC{bc: bc, mempool: mempool, evm: evm, chainID: chainID}
+	r.methods = map[string]rpcMethod{
+		"web3_clientVersion":        r.clientVersion,
+		"web3_sha3":                 r.sha3,
+		"net_version":               r.netVersion,
+		"net_listening":             r.netListening,
+		"net_peerCount":             r.netPeerCount,
+		"eth_chainId":               r.chainIDMethod,
+		"eth_blockNumber":           r.blockNumber,
+		"eth_gasPrice":              r.gasPrice,
+		"eth_maxPriorityFeePerGas":  r.maxPriorityFeePerGas,
+		"eth_getBalance":            r.getBalance,
+		"eth_getTransactionCount":   r.getTransactionCount,
+		"eth_call":                  r.call,
+		"eth_estimateGas":           r.estimateGas,
+		"eth_sendRawTransaction":    r.sendRawTransaction,
+		"eth_getTransactionByHash":  r.getTransactionByHash,
+		"eth_getTransactionReceipt": r.getTransactionReceipt,
+		"eth_getBlockByNumber":      r.getBlockByNumber,
+		"eth_getBlockByHash":        r.getBlockByHash,
+		"eth_getLogs":               r.getLogs,
+		"debug_traceTransaction":    r.traceTransaction,
+		"debug_traceCall":           r.traceCall,
+		"debug_traceBlockByNumber":  r.traceBlockByNumber,
+	}
+	return r
+}
+
+// ServeHTTP принимает POST с одиночным запросом или batch (массив запросов).
+func (r *EthRPC) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "JSON-RPC: используйте POST", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, rpcMaxBodySize))
+	if err != nil {
+		writeRPC(w, errorResponse(nil, &rpcError{Code: rpcParseError, Message: "parse error"}))
+		return
+	}
+	if out := r.Handle(req.Context(), body); out != nil {
+		writeRPC(w, out)
+	}
+}
+
+// Handle обрабатывает тело запроса и возвращает ответ (одиночный, массив для batch или nil, если все запросы — уведомления).
+func (r *EthRPC) Handle(ctx context.Context, body []byte) interface{} {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return errorResponse(nil, &rpcError{Code: rpcParseError, Message: "parse error"})
+		}
+		if len(batch) == 0 {
+			return errorResponse(nil, &rpcError{Code: rpcInvalidRequest, Message: "empty batch"})
+		}
+		if len(batch) > rpcMaxBatchSize {
+			return errorResponse(nil, &rpcError{Code: rpcInvalidRequest, Message: fmt.Sprintf("batch too large: max %d requests", rpcMaxBatchSize)})
+		}
+		responses := make([]*rpcResponse, 0, len(batch))
+		for _, raw := range batch {
+			if resp := r.handleOne(ctx, raw); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		return responses
+	}
+	if resp := r.handleOne(ctx, body); resp != nil {
+		return resp
+	}
+	return nil
+}
+
+// handleOne исполняет один запрос; для уведомлений (без id) возвращает nil.
+func (r *EthRPC) handleOne(ctx context.Context, raw json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return errorResponse(nil, &rpcError{Code: rpcInvalidRequest, Message: "invalid request"})
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, &rpcError{Code: rpcInvalidRequest, Message: "invalid request"})
+	}
+	method, ok := r.methods[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil
+		}
+		return errorResponse(req.ID, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("the method %s does not exist/is not available", req.Method)})
+	}
+	var params []json.RawMessage
+	if len(req.Params) > 0 && string(req.Params) != "null" {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return errorResponse(req.ID, invalidParams("params must be an array"))
+		}
+	}
+	result, err := r.invoke(ctx, method, params)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: rpcServerError, Message: err.Error()}
+		}
+		return errorResponse(req.ID, rpcErr)
+	}
+	if result == nil {
+		// null — допустимый результат (например, квитанция ещё не создана)
+		result = json.RawMessage("null")
+	}
+	return &rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// invoke вызывает метод; паника в обработчике возвращается как internal error, а не роняет сервер.
+func (r *EthRPC) invoke(ctx context.Context, method rpcMethod, params []json.RawMessage) (result interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, &rpcError{Code: rpcInternalError, Message: fmt.Sprintf("internal error: %v", p)}
+		}
+	}()
+	return method(ctx, params)
+}
+
+func errorResponse(id json.RawMessage, err *rpcError) *rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &rpcResponse{JSONRPC: "2.0", ID: id, Error: err}
+}
+
+func writeRPC(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// parseParams разбирает позиционные параметры в dst; первые required обязательны.
+func parseParams(params []json.RawMessage, required int, dst ...interface{}) error {
+	if len(params) < required {
+		return invalidParams("missing value for required argument %d", len(params))
+	}
+	if len(params) > len(dst) {
+		return invalidParams("too many arguments, want at most %d", len(dst))
+	}
+	for i, raw := range params {
+		if err := json.Unmarshal(raw, dst[i]); err != nil {
+			return invalidParams("invalid argument %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// --- web3 / net ---
+
+func (r *EthRPC) clientVersion(context.Context, []json.RawMessage) (interface{}, error) {
+	return rpcClientVersion, nil
+}
+
+func (r *EthRPC) sha3(_ context.Context, params []json.RawMessage) (interface{}, error) {
+	var data hexutil.Bytes
+	if err := parseParams(params, 1, &data); err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(crypto.Keccak256(data)), nil
+}
+
+func (r *EthRPC) netVersion(context.Context, []json.RawMessage) (interface{}, error) {
+	return strconv.FormatInt(r.chainID, 10), nil
+}
+
+func (r *EthRPC) netListening(context.Context, []json.RawMessage) (interface{}, error) {
+	return true, nil
+}
+
+// netPeerCount возвращает число подключённых пиров P2P (метрика NetworkMetrics.ActivePeers).
+func (r *EthRPC) netPeerCount(context.Context, []json.RawMessage) (interface{}, error) {
+	return hexutil.Uint64(core.GetMetrics().NetworkMetrics.ActivePeers), nil
+}
+
+// --- eth: сеть и состояние ---
+
+func (r *EthRPC) chainIDMethod(context.Context, []json.RawMessage) (interface{}, error) {
+	return hexutil.Uint64(r.chainID), nil
+}
+
+func (r *EthRPC) blockNumber(context.Context, []json.RawMessage) (interface{}, error) {
+	head, err := r.bc.LatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Uint64(head.Index), nil
+}
+
+// gasPrice — цена газа для обычной транзакции: base fee следующего блока и типичные чаевые.
+func (r *EthRPC) gasPrice(context.Context, []json.RawMessage) (interface{}, error) {
+	fees := r.bc.SuggestFees()
+	return (*hexutil.Big)(new(big.Int).Add(fees.BaseFee, fees.Standard.MaxPriorityFeePerGas)), nil
+}
+
+func (r *EthRPC) maxPriorityFeePerGas(context.Context, []json.RawMessage) (interface{}, error) {
+	return (*hexutil.Big)(r.bc.SuggestFees().Standard.MaxPriorityFeePerGas), nil
+}
+
+func (r *EthRPC) getBalance(_ context.Context, params []json.RawMessage) (interface{}, error) {
+	var addr, tag string
+	if err := parseParams(params, 1, &addr, &tag); err != nil {
+		return nil, err
+	}
+	if err := r.requireLatest(tag); err != nil {
+		return nil, err
+	}
+	gndAddr, err := gndAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	balance := r.bc.State.GetBalance(types.Address(gndAddr), core.GasSymbol)
+	if balance == nil {
+		balance = new(big.Int)
+	}
+	return (*hexutil.Big)(balance), nil
+}
+
+func (r *EthRPC) getTransactionCount(_ context.Context, params []json.RawMessage) (interface{}, error) {
+	var addr, tag string
+	if err := parseParams(params, 1, &addr, &tag); err != nil {
+		return nil, err
+	}
+	if err := r.requireLatest(tag); err != nil {
+		return nil, err
+	}
+	gndAddr, err := gndAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	// pending учитывает транзакции отправителя, ожидающие в мемпуле
+	if tag == "pending" && r.mempool != nil {
+		return hexutil.Uint64(r.mempool.NextNonce(types.Address(gndAddr))), nil
+	}
+	return hexutil.Uint64(r.bc.State.GetNonce(types.Address(gndAddr))), nil
+}
+
+// --- eth: исполнение ---
+
+// ethCallArgs — аргументы eth_call / eth_estimateGas (поле input имеет приоритет над data).
+type ethCallArgs struct {
+	From  string          `json:"from"`
+	To    string          `json:"to"`
+	Gas   *hexutil.Uint64 `json:"gas"`
+	Value *hexutil.Big    `json:"value"`
+	Data  *hexutil.Bytes  `json:"data"`
+	Input *hexutil.Bytes  `json:"input"`
+}
+
+func (a *ethCallArgs) data() []byte {
+	if a.Input != nil {
+		return *a.Input
+	}
+	if a.Data != nil {
+		return *a.Data
+	}
+	return nil
+}
+
+func (a *ethCallArgs) value() (uint64, error) {
+	if a.Value == nil {
+		return 0, nil
+	}
+	v := a.Value.ToInt()
+	if v.Sign() < 0 || !v.IsUint64() {
+		return 0, invalidParams("value out of range")
+	}
+	return v.Uint64(), nil
+}
+
+func (a *ethCallArgs) gas() uint64 {
+	if a.Gas == nil || uint64(*a.Gas) == 0 || uint64(*a.Gas) > core.DefaultBlockGasLimit {
+		return core.DefaultBlockGasLimit
+	}
+	return uint64(*a.Gas)
+}
+
+func (r *EthRPC) call(_ context.Context, params []json.RawMessage) (interface{}, error) {
+	var args ethCallArgs
+	var tag string
+	if err := parseParams(params, 1, &args, &tag); err != nil {
+		return nil, err
+	}
+	if err := r.requireLatest(tag); err != nil {
+		return nil, err
+	}
+	if args.To == "" {
+		return nil, invalidParams("missing \"to\" address")
+	}
+	result, err := r.staticCall(&args, args.gas())
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, revertError(result.Error, result.ReturnData)
+	}
+	return hexutil.Bytes(result.ReturnData), nil
+}
+
+// estimateGas возвращает минимальный лимит газа, при котором транзакция исполняется успешно (vm.EVM.Simulate):
+// для последнего блока — над текущим состоянием, для более раннего — над копией состояния после него.
+func (r *EthRPC) estimateGas(_ context.Context, params []json.RawMessage) (interface{}, error) {
+	var args ethCallArgs
+	var tag string
+	if err := parseParams(params, 1, &args, &tag); err != nil {
+		return nil, err
+	}
+	if r.evm == nil {
+		return nil, errors.New("EVM недоступна")
+	}
+	tx, err := args.transaction()
+	if err != nil {
+		return nil, err
+	}
+	st, block, err := r.stateAt(tag)
+	if err != nil {
+		return nil, err
+	}
+	sim, err := r.evm.Simulate(tx, block, st)
+	if err != nil {
+		return nil, err
+	}
+	if sim.Error != nil {
+		return nil, revertError(sim.Error, sim.ReturnData)
+	}
+	return hexutil.Uint64(sim.GasEstimate), nil
+}
+
+// transaction формирует транзакцию для пробного исполнения (пустой to — деплой).
+func (a *ethCallArgs) transaction() (*core.Transaction, error) {
+	from, to := "", ""
+	var err error
+	if a.From != "" {
+		if from, err = gndAddress(a.From); err != nil {
+			return nil, err
+		}
+	}
+	if a.To != "" {
+		if to, err = gndAddress(a.To); err != nil {
+			return nil, err
+		}
+	}
+	value, err := a.value()
+	if err != nil {
+		return nil, err
+	}
+	return &core.Transaction{
+		Sender:    types.Address(from),
+		Recipient: types.Address(to),
+		Data:      a.data(),
+		GasLimit:  a.gas(),
+		GasPrice:  big.NewInt(0),
+		Value:     new(big.Int).SetUint64(value),
+	}, nil
+}
+
+// staticCall исполняет вызов контракта без изменения состояния.
+func (r *EthRPC) staticCall(args *ethCallArgs, gas uint64) (*types.ExecutionResult, error) {
+	if r.evm == nil {
+		return nil, errors.New("EVM недоступна")
+	}
+	from, to := "", ""
+	var err error
+	if args.From != "" {
+		if from, err = gndAddress(args.From); err != nil {
+			return nil, err
+		}
+	}
+	if to, err = gndAddress(args.To); err != nil {
+		return nil, err
+	}
+	value, err := args.value()
+	if err != nil {
+		return nil, err
+	}
+	return r.evm.CallContractStatic(from, to, args.data(), gas, value)
+}
+
+// revertError формирует ошибку исполнения; для revert в data — return data (причина Error(string)).
+func revertError(err error, returnData []byte) *rpcError {
+	e := &rpcError{Code: rpcExecutionError, Message: err.Error()}
+	if len(returnData) > 0 {
+		e.Data = hexutil.Bytes(returnData).String()
+	}
+	return e
+}
+
+// sendRawTransaction декодирует подписанную транзакцию и отправляет её тем же путём, что POST /transaction.
+// Raw-транзакция кошелька Ethereum (legacy, EIP-2930, EIP-1559) принимается как есть, иначе — кодирование ГАНИМЕД
+// (core.DecodeRawTransaction).
+func (r *EthRPC) sendRawTransaction(_ context.Context, params []json.RawMessage) (interface{}, error) {
+	var raw hexutil.Bytes
+	if err := parseParams(params, 1, &raw); err != nil {
+		return nil, err
+	}
+	tx, err := r.decodeRawTransaction(raw)
+	if err != nil {
+		return nil, invalidParams("invalid raw transaction: %v", err)
+	}
+	hash, err := r.bc.SendTransaction(tx)
+	if errors.Is(err, core.ErrWrongChain) {
+		return nil, &rpcError{Code: ErrCodeWrongChain, Message: err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ethHash(hash), nil
+}
+
+// decodeRawTransaction разбирает raw-транзакцию Ethereum (types.Transaction.UnmarshalBinary) и, только если она
+// не декодируется, — транзакцию ГАНИМЕД. Отправитель транзакции Ethereum — GND-адрес ключа подписи; за ним
+// запоминается адрес кошелька Ethereum, чтобы eth_getBalance и eth_getTransactionCount видели тот же счёт.
+func (r *EthRPC) decodeRawTransaction(raw []byte) (*core.Transaction, error) {
+	var etx gethtypes.Transaction
+	if err := etx.UnmarshalBinary(raw); err != nil {
+		return core.DecodeRawTransaction(raw)
+	}
+	tx, err := core.DecodeEthereumTransaction(raw, vm.FromEVMAddress)
+	if err != nil {
+		return nil, err
+	}
+	tx.SubnetID = r.bc.SubnetID
+	if from, err := gethtypes.Sender(gethtypes.LatestSignerForChainID(etx.ChainId()), &etx); err == nil {
+		vm.MapEVMAddress(from, tx.Sender.String())
+	}
+	return tx, nil
+}
+
+// --- eth: транзакции и квитанции ---
+
+func (r *EthRPC) getTransactionByHash(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+	var hash string
+	if err := parseParams(params, 1, &hash); err != nil {
+		return nil, err
+	}
+	hash = gndHash(hash)
+	if r.mempool != nil {
+		if tx, err := r.mempool.GetTransaction(hash); err == nil && tx != nil {
+			return r.ethTransaction(tx, nil, 0), nil
+		}
+	}
+	receipt, err := r.bc.GetReceipt(ctx, hash)
+	if err != nil || receipt == nil {
+		return nil, nil
+	}
+	block, err := r.blockByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return nil, nil
+	}
+	for i, tx := range block.Transactions {
+		if tx.Hash == hash {
+			return r.ethTransaction(tx, block, i), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *EthRPC) getTransactionReceipt(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+	var hash string
+	if err := parseParams(params, 1, &hash); err != nil {
+		return nil, err
+	}
+	receipt, err := r.bc.GetReceipt(ctx, gndHash(hash))
+	if err != nil || receipt == nil {
+		// Ожидающая или неизвестная транзакция — null, как в Ethereum
+		return nil, nil
+	}
+	return ethReceipt(receipt), nil
+}
+
+// --- eth: блоки и логи ---
+
+func (r *EthRPC) getBlockByNumber(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+	var tag string
+	var fullTx bool
+	if err := parseParams(params, 1, &tag, &fullTx); err != nil {
+		return nil, err
+	}
+	number, err := r.resolveBlockNumber(tag)
+	if err != nil {
+		return nil, err
+	}
+	block, err := r.blockByNumber(ctx, number)
+	if err != nil || block == nil {
+		return nil, nil
+	}
+	return r.ethBlock(ctx, block, fullTx), nil
+}
+
+func (r *EthRPC) getBlockByHash(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+	var hash string
+	var fullTx bool
+	if err := parseParams(params, 1, &hash, &fullTx); err != nil {
+		return nil, err
+	}
+	block, err := r.blockByHash(gndHash(hash))
+	if err != nil || block == nil {
+		return nil, nil
+	}
+	return r.ethBlock(ctx, block, fullTx), nil
+}
+
+// ethLogFilter — фильтр eth_getLogs: диапазон блоков или blockHash, адрес (строка или массив), темы по позициям.
+type ethLogFilter struct {
+	FromBlock string            `json:"fromBlock"`
+	ToBlock   string            `json:"toBlock"`
+	BlockHash string            `json:"blockHash"`
+	Address   json.RawMessage   `json:"address"`
+	Topics    []json.RawMessage `json:"topics"`
+}
+
+func (r *EthRPC) getLogs(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+	var filter ethLogFilter
+	if err := parseParams(params, 1, &filter); err != nil {
+		return nil, err
+	}
+	addresses, err := stringOrList(filter.Address)
+	if err != nil {
+		return nil, invalidParams("invalid address filter: %v", err)
+	}
+	for i, a := range addresses {
+		addresses[i] = strings.ToLower(a)
+	}
+	topics := make([][]string, len(filter.Topics))
+	for i, raw := range filter.Topics {
+		if topics[i], err = stringOrList(raw); err != nil {
+			return nil, invalidParams("invalid topic %d: %v", i, err)
+		}
+		for j, t := range topics[i] {
+			topics[i][j] = strings.ToLower(t)
+		}
+	}
+
+	var blocks []*core.Block
+	if filter.BlockHash != "" {
+		if filter.FromBlock != "" || filter.ToBlock != "" {
+			return nil, invalidParams("cannot specify both blockHash and fromBlock/toBlock")
+		}
+		block, err := r.blockByHash(gndHash(filter.BlockHash))
+		if err != nil || block == nil {
+			return nil, invalidParams("unknown block")
+		}
+		blocks = append(blocks, block)
+	} else {
+		from, err := r.resolveBlockNumber(filter.FromBlock)
+		if err != nil {
+			return nil, err
+		}
+		to, err := r.resolveBlockNumber(filter.ToBlock)
+		if err != nil {
+			return nil, err
+		}
+		if from > to {
+			return nil, invalidParams("fromBlock %d is greater than toBlock %d", from, to)
+		}
+		if to-from >= rpcMaxLogsRange {
+			return nil, invalidParams("block range too large: max %d blocks", rpcMaxLogsRange)
+		}
+		for n := from; n <= to; n++ {
+			if block, err := r.blockByNumber(ctx, n); err == nil && block != nil {
+				blocks = append(blocks, block)
+			}
+		}
+	}
+
+	logs := make([]map[string]interface{}, 0)
+	for _, block := range blocks {
+		receipts, err := r.bc.BlockReceipts(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		for _, receipt := range receipts {
+			for _, l := range receipt.Logs {
+				entry := ethLog(receipt, l)
+				if matchLog(entry, addresses, topics) {
+					logs = append(logs, entry)
+				}
+			}
+		}
+	}
+	return logs, nil
+}
+
+// matchLog проверяет лог по фильтру адресов (любой из) и тем (на каждой позиции — любая из, пустая позиция — любая тема).
+func matchLog(entry map[string]interface{}, addresses []string, topics [][]string) bool {
+	if len(addresses) > 0 && !containsString(addresses, strings.ToLower(entry["address"].(string))) {
+		return false
+	}
+	logTopics := entry["topics"].([]string)
+	if len(topics) > len(logTopics) {
+		return false
+	}
+	for i, want := range topics {
+		if len(want) > 0 && !containsString(want, strings.ToLower(logTopics[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// stringOrList разбирает null, строку или массив строк.
+func stringOrList(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, errors.New("expected string or array of strings")
+	}
+	return list, nil
+}
+
+// --- блоки: поиск и теги ---
+
+// resolveBlockNumber переводит тег блока (latest, pending, safe, finalized, earliest) или hex-номер в номер блока.
+// safe — последний блок со статусом justified, finalized — последний финализированный блок.
+func (r *EthRPC) resolveBlockNumber(tag string) (uint64, error) {
+	switch tag {
+	case "safe":
+		return r.bc.JustifiedBlock().Index, nil
+	case "finalized":
+		return r.bc.FinalizedBlock().Index, nil
+	case "", "latest", "pending":
+		head, err := r.bc.LatestBlock()
+		if err != nil {
+			return 0, err
+		}
+		return head.Index, nil
+	case "earliest":
+		return 0, nil
+	}
+	n, err := hexutil.DecodeUint64(tag)
+	if err != nil {
+		return 0, invalidParams("invalid block number %q", tag)
+	}
+	return n, nil
+}
+
+// stateAt возвращает состояние для пробного исполнения в блоке tag: для вершины цепи — nil (текущее состояние EVM),
+// для более раннего блока — копию состояния после него (core.Blockchain.StateAt) и сам блок.
+func (r *EthRPC) stateAt(tag string) (core.StateIface, *core.Block, error) {
+	n, err := r.resolveBlockNumber(tag)
+	if err != nil {
+		return nil, nil, err
+	}
+	head, err := r.bc.LatestBlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	if n == head.Index {
+		return nil, nil, nil
+	}
+	st, block, err := r.bc.StateAt(&n)
+	if err != nil {
+		return nil, nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+	return st, block, nil
+}
+
+// requireLatest допускает только текущее состояние: историческое состояние нода не хранит.
+func (r *EthRPC) requireLatest(tag string) error {
+	n, err := r.resolveBlockNumber(tag)
+	if err != nil {
+		return err
+	}
+	head, err := r.bc.LatestBlock()
+	if err != nil {
+		return err
+	}
+	if n != head.Index {
+		return &rpcError{Code: rpcServerError, Message: fmt.Sprintf("historical state is not available (block %d, head %d)", n, head.Index)}
+	}
+	return nil
+}
+
+// blockByNumber ищет блок в памяти, затем в БД (с загрузкой транзакций).
+func (r *EthRPC) blockByNumber(ctx context.Context, number uint64) (*core.Block, error) {
+	blocks := r.bc.AllBlocks()
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if blocks[i].Index == number {
+			return blocks[i], nil
+		}
+	}
+	if r.bc.Pool == nil {
+		return nil, errors.New("block not found")
+	}
+	block, err := r.bc.GetBlockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	block.Transactions, _ = core.LoadTransactionsForBlock(ctx, r.bc.Pool, block.ID)
+	return block, nil
+}
+
+func (r *EthRPC) blockByHash(hash string) (*core.Block, error) {
+	for _, b := range r.bc.AllBlocks() {
+		if b.Hash == hash {
+			return b, nil
+		}
+	}
+	if r.bc.Pool == nil {
+		return nil, errors.New("block not found")
+	}
+	block, err := r.bc.GetBlockByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	block.Transactions, _ = core.LoadTransactionsForBlock(context.Background(), r.bc.Pool, block.ID)
+	return block, nil
+}
+
+// --- преобразование в формат Ethereum ---
+
+func (r *EthRPC) ethBlock(ctx context.Context, b *core.Block, fullTx bool) map[string]interface{} {
+	txs := make([]interface{}, 0, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		if fullTx {
+			txs = append(txs, r.ethTransaction(tx, b, i))
+		} else {
+			txs = append(txs, ethHash(tx.Hash))
+		}
+	}
+	var bloom gethtypes.Bloom
+	if receipts, err := r.bc.BlockReceipts(ctx, b); err == nil {
+		for _, receipt := range receipts {
+			addLogsToBloom(&bloom, receipt.Logs)
+		}
+	}
+	out := map[string]interface{}{
+		"number":           hexutil.Uint64(b.Index),
+		"hash":             ethHash(b.Hash),
+		"parentHash":       ethHash(b.PrevHash),
+		"nonce":            gethtypes.EncodeNonce(b.Nonce),
+		"sha3Uncles":       gethtypes.EmptyUncleHash,
+		"logsBloom":        bloom,
+		"transactionsRoot": ethHash(b.MerkleRoot),
+		"stateRoot":        ethHash(b.StateRoot),
+		"receiptsRoot":     common.Hash{},
+		"miner":            ethAddress(b.Miner),
+		"difficulty":       (*hexutil.Big)(new(big.Int).SetUint64(b.Difficulty)),
+		"totalDifficulty":  (*hexutil.Big)(new(big.Int)),
+		"extraData":        hexutil.Bytes(b.ExtraData),
+		"size":             hexutil.Uint64(b.Size),
+		"gasLimit":         hexutil.Uint64(b.GasLimit),
+		"gasUsed":          hexutil.Uint64(b.GasUsed),
+		"timestamp":        hexutil.Uint64(b.Timestamp.Unix()),
+		"mixHash":          common.Hash{},
+		"transactions":     txs,
+		"uncles":           []string{},
+	}
+	if b.BaseFee != nil {
+		out["baseFeePerGas"] = (*hexutil.Big)(b.BaseFee)
+	}
+	return out
+}
+
+// ethTransaction переводит транзакцию в формат Ethereum; block == nil — транзакция ещё в мемпуле.
+func (r *EthRPC) ethTransaction(tx *core.Transaction, block *core.Block, index int) map[string]interface{} {
+	value := new(big.Int)
+	if tx.Value != nil {
+		value = tx.Value
+	}
+	out := map[string]interface{}{
+		"hash":             ethHash(tx.Hash),
+		"nonce":            hexutil.Uint64(tx.Nonce),
+		"from":             ethAddress(tx.Sender.String()),
+		"to":               nil,
+		"value":            (*hexutil.Big)(value),
+		"gas":              hexutil.Uint64(tx.EffectiveGasLimit()),
+		"gasPrice":         (*hexutil.Big)(tx.EffectiveGasPrice()),
+		"input":            hexutil.Bytes(tx.Data),
+		"type":             hexutil.Uint64(gethtypes.LegacyTxType),
+		"chainId":          (*hexutil.Big)(big.NewInt(r.chainID)),
+		"v":                (*hexutil.Big)(new(big.Int)),
+		"r":                (*hexutil.Big)(new(big.Int)),
+		"s":                (*hexutil.Big)(new(big.Int)),
+		"blockHash":        nil,
+		"blockNumber":      nil,
+		"transactionIndex": nil,
+	}
+	if tx.Type != "contract_deploy" && !tx.IsDeploy() && tx.Recipient != "" {
+		out["to"] = ethAddress(tx.Recipient.String())
+	}
+	if len(tx.Signature) == 64 {
+		out["r"] = (*hexutil.Big)(new(big.Int).SetBytes(tx.Signature[:32]))
+		out["s"] = (*hexutil.Big)(new(big.Int).SetBytes(tx.Signature[32:]))
+	}
+	if block != nil {
+		out["blockHash"] = ethHash(block.Hash)
+		out["blockNumber"] = hexutil.Uint64(block.Index)
+		out["transactionIndex"] = hexutil.Uint64(index)
+	}
+	if tx.IsDynamicFee() {
+		out["type"] = hexutil.Uint64(gethtypes.DynamicFeeTxType)
+		out["maxFeePerGas"] = (*hexutil.Big)(tx.MaxFeePerGas)
+		out["maxPriorityFeePerGas"] = (*hexutil.Big)(tx.MaxPriorityFeePerGas)
+		if block != nil {
+			out["gasPrice"] = (*hexutil.Big)(tx.GasPriceAt(block.BaseFee))
+		}
+	}
+	return out
+}
+
+func ethReceipt(rc *core.Receipt) map[string]interface{} {
+	logs := make([]map[string]interface{}, 0, len(rc.Logs))
+	for _, l := range rc.Logs {
+		logs = append(logs, ethLog(rc, l))
+	}
+	var bloom gethtypes.Bloom
+	addLogsToBloom(&bloom, rc.Logs)
+	status := gethtypes.ReceiptStatusFailed
+	if rc.Succeeded() {
+		status = gethtypes.ReceiptStatusSuccessful
+	}
+	price := big.NewInt(core.DefaultGasPrice)
+	if fee, ok := new(big.Int).SetString(rc.Fee, 10); ok && rc.GasUsed > 0 && fee.Sign() > 0 {
+		price = fee.Div(fee, new(big.Int).SetUint64(rc.GasUsed))
+	}
+	out := map[string]interface{}{
+		"transactionHash":   ethHash(rc.TxHash),
+		"transactionIndex":  hexutil.Uint64(rc.TxIndex),
+		"blockHash":         ethHash(rc.BlockHash),
+		"blockNumber":       hexutil.Uint64(rc.BlockNumber),
+		"from":              ethAddress(rc.From),
+		"to":                nil,
+		"cumulativeGasUsed": hexutil.Uint64(rc.CumulativeGasUsed),
+		"gasUsed":           hexutil.Uint64(rc.GasUsed),
+		"effectiveGasPrice": (*hexutil.Big)(price),
+		"contractAddress":   nil,
+		"logs":              logs,
+		"logsBloom":         bloom,
+		"type":              hexutil.Uint64(gethtypes.LegacyTxType),
+		"status":            hexutil.Uint64(status),
+	}
+	if rc.ContractAddress != "" {
+		out["contractAddress"] = ethAddress(rc.ContractAddress)
+	} else {
+		out["to"] = ethAddress(rc.To)
+	}
+	if rc.RevertReason != "" {
+		out["revertReason"] = rc.RevertReason
+	}
+	return out
+}
+
+func ethLog(rc *core.Receipt, l *core.ReceiptLog) map[string]interface{} {
+	topics := make([]string, len(l.Topics))
+	copy(topics, l.Topics)
+	return map[string]interface{}{
+		"address":          ethAddress(l.Address),
+		"topics":           topics,
+		"data":             l.Data,
+		"blockNumber":      hexutil.Uint64(rc.BlockNumber),
+		"blockHash":        ethHash(rc.BlockHash),
+		"transactionHash":  ethHash(rc.TxHash),
+		"transactionIndex": hexutil.Uint64(rc.TxIndex),
+		"logIndex":         hexutil.Uint64(l.LogIndex),
+		"removed":          false,
+	}
+}
+
+func addLogsToBloom(bloom *gethtypes.Bloom, logs []*core.ReceiptLog) {
+	for _, l := range logs {
+		bloom.Add(vm.ToEVMAddress(l.Address).Bytes())
+		for _, t := range l.Topics {
+			if b, err := hexutil.Decode(t); err == nil {
+				bloom.Add(b)
+			}
+		}
+	}
+}
+
+// ethAddress переводит адрес ГАНИМЕД в 20-байтный адрес EVM (0x…); пустой адрес — null.
+func ethAddress(addr string) interface{} {
+	if addr == "" {
+		return nil
+	}
+	return vm.ToEVMAddress(addr).Hex()
+}
+
+// gndAddress переводит адрес из запроса в адрес ГАНИМЕД: 0x+40 hex — через vm.FromEVMAddress, адреса GN_/GNDct — как есть.
+func gndAddress(addr string) (string, error) {
+	s := strings.TrimSpace(addr)
+	if s == "" {
+		return "", invalidParams("empty address")
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		if !common.IsHexAddress(s) {
+			return "", invalidParams("invalid address %q", s)
+		}
+		return vm.FromEVMAddress(common.HexToAddress(s)), nil
+	}
+	return s, nil
+}
+
+// ethHash добавляет 0x к hex-хешу ГАНИМЕД.
+func ethHash(h string) string {
+	if h == "" {
+		return common.Hash{}.Hex()
+	}
+	if strings.HasPrefix(h, "0x") {
+		return h
+	}
+	return "0x" + h
+}
+
+// gndHash убирает 0x из хеша запроса (хеши ГАНИМЕД хранятся в hex без префикса).
+func gndHash(h string) string {
+	h = strings.TrimSpace(h)
+	if len(h) > 2 && (h[:2] == "0x" || h[:2] == "0X") {
+		return strings.ToLower(h[2:])
+	}
+	return h
+}
@@ -0,0 +1,140 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// api/jsonrpc_debug.go — JSON-RPC пространства debug: трассировка исполнения транзакций (debug_traceTransaction,
+// debug_traceCall, debug_traceBlockByNumber) повторным применением над копией состояния (vm.EVM.TraceTx).
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"GND/core"
+	"GND/types"
+	"GND/vm"
+)
+
+// traceConfig — настройки трассировки: имя трассировщика (пусто — structLogger) и его настройки.
+// Поля structLogger допускаются и на верхнем уровне, как в geth.
+type traceConfig struct {
+	Tracer       string          `json:"tracer"`
+	TracerConfig json.RawMessage `json:"tracerConfig"`
+	vm.StructLoggerConfig
+}
+
+// newTracer создаёт трассировщик по настройкам запроса.
+func (c *traceConfig) newTracer() (vm.Tracer, error) {
+	config := c.TracerConfig
+	if (c.Tracer == "" || c.Tracer == vm.DefaultTracer) && len(config) == 0 {
+		config, _ = json.Marshal(c.StructLoggerConfig)
+	}
+	tracer, err := vm.NewTracer(c.Tracer, config)
+	if err != nil {
+		return nil, invalidParams("%v", err)
+	}
+	return tracer, nil
+}
+
+// traceTransaction повторно применяет блок транзакции до неё и трассирует саму транзакцию.
+func (r *EthRPC) traceTransaction(ctx context.Context, params []json.RawMessage) (interface{}, error) {
+	var hash string
+	var cfg traceConfig
+	if err := parseParams(params, 1, &hash, &cfg); err != nil {
+		return nil, err
+	}
+	if r.evm == nil {
+		return nil, errors.New("EVM недоступна")
+	}
+	hash = gndHash(hash)
+	receipt, err := r.bc.GetReceipt(ctx, hash)
+	if err != nil || receipt == nil {
+		return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("transaction %s not found", ethHash(hash))}
+	}
+	replay, err := r.bc.ReplayBlock(receipt.BlockNumber)
+	if err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+	for _, tx := range replay.Block.Transactions {
+		if tx.Hash != hash {
+			r.evm.ReplayTx(replay, tx)
+			continue
+		}
+		tracer, err := cfg.newTracer()
+		if err != nil {
+			return nil, err
+		}
+		return r.evm.TraceTx(replay, tx, tracer)
+	}
+	return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("transaction %s not found in block %d", ethHash(hash), receipt.BlockNumber)}
+}
+
+// traceCall трассирует пробную транзакцию над состоянием после блока tag; nonce берётся из этого состояния.
+func (r *EthRPC) traceCall(_ context.Context, params []json.RawMessage) (interface{}, error) {
+	var args ethCallArgs
+	var tag string
+	var cfg traceConfig
+	if err := parseParams(params, 1, &args, &tag, &cfg); err != nil {
+		return nil, err
+	}
+	if r.evm == nil {
+		return nil, errors.New("EVM недоступна")
+	}
+	tx, err := args.transaction()
+	if err != nil {
+		return nil, err
+	}
+	n, err := r.resolveBlockNumber(tag)
+	if err != nil {
+		return nil, err
+	}
+	replay, err := r.bc.ReplayAt(&n)
+	if err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+	tx.Symbol = core.GasSymbol
+	tx.Nonce = replay.State.GetNonce(types.Address(tx.Sender))
+	tracer, err := cfg.newTracer()
+	if err != nil {
+		return nil, err
+	}
+	return r.evm.TraceTx(replay, tx, tracer)
+}
+
+// traceBlockByNumber трассирует все транзакции блока по порядку.
+func (r *EthRPC) traceBlockByNumber(_ context.Context, params []json.RawMessage) (interface{}, error) {
+	var tag string
+	var cfg traceConfig
+	if err := parseParams(params, 1, &tag, &cfg); err != nil {
+		return nil, err
+	}
+	if r.evm == nil {
+		return nil, errors.New("EVM недоступна")
+	}
+	n, err := r.resolveBlockNumber(tag)
+	if err != nil {
+		return nil, err
+	}
+	replay, err := r.bc.ReplayBlock(n)
+	if err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+	type txTrace struct {
+		TxHash string          `json:"txHash"`
+		Result json.RawMessage `json:"result,omitempty"`
+		Error  string          `json:"error,omitempty"`
+	}
+	traces := make([]txTrace, 0, len(replay.Block.Transactions))
+	for _, tx := range replay.Block.Transactions {
+		tracer, err := cfg.newTracer()
+		if err != nil {
+			return nil, err
+		}
+		entry := txTrace{TxHash: ethHash(tx.Hash)}
+		if entry.Result, err = r.evm.TraceTx(replay, tx, tracer); err != nil {
+			entry.Error = err.Error()
+		}
+		traces = append(traces, entry)
+	}
+	return traces, nil
+}
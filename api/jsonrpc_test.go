@@ -0,0 +1,593 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"GND/core"
+	gndcrypto "GND/core/crypto"
+	"GND/types"
+	"GND/vm"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	rpcTestRecipient = "GN_rpc_recipient_wallet"
+	rpcTestContract  = "GNDct00112233445566778899aabbccddeeff"
+)
+
+var (
+	// rpcTestWallet подписывает транзакции блока 1: адрес выводится из ключа, иначе блок не пройдёт проверку подписей.
+	rpcTestWallet = newRPCTestWallet()
+	rpcTestSender = string(rpcTestWallet.Address)
+	// хеши перевода и вызова контракта блока 1 (заполняет newTestEthRPC)
+	rpcTransferHash, rpcCallHash string
+)
+
+func newRPCTestWallet() *core.Wallet {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		panic(err)
+	}
+	addr, err := core.AddressFromPublicKey(key.PubKey().SerializeCompressed())
+	if err != nil {
+		panic(err)
+	}
+	return &core.Wallet{PrivateKey: key, Address: core.Address(addr)}
+}
+
+// rpcLogRuntime: LOG1(0, 32, topic=0x01) после MSTORE(0, 7), затем RETURN(0, 32).
+var rpcLogRuntime = []byte{
+	0x60, 0x07, 0x60, 0x00, 0x52, // MSTORE(0, 7)
+	0x60, 0x01, 0x60, 0x20, 0x60, 0x00, 0xa1, // LOG1(0, 32, 1)
+	0x60, 0x20, 0x60, 0x00, 0xf3, // RETURN(0, 32)
+}
+
+// newTestEthRPC создаёт цепь с генезисом и блоком 1: перевод 100 GND и вызов контракта с событием.
+func newTestEthRPC(t *testing.T) (*EthRPC, *core.Block) {
+	t.Helper()
+	genesis := &core.Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: core.DefaultBlockGasLimit, Consensus: "poa", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := core.NewBlockchain(genesis, nil)
+	st := bc.State.(*core.State)
+	core.SetState(st)
+	t.Cleanup(func() { core.SetState(nil) })
+	if err := st.AddBalance(types.Address(rpcTestSender), core.GasSymbol, big.NewInt(10_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetContractCode(rpcTestContract, rpcLogRuntime); err != nil {
+		t.Fatal(err)
+	}
+	evm := vm.NewEVM(vm.EVMConfig{Blockchain: bc, State: st, GasLimit: 1_000_000})
+	bc.Executor = evm
+
+	transfer := &core.Transaction{Sender: types.Address(rpcTestSender), Recipient: rpcTestRecipient, Value: big.NewInt(100), GasLimit: core.TxGas, Symbol: core.GasSymbol}
+	call := &core.Transaction{Sender: types.Address(rpcTestSender), Recipient: rpcTestContract, Value: big.NewInt(0), Data: []byte{0x01}, Nonce: 1, GasLimit: 100_000, Symbol: core.GasSymbol}
+	for _, tx := range []*core.Transaction{transfer, call} {
+		if err := rpcTestWallet.SignTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rpcTransferHash, rpcCallHash = transfer.Hash, call.Hash
+	txs := []*core.Transaction{transfer, call}
+	block := &core.Block{Index: 1, PrevHash: genesis.Hash, Timestamp: time.Now(), Miner: "miner", GasLimit: core.DefaultBlockGasLimit, Consensus: "poa", Status: "finalized",
+		Transactions: txs, MerkleRoot: core.ComputeMerkleRoot(txs)}
+	if err := bc.ExecuteBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	return NewEthRPC(bc, core.NewMempool(), evm, 7), block
+}
+
+// rpcCall выполняет один запрос и возвращает result (ошибка JSON-RPC — провал теста).
+func rpcCall(t *testing.T, r *EthRPC, method string, params ...interface{}) json.RawMessage {
+	t.Helper()
+	resp := rpcRaw(t, r, method, params...)
+	if resp.Error != nil {
+		t.Fatalf("%s: ошибка %d %s", method, resp.Error.Code, resp.Error.Message)
+	}
+	return resp.Result
+}
+
+type rpcTestResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+func rpcRaw(t *testing.T, r *EthRPC, method string, params ...interface{}) rpcTestResponse {
+	t.Helper()
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	out, _ := json.Marshal(r.Handle(context.Background(), body))
+	var resp rpcTestResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("%s: неверный ответ %s", method, out)
+	}
+	return resp
+}
+
+func TestEthRPC_BatchAndNotifications(t *testing.T) {
+	r, _ := newTestEthRPC(t)
+	body := `[
+		{"jsonrpc":"2.0","id":1,"method":"eth_chainId"},
+		{"jsonrpc":"2.0","id":2,"method":"eth_blockNumber","params":[]},
+		{"jsonrpc":"2.0","id":3,"method":"eth_unknown"},
+		{"jsonrpc":"2.0","method":"eth_blockNumber"}
+	]`
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp []rpcTestResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("ожидался массив ответов: %s", w.Body.String())
+	}
+	if len(resp) != 3 {
+		t.Fatalf("ожидалось 3 ответа (уведомление без ответа), получено %d", len(resp))
+	}
+	if string(resp[0].Result) != `"0x7"` {
+		t.Errorf("eth_chainId: ожидалось 0x7, получено %s", resp[0].Result)
+	}
+	if string(resp[1].Result) != `"0x1"` {
+		t.Errorf("eth_blockNumber: ожидалось 0x1, получено %s", resp[1].Result)
+	}
+	if resp[2].Error == nil || resp[2].Error.Code != rpcMethodNotFound {
+		t.Errorf("неизвестный метод: ожидалась ошибка %d, получено %+v", rpcMethodNotFound, resp[2].Error)
+	}
+
+	if out := r.Handle(context.Background(), []byte(`[]`)); out == nil {
+		t.Error("пустой batch должен возвращать ошибку invalid request")
+	}
+	if out := r.Handle(context.Background(), []byte(`{bad json`)); out == nil {
+		t.Error("неверный JSON должен возвращать parse error")
+	}
+}
+
+func TestEthRPC_BalanceBlockAndReceipt(t *testing.T) {
+	r, block := newTestEthRPC(t)
+
+	if got := string(rpcCall(t, r, "eth_getBalance", rpcTestRecipient, "latest")); got != `"0x64"` {
+		t.Errorf("eth_getBalance: ожидалось 0x64, получено %s", got)
+	}
+	if got := string(rpcCall(t, r, "eth_getTransactionCount", rpcTestSender, "latest")); got != `"0x2"` {
+		t.Errorf("eth_getTransactionCount: ожидалось 0x2, получено %s", got)
+	}
+	if resp := rpcRaw(t, r, "eth_getBalance", rpcTestRecipient, "0x0"); resp.Error == nil {
+		t.Error("историческое состояние недоступно — ожидалась ошибка")
+	}
+
+	var b struct {
+		Number        string   `json:"number"`
+		Hash          string   `json:"hash"`
+		BaseFeePerGas string   `json:"baseFeePerGas"`
+		Transactions  []string `json:"transactions"`
+	}
+	if err := json.Unmarshal(rpcCall(t, r, "eth_getBlockByNumber", "latest", false), &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Number != "0x1" || b.Hash != "0x"+block.Hash || b.BaseFeePerGas != "0x1" || len(b.Transactions) != 2 || b.Transactions[0] != "0x"+rpcTransferHash {
+		t.Errorf("eth_getBlockByNumber: %+v", b)
+	}
+
+	var rc struct {
+		Status            string `json:"status"`
+		GasUsed           string `json:"gasUsed"`
+		CumulativeGasUsed string `json:"cumulativeGasUsed"`
+		BlockHash         string `json:"blockHash"`
+		To                string `json:"to"`
+	}
+	if err := json.Unmarshal(rpcCall(t, r, "eth_getTransactionReceipt", "0x"+rpcTransferHash), &rc); err != nil {
+		t.Fatal(err)
+	}
+	if rc.Status != "0x1" || rc.GasUsed != "0x5208" || rc.CumulativeGasUsed != "0x5208" || rc.BlockHash != "0x"+block.Hash {
+		t.Errorf("eth_getTransactionReceipt: %+v", rc)
+	}
+	if rc.To != vm.ToEVMAddress(rpcTestRecipient).Hex() {
+		t.Errorf("to: ожидалось %s, получено %s", vm.ToEVMAddress(rpcTestRecipient).Hex(), rc.To)
+	}
+	if got := string(rpcCall(t, r, "eth_getTransactionReceipt", "0xffff")); got != "null" {
+		t.Errorf("квитанция неизвестной транзакции: ожидалось null, получено %s", got)
+	}
+}
+
+func TestEthRPC_GasPriceFollowsBaseFeeAndTips(t *testing.T) {
+	r, _ := newTestEthRPC(t)
+	// транзакции блока 1 платили ровно base fee: чаевые 0, base fee следующего блока не ниже минимальной
+	if got := string(rpcCall(t, r, "eth_maxPriorityFeePerGas")); got != `"0x0"` {
+		t.Errorf("eth_maxPriorityFeePerGas: ожидалось 0x0, получено %s", got)
+	}
+	if got := string(rpcCall(t, r, "eth_gasPrice")); got != `"0x1"` {
+		t.Errorf("eth_gasPrice: ожидалось 0x1, получено %s", got)
+	}
+}
+
+func TestEthRPC_CallEstimateGasAndLogs(t *testing.T) {
+	r, _ := newTestEthRPC(t)
+	contract := vm.ToEVMAddress(rpcTestContract).Hex()
+
+	var ret string
+	if err := json.Unmarshal(rpcCall(t, r, "eth_call", map[string]string{"to": contract, "data": "0x01"}, "latest"), &ret); err != nil {
+		t.Fatal(err)
+	}
+	if ret != "0x0000000000000000000000000000000000000000000000000000000000000007" {
+		t.Errorf("eth_call: получено %s", ret)
+	}
+	if got := string(rpcCall(t, r, "eth_estimateGas", map[string]string{"from": rpcTestSender, "to": rpcTestRecipient})); got != `"0x5208"` {
+		t.Errorf("eth_estimateGas для перевода: ожидалось 0x5208, получено %s", got)
+	}
+	var est string
+	if err := json.Unmarshal(rpcCall(t, r, "eth_estimateGas", map[string]string{"to": contract, "data": "0x01"}), &est); err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := new(big.Int).SetString(est[2:], 16); !ok || n.Uint64() <= core.IntrinsicGas([]byte{0x01}, false) {
+		t.Errorf("eth_estimateGas для вызова должен превышать базовый газ, получено %s", est)
+	}
+
+	var logs []struct {
+		Address         string   `json:"address"`
+		Topics          []string `json:"topics"`
+		TransactionHash string   `json:"transactionHash"`
+		LogIndex        string   `json:"logIndex"`
+	}
+	topic := "0x0000000000000000000000000000000000000000000000000000000000000001"
+	filter := map[string]interface{}{"fromBlock": "0x0", "toBlock": "latest", "address": contract, "topics": []interface{}{topic}}
+	if err := json.Unmarshal(rpcCall(t, r, "eth_getLogs", filter), &logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || logs[0].TransactionHash != "0x"+rpcCallHash || logs[0].Address != contract || logs[0].LogIndex != "0x0" {
+		t.Fatalf("eth_getLogs: %+v", logs)
+	}
+	filter["topics"] = []interface{}{"0x" + string(bytes.Repeat([]byte("0"), 63)) + "2"}
+	if err := json.Unmarshal(rpcCall(t, r, "eth_getLogs", filter), &logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 0 {
+		t.Errorf("фильтр по другой теме не должен находить события, получено %d", len(logs))
+	}
+}
+
+// TestSimulateTransaction — POST /transaction/simulate: вызов контракта и перевод над копией состояния без применения.
+func TestSimulateTransaction(t *testing.T) {
+	r, _ := newTestEthRPC(t)
+	s := NewServer(nil, r.bc, core.NewMempool(), nil, nil)
+	s.evm = r.evm
+	simulate := func(body string) (int, map[string]interface{}) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/transaction/simulate", bytes.NewBufferString(body)))
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v (%s)", err, w.Body.String())
+		}
+		return w.Code, resp.Data
+	}
+
+	code, data := simulate(`{"from":"` + rpcTestSender + `","to":"` + rpcTestContract + `","data":"0x01"}`)
+	if code != http.StatusOK || data["status"] != core.ReceiptStatusSuccess {
+		t.Fatalf("вызов контракта: статус %d, ответ %v", code, data)
+	}
+	if est := uint64(data["gas_estimate"].(float64)); est <= core.IntrinsicGas([]byte{0x01}, false) {
+		t.Errorf("оценка газа вызова должна превышать базовый газ, получено %d", est)
+	}
+	if data["return_data"] != "0x"+hex.EncodeToString(append(make([]byte, 31), 7)) {
+		t.Errorf("return_data: %v", data["return_data"])
+	}
+	if events := data["events"].([]interface{}); len(events) != 1 {
+		t.Errorf("ожидалось одно событие LOG1, получено %v", events)
+	}
+
+	// получатель перевода блока 1 переводит 100 GND: успех после блока 1, баланс цепи не меняется
+	transfer := `{"from":"` + rpcTestRecipient + `","to":"` + rpcTestSender + `","value":100,"block":1}`
+	code, data = simulate(transfer)
+	if code != http.StatusOK || data["status"] != core.ReceiptStatusSuccess || data["gas_estimate"].(float64) != float64(core.TxGas) {
+		t.Fatalf("перевод: статус %d, ответ %v", code, data)
+	}
+	if diff := data["state_diff"].([]interface{}); len(diff) != 2 {
+		t.Errorf("перевод: ожидались два изменения баланса, получено %v", diff)
+	}
+	if got := r.bc.State.GetBalance(rpcTestRecipient, core.GasSymbol); got.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("пробное исполнение не должно менять состояние, баланс %s", got)
+	}
+	code, data = simulate(`{"from":"` + rpcTestRecipient + `","to":"` + rpcTestSender + `","value":101}`)
+	if code != http.StatusOK || data["status"] != core.ReceiptStatusFailed || data["error"] == nil {
+		t.Errorf("перевод сверх баланса: статус %d, ответ %v", code, data)
+	}
+
+	// до блока 1 у получателя нет баланса; состояния после ещё не созданного блока нет
+	code, data = simulate(`{"from":"` + rpcTestRecipient + `","to":"` + rpcTestSender + `","value":100,"block":0}`)
+	if code != http.StatusOK || data["status"] != core.ReceiptStatusFailed {
+		t.Errorf("перевод над состоянием генезиса: статус %d, ответ %v", code, data)
+	}
+	if code, _ = simulate(`{"from":"` + rpcTestSender + `","to":"` + rpcTestRecipient + `","block":5}`); code != http.StatusNotFound {
+		t.Errorf("недоступное состояние: статус %d, ожидался 404", code)
+	}
+}
+
+// TestDeployContract_ExecutesInBlock — POST /api/v1/contract ставит подписанную транзакцию деплоя в мемпул: код, storage,
+// nonce и комиссия конструктора появляются только в блоке и входят в его state_root.
+func TestDeployContract_ExecutesInBlock(t *testing.T) {
+	r, _ := newTestEthRPC(t)
+	s := NewServer(nil, r.bc, core.NewMempool(), nil, nil)
+	st := r.bc.State.(*core.State)
+	deploy := func(body map[string]interface{}) (int, map[string]interface{}) {
+		t.Helper()
+		raw, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/contract", bytes.NewBuffer(raw)))
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v (%s)", err, w.Body.String())
+		}
+		return w.Code, resp.Data
+	}
+
+	// конструктор: SSTORE(0, 42) и RETURN runtime-кода rpcLogRuntime
+	n := byte(len(rpcLogRuntime))
+	initCode := append([]byte{0x60, 0x2a, 0x60, 0x00, 0x55, 0x60, n, 0x60, 17, 0x60, 0x00, 0x39, 0x60, n, 0x60, 0x00, 0xf3}, rpcLogRuntime...)
+	tx, err := r.bc.NewDeployTransaction(&core.ContractParams{From: rpcTestSender, Bytecode: hex.EncodeToString(initCode), GasLimit: 200_000, GasPrice: big.NewInt(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rpcTestWallet.SignTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	body := map[string]interface{}{"from": rpcTestSender, "bytecode": hex.EncodeToString(initCode), "name": "Deployed",
+		"gas_limit": 200_000, "gas_price": 10, "signature": hex.EncodeToString(tx.Signature), "key_type": "secp256k1"}
+
+	root, balance := st.RootHash(), st.GetBalance(types.Address(rpcTestSender), core.GasSymbol)
+	code, data := deploy(body)
+	if code != http.StatusOK || data["address"] != tx.Recipient.String() || data["hash"] != tx.Hash {
+		t.Fatalf("деплой: статус %d, ответ %v", code, data)
+	}
+	if got, _ := st.GetContractCode(tx.Recipient.String()); len(got) != 0 || st.RootHash() != root ||
+		st.GetBalance(types.Address(rpcTestSender), core.GasSymbol).Cmp(balance) != 0 || !r.bc.Mempool.Exists(tx.Hash) {
+		t.Fatal("деплой до блока не должен менять состояние: транзакция ждёт блока в мемпуле")
+	}
+
+	if err := r.bc.ProduceNextBlock(r.bc.Mempool, "miner", 10); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := st.GetContractCode(tx.Recipient.String()); !bytes.Equal(got, rpcLogRuntime) {
+		t.Errorf("runtime-код после блока: %x", got)
+	}
+	if slot := st.GetStorageSlot(tx.Recipient.String(), make([]byte, 32)); new(big.Int).SetBytes(slot).Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("слот 0 после блока: %x", slot)
+	}
+	if st.GetNonce(types.Address(rpcTestSender)) != tx.Nonce+1 || st.GetBalance(types.Address(rpcTestSender), core.GasSymbol).Cmp(balance) >= 0 {
+		t.Error("деплой в блоке должен увеличить nonce и списать комиссию")
+	}
+	if receipt, err := r.bc.GetReceipt(context.Background(), tx.Hash); err != nil || receipt.ContractAddress != tx.Recipient.String() || !receipt.Succeeded() {
+		t.Errorf("квитанция деплоя: %+v, %v", receipt, err)
+	}
+
+	// конструктор с revert не отправляется
+	reverting, err := r.bc.NewDeployTransaction(&core.ContractParams{From: rpcTestSender, Bytecode: "60006000fd", GasLimit: 200_000, GasPrice: big.NewInt(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rpcTestWallet.SignTransaction(reverting); err != nil {
+		t.Fatal(err)
+	}
+	body["bytecode"], body["signature"] = "60006000fd", hex.EncodeToString(reverting.Signature)
+	if code, _ := deploy(body); code != http.StatusBadRequest || r.bc.Mempool.Exists(reverting.Hash) {
+		t.Errorf("деплой с revert конструктора: статус %d, ожидался 400", code)
+	}
+}
+
+// TestGetLogs — GET /api/v1/logs: событие LOG1 вызова контракта в блоке 1 с фильтрами по адресу, теме и диапазону.
+func TestGetLogs(t *testing.T) {
+	r, _ := newTestEthRPC(t)
+	s := NewServer(nil, r.bc, core.NewMempool(), nil, nil)
+	getLogs := func(query string) (int, []core.ContractLog) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/logs?"+query, nil))
+		var resp struct {
+			Data []core.ContractLog `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v (%s)", err, w.Body.String())
+		}
+		return w.Code, resp.Data
+	}
+
+	topic := "0x" + strings.Repeat("0", 63) + "1"
+	code, logs := getLogs("address=" + rpcTestContract + "&topic0=" + topic + "&fromBlock=0&toBlock=1")
+	if code != http.StatusOK || len(logs) != 1 {
+		t.Fatalf("ожидалось одно событие, статус %d: %+v", code, logs)
+	}
+	if l := logs[0]; l.TxHash != rpcCallHash || l.BlockNumber != 1 || l.LogIndex != 0 || l.TxIndex != 1 || l.Address != rpcTestContract {
+		t.Errorf("событие: %+v", l)
+	}
+	if _, logs = getLogs("address=" + vm.ToEVMAddress(rpcTestContract).Hex()); len(logs) != 1 {
+		t.Errorf("адрес EVM: ожидалось одно событие, получено %d", len(logs))
+	}
+	if _, logs = getLogs("topic0=0x" + strings.Repeat("0", 63) + "2"); len(logs) != 0 {
+		t.Errorf("фильтр по другой теме не должен находить события, получено %d", len(logs))
+	}
+	if _, logs = getLogs("fromBlock=0&toBlock=0"); len(logs) != 0 {
+		t.Errorf("в генезисе событий нет, получено %d", len(logs))
+	}
+	if code, _ = getLogs("topic0=0x01"); code != http.StatusBadRequest {
+		t.Errorf("неверная тема: статус %d, ожидался 400", code)
+	}
+	if code, _ = getLogs("fromBlock=2&toBlock=1"); code != http.StatusBadRequest {
+		t.Errorf("fromBlock > toBlock: статус %d, ожидался 400", code)
+	}
+}
+
+// TestEthRPC_DebugTrace — debug_trace*: повторное исполнение транзакций блока 1 и пробного вызова над копией состояния.
+func TestEthRPC_DebugTrace(t *testing.T) {
+	r, _ := newTestEthRPC(t)
+	contract := vm.ToEVMAddress(rpcTestContract)
+
+	var frame vm.CallFrame
+	if err := json.Unmarshal(rpcCall(t, r, "debug_traceTransaction", "0x"+rpcCallHash, map[string]interface{}{"tracer": "callTracer", "tracerConfig": map[string]bool{"withLog": true}}), &frame); err != nil {
+		t.Fatal(err)
+	}
+	receipt, _ := r.bc.GetReceipt(context.Background(), rpcCallHash)
+	if frame.Type != "CALL" || frame.To != contract || len(frame.Logs) != 1 || uint64(frame.GasUsed) != receipt.GasUsed {
+		t.Errorf("callTracer: %+v", frame)
+	}
+	if new(big.Int).SetBytes(frame.Output).Int64() != 7 {
+		t.Errorf("callTracer: output %x", frame.Output)
+	}
+
+	var logger struct {
+		Failed      bool           `json:"failed"`
+		ReturnValue string         `json:"returnValue"`
+		StructLogs  []vm.StructLog `json:"structLogs"`
+	}
+	if err := json.Unmarshal(rpcCall(t, r, "debug_traceTransaction", "0x"+rpcCallHash), &logger); err != nil {
+		t.Fatal(err)
+	}
+	if logger.Failed || len(logger.StructLogs) == 0 || logger.StructLogs[len(logger.StructLogs)-1].Op != "RETURN" {
+		t.Errorf("structLogger: %+v", logger)
+	}
+
+	var diff struct {
+		Post map[string]struct {
+			Balance string `json:"balance"`
+		} `json:"post"`
+	}
+	if err := json.Unmarshal(rpcCall(t, r, "debug_traceTransaction", "0x"+rpcTransferHash, map[string]interface{}{"tracer": "prestateTracer", "tracerConfig": map[string]bool{"diffMode": true}}), &diff); err != nil {
+		t.Fatal(err)
+	}
+	recipient := strings.ToLower(vm.ToEVMAddress(rpcTestRecipient).Hex())
+	if diff.Post[recipient].Balance != "0x64" {
+		t.Errorf("prestateTracer: баланс получателя после перевода %+v", diff.Post)
+	}
+
+	var traces []struct {
+		TxHash string          `json:"txHash"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(rpcCall(t, r, "debug_traceBlockByNumber", "0x1", map[string]string{"tracer": "callTracer"}), &traces); err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 2 || traces[0].TxHash != "0x"+rpcTransferHash || traces[1].TxHash != "0x"+rpcCallHash || len(traces[1].Result) == 0 {
+		t.Errorf("debug_traceBlockByNumber: %+v", traces)
+	}
+
+	call := map[string]string{"from": rpcTestSender, "to": contract.Hex(), "data": "0x01"}
+	if err := json.Unmarshal(rpcCall(t, r, "debug_traceCall", call, "latest", map[string]string{"tracer": "callTracer"}), &frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Error != "" || new(big.Int).SetBytes(frame.Output).Int64() != 7 {
+		t.Errorf("debug_traceCall: %+v", frame)
+	}
+	if resp := rpcRaw(t, r, "debug_traceTransaction", "0x"+rpcCallHash, map[string]string{"tracer": "unknownTracer"}); resp.Error == nil || resp.Error.Code != rpcInvalidParams {
+		t.Errorf("неизвестный трассировщик: %+v", resp.Error)
+	}
+}
+
+func TestEthRPC_SendRawTransactionRejectsOtherChain(t *testing.T) {
+	r, _ := newTestEthRPC(t)
+	r.bc.ChainID, r.bc.SubnetID = 7, "subnet-a"
+	key, err := gndcrypto.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := types.Address(gndcrypto.PublicKeyToAddressP256(&key.PublicKey))
+	if err := r.bc.State.AddBalance(sender, core.GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	raw := func(chainID int64, subnetID string) string {
+		tx := &core.Transaction{ChainID: chainID, SubnetID: subnetID, Sender: sender, Recipient: rpcTestRecipient, Value: big.NewInt(5),
+			GasLimit: core.TxGas, GasPrice: big.NewInt(1), Symbol: core.GasSymbol,
+			SenderPublicKeyHex: hex.EncodeToString(gndcrypto.PublicKeyUncompressedBytes(&key.PublicKey))}
+		if err := tx.Sign(gndcrypto.PrivateKeyToHex(key)); err != nil {
+			t.Fatal(err)
+		}
+		data, err := core.EncodeRawTransaction(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "0x" + hex.EncodeToString(data)
+	}
+
+	for _, other := range []string{raw(8, "subnet-a"), raw(7, "subnet-b")} {
+		if resp := rpcRaw(t, r, "eth_sendRawTransaction", other); resp.Error == nil || resp.Error.Code != ErrCodeWrongChain {
+			t.Fatalf("транзакция другой сети: ожидалась ошибка %d, получено %+v", ErrCodeWrongChain, resp.Error)
+		}
+	}
+	var hash string
+	if err := json.Unmarshal(rpcCall(t, r, "eth_sendRawTransaction", raw(7, "subnet-a")), &hash); err != nil {
+		t.Fatal(err)
+	}
+	// перевод исполняется только в блоке
+	if got := r.bc.State.GetBalance(rpcTestRecipient, core.GasSymbol); got.Cmp(big.NewInt(100)) != 0 || !r.bc.Mempool.Exists(strings.TrimPrefix(hash, "0x")) {
+		t.Fatalf("перевод должен ждать блока в мемпуле: баланс получателя %s", got)
+	}
+	if err := r.bc.ProduceNextBlock(r.bc.Mempool, "miner", 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.bc.State.GetBalance(rpcTestRecipient, core.GasSymbol); got.Cmp(big.NewInt(105)) != 0 {
+		t.Errorf("перевод своей сети не применён: баланс получателя %s", got)
+	}
+}
+
+func TestEthRPC_SendRawTransactionSignedByGeth(t *testing.T) {
+	r, _ := newTestEthRPC(t)
+	r.bc.ChainID = 7
+	key, err := gethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := vm.ToEVMAddress(rpcTestRecipient)
+	etx, err := gethtypes.SignNewTx(key, gethtypes.LatestSignerForChainID(big.NewInt(7)), &gethtypes.DynamicFeeTx{
+		ChainID: big.NewInt(7), Nonce: 0, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), Gas: core.TxGas, To: &to, Value: big.NewInt(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := etx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := core.DecodeEthereumTransaction(raw, vm.FromEVMAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.bc.State.AddBalance(decoded.Sender, core.GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+
+	var hash string
+	if err := json.Unmarshal(rpcCall(t, r, "eth_sendRawTransaction", hexutil.Encode(raw)), &hash); err != nil {
+		t.Fatal(err)
+	}
+	if hash != etx.Hash().Hex() || !r.bc.Mempool.Exists(strings.TrimPrefix(hash, "0x")) {
+		t.Fatalf("хеш %s, ожидался хеш go-ethereum %s в мемпуле", hash, etx.Hash().Hex())
+	}
+	if got := vm.FromEVMAddress(gethcrypto.PubkeyToAddress(key.PublicKey)); got != decoded.Sender.String() {
+		t.Errorf("адрес кошелька Ethereum соответствует %s, ожидался %s", got, decoded.Sender)
+	}
+	if err := r.bc.ProduceNextBlock(r.bc.Mempool, "miner", 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.bc.State.GetBalance(rpcTestRecipient, core.GasSymbol); got.Cmp(big.NewInt(105)) != 0 {
+		t.Errorf("перевод из кошелька Ethereum не применён: баланс получателя %s", got)
+	}
+}
@@ -6,6 +6,7 @@ package api
 import (
 	"GND/audit"
 	"GND/core"
+	gndcrypto "GND/core/crypto"
 	"GND/tokens/deployer"
 	"GND/tokens/interfaces"
 	"GND/tokens/registry"
@@ -18,6 +19,7 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -26,6 +28,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"math/big"
@@ -50,6 +53,18 @@ type APIResponse struct {
 	Code    int         `json:"code,omitempty"`
 }
 
+// ErrCodeWrongChain — код ошибки транзакции, подписанной для другой сети (core.ErrWrongChain): chain_id или subnet_id
+// не совпадают с нодой. Возвращается в Code ответа REST, в code ответа RPC /tx/send и в коде ошибки JSON-RPC.
+const ErrCodeWrongChain = 1010
+
+// txErrorCode — код ошибки отклонённой транзакции: ErrCodeWrongChain для транзакции другой сети, иначе 400.
+func txErrorCode(err error) int {
+	if errors.Is(err, core.ErrWrongChain) {
+		return ErrCodeWrongChain
+	}
+	return http.StatusBadRequest
+}
+
 func sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -97,6 +112,7 @@ type Server struct {
 	cfg         *core.Config
 	evm         *vm.EVM     // для вызова контрактов (view) и отправки транзакций (write)
 	adminSigner AdminSigner // опционально: для подписи транзакций от имени кошелька при запросе из админки
+	signMu      sync.Mutex  // выбор nonce и отправка транзакций, подписанных нодой (sign-and-send)
 }
 
 // NewServer создает новый экземпляр сервера. deployer может быть nil — тогда POST /token/deploy недоступен. cfg опционально — для health (chain_id, subnet_id).
@@ -154,6 +170,9 @@ func (s *Server) GetFeeMetrics(c *gin.Context) {
 		MinFee          *big.Int
 		MaxFee          *big.Int
 		TotalFee        *big.Int
+		TotalGasUsed    uint64
+		BlockGasUsed    uint64
+		BlockGasLimit   uint64
 		FeeDistribution map[string]uint64
 		TypeMetrics     map[string]*core.TransactionTypeMetrics
 	}{
@@ -161,6 +180,9 @@ func (s *Server) GetFeeMetrics(c *gin.Context) {
 		MinFee:          metrics.TransactionMetrics.MinFee,
 		MaxFee:          metrics.TransactionMetrics.MaxFee,
 		TotalFee:        metrics.TransactionMetrics.TotalFee,
+		TotalGasUsed:    metrics.TransactionMetrics.TotalGasUsed,
+		BlockGasUsed:    metrics.BlockMetrics.GasUsed,
+		BlockGasLimit:   metrics.BlockMetrics.GasLimit,
 		FeeDistribution: metrics.TransactionMetrics.FeeDistribution,
 		TypeMetrics:     metrics.TransactionMetrics.TypeMetrics,
 	}
@@ -170,6 +192,16 @@ func (s *Server) GetFeeMetrics(c *gin.Context) {
 	})
 }
 
+// GetFees — GET /api/v1/fees: base fee следующего блока, подсказки slow/standard/fast (max_priority_fee_per_gas,
+// max_fee_per_gas) по чаевым последних блоков и заполненность последнего блока.
+func (s *Server) GetFees(c *gin.Context) {
+	if s.core == nil {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{Success: false, Error: "Блокчейн не инициализирован", Code: http.StatusServiceUnavailable})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: s.core.SuggestFees()})
+}
+
 // GetAlerts возвращает текущие алерты
 func (s *Server) GetAlerts(c *gin.Context) {
 	metrics := core.GetMetrics()
@@ -212,7 +244,7 @@ func (s *Server) SetAlertThresholds(c *gin.Context) {
 	})
 }
 
-// HealthCheck возвращает статус сервера и идентификаторы сети (для мостов и подсетей)
+// HealthCheck возвращает статус сервера, идентификаторы сети (для мостов и подсетей) и прогресс синхронизации с пирами
 func (s *Server) HealthCheck(c *gin.Context) {
 	data := gin.H{
 		"status":    "ok",
@@ -226,6 +258,10 @@ func (s *Server) HealthCheck(c *gin.Context) {
 			data["subnet_id"] = s.cfg.SubnetID
 		}
 	}
+	// Прогресс загрузки цепи от пиров (P2P-нода)
+	if s.core != nil && s.core.Sync != nil {
+		data["sync"] = s.core.Sync.SyncStatus()
+	}
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
 		Data:    data,
@@ -431,11 +467,9 @@ func decodeSignatureHex(s string) []byte {
 	if len(s) >= 2 && (s[:2] == "0x" || s[:2] == "0X") {
 		s = s[2:]
 	}
-	if len(s) == 128 {
-		b, err := hex.DecodeString(s)
-		if err == nil && len(b) == 64 {
-			return b
-		}
+	// r‖s P-256 (64 байта), восстанавливаемая secp256k1 (65 байт) или DER
+	if b, err := hex.DecodeString(s); err == nil && len(b) > 0 {
+		return b
 	}
 	return []byte(s)
 }
@@ -447,11 +481,18 @@ func (s *Server) SendTransaction(c *gin.Context) {
 		To              string   `json:"to"`
 		Value           *big.Int `json:"value"`
 		Fee             *big.Int `json:"fee"`
+		GasLimit        uint64   `json:"gas_limit"`
 		Nonce           uint64   `json:"nonce"`
 		Type            string   `json:"type"`
 		Data            string   `json:"data"`
 		Signature       string   `json:"signature"`
-		SenderPublicKey string   `json:"sender_public_key"` // hex публичного ключа P-256 для проверки подписи
+		SenderPublicKey string   `json:"sender_public_key"` // hex публичного ключа отправителя (для secp256k1 с восстанавливаемой подписью не нужен)
+		KeyType         string   `json:"key_type"`          // p256 или secp256k1 (по умолчанию — по формату адреса from)
+		ChainID         *int64   `json:"chain_id"`          // сеть, для которой подписана транзакция (по умолчанию — сеть ноды)
+		SubnetID        *string  `json:"subnet_id"`
+
+		MaxFeePerGas         *big.Int `json:"max_fee_per_gas"`          // транзакция рынка комиссий: вместо fee
+		MaxPriorityFeePerGas *big.Int `json:"max_priority_fee_per_gas"` // чаевые предлагающему блок (по умолчанию 0)
 	}
 	if err := c.ShouldBindJSON(&txData); err != nil {
 		c.JSON(http.StatusBadRequest, APIResponse{
@@ -491,7 +532,17 @@ func (s *Server) SendTransaction(c *gin.Context) {
 		fee = big.NewInt(0)
 	}
 
+	keyType, err := gndcrypto.ParseKeyType(txData.KeyType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: err.Error(), Code: http.StatusBadRequest})
+		return
+	}
 	sigBytes := decodeSignatureHex(txData.Signature)
+	// Лимит газа по умолчанию — базовая стоимость транзакции (21000 для простого перевода)
+	gasLimit := txData.GasLimit
+	if gasLimit == 0 {
+		gasLimit = core.IntrinsicGas([]byte(txData.Data), false)
+	}
 
 	tx := &core.Transaction{
 		Sender:             fromAddr,
@@ -500,13 +551,28 @@ func (s *Server) SendTransaction(c *gin.Context) {
 		Nonce:              int64(txData.Nonce),
 		Data:               []byte(txData.Data),
 		Signature:          sigBytes,
-		GasLimit:           21000, // Стандартный лимит газа для простой транзакции
+		GasLimit:           gasLimit,
 		GasPrice:           fee,
 		Symbol:             "GND",
 		IsVerified:         false, // пользовательские транзакции требуют проверки подписи
 		SenderPublicKeyHex: strings.TrimSpace(txData.SenderPublicKey),
+		KeyType:            keyType,
 		Timestamp:          core.BlockchainNow(),
 		Status:             "pending",
+		ChainID:            s.core.ChainID,
+		SubnetID:           s.core.SubnetID,
+	}
+	if txData.ChainID != nil {
+		tx.ChainID = *txData.ChainID
+	}
+	if txData.SubnetID != nil {
+		tx.SubnetID = *txData.SubnetID
+	}
+	setDynamicFee(tx, txData.MaxFeePerGas, txData.MaxPriorityFeePerGas)
+	// Из клиентских типов принимаются только транзакции стейкинга; служебные типы (genesis и др.) не передаются
+	switch core.TxType(txData.Type) {
+	case core.TxTypeStake, core.TxTypeUnstake, core.TxTypeValidator:
+		tx.Type = txData.Type
 	}
 	tx.Hash = tx.CalculateHash()
 
@@ -515,7 +581,7 @@ func (s *Server) SendTransaction(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, APIResponse{
 			Success: false,
 			Error:   "Ошибка отправки транзакции: " + err.Error(),
-			Code:    http.StatusBadRequest,
+			Code:    txErrorCode(err),
 		})
 		return
 	}
@@ -525,6 +591,25 @@ func (s *Server) SendTransaction(c *gin.Context) {
 	})
 }
 
+// setDynamicFee делает tx транзакцией рынка комиссий, если задан maxFee: цена газа — min(maxFee, base fee + tip),
+// gas_price не используется.
+func setDynamicFee(tx *core.Transaction, maxFee, tip *big.Int) {
+	if maxFee == nil {
+		return
+	}
+	tx.GasPrice = nil
+	tx.MaxFeePerGas = maxFee
+	tx.MaxPriorityFeePerGas = bigOrZero(tip)
+}
+
+// bigOrZero возвращает v или 0 для nil.
+func bigOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return big.NewInt(0)
+	}
+	return v
+}
+
 // transactionResponse формирует объект для JSON-ответа: contract_id как null или число (не sql.NullInt64).
 func transactionResponse(tx *core.Transaction) gin.H {
 	var contractID interface{}
@@ -562,12 +647,17 @@ func transactionResponse(tx *core.Transaction) gin.H {
 	data := gin.H{
 		"id": tx.ID, "sender": tx.Sender.String(), "recipient": tx.Recipient.String(),
 		"value": value, "data": dataHex, "nonce": tx.Nonce,
-		"gas_limit": tx.GasLimit, "gas_price": gasPrice,
+		"gas_limit": tx.GasLimit, "gas_price": gasPrice, "gas_used": tx.GasUsed,
 		"signature": sigHex, "hash": tx.Hash, "fee": fee,
 		"type": tx.Type, "status": tx.Status, "timestamp": tx.Timestamp,
 		"block_id": tx.BlockID, "contract_id": contractID, "payload": payloadHex,
 		"symbol": tx.Symbol, "is_verified": tx.IsVerified,
 	}
+	if tx.IsDynamicFee() {
+		data["gas_price"] = tx.MaxFeePerGas.Int64()
+		data["max_fee_per_gas"] = tx.MaxFeePerGas.String()
+		data["max_priority_fee_per_gas"] = bigOrZero(tx.MaxPriorityFeePerGas).String()
+	}
 	return data
 }
 
@@ -624,6 +714,113 @@ func (s *Server) GetTransaction(c *gin.Context) {
 	})
 }
 
+// GetTransactionReceipt возвращает квитанцию транзакции: статус, газ, события контрактов, адрес созданного контракта, причину ошибки.
+func (s *Server) GetTransactionReceipt(c *gin.Context) {
+	hash := strings.TrimSpace(c.Param("hash"))
+	if hash == "" {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Укажите хеш транзакции", Code: http.StatusBadRequest})
+		return
+	}
+	receipt, err := s.core.GetReceipt(c.Request.Context(), hash)
+	if err != nil || receipt == nil {
+		msg := "Квитанция не найдена"
+		if err != nil {
+			msg = "Квитанция не найдена: " + err.Error()
+		}
+		c.JSON(http.StatusNotFound, APIResponse{Success: false, Error: msg, Code: http.StatusNotFound})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: receipt})
+}
+
+// GetLogs — GET /api/v1/logs?address=&topic0=&topic1=&topic2=&topic3=&fromBlock=&toBlock=&limit=: события контрактов
+// в порядке блоков и log_index, расшифрованные по ABI контракта. address — GNDct… или 0x…; темы — 32 байта в hex с 0x;
+// номера блоков — десятичные или hex с 0x, по умолчанию toBlock — последний блок, fromBlock — начало допустимого диапазона.
+func (s *Server) GetLogs(c *gin.Context) {
+	if s.core == nil {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{Success: false, Error: "Блокчейн не инициализирован", Code: http.StatusServiceUnavailable})
+		return
+	}
+	filter, err := s.logFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: err.Error(), Code: http.StatusBadRequest})
+		return
+	}
+	logs, err := s.core.Logs(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: err.Error(), Code: http.StatusBadRequest})
+		return
+	}
+	if logs == nil {
+		logs = []*core.ContractLog{}
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: logs})
+}
+
+// logFilter разбирает параметры запроса GET /logs.
+func (s *Server) logFilter(c *gin.Context) (*core.LogFilter, error) {
+	filter := &core.LogFilter{}
+	if addr := strings.TrimSpace(c.Query("address")); addr != "" {
+		if strings.HasPrefix(addr, "0x") || strings.HasPrefix(addr, "0X") {
+			if len(addr) != 42 {
+				return nil, fmt.Errorf("неверный адрес %q", addr)
+			}
+			addr = vm.FromEVMAddress(vm.ToEVMAddress(addr))
+		}
+		filter.Address = addr
+	}
+	for i := range filter.Topics {
+		topic := strings.ToLower(strings.TrimSpace(c.Query(fmt.Sprintf("topic%d", i))))
+		if topic == "" {
+			continue
+		}
+		if b, err := hex.DecodeString(strings.TrimPrefix(topic, "0x")); err != nil || len(b) != 32 || !strings.HasPrefix(topic, "0x") {
+			return nil, fmt.Errorf("topic%d: ожидается 32 байта в hex с 0x", i)
+		}
+		filter.Topics[i] = topic
+	}
+	parseNumber := func(name string) (uint64, bool, error) {
+		v := strings.TrimSpace(c.Query(name))
+		if v == "" {
+			return 0, false, nil
+		}
+		base := 10
+		if strings.HasPrefix(v, "0x") {
+			v, base = v[2:], 16
+		}
+		n, err := strconv.ParseUint(v, base, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("%s: неверный номер блока", name)
+		}
+		return n, true, nil
+	}
+	to, ok, err := parseNumber("toBlock")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		head, err := s.core.LatestBlock()
+		if err != nil {
+			return nil, err
+		}
+		to = head.Index
+	}
+	from, ok, err := parseNumber("fromBlock")
+	if err != nil {
+		return nil, err
+	}
+	if !ok && to >= core.MaxLogsBlockRange {
+		from = to - core.MaxLogsBlockRange + 1
+	}
+	filter.FromBlock, filter.ToBlock = from, to
+	if l := c.Query("limit"); l != "" {
+		if filter.Limit, err = strconv.Atoi(l); err != nil || filter.Limit < 0 {
+			return nil, errors.New("limit: ожидается неотрицательное число")
+		}
+	}
+	return filter, nil
+}
+
 // GetLatestBlock возвращает последний блок (с полем Transactions — список транзакций)
 func (s *Server) GetLatestBlock(c *gin.Context) {
 	block, err := s.core.GetLatestBlock()
@@ -653,10 +850,17 @@ func (s *Server) GetLatestBlock(c *gin.Context) {
 	})
 }
 
-// GetBlockByNumber возвращает блок по номеру
+// GetBlockByNumber возвращает блок по номеру или тегу finalized / justified (safe)
 func (s *Server) GetBlockByNumber(c *gin.Context) {
 	numberStr := c.Param("number")
 	number, err := strconv.ParseUint(numberStr, 10, 64)
+	// Теги: finalized — последний финализированный блок, justified (safe) — последний блок с >2/3 prevote
+	switch numberStr {
+	case "finalized":
+		number, err = s.core.FinalizedBlock().Index, nil
+	case "justified", "safe":
+		number, err = s.core.JustifiedBlock().Index, nil
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, APIResponse{
 			Success: false,
@@ -699,29 +903,33 @@ func (s *Server) GetBlockByNumber(c *gin.Context) {
 	})
 }
 
-// DeployContract деплоит новый контракт
+// DeployContract деплоит новый контракт транзакцией: формирует транзакцию деплоя (core.NewDeployTransaction) и ставит её
+// в мемпул; код, storage и комиссия применяются в блоке. Транзакцию подписывает клиент (signature, sender_public_key, key_type);
+// без подписи, с X-Admin-Token и кошельком from под управлением ноды — подписывает нода.
 func (s *Server) DeployContract(c *gin.Context) {
 	var paramsData struct {
-		From        string                 `json:"from"`
-		Bytecode    string                 `json:"bytecode"`
-		ABI         json.RawMessage        `json:"abi"`
-		Name        string                 `json:"name"`
-		Symbol      string                 `json:"symbol"`
-		Standard    string                 `json:"standard"`
-		Owner       string                 `json:"owner"`
-		Compiler    string                 `json:"compiler"`
-		Version     string                 `json:"version"`
-		License     string                 `json:"license"`
-		Params      map[string]interface{} `json:"params"`
-		Description string                 `json:"description"`
-		MetadataCID string                 `json:"metadata_cid"`
-		Metadata    json.RawMessage        `json:"metadata"`
-		SourceCode  string                 `json:"source_code"`
-		GasLimit    uint64                 `json:"gas_limit"`
-		GasPrice    *big.Int               `json:"gas_price"`
-		Nonce       uint64                 `json:"nonce"`
-		Signature   string                 `json:"signature"`
-		TotalSupply *big.Int               `json:"total_supply"`
+		From            string                 `json:"from"`
+		Bytecode        string                 `json:"bytecode"`
+		ABI             json.RawMessage        `json:"abi"`
+		Name            string                 `json:"name"`
+		Symbol          string                 `json:"symbol"`
+		Standard        string                 `json:"standard"`
+		Owner           string                 `json:"owner"`
+		Compiler        string                 `json:"compiler"`
+		Version         string                 `json:"version"`
+		License         string                 `json:"license"`
+		Params          map[string]interface{} `json:"params"`
+		Description     string                 `json:"description"`
+		MetadataCID     string                 `json:"metadata_cid"`
+		Metadata        json.RawMessage        `json:"metadata"`
+		SourceCode      string                 `json:"source_code"`
+		GasLimit        uint64                 `json:"gas_limit"`
+		GasPrice        *big.Int               `json:"gas_price"`
+		Nonce           uint64                 `json:"nonce"`
+		Signature       string                 `json:"signature"`
+		SenderPublicKey string                 `json:"sender_public_key"`
+		KeyType         string                 `json:"key_type"`
+		TotalSupply     *big.Int               `json:"total_supply"`
 	}
 	if err := c.ShouldBindJSON(&paramsData); err != nil {
 		c.JSON(http.StatusBadRequest, APIResponse{
@@ -751,10 +959,11 @@ func (s *Server) DeployContract(c *gin.Context) {
 		GasPrice:    paramsData.GasPrice,
 		Nonce:       paramsData.Nonce,
 		Signature:   paramsData.Signature,
+		PublicKey:   paramsData.SenderPublicKey,
+		KeyType:     paramsData.KeyType,
 		TotalSupply: paramsData.TotalSupply,
 	}
-	// При деплое с адреса gndself_address комиссия за деплой не взимается (при наличии такой логики в core).
-	address, err := s.core.DeployContract(&params)
+	tx, err := s.core.NewDeployTransaction(&params)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, APIResponse{
 			Success: false,
@@ -763,23 +972,42 @@ func (s *Server) DeployContract(c *gin.Context) {
 		})
 		return
 	}
-	// Записываем транзакцию деплоя в БД (для отображения в админке и истории)
-	pool := s.core.Pool
-	if pool == nil {
-		pool = s.db
-	}
-	genesisID := int64(0)
-	if s.core != nil && s.core.Genesis != nil {
-		genesisID = s.core.Genesis.ID
+	var signAudit *signingAuditEntry
+	// Подпись из админки: без подписи клиента, с X-Admin-Token и кошельком from под управлением ноды подписывает нода
+	if len(tx.Signature) == 0 && tx.SenderPublicKeyHex == "" && s.adminSigner != nil && s.db != nil && ValidateAdminToken(c.GetHeader("X-Admin-Token")) {
+		walletID, errSigner := core.GetSignerWalletIDByAddress(c.Request.Context(), s.db, tx.Sender.String())
+		if errSigner == nil {
+			// в журнал подписи — после отправки: до успешной отправки операция считается отклонённой
+			signAudit = &signingAuditEntry{WalletAddress: tx.Sender.String(), SignerWalletID: walletID, Source: signingSourceAdmin, Status: signingStatusRejected, ClientIP: c.ClientIP()}
+			if errSig := signWithSigner(c.Request.Context(), s.adminSigner, walletID, tx); errSig != nil {
+				signAudit.Status, signAudit.Err = signingStatusFailed, errSig
+			}
+			signAudit.TxHash = tx.Hash
+			defer func() { s.recordSigningAudit(context.Background(), *signAudit) }()
+		}
 	}
-	if pool != nil {
-		if errTx := core.RecordAdminTransaction(c.Request.Context(), pool, genesisID, "contract_deploy", params.From, address, address); errTx != nil {
-			log.Printf("[REST] запись транзакции contract_deploy в gnd_db.transactions: %v", errTx)
+	address, err := s.core.SubmitDeployTransaction(&params, tx)
+	if err != nil {
+		if signAudit != nil && signAudit.Err == nil {
+			signAudit.Err = err
 		}
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   "Ошибка деплоя контракта: " + err.Error(),
+			Code:    txErrorCode(err),
+		})
+		return
+	}
+	if signAudit != nil {
+		signAudit.Status = signingStatusSent
 	}
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
-		Data:    gin.H{"address": address},
+		Data: gin.H{
+			"address": address,
+			"hash":    tx.Hash,
+			"message": "Транзакция деплоя отправлена в мемпул",
+		},
 	})
 }
 
@@ -871,6 +1099,7 @@ func (s *Server) GetContractView(c *gin.Context) {
 		"view_functions":  viewFuncs,
 		"write_functions": writeFuncs,
 		"compiler":        contract.Compiler,
+		"is_verified":     contract.IsVerified,
 	}
 	c.JSON(http.StatusOK, APIResponse{Success: true, Data: out})
 }
@@ -913,6 +1142,15 @@ func (s *Server) ContractCall(c *gin.Context) {
 	returnHex := ""
 	success := result != nil && result.Error == nil
 	dataMap := gin.H{"return_data": returnHex, "success": success}
+	if result != nil {
+		dataMap["gas_used"] = result.GasUsed
+		if result.Error != nil {
+			dataMap["error"] = result.Error.Error()
+			if reason := vm.RevertReason(result.Error); reason != "" {
+				dataMap["revert_reason"] = reason
+			}
+		}
+	}
 	if result != nil && len(result.ReturnData) > 0 {
 		returnHex = "0x" + hex.EncodeToString(result.ReturnData)
 		dataMap["return_data"] = returnHex
@@ -947,11 +1185,17 @@ func (s *Server) ContractSend(c *gin.Context) {
 		GasLimit        uint64 `json:"gas_limit"`
 		Signature       string `json:"signature"`
 		SenderPublicKey string `json:"sender_public_key"`
+		KeyType         string `json:"key_type"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Неверный формат данных", Code: http.StatusBadRequest})
 		return
 	}
+	keyType, err := gndcrypto.ParseKeyType(req.KeyType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: err.Error(), Code: http.StatusBadRequest})
+		return
+	}
 	if strings.TrimSpace(req.From) == "" {
 		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Укажите from (адрес отправителя)", Code: http.StatusBadRequest})
 		return
@@ -987,34 +1231,93 @@ func (s *Server) ContractSend(c *gin.Context) {
 		Symbol:             "GND",
 		Signature:          decodeSignatureHex(req.Signature),
 		SenderPublicKeyHex: strings.TrimSpace(req.SenderPublicKey),
+		KeyType:            keyType,
 		IsVerified:         false,
+		ChainID:            s.core.ChainID,
+		SubnetID:           s.core.SubnetID,
 	}
 	if s.core != nil && s.core.State != nil {
 		tx.Nonce = s.core.State.GetNonce(types.Address(fromAddr))
 	}
 	tx.Hash = tx.CalculateHash()
+	var signAudit *signingAuditEntry
 	// Подпись из админки: если подпись не передана, но передан X-Admin-Token и кошелёк from управляется нодой (signer_wallet_id), подписываем транзакцию нодой.
 	if len(tx.Signature) == 0 && tx.SenderPublicKeyHex == "" && s.adminSigner != nil && s.db != nil && ValidateAdminToken(c.GetHeader("X-Admin-Token")) {
 		walletID, errSigner := core.GetSignerWalletIDByAddress(c.Request.Context(), s.db, fromAddr)
 		if errSigner == nil {
-			sig, errSig := s.adminSigner.SignDigest(c.Request.Context(), walletID, []byte(tx.Hash))
-			if errSig == nil {
-				tx.Signature = sig
-				tx.IsVerified = true
+			// в журнал подписи — после отправки: до успешной отправки операция считается отклонённой
+			signAudit = &signingAuditEntry{WalletAddress: fromAddr, SignerWalletID: walletID, Source: signingSourceAdmin, Status: signingStatusRejected, ClientIP: c.ClientIP()}
+			if errSig := signWithSigner(c.Request.Context(), s.adminSigner, walletID, tx); errSig != nil {
+				signAudit.Status, signAudit.Err = signingStatusFailed, errSig
 			}
+			signAudit.TxHash = tx.Hash
+			defer func() { s.recordSigningAudit(context.Background(), *signAudit) }()
 		}
 	}
+	// Предварительное исполнение байткода: возвращаем return data и записи storage; при revert транзакция не отправляется
+	preview, err := s.evm.SimulateTransaction(tx)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Исполнение контракта: " + err.Error(), Code: http.StatusBadRequest})
+		return
+	}
+	if preview.Error != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Success: false,
+			Data:    contractExecutionData(preview),
+			Error:   "Вызов контракта откатился: " + preview.Error.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
 	hash, err := s.core.SendTransaction(tx)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Отправка транзакции: " + err.Error(), Code: http.StatusBadRequest})
+		if signAudit != nil && signAudit.Err == nil {
+			signAudit.Err = err
+		}
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Отправка транзакции: " + err.Error(), Code: txErrorCode(err)})
 		return
 	}
+	if signAudit != nil {
+		signAudit.Status = signingStatusSent
+	}
+	out := contractExecutionData(preview)
+	out["hash"] = hash
+	out["message"] = "Транзакция отправлена в мемпул"
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
-		Data:    gin.H{"hash": hash, "message": "Транзакция отправлена в мемпул"},
+		Data:    out,
 	})
 }
 
+// contractExecutionData формирует ответ с результатом исполнения контракта: return data, газ, причина revert и записи storage.
+func contractExecutionData(result *types.ExecutionResult) gin.H {
+	out := gin.H{
+		"return_data": "0x" + hex.EncodeToString(result.ReturnData),
+		"gas_used":    result.GasUsed,
+	}
+	if result.Error != nil {
+		out["error"] = result.Error.Error()
+		if reason := vm.RevertReason(result.Error); reason != "" {
+			out["revert_reason"] = reason
+		}
+	}
+	var writes []gin.H
+	for _, change := range result.StateChanges {
+		if change.Type != types.ChangeTypeStorage {
+			continue
+		}
+		writes = append(writes, gin.H{
+			"address":    change.Address,
+			"slot_key":   "0x" + hex.EncodeToString(change.Key),
+			"slot_value": "0x" + hex.EncodeToString(change.Value),
+		})
+	}
+	if len(writes) > 0 {
+		out["storage_changes"] = writes
+	}
+	return out
+}
+
 // AdminContractCall вызывает view/constant метод контракта по id (для страницы /admin/contracts/:id). POST /api/v1/admin/contracts/:id/call
 func (s *Server) AdminContractCall(c *gin.Context) {
 	address, err := s.resolveContractAddressByID(c)
@@ -1089,11 +1392,7 @@ func (s *Server) CompileContract(c *gin.Context) {
 	if req.Name == "" {
 		req.Name = "Contract"
 	}
-	solcPath := "solc"
-	if s.cfg != nil && s.cfg.EVM.SolcPath != "" {
-		solcPath = s.cfg.EVM.SolcPath
-	}
-	solc := compiler.DefaultSolidityCompiler{SolcPath: solcPath}
+	solc := compiler.DefaultSolidityCompiler{SolcPath: s.solcPath()}
 	metadata := compiler.ContractMetadata{
 		Name:     req.Name,
 		Standard: req.Standard,
@@ -1120,6 +1419,114 @@ func (s *Server) CompileContract(c *gin.Context) {
 	})
 }
 
+// solcPath возвращает путь к solc из конфигурации (пусто — "solc" из $PATH).
+func (s *Server) solcPath() string {
+	if s.cfg != nil && s.cfg.EVM.SolcPath != "" {
+		return s.cfg.EVM.SolcPath
+	}
+	return "solc"
+}
+
+// VerifyContract проверяет исходный код задеплоенного контракта: компилирует его с указанной версией solc и настройками
+// оптимизатора и сверяет с кодом контракта в БД без учёта хеша метаданных. При совпадении контракт отмечается
+// проверенным, исходный код и ABI сохраняются (GET /contract/:address возвращает IsVerified).
+// POST /api/v1/contract/:address/verify
+// Body: { "source": "...", "compiler_version": "0.8.20", "optimizer": { "enabled": true, "runs": 200 } [, "contract_name": "GNDToken"] }
+func (s *Server) VerifyContract(c *gin.Context) {
+	address := strings.TrimSpace(c.Param("address"))
+	if address == "" {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Укажите address контракта", Code: http.StatusBadRequest})
+		return
+	}
+	var req struct {
+		Source          string                      `json:"source"`
+		CompilerVersion string                      `json:"compiler_version"`
+		Optimizer       *compiler.OptimizerSettings `json:"optimizer"`
+		ContractName    string                      `json:"contract_name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Неверный формат данных", Code: http.StatusBadRequest})
+		return
+	}
+	if req.Source == "" || req.CompilerVersion == "" {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Поля source и compiler_version обязательны", Code: http.StatusBadRequest})
+		return
+	}
+	if req.Optimizer == nil {
+		req.Optimizer = &compiler.OptimizerSettings{}
+	}
+	if req.Optimizer.Enabled && req.Optimizer.Runs <= 0 {
+		req.Optimizer.Runs = 200
+	}
+	pool := s.db
+	if s.core != nil && s.core.Pool != nil {
+		pool = s.core.Pool
+	}
+	if pool == nil {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{Success: false, Error: "БД недоступна", Code: http.StatusServiceUnavailable})
+		return
+	}
+	ctx := c.Request.Context()
+	initCode, runtimeCode, err := core.LoadContractCode(ctx, pool, address)
+	if err != nil {
+		c.JSON(http.StatusNotFound, APIResponse{Success: false, Error: err.Error(), Code: http.StatusNotFound})
+		return
+	}
+
+	solc := compiler.DefaultSolidityCompiler{SolcPath: s.solcPath(), Optimizer: req.Optimizer}
+	installed, err := solc.Version()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{Success: false, Error: "Компилятор недоступен: " + err.Error(), Code: http.StatusServiceUnavailable})
+		return
+	}
+	if !compiler.VersionMatches(installed, req.CompilerVersion) {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Версия компилятора %s недоступна (установлен solc %s)", req.CompilerVersion, installed),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+	result, err := solc.Compile([]byte(req.Source), compiler.ContractMetadata{Name: req.ContractName, Compiler: "solc", Version: installed})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Ошибка компиляции: " + err.Error(), Code: http.StatusBadRequest})
+		return
+	}
+	constructorArgs, ok := compiler.MatchBytecode(result, initCode, runtimeCode)
+	if !ok {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Success: false,
+			Data:    gin.H{"address": address, "verified": false},
+			Error:   "Байткод, скомпилированный из исходного кода, не совпадает с кодом контракта",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+	verification := &core.ContractVerification{
+		SourceCode: req.Source,
+		ABI:        []byte(result.ABI),
+		Compiler:   installed,
+		Optimized:  req.Optimizer.Enabled,
+		Runs:       req.Optimizer.Runs,
+	}
+	if err := core.MarkContractVerified(ctx, pool, address, verification); err != nil {
+		c.JSON(http.StatusInternalServerError, APIResponse{Success: false, Error: err.Error(), Code: http.StatusInternalServerError})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data: gin.H{
+			"address":          address,
+			"verified":         true,
+			"compiler":         installed,
+			"optimized":        req.Optimizer.Enabled,
+			"runs":             req.Optimizer.Runs,
+			"abi":              json.RawMessage(result.ABI),
+			"constructor_args": constructorArgs,
+		},
+	})
+}
+
 // AnalyzeContract выполняет проверки безопасности по исходному коду. POST /contract/analyze
 func (s *Server) AnalyzeContract(c *gin.Context) {
 	var req struct {
@@ -1457,7 +1864,7 @@ func (s *Server) setupRoutes() {
 	api.GET("/metrics", s.GetMetrics)
 	api.GET("/metrics/transactions", s.GetTransactionMetrics)
 	api.GET("/metrics/fees", s.GetFeeMetrics)
-	api.GET("/fees", s.GetFeeMetrics) // алиас для удобства
+	api.GET("/fees", s.GetFees)
 	api.GET("/alerts", s.GetAlerts)
 	api.POST("/alerts/thresholds", s.SetAlertThresholds)
 
@@ -1467,16 +1874,30 @@ func (s *Server) setupRoutes() {
 	// Кошельки
 	api.POST("/wallet", s.CreateWallet)
 	api.GET("/wallet/:address/balance", s.GetBalance)
+	api.POST("/wallet/:address/sign-and-send", s.SignAndSend) // X-API-Key с правом wallet:sign:<address>
 
 	// Транзакции и мемпул
 	api.GET("/transaction", s.GetTransactionHelp)  // GET без хеша — подсказка (иначе 404)
 	api.GET("/transaction/", s.GetTransactionHelp) // то же при запросе с завершающим слэшем
 	api.POST("/transaction", s.SendTransaction)
+	api.POST("/transaction/simulate", s.SimulateTransaction) // пробное исполнение и оценка газа без отправки
 	api.GET("/transaction/:hash", s.GetTransaction)
+	api.GET("/transaction/:hash/receipt", s.GetTransactionReceipt)
+	api.GET("/logs", s.GetLogs)                            // события контрактов по адресу и темам topic0–topic3
 	api.GET("/transactions", s.GetTransactionsList)        // список ожидающих (как /mempool)
 	api.GET("/transactions/list", s.GetTransactionsFromDB) // список из gnd_db.transactions (для админки)
 	api.GET("/mempool", s.GetMempool)
 
+	// Консенсус PoA: предупреждения, баны и история нарушений валидаторов
+	api.GET("/consensus/validators", s.GetPoAValidators)
+	api.GET("/consensus/history", s.GetPoAHistory)
+	api.GET("/consensus/finality", s.GetFinality)
+	api.POST("/consensus/vote", s.SubmitVote)
+
+	// Стейкинг PoS: валидаторы, делегирования, разблокировки и награды
+	api.GET("/staking/validators", s.GetStakingValidators)
+	api.GET("/staking/:address", s.GetStakingAccount)
+
 	// Блоки
 	api.GET("/block/latest", s.GetLatestBlock)
 	api.GET("/block/:number", s.GetBlockByNumber)
@@ -1486,6 +1907,8 @@ func (s *Server) setupRoutes() {
 	api.POST("/contract/compile", s.CompileContract)
 	api.POST("/contract/analyze", s.AnalyzeContract)
 	api.GET("/contract/:address", s.GetContract)
+	// Верификация исходного кода: компиляция и сверка с кодом контракта без учёта метаданных solc
+	api.POST("/contract/:address/verify", s.VerifyContract)
 	// Состояние контракта (функции/геттеры: name, symbol, total_supply, balances). Query: addresses=addr1,addr2
 	api.GET("/contract/:address/state", s.GetContractState)
 	// Просмотр контракта: ABI, список view/write функций, базовая инфо. Чтение: POST /contract/:address/call. Запись: POST /contract/:address/send
@@ -1721,6 +2144,10 @@ func (s *Server) setupRoutes() {
 		admin.DELETE("/wallets/:address", s.AdminDeleteWallet)
 		admin.POST("/wallets/:address/delete", s.AdminDeleteWallet)
 		admin.POST("/record-transaction", s.AdminRecordTransaction)
+		// Мемпул: просмотр очередей и удаление транзакций
+		admin.GET("/mempool", s.AdminListMempool)
+		admin.DELETE("/mempool/:hash", s.AdminEvictMempoolTx)
+		admin.POST("/mempool/:hash/evict", s.AdminEvictMempoolTx)
 		// Контракты: запись транзакций блокировки/удаления (для GND_admin)
 		admin.POST("/contracts/:address/disable", s.AdminContractDisable)
 		admin.POST("/contracts/:address/delete", s.AdminContractDelete)
@@ -1739,6 +2166,11 @@ func (s *Server) setupRoutes() {
 	api.GET("/state/account/:address", s.GetAccountStateCurrent)
 	api.GET("/state/account/:address/block/:blockId", s.GetAccountStateAtBlock)
 	api.GET("/state/contract/:address/storage", s.GetContractStorage)
+
+	// Доказательства Меркла по state_root и merkle_root блока (проверка — пакет core/proof)
+	api.GET("/state/account/:address/proof", s.GetAccountProof)
+	api.GET("/state/contract/:address/storage/:slot/proof", s.GetStorageProof)
+	api.GET("/transaction/:hash/proof", s.GetTransactionProof)
 }
 
 // GetAccountStateCurrent возвращает текущее состояние аккаунта из accounts. GET /api/v1/state/account/:address
@@ -1818,6 +2250,69 @@ func (s *Server) GetContractStorage(c *gin.Context) {
 	c.JSON(http.StatusOK, APIResponse{Success: true, Data: gin.H{"address": address, "block_id": blockID, "slots": slots}})
 }
 
+// proofBlockNumber возвращает номер блока доказательства из query block (по умолчанию — вершина цепи).
+func (s *Server) proofBlockNumber(c *gin.Context) (uint64, bool) {
+	blockStr := c.Query("block")
+	if blockStr == "" {
+		return s.core.Height(), true
+	}
+	number, err := strconv.ParseUint(blockStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Некорректный block", Code: http.StatusBadRequest})
+		return 0, false
+	}
+	return number, true
+}
+
+// GetAccountProof возвращает доказательство балансов и nonce аккаунта по state_root блока.
+// GET /api/v1/state/account/:address/proof?block=N
+func (s *Server) GetAccountProof(c *gin.Context) {
+	address := strings.TrimSpace(c.Param("address"))
+	number, ok := s.proofBlockNumber(c)
+	if !ok {
+		return
+	}
+	p, err := s.core.AccountProof(address, number)
+	if err != nil {
+		c.JSON(http.StatusNotFound, APIResponse{Success: false, Error: err.Error(), Code: http.StatusNotFound})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: p})
+}
+
+// GetStorageProof возвращает доказательство значения слота storage контракта по state_root блока.
+// GET /api/v1/state/contract/:address/storage/:slot/proof?block=N — slot в hex (до 32 байт, дополняется нулями слева).
+func (s *Server) GetStorageProof(c *gin.Context) {
+	address := strings.TrimSpace(c.Param("address"))
+	slot, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(c.Param("slot")), "0x"))
+	if err != nil || len(slot) > 32 {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "slot — hex-ключ слота до 32 байт", Code: http.StatusBadRequest})
+		return
+	}
+	slot = append(make([]byte, 32-len(slot)), slot...)
+	number, ok := s.proofBlockNumber(c)
+	if !ok {
+		return
+	}
+	p, err := s.core.StorageProof(address, slot, number)
+	if err != nil {
+		c.JSON(http.StatusNotFound, APIResponse{Success: false, Error: err.Error(), Code: http.StatusNotFound})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: p})
+}
+
+// GetTransactionProof возвращает доказательство включения транзакции по merkle_root её блока.
+// GET /api/v1/transaction/:hash/proof
+func (s *Server) GetTransactionProof(c *gin.Context) {
+	p, err := s.core.TransactionProof(c.Request.Context(), strings.TrimSpace(c.Param("hash")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, APIResponse{Success: false, Error: err.Error(), Code: http.StatusNotFound})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: p})
+}
+
 // AdminWriteContractStorageSlot записывает слот storage контракта. POST /api/v1/admin/state/contract/:address/storage
 // Body: {"block_id": 1, "slot_key": "0x...", "slot_value": "0x..."} ИЛИ {"block_id": 1, "slot_index": 0, "slot_value": "0x..."}
 // slot_index (0, 1, 2...) — для NativeTokensController: 0=gndToken, 1=ganiToken.
@@ -1857,6 +2352,12 @@ func (s *Server) AdminWriteContractStorageSlot(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: err.Error(), Code: http.StatusBadRequest})
 		return
 	}
+	// Слот записан в обход блока — сбрасываем кэш storage, чтобы исполнение контракта видело новое значение
+	if s.core != nil {
+		if st, ok := s.core.State.(*core.State); ok {
+			st.InvalidateContractStorage(address)
+		}
+	}
 	// Все действия с контрактами формируют транзакции в блокчейне
 	genesisID := int64(0)
 	if s.core != nil && s.core.Genesis != nil {
@@ -1874,6 +2375,106 @@ func (s *Server) AdminWriteContractStorageSlot(c *gin.Context) {
 	c.JSON(http.StatusOK, APIResponse{Success: true, Data: "Слот записан"})
 }
 
+// SimulateTransaction исполняет транзакцию над копией состояния без отправки и применения (vm.EVM.Simulate):
+// возвращает оценку газа, return data, изменения состояния, события и причину неуспеха. Подпись не нужна.
+// POST /api/v1/transaction/simulate — пустой to: деплой (data — init-код); block — номер блока, после которого
+// исполнять (по умолчанию — текущее состояние ноды); gas_limit 0 — лимит блока.
+func (s *Server) SimulateTransaction(c *gin.Context) {
+	var req struct {
+		From     string   `json:"from"`
+		To       string   `json:"to"`
+		Value    *big.Int `json:"value"`
+		Symbol   string   `json:"symbol"` // монета value (по умолчанию GND)
+		Data     string   `json:"data"`   // hex calldata или init-кода
+		GasLimit uint64   `json:"gas_limit"`
+		Block    *uint64  `json:"block"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Неверный формат данных транзакции", Code: http.StatusBadRequest})
+		return
+	}
+	if s.core == nil || s.evm == nil {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{Success: false, Error: "Блокчейн или EVM не инициализированы", Code: http.StatusServiceUnavailable})
+		return
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(req.Data), "0x"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "data — hex-строка", Code: http.StatusBadRequest})
+		return
+	}
+	value := req.Value
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	if value.Sign() < 0 {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "value не может быть отрицательным", Code: http.StatusBadRequest})
+		return
+	}
+	tx := &core.Transaction{
+		Sender:    types.Address(strings.TrimSpace(req.From)),
+		Recipient: types.Address(strings.TrimSpace(req.To)),
+		Value:     value,
+		Symbol:    strings.TrimSpace(req.Symbol),
+		Data:      data,
+		GasLimit:  req.GasLimit,
+		GasPrice:  big.NewInt(0),
+	}
+	st, block, err := s.core.StateAt(req.Block)
+	if err != nil {
+		c.JSON(http.StatusNotFound, APIResponse{Success: false, Error: err.Error(), Code: http.StatusNotFound})
+		return
+	}
+	sim, err := s.evm.Simulate(tx, block, st)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Исполнение транзакции: " + err.Error(), Code: http.StatusBadRequest})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse{Success: true, Data: simulationData(sim, block)})
+}
+
+// simulationData формирует ответ пробного исполнения: статус, газ, return data, изменения состояния и события.
+func simulationData(sim *vm.Simulation, block *core.Block) gin.H {
+	out := gin.H{
+		"block":        block.Index,
+		"status":       core.ReceiptStatusSuccess,
+		"gas_used":     sim.GasUsed,
+		"gas_estimate": sim.GasEstimate,
+		"return_data":  "0x" + hex.EncodeToString(sim.ReturnData),
+	}
+	if sim.Error != nil {
+		out["status"] = core.ReceiptStatusFailed
+		out["error"] = sim.Error.Error()
+		if sim.RevertReason != "" {
+			out["revert_reason"] = sim.RevertReason
+		}
+	}
+	diff := make([]gin.H, 0, len(sim.StateChanges))
+	for _, change := range sim.StateChanges {
+		switch change.Type {
+		case types.ChangeTypeBalance:
+			diff = append(diff, gin.H{"type": "balance", "address": change.Address, "symbol": change.Symbol, "amount": change.Amount.String()})
+		case types.ChangeTypeStorage:
+			diff = append(diff, gin.H{"type": "storage", "address": change.Address,
+				"slot_key": "0x" + hex.EncodeToString(change.Key), "slot_value": "0x" + hex.EncodeToString(change.Value)})
+		case types.ChangeTypeNonce:
+			diff = append(diff, gin.H{"type": "nonce", "address": change.Address, "nonce": change.Amount.Uint64()})
+		case types.ChangeTypeCode:
+			diff = append(diff, gin.H{"type": "code", "address": change.Address, "code": "0x" + hex.EncodeToString(change.Value)})
+		}
+	}
+	out["state_diff"] = diff
+	events := make([]gin.H, 0, len(sim.Logs))
+	for _, l := range sim.Logs {
+		topics := make([]string, len(l.Topics))
+		for i, topic := range l.Topics {
+			topics[i] = "0x" + hex.EncodeToString(topic)
+		}
+		events = append(events, gin.H{"address": l.Address, "topics": topics, "data": "0x" + hex.EncodeToString(l.Data)})
+	}
+	out["events"] = events
+	return out
+}
+
 // GetTransactionHelp возвращает подсказку при GET /transaction без хеша (избегаем 404)
 func (s *Server) GetTransactionHelp(c *gin.Context) {
 	c.JSON(http.StatusBadRequest, APIResponse{
@@ -1961,21 +2562,27 @@ func (s *Server) GetTransactionsFromDB(c *gin.Context) {
 	c.JSON(http.StatusOK, APIResponse{Success: true, Data: gin.H{"list": list, "total": total}})
 }
 
-// GetMempool возвращает размер мемпула и список хешей ожидающих транзакций (для проверки работы mempool)
+// GetMempool возвращает размер мемпула, число pending/queued и список хешей ожидающих транзакций (для проверки работы mempool)
 func (s *Server) GetMempool(c *gin.Context) {
 	if s.mempool == nil {
-		c.JSON(http.StatusOK, APIResponse{Success: true, Data: gin.H{"size": 0, "pending_hashes": []string{}}})
+		c.JSON(http.StatusOK, APIResponse{Success: true, Data: gin.H{"size": 0, "pending": 0, "queued": 0, "pending_hashes": []string{}, "queued_hashes": []string{}}})
 		return
 	}
 	size := s.mempool.Size()
+	pendingCount, queuedCount := s.mempool.Stats()
 	pending := s.mempool.GetPendingTransactions()
 	hashes := make([]string, 0, len(pending))
 	for _, tx := range pending {
 		hashes = append(hashes, tx.Hash)
 	}
+	queued := s.mempool.GetQueuedTransactions()
+	queuedHashes := make([]string, 0, len(queued))
+	for _, tx := range queued {
+		queuedHashes = append(queuedHashes, tx.Hash)
+	}
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
-		Data:    gin.H{"size": size, "pending_hashes": hashes},
+		Data:    gin.H{"size": size, "pending": pendingCount, "queued": queuedCount, "pending_hashes": hashes, "queued_hashes": queuedHashes},
 	})
 }
 
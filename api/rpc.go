@@ -7,6 +7,7 @@ import (
 	"GND/types"
 	"GND/vm"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
@@ -34,8 +35,10 @@ type DeployContractParams struct {
 	TotalSupply *big.Int               `json:"total_supply"`
 }
 
-func StartRPCServer(evm *vm.EVM, addr string) error {
+// StartRPCServer запускает RPC-сервер: POST / — JSON-RPC 2.0 (eth_*, net_*, web3_*), остальные пути — REST-подобные эндпоинты ниже.
+func StartRPCServer(evm *vm.EVM, blockchain *core.Blockchain, mempool *core.Mempool, chainID int64, addr string) error {
 	mux := http.NewServeMux()
+	ethRPC := NewEthRPC(blockchain, mempool, evm, chainID)
 
 	// Регистрация всех эндпоинтов
 	endpoints := map[string]http.HandlerFunc{
@@ -71,6 +74,10 @@ func StartRPCServer(evm *vm.EVM, addr string) error {
 
 		// Обработка запроса
 		path := r.URL.Path
+		if path == "/" {
+			ethRPC.ServeHTTP(w, r)
+			return
+		}
 		if handler, ok := endpoints[path]; ok {
 			handler(w, r)
 		} else {
@@ -80,6 +87,7 @@ func StartRPCServer(evm *vm.EVM, addr string) error {
 
 	// Логирование информации о запуске
 	log.Printf("=== RPC Server запущен на %s ===", addr)
+	log.Println("JSON-RPC 2.0 (eth_*, net_*, web3_*): POST /")
 	log.Println("Доступные эндпоинты:")
 	for path := range endpoints {
 		log.Printf("  %s", path)
@@ -245,6 +253,10 @@ func SendTxHandler(evm *vm.EVM) http.HandlerFunc {
 			return
 		}
 		txHash, err := evm.SendRawTransaction(params.RawTx)
+		if errors.Is(err, core.ErrWrongChain) {
+			sendJSON(w, map[string]interface{}{"error": err.Error(), "code": ErrCodeWrongChain}, http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -0,0 +1,225 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// api/signing.go — подпись транзакций нодой ключами signing_service: POST /api/v1/wallet/:address/sign-and-send
+// по API-ключу с правом на кошелёк, подпись из админки и журнал операций подписи (signing_audit).
+
+package api
+
+import (
+	"GND/core"
+	gndcrypto "GND/core/crypto"
+	"GND/signing_service/service"
+	"GND/types"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Источник и результат операции подписи в signing_audit.
+const (
+	signingSourceAPIKey = "api_key"
+	signingSourceAdmin  = "admin"
+
+	signingStatusDenied   = "denied"
+	signingStatusFailed   = "failed"
+	signingStatusRejected = "rejected"
+	signingStatusSent     = "sent"
+)
+
+// signingAuditEntry — запись журнала подписи.
+type signingAuditEntry struct {
+	WalletAddress  string
+	SignerWalletID uuid.UUID // uuid.Nil — кошелёк не найден или не проверялся
+	Source         string
+	APIKeyID       int // 0 — без API-ключа (админка)
+	TxHash         string
+	Status         string
+	Err            error
+	ClientIP       string
+}
+
+// recordSigningAudit записывает операцию подписи в signing_audit. Ошибка записи журнала только логируется.
+func (s *Server) recordSigningAudit(ctx context.Context, e signingAuditEntry) {
+	if s.db == nil {
+		return
+	}
+	var signerID *uuid.UUID
+	if e.SignerWalletID != uuid.Nil {
+		signerID = &e.SignerWalletID
+	}
+	var keyID *int
+	if e.APIKeyID > 0 {
+		keyID = &e.APIKeyID
+	}
+	var errText *string
+	if e.Err != nil {
+		msg := e.Err.Error()
+		errText = &msg
+	}
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO public.signing_audit (wallet_address, signer_wallet_id, source, api_key_id, tx_hash, status, error, client_ip, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9)`,
+		e.WalletAddress, signerID, e.Source, keyID, e.TxHash, e.Status, errText, e.ClientIP, core.BlockchainNow())
+	if err != nil {
+		log.Printf("[REST] запись signing_audit (%s, %s): %v", e.WalletAddress, e.Status, err)
+	}
+}
+
+// signWithSigner подписывает транзакцию ключом кастодиального кошелька walletID: восстанавливаемая подпись secp256k1
+// от sha256(hex хеша), проверяется так же, как подпись клиента (core.VerifyTransactionSignature).
+func signWithSigner(ctx context.Context, signer AdminSigner, walletID uuid.UUID, tx *core.Transaction) error {
+	tx.KeyType = gndcrypto.KeyTypeSecp256k1
+	tx.Hash = tx.CalculateHash()
+	digest := sha256.Sum256([]byte(tx.Hash))
+	sig, err := signer.SignDigest(ctx, walletID, digest[:])
+	if err != nil {
+		return err
+	}
+	tx.Signature = sig
+	return nil
+}
+
+// signerErrorStatus возвращает HTTP-статус ошибки signing_service.
+func signerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrWalletDisabled):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrWalletNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// SignAndSend — POST /api/v1/wallet/:address/sign-and-send. Нода собирает транзакцию от кастодиального кошелька address
+// (ключ в signer_wallets), подписывает её через signing_service и отправляет в мемпул; nonce — следующий для кошелька.
+// Требуется X-API-Key с правом wallet:sign:<address> или wallet:sign:*. Тело: { "to", "value", "symbol", "fee", "gas_limit", "data", "type",
+// "max_fee_per_gas", "max_priority_fee_per_gas" } — с max_fee_per_gas транзакция рынка комиссий вместо fee; symbol по умолчанию GND,
+// без fee и max_fee_per_gas — рекомендованные комиссии (SuggestFees, Standard).
+func (s *Server) SignAndSend(c *gin.Context) {
+	ctx := c.Request.Context()
+	address := strings.TrimSpace(c.Param("address"))
+	key := LookupAPIKey(ctx, s.db, c.GetHeader("X-API-Key"))
+	if key == nil {
+		c.JSON(http.StatusUnauthorized, APIResponse{Success: false, Error: "Неверный или отсутствующий X-API-Key", Code: http.StatusUnauthorized})
+		return
+	}
+	if s.adminSigner == nil || s.core == nil {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{Success: false, Error: "Signing service не включён (GND_MASTER_KEY)", Code: http.StatusServiceUnavailable})
+		return
+	}
+	entry := signingAuditEntry{WalletAddress: address, Source: signingSourceAPIKey, APIKeyID: key.ID, ClientIP: c.ClientIP()}
+	if !key.CanSignFor(address) {
+		entry.Status = signingStatusDenied
+		s.recordSigningAudit(ctx, entry)
+		c.JSON(http.StatusForbidden, APIResponse{Success: false, Error: "У API-ключа нет права подписи для кошелька " + address, Code: http.StatusForbidden})
+		return
+	}
+	var req struct {
+		To       string   `json:"to"`
+		Value    *big.Int `json:"value"`
+		Symbol   string   `json:"symbol"` // символ переводимого актива, по умолчанию GND
+		Fee      *big.Int `json:"fee"`
+		GasLimit uint64   `json:"gas_limit"`
+		Data     string   `json:"data"` // hex calldata, можно с префиксом 0x
+		Type     string   `json:"type"` // stake, unstake, validator или пусто
+
+		MaxFeePerGas         *big.Int `json:"max_fee_per_gas"`
+		MaxPriorityFeePerGas *big.Int `json:"max_priority_fee_per_gas"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Неверный формат данных транзакции", Code: http.StatusBadRequest})
+		return
+	}
+	toAddr, err := types.ParseAddress(req.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Неверный формат адреса получателя: " + err.Error(), Code: http.StatusBadRequest})
+		return
+	}
+	var data []byte
+	if strings.TrimSpace(req.Data) != "" {
+		if data, err = decodeHex(req.Data); err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Неверный hex data: " + err.Error(), Code: http.StatusBadRequest})
+			return
+		}
+	}
+	walletID, err := core.GetSignerWalletIDByAddress(ctx, s.db, address)
+	if err != nil {
+		c.JSON(http.StatusNotFound, APIResponse{Success: false, Error: "Кошелёк не управляется signing_service: " + address, Code: http.StatusNotFound})
+		return
+	}
+	entry.SignerWalletID = walletID
+
+	value, fee := req.Value, req.Fee
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	if fee == nil {
+		fee = big.NewInt(0)
+	}
+	symbol := strings.TrimSpace(req.Symbol)
+	if symbol == "" {
+		symbol = core.GasSymbol
+	}
+	gasLimit := req.GasLimit
+	if gasLimit == 0 {
+		gasLimit = core.IntrinsicGas(data, false)
+	}
+	tx := &core.Transaction{
+		Sender:    types.Address(address),
+		Recipient: toAddr,
+		Value:     value,
+		Data:      data,
+		GasLimit:  gasLimit,
+		GasPrice:  fee,
+		Symbol:    symbol,
+		Timestamp: core.BlockchainNow(),
+		Status:    "pending",
+		ChainID:   s.core.ChainID,
+		SubnetID:  s.core.SubnetID,
+	}
+	setDynamicFee(tx, req.MaxFeePerGas, req.MaxPriorityFeePerGas)
+	// Без цены газа транзакция ниже base fee не попала бы в блок: подставляются рекомендованные комиссии
+	if req.Fee == nil && req.MaxFeePerGas == nil {
+		fees := s.core.SuggestFees()
+		setDynamicFee(tx, fees.Standard.MaxFeePerGas, fees.Standard.MaxPriorityFeePerGas)
+	}
+	switch core.TxType(req.Type) {
+	case core.TxTypeStake, core.TxTypeUnstake, core.TxTypeValidator:
+		tx.Type = req.Type
+	}
+
+	// nonce выбирается и занимается в мемпуле под одной блокировкой: параллельные запросы одного кошелька не получают один nonce
+	s.signMu.Lock()
+	defer s.signMu.Unlock()
+	if s.mempool != nil {
+		tx.Nonce = s.mempool.NextNonce(tx.Sender)
+	} else if s.core.State != nil {
+		tx.Nonce = s.core.State.GetNonce(tx.Sender)
+	}
+	if err := signWithSigner(ctx, s.adminSigner, walletID, tx); err != nil {
+		entry.TxHash, entry.Status, entry.Err = tx.Hash, signingStatusFailed, err
+		s.recordSigningAudit(ctx, entry)
+		status := signerErrorStatus(err)
+		c.JSON(status, APIResponse{Success: false, Error: "Ошибка подписи: " + err.Error(), Code: status})
+		return
+	}
+	entry.TxHash = tx.Hash
+	if _, err := s.core.SendTransaction(tx); err != nil {
+		entry.Status, entry.Err = signingStatusRejected, err
+		s.recordSigningAudit(ctx, entry)
+		c.JSON(http.StatusBadRequest, APIResponse{Success: false, Error: "Ошибка отправки транзакции: " + err.Error(), Code: txErrorCode(err)})
+		return
+	}
+	entry.Status = signingStatusSent
+	s.recordSigningAudit(ctx, entry)
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    gin.H{"hash": tx.Hash, "from": address, "to": string(toAddr), "nonce": tx.Nonce},
+	})
+}
@@ -0,0 +1,88 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package api
+
+import (
+	"GND/core"
+	signercrypto "GND/signing_service/crypto"
+	"GND/types"
+	"bytes"
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/google/uuid"
+)
+
+// keySigner — AdminSigner с одним ключом в памяти (вместо signer_wallets).
+type keySigner struct {
+	id  uuid.UUID
+	key *secp256k1.PrivateKey
+}
+
+func (k *keySigner) SignDigest(_ context.Context, walletID uuid.UUID, digest []byte) ([]byte, error) {
+	if walletID != k.id {
+		return nil, context.Canceled
+	}
+	return signercrypto.SignDigest(k.key, digest)
+}
+
+func TestAPIKey_CanSignFor(t *testing.T) {
+	tests := []struct {
+		perms   []string
+		address string
+		want    bool
+	}{
+		{[]string{"wallet:sign:GNDabc"}, "GNDabc", true},
+		{[]string{"wallet:sign:GNDabc"}, "GNDother", false},
+		{[]string{PermWalletSignAll}, "GNDother", true},
+		{[]string{"token:deploy"}, "GNDabc", false},
+		{nil, "GNDabc", false},
+		{[]string{"wallet:sign:"}, "", false},
+	}
+	for _, tt := range tests {
+		if got := (&APIKey{Permissions: tt.perms}).CanSignFor(tt.address); got != tt.want {
+			t.Errorf("CanSignFor(%v, %q) = %v, ожидалось %v", tt.perms, tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestSignWithSigner_SignatureVerifiesForCustodialAddress(t *testing.T) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := core.AddressFromPublicKey(key.PubKey().SerializeCompressed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := &keySigner{id: uuid.New(), key: key}
+	tx := &core.Transaction{Sender: types.Address(address), Recipient: "GN_recipient", Value: big.NewInt(10),
+		GasLimit: core.TxGas, GasPrice: big.NewInt(1), Symbol: "GND", ChainID: 1}
+	if err := signWithSigner(context.Background(), signer, signer.id, tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Hash != tx.CalculateHash() || tx.SenderPublicKeyHex != "" {
+		t.Fatalf("хеш %s должен совпадать с полями, публичный ключ не передаётся", tx.Hash)
+	}
+	if err := core.VerifyTransactionSignature(tx); err != nil {
+		t.Fatalf("подпись signing_service должна проверяться нодой: %v", err)
+	}
+	if err := signWithSigner(context.Background(), signer, uuid.New(), tx); err == nil {
+		t.Fatal("ошибка signing_service должна возвращаться")
+	}
+}
+
+func TestSignAndSend_RequiresAPIKey(t *testing.T) {
+	s := setupServerForDocTest(t)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet/GNDabc/sign-and-send", bytes.NewReader([]byte(`{"to":"GNDdef"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "gnd_unknown")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("без действующего API-ключа ожидался 401, получен %d: %s", w.Code, w.Body.String())
+	}
+}
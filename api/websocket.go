@@ -6,11 +6,14 @@ package api
 import (
 	"GND/core"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -32,27 +35,30 @@ const (
 	maxMessageSize = 512 * 1024 // 512KB
 )
 
-var (
-	clients  = make(map[*Client]bool) // было: map[*websocket.Conn]bool
-	hubMutex sync.RWMutex
+// Типы подписок WebSocket (gnd_subscribe). Для совместимости принимаются и имена Ethereum: newHeads, pendingTransactions, logs.
+const (
+	wsKindBlocks       = "blocks"
+	wsKindTransactions = "transactions"
+	wsKindEvents       = "events"
+	wsKindReorgs       = "reorgs"
 )
 
+var wsKindAliases = map[string]string{
+	wsKindBlocks:          wsKindBlocks,
+	wsKindTransactions:    wsKindTransactions,
+	wsKindEvents:          wsKindEvents,
+	wsKindReorgs:          wsKindReorgs,
+	"newHeads":            wsKindBlocks,
+	"pendingTransactions": wsKindTransactions,
+	"logs":                wsKindEvents,
+}
+
 // Структура сообщения для клиента
 type WSMessage struct {
 	Type string      `json:"type"` // "block", "tx", "event"
 	Data interface{} `json:"data"`
 }
 
-// Менеджер всех подключений
-type WSManager struct {
-	clients map[*websocket.Conn]bool
-	lock    sync.Mutex
-}
-
-var wsManager = &WSManager{
-	clients: make(map[*websocket.Conn]bool),
-}
-
 // Upgrader для WebSocket
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
@@ -74,13 +80,10 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// Hub поддерживает активные соединения и рассылку сообщений
+// Hub поддерживает активные соединения и рассылку уведомлений по подпискам
 type Hub struct {
-	clients    map[*Client]bool
-	broadcast  chan []byte
-	register   chan *Client
-	unregister chan *Client
-	mutex      sync.RWMutex
+	clients map[*Client]bool
+	mutex   sync.RWMutex
 }
 
 // Client представляет подключенного клиента
@@ -88,39 +91,53 @@ type Client struct {
 	hub           *Hub
 	conn          *websocket.Conn
 	send          chan []byte
-	subscriptions map[string]bool
+	mu            sync.Mutex
+	subscriptions map[string]*wsSubscription // id подписки -> подписка
 }
 
-var hub = &Hub{
-	clients:    make(map[*Client]bool),
-	broadcast:  make(chan []byte),
-	register:   make(chan *Client),
-	unregister: make(chan *Client),
+// wsSubscription — подписка клиента: тип (blocks, transactions, events) и фильтр.
+type wsSubscription struct {
+	ID     string
+	Kind   string
+	Filter wsFilter
+}
+
+// wsFilter — фильтр подписки. Address и EventType — для events; From и To — отправитель/получатель транзакции или события.
+type wsFilter struct {
+	Address   []string
+	EventType []string
+	From      string
+	To        string
+}
+
+// wsNotification — уведомление по подписке (как eth_subscription: params.subscription — id из gnd_subscribe).
+type wsNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  struct {
+		Subscription string      `json:"subscription"`
+		Result       interface{} `json:"result"`
+	} `json:"params"`
+}
+
+var hub = newHub()
+
+func newHub() *Hub {
+	return &Hub{clients: make(map[*Client]bool)}
 }
 
 // Запуск WebSocket сервера
 func StartWebSocketServer(blockchain *core.Blockchain, mempool *core.Mempool, cfg *core.Config) {
-	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		conn, err := upgrader.Upgrade(w, r, nil)
-		if err != nil {
-			log.Printf("Ошибка обновления соединения: %v", err)
-			return
-		}
-		defer conn.Close()
-
-		// Регистрация клиента
-		client := &Client{
-			hub:           hub,
-			conn:          conn,
-			send:          make(chan []byte, 256),
-			subscriptions: make(map[string]bool),
-		}
-		hub.register <- client
-
-		// Запуск горутин для чтения и записи
-		go client.writePump()
-		go client.readPump(blockchain, mempool)
-	})
+	// Новые блоки (с событиями контрактов из квитанций), реорганизации цепи и транзакции мемпула рассылаются подписчикам
+	if blockchain != nil {
+		blockchain.OnBlock(hub.publishBlock)
+		blockchain.OnReorg(hub.publishReorg)
+	}
+	if mempool != nil {
+		mempool.OnTx(hub.publishTx)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", hub.serveWS)
 
 	// Извлекаем порт из адреса
 	_, port, err := net.SplitHostPort(cfg.Server.WS.WSAddr)
@@ -131,21 +148,256 @@ func StartWebSocketServer(blockchain *core.Blockchain, mempool *core.Mempool, cf
 
 	addr := fmt.Sprintf("0.0.0.0:%s", port)
 	log.Printf("=== WebSocket Server запущен на %s ===", addr)
-	log.Println("Доступные подписки:")
+	log.Println("Доступные подписки (gnd_subscribe):")
 	log.Println("  - blocks: новые блоки")
-	log.Println("  - transactions: новые транзакции")
-	log.Println("  - events: события контрактов")
+	log.Println("  - transactions: новые транзакции (фильтр from/to)")
+	log.Println("  - events: события контрактов (фильтр address/event_type/from/to)")
+	log.Println("  - reorgs: реорганизации цепи (отменённые и новые блоки)")
 	log.Println("===============================")
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	if err := http.ListenAndServe(addr, mux); err != nil {
 		log.Printf("[WebSocket] Ошибка запуска: %v. REST и RPC продолжают работать. Освободите порт (см. docs/deployment-server.md) и перезапустите ноду для включения WebSocket.", err)
 	}
 }
 
-// Обработка сообщений от клиента
-func (c *Client) readPump(_ *core.Blockchain, _ *core.Mempool) {
+// serveWS принимает WebSocket-соединение и регистрирует клиента в хабе.
+func (h *Hub) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Ошибка обновления соединения: %v", err)
+		return
+	}
+	client := &Client{
+		hub:           h,
+		conn:          conn,
+		send:          make(chan []byte, 256),
+		subscriptions: make(map[string]*wsSubscription),
+	}
+	h.register(client)
+
+	// Запуск горутин для чтения и записи; соединение закрывает readPump/writePump
+	go client.writePump()
+	go client.readPump()
+}
+
+func (h *Hub) register(c *Client) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.clients[c] = true
+}
+
+func (h *Hub) unregister(c *Client) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.clients[c] {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+// publish отправляет payload всем подпискам типа kind, для которых match возвращает true.
+// Медленному клиенту (полный буфер) уведомление не доставляется.
+func (h *Hub) publish(kind string, payload interface{}, match func(f *wsFilter) bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for c := range h.clients {
+		for _, sub := range c.matching(kind, match) {
+			var n wsNotification
+			n.JSONRPC = "2.0"
+			n.Method = "gnd_subscription"
+			n.Params.Subscription = sub.ID
+			n.Params.Result = payload
+			msg, err := json.Marshal(n)
+			if err != nil {
+				log.Printf("WS: ошибка сериализации уведомления: %v", err)
+				return
+			}
+			select {
+			case c.send <- msg:
+			default:
+				log.Printf("WS: буфер клиента переполнен, уведомление %s пропущено", sub.ID)
+			}
+		}
+	}
+}
+
+// publishBlock рассылает новый блок подписчикам blocks и события контрактов из квитанций — подписчикам events.
+func (h *Hub) publishBlock(block *core.Block, receipts []*core.Receipt) {
+	h.publish(wsKindBlocks, wsBlockPayload(block), func(*wsFilter) bool { return true })
+	for _, r := range receipts {
+		for _, l := range r.Logs {
+			h.publishEvent(wsLogEvent(block, r, l))
+		}
+	}
+}
+
+// publishReorg рассылает реорганизацию цепи подписчикам reorgs.
+func (h *Hub) publishReorg(ev *core.ReorgEvent) {
+	h.publish(wsKindReorgs, wsReorgPayload(ev), func(*wsFilter) bool { return true })
+}
+
+// publishTx рассылает новую транзакцию мемпула подписчикам transactions (фильтр from/to).
+func (h *Hub) publishTx(tx *core.Transaction) {
+	h.publish(wsKindTransactions, transactionResponse(tx), func(f *wsFilter) bool {
+		return matchAddr(f.From, tx.Sender.String()) && matchAddr(f.To, tx.Recipient.String())
+	})
+}
+
+// publishEvent рассылает событие контракта подписчикам events (фильтр address/event_type/from/to по полям contract/type/from/to).
+func (h *Hub) publishEvent(event map[string]interface{}) {
+	field := func(k string) string {
+		s, _ := event[k].(string)
+		return s
+	}
+	h.publish(wsKindEvents, event, func(f *wsFilter) bool {
+		if len(f.Address) > 0 && !containsFold(f.Address, field("contract")) {
+			return false
+		}
+		if len(f.EventType) > 0 && !containsFold(f.EventType, field("type")) {
+			return false
+		}
+		return matchAddr(f.From, field("from")) && matchAddr(f.To, field("to"))
+	})
+}
+
+// matching возвращает подписки клиента типа kind, подходящие под фильтр.
+func (c *Client) matching(kind string, match func(f *wsFilter) bool) []*wsSubscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var subs []*wsSubscription
+	for _, sub := range c.subscriptions {
+		if sub.Kind == kind && match(&sub.Filter) {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+func wsBlockPayload(b *core.Block) map[string]interface{} {
+	hashes := make([]string, 0, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		hashes = append(hashes, tx.Hash)
+	}
+	return map[string]interface{}{
+		"number":       b.Index,
+		"hash":         b.Hash,
+		"parent_hash":  b.PrevHash,
+		"timestamp":    b.Timestamp,
+		"miner":        b.Miner,
+		"tx_count":     len(b.Transactions),
+		"gas_used":     b.GasUsed,
+		"gas_limit":    b.GasLimit,
+		"state_root":   b.StateRoot,
+		"base_fee":     b.BaseFee,
+		"transactions": hashes,
+	}
+}
+
+// wsReorgPayload формирует уведомление о реорганизации: прежняя и новая вершины, общий предок, хеши отменённых
+// и новых блоков, транзакции, вернувшиеся в мемпул.
+func wsReorgPayload(ev *core.ReorgEvent) map[string]interface{} {
+	hashes := func(blocks []*core.Block) []string {
+		out := make([]string, 0, len(blocks))
+		for _, b := range blocks {
+			out = append(out, b.Hash)
+		}
+		return out
+	}
+	returned := make([]string, 0, len(ev.Returned))
+	for _, tx := range ev.Returned {
+		returned = append(returned, tx.Hash)
+	}
+	return map[string]interface{}{
+		"old_head":              map[string]interface{}{"number": ev.OldHead.Index, "hash": ev.OldHead.Hash},
+		"new_head":              map[string]interface{}{"number": ev.NewHead.Index, "hash": ev.NewHead.Hash},
+		"common_ancestor":       map[string]interface{}{"number": ev.CommonAncestor.Index, "hash": ev.CommonAncestor.Hash},
+		"depth":                 len(ev.Orphaned),
+		"orphaned":              hashes(ev.Orphaned),
+		"adopted":               hashes(ev.Adopted),
+		"returned_transactions": returned,
+	}
+}
+
+// wsLogEvent формирует событие из лога квитанции: type — topic0, from/to — отправитель и получатель транзакции.
+func wsLogEvent(b *core.Block, r *core.Receipt, l *core.ReceiptLog) map[string]interface{} {
+	eventType := ""
+	if len(l.Topics) > 0 {
+		eventType = l.Topics[0]
+	}
+	return map[string]interface{}{
+		"contract":     l.Address,
+		"type":         eventType,
+		"topics":       l.Topics,
+		"data":         l.Data,
+		"from":         r.From,
+		"to":           r.To,
+		"tx_hash":      r.TxHash,
+		"block_number": b.Index,
+		"block_hash":   b.Hash,
+		"log_index":    l.LogIndex,
+	}
+}
+
+func matchAddr(want, got string) bool {
+	return want == "" || strings.EqualFold(want, got)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWSFilter разбирает фильтр подписки: address и event_type — строка или массив; адреса 0x… переводятся в адреса ГАНИМЕД.
+func parseWSFilter(raw json.RawMessage) (wsFilter, error) {
+	var f wsFilter
+	if len(raw) == 0 || string(raw) == "null" {
+		return f, nil
+	}
+	var p struct {
+		Address   json.RawMessage `json:"address"`
+		EventType json.RawMessage `json:"event_type"`
+		From      string          `json:"from"`
+		To        string          `json:"to"`
+	}
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return f, fmt.Errorf("неверный фильтр: %w", err)
+	}
+	addresses, err := stringOrList(p.Address)
+	if err != nil {
+		return f, fmt.Errorf("address: %w", err)
+	}
+	for _, a := range addresses {
+		gnd, err := gndAddress(a)
+		if err != nil {
+			return f, err
+		}
+		f.Address = append(f.Address, gnd)
+	}
+	if f.EventType, err = stringOrList(p.EventType); err != nil {
+		return f, fmt.Errorf("event_type: %w", err)
+	}
+	for _, addr := range []struct {
+		src string
+		dst *string
+	}{{p.From, &f.From}, {p.To, &f.To}} {
+		if addr.src == "" {
+			continue
+		}
+		if *addr.dst, err = gndAddress(addr.src); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// Обработка сообщений от клиента: gnd_subscribe [тип, фильтр?] → id подписки; gnd_unsubscribe [id] → true/false.
+func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.unregister(c)
 		c.conn.Close()
 	}()
 
@@ -164,46 +416,89 @@ func (c *Client) readPump(_ *core.Blockchain, _ *core.Mempool) {
 			}
 			break
 		}
+		c.handleMessage(message)
+	}
+}
 
-		// Обработка JSON-RPC запроса
-		var request struct {
-			JSONRPC string        `json:"jsonrpc"`
-			ID      interface{}   `json:"id"`
-			Method  string        `json:"method"`
-			Params  []interface{} `json:"params"`
-		}
+// handleMessage обрабатывает JSON-RPC запрос клиента и ставит ответ в очередь отправки.
+func (c *Client) handleMessage(message []byte) {
+	var request rpcRequest
+	var params []json.RawMessage
+	if err := json.Unmarshal(message, &request); err != nil {
+		c.reply(errorResponse(nil, &rpcError{Code: rpcParseError, Message: "Ошибка разбора JSON"}))
+		return
+	}
+	if len(request.Params) > 0 && json.Unmarshal(request.Params, &params) != nil {
+		c.reply(errorResponse(request.ID, invalidParams("params должен быть массивом")))
+		return
+	}
 
-		if err := json.Unmarshal(message, &request); err != nil {
-			c.send <- []byte(`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Ошибка разбора JSON"},"id":null}`)
-			continue
+	switch request.Method {
+	case "gnd_subscribe":
+		var kind string
+		if len(params) < 1 || json.Unmarshal(params[0], &kind) != nil {
+			c.reply(errorResponse(request.ID, invalidParams("Неверное количество параметров")))
+			return
 		}
-
-		// Обработка методов
-		switch request.Method {
-		case "gnd_subscribe":
-			if len(request.Params) < 1 {
-				c.send <- []byte(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Неверное количество параметров"},"id":null}`)
-				continue
-			}
-			subscription := request.Params[0].(string)
-			c.subscriptions[subscription] = true
-			c.send <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","result":"%s","id":%v}`, subscription, request.ID))
-
-		case "gnd_unsubscribe":
-			if len(request.Params) < 1 {
-				c.send <- []byte(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Неверное количество параметров"},"id":null}`)
-				continue
-			}
-			subscription := request.Params[0].(string)
-			delete(c.subscriptions, subscription)
-			c.send <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","result":true,"id":%v}`, request.ID))
-
-		default:
-			c.send <- []byte(fmt.Sprintf(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Метод не найден"},"id":%v}`, request.ID))
+		canonical, ok := wsKindAliases[kind]
+		if !ok {
+			c.reply(errorResponse(request.ID, invalidParams("Неизвестный тип подписки: %s (blocks, transactions, events, reorgs)", kind)))
+			return
+		}
+		var filterRaw json.RawMessage
+		if len(params) > 1 {
+			filterRaw = params[1]
+		}
+		filter, err := parseWSFilter(filterRaw)
+		if err != nil {
+			c.reply(errorResponse(request.ID, invalidParams("%v", err)))
+			return
 		}
+		sub := &wsSubscription{ID: newSubscriptionID(), Kind: canonical, Filter: filter}
+		c.mu.Lock()
+		c.subscriptions[sub.ID] = sub
+		c.mu.Unlock()
+		c.reply(&rpcResponse{JSONRPC: "2.0", ID: request.ID, Result: sub.ID})
+
+	case "gnd_unsubscribe":
+		var id string
+		if len(params) < 1 || json.Unmarshal(params[0], &id) != nil {
+			c.reply(errorResponse(request.ID, invalidParams("Неверное количество параметров")))
+			return
+		}
+		c.mu.Lock()
+		_, existed := c.subscriptions[id]
+		delete(c.subscriptions, id)
+		c.mu.Unlock()
+		c.reply(&rpcResponse{JSONRPC: "2.0", ID: request.ID, Result: existed})
+
+	default:
+		c.reply(errorResponse(request.ID, &rpcError{Code: rpcMethodNotFound, Message: "Метод не найден"}))
+	}
+}
+
+// reply ставит ответ в очередь отправки клиента (при переполненном буфере ответ отбрасывается).
+func (c *Client) reply(resp *rpcResponse) {
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	msg, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+		log.Println("WS: буфер клиента переполнен, ответ пропущен")
 	}
 }
 
+func newSubscriptionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "0x" + hex.EncodeToString(b)
+}
+
 // Отправка сообщений клиенту
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
@@ -240,58 +535,38 @@ func (c *Client) writePump() {
 	}
 }
 
-// Отправка сообщения всем клиентам
-func wsBroadcast(msg WSMessage) {
-	wsManager.lock.Lock()
-	defer wsManager.lock.Unlock()
-	for conn := range wsManager.clients {
-		if err := conn.WriteJSON(msg); err != nil {
-			log.Println("WS: ошибка отправки:", err)
-			if err := conn.Close(); err != nil {
-				log.Println("WS: ошибка при закрытии соединения:", err)
-			}
-			delete(wsManager.clients, conn)
-		}
-	}
-}
-
-// Периодически слать последний блок (или по событию)
+// broadcastBlocks отправляет последний блок цепи подписчикам blocks.
 func broadcastBlocks(blockchain *core.Blockchain) {
 	if blockchain == nil {
 		log.Println("Blockchain is nil")
 		return
 	}
-
-	hubMutex.RLock()
-	defer hubMutex.RUnlock()
-
-	for client := range clients {
-		if client.conn != nil {
-			block, err := blockchain.LatestBlock()
-			if err != nil {
-				log.Printf("Error getting latest block: %v", err)
-				continue
-			}
-
-			if err := client.conn.WriteJSON(block); err != nil {
-				log.Printf("Error sending block to client: %v", err)
-				client.conn.Close()
-				delete(clients, client)
-			}
-		}
+	block, err := blockchain.LatestBlock()
+	if err != nil {
+		log.Printf("Error getting latest block: %v", err)
+		return
 	}
+	hub.publishBlock(block, nil)
 }
 
-// Пример отправки новой транзакции в WebSocket
+// NotifyNewTx отправляет транзакцию подписчикам transactions.
 func NotifyNewTx(tx *core.Transaction) {
-	msg := WSMessage{Type: "tx", Data: tx}
-	wsBroadcast(msg)
+	if tx == nil {
+		return
+	}
+	hub.publishTx(tx)
 }
 
-// Пример отправки события контракта
+// NotifyContractEvent отправляет событие контракта подписчикам events. Поля contract, type, from, to используются фильтрами.
 func NotifyContractEvent(event interface{}) {
-	msg := WSMessage{Type: "event", Data: event}
-	wsBroadcast(msg)
+	m, ok := event.(map[string]interface{})
+	if !ok {
+		raw, err := json.Marshal(event)
+		if err != nil || json.Unmarshal(raw, &m) != nil || m == nil {
+			return
+		}
+	}
+	hub.publishEvent(m)
 }
 
 func SaveContractEventToDB(ctx context.Context, pool *pgxpool.Pool, event WSMessage) error {
@@ -4,6 +4,7 @@ package api
 import (
 	"GND/core"
 	"context"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -83,3 +84,142 @@ func TestBroadcastBlocks_withBlockchain(t *testing.T) {
 	}
 	broadcastBlocks(bc)
 }
+
+// wsTestClient подключается к хабу h и возвращает соединение.
+func wsTestClient(t *testing.T, h *Hub) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(h.serveWS))
+	t.Cleanup(srv.Close)
+	header := http.Header{"Origin": []string{"http://localhost:8182"}}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+srv.URL[4:], header)
+	if err != nil {
+		t.Fatalf("WebSocket dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// wsSubscribe отправляет gnd_subscribe и возвращает id подписки.
+func wsSubscribe(t *testing.T, conn *websocket.Conn, params ...interface{}) string {
+	t.Helper()
+	if err := conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "gnd_subscribe", "params": params}); err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Result string    `json:"result"`
+		Error  *rpcError `json:"error"`
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(&resp); err != nil || resp.Error != nil || resp.Result == "" {
+		t.Fatalf("gnd_subscribe %v: err=%v resp=%+v", params, err, resp)
+	}
+	return resp.Result
+}
+
+type wsTestNotification struct {
+	Method string `json:"method"`
+	Params struct {
+		Subscription string                 `json:"subscription"`
+		Result       map[string]interface{} `json:"result"`
+	} `json:"params"`
+}
+
+func TestHub_SubscriptionsWithFilters(t *testing.T) {
+	h := newHub()
+	genesis := &core.Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: core.DefaultBlockGasLimit, Consensus: "poa", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := core.NewBlockchain(genesis, nil)
+	mp := core.NewMempool()
+	bc.OnBlock(h.publishBlock)
+	mp.OnTx(h.publishTx)
+
+	conn := wsTestClient(t, h)
+	blocksID := wsSubscribe(t, conn, "newHeads")
+	txID := wsSubscribe(t, conn, "transactions", map[string]string{"from": "GN_ws_sender_one"})
+	eventsID := wsSubscribe(t, conn, "events", map[string]interface{}{"address": []string{"GNDct00112233445566778899aabbccddeeff"}, "event_type": "Transfer"})
+
+	// Транзакция другого отправителя не проходит фильтр, подходящая — приходит
+	_ = mp.Add(&core.Transaction{Sender: "GN_ws_sender_two", Recipient: "GN_ws_recipient", Value: big.NewInt(1), Hash: "ws_tx_other"})
+	_ = mp.Add(&core.Transaction{Sender: "GN_ws_sender_one", Recipient: "GN_ws_recipient", Value: big.NewInt(1), Hash: "ws_tx_match"})
+	h.publishEvent(map[string]interface{}{"contract": "GNDct00112233445566778899aabbccddeeff", "type": "Approval"})
+	h.publishEvent(map[string]interface{}{"contract": "GNDct00112233445566778899aabbccddeeff", "type": "Transfer", "from": "a", "to": "b"})
+	block := &core.Block{Index: 1, PrevHash: genesis.Hash, Timestamp: time.Now(), Miner: "miner", GasLimit: core.DefaultBlockGasLimit, Consensus: "poa", Status: "finalized",
+		MerkleRoot: core.ComputeMerkleRoot(nil)}
+	if err := bc.ExecuteBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]map[string]interface{})
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for i := 0; i < 3; i++ {
+		var n wsTestNotification
+		if err := conn.ReadJSON(&n); err != nil {
+			t.Fatalf("ожидалось 3 уведомления, получено %d: %v", i, err)
+		}
+		if n.Method != "gnd_subscription" {
+			t.Errorf("метод уведомления: %s", n.Method)
+		}
+		got[n.Params.Subscription] = append(got[n.Params.Subscription], n.Params.Result)
+	}
+	if len(got[txID]) != 1 || got[txID][0]["hash"] != "ws_tx_match" {
+		t.Errorf("transactions: ожидалась только ws_tx_match, получено %v", got[txID])
+	}
+	if len(got[eventsID]) != 1 || got[eventsID][0]["type"] != "Transfer" {
+		t.Errorf("events: ожидалось только Transfer, получено %v", got[eventsID])
+	}
+	if len(got[blocksID]) != 1 || got[blocksID][0]["hash"] != block.Hash {
+		t.Errorf("blocks: ожидался блок %s, получено %v", block.Hash, got[blocksID])
+	}
+
+	// После отписки уведомления не приходят
+	if err := conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "gnd_unsubscribe", "params": []string{blocksID}}); err != nil {
+		t.Fatal(err)
+	}
+	var unsub struct {
+		Result bool `json:"result"`
+	}
+	if err := conn.ReadJSON(&unsub); err != nil || !unsub.Result {
+		t.Fatalf("gnd_unsubscribe: err=%v result=%v", err, unsub.Result)
+	}
+	broadcastBlocks(bc) // глобальный хаб — клиента теста там нет
+	h.publishBlock(block, nil)
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	var extra wsTestNotification
+	if err := conn.ReadJSON(&extra); err == nil {
+		t.Errorf("после отписки пришло уведомление: %+v", extra)
+	}
+}
+
+func TestHub_ReorgNotification(t *testing.T) {
+	h := newHub()
+	conn := wsTestClient(t, h)
+	reorgsID := wsSubscribe(t, conn, "reorgs")
+
+	ancestor := &core.Block{Index: 4, Hash: "ancestor"}
+	oldHead, newHead := &core.Block{Index: 5, Hash: "old_5"}, &core.Block{Index: 6, Hash: "new_6"}
+	h.publishReorg(&core.ReorgEvent{CommonAncestor: ancestor, OldHead: oldHead, NewHead: newHead,
+		Orphaned: []*core.Block{oldHead}, Adopted: []*core.Block{{Index: 5, Hash: "new_5"}, newHead},
+		Returned: []*core.Transaction{{Hash: "tx_returned"}}})
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var n wsTestNotification
+	if err := conn.ReadJSON(&n); err != nil {
+		t.Fatal(err)
+	}
+	r := n.Params.Result
+	if n.Params.Subscription != reorgsID || r["depth"] != float64(1) {
+		t.Fatalf("уведомление о реорганизации: %+v", n)
+	}
+	if head, _ := r["new_head"].(map[string]interface{}); head["hash"] != "new_6" || head["number"] != float64(6) {
+		t.Errorf("new_head: %v", r["new_head"])
+	}
+	if adopted, _ := r["adopted"].([]interface{}); len(adopted) != 2 || adopted[0] != "new_5" {
+		t.Errorf("adopted: %v", r["adopted"])
+	}
+	if returned, _ := r["returned_transactions"].([]interface{}); len(returned) != 1 || returned[0] != "tx_returned" {
+		t.Errorf("returned_transactions: %v", r["returned_transactions"])
+	}
+}
@@ -81,28 +81,6 @@ func (c *BaseConsensus) Type() string {
 	return "base"
 }
 
-// Пример реализации PoS
-type PoSConsensus struct {
-	BaseConsensus
-	// дополнительные поля для PoS
-}
-
-func (p *PoSConsensus) run() {
-	for {
-		select {
-		case <-p.stopCh:
-			p.wg.Done()
-			return
-		default:
-			// Здесь логика PoS
-		}
-	}
-}
-
-func (p *PoSConsensus) Type() string {
-	return "pos"
-}
-
 // Пример реализации PoA
 type PoAConsensus struct {
 	BaseConsensus
@@ -0,0 +1,300 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// consensus/finality.go — BFT-финальность: голоса prevote/precommit валидаторов, justified при >2/3 prevote, finalized при >2/3 precommit.
+package consensus
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+
+	"GND/core"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Voter — валидатор с правом голоса в слое финальности; Weight — стейк (PoS) или 1 (PoA).
+type Voter struct {
+	Address string
+	PubKey  *secp256k1.PublicKey
+	Weight  *big.Int
+}
+
+// VoterSet — источник набора голосующих валидаторов (движок PoA или PoS).
+type VoterSet interface {
+	Voters() []Voter
+}
+
+// FinalityStatus — состояние финальности для API.
+type FinalityStatus struct {
+	Head            uint64 `json:"head"`
+	JustifiedHeight uint64 `json:"justified_height"`
+	JustifiedHash   string `json:"justified_hash"`
+	FinalizedHeight uint64 `json:"finalized_height"`
+	FinalizedHash   string `json:"finalized_hash"`
+	FinalityLag     uint64 `json:"finality_lag"`
+}
+
+// finalityRound — голоса за блоки одной высоты: тип голоса → валидатор → хеш блока.
+type finalityRound struct {
+	votes map[string]map[string]string
+}
+
+// Finality — слой финальности поверх движка консенсуса. Валидатор голосует prevote за каждый новый блок и precommit,
+// когда блок набрал более 2/3 веса prevote (justified). Блок с более 2/3 веса precommit и все его предки — finalized.
+// Повторный голос валидатора того же типа за другой блок той же высоты отклоняется (двойное голосование).
+type Finality struct {
+	mu        sync.Mutex
+	bc        *core.Blockchain
+	voters    VoterSet
+	key       *secp256k1.PrivateKey // ключ валидатора ноды; nil — нода не голосует
+	address   string
+	blocks    map[string]*core.Block // блоки выше финализированного по хешу
+	rounds    map[uint64]*finalityRound
+	finalized uint64
+	listeners []func(v *core.Vote)
+	logger    *log.Logger
+}
+
+// NewFinality создаёт слой финальности с набором голосующих voters. wallet — кошелёк валидатора ноды (может быть nil).
+func NewFinality(voters VoterSet, wallet *core.Wallet) *Finality {
+	f := &Finality{
+		voters: voters,
+		blocks: make(map[string]*core.Block),
+		rounds: make(map[uint64]*finalityRound),
+		logger: log.New(os.Stdout, "[Finality] ", log.LstdFlags),
+	}
+	if wallet != nil && wallet.PrivateKey != nil {
+		f.key = wallet.PrivateKey
+		f.address = string(wallet.Address)
+	}
+	return f
+}
+
+// Attach подключает слой к цепи: финализированной считается высота последнего блока со статусом finalized,
+// блоки выше неё ожидают голосов. Подписывается на новые блоки.
+func (f *Finality) Attach(bc *core.Blockchain) {
+	f.mu.Lock()
+	f.bc = bc
+	f.finalized = bc.FinalizedBlock().Index
+	for _, b := range bc.AllBlocks() {
+		if b.Index > f.finalized {
+			f.blocks[b.Hash] = b
+		}
+	}
+	f.mu.Unlock()
+	bc.Finality = f
+	bc.OnBlock(func(block *core.Block, _ []*core.Receipt) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.blocks[block.Hash] = block
+		f.castLocked(core.VotePrevote, block)
+	})
+}
+
+// OnVote регистрирует обработчик принятых голосов (в т.ч. собственных) — для рассылки другим нодам.
+// Вызывается под блокировкой слоя финальности: обработчик не должен блокироваться и вызывать AddVote.
+func (f *Finality) OnVote(l func(v *core.Vote)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listeners = append(f.listeners, l)
+}
+
+// AddVote принимает голос валидатора: проверяет право голоса, подпись и блок, учитывает вес и меняет статусы блоков.
+// Голос за уже финализированную высоту и повтор принятого голоса игнорируются.
+func (f *Finality) AddVote(v *core.Vote) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addVoteLocked(v)
+}
+
+func (f *Finality) addVoteLocked(v *core.Vote) error {
+	if f.bc == nil {
+		return errors.New("слой финальности не подключён к цепи")
+	}
+	if v.Type != core.VotePrevote && v.Type != core.VotePrecommit {
+		return fmt.Errorf("неизвестный тип голоса %q", v.Type)
+	}
+	if v.Height <= f.finalized {
+		return nil
+	}
+	voters := f.voters.Voters()
+	var voter *Voter
+	for i := range voters {
+		if voters[i].Address == v.Validator {
+			voter = &voters[i]
+			break
+		}
+	}
+	if voter == nil {
+		return fmt.Errorf("%s не входит в набор валидаторов", v.Validator)
+	}
+	sigBytes, err := hex.DecodeString(v.Signature)
+	if err != nil {
+		return fmt.Errorf("подпись голоса: %w", err)
+	}
+	sig, err := ecdsa.ParseDERSignature(sigBytes)
+	if err != nil {
+		return fmt.Errorf("подпись голоса: %w", err)
+	}
+	if !sig.Verify(v.SigningHash(), voter.PubKey) {
+		return fmt.Errorf("подпись голоса не соответствует ключу валидатора %s", v.Validator)
+	}
+	round, ok := f.rounds[v.Height]
+	if ok {
+		if prev, ok := round.votes[v.Type][v.Validator]; ok {
+			if prev == v.BlockHash {
+				return nil
+			}
+			return fmt.Errorf("двойное голосование %s: %s уже проголосовал за %s на высоте %d", v.Type, v.Validator, prev, v.Height)
+		}
+	}
+	block, ok := f.blocks[v.BlockHash]
+	if !ok || block.Index != v.Height {
+		return fmt.Errorf("блок %s на высоте %d не найден", v.BlockHash, v.Height)
+	}
+	if round == nil {
+		round = &finalityRound{votes: map[string]map[string]string{core.VotePrevote: {}, core.VotePrecommit: {}}}
+		f.rounds[v.Height] = round
+	}
+	round.votes[v.Type][v.Validator] = v.BlockHash
+	for _, l := range f.listeners {
+		l(v)
+	}
+
+	if !f.supermajorityLocked(voters, round.votes[v.Type], v.BlockHash) {
+		return nil
+	}
+	switch v.Type {
+	case core.VotePrevote:
+		if block.Status == core.BlockStatusProposed {
+			for b := block; b != nil && b.Status == core.BlockStatusProposed; b = f.blocks[b.PrevHash] {
+				f.setStatusLocked(b, core.BlockStatusJustified)
+			}
+			f.castLocked(core.VotePrecommit, block)
+		}
+	case core.VotePrecommit:
+		f.finalizeLocked(block)
+	}
+	return nil
+}
+
+// supermajorityLocked сообщает, превышает ли вес голосов за hash 2/3 общего веса набора.
+func (f *Finality) supermajorityLocked(voters []Voter, votes map[string]string, hash string) bool {
+	total, signed := big.NewInt(0), big.NewInt(0)
+	for _, vt := range voters {
+		total.Add(total, vt.Weight)
+		if votes[vt.Address] == hash {
+			signed.Add(signed, vt.Weight)
+		}
+	}
+	if total.Sign() == 0 {
+		return false
+	}
+	return new(big.Int).Mul(signed, big.NewInt(3)).Cmp(new(big.Int).Mul(total, big.NewInt(2))) > 0
+}
+
+// castLocked подписывает и учитывает голос валидатора ноды, если нода входит в набор.
+func (f *Finality) castLocked(voteType string, block *core.Block) {
+	if f.key == nil || block.Index <= f.finalized || !f.isVoterLocked() {
+		return
+	}
+	v := &core.Vote{Type: voteType, Height: block.Index, BlockHash: block.Hash, Validator: f.address}
+	v.Signature = hex.EncodeToString(ecdsa.Sign(f.key, v.SigningHash()).Serialize())
+	if err := f.addVoteLocked(v); err != nil {
+		f.logger.Printf("Собственный голос %s за блок %d не учтён: %v", voteType, block.Index, err)
+	}
+}
+
+func (f *Finality) isVoterLocked() bool {
+	for _, vt := range f.voters.Voters() {
+		if vt.Address == f.address {
+			return true
+		}
+	}
+	return false
+}
+
+// finalizeLocked финализирует блок и его предков, обновляет метрики и удаляет голоса финализированных высот.
+func (f *Finality) finalizeLocked(block *core.Block) {
+	for b := block; b != nil && b.Index > f.finalized; b = f.blocks[b.PrevHash] {
+		if b.Status != core.BlockStatusFinalized {
+			f.setStatusLocked(b, core.BlockStatusFinalized)
+		}
+	}
+	f.finalized = block.Index
+	for hash, b := range f.blocks {
+		if b.Index <= f.finalized {
+			delete(f.blocks, hash)
+		}
+	}
+	for h := range f.rounds {
+		if h <= f.finalized {
+			delete(f.rounds, h)
+		}
+	}
+	head := f.bc.Height()
+	lag := uint64(0)
+	if head > f.finalized {
+		lag = head - f.finalized
+	}
+	core.GetMetrics().UpdateFinalityMetrics(f.finalized, lag)
+}
+
+func (f *Finality) setStatusLocked(block *core.Block, status string) {
+	if err := f.bc.SetBlockStatus(block, status); err != nil {
+		f.logger.Printf("Статус %s блока %d не сохранён: %v", status, block.Index, err)
+	}
+}
+
+// FinalityStatusOf возвращает состояние финальности цепи по статусам блоков.
+func FinalityStatusOf(bc *core.Blockchain) FinalityStatus {
+	justified, finalized := bc.JustifiedBlock(), bc.FinalizedBlock()
+	return FinalityStatus{
+		Head:            bc.Height(),
+		JustifiedHeight: justified.Index,
+		JustifiedHash:   justified.Hash,
+		FinalizedHeight: finalized.Index,
+		FinalizedHash:   finalized.Hash,
+		FinalityLag:     bc.FinalityLag(),
+	}
+}
+
+// Voters возвращает набор валидаторов PoA с равным весом (authority set); валидаторы без ключа не голосуют.
+func (p *PoA) Voters() []Voter {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make([]Voter, 0, len(p.validators))
+	for _, v := range p.validators {
+		if v.PubKey != nil {
+			out = append(out, Voter{Address: v.Address, PubKey: v.PubKey, Weight: big.NewInt(1)})
+		}
+	}
+	return out
+}
+
+// Voters возвращает валидаторов PoS со стейком (вес — стейк); пока стейка нет — генезис-валидатора с весом 1.
+func (p *PoS) Voters() []Voter {
+	if p.bc == nil || p.bc.Stakes == nil {
+		return nil
+	}
+	var out []Voter
+	for _, v := range p.bc.Stakes.Validators() {
+		if v.TotalStake.Sign() <= 0 {
+			continue
+		}
+		pub, err := parsePubKeyHex(v.PubKey)
+		if err != nil {
+			continue
+		}
+		out = append(out, Voter{Address: v.Address, PubKey: pub, Weight: v.TotalStake})
+	}
+	if len(out) == 0 && p.bootstrapKey != nil {
+		out = append(out, Voter{Address: p.bootstrapAddr, PubKey: p.bootstrapKey, Weight: big.NewInt(1)})
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package consensus
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"GND/core"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+func signVote(w *core.Wallet, voteType string, block *core.Block) *core.Vote {
+	v := &core.Vote{Type: voteType, Height: block.Index, BlockHash: block.Hash, Validator: string(w.Address)}
+	v.Signature = hex.EncodeToString(ecdsa.Sign(w.PrivateKey, v.SigningHash()).Serialize())
+	return v
+}
+
+func TestFinality_JustifyAndFinalizeBySupermajority(t *testing.T) {
+	genesis := &core.Block{Index: 0, Timestamp: time.Now().Add(-time.Minute), Miner: "miner", GasLimit: core.DefaultBlockGasLimit, Consensus: "poa", Status: core.BlockStatusFinalized}
+	genesis.Hash = genesis.CalculateHash()
+	bc := core.NewBlockchain(genesis, nil)
+
+	cfg := &core.ConsensusPoaConfig{RoundDuration: "1h"}
+	w1, v1 := newTestValidator(t, "GN_val1")
+	w2, v2 := newTestValidator(t, "GN_val2")
+	w3, v3 := newTestValidator(t, "GN_val3")
+	poa1, err := NewPoA(cfg, nil, genesis.Timestamp, w1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poa2, _ := NewPoA(cfg, nil, genesis.Timestamp, w2)
+	for _, p := range []*PoA{poa1, poa2} {
+		p.SetValidators([]PoAValidator{v1, v2, v3})
+	}
+	poa1.Attach(bc)
+	NewFinality(poa1, w1).Attach(bc)
+
+	// слот 0 — очередь GN_val1; нода голосует prevote сама, 1 из 3 голосов — не большинство
+	if err := bc.ProduceNextBlock(bc.Mempool, "GN_val1", 10); err != nil {
+		t.Fatal(err)
+	}
+	b1, _ := bc.LatestBlock()
+	if b1.Status != core.BlockStatusProposed || b1.IsFinalized {
+		t.Fatalf("новый блок должен быть proposed, получено %q", b1.Status)
+	}
+
+	if err := bc.Finality.AddVote(signVote(w2, core.VotePrevote, b1)); err != nil {
+		t.Fatal(err)
+	}
+	if b1.Status != core.BlockStatusProposed {
+		t.Error("2 из 3 голосов не больше 2/3 — блок должен остаться proposed")
+	}
+	forged := signVote(w3, core.VotePrevote, b1)
+	forged.Validator = "GN_val2"
+	if err := bc.Finality.AddVote(forged); err == nil {
+		t.Error("голос, подписанный чужим ключом, должен отклоняться")
+	}
+	if err := bc.Finality.AddVote(signVote(&core.Wallet{PrivateKey: w3.PrivateKey, Address: "GN_val4"}, core.VotePrevote, b1)); err == nil {
+		t.Error("голос не из набора валидаторов должен отклоняться")
+	}
+	if err := bc.Finality.AddVote(signVote(w3, core.VotePrevote, b1)); err != nil {
+		t.Fatal(err)
+	}
+	if b1.Status != core.BlockStatusJustified || bc.JustifiedBlock() != b1 {
+		t.Fatalf("3 из 3 prevote — блок должен быть justified, получено %q", b1.Status)
+	}
+	other := &core.Block{Index: 1, Hash: "other"}
+	if err := bc.Finality.AddVote(signVote(w2, core.VotePrevote, other)); err == nil || !strings.Contains(err.Error(), "двойное голосование") {
+		t.Errorf("второй prevote за другой блок той же высоты должен отклоняться, получено %v", err)
+	}
+
+	// слот 1 — очередь GN_val2; блок 2 финализируется вместе с блоком 1
+	b2 := &core.Block{Index: 2, PrevHash: b1.Hash, Timestamp: genesis.Timestamp.Add(time.Hour + time.Second), Miner: "GN_val2", GasLimit: core.DefaultBlockGasLimit, Consensus: "poa",
+		MerkleRoot: core.ComputeMerkleRoot(nil)}
+	if err := bc.ExecuteBlock(b2); err != nil {
+		t.Fatal(err)
+	}
+	if err := poa2.Seal(b2); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(b2); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []*core.Wallet{w2, w3} {
+		if err := bc.Finality.AddVote(signVote(w, core.VotePrevote, b2)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bc.Finality.AddVote(signVote(w2, core.VotePrecommit, b2)); err != nil {
+		t.Fatal(err)
+	}
+	if b2.Status != core.BlockStatusJustified || bc.FinalityLag() != 2 {
+		t.Fatalf("2 из 3 precommit — блок должен остаться justified: %q, отставание %d", b2.Status, bc.FinalityLag())
+	}
+	if err := bc.Finality.AddVote(signVote(w3, core.VotePrecommit, b2)); err != nil {
+		t.Fatal(err)
+	}
+	if b2.Status != core.BlockStatusFinalized || b1.Status != core.BlockStatusFinalized || !b1.IsFinalized {
+		t.Fatalf("блок 2 и его предок должны быть finalized: %q, %q", b2.Status, b1.Status)
+	}
+	if bc.FinalizedBlock() != b2 || bc.FinalityLag() != 0 || core.GetMetrics().ConsensusMetrics.FinalizedHeight != 2 {
+		t.Errorf("финализированный блок %d, отставание %d", bc.FinalizedBlock().Index, bc.FinalityLag())
+	}
+	if err := bc.Finality.AddVote(signVote(w3, core.VotePrecommit, b1)); err != nil {
+		t.Errorf("голос за финализированную высоту должен игнорироваться: %v", err)
+	}
+}
@@ -1,40 +1,316 @@
 // | KB @CerberRus00 - Nexus Invest Team
+// consensus/poa.go — PoA: набор валидаторов из poa_validators, очередь предлагающих по слотам round_duration, подпись и проверка блоков.
 package consensus
 
 import (
-	"GND/core"
+	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
 	"time"
+
+	"GND/core"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// Объявление типа PoA
+// DefaultMaxTxsPerBlock — сколько транзакций мемпула валидатор включает в блок своего слота.
+const DefaultMaxTxsPerBlock = 100
+
+// PoAValidator — валидатор PoA: адрес и публичный ключ secp256k1 (validators.pubkey, hex).
+type PoAValidator struct {
+	ID      int64
+	Address string
+	PubKey  *secp256k1.PublicKey
+}
+
+// PoA — движок Proof-of-Authority. Время делится на слоты длиной round_duration от времени генезиса;
+// блок слота предлагает валидатор validators[slot % n] (round-robin) и подписывает SealHash своим ключом.
+// Реализует core.ConsensusEngine: AddBlock проверяет подпись и очередь предлагающего для каждого блока.
 type PoA struct {
-	running bool
+	mu         sync.RWMutex
+	validators []PoAValidator
+	pool       *pgxpool.Pool
+	round      time.Duration
+	genesis    time.Time
+	key        *secp256k1.PrivateKey // ключ валидатора ноды; nil — нода только проверяет блоки
+	address    string
+	MaxTxs     int
+
+	// Учёт нарушений (poa_bans.go): предупреждения и баны по адресу валидатора, история событий
+	warningsForBan int
+	banBlocks      uint64
+	maxBansPercent int
+	penalties      map[string]*PoAValidatorStatus
+	history        []PoAEvent
+	invalidSlot    map[string]int64 // последний слот, за который валидатор получил invalid_proposal
+	tipSlot        int64            // слот последнего принятого блока; -1 — генезис или неизвестно
+	tipHeight      uint64           // высота последнего принятого блока
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+	logger *log.Logger
+}
+
+// NewPoA создаёт движок PoA. genesisTime — время генезис-блока (начало отсчёта слотов),
+// wallet — кошелёк валидатора ноды (nil или без приватного ключа — нода не предлагает блоки).
+func NewPoA(cfg *core.ConsensusPoaConfig, pool *pgxpool.Pool, genesisTime time.Time, wallet *core.Wallet) (*PoA, error) {
+	if cfg == nil {
+		return nil, errors.New("конфиг PoA не задан")
+	}
+	round, err := time.ParseDuration(cfg.RoundDuration)
+	if err != nil || round <= 0 {
+		return nil, fmt.Errorf("неверный round_duration %q", cfg.RoundDuration)
+	}
+	p := &PoA{
+		pool:           pool,
+		round:          round,
+		genesis:        genesisTime,
+		MaxTxs:         DefaultMaxTxsPerBlock,
+		warningsForBan: cfg.WarningsForBan,
+		maxBansPercent: cfg.MaxBansPercentage,
+		penalties:      make(map[string]*PoAValidatorStatus),
+		invalidSlot:    make(map[string]int64),
+		tipSlot:        -1,
+		logger:         log.New(os.Stdout, "[PoA] ", log.LstdFlags),
+	}
+	if cfg.BanDurationBlocks > 0 {
+		p.banBlocks = uint64(cfg.BanDurationBlocks)
+	}
+	if wallet != nil && wallet.PrivateKey != nil {
+		p.key = wallet.PrivateKey
+		p.address = string(wallet.Address)
+	}
+	return p, nil
+}
+
+// LoadValidators загружает набор валидаторов из poa_validators (активные записи validators, по возрастанию id).
+// Если таблица пуста, а у ноды есть ключ, единственным валидатором считается сама нода.
+// Предупреждения и бан валидатора восстанавливаются из poa_metadata при первой загрузке.
+func (p *PoA) LoadValidators(ctx context.Context) error {
+	var list []PoAValidator
+	saved := make(map[string]PoAValidatorStatus)
+	if p.pool != nil {
+		rows, err := p.pool.Query(ctx, `
+			SELECT v.id, v.address, COALESCE(v.pubkey, ''),
+				COALESCE((pv.poa_metadata->>'warnings')::int, 0),
+				COALESCE((pv.poa_metadata->>'missed_slots')::bigint, 0),
+				COALESCE((pv.poa_metadata->>'invalid_proposals')::bigint, 0),
+				COALESCE((pv.poa_metadata->>'banned_until')::bigint, 0)
+			FROM poa_validators pv
+			JOIN validators v ON v.id = pv.validator_id
+			WHERE COALESCE(v.status, 'active') = 'active'
+			ORDER BY v.id`)
+		if err != nil {
+			return fmt.Errorf("загрузка poa_validators: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var v PoAValidator
+			var pubHex string
+			var st PoAValidatorStatus
+			if err := rows.Scan(&v.ID, &v.Address, &pubHex, &st.Warnings, &st.MissedSlots, &st.InvalidProposals, &st.BannedUntil); err != nil {
+				return err
+			}
+			saved[v.Address] = st
+			pub, err := parsePubKeyHex(pubHex)
+			if err != nil {
+				p.logger.Printf("Валидатор %s пропущен: неверный pubkey: %v", v.Address, err)
+				continue
+			}
+			v.PubKey = pub
+			list = append(list, v)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	if len(list) == 0 && p.key != nil {
+		p.logger.Printf("poa_validators пуст — единственный валидатор: нода %s", p.address)
+		list = []PoAValidator{{Address: p.address, PubKey: p.key.PubKey()}}
+	}
+	if len(list) == 0 {
+		return errors.New("набор валидаторов PoA пуст")
+	}
+	p.SetValidators(list)
+	p.restorePenalties(saved)
+	return nil
+}
+
+// SetValidators задаёт набор валидаторов (порядок определяет очередь round-robin).
+func (p *PoA) SetValidators(list []PoAValidator) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.validators = append([]PoAValidator(nil), list...)
+}
+
+// Validators возвращает текущий набор валидаторов.
+func (p *PoA) Validators() []PoAValidator {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]PoAValidator(nil), p.validators...)
 }
 
-// Конструктор
-func NewPoA() *PoA {
-	return &PoA{}
+// Slot возвращает номер слота для момента t (отсчёт от времени генезиса); до генезиса — -1.
+func (p *PoA) Slot(t time.Time) int64 {
+	if t.Before(p.genesis) {
+		return -1
+	}
+	return int64(t.Sub(p.genesis) / p.round)
 }
 
-// Запуск PoA-консенсуса
+// InTurn возвращает валидатора, предлагающего блок высоты height в слоте slot.
+// Очередь — весь набор валидаторов: баны (poa_bans.go) локальны для ноды и её не меняют.
+func (p *PoA) InTurn(slot int64, height uint64) (PoAValidator, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.inTurnLocked(slot, height)
+}
+
+func (p *PoA) inTurnLocked(slot int64, _ uint64) (PoAValidator, bool) {
+	if len(p.validators) == 0 || slot < 0 {
+		return PoAValidator{}, false
+	}
+	return p.validators[slot%int64(len(p.validators))], true
+}
+
+// Seal подписывает SealHash блока ключом валидатора ноды. block.Miner должен быть адресом ноды.
+func (p *PoA) Seal(block *core.Block) error {
+	if p.key == nil {
+		return errors.New("у ноды нет ключа валидатора")
+	}
+	if block.Miner != p.address {
+		return fmt.Errorf("блок предложен %s, ключ ноды — %s", block.Miner, p.address)
+	}
+	block.Signature = ecdsa.Sign(p.key, block.SealHash()).Serialize()
+	return nil
+}
+
+// VerifyBlock проверяет, что block.Miner — валидатор очереди для слота времени блока, слот позже слота
+// родителя и подпись SealHash сделана ключом этого валидатора. Блок, подписанный ключом известного
+// валидатора, но не прошедший проверку, считается недопустимым предложением (предупреждение валидатору).
+func (p *PoA) VerifyBlock(block, parent *core.Block) error {
+	err := p.verifyBlock(block, parent)
+	if err != nil && p.signedByValidator(block) {
+		p.recordInvalidProposal(block, err)
+	}
+	return err
+}
+
+func (p *PoA) verifyBlock(block, parent *core.Block) error {
+	if len(block.Signature) == 0 {
+		return errors.New("блок не подписан")
+	}
+	slot := p.Slot(block.Timestamp)
+	if slot < 0 {
+		return fmt.Errorf("время блока %s раньше генезиса", block.Timestamp.Format(time.RFC3339))
+	}
+	if block.Timestamp.After(time.Now().Add(p.round)) {
+		return fmt.Errorf("время блока %s в будущем", block.Timestamp.Format(time.RFC3339))
+	}
+	// Генезис не предлагается по слотам, поэтому слот сравнивается только с обычными блоками
+	if parent != nil && parent.Index > 0 {
+		if parentSlot := p.Slot(parent.Timestamp); slot <= parentSlot {
+			return fmt.Errorf("слот блока %d не позже слота родителя %d", slot, parentSlot)
+		}
+	}
+	v, ok := p.InTurn(slot, block.Index)
+	if !ok {
+		return errors.New("набор валидаторов PoA пуст")
+	}
+	if block.Miner != v.Address {
+		return fmt.Errorf("предлагающий %s не в очереди слота %d (ожидался %s)", block.Miner, slot, v.Address)
+	}
+	sig, err := ecdsa.ParseDERSignature(block.Signature)
+	if err != nil {
+		return fmt.Errorf("подпись блока: %w", err)
+	}
+	if !sig.Verify(block.SealHash(), v.PubKey) {
+		return fmt.Errorf("подпись блока не соответствует ключу валидатора %s", v.Address)
+	}
+	return nil
+}
+
+// Attach подключает движок к цепи: AddBlock проверяет блоки через VerifyBlock, а по принятым блокам
+// учитываются пропущенные слоты и сроки банов.
+func (p *PoA) Attach(bc *core.Blockchain) {
+	if last, err := bc.LatestBlock(); err == nil {
+		p.tipHeight = last.Index
+		if last.Index > 0 {
+			p.tipSlot = p.Slot(last.Timestamp)
+		}
+	}
+	bc.Engine = p
+	bc.OnBlock(p.onBlock)
+}
+
+// Start запускает производство блоков: в начале каждого слота набор валидаторов перечитывается из БД,
+// и если нода — валидатор очереди, она создаёт, подписывает и добавляет блок.
 func (p *PoA) Start(bc *core.Blockchain, mempool *core.Mempool) {
-	p.running = true
+	p.stopCh = make(chan struct{})
+	p.wg.Add(1)
 	go func() {
-		for p.running {
-			fmt.Println("[PoA] Авторитетный узел создает блок...")
-			// Реализация PoA-логики
-			time.Sleep(time.Second * 5)
+		defer p.wg.Done()
+		p.logger.Printf("Запущен: слот %v, валидатор ноды %s", p.round, p.address)
+		for {
+			now := time.Now()
+			slot := p.Slot(now)
+			wait := p.genesis.Add(time.Duration(slot+1) * p.round).Sub(now)
+			select {
+			case <-p.stopCh:
+				return
+			case <-time.After(wait):
+			}
+			p.produce(bc, mempool, p.Slot(time.Now()))
+		}
+	}()
+}
+
+// produce создаёт блок слота slot, если нода — валидатор очереди.
+func (p *PoA) produce(bc *core.Blockchain, mempool *core.Mempool, slot int64) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Printf("Паника при создании блока (восстановление): %v", r)
 		}
 	}()
+	if err := p.LoadValidators(context.Background()); err != nil {
+		p.logger.Printf("Набор валидаторов не обновлён: %v", err)
+	}
+	v, ok := p.InTurn(slot, bc.Height()+1)
+	if !ok || p.key == nil || v.Address != p.address {
+		return
+	}
+	if err := bc.ProduceNextBlock(mempool, p.address, p.MaxTxs); err != nil {
+		p.logger.Printf("Ошибка создания блока слота %d: %v", slot, err)
+		return
+	}
+	p.logger.Printf("Блок слота %d создан, высота цепи: %d", slot, bc.Height())
 }
 
-// Остановка
+// Stop останавливает производство блоков.
 func (p *PoA) Stop() {
-	p.running = false
+	if p.stopCh != nil {
+		close(p.stopCh)
+		p.wg.Wait()
+	}
 }
 
-// Тип консенсуса
+// Type возвращает тип консенсуса
 func (p *PoA) Type() string {
 	return "poa"
 }
+
+// parsePubKeyHex разбирает публичный ключ secp256k1 из hex (сжатый 33 байта или несжатый 65 байт).
+func parsePubKeyHex(s string) (*secp256k1.PublicKey, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return secp256k1.ParsePubKey(b)
+}
@@ -0,0 +1,242 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// consensus/poa_bans.go — учёт нарушений PoA: предупреждения за пропущенные слоты и недопустимые блоки, баны на ban_duration_blocks.
+// Нарушения фиксирует каждая нода по своему видению сети, поэтому баны носят рекомендательный характер (API, метрики):
+// очередь предлагающих и проверка блоков от них не зависят, иначе ноды с разной историей разошлись бы в наборе.
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"GND/core"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Типы событий истории нарушений PoA.
+const (
+	PoAEventMissedSlot      = "missed_slot"      // валидатор очереди не предложил блок в своём слоте
+	PoAEventInvalidProposal = "invalid_proposal" // блок подписан валидатором, но не прошёл проверку
+	PoAEventBan             = "ban"              // валидатор помечен забаненным на ban_duration_blocks блоков (очередь не меняется)
+	PoAEventBanSkipped      = "ban_skipped"      // бан не применён: забаненных стало бы больше max_bans_percentage
+	PoAEventUnban           = "unban"            // срок бана истёк
+)
+
+// maxPoAHistory — сколько последних событий нарушений хранится в памяти.
+const maxPoAHistory = 1000
+
+// PoAValidatorStatus — предупреждения и бан валидатора PoA.
+type PoAValidatorStatus struct {
+	Address          string `json:"address"`
+	Warnings         int    `json:"warnings"` // предупреждения с последнего бана
+	MissedSlots      uint64 `json:"missed_slots"`
+	InvalidProposals uint64 `json:"invalid_proposals"`
+	BannedUntil      uint64 `json:"banned_until"` // высота окончания бана; 0 — не забанен
+	Banned           bool   `json:"banned"`
+}
+
+// PoAEvent — запись истории нарушений PoA.
+type PoAEvent struct {
+	Type    string    `json:"type"`
+	Address string    `json:"address"`
+	Height  uint64    `json:"height"` // высота цепи, на которой зафиксировано событие
+	Slot    int64     `json:"slot"`
+	Reason  string    `json:"reason,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Statuses возвращает состояние нарушений всех валидаторов набора (по порядку очереди).
+func (p *PoA) Statuses() []PoAValidatorStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make([]PoAValidatorStatus, 0, len(p.validators))
+	for _, v := range p.validators {
+		st := PoAValidatorStatus{Address: v.Address}
+		if saved := p.penalties[v.Address]; saved != nil {
+			st = *saved
+		}
+		st.Banned = st.BannedUntil > p.tipHeight+1
+		out = append(out, st)
+	}
+	return out
+}
+
+// History возвращает события нарушений от новых к старым; address — фильтр по валидатору (пусто — все).
+func (p *PoA) History(address string) []PoAEvent {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var out []PoAEvent
+	for i := len(p.history) - 1; i >= 0; i-- {
+		if address == "" || p.history[i].Address == address {
+			out = append(out, p.history[i])
+		}
+	}
+	return out
+}
+
+// onBlock учитывает принятый блок: предупреждения валидаторам, пропустившим слоты между родителем и блоком,
+// и окончание банов. Если блоков не было целый круг очереди, сеть считается остановленной и пропуски не учитываются.
+func (p *PoA) onBlock(block *core.Block, _ []*core.Receipt) {
+	slot := p.Slot(block.Timestamp)
+	p.mu.Lock()
+	changed := make(map[string]bool)
+	var missed uint64
+	if p.tipSlot >= 0 && slot > p.tipSlot+1 {
+		if gap := slot - p.tipSlot - 1; gap < int64(len(p.validators)) {
+			for s := p.tipSlot + 1; s < slot; s++ {
+				if v, ok := p.inTurnLocked(s, block.Index); ok {
+					p.warnLocked(v.Address, PoAEventMissedSlot, block.Index, s, "")
+					changed[v.Address] = true
+					missed++
+				}
+			}
+		}
+	}
+	if block.Index > 0 {
+		p.tipSlot = slot
+	}
+	p.tipHeight = block.Index
+	for addr, st := range p.penalties {
+		if st.BannedUntil != 0 && st.BannedUntil <= block.Index+1 {
+			st.BannedUntil = 0
+			st.Warnings = 0
+			p.addEventLocked(PoAEvent{Type: PoAEventUnban, Address: addr, Height: block.Index, Slot: slot})
+			changed[addr] = true
+		}
+	}
+	p.mu.Unlock()
+	p.afterPenalties(changed, missed)
+}
+
+// signedByValidator сообщает, подписан ли блок ключом валидатора набора с адресом block.Miner.
+func (p *PoA) signedByValidator(block *core.Block) bool {
+	sig, err := ecdsa.ParseDERSignature(block.Signature)
+	if err != nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, v := range p.validators {
+		if v.Address == block.Miner {
+			return sig.Verify(block.SealHash(), v.PubKey)
+		}
+	}
+	return false
+}
+
+// recordInvalidProposal выносит предупреждение за недопустимый блок (не чаще одного на валидатора за слот).
+func (p *PoA) recordInvalidProposal(block *core.Block, reason error) {
+	slot := p.Slot(block.Timestamp)
+	p.mu.Lock()
+	if last, ok := p.invalidSlot[block.Miner]; ok && last == slot {
+		p.mu.Unlock()
+		return
+	}
+	p.invalidSlot[block.Miner] = slot
+	p.warnLocked(block.Miner, PoAEventInvalidProposal, p.tipHeight, slot, reason.Error())
+	p.mu.Unlock()
+	p.afterPenalties(map[string]bool{block.Miner: true}, 0)
+}
+
+// warnLocked выносит предупреждение и банит валидатора после warnings_for_ban предупреждений,
+// если забаненных не станет больше max_bans_percentage набора.
+func (p *PoA) warnLocked(addr, eventType string, height uint64, slot int64, reason string) {
+	st := p.statusLocked(addr)
+	st.Warnings++
+	if eventType == PoAEventMissedSlot {
+		st.MissedSlots++
+	} else {
+		st.InvalidProposals++
+	}
+	p.addEventLocked(PoAEvent{Type: eventType, Address: addr, Height: height, Slot: slot, Reason: reason})
+
+	if p.warningsForBan <= 0 || p.banBlocks == 0 || st.Warnings < p.warningsForBan || st.BannedUntil > height+1 {
+		return
+	}
+	if limit := len(p.validators) * p.maxBansPercent / 100; p.bannedCountLocked(height)+1 > limit {
+		p.addEventLocked(PoAEvent{Type: PoAEventBanSkipped, Address: addr, Height: height, Slot: slot,
+			Reason: fmt.Sprintf("забаненных стало бы больше %d%% набора", p.maxBansPercent)})
+		return
+	}
+	st.BannedUntil = height + p.banBlocks + 1
+	st.Warnings = 0
+	p.addEventLocked(PoAEvent{Type: PoAEventBan, Address: addr, Height: height, Slot: slot,
+		Reason: fmt.Sprintf("%d предупреждений, бан до высоты %d", p.warningsForBan, st.BannedUntil)})
+}
+
+func (p *PoA) statusLocked(addr string) *PoAValidatorStatus {
+	st := p.penalties[addr]
+	if st == nil {
+		st = &PoAValidatorStatus{Address: addr}
+		p.penalties[addr] = st
+	}
+	return st
+}
+
+// bannedCountLocked — число валидаторов набора, забаненных для следующего блока после height.
+func (p *PoA) bannedCountLocked(height uint64) int {
+	n := 0
+	for _, v := range p.validators {
+		if st := p.penalties[v.Address]; st != nil && st.BannedUntil > height+1 {
+			n++
+		}
+	}
+	return n
+}
+
+func (p *PoA) addEventLocked(e PoAEvent) {
+	e.Time = time.Now()
+	p.history = append(p.history, e)
+	if len(p.history) > maxPoAHistory {
+		p.history = p.history[len(p.history)-maxPoAHistory:]
+	}
+	p.logger.Printf("%s: %s (высота %d, слот %d) %s", e.Type, e.Address, e.Height, e.Slot, e.Reason)
+}
+
+// restorePenalties восстанавливает состояние нарушений из poa_metadata для валидаторов, ещё не учтённых в памяти.
+func (p *PoA) restorePenalties(saved map[string]PoAValidatorStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, st := range saved {
+		if _, ok := p.penalties[addr]; !ok {
+			st.Address = addr
+			p.penalties[addr] = &st
+		}
+	}
+}
+
+// afterPenalties обновляет метрики консенсуса и сохраняет изменённое состояние валидаторов в poa_validators.poa_metadata.
+func (p *PoA) afterPenalties(changed map[string]bool, missed uint64) {
+	p.mu.RLock()
+	total := uint64(len(p.validators))
+	active := total - uint64(p.bannedCountLocked(p.tipHeight))
+	var states []PoAValidatorStatus
+	for addr := range changed {
+		if st := p.penalties[addr]; st != nil {
+			states = append(states, *st)
+		}
+	}
+	p.mu.RUnlock()
+	core.GetMetrics().UpdateConsensusMetrics(total, active, missed)
+
+	if p.pool == nil || len(states) == 0 {
+		return
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Address < states[j].Address })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, st := range states {
+		_, err := p.pool.Exec(ctx, `
+			UPDATE poa_validators pv
+			SET poa_metadata = COALESCE(pv.poa_metadata, '{}'::jsonb) || jsonb_build_object(
+				'warnings', $2::int, 'missed_slots', $3::bigint, 'invalid_proposals', $4::bigint, 'banned_until', $5::bigint)
+			FROM validators v
+			WHERE v.id = pv.validator_id AND v.address = $1`,
+			st.Address, st.Warnings, st.MissedSlots, st.InvalidProposals, st.BannedUntil)
+		if err != nil {
+			p.logger.Printf("Не удалось сохранить состояние валидатора %s: %v", st.Address, err)
+		}
+	}
+}
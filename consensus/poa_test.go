@@ -0,0 +1,211 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package consensus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"GND/core"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+func newTestValidator(t *testing.T, addr string) (*core.Wallet, PoAValidator) {
+	t.Helper()
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &core.Wallet{PrivateKey: key, Address: core.Address(addr)}, PoAValidator{Address: addr, PubKey: key.PubKey()}
+}
+
+// newKeyedValidator создаёт валидатора с адресом, выведенным из ключа: его транзакции проходят проверку подписи в блоках.
+func newKeyedValidator(t *testing.T) (*core.Wallet, PoAValidator) {
+	t.Helper()
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := core.AddressFromPublicKey(key.PubKey().SerializeCompressed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &core.Wallet{PrivateKey: key, Address: core.Address(addr)}, PoAValidator{Address: addr, PubKey: key.PubKey()}
+}
+
+func TestPoA_RoundRobinSealAndVerify(t *testing.T) {
+	genesisTime := time.Now().Add(-time.Hour)
+	cfg := &core.ConsensusPoaConfig{RoundDuration: "10s"}
+	w1, v1 := newTestValidator(t, "GN_val1")
+	w2, v2 := newTestValidator(t, "GN_val2")
+
+	poa1, err := NewPoA(cfg, nil, genesisTime, w1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poa2, _ := NewPoA(cfg, nil, genesisTime, w2)
+	for _, p := range []*PoA{poa1, poa2} {
+		p.SetValidators([]PoAValidator{v1, v2})
+	}
+
+	parent := &core.Block{Index: 1, Timestamp: genesisTime.Add(15 * time.Second), Miner: "GN_val2"} // слот 1
+	newBlock := func(miner string, slot int64) *core.Block {
+		b := &core.Block{Index: 2, PrevHash: "p", Timestamp: genesisTime.Add(time.Duration(slot)*10*time.Second + time.Second), Miner: miner, Consensus: "poa"}
+		b.Hash = b.CalculateHash()
+		return b
+	}
+
+	// слот 2 — очередь GN_val1 (2 % 2 = 0)
+	b := newBlock("GN_val1", 2)
+	if err := poa1.Seal(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := poa2.VerifyBlock(b, parent); err != nil {
+		t.Errorf("блок валидатора очереди должен приниматься: %v", err)
+	}
+
+	b.ExtraData = []byte("x")
+	if err := poa2.VerifyBlock(b, parent); err == nil {
+		t.Error("изменённый после подписи заголовок должен отклоняться")
+	}
+	b.ExtraData = nil
+	b.StateRoot = "00"
+	if err := poa2.VerifyBlock(b, parent); err == nil {
+		t.Error("state_root входит в подпись: подменённый корень должен отклоняться")
+	}
+
+	// GN_val2 подписывает блок в чужом слоте
+	b = newBlock("GN_val2", 2)
+	if err := poa2.Seal(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := poa1.VerifyBlock(b, parent); err == nil || !strings.Contains(err.Error(), "не в очереди") {
+		t.Errorf("блок не от валидатора очереди должен отклоняться, получено %v", err)
+	}
+
+	// GN_val2 выдаёт себя за GN_val1: подпись не соответствует ключу GN_val1
+	b = newBlock("GN_val1", 4)
+	poa2.address = "GN_val1"
+	if err := poa2.Seal(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := poa1.VerifyBlock(b, parent); err == nil {
+		t.Error("подпись чужим ключом должна отклоняться")
+	}
+
+	if err := poa1.VerifyBlock(newBlock("GN_val1", 4), parent); err == nil {
+		t.Error("блок без подписи должен отклоняться")
+	}
+	b = newBlock("GN_val2", 1)
+	poa2.address = "GN_val2"
+	_ = poa2.Seal(b)
+	if err := poa1.VerifyBlock(b, parent); err == nil {
+		t.Error("блок в слоте родителя должен отклоняться")
+	}
+}
+
+func TestPoA_ProduceNextBlockSignedAndVerified(t *testing.T) {
+	genesis := &core.Block{Index: 0, Timestamp: time.Now().Add(-time.Minute), Miner: "miner", GasLimit: core.DefaultBlockGasLimit, Consensus: "poa", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := core.NewBlockchain(genesis, nil)
+
+	w, v := newTestValidator(t, "GN_val1")
+	poa, err := NewPoA(&core.ConsensusPoaConfig{RoundDuration: "5s"}, nil, genesis.Timestamp, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poa.SetValidators([]PoAValidator{v})
+	poa.Attach(bc)
+
+	if err := bc.ProduceNextBlock(bc.Mempool, "GN_val1", 10); err != nil {
+		t.Fatal(err)
+	}
+	block, _ := bc.LatestBlock()
+	if block.Index != 1 || len(block.Signature) == 0 {
+		t.Fatalf("ожидался подписанный блок 1, получено: index %d, подпись %d байт", block.Index, len(block.Signature))
+	}
+
+	unsigned := &core.Block{Index: 2, PrevHash: block.Hash, Timestamp: time.Now().Add(5 * time.Second), Miner: "GN_val1", GasLimit: core.DefaultBlockGasLimit, Consensus: "poa"}
+	unsigned.Hash = unsigned.CalculateHash()
+	if err := bc.AddBlock(unsigned); err == nil {
+		t.Error("AddBlock должен отклонять блок без подписи")
+	}
+	if bc.Height() != 1 {
+		t.Errorf("отклонённый блок не должен попадать в цепь, высота %d", bc.Height())
+	}
+}
+
+func TestPoA_WarningsBansAndLimit(t *testing.T) {
+	genesisTime := time.Now().Add(-time.Hour)
+	cfg := &core.ConsensusPoaConfig{RoundDuration: "10s", WarningsForBan: 2, BanDurationBlocks: 10, MaxBansPercentage: 40}
+	_, v1 := newTestValidator(t, "GN_val1")
+	_, v2 := newTestValidator(t, "GN_val2")
+	w3, v3 := newTestValidator(t, "GN_val3")
+	p, err := NewPoA(cfg, nil, genesisTime, w3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetValidators([]PoAValidator{v1, v2, v3})
+	missedBefore := core.GetMetrics().ConsensusMetrics.MissedBlocks
+
+	accept := func(index uint64, slot int64, miner string) *core.Block {
+		b := &core.Block{Index: index, Timestamp: genesisTime.Add(time.Duration(slot)*10*time.Second + time.Second), Miner: miner}
+		p.onBlock(b, nil)
+		return b
+	}
+	status := func(addr string) PoAValidatorStatus {
+		for _, st := range p.Statuses() {
+			if st.Address == addr {
+				return st
+			}
+		}
+		t.Fatalf("валидатор %s не найден", addr)
+		return PoAValidatorStatus{}
+	}
+
+	accept(1, 0, "GN_val1")
+	accept(2, 2, "GN_val3") // слот 1 (GN_val2) пропущен
+	accept(3, 5, "GN_val3") // слоты 3 (GN_val1) и 4 (GN_val2) пропущены — у GN_val2 второе предупреждение
+	if st := status("GN_val2"); !st.Banned || st.BannedUntil != 14 || st.MissedSlots != 2 || st.Warnings != 0 {
+		t.Fatalf("GN_val2 должен быть забанен до высоты 14: %+v", st)
+	}
+	if v, _ := p.InTurn(7, 4); v.Address != "GN_val2" {
+		t.Errorf("бан не должен менять очередь: в слоте 7 предлагает %s", v.Address)
+	}
+
+	// слот 6 — очередь GN_val1; второй бан превысил бы 40% набора из 3
+	parent := accept(4, 7, "GN_val2")
+	if st := status("GN_val1"); st.Banned || st.Warnings != 2 {
+		t.Errorf("GN_val1 не должен быть забанен сверх max_bans_percentage: %+v", st)
+	}
+	if h := p.History("GN_val1"); len(h) == 0 || h[0].Type != PoAEventBanSkipped {
+		t.Errorf("последним событием GN_val1 должен быть %s: %+v", PoAEventBanSkipped, h)
+	}
+	if got := core.GetMetrics().ConsensusMetrics.MissedBlocks - missedBefore; got != 4 {
+		t.Errorf("MissedBlocks: ожидалось +4, получено +%d", got)
+	}
+
+	// GN_val3 подписывает блок в чужом слоте: одно предупреждение за слот, сколько бы раз блок ни пришёл
+	bad := &core.Block{Index: 5, Timestamp: genesisTime.Add(90*time.Second + time.Second), Miner: "GN_val3"}
+	bad.Hash = bad.CalculateHash()
+	if err := p.Seal(bad); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := p.VerifyBlock(bad, parent); err == nil {
+			t.Fatal("блок не в своём слоте должен отклоняться")
+		}
+	}
+	if st := status("GN_val3"); st.InvalidProposals != 1 {
+		t.Errorf("invalid_proposals GN_val3: ожидалось 1, получено %d", st.InvalidProposals)
+	}
+
+	accept(13, 8, "GN_val1")
+	if st := status("GN_val2"); st.Banned || st.BannedUntil != 0 {
+		t.Errorf("срок бана GN_val2 истёк: %+v", st)
+	}
+	if h := p.History("GN_val2"); h[0].Type != PoAEventUnban {
+		t.Errorf("последним событием GN_val2 должен быть %s, получено %s", PoAEventUnban, h[0].Type)
+	}
+}
@@ -1,36 +1,332 @@
 // | KB @CerberRus00 - Nexus Invest Team
+// consensus/pos.go — PoS: выбор предлагающего с весом по стейку (hit/base target), подпись и проверка блоков.
 package consensus
 
 import (
-	"GND/core"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"GND/core"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// maxPoSClockDrift — насколько время блока PoS может опережать часы ноды.
+const maxPoSClockDrift = 15 * time.Second
+
+// PoS — движок Proof-of-Stake. Для блока после parent у валидатора hit = первые 8 байт sha256(parent.Hash || адрес);
+// валидатор вправе предложить блок через delay секунд после parent, когда hit < baseTarget × delay × вес,
+// где вес — доля стейка валидатора в общем стейке, умноженная на initial_balance (initial_base_target = 2^63 / (интервал × initial_balance)).
+// Валидатор с большим стейком в среднем успевает раньше; baseTarget подстраивается под average_block_delay.
+// Пока в реестре стейкинга нет стейка, единственный валидатор — майнер генезиса с весом initial_balance.
 type PoS struct {
-	running bool
+	mu                sync.RWMutex
+	bc                *core.Blockchain
+	pool              *pgxpool.Pool
+	avgDelay          time.Duration
+	initialBaseTarget uint64
+	baseTargets       map[string]uint64 // baseTarget после блока (по хешу блока)
+	totalWeight       *big.Int          // initial_balance: суммарный вес всех валидаторов
+	bootstrapAddr     string
+	bootstrapKey      *secp256k1.PublicKey
+	key               *secp256k1.PrivateKey // ключ валидатора ноды; nil — нода только проверяет блоки
+	address           string
+	MaxTxs            int
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+	logger *log.Logger
+}
+
+// NewPoS создаёт движок PoS по записи pos из consensus.json. wallet — кошелёк валидатора ноды (может быть nil).
+func NewPoS(cfg *core.ConsensusPosConfig, pool *pgxpool.Pool, wallet *core.Wallet) (*PoS, error) {
+	if cfg == nil {
+		return nil, errors.New("конфиг PoS не задан")
+	}
+	delay, err := time.ParseDuration(cfg.AverageBlockDelay)
+	if err != nil || delay < time.Second {
+		return nil, fmt.Errorf("неверный average_block_delay %q", cfg.AverageBlockDelay)
+	}
+	if cfg.InitialBaseTarget <= 0 {
+		return nil, fmt.Errorf("неверный initial_base_target %d", cfg.InitialBaseTarget)
+	}
+	totalWeight, ok := new(big.Int).SetString(cfg.InitialBalance, 10)
+	if !ok || totalWeight.Sign() <= 0 {
+		return nil, fmt.Errorf("неверный initial_balance %q", cfg.InitialBalance)
+	}
+	p := &PoS{
+		pool:              pool,
+		avgDelay:          delay,
+		initialBaseTarget: uint64(cfg.InitialBaseTarget),
+		baseTargets:       make(map[string]uint64),
+		totalWeight:       totalWeight,
+		MaxTxs:            DefaultMaxTxsPerBlock,
+		logger:            log.New(os.Stdout, "[PoS] ", log.LstdFlags),
+	}
+	if wallet != nil && wallet.PrivateKey != nil {
+		p.key = wallet.PrivateKey
+		p.address = string(wallet.Address)
+	}
+	return p, nil
 }
 
-func NewPoS() *PoS {
-	return &PoS{}
+// Attach подключает движок к цепи: пересчитывает baseTarget по загруженным блокам, определяет генезис-валидатора
+// (ключ — кошелёк ноды или validators.pubkey) и подписывается на новые блоки.
+func (p *PoS) Attach(bc *core.Blockchain) {
+	p.mu.Lock()
+	p.bc = bc
+	p.bootstrapAddr = bc.Genesis.Miner
+	if p.key != nil && p.address == p.bootstrapAddr {
+		p.bootstrapKey = p.key.PubKey()
+	}
+	blocks := bc.AllBlocks()
+	for i, b := range blocks {
+		var parent *core.Block
+		if i > 0 {
+			parent = blocks[i-1]
+		}
+		p.recordBaseTargetLocked(b, parent)
+	}
+	p.mu.Unlock()
+	if p.bootstrapKey == nil && p.pool != nil {
+		var pubHex string
+		if err := p.pool.QueryRow(context.Background(), `SELECT COALESCE(pubkey, '') FROM validators WHERE address = $1`, p.bootstrapAddr).Scan(&pubHex); err == nil {
+			if pub, err := parsePubKeyHex(pubHex); err == nil {
+				p.bootstrapKey = pub
+			}
+		}
+	}
+	bc.Engine = p
+	bc.OnBlock(func(block *core.Block, _ []*core.Receipt) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.recordBaseTargetLocked(block, p.parentOf(block))
+	})
 }
 
+// parentOf находит родителя блока в цепи (блок уже добавлен последним).
+func (p *PoS) parentOf(block *core.Block) *core.Block {
+	blocks := p.bc.Blocks
+	for i := len(blocks) - 1; i > 0; i-- {
+		if blocks[i] == block || blocks[i].Hash == block.Hash {
+			return blocks[i-1]
+		}
+	}
+	return nil
+}
+
+// recordBaseTargetLocked запоминает baseTarget после блока: для блока PoS — baseTarget родителя × фактическая задержка /
+// average_block_delay (не менее половины и не более удвоенного значения родителя), для прочих — без изменений.
+func (p *PoS) recordBaseTargetLocked(block, parent *core.Block) {
+	bt := p.initialBaseTarget
+	if parent != nil {
+		bt = p.baseTargetLocked(parent)
+		if block.Consensus == "pos" {
+			delay := block.Timestamp.Sub(parent.Timestamp)
+			next := new(big.Int).Mul(new(big.Int).SetUint64(bt), big.NewInt(int64(delay/time.Millisecond)))
+			next.Div(next, big.NewInt(int64(p.avgDelay/time.Millisecond)))
+			lo, hi := bt/2, bt*2
+			switch {
+			case !next.IsUint64() || next.Uint64() > hi:
+				bt = hi
+			case next.Uint64() < lo:
+				bt = lo
+			default:
+				bt = next.Uint64()
+			}
+			if bt == 0 {
+				bt = 1
+			}
+		}
+	}
+	p.baseTargets[block.Hash] = bt
+}
+
+func (p *PoS) baseTargetLocked(block *core.Block) uint64 {
+	if bt, ok := p.baseTargets[block.Hash]; ok {
+		return bt
+	}
+	return p.initialBaseTarget
+}
+
+// BaseTarget возвращает baseTarget для блока после block.
+func (p *PoS) BaseTarget(block *core.Block) uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.baseTargetLocked(block)
+}
+
+// Weight возвращает вес валидатора: доля его стейка в общем стейке реестра × initial_balance
+// или, пока стейка в реестре нет, initial_balance для генезис-валидатора.
+func (p *PoS) Weight(addr string) *big.Int {
+	if p.bc == nil || p.bc.Stakes == nil {
+		return big.NewInt(0)
+	}
+	total := big.NewInt(0)
+	for _, v := range p.bc.Stakes.Validators() {
+		total.Add(total, v.TotalStake)
+	}
+	if total.Sign() == 0 {
+		if addr == p.bootstrapAddr {
+			return new(big.Int).Set(p.totalWeight)
+		}
+		return big.NewInt(0)
+	}
+	weight := new(big.Int).Mul(p.bc.Stakes.TotalStake(addr), p.totalWeight)
+	return weight.Div(weight, total)
+}
+
+// hit — первые 8 байт sha256(parent.Hash || addr).
+func hit(parent *core.Block, addr string) uint64 {
+	sum := sha256.Sum256([]byte(parent.Hash + addr))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// ForgingDelay возвращает, через сколько после parent валидатор addr вправе предложить блок
+// (наименьшее целое число секунд delay с hit < baseTarget × delay × вес); false — у валидатора нет стейка.
+func (p *PoS) ForgingDelay(parent *core.Block, addr string) (time.Duration, bool) {
+	weight := p.Weight(addr)
+	if weight.Sign() <= 0 {
+		return 0, false
+	}
+	perSecond := new(big.Int).Mul(new(big.Int).SetUint64(p.BaseTarget(parent)), weight)
+	secs := new(big.Int).Div(new(big.Int).SetUint64(hit(parent, addr)), perSecond)
+	secs.Add(secs, big.NewInt(1))
+	if !secs.IsInt64() || secs.Int64() > int64(365*24*time.Hour/time.Second) {
+		return 0, false
+	}
+	return time.Duration(secs.Int64()) * time.Second, true
+}
+
+// pubKeyOf возвращает ключ подписи блоков валидатора: из реестра стейкинга или ключ генезис-валидатора.
+func (p *PoS) pubKeyOf(addr string) (*secp256k1.PublicKey, error) {
+	if v, ok := p.bc.Stakes.Validator(addr); ok {
+		return parsePubKeyHex(v.PubKey)
+	}
+	if addr == p.bootstrapAddr && p.bootstrapKey != nil {
+		return p.bootstrapKey, nil
+	}
+	return nil, fmt.Errorf("ключ валидатора %s неизвестен", addr)
+}
+
+// Seal подписывает SealHash блока ключом валидатора ноды.
+func (p *PoS) Seal(block *core.Block) error {
+	if p.key == nil {
+		return errors.New("у ноды нет ключа валидатора")
+	}
+	if block.Miner != p.address {
+		return fmt.Errorf("блок предложен %s, ключ ноды — %s", block.Miner, p.address)
+	}
+	block.Signature = ecdsa.Sign(p.key, block.SealHash()).Serialize()
+	return nil
+}
+
+// VerifyBlock проверяет блок PoS: награда равна настроенной, время не раньше задержки валидатора после родителя
+// и не в будущем, подпись SealHash сделана ключом валидатора.
+func (p *PoS) VerifyBlock(block, parent *core.Block) error {
+	if p.bc == nil {
+		return errors.New("движок PoS не подключён к цепи")
+	}
+	if len(block.Signature) == 0 {
+		return errors.New("блок не подписан")
+	}
+	if parent == nil {
+		return errors.New("нет родительского блока")
+	}
+	if block.Consensus != "pos" {
+		return fmt.Errorf("ожидался блок pos, получен %q", block.Consensus)
+	}
+	if reward := p.bc.Stakes.BlockReward(); block.Reward == nil || block.Reward.Cmp(reward) != 0 {
+		return fmt.Errorf("награда блока %v, ожидалась %s", block.Reward, reward)
+	}
+	if block.Timestamp.After(time.Now().Add(maxPoSClockDrift)) {
+		return fmt.Errorf("время блока %s в будущем", block.Timestamp.Format(time.RFC3339))
+	}
+	delay, ok := p.ForgingDelay(parent, block.Miner)
+	if !ok {
+		return fmt.Errorf("у %s нет стейка для предложения блока", block.Miner)
+	}
+	if elapsed := block.Timestamp.Sub(parent.Timestamp); elapsed < delay {
+		return fmt.Errorf("блок %s предложен через %v после родителя, право наступает через %v", block.Miner, elapsed, delay)
+	}
+	pub, err := p.pubKeyOf(block.Miner)
+	if err != nil {
+		return err
+	}
+	sig, err := ecdsa.ParseDERSignature(block.Signature)
+	if err != nil {
+		return fmt.Errorf("подпись блока: %w", err)
+	}
+	if !sig.Verify(block.SealHash(), pub) {
+		return fmt.Errorf("подпись блока не соответствует ключу валидатора %s", block.Miner)
+	}
+	return nil
+}
+
+// Start запускает производство блоков: раз в секунду нода проверяет, наступило ли её право на блок после текущей вершины.
 func (p *PoS) Start(bc *core.Blockchain, mempool *core.Mempool) {
-	p.running = true
+	p.stopCh = make(chan struct{})
+	p.wg.Add(1)
 	go func() {
-		for p.running {
-			// Логика выбора валидатора, создание и добавление блока
-			fmt.Println("[PoS] Валидатор выбирается, блок создается...")
-			// ...реализация PoS...
-			// time.Sleep(time.Second * 5)
-			break // demo, уберите break для реального цикла
+		defer p.wg.Done()
+		p.logger.Printf("Запущен: average_block_delay %v, валидатор ноды %s", p.avgDelay, p.address)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stopCh:
+				return
+			case <-ticker.C:
+				p.produce(bc, mempool)
+			}
+		}
+	}()
+}
+
+// produce создаёт блок, если с вершины цепи прошла задержка валидатора ноды.
+func (p *PoS) produce(bc *core.Blockchain, mempool *core.Mempool) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Printf("Паника при создании блока (восстановление): %v", r)
 		}
 	}()
+	if p.key == nil {
+		return
+	}
+	tip, err := bc.LatestBlock()
+	if err != nil {
+		return
+	}
+	delay, ok := p.ForgingDelay(tip, p.address)
+	if !ok || time.Since(tip.Timestamp) < delay {
+		return
+	}
+	if err := bc.ProduceNextBlock(mempool, p.address, p.MaxTxs); err != nil {
+		p.logger.Printf("Ошибка создания блока: %v", err)
+		return
+	}
+	p.logger.Printf("Блок создан, высота цепи: %d", bc.Height())
 }
 
+// Stop останавливает производство блоков.
 func (p *PoS) Stop() {
-	p.running = false
+	if p.stopCh != nil {
+		close(p.stopCh)
+		p.wg.Wait()
+	}
 }
 
+// Type возвращает тип консенсуса
 func (p *PoS) Type() string {
 	return "pos"
 }
@@ -0,0 +1,145 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package consensus
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"GND/core"
+	"GND/types"
+)
+
+func TestPoS_BootstrapStakeWeightedDelayAndVerify(t *testing.T) {
+	w1, v1 := newKeyedValidator(t)
+	w2, v2 := newKeyedValidator(t)
+	val1, val2 := v1.Address, v2.Address
+	genesis := &core.Block{Index: 0, Timestamp: time.Now().Add(-2 * time.Hour), Miner: val1, GasLimit: core.DefaultBlockGasLimit, Consensus: "pos", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := core.NewBlockchain(genesis, nil)
+	st := bc.State.(*core.State)
+	cfg := &core.ConsensusPosConfig{AverageBlockDelay: "30s", InitialBaseTarget: 153722867, InitialBalance: "1000000000"}
+
+	pos1, err := NewPoS(cfg, nil, w1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos2, _ := NewPoS(cfg, nil, w2)
+	pos1.Attach(bc)
+	pos2.bc = bc
+
+	// пока стейка нет, блоки создаёт только майнер генезиса; его блок регистрирует валидаторов
+	// и их собственный стейк: GN_val1 — 100, GN_val2 — 300
+	if _, ok := pos1.ForgingDelay(genesis, val2); ok {
+		t.Error("без стейка валидатор не должен иметь права на блок")
+	}
+	var txs []*core.Transaction
+	for _, v := range []struct {
+		val    PoAValidator
+		wallet *core.Wallet
+		stake  int64
+	}{{v1, w1, 100}, {v2, w2, 300}} {
+		addr := types.Address(v.val.Address)
+		if err := st.AddBalance(addr, core.GasSymbol, big.NewInt(1e15)); err != nil {
+			t.Fatal(err)
+		}
+		payload := fmt.Sprintf(`{"pubkey":"%s","commission_percent":5}`, hex.EncodeToString(v.val.PubKey.SerializeCompressed()))
+		nonce := st.GetNonce(addr)
+		for _, tx := range []*core.Transaction{
+			{Type: string(core.TxTypeValidator), Sender: addr, Recipient: addr, Value: big.NewInt(0), Nonce: nonce, Data: []byte(payload), GasLimit: 100000, Symbol: core.GasSymbol},
+			{Type: string(core.TxTypeStake), Sender: addr, Recipient: addr, Value: big.NewInt(v.stake), Nonce: nonce + 1, GasLimit: 100000, Symbol: core.GasSymbol},
+		} {
+			if err := v.wallet.SignTransaction(tx); err != nil {
+				t.Fatal(err)
+			}
+			txs = append(txs, tx)
+		}
+	}
+	newBlock := func(parent *core.Block, miner string, after time.Duration) *core.Block {
+		b := &core.Block{Index: parent.Index + 1, PrevHash: parent.Hash, Timestamp: parent.Timestamp.Add(after), Miner: miner,
+			GasLimit: core.DefaultBlockGasLimit, Consensus: "pos", Status: "finalized", Reward: bc.Stakes.BlockReward()}
+		b.Hash = b.CalculateHash()
+		return b
+	}
+	delay, _ := pos1.ForgingDelay(genesis, val1)
+	b := newBlock(genesis, val1, delay)
+	b.Transactions, b.MerkleRoot = txs, core.ComputeMerkleRoot(txs)
+	if err := bc.ExecuteBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := pos1.Seal(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := pos1.Weight(val2); got.Int64() != 750_000_000 {
+		t.Errorf("вес GN_val2 (3/4 стейка): ожидалось 750000000, получено %s", got)
+	}
+
+	// больший стейк — в среднем меньшая задержка
+	var sum1, sum2 time.Duration
+	for i := 0; i < 200; i++ {
+		parent := &core.Block{Hash: fmt.Sprintf("parent%d", i)}
+		d1, ok1 := pos1.ForgingDelay(parent, val1)
+		d2, ok2 := pos1.ForgingDelay(parent, val2)
+		if !ok1 || !ok2 {
+			t.Fatal("у валидаторов со стейком должно быть право на блок")
+		}
+		sum1 += d1
+		sum2 += d2
+	}
+	if sum2 >= sum1 {
+		t.Errorf("средняя задержка GN_val2 (%v) должна быть меньше, чем у GN_val1 (%v)", sum2/200, sum1/200)
+	}
+	if _, ok := pos1.ForgingDelay(b, "GN_val3"); ok {
+		t.Error("валидатор без стейка не должен иметь права на блок")
+	}
+
+	delay, _ = pos1.ForgingDelay(b, val2)
+	next := newBlock(b, val2, delay)
+	if err := pos2.Seal(next); err != nil {
+		t.Fatal(err)
+	}
+	if err := pos1.VerifyBlock(next, b); err != nil {
+		t.Errorf("блок GN_val2 после его задержки должен приниматься: %v", err)
+	}
+
+	early := newBlock(b, val2, delay-time.Second)
+	_ = pos2.Seal(early)
+	if err := pos1.VerifyBlock(early, b); err == nil || !strings.Contains(err.Error(), "право наступает") {
+		t.Errorf("блок раньше задержки валидатора должен отклоняться, получено %v", err)
+	}
+
+	greedy := newBlock(b, val2, delay)
+	greedy.Reward = new(big.Int).Mul(bc.Stakes.BlockReward(), big.NewInt(2))
+	_ = pos2.Seal(greedy)
+	if err := pos1.VerifyBlock(greedy, b); err == nil {
+		t.Error("блок с завышенной наградой должен отклоняться")
+	}
+
+	// GN_val1 подписывает блок от имени GN_val2
+	forged := newBlock(b, val2, delay)
+	pos1.address = val2
+	_ = pos1.Seal(forged)
+	pos1.address = val1
+	if err := pos1.VerifyBlock(forged, b); err == nil {
+		t.Error("подпись чужим ключом должна отклоняться")
+	}
+
+	// нода GN_val2 создаёт следующий блок: задержка после b давно прошла
+	pos2.Attach(bc)
+	if err := bc.ProduceNextBlock(bc.Mempool, val2, 10); err != nil {
+		t.Fatal(err)
+	}
+	tip, _ := bc.LatestBlock()
+	if tip.Index != 2 || tip.Consensus != "pos" || len(tip.Signature) == 0 || tip.Reward.Cmp(bc.Stakes.BlockReward()) != 0 {
+		t.Fatalf("ожидался подписанный блок PoS 2 с наградой: %+v", tip)
+	}
+	if bc.Stakes.Rewards(val2).Sign() <= 0 {
+		t.Error("награда за блок должна начисляться предлагающему валидатору")
+	}
+}
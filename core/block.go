@@ -23,7 +23,7 @@ type Block struct {
 	Hash         string         // Хеш блока
 	PrevHash     string         // Хеш предыдущего блока
 	MerkleRoot   string         // Корень дерева Меркла (от хешей транзакций)
-	StateRoot    string         // Корень состояния после применения блока (хеш состояния)
+	StateRoot    string         // Корень дерева состояния после применения блока (core/state_trie.go)
 	Timestamp    time.Time      // Временная метка создания блока
 	Height       uint64         // Высота блока
 	Version      uint32         // Версия блока
@@ -45,6 +45,8 @@ type Block struct {
 	Index        uint64         // Индекс блока
 	Consensus    string         // Тип консенсуса
 	Header       *BlockHeader   // Заголовок блока
+	Signature    []byte         // Подпись SealHash ключом предлагающего валидатора (Miner)
+	BaseFee      *big.Int       // Base fee блока (CalcBaseFee от родителя); выводится из родителя, поэтому в хеш не входит
 	Transactions []*Transaction // Транзакции в блоке
 }
 
@@ -96,15 +98,15 @@ func (b *Block) SaveToDB(ctx context.Context, pool *pgxpool.Pool) error {
 			version, size, tx_count, gas_used, gas_limit,
 			difficulty, nonce, miner, reward, extra_data,
 			created_at, updated_at, status, parent_id,
-			is_orphaned, is_finalized, index, consensus
+			is_orphaned, is_finalized, index, consensus, signature, state_root, base_fee
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
-			$11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+			$11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, NULLIF($24, ''), NULLIF($25, ''), NULLIF($26, '')::numeric)
 		RETURNING id`,
 		b.Hash, b.PrevHash, b.MerkleRoot, b.Timestamp, b.Height,
 		b.Version, b.Size, b.TxCount, b.GasUsed, b.GasLimit,
 		b.Difficulty, nonceStr, b.Miner, rewardStr, b.ExtraData,
 		createdAt, updatedAt, b.Status, b.ParentID,
-		b.IsOrphaned, b.IsFinalized, b.Index, b.Consensus,
+		b.IsOrphaned, b.IsFinalized, b.Index, b.Consensus, hex.EncodeToString(b.Signature), b.StateRoot, bigIntString(b.BaseFee),
 	).Scan(&b.ID)
 
 	if err != nil {
@@ -114,6 +116,14 @@ func (b *Block) SaveToDB(ctx context.Context, pool *pgxpool.Pool) error {
 	return nil
 }
 
+// bigIntString возвращает десятичную запись суммы для NUMERIC-колонок; nil — пустая строка (в БД NULL через NULLIF).
+func bigIntString(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
 // UpdateStatus обновляет статус блока и флаг is_finalized в БД.
 func (b *Block) UpdateStatus(ctx context.Context, pool *pgxpool.Pool, status string) error {
 	b.Status = status
@@ -154,6 +164,9 @@ type blockNullables struct {
 	difficulty sql.NullInt64
 	status     sql.NullString
 	consensus  sql.NullString
+	signature  sql.NullString
+	stateRoot  sql.NullString
+	baseFee    sql.NullString
 }
 
 func applyBlockNullables(block *Block, n blockNullables) {
@@ -161,6 +174,10 @@ func applyBlockNullables(block *Block, n blockNullables) {
 	if n.status.Valid {
 		block.Status = n.status.String
 	}
+	// Блоки, записанные без status (до слоя финальности), различаются только по is_finalized
+	if block.Status == "" && block.IsFinalized {
+		block.Status = BlockStatusFinalized
+	}
 	if n.consensus.Valid {
 		block.Consensus = n.consensus.String
 	}
@@ -187,6 +204,13 @@ func applyBlockNullables(block *Block, n blockNullables) {
 	if n.difficulty.Valid {
 		block.Difficulty = uint64(n.difficulty.Int64)
 	}
+	if n.signature.Valid && n.signature.String != "" {
+		block.Signature, _ = hex.DecodeString(n.signature.String)
+	}
+	block.StateRoot = n.stateRoot.String
+	if n.baseFee.Valid {
+		block.BaseFee, _ = new(big.Int).SetString(n.baseFee.String, 10)
+	}
 }
 
 // LoadBlockByHash загружает блок из БД по хешу
@@ -195,7 +219,7 @@ func LoadBlockByHash(pool *pgxpool.Pool, hash string) (*Block, error) {
 	var rewardStr, nonceStr string
 	var n blockNullables
 	err := pool.QueryRow(context.Background(), `
-		SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus
+		SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus, signature, state_root, base_fee::text
 		FROM blocks WHERE hash = $1`, hash).Scan(
 		&block.ID,
 		&block.Hash,
@@ -221,6 +245,9 @@ func LoadBlockByHash(pool *pgxpool.Pool, hash string) (*Block, error) {
 		&block.IsFinalized,
 		&block.Index,
 		&n.consensus,
+		&n.signature,
+		&n.stateRoot,
+		&n.baseFee,
 	)
 	if err != nil {
 		return nil, err
@@ -240,8 +267,8 @@ func LoadBlock(pool *pgxpool.Pool, height uint64) (*Block, error) {
 	var rewardStr, nonceStr string
 	var n blockNullables
 	err := pool.QueryRow(context.Background(), `
-		SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus
-		FROM blocks WHERE index = $1`, height).Scan(
+		SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus, signature, state_root, base_fee::text
+		FROM blocks WHERE index = $1 AND NOT is_orphaned`, height).Scan(
 		&block.ID,
 		&block.Hash,
 		&block.PrevHash,
@@ -266,6 +293,9 @@ func LoadBlock(pool *pgxpool.Pool, height uint64) (*Block, error) {
 		&block.IsFinalized,
 		&block.Index,
 		&n.consensus,
+		&n.signature,
+		&n.stateRoot,
+		&n.baseFee,
 	)
 	if err != nil {
 		return nil, err
@@ -284,7 +314,7 @@ func GetBlockByHeight(pool *pgxpool.Pool, height uint64) (*Block, error) {
 	var block Block
 	var rewardStr, nonceStr string
 	err := pool.QueryRow(context.Background(),
-		"SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus FROM blocks WHERE height = $1",
+		"SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus FROM blocks WHERE height = $1 AND NOT is_orphaned",
 		height,
 	).Scan(
 		&block.ID,
@@ -329,7 +359,7 @@ func GetLatestBlock(pool *pgxpool.Pool) (*Block, error) {
 	var rewardStr, nonceStr string
 	var n blockNullables
 	err := pool.QueryRow(context.Background(),
-		"SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus FROM blocks ORDER BY index DESC LIMIT 1",
+		"SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus, signature, state_root, base_fee::text FROM blocks WHERE NOT is_orphaned ORDER BY index DESC LIMIT 1",
 	).Scan(
 		&block.ID,
 		&block.Hash,
@@ -355,6 +385,9 @@ func GetLatestBlock(pool *pgxpool.Pool) (*Block, error) {
 		&block.IsFinalized,
 		&block.Index,
 		&n.consensus,
+		&n.signature,
+		&n.stateRoot,
+		&n.baseFee,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest block: %v", err)
@@ -385,11 +418,13 @@ func ComputeMerkleRoot(txs []*Transaction) string {
 	return hex.EncodeToString(h[:])
 }
 
-// CalculateHash вычисляет хеш блока
+// CalculateHash вычисляет хеш блока. StateRoot входит в хеш, поэтому заголовок подтверждает состояние после блока
+// (пустой StateRoot не меняет хеш блоков, созданных до дерева состояния).
 func (b *Block) CalculateHash() string {
 	var sb strings.Builder
 	sb.WriteString(b.PrevHash)
 	sb.WriteString(b.MerkleRoot)
+	sb.WriteString(b.StateRoot)
 	sb.WriteString(b.Timestamp.Format(time.RFC3339))
 	sb.WriteString(strconv.FormatInt(int64(b.Height), 10))
 	sb.WriteString(strconv.Itoa(int(b.Version)))
@@ -406,6 +441,30 @@ func (b *Block) CalculateHash() string {
 	return hex.EncodeToString(hash[:])
 }
 
+// SealHash — хеш заголовка, который подписывает предлагающий валидатор, включая результат исполнения (StateRoot, GasUsed):
+// собственный блок исполняется до подписи (ProduceNextBlock), и подпись подтверждает state_root блока.
+func (b *Block) SealHash() []byte {
+	var sb strings.Builder
+	sb.WriteString(b.PrevHash)
+	sb.WriteString(b.MerkleRoot)
+	sb.WriteString(b.StateRoot)
+	sb.WriteString(strconv.FormatUint(b.GasUsed, 10))
+	sb.WriteString(strconv.FormatInt(b.Timestamp.Unix(), 10))
+	sb.WriteString(strconv.FormatUint(b.Index, 10))
+	sb.WriteString(strconv.Itoa(int(b.Version)))
+	sb.WriteString(strconv.Itoa(int(b.TxCount)))
+	sb.WriteString(strconv.FormatUint(b.GasLimit, 10))
+	sb.WriteString(b.Miner)
+	sb.WriteString(b.Consensus)
+	if b.Reward != nil {
+		sb.WriteString(b.Reward.String())
+	}
+	sb.Write(b.ExtraData)
+
+	hash := sha256.Sum256([]byte(sb.String()))
+	return hash[:]
+}
+
 // CalculateHashWithoutNonce вычисляет хеш блока без учета nonce
 func (b *Block) CalculateHashWithoutNonce() string {
 	var sb strings.Builder
@@ -431,8 +490,8 @@ func GetBlockByNumber(pool *pgxpool.Pool, number uint64) (*Block, error) {
 	var block Block
 	var rewardStr, nonceStr string
 	var n blockNullables
-	sel := "SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus FROM blocks"
-	err := pool.QueryRow(context.Background(), sel+" WHERE height = $1", number).Scan(
+	sel := "SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus, signature, state_root, base_fee::text FROM blocks"
+	err := pool.QueryRow(context.Background(), sel+" WHERE height = $1 AND NOT is_orphaned", number).Scan(
 		&block.ID,
 		&block.Hash,
 		&block.PrevHash,
@@ -457,11 +516,14 @@ func GetBlockByNumber(pool *pgxpool.Pool, number uint64) (*Block, error) {
 		&block.IsFinalized,
 		&block.Index,
 		&n.consensus,
+		&n.signature,
+		&n.stateRoot,
+		&n.baseFee,
 	)
 	if err != nil {
 		// Старые строки могли быть записаны без height (только index). Пробуем по index.
 		if errors.Is(err, pgx.ErrNoRows) {
-			err = pool.QueryRow(context.Background(), sel+" WHERE index = $1", number).Scan(
+			err = pool.QueryRow(context.Background(), sel+" WHERE index = $1 AND NOT is_orphaned", number).Scan(
 				&block.ID,
 				&block.Hash,
 				&block.PrevHash,
@@ -486,6 +548,9 @@ func GetBlockByNumber(pool *pgxpool.Pool, number uint64) (*Block, error) {
 				&block.IsFinalized,
 				&block.Index,
 				&n.consensus,
+				&n.signature,
+				&n.stateRoot,
+				&n.baseFee,
 			)
 		}
 		if err != nil {
@@ -510,7 +575,7 @@ func GetBlockByHash(pool *pgxpool.Pool, hash string) (*Block, error) {
 	var rewardStr, nonceStr string
 	var n blockNullables
 	err := pool.QueryRow(context.Background(),
-		"SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus FROM blocks WHERE hash = $1",
+		"SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus, signature, state_root, base_fee::text FROM blocks WHERE hash = $1",
 		hash,
 	).Scan(
 		&block.ID,
@@ -537,6 +602,9 @@ func GetBlockByHash(pool *pgxpool.Pool, hash string) (*Block, error) {
 		&block.IsFinalized,
 		&block.Index,
 		&n.consensus,
+		&n.signature,
+		&n.stateRoot,
+		&n.baseFee,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get block by hash: %v", err)
@@ -567,7 +635,7 @@ func GetBlockIndexByID(ctx context.Context, pool *pgxpool.Pool, blockID int64) (
 // GetBlocks returns a list of blocks with pagination
 func GetBlocks(pool *pgxpool.Pool, limit, offset int) ([]*Block, error) {
 	rows, err := pool.Query(context.Background(),
-		"SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus FROM blocks ORDER BY height DESC LIMIT $1 OFFSET $2",
+		"SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus, signature, state_root, base_fee::text FROM blocks WHERE NOT is_orphaned ORDER BY height DESC LIMIT $1 OFFSET $2",
 		limit, offset,
 	)
 	if err != nil {
@@ -605,6 +673,9 @@ func GetBlocks(pool *pgxpool.Pool, limit, offset int) ([]*Block, error) {
 			&block.IsFinalized,
 			&block.Index,
 			&n.consensus,
+			&n.signature,
+			&n.stateRoot,
+			&n.baseFee,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan block: %v", err)
@@ -632,7 +703,7 @@ func LoadChainBlocksOrderedByIndex(pool *pgxpool.Pool, maxBlocks int) ([]*Block,
 		return nil, nil
 	}
 	rows, err := pool.Query(context.Background(),
-		"SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus FROM blocks ORDER BY index ASC LIMIT $1",
+		"SELECT id, hash, prev_hash, merkle_root, timestamp, height, version, size, tx_count, gas_used, gas_limit, difficulty, nonce::text, miner, reward, extra_data, created_at, updated_at, status, parent_id, is_orphaned, is_finalized, index, consensus, signature, state_root, base_fee::text FROM blocks WHERE NOT is_orphaned ORDER BY index ASC LIMIT $1",
 		maxBlocks,
 	)
 	if err != nil {
@@ -669,6 +740,9 @@ func LoadChainBlocksOrderedByIndex(pool *pgxpool.Pool, maxBlocks int) ([]*Block,
 			&block.IsFinalized,
 			&block.Index,
 			&n.consensus,
+			&n.signature,
+			&n.stateRoot,
+			&n.baseFee,
 		)
 		if err != nil {
 			return nil, err
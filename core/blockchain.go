@@ -10,14 +10,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"math/big"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"GND/core/crypto"
 	"GND/types"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -32,17 +30,37 @@ type Blockchain struct {
 	Mempool       *Mempool
 	mutex         sync.Mutex
 	SignerCreator SignerWalletCreator // опционально: для создания кошельков через signing_service
+	Executor      ContractExecutor    // опционально: исполнение байткода контрактов (vm.EVM); без него — запись storage по селекторам
+	Engine        ConsensusEngine     // опционально: подпись и проверка предлагающего блока (consensus.PoA/PoS); без него блоки не подписываются
+	Stakes        *StakeLedger        // реестр стейкинга PoS (транзакции stake/unstake/validator, награды за блок)
+	Finality      FinalityGadget      // опционально: голоса валидаторов и статусы proposed → justified → finalized; без движка блоки финальны сразу
+	Sync          SyncReporter        // опционально: статус синхронизации с пирами (p2p.Node) для /api/v1/health
+	ChainID       int64               // chain_id сети из config.json: пользовательские транзакции с другим chain_id отклоняются
+	SubnetID      string              // subnet_id подсети из config.json: транзакции другой подсети отклоняются
+
+	receiptsMu sync.RWMutex
+	receipts   map[string]*Receipt // квитанции транзакций, применённых с момента старта ноды (по хешу)
+
+	sideBlocks  map[string]*Block           // блоки боковых веток и отменённые реорганизацией, выше финализированного (по хешу)
+	checkpoints map[string]*stateCheckpoint // состояние после последних блоков цепи для отката и доказательств (по хешу блока)
+
+	listenersMu    sync.RWMutex
+	blockListeners []BlockListener // подписчики на новые блоки (WebSocket и т.п.)
+	reorgListeners []ReorgListener // подписчики на реорганизации цепи
 }
 
 // NewBlockchain creates a new blockchain
 func NewBlockchain(genesis *Block, pool *pgxpool.Pool) *Blockchain {
-	return &Blockchain{
+	bc := &Blockchain{
 		Genesis: genesis,
 		State:   NewState(),
 		Pool:    pool,
 		Blocks:  []*Block{genesis},
-		Mempool: NewMempool(),
+		Stakes:  NewStakeLedger(StakingConfig{}),
 	}
+	bc.setPendingFeeContext(genesis)
+	bc.SetMempool(NewMempool())
+	return bc
 }
 
 // LoadBlockchainFromDB loads blockchain from database.
@@ -66,10 +84,17 @@ func LoadBlockchainFromDB(pool *pgxpool.Pool, configOptional ...*Config) (*Block
 		if nc.FeeCollectorAddress != "" {
 			state.SetFeeCollectorAddress(nc.FeeCollectorAddress)
 		}
+		if nc.TreasuryAddress != "" {
+			state.SetTreasuryAddress(nc.TreasuryAddress)
+		}
 	}
 	if err := state.LoadFromDB(ctx); err != nil {
 		return nil, fmt.Errorf("failed to load state from DB: %w", err)
 	}
+	// Нода после быстрой синхронизации: код контрактов, созданных до снимка, есть только в state_snapshots
+	if err := state.loadSnapshotCodes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load snapshot contract codes: %w", err)
+	}
 
 	// Цепочка блоков по порядку index (генезис, 1, 2, …) — чтобы продолжить с последнего после перезапуска
 	blocks, err := LoadChainBlocksOrderedByIndex(pool, 10_000_000)
@@ -80,13 +105,23 @@ func LoadBlockchainFromDB(pool *pgxpool.Pool, configOptional ...*Config) (*Block
 		blocks = []*Block{genesis}
 	}
 
-	return &Blockchain{
+	stakes := NewStakeLedger(StakingConfig{})
+	if err := stakes.LoadFromDB(ctx, pool); err != nil {
+		return nil, fmt.Errorf("failed to load stake ledger: %w", err)
+	}
+
+	// Газ вне блоков (деплой через API) списывается по base fee следующего блока
+	state.SetFeeContext(CalcBaseFee(blocks[len(blocks)-1]), "")
+
+	bc := &Blockchain{
 		Genesis: genesis,
 		State:   state,
 		Pool:    pool,
 		Blocks:  blocks,
-		Mempool: NewMempool(),
-	}, nil
+		Stakes:  stakes,
+	}
+	bc.SetMempool(NewMempool())
+	return bc, nil
 }
 
 // GetBlockByNumber returns a block by its number
@@ -364,16 +399,22 @@ func (bc *Blockchain) storeBlock(block *Block) error {
 
 	// nonce в БД — varchar, передаём строку. is_finalized = true для финализированных блоков.
 	// height записываем для GET /api/v1/block/:number (поиск по height в цепи).
-	isFinalized := block.Status == "finalized"
+	isFinalized := block.Status == BlockStatusFinalized
+	// Блок, отменённый реорганизацией, при возврате в цепь снова становится каноническим
+	if block.ID != 0 && block.IsOrphaned {
+		_, err := bc.Pool.Exec(ctx, `UPDATE blocks SET is_orphaned = FALSE, status = $2, is_finalized = $3, updated_at = $4 WHERE id = $1`,
+			block.ID, block.Status, isFinalized, block.UpdatedAt)
+		return err
+	}
 	nonceStr := strconv.FormatUint(block.Nonce, 10)
 	err := bc.Pool.QueryRow(ctx, `
-		INSERT INTO blocks (index, height, hash, prev_hash, merkle_root, timestamp, miner, gas_used, gas_limit, consensus, nonce, tx_count, created_at, updated_at, is_finalized)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-		ON CONFLICT (index) DO UPDATE SET height = EXCLUDED.height, tx_count = EXCLUDED.tx_count, merkle_root = EXCLUDED.merkle_root, updated_at = EXCLUDED.updated_at, is_finalized = EXCLUDED.is_finalized
+		INSERT INTO blocks (index, height, hash, prev_hash, merkle_root, timestamp, miner, gas_used, gas_limit, consensus, nonce, tx_count, created_at, updated_at, is_finalized, signature, status, parent_id, state_root, base_fee)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NULLIF($16, ''), $17, $18, NULLIF($19, ''), NULLIF($20, '')::numeric)
+		ON CONFLICT (index) WHERE NOT is_orphaned DO UPDATE SET height = EXCLUDED.height, tx_count = EXCLUDED.tx_count, merkle_root = EXCLUDED.merkle_root, updated_at = EXCLUDED.updated_at, is_finalized = EXCLUDED.is_finalized, signature = EXCLUDED.signature, status = EXCLUDED.status, state_root = EXCLUDED.state_root, base_fee = EXCLUDED.base_fee
 		RETURNING id`,
 		block.Index, block.Height, block.Hash, block.PrevHash, block.MerkleRoot, block.Timestamp,
 		block.Miner, block.GasUsed, block.GasLimit, block.Consensus, nonceStr,
-		block.TxCount, block.CreatedAt, block.UpdatedAt, isFinalized,
+		block.TxCount, block.CreatedAt, block.UpdatedAt, isFinalized, hex.EncodeToString(block.Signature), block.Status, block.ParentID, block.StateRoot, bigIntString(block.BaseFee),
 	).Scan(&block.ID)
 	if err != nil {
 		return err
@@ -381,68 +422,284 @@ func (bc *Blockchain) storeBlock(block *Block) error {
 	return nil
 }
 
-// validateBlock проверяет целостность блока
-func (bc *Blockchain) validateBlock(block *Block) bool {
+// validateBlock проверяет целостность блока и то, что он продолжает parent (вершину цепи или блок боковой ветки)
+func (bc *Blockchain) validateBlock(block, parent *Block) bool {
 	if block.Hash != block.CalculateHash() {
 		fmt.Println("Хеш блока не совпадает")
 		return false
 	}
-	// TODO: добавить проверку подписи, времени, консенсуса и уникальности транзакций
+	// Следующий номер и хеш родителя
+	if parent != nil {
+		prevHash := parent.Hash
+		if prevHash == "" {
+			prevHash = "0"
+		}
+		if block.Index != parent.Index+1 || block.PrevHash != prevHash {
+			fmt.Printf("Блок %d (родитель %s) не продолжает блок %d (%s)\n", block.Index, block.PrevHash, parent.Index, prevHash)
+			return false
+		}
+	}
+	// Сумма лимитов газа транзакций не должна превышать лимит газа блока
+	if block.GasLimit > 0 {
+		var gas uint64
+		for _, tx := range block.Transactions {
+			if tx != nil {
+				gas += tx.EffectiveGasLimit()
+			}
+		}
+		if gas > block.GasLimit {
+			fmt.Printf("Лимит газа блока превышен: %d > %d\n", gas, block.GasLimit)
+			return false
+		}
+	}
+	// Транзакции должны соответствовать корню Меркла подписанного заголовка, иначе ретранслирующий пир мог бы их подменить
+	if block.MerkleRoot != ComputeMerkleRoot(block.Transactions) {
+		fmt.Printf("Корень Меркла блока %d не соответствует его транзакциям\n", block.Index)
+		return false
+	}
+	for _, tx := range block.Transactions {
+		if err := bc.validateBlockTx(tx); err != nil {
+			fmt.Printf("Блок %d: транзакция отклонена: %v\n", block.Index, err)
+			return false
+		}
+	}
+	// Транзакция входит в цепь один раз: повтор в блоке или транзакции из блоков, которые он продолжает, не допускается
+	hashes := make(map[string]struct{}, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		if _, dup := hashes[tx.Hash]; dup {
+			fmt.Printf("Блок %d: транзакция %s включена дважды\n", block.Index, tx.Hash)
+			return false
+		}
+		hashes[tx.Hash] = struct{}{}
+	}
+	if hash := bc.includedTxLocked(hashes, parent); hash != "" {
+		fmt.Printf("Блок %d: транзакция %s уже включена в цепь\n", block.Index, hash)
+		return false
+	}
+	// Подпись и право предлагающего проверяет Engine в AddBlock
 	return true
 }
 
-// applyBlock применяет все транзакции из блока к состоянию.
-// Для contract_call строит результат из calldata (storage) и вызывает ApplyExecutionResult,
-// чтобы изменения контракта попали в contract_storage при SaveToDB.
-func (bc *Blockchain) applyBlock(block *Block) {
+// includedTxLocked возвращает хеш транзакции из hashes, уже включённой в цепь, которую продолжает parent:
+// в блоки боковой ветки до parent и в блоки цепи до точки ответвления (с БД — по transactions неотменённых блоков,
+// так как блоки, загруженные при запуске, хранятся без транзакций). Пустая строка — таких транзакций нет.
+func (bc *Blockchain) includedTxLocked(hashes map[string]struct{}, parent *Block) string {
+	if parent == nil || len(hashes) == 0 {
+		return ""
+	}
+	fork := parent
+	for fork != nil {
+		if _, canonical := bc.findBlockLocked(fork.Hash); canonical {
+			break
+		}
+		for _, tx := range fork.Transactions {
+			if tx == nil {
+				continue
+			}
+			if _, ok := hashes[tx.Hash]; ok {
+				return tx.Hash
+			}
+		}
+		fork, _ = bc.findBlockLocked(fork.PrevHash)
+	}
+	if fork == nil {
+		return ""
+	}
+	if bc.Pool != nil {
+		list := make([]string, 0, len(hashes))
+		for h := range hashes {
+			list = append(list, h)
+		}
+		var hash string
+		err := bc.Pool.QueryRow(context.Background(), `
+			SELECT t.hash FROM transactions t JOIN blocks b ON b.id = t.block_id
+			WHERE t.hash = ANY($1) AND NOT b.is_orphaned AND b.index <= $2
+			LIMIT 1`, list, int64(fork.Index)).Scan(&hash)
+		if err == nil {
+			return hash
+		}
+	}
+	// Блоки цепи в памяти: с БД — только последние (их транзакции могли не записаться), без БД — все
+	for i, checked := len(bc.Blocks)-1, 0; i >= 0 && (bc.Pool == nil || checked <= MaxReorgDepth); i-- {
+		b := bc.Blocks[i]
+		if b.Index > fork.Index {
+			continue
+		}
+		checked++
+		for _, tx := range b.Transactions {
+			if tx == nil {
+				continue
+			}
+			if _, ok := hashes[tx.Hash]; ok {
+				return tx.Hash
+			}
+		}
+	}
+	return ""
+}
+
+// validateBlockTx проверяет транзакцию блока так же, как транзакцию мемпула: хеш по полям, сеть (chain_id, subnet_id)
+// и подпись отправителя. Системные транзакции создаются только в генезисе и в блоках не допускаются.
+func (bc *Blockchain) validateBlockTx(tx *Transaction) error {
+	if tx == nil {
+		return errors.New("пустая транзакция")
+	}
+	if IsSystemTransaction(tx) {
+		return fmt.Errorf("системная транзакция %s", tx.Hash)
+	}
+	if tx.ChainID != bc.ChainID || tx.SubnetID != bc.SubnetID {
+		return fmt.Errorf("%w: chain_id %d, subnet_id %q, у ноды %d, %q", ErrWrongChain, tx.ChainID, tx.SubnetID, bc.ChainID, bc.SubnetID)
+	}
+	if hash := tx.CalculateHash(); tx.Hash != hash {
+		return fmt.Errorf("хеш транзакции %s не соответствует её полям (%s)", tx.Hash, hash)
+	}
+	if err := validateDeployTx(tx); err != nil {
+		return err
+	}
+	return VerifyTransactionSignature(tx)
+}
+
+// applyBlock применяет все транзакции из блока к состоянию и формирует квитанции.
+// Для contract_call исполняет байткод через Executor (или, без него, строит результат из calldata по селекторам)
+// и вызывает ApplyExecutionResult, чтобы изменения контракта попали в contract_storage при SaveToDB.
+// Статус транзакции — confirmed или failed (revert, неверный nonce, недостаточный баланс); tx.GasUsed заполняется при применении.
+// Возвращает квитанции (в порядке транзакций) и суммарный газ блока.
+func (bc *Blockchain) applyBlock(block *Block) ([]*Receipt, uint64) {
 	if st, ok := bc.State.(*State); ok {
 		st.ClearTouched()
+		// base fee блока уходит в казну (или сжигается), чаевые — предлагающему блок
+		st.SetFeeContext(block.BaseFee, block.Miner)
 	}
-	for _, tx := range block.Transactions {
+	var gasUsed uint64
+	var logIndex uint
+	receipts := make([]*Receipt, 0, len(block.Transactions))
+	for i, tx := range block.Transactions {
 		if tx == nil {
 			continue
 		}
-		if tx.IsContractCall() {
-			result := buildContractCallExecutionResult(tx)
-			if result != nil {
-				if st, ok := bc.State.(*State); ok {
-					expected := bc.State.GetNonce(types.Address(tx.Sender))
-					if int64(tx.Nonce) != expected {
-						fmt.Printf("Транзакция %s: неверный nonce (expected %d, got %d), пропущена\n", tx.Hash, expected, tx.Nonce)
-						continue
-					}
-					symbol := "GND"
-					if tx.Symbol != "" {
-						symbol = tx.Symbol
-					}
-					if tx.Value != nil && tx.Value.Sign() > 0 {
-						if err := st.SubBalance(tx.Sender, symbol, tx.Value); err != nil {
-							fmt.Printf("Транзакция %s не прошла (value): %v\n", tx.Hash, err)
-							continue
-						}
-						if err := st.AddBalance(tx.Recipient, symbol, tx.Value); err != nil {
-							st.AddBalance(tx.Sender, symbol, tx.Value)
-							fmt.Printf("Транзакция %s не прошла (value): %v\n", tx.Hash, err)
-							continue
-						}
-					}
-					if err := st.ApplyExecutionResult(tx, result); err != nil {
-						fmt.Printf("Транзакция %s не прошла (ApplyExecutionResult): %v\n", tx.Hash, err)
-					}
-					continue
+		tx.GasUsed = 0
+		result, err := bc.applyTx(tx, block)
+		if err != nil {
+			fmt.Printf("Транзакция %s не прошла, пропущена: %v\n", tx.Hash, err)
+		} else if result != nil && result.Error != nil {
+			fmt.Printf("Транзакция %s: вызов контракта откатился: %v\n", tx.Hash, result.Error)
+		}
+		gasUsed += tx.GasUsed
+		receipt := newReceipt(tx, block, i, gasUsed, result, err)
+		for _, l := range receipt.Logs {
+			l.LogIndex = logIndex
+			logIndex++
+		}
+		tx.Status = TxStatusConfirmed
+		if !receipt.Succeeded() {
+			tx.Status = TxStatusFailed
+		}
+		receipts = append(receipts, receipt)
+	}
+	if st, ok := bc.State.(*State); ok && bc.Stakes != nil {
+		bc.Stakes.EndBlock(st, block)
+	}
+	return receipts, gasUsed
+}
+
+// applyTx применяет одну транзакцию блока. Ошибка — транзакция не применена (nonce, баланс, исполнение);
+// result — результат исполнения контракта (для перевода nil), при revert result.Error != nil.
+func (bc *Blockchain) applyTx(tx *Transaction, block *Block) (*types.ExecutionResult, error) {
+	st, isState := bc.State.(*State)
+	if IsStakingTx(tx) {
+		if !isState || bc.Stakes == nil {
+			return nil, errors.New("стейкинг недоступен: нет реестра стейкинга")
+		}
+		return nil, bc.applyStakingTx(st, tx, block)
+	}
+	if tx.IsDeploy() {
+		if !isState || bc.Executor == nil {
+			return nil, errors.New("деплой недоступен: нет исполнителя контрактов")
+		}
+		return bc.applyContractDeploy(st, tx, block)
+	}
+	if tx.IsContractCall() && bc.Executor != nil && isState {
+		return bc.applyContractCall(st, tx, block)
+	}
+	if tx.IsContractCall() && isState {
+		if result := buildContractCallExecutionResult(tx); result != nil {
+			expected := bc.State.GetNonce(types.Address(tx.Sender))
+			if int64(tx.Nonce) != expected {
+				return nil, fmt.Errorf("неверный nonce (expected %d, got %d)", expected, tx.Nonce)
+			}
+			symbol := "GND"
+			if tx.Symbol != "" {
+				symbol = tx.Symbol
+			}
+			if tx.Value != nil && tx.Value.Sign() > 0 {
+				if err := st.SubBalance(tx.Sender, symbol, tx.Value); err != nil {
+					return nil, fmt.Errorf("value: %w", err)
 				}
+				if err := st.AddBalance(tx.Recipient, symbol, tx.Value); err != nil {
+					st.AddBalance(tx.Sender, symbol, tx.Value)
+					return nil, fmt.Errorf("value: %w", err)
+				}
+			}
+			if err := st.ApplyExecutionResult(tx, result); err != nil {
+				return nil, fmt.Errorf("ApplyExecutionResult: %w", err)
 			}
+			return result, nil
 		}
-		if err := bc.State.ApplyTransaction(tx); err != nil {
-			exp := bc.State.GetNonce(types.Address(tx.Sender))
-			fmt.Printf("Транзакция %s не прошла, пропущена: %v (sender nonce в tx: %d, expected: %d)\n", tx.Hash, err, tx.Nonce, exp)
+	}
+	if err := bc.State.ApplyTransaction(tx); err != nil {
+		exp := bc.State.GetNonce(types.Address(tx.Sender))
+		return nil, fmt.Errorf("%w (sender nonce в tx: %d, expected: %d)", err, tx.Nonce, exp)
+	}
+	return nil, nil
+}
+
+// applyContractCall исполняет вызов контракта через Executor и применяет результат к состоянию.
+// При revert изменения storage и value не применяются, но газ списывается и nonce увеличивается.
+func (bc *Blockchain) applyContractCall(st *State, tx *Transaction, block *Block) (*types.ExecutionResult, error) {
+	expected := st.GetNonce(types.Address(tx.Sender))
+	if int64(tx.Nonce) != expected {
+		return nil, fmt.Errorf("неверный nonce (expected %d, got %d)", expected, tx.Nonce)
+	}
+	result, err := bc.Executor.ExecuteContractCall(tx, block)
+	if err != nil {
+		return nil, fmt.Errorf("исполнение контракта: %w", err)
+	}
+	if err := st.ApplyExecutionResult(tx, result); err != nil {
+		return nil, fmt.Errorf("ApplyExecutionResult: %w", err)
+	}
+	return result, nil
+}
+
+// applyContractDeploy исполняет init-код транзакции деплоя в контексте блока и применяет результат: runtime-код
+// по адресу tx.Recipient, storage и nonce, заданные конструктором, и газ. При revert контракт не создаётся,
+// но газ списывается и nonce увеличивается.
+func (bc *Blockchain) applyContractDeploy(st *State, tx *Transaction, block *Block) (*types.ExecutionResult, error) {
+	expected := st.GetNonce(types.Address(tx.Sender))
+	if int64(tx.Nonce) != expected {
+		return nil, fmt.Errorf("неверный nonce (expected %d, got %d)", expected, tx.Nonce)
+	}
+	result, code, err := bc.Executor.ExecuteContractDeploy(tx, block)
+	if err != nil {
+		return nil, fmt.Errorf("исполнение конструктора: %w", err)
+	}
+	if err := st.ApplyExecutionResult(tx, result); err != nil {
+		return nil, fmt.Errorf("ApplyExecutionResult: %w", err)
+	}
+	if result.Error == nil {
+		if err := st.SetContractCode(tx.Recipient.String(), code); err != nil {
+			return nil, fmt.Errorf("код контракта: %w", err)
 		}
 	}
+	return result, nil
 }
 
-// Height возвращает текущую высоту цепочки
+// Height возвращает текущую высоту цепочки — номер последнего блока (после быстрой синхронизации
+// блоков между генезисом и снимком состояния в цепи нет).
 func (bc *Blockchain) Height() uint64 {
-	return uint64(len(bc.Blocks) - 1)
+	if len(bc.Blocks) == 0 {
+		return 0
+	}
+	return bc.Blocks[len(bc.Blocks)-1].Index
 }
 
 // AllBlocks возвращает копию всех блоков (для API)
@@ -454,8 +711,27 @@ func (bc *Blockchain) AllBlocks() []*Block {
 
 // AddTx добавляет транзакцию в мемпул
 func (bc *Blockchain) AddTx(tx *Transaction) error {
-	bc.Mempool.Add(tx)
-	return nil
+	return bc.Mempool.Add(tx)
+}
+
+// SetMempool привязывает мемпул к цепи: nonce аккаунтов берутся из State, у удалённых из мемпула
+// транзакций (замена, вытеснение, TTL) обновляется status в БД.
+func (bc *Blockchain) SetMempool(m *Mempool) {
+	m.SetNonceSource(bc.State)
+	m.OnDrop(bc.markDroppedTx)
+	bc.Mempool = m
+}
+
+// markDroppedTx записывает в transactions.status причину удаления транзакции из мемпула (только для ещё не включённых в блок).
+func (bc *Blockchain) markDroppedTx(tx *Transaction, reason string) {
+	tx.Status = reason
+	if bc.Pool == nil {
+		return
+	}
+	if _, err := bc.Pool.Exec(context.Background(),
+		`UPDATE transactions SET status = $2 WHERE hash = $1 AND status = 'pending'`, tx.Hash, reason); err != nil {
+		fmt.Printf("[Mempool] Не удалось обновить статус транзакции %s: %v\n", tx.Hash, err)
+	}
 }
 
 // GetTxStatus возвращает статус транзакции: confirmed / pending / not found
@@ -463,7 +739,10 @@ func (bc *Blockchain) GetTxStatus(hash string) (string, error) {
 	for _, block := range bc.Blocks {
 		for _, tx := range block.Transactions {
 			if tx.Hash == hash {
-				return "confirmed", nil
+				if tx.Status == TxStatusFailed {
+					return TxStatusFailed, nil
+				}
+				return TxStatusConfirmed, nil
 			}
 		}
 	}
@@ -484,7 +763,7 @@ func (bc *Blockchain) LatestBlock() (*Block, error) {
 }
 
 // ProduceNextBlock создаёт новый блок, включая до maxTxs транзакций из mempool, и добавляет его в цепь.
-// При открытии нового блока предыдущий (текущий tip) финализируется, даже если он пустой.
+// С движком консенсуса блок получает статус proposed и финализируется голосами валидаторов (Finality), без движка — финален сразу.
 // miner — адрес валидатора. Вызывается по таймеру (например из main с интервалом round_duration).
 func (bc *Blockchain) ProduceNextBlock(mempool *Mempool, miner string, maxTxs int) error {
 	if mempool == nil {
@@ -495,15 +774,6 @@ func (bc *Blockchain) ProduceNextBlock(mempool *Mempool, miner string, maxTxs in
 	if err != nil || last == nil {
 		return err
 	}
-	// Финализировать предыдущий блок при открытии нового (даже если он пустой)
-	if last.Status != "finalized" && bc.Pool != nil && last.ID != 0 {
-		ctx := context.Background()
-		if err := last.UpdateStatus(ctx, bc.Pool, "finalized"); err != nil {
-			return fmt.Errorf("финализация предыдущего блока %d: %w", last.Index, err)
-		}
-	}
-	last.Status = "finalized"
-
 	height := last.Index + 1
 	prevHash := last.Hash
 	if prevHash == "" {
@@ -512,19 +782,40 @@ func (bc *Blockchain) ProduceNextBlock(mempool *Mempool, miner string, maxTxs in
 	block := NewBlock(prevHash, height, miner)
 	block.Index = height
 	block.Reward = big.NewInt(0)
-	block.GasLimit = 10_000_000
+	block.GasLimit = DefaultBlockGasLimit
 	block.GasUsed = 0
 	block.Consensus = "poa"
-	block.Status = "finalized"
+	if bc.Engine != nil {
+		block.Consensus = bc.Engine.Type()
+	}
+	// Блок PoS несёт награду валидатору и делегаторам (начисляется в StakeLedger.EndBlock)
+	if block.Consensus == "pos" && bc.Stakes != nil {
+		block.Reward = bc.Stakes.BlockReward()
+	}
+	block.Status = BlockStatusFinalized
 	block.IsFinalized = true
+	block.BaseFee = CalcBaseFee(last)
 
-	rawTxs := mempool.TakePending(maxTxs)
-	// Фильтруем транзакции с неверным nonce — не включаем в блок, чтобы не спамить "invalid nonce" при каждом applyBlock
+	// Мемпул отдаёт непрерывные по nonce цепочки отправителей в порядке цены газа при base fee блока и в пределах лимита газа блока.
+	// Транзакции, которые validateBlock не примет (без подписи, другой сети), в блок не включаются и удаляются из мемпула
+	// (статус evicted); следующие транзакции того же отправителя без неё не исполнятся (пропуск nonce) и возвращаются в мемпул.
 	var txs []*Transaction
-	for _, tx := range rawTxs {
-		expected := bc.State.GetNonce(types.Address(tx.Sender))
-		if int64(tx.Nonce) != expected {
-			fmt.Printf("[Mempool] Транзакция %s не включена в блок: invalid nonce (expected %d, got %d)\n", tx.Hash, expected, tx.Nonce)
+	rejected := make(map[types.Address]bool)
+	for _, tx := range mempool.TakePending(maxTxs, block.GasLimit, block.BaseFee) {
+		if rejected[tx.Sender] {
+			if err := mempool.Add(tx); err != nil {
+				mempool.dropTaken(tx, TxStatusEvicted)
+			}
+			continue
+		}
+		err := bc.validateBlockTx(tx)
+		if gasLimit, intrinsic := tx.EffectiveGasLimit(), tx.IntrinsicGas(); err == nil && gasLimit < intrinsic {
+			err = fmt.Errorf("intrinsic gas too low (have %d, want %d)", gasLimit, intrinsic)
+		}
+		if err != nil {
+			fmt.Printf("[Mempool] Транзакция %s не включена в блок: %v\n", tx.Hash, err)
+			rejected[tx.Sender] = true
+			mempool.dropTaken(tx, TxStatusEvicted)
 			continue
 		}
 		txs = append(txs, tx)
@@ -533,14 +824,39 @@ func (bc *Blockchain) ProduceNextBlock(mempool *Mempool, miner string, maxTxs in
 	block.TxCount = uint32(len(txs))
 	block.MerkleRoot = ComputeMerkleRoot(txs)
 
-	block.Hash = block.CalculateHash()
+	// Газ и state_root входят в подписываемый заголовок, поэтому блок исполняется до подписи.
+	// Если блок не добавлен в цепь, взятые транзакции возвращаются в мемпул
+	if err := bc.ExecuteBlock(block); err != nil {
+		returnToMempool(mempool, txs)
+		return fmt.Errorf("ProduceNextBlock execute: %w", err)
+	}
+	if bc.Engine != nil {
+		if err := bc.Engine.Seal(block); err != nil {
+			returnToMempool(mempool, txs)
+			return fmt.Errorf("ProduceNextBlock Seal: %w", err)
+		}
+	}
 
 	if err := bc.AddBlock(block); err != nil {
+		returnToMempool(mempool, txs)
 		return fmt.Errorf("ProduceNextBlock AddBlock: %w", err)
 	}
+	if mempool != bc.Mempool {
+		mempool.Reset()
+	}
 	return nil
 }
 
+// returnToMempool возвращает в мемпул транзакции блока, не добавленного в цепь; не принятые мемпулом удаляются (evicted).
+func returnToMempool(mempool *Mempool, txs []*Transaction) {
+	for _, tx := range txs {
+		tx.Status, tx.BlockID, tx.GasUsed = "pending", 0, 0
+		if err := mempool.Add(tx); err != nil {
+			mempool.dropTaken(tx, TxStatusEvicted)
+		}
+	}
+}
+
 // LoadTransactionsForBlock загружает транзакции блока по block_id (blocks.id). Для ответа API (block/latest, block/:number).
 func LoadTransactionsForBlock(ctx context.Context, pool *pgxpool.Pool, blockID int64) ([]*Transaction, error) {
 	if pool == nil {
@@ -557,7 +873,12 @@ func LoadTransactionsForBlock(ctx context.Context, pool *pgxpool.Pool, blockID i
 			type, 
 			payload, 
 			status, 
-			timestamp 
+			timestamp,
+			COALESCE(gas_limit, 0),
+			COALESCE(gas_price, 0)::text,
+			COALESCE(gas_used, 0),
+			max_fee_per_gas::text,
+			max_priority_fee_per_gas::text
 		FROM transactions 
 		WHERE block_id = $1`, blockID)
 	if err != nil {
@@ -568,7 +889,8 @@ func LoadTransactionsForBlock(ctx context.Context, pool *pgxpool.Pool, blockID i
 	var txs []*Transaction
 	for rows.Next() {
 		var tx Transaction
-		var valueStr string
+		var valueStr, gasPriceStr string
+		var maxFee, maxPriorityFee sql.NullString
 		var payload []byte
 
 		err := rows.Scan(
@@ -582,6 +904,11 @@ func LoadTransactionsForBlock(ctx context.Context, pool *pgxpool.Pool, blockID i
 			&payload,
 			&tx.Status,
 			&tx.Timestamp,
+			&tx.GasLimit,
+			&gasPriceStr,
+			&tx.GasUsed,
+			&maxFee,
+			&maxPriorityFee,
 		)
 		if err != nil {
 			return nil, err
@@ -589,6 +916,7 @@ func LoadTransactionsForBlock(ctx context.Context, pool *pgxpool.Pool, blockID i
 
 		tx.Data = payload
 		tx.Value, _ = new(big.Int).SetString(valueStr, 10)
+		tx.setGasPriceColumns(gasPriceStr, maxFee, maxPriorityFee)
 		tx.BlockID = int(blockID)
 		txs = append(txs, &tx)
 	}
@@ -602,7 +930,9 @@ func LoadPendingTransactionsFromDB(ctx context.Context, pool *pgxpool.Pool) ([]*
 		return nil, nil
 	}
 	rows, err := pool.Query(ctx, `
-		SELECT block_id, hash, sender, recipient, value, fee, nonce, type, payload, status, timestamp, contract_id
+		SELECT block_id, hash, sender, recipient, value, fee, nonce, type, payload, status, timestamp, contract_id,
+			COALESCE(gas_limit, 0), COALESCE(gas_price, 0)::text, COALESCE(gas_used, 0),
+			max_fee_per_gas::text, max_priority_fee_per_gas::text
 		FROM transactions
 		WHERE block_id IS NULL
 		ORDER BY timestamp ASC`)
@@ -613,7 +943,8 @@ func LoadPendingTransactionsFromDB(ctx context.Context, pool *pgxpool.Pool) ([]*
 	var list []*Transaction
 	for rows.Next() {
 		var tx Transaction
-		var valueStr, feeStr string
+		var valueStr, feeStr, gasPriceStr string
+		var maxFee, maxPriorityFee sql.NullString
 		var payload []byte
 		var blockIDNull sql.NullInt64
 		var contractIDNull sql.NullInt64
@@ -630,9 +961,15 @@ func LoadPendingTransactionsFromDB(ctx context.Context, pool *pgxpool.Pool) ([]*
 			&tx.Status,
 			&tx.Timestamp,
 			&contractIDNull,
+			&tx.GasLimit,
+			&gasPriceStr,
+			&tx.GasUsed,
+			&maxFee,
+			&maxPriorityFee,
 		); err != nil {
 			continue
 		}
+		tx.setGasPriceColumns(gasPriceStr, maxFee, maxPriorityFee)
 		tx.Data = payload
 		if len(tx.Payload) == 0 {
 			tx.Payload = payload
@@ -662,14 +999,17 @@ func LoadTransactionByHash(ctx context.Context, pool *pgxpool.Pool, hash string)
 		return nil, errors.New("pool or hash empty")
 	}
 	var tx Transaction
-	var valueStr, feeStr string
+	var valueStr, feeStr, gasPriceStr string
+	var maxFee, maxPriorityFee sql.NullString
 	var payload []byte
 	var blockIDNull sql.NullInt64
 	var contractIDNull sql.NullInt64
 	var sigStr sql.NullString
 	var isVerifiedNull sql.NullBool
 	err := pool.QueryRow(ctx, `
-		SELECT block_id, hash, sender, recipient, value, fee, nonce, type, payload, status, timestamp, contract_id, signature, is_verified
+		SELECT block_id, hash, sender, recipient, value, fee, nonce, type, payload, status, timestamp, contract_id, signature, is_verified,
+			COALESCE(gas_limit, 0), COALESCE(gas_price, 0)::text, COALESCE(gas_used, 0),
+			max_fee_per_gas::text, max_priority_fee_per_gas::text
 		FROM transactions
 		WHERE hash = $1
 		ORDER BY block_id DESC NULLS LAST
@@ -688,6 +1028,11 @@ func LoadTransactionByHash(ctx context.Context, pool *pgxpool.Pool, hash string)
 		&contractIDNull,
 		&sigStr,
 		&isVerifiedNull,
+		&tx.GasLimit,
+		&gasPriceStr,
+		&tx.GasUsed,
+		&maxFee,
+		&maxPriorityFee,
 	)
 	if err != nil {
 		return nil, err
@@ -700,6 +1045,7 @@ func LoadTransactionByHash(ctx context.Context, pool *pgxpool.Pool, hash string)
 	tx.Value, _ = new(big.Int).SetString(valueStr, 10)
 	tx.Fee, _ = new(big.Int).SetString(feeStr, 10)
 	tx.IsVerified = isVerifiedNull.Valid && isVerifiedNull.Bool
+	tx.setGasPriceColumns(gasPriceStr, maxFee, maxPriorityFee)
 	if sigStr.Valid && sigStr.String != "" {
 		tx.Signature, _ = hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(sigStr.String, "0x"), "0X"))
 	}
@@ -719,7 +1065,12 @@ func loadTransactionsForBlock(ctx context.Context, pool *pgxpool.Pool, blockID i
 			type, 
 			payload, 
 			status, 
-			timestamp 
+			timestamp,
+			COALESCE(gas_limit, 0),
+			COALESCE(gas_price, 0)::text,
+			COALESCE(gas_used, 0),
+			max_fee_per_gas::text,
+			max_priority_fee_per_gas::text
 		FROM transactions 
 		WHERE block_id = $1`, blockID)
 	if err != nil {
@@ -730,7 +1081,8 @@ func loadTransactionsForBlock(ctx context.Context, pool *pgxpool.Pool, blockID i
 	var txs []*Transaction
 	for rows.Next() {
 		var tx Transaction
-		var valueStr string
+		var valueStr, gasPriceStr string
+		var maxFee, maxPriorityFee sql.NullString
 		var payload []byte
 
 		err := rows.Scan(
@@ -744,6 +1096,11 @@ func loadTransactionsForBlock(ctx context.Context, pool *pgxpool.Pool, blockID i
 			&payload,
 			&tx.Status,
 			&tx.Timestamp,
+			&tx.GasLimit,
+			&gasPriceStr,
+			&tx.GasUsed,
+			&maxFee,
+			&maxPriorityFee,
 		)
 		if err != nil {
 			return nil, err
@@ -751,45 +1108,150 @@ func loadTransactionsForBlock(ctx context.Context, pool *pgxpool.Pool, blockID i
 
 		tx.Data = payload
 		tx.Value, _ = new(big.Int).SetString(valueStr, 10)
+		tx.setGasPriceColumns(gasPriceStr, maxFee, maxPriorityFee)
 		txs = append(txs, &tx)
 	}
 	return txs, nil
 }
 
-// AddBlock добавляет новый блок в цепочку
+// AddBlock добавляет новый блок в цепочку. Блок, продолжающий не вершину, а более ранний блок цепи или боковую ветку
+// (только с движком консенсуса), сохраняется в дереве блоков; если его ветка тяжелее текущей, цепь реорганизуется (forkchoice.go).
 func (bc *Blockchain) AddBlock(block *Block) error {
 	bc.mutex.Lock()
 	defer bc.mutex.Unlock()
 
-	if !bc.validateBlock(block) {
+	if known, _ := bc.findBlockLocked(block.Hash); known != nil {
+		return fmt.Errorf("блок %d (%s) уже известен", block.Index, block.Hash)
+	}
+	var tip *Block
+	if len(bc.Blocks) > 0 {
+		tip = bc.Blocks[len(bc.Blocks)-1]
+	}
+	parent := tip
+	if known, _ := bc.findBlockLocked(block.PrevHash); known != nil {
+		parent = known
+	}
+	if !bc.validateBlock(block, parent) {
 		return errors.New("invalid block")
 	}
+	if bc.Engine != nil {
+		if err := bc.Engine.VerifyBlock(block, parent); err != nil {
+			return fmt.Errorf("invalid block proposer: %w", err)
+		}
+	}
+	if parent != tip {
+		return bc.addSideBlockLocked(block, parent)
+	}
+	return bc.connectBlockLocked(block, parent)
+}
+
+// setPendingFeeContext переключает списание газа вне блоков на base fee блока-потомка head.
+func (bc *Blockchain) setPendingFeeContext(head *Block) {
+	if st, ok := bc.State.(*State); ok {
+		st.SetFeeContext(CalcBaseFee(head), "")
+	}
+}
+
+// PendingBaseFee возвращает base fee следующего блока (потомка вершины цепи).
+func (bc *Blockchain) PendingBaseFee() *big.Int {
+	last, err := bc.LatestBlock()
+	if err != nil {
+		return big.NewInt(InitialBaseFee)
+	}
+	return CalcBaseFee(last)
+}
+
+// ExecuteBlock исполняет блок, продолжающий вершину цепи, и заполняет в заголовке base fee, газ, state_root и хеш
+// (до подписи движком консенсуса). Состояние цепи после исполнения возвращается к исходному, в том числе в БД:
+// блок применяется заново в AddBlock, который сверяет заголовок с исполнением.
+func (bc *Blockchain) ExecuteBlock(block *Block) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	tip, err := bc.LatestBlock()
+	if err != nil {
+		return err
+	}
+	prevHash := tip.Hash
+	if prevHash == "" {
+		prevHash = "0"
+	}
+	if block.Index != tip.Index+1 || block.PrevHash != prevHash {
+		return fmt.Errorf("блок %d (родитель %s) не продолжает вершину цепи %d (%s)", block.Index, block.PrevHash, tip.Index, prevHash)
+	}
+	if err := bc.executeBlockLocked(block, tip); err != nil {
+		return err
+	}
+	block.Hash = block.CalculateHash()
+	return nil
+}
+
+// executeBlockLocked исполняет блок поверх вершины цепи parent, записывает в заголовок газ и state_root и возвращает
+// состояние и реестр стейкинга к исходным. Вызывается под блокировкой цепи.
+func (bc *Blockchain) executeBlockLocked(block, parent *Block) error {
+	st, ok := bc.State.(*State)
+	if !ok {
+		return errors.New("состояние цепи не поддерживает откат после исполнения")
+	}
+	cp := st.checkpoint()
+	if bc.Stakes != nil {
+		cp.stakes = bc.Stakes.snapshot()
+	}
+	block.BaseFee = CalcBaseFee(parent)
+	_, block.GasUsed = bc.applyBlock(block)
+	block.StateRoot = st.RootHash()
+	bc.revertStateLocked(cp)
+	bc.setPendingFeeContext(parent)
+	return nil
+}
+
+// connectBlockLocked применяет блок к состоянию, сохраняет его и добавляет на вершину цепи (parent — текущая вершина).
+// Газ и state_root заголовка должны совпасть с исполнением (собственный блок исполняется до подписи в ProduceNextBlock):
+// блок без state_root или с расхождением отклоняется, состояние возвращается к parent. Вызывается под блокировкой цепи.
+func (bc *Blockchain) connectBlockLocked(block, parent *Block) error {
+	// С движком консенсуса блок финализируется только голосами валидаторов
+	if bc.Engine != nil {
+		block.Status = BlockStatusProposed
+		block.IsFinalized = false
+	}
+	// Состояние после родителя запоминается для отката и повторного исполнения блока (трассировка), если ещё не сохранено
+	bc.saveCheckpointLocked(parent)
+	if parent != nil && parent.ID != 0 {
+		parentID := parent.ID
+		block.ParentID = &parentID
+	}
+
+	// base fee выводится из родителя каждой нодой и в заголовок не подписывается
+	block.BaseFee = CalcBaseFee(parent)
+
+	// Применяем транзакции к состоянию и сверяем заголовок с результатом
+	receipts, gasUsed := bc.applyBlock(block)
+	root := ""
+	if st, ok := bc.State.(*State); ok {
+		root = st.RootHash()
+	}
+	var mismatch error
+	switch {
+	case gasUsed != block.GasUsed:
+		mismatch = fmt.Errorf("блок %d: использовано %d газа, в заголовке %d", block.Index, gasUsed, block.GasUsed)
+	case block.StateRoot != root:
+		mismatch = fmt.Errorf("блок %d: state_root после исполнения %s, в заголовке %q", block.Index, root, block.StateRoot)
+	}
+	if mismatch != nil {
+		bc.revertStateLocked(bc.checkpoints[parent.Hash])
+		bc.setPendingFeeContext(parent)
+		return mismatch
+	}
+	// Состояние сразу после блока — для отката к нему и доказательств по state_root (изменения вне блоков в него не входят)
+	bc.saveCheckpointLocked(block)
 
 	// Сохраняем блок в БД (tx_count, created_at, updated_at; block.ID заполняется)
 	if err := bc.storeBlock(block); err != nil {
 		return fmt.Errorf("failed to store block: %v", err)
 	}
+	block.IsOrphaned = false
 
-	// Обновляем существующую запись транзакции (pending): выставляем block_id, status и contract_id по recipient
-	if bc.Pool != nil && block.ID != 0 {
-		ctx := context.Background()
-		for _, tx := range block.Transactions {
-			if tx.Timestamp.IsZero() {
-				tx.Timestamp = block.Timestamp
-			}
-			tx.BlockID = int(block.ID)
-			if _, err := bc.Pool.Exec(ctx, `
-				UPDATE transactions SET block_id = $1, status = 'confirmed',
-					contract_id = (SELECT id FROM contracts WHERE address = $2 LIMIT 1)
-				WHERE hash = $3 AND block_id IS NULL`,
-				block.ID, tx.Recipient.String(), tx.Hash); err != nil {
-				fmt.Printf("предупреждение: не удалось обновить транзакцию %s для блока %d: %v\n", tx.Hash, block.ID, err)
-			}
-		}
-	}
-
-	// Применяем транзакции к состоянию
-	bc.applyBlock(block)
+	bc.storeReceipts(block, receipts)
+	bc.storeBlockTransactions(block)
 
 	// Сохраняем состояние (accounts, account_states, contract_storage при blockID > 0)
 	if bc.Pool != nil && bc.State != nil {
@@ -801,68 +1263,169 @@ func (bc *Blockchain) AddBlock(block *Block) error {
 			fmt.Printf("предупреждение: не удалось сохранить состояние после блока %d: %v\n", block.ID, err)
 		}
 		if st, ok := bc.State.(*State); ok {
-			// state_root — хеш состояния после применения блока
-			block.StateRoot = st.RootHash()
-			if block.ID != 0 {
-				_, _ = bc.Pool.Exec(context.Background(), `UPDATE blocks SET state_root = $1 WHERE id = $2`, block.StateRoot, block.ID)
-			}
 			st.ClearTouched()
 		}
+		if bc.Stakes != nil {
+			if err := bc.Stakes.SaveToDB(context.Background(), bc.Pool); err != nil {
+				fmt.Printf("предупреждение: не удалось сохранить реестр стейкинга после блока %d: %v\n", block.ID, err)
+			}
+		}
 	}
 
 	// Добавляем блок в цепочку
 	bc.Blocks = append(bc.Blocks, block)
+	bc.pruneForksLocked()
+	bc.setPendingFeeContext(block)
 
 	// Обновляем метрики блоков и транзакций для актуальных значений в API
 	if m := GetMetrics(); m != nil {
 		m.UpdateBlockMetrics(block)
 		for _, tx := range block.Transactions {
-			m.UpdateTransactionMetrics(tx, "confirmed")
+			m.UpdateTransactionMetrics(tx, tx.Status)
 		}
 	}
 
+	// Транзакции с использованным nonce уходят из мемпула, queued с заполненным пропуском переходят в pending
+	if bc.Mempool != nil {
+		bc.Mempool.Reset()
+	}
+	bc.notifyBlock(block, receipts)
 	return nil
 }
 
-// ProcessTransaction обрабатывает транзакцию
-func (bc *Blockchain) ProcessTransaction(tx *Transaction) error {
-	if err := bc.ValidateTransaction(tx); err != nil {
-		return err
+// storeBlockTransactions привязывает транзакции блока к block_id: обновляет ожидающую запись (pending) — block_id,
+// status (confirmed/failed), contract_id по recipient, фактические комиссия и газ; транзакции, которых нет в БД
+// (блок получен от пира), записываются.
+func (bc *Blockchain) storeBlockTransactions(block *Block) {
+	if bc.Pool == nil || block.ID == 0 {
+		return
 	}
-
-	if tx.IsContractCall() {
-		return bc.processContract(tx)
+	ctx := context.Background()
+	for _, tx := range block.Transactions {
+		if tx.Timestamp.IsZero() {
+			tx.Timestamp = block.Timestamp
+		}
+		tx.BlockID = int(block.ID)
+		fee := "0"
+		if tx.Fee != nil {
+			fee = tx.Fee.String()
+		}
+		ct, err := bc.Pool.Exec(ctx, `
+			UPDATE transactions SET block_id = $1, status = $6,
+				contract_id = (SELECT id FROM contracts WHERE address = $2 LIMIT 1),
+				fee = $4, gas_used = $5
+			WHERE hash = $3 AND block_id IS NULL`,
+			block.ID, tx.Recipient.String(), tx.Hash, fee, tx.GasUsed, tx.Status)
+		if err == nil && ct.RowsAffected() == 0 {
+			err = tx.SaveToDB(ctx, bc.Pool)
+		}
+		if err != nil {
+			fmt.Printf("предупреждение: не удалось обновить транзакцию %s для блока %d: %v\n", tx.Hash, block.ID, err)
+		}
 	}
+}
 
-	return bc.processTransfer(tx)
+// storeReceipts дополняет квитанции данными блока (id, финальный хеш), кэширует их в памяти и сохраняет в таблицу receipts,
+// а события контрактов — в contract_logs.
+func (bc *Blockchain) storeReceipts(block *Block, receipts []*Receipt) {
+	bc.receiptsMu.Lock()
+	if bc.receipts == nil {
+		bc.receipts = make(map[string]*Receipt)
+	}
+	for _, r := range receipts {
+		r.BlockID = block.ID
+		r.BlockHash = block.Hash
+		bc.receipts[r.TxHash] = r
+	}
+	bc.receiptsMu.Unlock()
+	if bc.Pool == nil {
+		return
+	}
+	ctx := context.Background()
+	for _, r := range receipts {
+		if err := r.SaveToDB(ctx, bc.Pool); err != nil {
+			fmt.Printf("предупреждение: не удалось сохранить квитанцию %s: %v\n", r.TxHash, err)
+		}
+	}
+	if err := saveContractLogs(ctx, bc.Pool, block.ID, blockContractLogs(block, receipts)); err != nil {
+		fmt.Printf("предупреждение: не удалось сохранить события блока %d: %v\n", block.Index, err)
+	}
 }
 
-// processTransfer обрабатывает перевод нативной монеты (GND или GANI)
-func (bc *Blockchain) processTransfer(tx *Transaction) error {
-	if !tx.HasSufficientBalance() {
-		return errors.New("insufficient balance")
+// GetReceipt возвращает квитанцию транзакции: из памяти, затем из таблицы receipts; для подтверждённых транзакций
+// без сохранённой квитанции (системные, до появления квитанций) квитанция строится по записи transactions.
+func (bc *Blockchain) GetReceipt(ctx context.Context, hash string) (*Receipt, error) {
+	bc.receiptsMu.RLock()
+	r, ok := bc.receipts[hash]
+	bc.receiptsMu.RUnlock()
+	if ok {
+		return r, nil
 	}
-	symbol := tx.Symbol
-	if symbol == "" {
-		symbol = GasSymbol
+	if bc.Pool == nil {
+		return nil, errors.New("receipt not found")
 	}
-	if !IsNativeSymbol(symbol) {
-		return errors.New("symbol must be GND or GANI for native transfer")
+	r, err := LoadReceipt(ctx, bc.Pool, hash)
+	if err != nil {
+		return nil, err
 	}
-	if err := bc.State.SubBalance(types.Address(tx.Sender), symbol, tx.Value); err != nil {
-		return err
+	if r != nil {
+		return r, nil
+	}
+	tx, err := LoadTransactionByHash(ctx, bc.Pool, hash)
+	if err != nil || tx == nil {
+		return nil, errors.New("receipt not found")
 	}
-	if err := bc.State.AddBalance(types.Address(tx.Recipient), symbol, tx.Value); err != nil {
-		bc.State.AddBalance(types.Address(tx.Sender), symbol, tx.Value)
+	if tx.BlockID <= 0 {
+		return nil, errors.New("transaction is pending")
+	}
+	blockNumber, _ := GetBlockIndexByID(ctx, bc.Pool, int64(tx.BlockID))
+	r = ReceiptFromTransaction(tx, blockNumber)
+	if b, err := GetBlockByNumber(bc.Pool, blockNumber); err == nil && b != nil {
+		r.BlockHash = b.Hash
+	}
+	return r, nil
+}
+
+// BlockReceipts возвращает квитанции всех транзакций блока в порядке включения (транзакции без квитанции пропускаются).
+func (bc *Blockchain) BlockReceipts(ctx context.Context, block *Block) ([]*Receipt, error) {
+	txs := block.Transactions
+	if len(txs) == 0 && bc.Pool != nil && block.ID != 0 {
+		loaded, err := LoadTransactionsForBlock(ctx, bc.Pool, block.ID)
+		if err != nil {
+			return nil, err
+		}
+		txs = loaded
+	}
+	receipts := make([]*Receipt, 0, len(txs))
+	for _, tx := range txs {
+		r, err := bc.GetReceipt(ctx, tx.Hash)
+		if err != nil || r == nil {
+			continue
+		}
+		receipts = append(receipts, r)
+	}
+	return receipts, nil
+}
+
+// ProcessTransaction проверяет пользовательскую транзакцию и ставит её в мемпул. Переводы, вызовы контрактов и стейкинг
+// исполняются только в блоке; принятая в мемпул транзакция рассылается пирам (Mempool.OnTx).
+func (bc *Blockchain) ProcessTransaction(tx *Transaction) error {
+	if IsSystemTransaction(tx) {
+		return errors.New("системные транзакции создаются только нодой")
+	}
+	if err := bc.ValidateTransaction(tx); err != nil {
 		return err
 	}
-	return nil
+	return bc.submitTransaction(tx)
 }
 
-// processContract обрабатывает вызов контракта: добавляет транзакцию в мемпул и записывает в БД (таблица transactions),
-// чтобы все вызовы (transfer, approve и т.д.) включались в транзакции блокчейна.
-func (bc *Blockchain) processContract(tx *Transaction) error {
-	if tx.Type == "" {
+// submitTransaction добавляет проверенную транзакцию в мемпул и записывает в БД (таблица transactions, block_id = NULL).
+// Перевод должен быть в нативной монете (GND или GANI): остальные токены переводятся вызовом контракта.
+func (bc *Blockchain) submitTransaction(tx *Transaction) error {
+	if !tx.IsContractCall() && !IsStakingTx(tx) && tx.Symbol != "" && !IsNativeSymbol(tx.Symbol) {
+		return errors.New("symbol must be GND or GANI for native transfer")
+	}
+	if tx.Type == "" && tx.IsContractCall() {
 		tx.Type = "contract_call"
 	}
 	if tx.Status == "" {
@@ -876,46 +1439,67 @@ func (bc *Blockchain) processContract(tx *Transaction) error {
 		tx.Hash = tx.CalculateHash()
 	}
 	if bc.Mempool != nil {
-		bc.Mempool.Add(tx)
+		if err := bc.Mempool.Add(tx); err != nil {
+			return fmt.Errorf("мемпул: %w", err)
+		}
 	}
 	if bc.Pool != nil {
 		if err := tx.SaveToDB(context.Background(), bc.Pool); err != nil {
-			return fmt.Errorf("сохранение транзакции вызова контракта: %w", err)
+			return fmt.Errorf("сохранение транзакции: %w", err)
 		}
 	}
 	return nil
 }
 
+// AddPeerTransaction принимает транзакцию, полученную от другой ноды: проверка как у SendTransaction (подпись, баланс, nonce),
+// затем мемпул и БД. Системные транзакции от пиров отклоняются.
+func (bc *Blockchain) AddPeerTransaction(tx *Transaction) error {
+	if IsSystemTransaction(tx) {
+		return errors.New("системные транзакции от пиров не принимаются")
+	}
+	if err := bc.ValidateTransaction(tx); err != nil {
+		return err
+	}
+	return bc.submitTransaction(tx)
+}
+
 // ValidateTransaction проверяет транзакцию (формат, подпись, баланс, nonce).
 func (bc *Blockchain) ValidateTransaction(tx *Transaction) error {
 	if err := tx.Validate(); err != nil {
 		return err
 	}
+	if err := validateDeployTx(tx); err != nil {
+		return err
+	}
 
-	// Проверка подписи для пользовательских транзакций (системные пропускаем).
+	// Лимит газа должен покрывать базовую стоимость и помещаться в блок
 	if !IsSystemTransaction(tx) {
-		if tx.Hash == "" {
-			tx.Hash = tx.CalculateHash()
+		if intrinsic := tx.IntrinsicGas(); tx.GasLimit < intrinsic {
+			return fmt.Errorf("intrinsic gas too low: have %d, want %d", tx.GasLimit, intrinsic)
 		}
-		if len(tx.Signature) == 0 {
-			return errors.New("транзакция должна быть подписана (signature обязателен)")
+		if tx.GasLimit > DefaultBlockGasLimit {
+			return fmt.Errorf("gas limit %d exceeds block gas limit %d", tx.GasLimit, DefaultBlockGasLimit)
 		}
-		if tx.SenderPublicKeyHex == "" {
-			return errors.New("для проверки подписи укажите sender_public_key (hex публичного ключа P-256)")
+		// Наибольшая цена газа должна покрывать base fee следующего блока, иначе транзакция не попадёт в блок
+		if baseFee := bc.PendingBaseFee(); tx.EffectiveGasPrice().Cmp(baseFee) < 0 {
+			return fmt.Errorf("max fee per gas less than block base fee: have %s, want %s", tx.EffectiveGasPrice(), baseFee)
 		}
-		pubKey, err := crypto.ParsePublicKeyHex(tx.SenderPublicKeyHex)
-		if err != nil {
-			return fmt.Errorf("неверный sender_public_key: %w", err)
-		}
-		if !VerifyTransactionSignature(tx, pubKey) {
-			return errors.New("неверная подпись транзакции")
+	}
+
+	// Защита от повтора в другой сети: chain_id и subnet_id входят в подписанные данные и должны совпадать с сетью ноды
+	if !IsSystemTransaction(tx) && (tx.ChainID != bc.ChainID || tx.SubnetID != bc.SubnetID) {
+		return fmt.Errorf("%w: chain_id %d, subnet_id %q, у ноды %d, %q", ErrWrongChain, tx.ChainID, tx.SubnetID, bc.ChainID, bc.SubnetID)
+	}
+
+	// Проверка подписи для пользовательских транзакций (системные пропускаем).
+	if !IsSystemTransaction(tx) {
+		if hash := tx.CalculateHash(); tx.Hash == "" {
+			tx.Hash = hash
+		} else if tx.Hash != hash {
+			return fmt.Errorf("хеш транзакции %s не соответствует её полям (%s)", tx.Hash, hash)
 		}
-		// Проверка соответствия адреса ключу (формат 64 hex)
-		if tx.Sender.IsValid() {
-			derivedAddr := crypto.PublicKeyToAddressP256(pubKey)
-			if derivedAddr != tx.Sender.String() {
-				return errors.New("адрес отправителя не соответствует публичному ключу")
-			}
+		if err := VerifyTransactionSignature(tx); err != nil {
+			return err
 		}
 	}
 
@@ -935,10 +1519,9 @@ func (bc *Blockchain) ValidateTransaction(tx *Transaction) error {
 		return errors.New("insufficient balance")
 	}
 
-	// Проверяем nonce
-	expectedNonce := bc.State.GetNonce(types.Address(tx.Sender))
-	if tx.Nonce != expectedNonce {
-		return fmt.Errorf("invalid nonce: expected %d, got %d", expectedNonce, tx.Nonce)
+	// Использованный nonce отклоняется; будущий мемпул держит в queued до заполнения пропуска
+	if expectedNonce := bc.State.GetNonce(types.Address(tx.Sender)); tx.Nonce < expectedNonce {
+		return fmt.Errorf("nonce too low: expected %d, got %d", expectedNonce, tx.Nonce)
 	}
 
 	return nil
@@ -988,98 +1571,6 @@ func (bc *Blockchain) GetTransaction(hash string) (*Transaction, error) {
 	return nil, errors.New("transaction not found")
 }
 
-// DeployContract deploys a new contract
-func (bc *Blockchain) DeployContract(params *ContractParams) (string, error) {
-	// При наличии params и ABI дополняем bytecode ABI-кодированными аргументами конструктора
-	var bytecode []byte
-	if len(params.Params) > 0 && len(params.ABI) > 0 {
-		full, err := AppendConstructorArgs(params.Bytecode, params.ABI, params.Params)
-		if err != nil {
-			return "", fmt.Errorf("constructor args: %w", err)
-		}
-		bytecode = full
-	} else {
-		var err error
-		bytecode, err = hex.DecodeString(params.Bytecode)
-		if err != nil {
-			return "", fmt.Errorf("invalid bytecode: %v", err)
-		}
-	}
-
-	// Уникальный адрес контракта: hash(bytecode, from, nonce). При nonce=0 подставляем UnixNano, чтобы один кошелёк мог деплоить несколько контрактов без дубликата contracts_address_key
-	nonce := params.Nonce
-	if nonce == 0 {
-		nonce = uint64(time.Now().UnixNano())
-	}
-	contractAddress := generateContractAddress(bytecode, params.From, nonce)
-
-	// ABI для сохранения в БД (нужен для GetContractView — список методов чтения/записи)
-	abiBytes := []byte(params.ABI)
-	if len(abiBytes) == 0 {
-		abiBytes = nil
-	}
-
-	// Create new contract
-	contract := NewContract(
-		contractAddress,
-		params.From,
-		bytecode,
-		abiBytes,
-		params.Standard,
-		params.Version,
-		0, // blockID will be set when block is created
-		0, // txID will be set when transaction is created
-	)
-	contract.Creator = params.From
-	contract.Name = params.Name
-	if params.Symbol != "" {
-		contract.Symbol = params.Symbol
-	} else {
-		contract.Symbol = params.Standard
-	}
-	contract.Standard = params.Standard
-	contract.Description = params.Description
-	contract.License = params.License
-	contract.MetadataCID = params.MetadataCID
-	if params.Owner != "" {
-		contract.Owner = params.Owner
-	} else {
-		contract.Owner = params.From
-	}
-	contract.SourceCode = params.SourceCode
-	contract.Compiler = params.Compiler
-	if len(params.Metadata) > 0 {
-		contract.Metadata = params.Metadata
-	}
-	if len(params.Params) > 0 {
-		contract.Params, _ = json.Marshal(params.Params)
-	}
-	// code записывается в БД: при деплое подставляем bytecode
-	contract.Code = bytecode
-
-	// Save contract to database
-	if err := contract.SaveToDB(context.Background(), bc.Pool); err != nil {
-		return "", fmt.Errorf("failed to save contract: %v", err)
-	}
-
-	// Записываем начальный storage (слот 0 = _totalSupply) для корректного чтения totalSupply() через CallStatic
-	ctx := context.Background()
-	blockID := int64(0)
-	if bc.Genesis != nil {
-		blockID = bc.Genesis.ID
-	}
-	if blockID <= 0 && bc.Pool != nil {
-		_ = bc.Pool.QueryRow(ctx, `SELECT id FROM blocks WHERE index = 0 LIMIT 1`).Scan(&blockID)
-	}
-	if blockID > 0 && bc.Pool != nil && len(params.Params) > 0 {
-		if errInit := WriteInitialStorageForDeployedContract(ctx, bc.Pool, blockID, contract.Address, params.Params); errInit != nil {
-			log.Printf("[DeployContract] запись начального storage для %s: %v", contract.Address, errInit)
-		}
-	}
-
-	return contract.Address, nil
-}
-
 // generateContractAddress формирует уникальный адрес контракта по bytecode, адресу создателя и nonce (избегает дубликата contracts_address_key)
 func generateContractAddress(bytecode []byte, creator string, nonce uint64) string {
 	h := sha256.New()
@@ -3,10 +3,13 @@ package core
 
 import (
 	"context"
+	"math/big"
 	"testing"
 	"time"
 
 	"GND/types"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 )
 
 func TestGenesisTimestamp_InFirstPartitionRange(t *testing.T) {
@@ -67,8 +70,9 @@ func TestAddBlock_WithNilPool_AppendsBlock(t *testing.T) {
 		Nonce:        0,
 		Status:       "finalized",
 		Transactions: []*Transaction{},
+		MerkleRoot:   ComputeMerkleRoot(nil),
 	}
-	block.Hash = block.CalculateHash()
+	executeTestBlock(t, bc, block)
 
 	err := bc.AddBlock(block)
 	if err != nil {
@@ -147,3 +151,328 @@ func TestStoreBlock_WithNilPool_DoesNotPanic(t *testing.T) {
 		t.Errorf("при pool=nil block.ID должен оставаться 0, получен %d", block.ID)
 	}
 }
+
+func TestIntrinsicGas(t *testing.T) {
+	if got := IntrinsicGas(nil, false); got != TxGas {
+		t.Errorf("перевод без данных: ожидалось %d, получено %d", TxGas, got)
+	}
+	// 4 ненулевых байта селектора + 32 байта аргумента (31 нулевой, 1 ненулевой)
+	data := append([]byte{0xa9, 0x05, 0x9c, 0xbb}, make([]byte, 32)...)
+	data[35] = 1
+	want := TxGas + 5*TxDataNonZeroGas + 31*TxDataZeroGas
+	if got := IntrinsicGas(data, false); got != want {
+		t.Errorf("вызов контракта: ожидалось %d, получено %d", want, got)
+	}
+	if got := IntrinsicGas(make([]byte, 33), true); got != TxGasContractCreation+2*InitCodeWordGas+33*TxDataZeroGas {
+		t.Errorf("деплой: неверный газ %d", got)
+	}
+}
+
+func TestCalcBaseFee(t *testing.T) {
+	block := func(baseFee int64, gasUsed uint64) *Block {
+		return &Block{BaseFee: big.NewInt(baseFee), GasLimit: 1000, GasUsed: gasUsed}
+	}
+	tests := []struct {
+		parent *Block
+		want   int64
+	}{
+		{&Block{GasLimit: 1000, GasUsed: 1000}, InitialBaseFee}, // родитель до рынка комиссий
+		{block(800, 500), 800},  // ровно целевой газ
+		{block(800, 1000), 900}, // полный блок: +1/8
+		{block(800, 0), 700},    // пустой блок: -1/8
+		{block(800, 750), 850},
+		{block(2, 1000), 3}, // изменение не меньше 1
+		{block(MinBaseFee, 0), MinBaseFee},
+	}
+	for _, tt := range tests {
+		if got := CalcBaseFee(tt.parent); got.Int64() != tt.want {
+			t.Errorf("base fee %v, газ %d: ожидалось %d, получено %s", tt.parent.BaseFee, tt.parent.GasUsed, tt.want, got)
+		}
+	}
+}
+
+func TestChargeGas_DynamicFeeBurnsBaseFee(t *testing.T) {
+	st := NewState()
+	sender := types.Address("GN_sender")
+	if err := st.AddBalance(sender, GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	st.SetFeeContext(big.NewInt(10), "GN_proposer")
+
+	// цена газа — min(max_fee 12, base fee 10 + чаевые 5) = 12: 10 сжигается, 2 — чаевые
+	tx := &Transaction{Sender: sender, MaxFeePerGas: big.NewInt(12), MaxPriorityFeePerGas: big.NewInt(5)}
+	charge, err := st.ChargeGas(tx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if charge.Total.Int64() != 1200 || charge.Burned.Int64() != 1000 || charge.Tip.Int64() != 200 || charge.Treasury != "" {
+		t.Fatalf("списание %+v", charge)
+	}
+	if got := st.GetBalance(sender, GasSymbol); got.Int64() != 1_000_000-1200 {
+		t.Errorf("отправитель: получено %s", got)
+	}
+	if got := st.GetBalance("GN_proposer", GasSymbol); got.Int64() != 200 {
+		t.Errorf("предлагающий блок: ожидалось 200, получено %s", got)
+	}
+
+	if _, err := st.ChargeGas(&Transaction{Sender: sender, MaxFeePerGas: big.NewInt(9), MaxPriorityFeePerGas: big.NewInt(1)}, 100); err == nil {
+		t.Error("max_fee_per_gas ниже base fee должен отклоняться")
+	}
+	if _, err := st.ChargeGas(&Transaction{Sender: sender, GasPrice: big.NewInt(9)}, 100); err == nil {
+		t.Error("gas_price ниже base fee должен отклоняться")
+	}
+}
+
+func TestApplyExecutionResult_RevertsWholeResultOnError(t *testing.T) {
+	st := NewState()
+	sender := types.Address("GN_exec_sender")
+	if err := st.AddBalance(sender, GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	st.SetFeeContext(big.NewInt(1), "GN_proposer")
+	root := st.RootHash()
+
+	slot := make([]byte, 32)
+	result := &types.ExecutionResult{GasUsed: 100, StateChanges: []*types.StateChange{
+		types.NewStateChange(types.ChangeTypeBalance, "GN_exec_recipient", GasSymbol, big.NewInt(5)),
+		types.NewNonceChange("GN_exec_contract", 1),
+		types.NewStorageChange("GN_exec_contract", slot, []byte{0x01}),
+		// списание больше баланса — результат не применяется
+		types.NewStateChange(types.ChangeTypeBalance, "GN_exec_contract", GasSymbol, big.NewInt(-1)),
+	}}
+	tx := &Transaction{Sender: sender, GasPrice: big.NewInt(1)}
+	if err := st.ApplyExecutionResult(tx, result); err == nil {
+		t.Fatal("списание сверх баланса должно отклонять результат")
+	}
+	if st.RootHash() != root {
+		t.Error("частично применённый результат и комиссия должны быть отменены")
+	}
+	st.mutex.RLock()
+	changes := len(st.storageChanges)
+	st.mutex.RUnlock()
+	if changes != 0 {
+		t.Errorf("изменения storage не должны попасть в contract_storage: %d", changes)
+	}
+}
+
+func TestAddBlock_BaseFeeToTreasuryTipToProposer(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+	st := bc.State.(*State)
+	SetState(st)
+	defer SetState(nil)
+
+	wallet := newTestWallet(t)
+	sender := types.Address(wallet.Address)
+	recipient := types.Address("GN_recipient")
+	treasury := "GN_treasury"
+	st.SetFeeCollectorAddress("GN_fee_collector")
+	st.SetTreasuryAddress(treasury)
+	if err := st.AddBalance(sender, GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := signTestTx(t, wallet, &Transaction{Recipient: recipient, Value: big.NewInt(100), GasLimit: 30000, GasPrice: big.NewInt(2), Symbol: GasSymbol})
+	block := &Block{Index: 1, PrevHash: genesis.Hash, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: "finalized", Transactions: []*Transaction{tx}}
+	block.MerkleRoot = ComputeMerkleRoot(block.Transactions)
+	executeTestBlock(t, bc, block)
+	if st.GetBalance(recipient, GasSymbol).Sign() != 0 {
+		t.Fatal("исполнение до подписи не должно менять состояние цепи")
+	}
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	if block.GasUsed != TxGas || tx.GasUsed != TxGas {
+		t.Errorf("GasUsed блока/транзакции: ожидалось %d, получено %d/%d", TxGas, block.GasUsed, tx.GasUsed)
+	}
+	if block.StateRoot != st.RootHash() {
+		t.Error("state_root заголовка должен совпасть с состоянием после блока")
+	}
+	if block.BaseFee == nil || block.BaseFee.Int64() != InitialBaseFee {
+		t.Fatalf("base fee первого блока: ожидалось %d, получено %v", InitialBaseFee, block.BaseFee)
+	}
+	fee := big.NewInt(int64(TxGas) * 2)
+	if tx.Fee.Cmp(fee) != 0 || CalculateTxFee(tx).Cmp(fee) != 0 {
+		t.Errorf("комиссия: ожидалось %s, получено %s", fee, tx.Fee)
+	}
+	// base fee 1 × 21000 — в казну, чаевые (2 - 1) × 21000 — предлагающему блок
+	if got := st.GetBalance(types.Address(treasury), GasSymbol); got.Int64() != int64(TxGas) {
+		t.Errorf("казна: ожидалось %d, получено %s", TxGas, got)
+	}
+	if got := st.GetBalance("miner", GasSymbol); got.Int64() != int64(TxGas) {
+		t.Errorf("предлагающий блок: ожидалось %d, получено %s", TxGas, got)
+	}
+	if got := st.GetBalance("GN_fee_collector", GasSymbol); got.Sign() != 0 {
+		t.Errorf("сборщик комиссий не получает газ транзакций блока, получено %s", got)
+	}
+	wantSender := new(big.Int).Sub(big.NewInt(1_000_000-100), fee)
+	if got := st.GetBalance(sender, GasSymbol); got.Cmp(wantSender) != 0 {
+		t.Errorf("отправитель: ожидалось %s (неиспользованный газ не списан), получено %s", wantSender, got)
+	}
+	if got := st.BaseFee(); got.Cmp(CalcBaseFee(block)) != 0 {
+		t.Errorf("газ вне блоков списывается по base fee следующего блока %s, получено %s", CalcBaseFee(block), got)
+	}
+}
+
+func TestAddBlock_ExceedsBlockGasLimit_ReturnsError(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+
+	block := &Block{Index: 1, PrevHash: genesis.Hash, Timestamp: time.Now(), Miner: "miner", GasLimit: 30000, Consensus: "poa", Status: "finalized",
+		Transactions: []*Transaction{
+			{Sender: "GN_a", Recipient: "GN_b", Value: big.NewInt(0), GasLimit: 21000, Hash: "a"},
+			{Sender: "GN_a", Recipient: "GN_b", Value: big.NewInt(0), GasLimit: 21000, Nonce: 1, Hash: "b"},
+		}}
+	block.Hash = block.CalculateHash()
+	if err := bc.AddBlock(block); err == nil {
+		t.Fatal("блок с суммой лимитов газа больше лимита блока должен отклоняться")
+	}
+}
+
+func TestAddBlock_RejectsBlockNotExtendingTip(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+
+	for _, block := range []*Block{
+		{Index: 1, PrevHash: "other", Timestamp: time.Now(), Miner: "miner", Consensus: "poa"},
+		{Index: 2, PrevHash: genesis.Hash, Timestamp: time.Now(), Miner: "miner", Consensus: "poa"},
+	} {
+		block.Hash = block.CalculateHash()
+		if err := bc.AddBlock(block); err == nil {
+			t.Errorf("блок %d с родителем %s не продолжает генезис и должен отклоняться", block.Index, block.PrevHash)
+		}
+	}
+	if bc.Height() != 0 {
+		t.Errorf("высота цепи должна остаться 0, получено %d", bc.Height())
+	}
+}
+
+func TestAddBlock_FailedTransactionGetsFailedReceipt(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+	st := bc.State.(*State)
+	SetState(st)
+	defer SetState(nil)
+
+	wallet := newTestWallet(t)
+	if err := st.AddBalance(types.Address(wallet.Address), GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	ok := signTestTx(t, wallet, &Transaction{Recipient: "GN_recipient", Value: big.NewInt(100), GasLimit: TxGas, Symbol: GasSymbol})
+	// повтор nonce 0 — транзакция остаётся в блоке, но исполнение не проходит
+	bad := signTestTx(t, wallet, &Transaction{Recipient: "GN_recipient", Value: big.NewInt(200), GasLimit: TxGas, Symbol: GasSymbol})
+	block := &Block{Index: 1, PrevHash: genesis.Hash, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: "finalized", Transactions: []*Transaction{ok, bad}}
+	block.MerkleRoot = ComputeMerkleRoot(block.Transactions)
+	executeTestBlock(t, bc, block)
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := bc.GetReceipt(context.Background(), ok.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Succeeded() || r.GasUsed != TxGas || r.CumulativeGasUsed != TxGas || r.BlockHash != block.Hash {
+		t.Errorf("квитанция успешной транзакции: %+v", r)
+	}
+	r, err = bc.GetReceipt(context.Background(), bad.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != ReceiptStatusFailed || r.Error == "" || r.TxIndex != 1 {
+		t.Errorf("квитанция неуспешной транзакции: %+v", r)
+	}
+	if status, _ := bc.GetTxStatus(bad.Hash); status != TxStatusFailed {
+		t.Errorf("статус транзакции: ожидалось %q, получено %q", TxStatusFailed, status)
+	}
+}
+
+func TestAddBlock_RejectsInvalidTransactions(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+	bc.ChainID = 7
+	wallet := newTestWallet(t)
+	if err := bc.State.AddBalance(types.Address(wallet.Address), GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	newTx := func() *Transaction {
+		return signTestTx(t, wallet, &Transaction{ChainID: 7, Recipient: "GN_recipient", Value: big.NewInt(1), GasLimit: TxGas, Symbol: GasSymbol})
+	}
+
+	unsigned := newTx()
+	unsigned.Signature = nil
+	tampered := newTx()
+	tampered.Value = big.NewInt(1000)
+	otherChain := signTestTx(t, wallet, &Transaction{ChainID: 8, Recipient: "GN_recipient", Value: big.NewInt(1), GasLimit: TxGas, Symbol: GasSymbol})
+	system := &Transaction{Sender: "GND_GENESIS", Recipient: "GN_recipient", Value: big.NewInt(1), GasLimit: TxGas, Symbol: GasSymbol, IsVerified: true}
+	system.Hash = system.CalculateHash()
+	wrongRoot := childBlock(genesis, "miner", newTx())
+	wrongRoot.MerkleRoot = ComputeMerkleRoot(nil)
+	wrongRoot.Hash = wrongRoot.CalculateHash()
+	for name, block := range map[string]*Block{
+		"транзакция без подписи":        childBlock(genesis, "miner", unsigned),
+		"изменённая транзакция":         childBlock(genesis, "miner", tampered),
+		"транзакция другой сети":        childBlock(genesis, "miner", otherChain),
+		"системная транзакция":          childBlock(genesis, "miner", system),
+		"merkle_root других транзакций": wrongRoot,
+		"блок без state_root":           childBlock(genesis, "miner", newTx()),
+	} {
+		if err := bc.AddBlock(block); err == nil {
+			t.Errorf("блок должен отклоняться: %s", name)
+		}
+	}
+	if bc.Height() != 0 || bc.State.GetBalance("GN_recipient", GasSymbol).Sign() != 0 {
+		t.Errorf("отклонённые блоки не должны менять цепь и состояние: высота %d", bc.Height())
+	}
+	dup := newTx()
+	if err := bc.AddBlock(executeTestBlock(t, bc, childBlock(genesis, "miner", dup, dup))); err == nil {
+		t.Error("блок с повторённой транзакцией должен отклоняться")
+	}
+	first := newTx()
+	accepted := executeTestBlock(t, bc, childBlock(genesis, "miner", first))
+	if err := bc.AddBlock(accepted); err != nil {
+		t.Fatalf("блок с подписанной транзакцией своей сети должен приниматься: %v", err)
+	}
+	if err := bc.AddBlock(executeTestBlock(t, bc, childBlock(accepted, "miner", first))); err == nil {
+		t.Error("блок с транзакцией, уже включённой в цепь, должен отклоняться")
+	}
+}
+
+// newTestWallet создаёт кошелёк secp256k1 без БД: адрес выводится из ключа, поэтому его транзакции проходят проверку подписи.
+func newTestWallet(t *testing.T) *Wallet {
+	t.Helper()
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := AddressFromPublicKey(key.PubKey().SerializeCompressed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Wallet{PrivateKey: key, Address: Address(address)}
+}
+
+// signTestTx подписывает транзакцию от имени кошелька: заполняет Sender, Hash и Signature.
+func signTestTx(t *testing.T, w *Wallet, tx *Transaction) *Transaction {
+	t.Helper()
+	tx.Sender = types.Address(w.Address)
+	if err := w.SignTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// executeTestBlock исполняет блок поверх вершины цепи и заполняет газ, state_root и хеш заголовка, как ProduceNextBlock.
+func executeTestBlock(t *testing.T, bc *Blockchain, block *Block) *Block {
+	t.Helper()
+	if err := bc.ExecuteBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
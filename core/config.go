@@ -19,6 +19,8 @@ type NativeContractsConfig struct {
 	GaniContractAddress string `json:"gani_contract_address"`
 	FeeCollectorAddress string `json:"fee_collector_address"`
 	GndselfAddress      string `json:"gndself_address"` // мультиподписной кошелёк платформы; при owner = gndself_address комиссия за деплой не взимается
+
+	TreasuryAddress string `json:"treasury_address"` // казна: сюда зачисляется base fee; пусто — base fee сжигается
 }
 
 type Config struct {
@@ -33,6 +35,8 @@ type Config struct {
 	Coins           []CoinConfig             `json:"coins"`
 	Consensus       []map[string]interface{} `json:"consensus"`
 	EVM             EVMConfig                `json:"evm"`
+	Mempool         MempoolConfig            `json:"mempool"`
+	P2P             P2PConfig                `json:"p2p"`
 	Server          ServerConfig             `json:"server"`
 	DB              DBConfig                 `json:"database"`
 	NativeContracts *NativeContractsConfig   `json:"-"` // загружается из native_contracts.json
@@ -72,6 +76,8 @@ type ConsensusPosConfig struct {
 	AverageBlockDelay string `json:"average_block_delay"`
 	InitialBaseTarget int    `json:"initial_base_target"`
 	InitialBalance    string `json:"initial_balance"`
+	UnbondingBlocks   uint64 `json:"unbonding_blocks"` // через сколько блоков разблокируется unstake
+	BlockReward       string `json:"block_reward"`     // награда за блок PoS в минимальных единицах GND
 }
 
 type ConsensusPoaConfig struct {
@@ -92,6 +98,26 @@ type EVMConfig struct {
 	SolcPath string `json:"solc_path"` // путь к solc (например "solc" или "C:\\...\\solc.exe"); пусто — "solc"
 }
 
+// MempoolConfig — лимиты мемпула (config.json, секция "mempool"); нулевые значения заменяются значениями по умолчанию.
+type MempoolConfig struct {
+	MaxSize          int    `json:"max_size"`           // максимум транзакций в мемпуле; при переполнении вытесняется самая дешёвая
+	MaxPerAccount    int    `json:"max_per_account"`    // максимум транзакций одного отправителя (pending + queued)
+	PriceBumpPercent int    `json:"price_bump_percent"` // минимальное повышение цены газа (%) для замены транзакции с тем же nonce
+	TTL              string `json:"ttl"`                // время жизни транзакции в мемпуле, например "3h"
+}
+
+// P2PConfig — сеть между нодами (config.json, секция "p2p"): адрес приёма соединений и статический список пиров.
+type P2PConfig struct {
+	ListenAddr   string   `json:"listen_addr"`   // адрес входящих соединений, например ":30303"; пусто — ":" + port
+	Peers        []string `json:"peers"`         // статические пиры host:port, к которым нода подключается сама
+	MaxPeers     int      `json:"max_peers"`     // максимум одновременных соединений (0 — 25)
+	DialInterval string   `json:"dial_interval"` // период переподключения к статическим пирам, например "10s"
+	// SyncMode — режим загрузки цепи от пиров: "full" (по умолчанию) — все блоки; "fast" — снимок состояния
+	// на финализированной высоте и блоки после неё (только для новой ноды)
+	SyncMode         string `json:"sync_mode"`
+	SnapshotInterval uint64 `json:"snapshot_interval"` // каждые N блоков нода снимает состояние для быстрой синхронизации пиров (0 — 100)
+}
+
 type ServerRPCConfig struct {
 	RPCAddr string `json:"rpc_addr"`
 	Name    string `json:"name"`
@@ -194,6 +220,8 @@ func InitGlobalConfigDefault() (*GlobalConfig, error) {
 			Gani    string `json:"gani_contract_address"`
 			Fee     string `json:"fee_collector_address"`
 			Gndself string `json:"gndself_address"`
+
+			Treasury string `json:"treasury_address"`
 		}
 		if err := json.Unmarshal(data, &nc); err == nil {
 			cfg.NativeContracts = &NativeContractsConfig{
@@ -201,6 +229,7 @@ func InitGlobalConfigDefault() (*GlobalConfig, error) {
 				GaniContractAddress: strings.TrimSpace(nc.Gani),
 				FeeCollectorAddress: strings.TrimSpace(nc.Fee),
 				GndselfAddress:      strings.TrimSpace(nc.Gndself),
+				TreasuryAddress:     strings.TrimSpace(nc.Treasury),
 			}
 		}
 	}
@@ -5,10 +5,12 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -67,7 +69,9 @@ type ContractParams struct {
 	GasLimit    uint64                 `json:"gas_limit"`
 	GasPrice    *big.Int               `json:"gas_price"`
 	Nonce       uint64                 `json:"nonce"`
-	Signature   string                 `json:"signature"`
+	Signature   string                 `json:"signature"` // hex подписи транзакции деплоя (NewDeployTransaction)
+	PublicKey   string                 `json:"sender_public_key"`
+	KeyType     string                 `json:"key_type"`
 	TotalSupply *big.Int               `json:"total_supply"`
 }
 
@@ -256,6 +260,49 @@ func UpdateContractABI(ctx context.Context, pool *pgxpool.Pool, address string,
 	return nil
 }
 
+// LoadContractCode возвращает init-код контракта (bytecode или code, с аргументами конструктора) и runtime-код
+// (пусто, если контракт ещё не вызывался после миграции 019).
+func LoadContractCode(ctx context.Context, pool *pgxpool.Pool, address string) (initCode, runtimeCode []byte, err error) {
+	err = pool.QueryRow(ctx, `
+		SELECT COALESCE(bytecode, code), runtime_code
+		FROM contracts
+		WHERE address = $1`,
+		address,
+	).Scan(&initCode, &runtimeCode)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, fmt.Errorf("контракт не найден: %s", address)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка загрузки кода контракта: %w", err)
+	}
+	return initCode, runtimeCode, nil
+}
+
+// ContractVerification — исходный код и настройки компиляции, с которыми байткод контракта совпал при верификации.
+type ContractVerification struct {
+	SourceCode string
+	ABI        []byte
+	Compiler   string // версия solc
+	Optimized  bool
+	Runs       int
+}
+
+// MarkContractVerified отмечает контракт проверенным и сохраняет исходный код, ABI и настройки компилятора.
+func MarkContractVerified(ctx context.Context, pool *pgxpool.Pool, address string, v *ContractVerification) error {
+	cmd, err := pool.Exec(ctx, `
+		UPDATE contracts
+		SET is_verified = TRUE, source_code = $1, abi = $2, compiler = $3, optimized = $4, runs = $5, updated_at = NOW()
+		WHERE address = $6`,
+		v.SourceCode, v.ABI, v.Compiler, v.Optimized, v.Runs, address)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения верификации: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return fmt.Errorf("контракт не найден: %s", address)
+	}
+	return nil
+}
+
 // GetContractAddressByID возвращает адрес контракта по его id (для админки: call/send по id).
 func GetContractAddressByID(ctx context.Context, pool *pgxpool.Pool, id int) (string, error) {
 	var address string
@@ -70,7 +70,7 @@ func writeSetOwner(tx *Transaction) []*types.StateChange {
 }
 
 // buildContractCallExecutionResult по calldata вызова контракта строит ExecutionResult с изменениями storage,
-// чтобы ApplyExecutionResult записал слоты в contract_storage при SaveToDB.
+// чтобы ApplyExecutionResult записал слоты в contract_storage при SaveToDB. Газ: базовый + StorageWriteGas за каждый слот.
 // Поддерживаемые селекторы: setGndToken(address) — слот 0; setGaniToken(address) — слот 1; setOwner(address) — слот 2.
 func buildContractCallExecutionResult(tx *Transaction) *types.ExecutionResult {
 	if tx == nil || len(tx.Data) < 4 {
@@ -86,7 +86,7 @@ func buildContractCallExecutionResult(tx *Transaction) *types.ExecutionResult {
 		return nil
 	}
 	return &types.ExecutionResult{
-		GasUsed:      0,
+		GasUsed:      IntrinsicGas(tx.Data, false) + StorageWriteGas*uint64(len(changes)),
 		StateChanges: changes,
 		ReturnData:   nil,
 		Error:        nil,
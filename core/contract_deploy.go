@@ -1,19 +1,178 @@
 // | KB @CerberRus00 - Nexus Invest Team
-// Пакет core: склейка bytecode с ABI-кодированными аргументами конструктора при деплое.
+// Пакет core: деплой контракта транзакцией (подписанная транзакция типа deploy исполняется в блоке) и склейка bytecode
+// с ABI-кодированными аргументами конструктора.
 
 package core
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"math/big"
 	"strings"
 
+	"GND/core/crypto"
+	"GND/types"
+
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// deployGasMargin — запас к газу пробного исполнения конструктора, когда лимит газа деплоя подбирает нода (в процентах).
+const deployGasMargin = 20
+
+// NewDeployTransaction формирует транзакцию деплоя контракта по params: Data — bytecode с ABI-кодированными аргументами
+// конструктора, Recipient — адрес контракта, выведенный из отправителя, nonce и init-кода. Не заданные поля заполняет нода:
+// nonce — следующий nonce отправителя, цена газа — рекомендуемая (SuggestFees), лимит газа — газ пробного исполнения
+// конструктора с запасом. Подпись params.Signature должна покрывать итоговые поля; без неё транзакцию подписывает
+// вызывающая сторона до SubmitDeployTransaction.
+func (bc *Blockchain) NewDeployTransaction(params *ContractParams) (*Transaction, error) {
+	sender := strings.TrimSpace(params.From)
+	if sender == "" {
+		return nil, errors.New("укажите from (адрес отправителя)")
+	}
+	var bytecode []byte
+	var err error
+	if len(params.Params) > 0 && len(params.ABI) > 0 {
+		if bytecode, err = AppendConstructorArgs(params.Bytecode, params.ABI, params.Params); err != nil {
+			return nil, fmt.Errorf("constructor args: %w", err)
+		}
+	} else if bytecode, err = hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(params.Bytecode), "0x")); err != nil {
+		return nil, fmt.Errorf("invalid bytecode: %v", err)
+	}
+	if len(bytecode) == 0 {
+		return nil, errors.New("empty contract bytecode")
+	}
+	keyType, err := crypto.ParseKeyType(params.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	nonce := int64(params.Nonce)
+	if nonce == 0 && bc.State != nil {
+		nonce = bc.State.GetNonce(types.Address(sender))
+	}
+	tx := &Transaction{
+		ChainID:            bc.ChainID,
+		SubnetID:           bc.SubnetID,
+		Type:               string(TxTypeDeploy),
+		Sender:             types.Address(sender),
+		Recipient:          types.Address(generateContractAddress(bytecode, sender, uint64(nonce))),
+		Value:              big.NewInt(0),
+		Data:               bytecode,
+		Nonce:              nonce,
+		GasLimit:           params.GasLimit,
+		Symbol:             GasSymbol,
+		Timestamp:          BlockchainNow(),
+		Status:             "pending",
+		SenderPublicKeyHex: strings.TrimSpace(params.PublicKey),
+		KeyType:            keyType,
+	}
+	if params.GasPrice != nil && params.GasPrice.Sign() > 0 {
+		tx.GasPrice = new(big.Int).Set(params.GasPrice)
+	} else {
+		fees := bc.SuggestFees()
+		tx.MaxFeePerGas, tx.MaxPriorityFeePerGas = fees.Standard.MaxFeePerGas, balanceOrZero(fees.Standard.MaxPriorityFeePerGas)
+	}
+	if tx.GasLimit == 0 && bc.Executor != nil {
+		if result, _, err := bc.Executor.ExecuteContractCreate(tx.Sender, tx.Recipient.String(), bytecode, 0); err == nil && result.Error == nil {
+			tx.GasLimit = min(result.GasUsed+result.GasUsed*deployGasMargin/100, DefaultBlockGasLimit)
+		}
+	}
+	if sig := strings.TrimSpace(params.Signature); sig != "" {
+		if tx.Signature, err = hex.DecodeString(strings.TrimPrefix(sig, "0x")); err != nil {
+			return nil, fmt.Errorf("invalid signature hex: %v", err)
+		}
+	}
+	tx.Hash = tx.CalculateHash()
+	return tx, nil
+}
+
+// SubmitDeployTransaction проверяет подписанную транзакцию деплоя, исполняет конструктор пробно (revert отклоняет деплой),
+// ставит транзакцию в мемпул и записывает описание контракта из params (ABI, имя, владелец и т.д.) в contracts со статусом
+// pending. Код, storage, nonce и комиссия деплоя применяются только в блоке (applyContractDeploy); до включения в блок
+// у контракта нет кода. Возвращает адрес контракта.
+func (bc *Blockchain) SubmitDeployTransaction(params *ContractParams, tx *Transaction) (string, error) {
+	if !tx.IsDeploy() {
+		return "", errors.New("транзакция не является деплоем контракта")
+	}
+	if bc.Executor == nil {
+		return "", errors.New("деплой недоступен: нет исполнителя контрактов")
+	}
+	if err := bc.ValidateTransaction(tx); err != nil {
+		return "", err
+	}
+	result, _, err := bc.Executor.ExecuteContractCreate(tx.Sender, tx.Recipient.String(), tx.Data, tx.GasLimit)
+	if err != nil {
+		return "", fmt.Errorf("constructor execution: %w", err)
+	}
+	if result.Error != nil {
+		return "", fmt.Errorf("constructor execution: %w", result.Error)
+	}
+	if err := bc.ProcessTransaction(tx); err != nil {
+		return "", err
+	}
+	if bc.Pool != nil {
+		if err := newDeployedContract(params, tx).SaveToDB(context.Background(), bc.Pool); err != nil {
+			log.Printf("[DeployContract] описание контракта %s: %v", tx.Recipient, err)
+		}
+	}
+	return tx.Recipient.String(), nil
+}
+
+// newDeployedContract возвращает описание контракта, создаваемого транзакцией деплоя tx. Код не записывается:
+// contracts.runtime_code появляется при сохранении состояния после блока с деплоем.
+func newDeployedContract(params *ContractParams, tx *Transaction) *Contract {
+	var abiBytes []byte
+	if len(params.ABI) > 0 {
+		abiBytes = []byte(params.ABI)
+	}
+	contract := NewContract(tx.Recipient.String(), tx.Sender.String(), nil, abiBytes, params.Standard, params.Version, 0, 0)
+	contract.Name = params.Name
+	contract.Symbol = params.Symbol
+	if contract.Symbol == "" {
+		contract.Symbol = params.Standard
+	}
+	contract.Standard = params.Standard
+	contract.Description = params.Description
+	contract.License = params.License
+	contract.MetadataCID = params.MetadataCID
+	contract.Owner = params.Owner
+	if contract.Owner == "" {
+		contract.Owner = tx.Sender.String()
+	}
+	contract.SourceCode = params.SourceCode
+	contract.Compiler = params.Compiler
+	contract.GasLimit = int64(tx.GasLimit)
+	if len(params.Metadata) > 0 {
+		contract.Metadata = params.Metadata
+	}
+	if len(params.Params) > 0 {
+		contract.Params, _ = json.Marshal(params.Params)
+	}
+	return contract
+}
+
+// validateDeployTx проверяет транзакцию деплоя: init-код задан, value не переводится, адрес контракта выведен из
+// отправителя, nonce и init-кода — деплой не может занять адрес существующего контракта.
+func validateDeployTx(tx *Transaction) error {
+	if !tx.IsDeploy() {
+		return nil
+	}
+	if len(tx.Data) == 0 {
+		return errors.New("транзакция деплоя без init-кода")
+	}
+	if tx.Value != nil && tx.Value.Sign() != 0 {
+		return errors.New("транзакция деплоя не переводит value")
+	}
+	if want := generateContractAddress(tx.Data, tx.Sender.String(), uint64(tx.Nonce)); tx.Recipient.String() != want {
+		return fmt.Errorf("адрес контракта %s не соответствует отправителю, nonce и init-коду (%s)", tx.Recipient, want)
+	}
+	return nil
+}
+
 // AppendConstructorArgs возвращает bytecode + ABI-кодированные аргументы конструктора.
 // abiJSON — полный ABI контракта (массив); params — карта имя_аргумента -> значение (строка или число).
 // Адреса могут быть в формате GNDct<32hex>, 0x<40hex> или GN_/GND (тогда извлекаем 20 байт из hex-суффикса или используем хеш).
@@ -0,0 +1,293 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/contract_logs.go — события контрактов из исполнения транзакций: таблица contract_logs с индексами по адресу
+// и темам topic0–topic3, выборка по фильтру и расшифровка по ABI контракта (contracts.abi, UpdateContractABI).
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Ограничения выборки событий
+const (
+	MaxLogsBlockRange = 10_000 // наибольший диапазон блоков одного запроса
+	DefaultLogsLimit  = 1_000  // наибольшее число событий в ответе
+)
+
+// ContractLog — событие контракта в цепи: блок, транзакция, порядковый номер в блоке, темы и данные (hex с 0x).
+// Event и Args заполняются расшифровкой по ABI контракта, если событие в нём есть.
+type ContractLog struct {
+	BlockNumber uint64                 `json:"block_number"`
+	BlockHash   string                 `json:"block_hash"`
+	TxHash      string                 `json:"tx_hash"`
+	TxIndex     int                    `json:"tx_index"`
+	LogIndex    uint                   `json:"log_index"`
+	Address     string                 `json:"address"`
+	Topics      []string               `json:"topics"`
+	Data        string                 `json:"data"`
+	Event       string                 `json:"event,omitempty"`
+	Signature   string                 `json:"signature,omitempty"`
+	Args        map[string]interface{} `json:"args,omitempty"`
+}
+
+// LogFilter — фильтр событий: адрес контракта и темы на позициях 0–3 (пусто — любое значение), диапазон блоков включительно.
+type LogFilter struct {
+	Address   string
+	Topics    [4]string
+	FromBlock uint64
+	ToBlock   uint64
+	Limit     int // 0 — DefaultLogsLimit
+}
+
+// Match проверяет событие по фильтру без учёта диапазона блоков.
+func (f *LogFilter) Match(l *ContractLog) bool {
+	if f.Address != "" && !strings.EqualFold(f.Address, l.Address) {
+		return false
+	}
+	for i, want := range f.Topics {
+		if want == "" {
+			continue
+		}
+		if i >= len(l.Topics) || !strings.EqualFold(want, l.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *LogFilter) limit() int {
+	if f.Limit <= 0 || f.Limit > DefaultLogsLimit {
+		return DefaultLogsLimit
+	}
+	return f.Limit
+}
+
+// blockContractLogs собирает события квитанций блока в порядке log_index.
+func blockContractLogs(block *Block, receipts []*Receipt) []*ContractLog {
+	var logs []*ContractLog
+	for _, r := range receipts {
+		for _, l := range r.Logs {
+			logs = append(logs, &ContractLog{
+				BlockNumber: block.Index,
+				BlockHash:   block.Hash,
+				TxHash:      r.TxHash,
+				TxIndex:     r.TxIndex,
+				LogIndex:    l.LogIndex,
+				Address:     l.Address,
+				Topics:      l.Topics,
+				Data:        l.Data,
+			})
+		}
+	}
+	return logs
+}
+
+// saveContractLogs записывает события блока в contract_logs (повторная запись блока не дублирует события).
+func saveContractLogs(ctx context.Context, pool *pgxpool.Pool, blockID int64, logs []*ContractLog) error {
+	if pool == nil || len(logs) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, l := range logs {
+		var topics [4]*string
+		for i := 0; i < len(l.Topics) && i < len(topics); i++ {
+			topics[i] = &l.Topics[i]
+		}
+		data, _ := hex.DecodeString(strings.TrimPrefix(l.Data, "0x"))
+		batch.Queue(`
+			INSERT INTO contract_logs (
+				block_id, block_number, block_hash, tx_hash, tx_index, log_index, address,
+				topic0, topic1, topic2, topic3, topics_count, data
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			ON CONFLICT (block_hash, log_index) DO NOTHING`,
+			blockID, l.BlockNumber, l.BlockHash, l.TxHash, l.TxIndex, l.LogIndex, l.Address,
+			topics[0], topics[1], topics[2], topics[3], len(l.Topics), data)
+	}
+	return pool.SendBatch(ctx, batch).Close()
+}
+
+// QueryContractLogs выбирает события из contract_logs по фильтру в порядке блоков и log_index.
+func QueryContractLogs(ctx context.Context, pool *pgxpool.Pool, f *LogFilter) ([]*ContractLog, error) {
+	if pool == nil {
+		return nil, errors.New("pool is nil")
+	}
+	query := `
+		SELECT block_number, block_hash, tx_hash, tx_index, log_index, address,
+			topic0, topic1, topic2, topic3, topics_count, data
+		FROM contract_logs
+		WHERE block_number BETWEEN $1 AND $2`
+	args := []interface{}{f.FromBlock, f.ToBlock}
+	if f.Address != "" {
+		args = append(args, f.Address)
+		query += fmt.Sprintf(" AND address = $%d", len(args))
+	}
+	for i, topic := range f.Topics {
+		if topic != "" {
+			args = append(args, strings.ToLower(topic))
+			query += fmt.Sprintf(" AND topic%d = $%d", i, len(args))
+		}
+	}
+	args = append(args, f.limit())
+	query += fmt.Sprintf(" ORDER BY block_number, log_index LIMIT $%d", len(args))
+
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выборки событий: %w", err)
+	}
+	defer rows.Close()
+	var logs []*ContractLog
+	for rows.Next() {
+		var l ContractLog
+		var topics [4]*string
+		var count int
+		var data []byte
+		if err := rows.Scan(&l.BlockNumber, &l.BlockHash, &l.TxHash, &l.TxIndex, &l.LogIndex, &l.Address,
+			&topics[0], &topics[1], &topics[2], &topics[3], &count, &data); err != nil {
+			return nil, fmt.Errorf("ошибка чтения события: %w", err)
+		}
+		l.Topics = make([]string, 0, count)
+		for i := 0; i < count && i < len(topics); i++ {
+			if topics[i] != nil {
+				l.Topics = append(l.Topics, *topics[i])
+			}
+		}
+		l.Data = "0x" + hex.EncodeToString(data)
+		logs = append(logs, &l)
+	}
+	return logs, rows.Err()
+}
+
+// Logs возвращает события контрактов по фильтру: из contract_logs, без БД — из квитанций блоков в памяти.
+// События расшифровываются по ABI контрактов из contracts.abi (без БД не расшифровываются).
+func (bc *Blockchain) Logs(ctx context.Context, f *LogFilter) ([]*ContractLog, error) {
+	if f.ToBlock < f.FromBlock {
+		return nil, fmt.Errorf("fromBlock %d больше toBlock %d", f.FromBlock, f.ToBlock)
+	}
+	if f.ToBlock-f.FromBlock >= MaxLogsBlockRange {
+		return nil, fmt.Errorf("диапазон блоков больше %d", MaxLogsBlockRange)
+	}
+	if bc.Pool != nil {
+		logs, err := QueryContractLogs(ctx, bc.Pool, f)
+		if err != nil {
+			return nil, err
+		}
+		DecodeContractLogs(ctx, bc.Pool, logs)
+		return logs, nil
+	}
+	var logs []*ContractLog
+	for _, block := range bc.AllBlocks() {
+		if block.Index < f.FromBlock || block.Index > f.ToBlock {
+			continue
+		}
+		receipts, err := bc.BlockReceipts(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range blockContractLogs(block, receipts) {
+			if f.Match(l) && len(logs) < f.limit() {
+				logs = append(logs, l)
+			}
+		}
+	}
+	return logs, nil
+}
+
+// DecodeContractLogs расшифровывает события по ABI их контрактов (ABI каждого контракта загружается один раз).
+// События контрактов без ABI и события, которых нет в ABI, остаются без расшифровки.
+func DecodeContractLogs(ctx context.Context, pool *pgxpool.Pool, logs []*ContractLog) {
+	abis := make(map[string]*abi.ABI)
+	for _, l := range logs {
+		parsed, ok := abis[l.Address]
+		if !ok {
+			parsed = loadContractABI(ctx, pool, l.Address)
+			abis[l.Address] = parsed
+		}
+		if parsed != nil {
+			_ = DecodeContractLog(parsed, l)
+		}
+	}
+}
+
+// loadContractABI загружает и разбирает ABI контракта (nil — контракта нет, ABI пуст или не разбирается).
+func loadContractABI(ctx context.Context, pool *pgxpool.Pool, address string) *abi.ABI {
+	if pool == nil {
+		return nil
+	}
+	var raw []byte
+	if err := pool.QueryRow(ctx, `SELECT abi FROM contracts WHERE address = $1`, address).Scan(&raw); err != nil || len(raw) == 0 {
+		return nil
+	}
+	parsed, err := abi.JSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
+// DecodeContractLog находит событие по topic0 в ABI и заполняет Event, Signature и Args: индексированные аргументы —
+// из тем (динамические типы — только хеш), остальные — из данных.
+func DecodeContractLog(contractABI *abi.ABI, l *ContractLog) error {
+	if len(l.Topics) == 0 {
+		return errors.New("anonymous event")
+	}
+	event, err := contractABI.EventByID(common.HexToHash(l.Topics[0]))
+	if err != nil {
+		return err
+	}
+	args := make(map[string]interface{})
+	data, err := hex.DecodeString(strings.TrimPrefix(l.Data, "0x"))
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		if err := event.Inputs.UnpackIntoMap(args, data); err != nil {
+			return err
+		}
+	}
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	topics := make([]common.Hash, 0, len(l.Topics)-1)
+	for _, t := range l.Topics[1:] {
+		topics = append(topics, common.HexToHash(t))
+	}
+	if err := abi.ParseTopicsIntoMap(args, indexed, topics); err != nil {
+		return err
+	}
+	for name, v := range args {
+		args[name] = abiJSONValue(v)
+	}
+	l.Event, l.Signature, l.Args = event.Name, event.Sig, args
+	return nil
+}
+
+// abiJSONValue приводит значение аргумента ABI к виду для JSON: целые — десятичной строкой, байты и хеши — hex с 0x.
+func abiJSONValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case *big.Int:
+		return x.String()
+	case common.Address:
+		return x.Hex()
+	case common.Hash:
+		return x.Hex()
+	case []byte:
+		return "0x" + hex.EncodeToString(x)
+	case [32]byte:
+		return "0x" + hex.EncodeToString(x[:])
+	}
+	return v
+}
@@ -0,0 +1,68 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const transferABI = `[{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+	{"name":"from","type":"address","indexed":true},
+	{"name":"to","type":"address","indexed":true},
+	{"name":"value","type":"uint256","indexed":false}]}]`
+
+func TestDecodeContractLog_Transfer(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(transferABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := common.HexToAddress("0x00000000112233445566778899aabbccddeeff00")
+	to := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	l := &ContractLog{
+		Address: "GNDct112233445566778899aabbccddeeff00",
+		Topics: []string{
+			crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")).Hex(),
+			common.BytesToHash(from.Bytes()).Hex(),
+			common.BytesToHash(to.Bytes()).Hex(),
+		},
+		Data: common.BigToHash(common.Big3).Hex(),
+	}
+	if err := DecodeContractLog(&parsed, l); err != nil {
+		t.Fatal(err)
+	}
+	if l.Event != "Transfer" || l.Signature != "Transfer(address,address,uint256)" {
+		t.Errorf("событие: %q %q", l.Event, l.Signature)
+	}
+	if l.Args["from"] != from.Hex() || l.Args["to"] != to.Hex() || l.Args["value"] != "3" {
+		t.Errorf("аргументы: %v", l.Args)
+	}
+
+	unknown := &ContractLog{Topics: []string{common.Hash{1}.Hex()}, Data: "0x"}
+	if err := DecodeContractLog(&parsed, unknown); err == nil || unknown.Event != "" {
+		t.Error("событие, которого нет в ABI, не должно расшифровываться")
+	}
+}
+
+func TestLogFilter_Match(t *testing.T) {
+	l := &ContractLog{Address: "GNDct00112233445566778899aabbccddeeff", Topics: []string{"0xaa", "0xbb"}}
+	cases := []struct {
+		filter LogFilter
+		want   bool
+	}{
+		{LogFilter{}, true},
+		{LogFilter{Address: "gndct00112233445566778899aabbccddeeff"}, true},
+		{LogFilter{Address: "GNDct0000000000000000000000000000000"}, false},
+		{LogFilter{Topics: [4]string{"", "0xBB"}}, true},
+		{LogFilter{Topics: [4]string{"0xbb"}}, false},
+		{LogFilter{Topics: [4]string{"", "", "0xcc"}}, false},
+	}
+	for i, tc := range cases {
+		if got := tc.filter.Match(l); got != tc.want {
+			t.Errorf("случай %d: ожидалось %v, получено %v", i, tc.want, got)
+		}
+	}
+}
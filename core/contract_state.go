@@ -0,0 +1,219 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/contract_state.go — код и слоты storage контрактов для исполнения байткода (vm).
+
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// GetContractCode возвращает код контракта по адресу. isInitCode = true, если runtime-код ещё не известен
+// и возвращён init-код из contracts.code (контракт задеплоен до исполнения байткода) — его нужно исполнить, чтобы получить runtime-код.
+// Код, прочитанный из БД, в состояние не добавляется: state_root меняется только через SetContractCode.
+func (s *State) GetContractCode(address string) (code []byte, isInitCode bool) {
+	s.mutex.RLock()
+	cached, ok := s.codes[address]
+	pool := s.pool
+	s.mutex.RUnlock()
+	if ok {
+		return cached, false
+	}
+	if pool == nil || address == "" {
+		return nil, false
+	}
+	var runtimeCode, initCode []byte
+	err := pool.QueryRow(context.Background(), `
+		SELECT runtime_code, COALESCE(code, bytecode)
+		FROM contracts
+		WHERE address = $1`,
+		address,
+	).Scan(&runtimeCode, &initCode)
+	if err != nil {
+		return nil, false
+	}
+	if len(runtimeCode) > 0 {
+		return runtimeCode, false
+	}
+	return initCode, len(initCode) > 0
+}
+
+// SetContractCode сохраняет runtime-код контракта в кэше. В contracts.runtime_code код записывается только SaveToDB
+// (при сохранении состояния после блока): исполнение, которое затем откатывается (ExecuteBlock, отклонённый блок),
+// не меняет БД.
+func (s *State) SetContractCode(address string, code []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.codes[address] = code
+	if s.codeChanges == nil {
+		s.codeChanges = make(map[string]struct{})
+	}
+	s.codeChanges[address] = struct{}{}
+	return nil
+}
+
+// saveContractCodesLocked записывает в contracts.runtime_code код, изменённый после прошлой записи. Для контракта,
+// созданного опкодом CREATE при исполнении (записи в contracts нет), добавляется запись только с адресом и runtime-кодом —
+// иначе код теряется при перезапуске; код, удалённый откатом результата исполнения, очищается. Вызывать при s.mutex (Lock).
+func (s *State) saveContractCodesLocked(ctx context.Context) error {
+	addresses := make([]string, 0, len(s.codeChanges))
+	for addr := range s.codeChanges {
+		addresses = append(addresses, addr)
+	}
+	sort.Strings(addresses)
+	for _, addr := range addresses {
+		code, ok := s.codes[addr]
+		var err error
+		if ok {
+			_, err = s.pool.Exec(ctx, `
+				INSERT INTO contracts (
+					address, creator, name, symbol, owner, type, standard, description, version, status,
+					block_id, tx_id, gas_limit, gas_used, value, created_at, updated_at,
+					is_verified, source_code, compiler, optimized, runs, license, runtime_code
+				) VALUES ($1, '', '', '', '', '', '', '', '', 'active', 0, 0, 0, 0, '0', NOW(), NOW(), FALSE, '', '', FALSE, 0, '', $2)
+				ON CONFLICT (address) DO UPDATE SET runtime_code = EXCLUDED.runtime_code,
+					status = CASE WHEN contracts.status = 'pending' THEN 'active' ELSE contracts.status END`,
+				addr, code)
+		} else {
+			_, err = s.pool.Exec(ctx, `UPDATE contracts SET runtime_code = NULL WHERE address = $1`, addr)
+		}
+		if err != nil {
+			return err
+		}
+		delete(s.codeChanges, addr)
+	}
+	return nil
+}
+
+// SaveEVMAddress записывает в evm_addresses кошелёк ГАНИМЕД address, которому соответствует адрес EVM evmAddress (0x+40 hex);
+// существующая запись не меняется.
+func (s *State) SaveEVMAddress(evmAddress, address string) error {
+	s.mutex.RLock()
+	pool := s.pool
+	s.mutex.RUnlock()
+	if pool == nil {
+		return nil
+	}
+	_, err := pool.Exec(context.Background(), `
+		INSERT INTO evm_addresses (evm_address, address) VALUES ($1, $2)
+		ON CONFLICT (evm_address) DO NOTHING`,
+		evmAddress, address)
+	return err
+}
+
+// LoadEVMAddress возвращает кошелёк ГАНИМЕД, записанный в evm_addresses для адреса EVM evmAddress.
+func (s *State) LoadEVMAddress(evmAddress string) (string, bool) {
+	s.mutex.RLock()
+	pool := s.pool
+	s.mutex.RUnlock()
+	if pool == nil {
+		return "", false
+	}
+	var address string
+	if err := pool.QueryRow(context.Background(),
+		`SELECT address FROM evm_addresses WHERE evm_address = $1`, evmAddress).Scan(&address); err != nil {
+		return "", false
+	}
+	return address, true
+}
+
+// GetStorageSlot возвращает значение слота storage контракта (32 байта) или nil, если слот не записан.
+// Учитываются изменения, применённые в текущем блоке, но ещё не сохранённые в contract_storage. Чтение не обращается к БД:
+// storage всех контрактов загружается в LoadFromDB и обновляется только записью слотов.
+func (s *State) GetStorageSlot(address string, key []byte) []byte {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.storage[address][string(key)]
+}
+
+// InvalidateContractStorage перечитывает storage контракта из БД (после записи слотов в обход блока, например из админки):
+// state_root считается по storage в памяти и должен включать все слоты.
+func (s *State) InvalidateContractStorage(address string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.loadContractStorageLocked(address)
+}
+
+// loadContractsState загружает runtime-код (contracts.runtime_code) и последние слоты storage всех контрактов.
+func (s *State) loadContractsState(ctx context.Context) error {
+	rows, err := s.pool.Query(ctx, `SELECT address, runtime_code FROM contracts WHERE runtime_code IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+	codes := make(map[string][]byte)
+	for rows.Next() {
+		var addr string
+		var code []byte
+		if err := rows.Scan(&addr, &code); err != nil {
+			rows.Close()
+			return err
+		}
+		if len(code) > 0 {
+			codes[addr] = code
+		}
+	}
+	rows.Close()
+
+	rows, err = s.pool.Query(ctx, `SELECT DISTINCT address FROM contract_storage`)
+	if err != nil {
+		return err
+	}
+	var withStorage []string
+	for rows.Next() {
+		var addr string
+		if err := rows.Scan(&addr); err != nil {
+			rows.Close()
+			return err
+		}
+		withStorage = append(withStorage, addr)
+	}
+	rows.Close()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for addr, code := range codes {
+		s.codes[addr] = code
+	}
+	for _, addr := range withStorage {
+		s.loadContractStorageLocked(addr)
+	}
+	return nil
+}
+
+// contractStorageLocked возвращает слоты контракта для записи, создавая пустой набор для нового контракта.
+// Вызывать при удержанном s.mutex (Lock).
+func (s *State) contractStorageLocked(address string) map[string][]byte {
+	if s.storage == nil {
+		s.storage = make(map[string]map[string][]byte)
+	}
+	slots, ok := s.storage[address]
+	if !ok {
+		slots = make(map[string][]byte)
+		s.storage[address] = slots
+	}
+	return slots
+}
+
+// loadContractStorageLocked заменяет слоты контракта последними значениями из contract_storage.
+// Вызывать при удержанном s.mutex (Lock).
+func (s *State) loadContractStorageLocked(address string) {
+	if s.storage == nil {
+		s.storage = make(map[string]map[string][]byte)
+	}
+	slots := make(map[string][]byte)
+	if s.pool != nil {
+		if stored, err := GetContractStorageLatest(context.Background(), s.pool, address); err == nil {
+			for _, slot := range stored {
+				key, errKey := hex.DecodeString(strings.TrimPrefix(slot.SlotKey, "0x"))
+				value, errValue := hex.DecodeString(strings.TrimPrefix(slot.SlotValue, "0x"))
+				if errKey != nil || errValue != nil || len(key) != 32 {
+					continue
+				}
+				slots[string(key)] = value
+			}
+		}
+	}
+	s.storage[address] = slots
+}
@@ -0,0 +1,146 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/crypto/scheme.go — типы ключей (схемы подписи) кошельков: P-256 и secp256k1. Единая проверка подписи,
+// восстановление публичного ключа и сверка адреса отправителя с ключом.
+
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// KeyType — схема подписи ключа. Схема однозначно следует из формата адреса (KeyTypeOfAddress).
+type KeyType string
+
+const (
+	// KeyTypeP256 — NIST P-256: адрес — 64 hex sha256(04‖X‖Y), подпись r‖s (64 байта), публичный ключ передаётся с транзакцией.
+	KeyTypeP256 KeyType = "p256"
+	// KeyTypeSecp256k1 — secp256k1 (кошельки ноды и signing_service): адрес — GND/GN_ + base58check(ripemd160(sha256(сжатый ключ))),
+	// подпись восстанавливаемая v‖r‖s (65 байт, публичный ключ из неё восстанавливается) или DER с публичным ключом.
+	KeyTypeSecp256k1 KeyType = "secp256k1"
+)
+
+// CompactSignatureSize — длина восстанавливаемой подписи secp256k1 (v‖r‖s).
+const CompactSignatureSize = 65
+
+// ParseKeyType разбирает тип ключа; пустая строка — тип не указан.
+func ParseKeyType(s string) (KeyType, error) {
+	switch k := KeyType(strings.ToLower(strings.TrimSpace(s))); k {
+	case "", KeyTypeP256, KeyTypeSecp256k1:
+		return k, nil
+	}
+	return "", fmt.Errorf("неизвестный тип ключа %q (p256, secp256k1)", s)
+}
+
+// KeyTypeOfAddress возвращает схему подписи по формату адреса; пустую — если адрес не принадлежит ключу (контракт, служебный).
+func KeyTypeOfAddress(address string) KeyType {
+	if b, err := hex.DecodeString(address); err == nil && len(b) == sha256.Size {
+		return KeyTypeP256
+	}
+	if _, ok := secp256k1AddressPayload(address); ok {
+		return KeyTypeSecp256k1
+	}
+	return ""
+}
+
+// VerifyMessage проверяет подпись sig сообщения msg (подписывается sha256(msg)). pub может быть пустым, если схема
+// восстанавливает ключ из подписи. Возвращает публичный ключ, которым проверена подпись.
+func (k KeyType) VerifyMessage(msg, sig, pub []byte) ([]byte, error) {
+	digest := sha256.Sum256(msg)
+	switch k {
+	case KeyTypeP256:
+		if len(pub) == 0 {
+			return nil, errors.New("для ключа p256 нужен публичный ключ отправителя (sender_public_key)")
+		}
+		key, err := ParsePublicKeyHex(hex.EncodeToString(pub))
+		if err != nil {
+			return nil, fmt.Errorf("неверный публичный ключ p256: %w", err)
+		}
+		if !Verify(msg, sig, key) {
+			return nil, errors.New("неверная подпись транзакции")
+		}
+		return pub, nil
+	case KeyTypeSecp256k1:
+		var key *secp256k1.PublicKey
+		if len(pub) > 0 {
+			parsed, err := secp256k1.ParsePubKey(pub)
+			if err != nil {
+				return nil, fmt.Errorf("неверный публичный ключ secp256k1: %w", err)
+			}
+			key = parsed
+		}
+		if len(sig) == CompactSignatureSize {
+			recovered, _, err := secpecdsa.RecoverCompact(sig, digest[:])
+			if err != nil || (key != nil && !recovered.IsEqual(key)) {
+				return nil, errors.New("неверная подпись транзакции")
+			}
+			return recovered.SerializeCompressed(), nil
+		}
+		if key == nil {
+			return nil, errors.New("для подписи secp256k1 без восстановления нужен публичный ключ отправителя")
+		}
+		parsed, err := secpecdsa.ParseDERSignature(sig)
+		if err != nil || !parsed.Verify(digest[:], key) {
+			return nil, errors.New("неверная подпись транзакции")
+		}
+		return key.SerializeCompressed(), nil
+	}
+	return nil, fmt.Errorf("неизвестный тип ключа %q", k)
+}
+
+// AddressMatches проверяет, что адрес принадлежит публичному ключу pub схемы k.
+func (k KeyType) AddressMatches(address string, pub []byte) bool {
+	switch k {
+	case KeyTypeP256:
+		key, err := ParsePublicKeyHex(hex.EncodeToString(pub))
+		return err == nil && PublicKeyToAddressP256(key) == address
+	case KeyTypeSecp256k1:
+		key, err := secp256k1.ParsePubKey(pub)
+		if err != nil {
+			return false
+		}
+		payload, ok := secp256k1AddressPayload(address)
+		return ok && bytes.Equal(payload, Hash160(key.SerializeCompressed()))
+	}
+	return false
+}
+
+// SignSecp256k1 подписывает сообщение msg (sha256(msg)) ключом secp256k1: восстанавливаемая подпись v‖r‖s.
+func SignSecp256k1(msg []byte, key *secp256k1.PrivateKey) []byte {
+	digest := sha256.Sum256(msg)
+	return secpecdsa.SignCompact(key, digest[:], true)
+}
+
+// Hash160 — ripemd160(sha256(b)), хеш ключа в адресе secp256k1.
+func Hash160(b []byte) []byte {
+	sha := sha256.Sum256(b)
+	h := ripemd160.New()
+	h.Write(sha[:])
+	return h.Sum(nil)
+}
+
+// secp256k1AddressPayload возвращает хеш ключа из адреса GND/GN_ с верной контрольной суммой base58check.
+func secp256k1AddressPayload(address string) ([]byte, bool) {
+	if !strings.HasPrefix(address, "GND") && !strings.HasPrefix(address, "GN_") {
+		return nil, false
+	}
+	decoded := base58.Decode(address[3:])
+	if len(decoded) != 24 {
+		return nil, false
+	}
+	first := sha256.Sum256(decoded[:20])
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], decoded[20:]) {
+		return nil, false
+	}
+	return decoded[:20], true
+}
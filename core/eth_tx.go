@@ -0,0 +1,157 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/eth_tx.go — транзакции, подписанные кошельками Ethereum (MetaMask, ethers.js, Foundry): raw-транзакция
+// go-ethereum (legacy с EIP-155, EIP-2930, EIP-1559) хранится в Signature, отправитель — GND-адрес ключа secp256k1,
+// восстановленного из подписи. Поля транзакции ГАНИМЕД сверяются с raw-транзакцией при каждой проверке подписи.
+
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"GND/core/crypto"
+	"GND/types"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// maxNativeSignatureSize — наибольшая длина подписи ГАНИМЕД (DER secp256k1; p256 — 64 байта, восстанавливаемая
+// secp256k1 — 65). Подпись длиннее — raw-транзакция Ethereum.
+const maxNativeSignatureSize = 72
+
+// IsEthereumSigned сообщает, подписана ли транзакция кошельком Ethereum (Signature — raw-транзакция go-ethereum).
+func (tx *Transaction) IsEthereumSigned() bool {
+	return len(tx.Signature) > maxNativeSignatureSize
+}
+
+// EVMAddress переводит адрес ГАНИМЕД в 20-байтный адрес EVM: 0x+40 hex и 40 hex — как есть; GNDct+32 hex — 16 байт,
+// дополненные слева нулями; остальные (кошельки) — последние 20 байт keccak256 от строки адреса.
+func EVMAddress(addr string) common.Address {
+	s := strings.TrimSpace(addr)
+	if types.IsContractAddress(s) {
+		b, _ := hex.DecodeString(s[len(types.ContractAddressPrefix):])
+		return common.BytesToAddress(b)
+	}
+	hexPart := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(hexPart) == 40 {
+		if b, err := hex.DecodeString(hexPart); err == nil {
+			return common.BytesToAddress(b)
+		}
+	}
+	return common.BytesToAddress(gethcrypto.Keccak256([]byte(s)))
+}
+
+// DecodeEthereumTransaction разбирает raw-транзакцию Ethereum (types.Transaction.UnmarshalBinary) в транзакцию ГАНИМЕД.
+// Отправитель — адрес с префиксом GND ключа, восстановленного по подписи (LatestSignerForChainID); recipient переводит
+// адрес получателя EVM в адрес ГАНИМЕД. Создание контракта (получатель не задан) и транзакции без chain_id не принимаются.
+func DecodeEthereumTransaction(raw []byte, recipient func(common.Address) string) (*Transaction, error) {
+	etx, sender, err := decodeEthereumTransaction(raw)
+	if err != nil {
+		return nil, err
+	}
+	tx := ethereumFields(etx, sender)
+	tx.Recipient = types.Address(recipient(*etx.To()))
+	tx.Signature = raw
+	tx.Timestamp = BlockchainNow()
+	tx.Status = "pending"
+	tx.Hash = tx.CalculateHash()
+	return tx, nil
+}
+
+// decodeEthereumTransaction декодирует raw-транзакцию и восстанавливает GND-адрес отправителя.
+func decodeEthereumTransaction(raw []byte) (*gethtypes.Transaction, string, error) {
+	etx := new(gethtypes.Transaction)
+	if err := etx.UnmarshalBinary(raw); err != nil {
+		return nil, "", err
+	}
+	switch etx.Type() {
+	case gethtypes.LegacyTxType, gethtypes.AccessListTxType, gethtypes.DynamicFeeTxType:
+	default:
+		return nil, "", fmt.Errorf("тип транзакции Ethereum %d не поддерживается", etx.Type())
+	}
+	if !etx.Protected() || etx.ChainId().Sign() <= 0 || !etx.ChainId().IsInt64() {
+		return nil, "", errors.New("транзакция Ethereum без chain_id (EIP-155) не принимается")
+	}
+	if etx.To() == nil {
+		return nil, "", errors.New("создание контракта raw-транзакцией Ethereum не поддерживается")
+	}
+	signer := gethtypes.LatestSignerForChainID(etx.ChainId())
+	from, err := gethtypes.Sender(signer, etx)
+	if err != nil {
+		return nil, "", fmt.Errorf("подпись транзакции Ethereum: %w", err)
+	}
+	v, r, s := etx.RawSignatureValues()
+	recID := new(big.Int).Set(v)
+	if etx.Type() == gethtypes.LegacyTxType {
+		recID.Sub(recID, new(big.Int).Add(new(big.Int).Lsh(etx.ChainId(), 1), big.NewInt(35)))
+	}
+	sig := make([]byte, 65)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:64])
+	sig[64] = byte(recID.Uint64())
+	pub, err := gethcrypto.SigToPub(signer.Hash(etx).Bytes(), sig)
+	if err != nil || gethcrypto.PubkeyToAddress(*pub) != from {
+		return nil, "", errors.New("публичный ключ отправителя транзакции Ethereum не восстанавливается")
+	}
+	hash := crypto.Hash160(gethcrypto.CompressPubkey(pub))
+	return etx, "GND" + base58.Encode(append(hash, Checksum(hash)...)), nil
+}
+
+// ethereumFields возвращает поля транзакции ГАНИМЕД, подписанные raw-транзакцией etx (кроме получателя).
+func ethereumFields(etx *gethtypes.Transaction, sender string) *Transaction {
+	tx := &Transaction{
+		ChainID:  etx.ChainId().Int64(),
+		Sender:   types.Address(sender),
+		Value:    new(big.Int).Set(etx.Value()),
+		Data:     etx.Data(),
+		Nonce:    int64(etx.Nonce()),
+		GasLimit: etx.Gas(),
+		Symbol:   GasSymbol,
+	}
+	if etx.Type() == gethtypes.DynamicFeeTxType {
+		tx.MaxFeePerGas = new(big.Int).Set(etx.GasFeeCap())
+		tx.MaxPriorityFeePerGas = new(big.Int).Set(etx.GasTipCap())
+	} else {
+		tx.GasPrice = new(big.Int).Set(etx.GasPrice())
+	}
+	return tx
+}
+
+// verifyEthereumTransaction проверяет подпись raw-транзакции Ethereum и то, что поля транзакции совпадают с ней.
+func verifyEthereumTransaction(tx *Transaction) error {
+	etx, sender, err := decodeEthereumTransaction(tx.Signature)
+	if err != nil {
+		return err
+	}
+	want := ethereumFields(etx, sender)
+	switch {
+	case tx.Sender != want.Sender:
+		return fmt.Errorf("отправитель %s не соответствует подписи транзакции Ethereum (%s)", tx.Sender, want.Sender)
+	case EVMAddress(tx.Recipient.String()) != *etx.To():
+		return fmt.Errorf("получатель %s не соответствует адресу %s транзакции Ethereum", tx.Recipient, etx.To().Hex())
+	case tx.ChainID != want.ChainID || tx.Nonce != want.Nonce || tx.GasLimit != want.GasLimit ||
+		balanceOrZero(tx.Value).Cmp(want.Value) != 0 || !bytes.Equal(tx.Data, want.Data):
+		return errors.New("поля транзакции не соответствуют транзакции Ethereum")
+	case !sameAmount(tx.GasPrice, want.GasPrice) || !sameAmount(tx.MaxFeePerGas, want.MaxFeePerGas) ||
+		!sameAmount(tx.MaxPriorityFeePerGas, want.MaxPriorityFeePerGas):
+		return errors.New("цена газа не соответствует транзакции Ethereum")
+	case signedType(tx.Type) != "" || (tx.Symbol != "" && tx.Symbol != GasSymbol):
+		return errors.New("транзакция Ethereum переводит только GND без типа транзакции")
+	}
+	return nil
+}
+
+// sameAmount сравнивает необязательные суммы: обе не заданы или равны.
+func sameAmount(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Cmp(b) == 0
+}
@@ -1,10 +1,202 @@
 // | KB @CerberRus00 - Nexus Invest Team
 package core
 
-import "math/big"
+import (
+	"math/big"
+	"sort"
+)
 
-// Fees related code
-// CalculateTxFee возвращает комиссию за транзакцию
+// Газ: базовая стоимость транзакции (как в Ethereum) и лимиты блока
+const (
+	TxGas                 uint64 = 21000      // базовый газ транзакции (перевод или вызов контракта)
+	TxGasContractCreation uint64 = 53000      // базовый газ деплоя контракта
+	TxDataZeroGas         uint64 = 4          // газ за нулевой байт calldata
+	TxDataNonZeroGas      uint64 = 16         // газ за ненулевой байт calldata
+	InitCodeWordGas       uint64 = 2          // газ за 32-байтное слово init-кода (EIP-3860)
+	StorageWriteGas       uint64 = 20000      // газ за запись слота storage (вызовы без байткода, по селекторам)
+	DefaultBlockGasLimit  uint64 = 10_000_000 // лимит газа блока
+	DefaultGasPrice       int64  = 1          // цена газа в GND, если в транзакции не задана
+)
+
+// IntrinsicGas возвращает газ, который списывается до исполнения: базовая стоимость и calldata (для деплоя — ещё слова init-кода).
+func IntrinsicGas(data []byte, isCreate bool) uint64 {
+	gas := TxGas
+	if isCreate {
+		gas = TxGasContractCreation + InitCodeWordGas*((uint64(len(data))+31)/32)
+	}
+	for _, b := range data {
+		if b == 0 {
+			gas += TxDataZeroGas
+		} else {
+			gas += TxDataNonZeroGas
+		}
+	}
+	return gas
+}
+
+// Рынок комиссий (EIP-1559): base fee блока следует за заполненностью родителя и уходит в казну (или сжигается),
+// сверх неё отправитель платит чаевые предлагающему блок.
+const (
+	InitialBaseFee           int64  = DefaultGasPrice // base fee блока, родитель которого без base fee (первый блок рынка комиссий)
+	MinBaseFee               int64  = 1               // base fee не опускается ниже
+	BaseFeeChangeDenominator int64  = 8               // за блок base fee меняется не более чем на 1/8
+	ElasticityMultiplier     uint64 = 2               // целевой газ блока — лимит газа / 2
+	DefaultPriorityFee       int64  = 1               // чаевые в подсказках, пока в блоках нет транзакций с чаевыми
+)
+
+// CalcBaseFee возвращает base fee блока-потомка parent: газ родителя выше целевого (половина лимита) повышает её,
+// ниже — снижает, не более чем на 1/8 и не менее чем на 1 (иначе малые значения не менялись бы при целочисленном делении).
+func CalcBaseFee(parent *Block) *big.Int {
+	if parent == nil || parent.BaseFee == nil {
+		return big.NewInt(InitialBaseFee)
+	}
+	baseFee := new(big.Int).Set(parent.BaseFee)
+	target := parent.GasLimit / ElasticityMultiplier
+	if target == 0 || parent.GasUsed == target {
+		return baseFee
+	}
+	var gasDelta uint64
+	if parent.GasUsed > target {
+		gasDelta = parent.GasUsed - target
+	} else {
+		gasDelta = target - parent.GasUsed
+	}
+	delta := new(big.Int).Mul(parent.BaseFee, new(big.Int).SetUint64(gasDelta))
+	delta.Div(delta, new(big.Int).SetUint64(target))
+	delta.Div(delta, big.NewInt(BaseFeeChangeDenominator))
+	if delta.Sign() == 0 {
+		delta.SetInt64(1)
+	}
+	if parent.GasUsed > target {
+		return baseFee.Add(baseFee, delta)
+	}
+	if baseFee.Sub(baseFee, delta); baseFee.Cmp(big.NewInt(MinBaseFee)) < 0 {
+		baseFee.SetInt64(MinBaseFee)
+	}
+	return baseFee
+}
+
+// IsDynamicFee — транзакция рынка комиссий (max_fee_per_gas, max_priority_fee_per_gas); иначе цена газа — gas_price.
+func (tx *Transaction) IsDynamicFee() bool {
+	return tx.MaxFeePerGas != nil
+}
+
+// GasPriceAt возвращает цену газа транзакции в блоке с base fee baseFee: для транзакции рынка комиссий —
+// min(max_fee_per_gas, base fee + max_priority_fee_per_gas), для обычной — EffectiveGasPrice.
+func (tx *Transaction) GasPriceAt(baseFee *big.Int) *big.Int {
+	if !tx.IsDynamicFee() {
+		return tx.EffectiveGasPrice()
+	}
+	price := new(big.Int)
+	if tx.MaxPriorityFeePerGas != nil {
+		price.Set(tx.MaxPriorityFeePerGas)
+	}
+	if baseFee != nil {
+		price.Add(price, baseFee)
+	}
+	if price.Cmp(tx.MaxFeePerGas) > 0 {
+		price.Set(tx.MaxFeePerGas)
+	}
+	return price
+}
+
+// PriorityFeeAt возвращает чаевые за единицу газа сверх base fee (цена газа ниже base fee — 0).
+func (tx *Transaction) PriorityFeeAt(baseFee *big.Int) *big.Int {
+	tip := tx.GasPriceAt(baseFee)
+	if baseFee != nil {
+		tip.Sub(tip, baseFee)
+	}
+	if tip.Sign() < 0 {
+		tip.SetInt64(0)
+	}
+	return tip
+}
+
+// EffectiveGasPrice возвращает наибольшую цену газа транзакции в GND: gas_price (DefaultGasPrice, если не задана),
+// для транзакции рынка комиссий — max_fee_per_gas. По ней резервируется газ и сравниваются транзакции мемпула.
+func (tx *Transaction) EffectiveGasPrice() *big.Int {
+	if tx.IsDynamicFee() {
+		return new(big.Int).Set(tx.MaxFeePerGas)
+	}
+	if tx.GasPrice == nil || tx.GasPrice.Sign() <= 0 {
+		return big.NewInt(DefaultGasPrice)
+	}
+	return new(big.Int).Set(tx.GasPrice)
+}
+
+// EffectiveGasLimit возвращает лимит газа транзакции; для записей без лимита (старые транзакции из БД) — базовый газ.
+func (tx *Transaction) EffectiveGasLimit() uint64 {
+	if tx.GasLimit == 0 {
+		return tx.IntrinsicGas()
+	}
+	return tx.GasLimit
+}
+
+// IntrinsicGas возвращает базовый газ транзакции (для деплоя — с доплатой за создание контракта).
+func (tx *Transaction) IntrinsicGas() uint64 {
+	return IntrinsicGas(tx.Data, tx.IsDeploy())
+}
+
+// CalculateTxFee возвращает фактическую комиссию за транзакцию: после применения в блоке — списанная (tx.Fee),
+// до применения — использованный газ × цена газа.
 func CalculateTxFee(tx *Transaction) *big.Int {
-	return tx.Fee
+	if tx.Fee != nil {
+		return new(big.Int).Set(tx.Fee)
+	}
+	return tx.CalculateTxFee(tx.GasUsed)
+}
+
+// FeeHistoryBlocks — число последних блоков, по чаевым которых строятся подсказки комиссий.
+const FeeHistoryBlocks = 20
+
+// FeeSuggestion — подсказка для транзакции рынка комиссий.
+type FeeSuggestion struct {
+	MaxPriorityFeePerGas *big.Int `json:"max_priority_fee_per_gas"`
+	MaxFeePerGas         *big.Int `json:"max_fee_per_gas"` // 2 × base fee + чаевые: транзакция остаётся включаемой при росте base fee
+}
+
+// FeeSuggestions — base fee следующего блока и подсказки чаевых по последним блокам.
+type FeeSuggestions struct {
+	BaseFee      *big.Int      `json:"base_fee"`       // base fee следующего блока
+	GasUsedRatio float64       `json:"gas_used_ratio"` // заполненность последнего блока (gas_used / gas_limit)
+	Slow         FeeSuggestion `json:"slow"`           // 25-й перцентиль чаевых
+	Standard     FeeSuggestion `json:"standard"`       // медиана
+	Fast         FeeSuggestion `json:"fast"`           // 75-й перцентиль
+}
+
+// SuggestFees возвращает base fee следующего блока и подсказки чаевых — перцентили чаевых транзакций последних
+// FeeHistoryBlocks блоков (без транзакций — DefaultPriorityFee).
+func (bc *Blockchain) SuggestFees() *FeeSuggestions {
+	blocks := bc.Blocks
+	if len(blocks) > FeeHistoryBlocks {
+		blocks = blocks[len(blocks)-FeeHistoryBlocks:]
+	}
+	var tips []*big.Int
+	for _, b := range blocks {
+		for _, tx := range b.Transactions {
+			if tx != nil && !IsSystemTransaction(tx) {
+				tips = append(tips, tx.PriorityFeeAt(b.BaseFee))
+			}
+		}
+	}
+	sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+
+	out := &FeeSuggestions{BaseFee: big.NewInt(InitialBaseFee)}
+	if n := len(bc.Blocks); n > 0 {
+		last := bc.Blocks[n-1]
+		out.BaseFee = CalcBaseFee(last)
+		if last.GasLimit > 0 {
+			out.GasUsedRatio = float64(last.GasUsed) / float64(last.GasLimit)
+		}
+	}
+	suggest := func(percent int) FeeSuggestion {
+		tip := big.NewInt(DefaultPriorityFee)
+		if len(tips) > 0 {
+			tip = new(big.Int).Set(tips[(len(tips)-1)*percent/100])
+		}
+		maxFee := new(big.Int).Mul(out.BaseFee, big.NewInt(2))
+		return FeeSuggestion{MaxPriorityFeePerGas: tip, MaxFeePerGas: maxFee.Add(maxFee, tip)}
+	}
+	out.Slow, out.Standard, out.Fast = suggest(25), suggest(50), suggest(75)
+	return out
 }
@@ -0,0 +1,88 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/finality.go — финальность блоков: статусы proposed → justified → finalized, голоса валидаторов (prevote/precommit).
+
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+)
+
+// Статусы блока в слое финальности.
+const (
+	BlockStatusProposed  = "proposed"  // блок добавлен в цепь, голосов большинства ещё нет
+	BlockStatusJustified = "justified" // более 2/3 веса валидаторов проголосовали prevote
+	BlockStatusFinalized = "finalized" // более 2/3 веса валидаторов проголосовали precommit; блок необратим
+)
+
+// Типы голосов валидаторов.
+const (
+	VotePrevote   = "prevote"
+	VotePrecommit = "precommit"
+)
+
+// Vote — голос валидатора за блок на высоте Height. Signature — DER-подпись secp256k1 SigningHash в hex.
+type Vote struct {
+	Type      string `json:"type"`
+	Height    uint64 `json:"height"`
+	BlockHash string `json:"block_hash"`
+	Validator string `json:"validator"`
+	Signature string `json:"signature"`
+}
+
+// SigningHash возвращает хеш, который подписывает валидатор.
+func (v *Vote) SigningHash() []byte {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("GND-vote|%s|%d|%s|%s", v.Type, v.Height, v.BlockHash, v.Validator)))
+	return sum[:]
+}
+
+// FinalityGadget — слой финальности поверх движка консенсуса: принимает голоса валидаторов и меняет статусы блоков.
+type FinalityGadget interface {
+	AddVote(v *Vote) error
+}
+
+// SetBlockStatus меняет статус блока в памяти и в БД (blocks.status, is_finalized).
+// Не берёт блокировку цепи: может вызываться из BlockListener.
+func (bc *Blockchain) SetBlockStatus(block *Block, status string) error {
+	if bc.Pool == nil || block.ID == 0 {
+		block.Status = status
+		block.IsFinalized = status == BlockStatusFinalized
+		block.UpdatedAt = time.Now()
+		return nil
+	}
+	return block.UpdateStatus(context.Background(), bc.Pool, status)
+}
+
+// FinalizedBlock возвращает последний финализированный блок цепи (не ниже генезиса).
+func (bc *Blockchain) FinalizedBlock() *Block {
+	return bc.lastWithStatus(BlockStatusFinalized)
+}
+
+// JustifiedBlock возвращает последний блок со статусом justified или finalized.
+func (bc *Blockchain) JustifiedBlock() *Block {
+	return bc.lastWithStatus(BlockStatusJustified, BlockStatusFinalized)
+}
+
+func (bc *Blockchain) lastWithStatus(statuses ...string) *Block {
+	for i := len(bc.Blocks) - 1; i >= 0; i-- {
+		b := bc.Blocks[i]
+		for _, s := range statuses {
+			if b.Status == s {
+				return b
+			}
+		}
+	}
+	return bc.Genesis
+}
+
+// FinalityLag возвращает, на сколько блоков вершина цепи опережает последний финализированный блок.
+func (bc *Blockchain) FinalityLag() uint64 {
+	head := bc.Height()
+	finalized := bc.FinalizedBlock()
+	if finalized == nil || finalized.Index >= head {
+		return 0
+	}
+	return head - finalized.Index
+}
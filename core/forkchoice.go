@@ -0,0 +1,547 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/forkchoice.go — дерево блоков: боковые ветки, выбор ветки (PoA — самая длинная, PoS — наибольший стейк предлагающих)
+// и реорганизация цепи с откатом состояния к общему предку.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"GND/types"
+)
+
+// MaxReorgDepth — для скольких последних блоков цепи хранится состояние; глубже реорганизация не принимается.
+const MaxReorgDepth = 64
+
+const maxSideBlocks = 256 // предел блоков боковых веток в памяти
+
+// ReorgEvent — реорганизация цепи: блоки после CommonAncestor до OldHead заменены веткой до NewHead.
+type ReorgEvent struct {
+	CommonAncestor *Block
+	OldHead        *Block
+	NewHead        *Block
+	Orphaned       []*Block       // блоки прежней ветки по возрастанию номера (is_orphaned = true)
+	Adopted        []*Block       // блоки новой ветки по возрастанию номера
+	Returned       []*Transaction // транзакции прежней ветки, вернувшиеся в мемпул
+}
+
+// stateCheckpoint — копия состояния после блока: балансы, nonce, кэши кода и storage контрактов, реестр стейкинга.
+type stateCheckpoint struct {
+	index    uint64
+	balances map[types.Address]map[string]*big.Int
+	nonces   map[types.Address]uint64
+	codes    map[string][]byte
+	storage  map[string]map[string][]byte
+	stakes   *StakeSnapshot
+}
+
+// HasBlock сообщает, известен ли блок: среди последних MaxReorgDepth блоков цепи или в боковой ветке.
+func (bc *Blockchain) HasBlock(hash string) bool {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	block, _ := bc.findBlockLocked(hash)
+	return block != nil
+}
+
+// SideBlocks возвращает блоки боковых веток (в т.ч. отменённые реорганизацией), которые могут стать частью цепи.
+func (bc *Blockchain) SideBlocks() []*Block {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	blocks := make([]*Block, 0, len(bc.sideBlocks))
+	for _, b := range bc.sideBlocks {
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// findBlockLocked ищет блок по хешу среди последних блоков цепи и в боковых ветках; canonical — блок в цепи.
+func (bc *Blockchain) findBlockLocked(hash string) (block *Block, canonical bool) {
+	for i := len(bc.Blocks) - 1; i >= 0 && i >= len(bc.Blocks)-1-MaxReorgDepth; i-- {
+		if bc.Blocks[i].Hash == hash {
+			return bc.Blocks[i], true
+		}
+	}
+	if b, ok := bc.sideBlocks[hash]; ok {
+		return b, false
+	}
+	return nil, false
+}
+
+// addSideBlockLocked сохраняет блок боковой ветки в памяти (в БД он попадает, только став частью цепи)
+// и реорганизует цепь, если ветка блока тяжелее текущей. Ветки ниже финализированного блока не принимаются.
+func (bc *Blockchain) addSideBlockLocked(block, parent *Block) error {
+	if finalized := bc.FinalizedBlock(); finalized != nil && parent.Index < finalized.Index {
+		return fmt.Errorf("блок %d ответвляется от блока %d ниже финализированного %d", block.Index, parent.Index, finalized.Index)
+	}
+	if len(bc.sideBlocks) >= maxSideBlocks {
+		return fmt.Errorf("блоков боковых веток уже %d (предел)", maxSideBlocks)
+	}
+	block.ID, block.ParentID, block.IsOrphaned = 0, nil, false
+	block.Status, block.IsFinalized = BlockStatusProposed, false
+	if bc.sideBlocks == nil {
+		bc.sideBlocks = make(map[string]*Block)
+	}
+	bc.sideBlocks[block.Hash] = block
+
+	ancestor, oldBranch, newBranch := bc.forkLocked(block)
+	if ancestor == nil {
+		return nil
+	}
+	var stakes *StakeSnapshot
+	if cp := bc.checkpoints[ancestor.Hash]; cp != nil {
+		stakes = cp.stakes
+	}
+	if branchWeight(newBranch, stakes).Cmp(branchWeight(oldBranch, stakes)) <= 0 {
+		fmt.Printf("Блок %d (%s) сохранён в боковой ветке от блока %d\n", block.Index, block.Hash, ancestor.Index)
+		return nil
+	}
+	return bc.reorgLocked(ancestor, oldBranch, newBranch)
+}
+
+// forkLocked возвращает общего предка ветки head с цепью, блоки цепи после него и блоки ветки до head (по возрастанию номера).
+func (bc *Blockchain) forkLocked(head *Block) (ancestor *Block, oldBranch, newBranch []*Block) {
+	for b := head; ancestor == nil; {
+		newBranch = append([]*Block{b}, newBranch...)
+		parent, canonical := bc.findBlockLocked(b.PrevHash)
+		if parent == nil {
+			return nil, nil, nil
+		}
+		if canonical {
+			ancestor = parent
+		}
+		b = parent
+	}
+	for i := len(bc.Blocks) - 1; i >= 0 && bc.Blocks[i] != ancestor; i-- {
+		oldBranch = append([]*Block{bc.Blocks[i]}, oldBranch...)
+	}
+	return ancestor, oldBranch, newBranch
+}
+
+// branchWeight возвращает вес ветки для выбора: стейк предлагающего для блоков PoS, 1 для остальных
+// (для PoA выигрывает самая длинная ветка). Ветка выбирается, только если она строго тяжелее текущей.
+// Стейк берётся из снимка реестра в контрольной точке общего предка, а не из текущей вершины: обе ветки
+// взвешиваются одинаково на всех узлах независимо от того, на какой ветке узел сейчас стоит.
+func branchWeight(branch []*Block, stakes *StakeSnapshot) *big.Int {
+	weight := new(big.Int)
+	for _, b := range branch {
+		if b.Consensus == "pos" && stakes != nil {
+			if stake := stakes.TotalStake(b.Miner); stake.Sign() > 0 {
+				weight.Add(weight, stake)
+				continue
+			}
+		}
+		weight.Add(weight, big.NewInt(1))
+	}
+	return weight
+}
+
+// reorgLocked переключает цепь на newBranch: откатывает состояние к общему предку, отменяет блоки oldBranch
+// (is_orphaned в blocks), применяет новую ветку, возвращает транзакции отменённых блоков в мемпул и уведомляет подписчиков.
+// Если блок новой ветки не применяется, он и его потомки отбрасываются, а цепь возвращается на прежнюю ветку.
+func (bc *Blockchain) reorgLocked(ancestor *Block, oldBranch, newBranch []*Block) error {
+	cp := bc.checkpoints[ancestor.Hash]
+	if cp == nil {
+		return fmt.Errorf("нет состояния после блока %d: реорганизация глубже %d блоков невозможна", ancestor.Index, MaxReorgDepth)
+	}
+	oldHead := bc.Blocks[len(bc.Blocks)-1]
+	bc.rollbackLocked(cp, oldBranch)
+
+	adopted, err := bc.connectBranchLocked(newBranch)
+	if err != nil {
+		bc.dropSideBranchLocked(newBranch[len(adopted)])
+		bc.rollbackLocked(cp, adopted)
+		if _, errBack := bc.connectBranchLocked(oldBranch); errBack != nil {
+			fmt.Printf("предупреждение: прежняя ветка не восстановлена после блока %d: %v\n", ancestor.Index, errBack)
+		}
+		return fmt.Errorf("реорганизация от блока %d отменена: %w", ancestor.Index, err)
+	}
+
+	// Транзакции отменённых блоков, не вошедшие в новую ветку, снова ожидают включения
+	included := make(map[string]bool)
+	for _, b := range adopted {
+		for _, tx := range b.Transactions {
+			included[tx.Hash] = true
+		}
+	}
+	var returned []*Transaction
+	for _, b := range oldBranch {
+		for _, tx := range b.Transactions {
+			if tx == nil || included[tx.Hash] {
+				continue
+			}
+			tx.Status, tx.BlockID, tx.GasUsed = "pending", 0, 0
+			if bc.Mempool == nil {
+				continue
+			}
+			if err := bc.Mempool.Add(tx); err != nil {
+				bc.markDroppedTx(tx, TxStatusEvicted)
+				continue
+			}
+			returned = append(returned, tx)
+		}
+	}
+	if bc.Mempool != nil {
+		bc.Mempool.Reset()
+	}
+
+	if m := GetMetrics(); m != nil {
+		m.RecordReorg()
+	}
+	newHead := adopted[len(adopted)-1]
+	fmt.Printf("Реорганизация цепи: блок %d (%s) → %d (%s), общий предок %d, отменено блоков %d, в мемпул возвращено транзакций %d\n",
+		oldHead.Index, oldHead.Hash, newHead.Index, newHead.Hash, ancestor.Index, len(oldBranch), len(returned))
+	bc.notifyReorg(&ReorgEvent{CommonAncestor: ancestor, OldHead: oldHead, NewHead: newHead,
+		Orphaned: oldBranch, Adopted: adopted, Returned: returned})
+	return nil
+}
+
+// connectBranchLocked добавляет блоки боковой ветки на вершину цепи по порядку; возвращает добавленные до первой ошибки.
+func (bc *Blockchain) connectBranchLocked(branch []*Block) ([]*Block, error) {
+	connected := make([]*Block, 0, len(branch))
+	for _, b := range branch {
+		delete(bc.sideBlocks, b.Hash)
+		if err := bc.connectBlockLocked(b, bc.Blocks[len(bc.Blocks)-1]); err != nil {
+			bc.sideBlocks[b.Hash] = b
+			return connected, err
+		}
+		connected = append(connected, b)
+	}
+	return connected, nil
+}
+
+// rollbackLocked снимает blocks с вершины цепи: возвращает состояние к контрольной точке общего предка,
+// переносит блоки в боковые ветки и помечает их в БД отменёнными.
+func (bc *Blockchain) rollbackLocked(cp *stateCheckpoint, blocks []*Block) {
+	bc.revertStateLocked(cp)
+	bc.Blocks = bc.Blocks[:len(bc.Blocks)-len(blocks)]
+	bc.setPendingFeeContext(bc.Blocks[len(bc.Blocks)-1])
+	if bc.sideBlocks == nil {
+		bc.sideBlocks = make(map[string]*Block)
+	}
+	bc.receiptsMu.Lock()
+	for _, b := range blocks {
+		b.IsOrphaned = true
+		bc.sideBlocks[b.Hash] = b
+		delete(bc.checkpoints, b.Hash)
+		for _, tx := range b.Transactions {
+			if r, ok := bc.receipts[tx.Hash]; ok && r.BlockHash == b.Hash {
+				delete(bc.receipts, tx.Hash)
+			}
+		}
+	}
+	bc.receiptsMu.Unlock()
+	bc.orphanBlocksInDB(blocks)
+}
+
+// dropSideBranchLocked удаляет из боковых веток блок и всех его потомков (блок не применяется к состоянию).
+func (bc *Blockchain) dropSideBranchLocked(block *Block) {
+	delete(bc.sideBlocks, block.Hash)
+	for _, b := range bc.sideBlocks {
+		if b.PrevHash == block.Hash {
+			bc.dropSideBranchLocked(b)
+		}
+	}
+}
+
+// orphanBlocksInDB помечает блоки отменёнными (is_orphaned) и удаляет записанные ими квитанции, события и снимки состояния;
+// их транзакции снова ожидают включения (block_id = NULL, status = pending).
+func (bc *Blockchain) orphanBlocksInDB(blocks []*Block) {
+	if bc.Pool == nil {
+		return
+	}
+	ids := make([]int64, 0, len(blocks))
+	for _, b := range blocks {
+		if b.ID != 0 {
+			ids = append(ids, b.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	ctx := context.Background()
+	if _, err := bc.Pool.Exec(ctx, `UPDATE blocks SET is_orphaned = TRUE, is_finalized = FALSE, updated_at = $2 WHERE id = ANY($1)`,
+		ids, BlockchainNow()); err != nil {
+		fmt.Printf("предупреждение: не удалось пометить блоки %v отменёнными: %v\n", ids, err)
+	}
+	for _, q := range []string{
+		`DELETE FROM receipts WHERE block_id = ANY($1)`,
+		`DELETE FROM contract_logs WHERE block_id = ANY($1)`,
+		`DELETE FROM account_states WHERE block_id = ANY($1)`,
+		`DELETE FROM contract_storage WHERE block_id = ANY($1)`,
+		`UPDATE transactions SET block_id = NULL, status = 'pending' WHERE block_id = ANY($1)`,
+	} {
+		if _, err := bc.Pool.Exec(ctx, q, ids); err != nil {
+			fmt.Printf("предупреждение: не удалось отменить данные блоков %v: %v\n", ids, err)
+		}
+	}
+}
+
+// saveCheckpointLocked запоминает текущее состояние как состояние после block, если оно ещё не сохранено.
+func (bc *Blockchain) saveCheckpointLocked(block *Block) {
+	st, ok := bc.State.(*State)
+	if !ok || block == nil {
+		return
+	}
+	if _, exists := bc.checkpoints[block.Hash]; exists {
+		return
+	}
+	if bc.checkpoints == nil {
+		bc.checkpoints = make(map[string]*stateCheckpoint)
+	}
+	cp := st.checkpoint()
+	cp.index = block.Index
+	if bc.Stakes != nil {
+		cp.stakes = bc.Stakes.snapshot()
+	}
+	bc.checkpoints[block.Hash] = cp
+}
+
+// revertStateLocked возвращает состояние и реестр стейкинга к контрольной точке и записывает в БД значения аккаунтов
+// и кода контрактов, которые отличались от неё: иначе accounts, native_balances, token_balances и contracts
+// остаются в состоянии отменённой ветки (SaveToDB пишет только аккаунты, известные состоянию).
+func (bc *Blockchain) revertStateLocked(cp *stateCheckpoint) {
+	st, ok := bc.State.(*State)
+	if !ok || cp == nil {
+		return
+	}
+	accounts, codes := st.revert(cp)
+	if bc.Stakes != nil && cp.stakes != nil {
+		bc.Stakes.restore(cp.stakes)
+	}
+	if err := st.saveReverted(accounts, codes); err != nil {
+		fmt.Printf("предупреждение: не удалось записать состояние после отката: %v\n", err)
+	}
+}
+
+// pruneForksLocked удаляет боковые ветки не выше финализированного блока и глубже MaxReorgDepth. Контрольные точки
+// нужны и для доказательств по state_root, поэтому хранятся для последних MaxReorgDepth блоков независимо от финальности.
+func (bc *Blockchain) pruneForksLocked() {
+	depthFloor := uint64(0)
+	if h := bc.Height(); h > MaxReorgDepth {
+		depthFloor = h - MaxReorgDepth
+	}
+	floor := depthFloor
+	if finalized := bc.FinalizedBlock(); finalized != nil && finalized.Index > floor {
+		floor = finalized.Index
+	}
+	for hash, b := range bc.sideBlocks {
+		if b.Index <= floor {
+			delete(bc.sideBlocks, hash)
+		}
+	}
+	for hash, cp := range bc.checkpoints {
+		if cp.index < depthFloor {
+			delete(bc.checkpoints, hash)
+		}
+	}
+}
+
+// checkpoint возвращает копию состояния для отката при реорганизации.
+func (s *State) checkpoint() *stateCheckpoint {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	cp := &stateCheckpoint{
+		balances: copyBalances(s.balances),
+		nonces:   make(map[types.Address]uint64, len(s.nonces)),
+		codes:    make(map[string][]byte, len(s.codes)),
+		storage:  copyStorage(s.storage),
+	}
+	for addr, n := range s.nonces {
+		cp.nonces[addr] = n
+	}
+	for addr, code := range s.codes {
+		cp.codes[addr] = code
+	}
+	return cp
+}
+
+// StateAt возвращает отдельную копию состояния после блока number (nil — текущее состояние ноды) и сам блок.
+// Копия не связана с БД и цепью: исполнение в ней ничего не применяет. Списание газа в копии — по base fee
+// блока-потомка. Состояние после блока хранится для последних MaxReorgDepth блоков, для более старых — ErrStateUnavailable.
+func (bc *Blockchain) StateAt(number *uint64) (*State, *Block, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	st, block, _, err := bc.stateAtLocked(number)
+	return st, block, err
+}
+
+// stateAtLocked — StateAt под блокировкой цепи; также возвращает снимок реестра стейкинга после блока (nil — нет данных).
+func (bc *Blockchain) stateAtLocked(number *uint64) (*State, *Block, *StakeSnapshot, error) {
+	src, ok := bc.State.(*State)
+	if !ok || src == nil {
+		return nil, nil, nil, errors.New("состояние цепи не поддерживает копирование")
+	}
+	head, err := bc.LatestBlock()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	block, cp := head, (*stateCheckpoint)(nil)
+	if number == nil || *number == head.Index {
+		cp = src.checkpoint()
+		if bc.Stakes != nil {
+			cp.stakes = bc.Stakes.snapshot()
+		}
+	} else {
+		block = nil
+		for i := len(bc.Blocks) - 1; i >= 0; i-- {
+			if bc.Blocks[i].Index == *number {
+				block = bc.Blocks[i]
+				break
+			}
+		}
+		if block == nil {
+			return nil, nil, nil, fmt.Errorf("блок %d не найден в цепи", *number)
+		}
+		if cp = bc.checkpoints[block.Hash]; cp == nil {
+			return nil, nil, nil, fmt.Errorf("блок %d: %w", *number, ErrStateUnavailable)
+		}
+	}
+	st := NewState()
+	st.revert(cp)
+	src.mutex.RLock()
+	st.gndselfAddress = src.gndselfAddress
+	st.feeCollectorAddress = src.feeCollectorAddress
+	st.treasuryAddress = src.treasuryAddress
+	src.mutex.RUnlock()
+	st.baseFee = CalcBaseFee(block)
+	return st, block, cp.stakes, nil
+}
+
+// revertedAccount — аккаунт, значения которого до отката отличались от контрольной точки: nonce и (или) балансы по symbols.
+type revertedAccount struct {
+	address types.Address
+	symbols []string
+}
+
+// revert заменяет состояние копией контрольной точки (точка остаётся пригодной для повторного отката)
+// и возвращает аккаунты и адреса контрактов, значения которых отличались от точки.
+func (s *State) revert(cp *stateCheckpoint) ([]revertedAccount, []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	seen := make(map[types.Address]struct{})
+	for _, m := range []map[types.Address]uint64{s.nonces, cp.nonces} {
+		for addr := range m {
+			seen[addr] = struct{}{}
+		}
+	}
+	for _, m := range []map[types.Address]map[string]*big.Int{s.balances, cp.balances} {
+		for addr := range m {
+			seen[addr] = struct{}{}
+		}
+	}
+	var accounts []revertedAccount
+	for addr := range seen {
+		acc := revertedAccount{address: addr}
+		symbols := make(map[string]struct{})
+		for sym := range s.balances[addr] {
+			symbols[sym] = struct{}{}
+		}
+		for sym := range cp.balances[addr] {
+			symbols[sym] = struct{}{}
+		}
+		for sym := range symbols {
+			if balanceOrZero(s.balances[addr][sym]).Cmp(balanceOrZero(cp.balances[addr][sym])) != 0 {
+				acc.symbols = append(acc.symbols, sym)
+			}
+		}
+		if len(acc.symbols) > 0 || s.nonces[addr] != cp.nonces[addr] {
+			accounts = append(accounts, acc)
+		}
+	}
+	// Код, изменённый после последней записи (codeChanges), в contracts не попадал — его возвращать в БД не нужно
+	var codes []string
+	for addr, code := range s.codes {
+		if _, pending := s.codeChanges[addr]; !pending && !bytes.Equal(code, cp.codes[addr]) {
+			codes = append(codes, addr)
+		}
+	}
+	for addr := range cp.codes {
+		if _, pending := s.codeChanges[addr]; !pending {
+			if _, ok := s.codes[addr]; !ok {
+				codes = append(codes, addr)
+			}
+		}
+	}
+
+	s.balances = copyBalances(cp.balances)
+	s.nonces = make(map[types.Address]uint64, len(cp.nonces))
+	for addr, n := range cp.nonces {
+		s.nonces[addr] = n
+	}
+	s.codes = make(map[string][]byte, len(cp.codes))
+	for addr, code := range cp.codes {
+		s.codes[addr] = code
+	}
+	s.storage = copyStorage(cp.storage)
+	s.touchedInBlock = make(map[types.Address]struct{})
+	s.storageChanges = nil
+	s.savedLeaves = nil
+	s.codeChanges = nil
+	return accounts, codes
+}
+
+// saveReverted записывает в БД значения после отката для аккаунтов и контрактов, отличавшихся от контрольной точки:
+// nonce и баланс GND в accounts, изменившиеся балансы (native_balances или token_balances) и contracts.runtime_code
+// (NULL для контрактов, созданных в отменённых блоках). Слоты storage отменённых блоков удаляет orphanBlocksInDB.
+func (s *State) saveReverted(accounts []revertedAccount, codes []string) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.pool == nil {
+		return nil
+	}
+	ctx := context.Background()
+	for _, acc := range accounts {
+		for _, sym := range acc.symbols {
+			if err := s.saveBalanceLocked(ctx, acc.address, sym, balanceOrZero(s.balances[acc.address][sym])); err != nil {
+				return err
+			}
+		}
+		if err := s.saveAccountLocked(ctx, acc.address); err != nil {
+			return err
+		}
+	}
+	for _, addr := range codes {
+		if _, err := s.pool.Exec(ctx, `UPDATE contracts SET runtime_code = $2 WHERE address = $1`, addr, s.codes[addr]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func balanceOrZero(b *big.Int) *big.Int {
+	if b == nil {
+		return new(big.Int)
+	}
+	return b
+}
+
+func copyBalances(src map[types.Address]map[string]*big.Int) map[types.Address]map[string]*big.Int {
+	dst := make(map[types.Address]map[string]*big.Int, len(src))
+	for addr, bm := range src {
+		m := make(map[string]*big.Int, len(bm))
+		for symbol, b := range bm {
+			if b != nil {
+				m[symbol] = new(big.Int).Set(b)
+			}
+		}
+		dst[addr] = m
+	}
+	return dst
+}
+
+// copyStorage копирует кэш слотов; значения слотов не изменяются на месте, а заменяются, поэтому копируются ссылки.
+func copyStorage(src map[string]map[string][]byte) map[string]map[string][]byte {
+	dst := make(map[string]map[string][]byte, len(src))
+	for addr, slots := range src {
+		m := make(map[string][]byte, len(slots))
+		for k, v := range slots {
+			m[k] = v
+		}
+		dst[addr] = m
+	}
+	return dst
+}
@@ -0,0 +1,284 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"GND/types"
+)
+
+// acceptAllEngine — движок консенсуса без подписей: блоки получают статус proposed и допускают реорганизацию.
+type acceptAllEngine struct{}
+
+func (acceptAllEngine) Type() string                  { return "poa" }
+func (acceptAllEngine) Seal(*Block) error             { return nil }
+func (acceptAllEngine) VerifyBlock(_, _ *Block) error { return nil }
+
+// childBlock — блок-потомок parent без исполнения: state_root заголовка не заполнен (см. executeTestBlock, emptyChild).
+func childBlock(parent *Block, miner string, txs ...*Transaction) *Block {
+	b := &Block{Index: parent.Index + 1, PrevHash: parent.Hash, Timestamp: time.Now(), Miner: miner, GasLimit: DefaultBlockGasLimit,
+		Consensus: "poa", Transactions: txs, MerkleRoot: ComputeMerkleRoot(txs)}
+	b.Hash = b.CalculateHash()
+	return b
+}
+
+// emptyChild — пустой блок-потомок parent боковой ветки: состояние после него совпадает с состоянием после parent (root).
+func emptyChild(parent *Block, miner, root string) *Block {
+	b := childBlock(parent, miner)
+	b.StateRoot = root
+	b.Hash = b.CalculateHash()
+	return b
+}
+
+func TestAddBlock_ReorgsToLongerBranch(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: BlockStatusFinalized}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+	bc.Engine = acceptAllEngine{}
+	st := bc.State.(*State)
+	SetState(st)
+	defer SetState(nil)
+	var events []*ReorgEvent
+	bc.OnReorg(func(ev *ReorgEvent) { events = append(events, ev) })
+	forks := GetMetrics().ConsensusMetrics.ForkCount
+
+	wallet := newTestWallet(t)
+	sender, recipient := types.Address(wallet.Address), types.Address("GN_fork_recipient")
+	if err := st.AddBalance(sender, GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	tx := signTestTx(t, wallet, &Transaction{Recipient: recipient, Value: big.NewInt(100), GasLimit: TxGas, Symbol: GasSymbol})
+	genesisRoot := st.RootHash()
+	a1 := executeTestBlock(t, bc, childBlock(genesis, "validator_a", tx))
+	if err := bc.AddBlock(a1); err != nil {
+		t.Fatal(err)
+	}
+
+	// ветка B той же длины не тяжелее текущей — блок остаётся в боковой ветке
+	b1 := emptyChild(genesis, "validator_b", genesisRoot)
+	if err := bc.AddBlock(b1); err != nil {
+		t.Fatal(err)
+	}
+	if tip, _ := bc.LatestBlock(); tip != a1 || len(bc.SideBlocks()) != 1 {
+		t.Fatalf("вершина %d, боковых блоков %d: ветка B не должна выбираться при равной длине", tip.Index, len(bc.SideBlocks()))
+	}
+
+	// блок с газом, не совпадающим с исполнением, отменяет реорганизацию: цепь и состояние остаются на ветке A
+	bad := emptyChild(b1, "validator_b", genesisRoot)
+	bad.GasUsed = 1
+	bad.Hash = bad.CalculateHash()
+	if err := bc.AddBlock(bad); err == nil {
+		t.Fatal("реорганизация на ветку с неверным блоком должна отклоняться")
+	}
+	if tip, _ := bc.LatestBlock(); tip != a1 || st.GetBalance(recipient, GasSymbol).Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("после отменённой реорганизации вершина %s, баланс получателя %s", tip.Hash, st.GetBalance(recipient, GasSymbol))
+	}
+
+	b2 := emptyChild(b1, "validator_b", genesisRoot)
+	if err := bc.AddBlock(b2); err != nil {
+		t.Fatal(err)
+	}
+	blocks := bc.AllBlocks()
+	if len(blocks) != 3 || blocks[1] != b1 || blocks[2] != b2 {
+		t.Fatalf("цепь должна перейти на ветку B: %d блоков", len(blocks))
+	}
+	if !a1.IsOrphaned || b1.IsOrphaned {
+		t.Errorf("блок A1 должен стать орфаном, B1 — нет: %v, %v", a1.IsOrphaned, b1.IsOrphaned)
+	}
+	if got := st.GetBalance(recipient, GasSymbol); got.Sign() != 0 || st.GetNonce(sender) != 0 {
+		t.Errorf("перевод отменённого блока должен откатиться: баланс получателя %s, nonce %d", got, st.GetNonce(sender))
+	}
+	if !bc.Mempool.Exists(tx.Hash) || tx.Status != "pending" {
+		t.Errorf("транзакция отменённого блока должна вернуться в мемпул, статус %q", tx.Status)
+	}
+	if len(events) != 1 || events[0].CommonAncestor != genesis || events[0].OldHead != a1 || events[0].NewHead != b2 ||
+		len(events[0].Orphaned) != 1 || len(events[0].Adopted) != 2 || len(events[0].Returned) != 1 {
+		t.Fatalf("событие реорганизации: %+v", events)
+	}
+	if got := GetMetrics().ConsensusMetrics.ForkCount; got != forks+1 {
+		t.Errorf("ForkCount: ожидалось %d, получено %d", forks+1, got)
+	}
+
+	// транзакция снова включается в блок новой ветки
+	if err := bc.ProduceNextBlock(bc.Mempool, "validator_b", 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.GetBalance(recipient, GasSymbol); got.Cmp(big.NewInt(100)) != 0 || bc.Mempool.Exists(tx.Hash) {
+		t.Errorf("после повторного включения баланс получателя %s", got)
+	}
+}
+
+func TestAddBlock_RejectsForkBelowFinalized(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: BlockStatusFinalized}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+	bc.Engine = acceptAllEngine{}
+	root := bc.State.(*State).RootHash() // пустые блоки состояние не меняют
+
+	a1 := emptyChild(genesis, "validator_a", root)
+	if err := bc.AddBlock(a1); err != nil {
+		t.Fatal(err)
+	}
+	a2 := emptyChild(a1, "validator_a", root)
+	if err := bc.AddBlock(a2); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.SetBlockStatus(a1, BlockStatusFinalized); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(emptyChild(genesis, "validator_b", root)); err == nil {
+		t.Error("ветка от блока ниже финализированного должна отклоняться")
+	}
+	if err := bc.AddBlock(emptyChild(a1, "validator_b", root)); err != nil {
+		t.Errorf("ветка от финализированного блока допустима: %v", err)
+	}
+	if err := bc.AddBlock(a2); err == nil {
+		t.Error("повторный блок должен отклоняться")
+	}
+}
+
+func TestStateAt_DetachedCopyAfterBlock(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: BlockStatusFinalized}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+	bc.Engine = acceptAllEngine{} // с движком сохраняется и состояние после генезиса
+	st := bc.State.(*State)
+	SetState(st)
+	defer SetState(nil)
+
+	wallet := newTestWallet(t)
+	sender, recipient := types.Address(wallet.Address), types.Address("GN_stateat_recipient")
+	if err := st.AddBalance(sender, GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	tx := signTestTx(t, wallet, &Transaction{Recipient: recipient, Value: big.NewInt(100), GasLimit: TxGas, Symbol: GasSymbol})
+	b1 := executeTestBlock(t, bc, childBlock(genesis, "miner", tx))
+	if err := bc.AddBlock(b1); err != nil {
+		t.Fatal(err)
+	}
+
+	zero := uint64(0)
+	past, block, err := bc.StateAt(&zero)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block != genesis || past.GetBalance(recipient, GasSymbol).Sign() != 0 {
+		t.Errorf("состояние после генезиса: блок %d, баланс получателя %s", block.Index, past.GetBalance(recipient, GasSymbol))
+	}
+	latest, block, err := bc.StateAt(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block != b1 || latest.GetBalance(recipient, GasSymbol).Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("текущее состояние: блок %d, баланс получателя %s", block.Index, latest.GetBalance(recipient, GasSymbol))
+	}
+	if latest.BaseFee().Cmp(CalcBaseFee(b1)) != 0 {
+		t.Errorf("base fee копии %s, ожидалась base fee следующего блока %s", latest.BaseFee(), CalcBaseFee(b1))
+	}
+
+	// изменения в копии не попадают в состояние цепи
+	if err := latest.AddBalance(recipient, GasSymbol, big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.GetBalance(recipient, GasSymbol); got.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("копия должна быть отделена от состояния цепи: баланс получателя %s", got)
+	}
+
+	missing := uint64(5)
+	if _, _, err := bc.StateAt(&missing); err == nil {
+		t.Error("для блока вне цепи ожидалась ошибка")
+	}
+}
+
+func TestStateRevert_ReportsChangedAccountsAndCodes(t *testing.T) {
+	st := NewState()
+	kept, changed, added := types.Address("GN_revert_kept"), types.Address("GN_revert_changed"), types.Address("GN_revert_added")
+	for _, a := range []types.Address{kept, changed} {
+		if err := st.AddBalance(a, GasSymbol, big.NewInt(10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.SetContractCode("GN_revert_contract", []byte{0x00}); err != nil {
+		t.Fatal(err)
+	}
+	cp := st.checkpoint()
+
+	if err := st.AddBalance(changed, "GANI", big.NewInt(3)); err != nil {
+		t.Fatal(err)
+	}
+	st.SetNonce(added, 1)
+	if err := st.SetContractCode("GN_revert_created", []byte{0x01}); err != nil {
+		t.Fatal(err)
+	}
+	// блок отменённой ветки сохранён: код записан в contracts и при откате должен быть возвращён
+	if err := st.SaveToDB(0); err != nil {
+		t.Fatal(err)
+	}
+
+	accounts, codes := st.revert(cp)
+	got := make(map[types.Address][]string)
+	for _, acc := range accounts {
+		got[acc.address] = acc.symbols
+	}
+	if _, ok := got[kept]; ok || len(got) != 2 {
+		t.Errorf("ожидались только изменённые аккаунты, получено %v", got)
+	}
+	if syms := got[changed]; len(syms) != 1 || syms[0] != "GANI" {
+		t.Errorf("у %s изменился только баланс GANI, получено %v", changed, syms)
+	}
+	if _, ok := got[added]; !ok {
+		t.Errorf("аккаунт %s, которого нет в контрольной точке, должен быть обнулён", added)
+	}
+	if len(codes) != 1 || codes[0] != "GN_revert_created" {
+		t.Errorf("ожидался код только созданного контракта, получено %v", codes)
+	}
+	if st.GetBalance(changed, "GANI").Sign() != 0 || st.GetNonce(added) != 0 {
+		t.Error("состояние должно вернуться к контрольной точке")
+	}
+}
+
+func TestBranchWeight_UsesStakeSnapshot(t *testing.T) {
+	stakes := &StakeSnapshot{Delegations: []Delegation{
+		{Validator: "validator_a", Delegator: "d1", Amount: big.NewInt(10)},
+		{Validator: "validator_a", Delegator: "d2", Amount: big.NewInt(5)},
+		{Validator: "validator_b", Delegator: "d1", Amount: big.NewInt(3)},
+	}}
+	branch := []*Block{{Consensus: "pos", Miner: "validator_a"}, {Consensus: "pos", Miner: "validator_b"},
+		{Consensus: "pos", Miner: "validator_c"}, {Consensus: "poa", Miner: "validator_a"}}
+	if got := branchWeight(branch, stakes); got.Cmp(big.NewInt(15+3+1+1)) != 0 {
+		t.Errorf("вес ветки по снимку: %s", got)
+	}
+	if got := branchWeight(branch, nil); got.Cmp(big.NewInt(4)) != 0 {
+		t.Errorf("без снимка вес ветки — её длина: %s", got)
+	}
+}
+
+func TestSetContractCode_BufferedUntilSave(t *testing.T) {
+	st := NewState()
+	if err := st.SetContractCode("GN_committed", []byte{0x01}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveToDB(0); err != nil {
+		t.Fatal(err)
+	}
+	cp := st.checkpoint()
+
+	// код, записанный исполнением и откатанный, в БД не попадал: откат не возвращает его адрес для записи
+	if err := st.SetContractCode("GN_created", []byte{0x02}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetContractCode("GN_committed", []byte{0x03}); err != nil {
+		t.Fatal(err)
+	}
+	if len(st.codeChanges) != 2 {
+		t.Fatalf("изменения кода должны ждать SaveToDB: %v", st.codeChanges)
+	}
+	if _, codes := st.revert(cp); len(codes) != 0 || len(st.codeChanges) != 0 {
+		t.Fatalf("откат вернул для записи %v, ожидающих изменений %d", codes, len(st.codeChanges))
+	}
+	if code, _ := st.GetContractCode("GN_committed"); len(code) != 1 || code[0] != 0x01 {
+		t.Errorf("код после отката: %x", code)
+	}
+}
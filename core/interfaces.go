@@ -11,6 +11,7 @@ type BlockchainIface interface {
 	LatestBlock() (*Block, error)
 	GetBlockByNumber(number uint64) (*Block, error)
 	AddTx(tx *Transaction) error
+	SendTransaction(tx *Transaction) (string, error)
 	GetTxStatus(hash string) (string, error)
 }
 
@@ -25,3 +26,34 @@ type StateIface interface {
 	SaveToDB(blockID int64) error
 	WillSkipGasForTx(tx *Transaction) bool
 }
+
+// ContractStateIface — состояние с доступом к коду и storage контрактов (для исполнения байткода в vm; реализует *State).
+type ContractStateIface interface {
+	StateIface
+	GetContractCode(address string) ([]byte, bool)
+	SetContractCode(address string, code []byte) error
+	GetStorageSlot(address string, key []byte) []byte
+}
+
+// ContractExecutor исполняет байткод контрактов (реализация — vm.EVM). Результат содержит изменения storage,
+// балансов и кода; применяется к состоянию через State.ApplyExecutionResult.
+type ContractExecutor interface {
+	// ExecuteContractCall исполняет вызов контракта tx в контексте блока block (nil — последний блок цепи).
+	ExecuteContractCall(tx *Transaction, block *Block) (*types.ExecutionResult, error)
+	// ExecuteContractDeploy исполняет init-код транзакции деплоя tx по адресу tx.Recipient в контексте блока block
+	// и возвращает runtime-код контракта.
+	ExecuteContractDeploy(tx *Transaction, block *Block) (*types.ExecutionResult, []byte, error)
+	// ExecuteContractCreate исполняет init-код по адресу contractAddress и возвращает runtime-код контракта (пробное
+	// исполнение конструктора вне блока: результат к состоянию не применяется).
+	ExecuteContractCreate(from types.Address, contractAddress string, initCode []byte, gasLimit uint64) (*types.ExecutionResult, []byte, error)
+}
+
+// ConsensusEngine подписывает блоки ноды и проверяет право предлагающего на блок (реализации — consensus.PoA, consensus.PoS).
+type ConsensusEngine interface {
+	// Type возвращает тип консенсуса блоков движка ("poa", "pos").
+	Type() string
+	// Seal подписывает заголовок блока ключом валидатора ноды (block.Signature).
+	Seal(block *Block) error
+	// VerifyBlock проверяет подпись блока и то, что block.Miner вправе предложить блок после parent.
+	VerifyBlock(block, parent *Block) error
+}
@@ -0,0 +1,88 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/listeners.go — подписка на события цепи: новые блоки и реорганизации (Blockchain), новые и удалённые транзакции (Mempool).
+
+package core
+
+// BlockListener вызывается после добавления блока в цепь, receipts — квитанции его транзакций.
+// Вызывается под блокировкой цепи: обработчик не должен блокироваться и вызывать AddBlock.
+type BlockListener func(block *Block, receipts []*Receipt)
+
+// ReorgListener вызывается после реорганизации цепи (блоки новой ветки перед этим переданы BlockListener).
+// Вызывается под блокировкой цепи: обработчик не должен блокироваться и вызывать AddBlock.
+type ReorgListener func(ev *ReorgEvent)
+
+// TxListener вызывается после добавления транзакции в мемпул. Обработчик не должен блокироваться.
+type TxListener func(tx *Transaction)
+
+// TxDropListener вызывается после удаления транзакции из мемпула без включения в блок;
+// reason — новый статус транзакции (replaced, evicted, expired).
+type TxDropListener func(tx *Transaction, reason string)
+
+// OnBlock регистрирует обработчик новых блоков.
+func (bc *Blockchain) OnBlock(l BlockListener) {
+	bc.listenersMu.Lock()
+	defer bc.listenersMu.Unlock()
+	bc.blockListeners = append(bc.blockListeners, l)
+}
+
+func (bc *Blockchain) notifyBlock(block *Block, receipts []*Receipt) {
+	bc.listenersMu.RLock()
+	listeners := bc.blockListeners
+	bc.listenersMu.RUnlock()
+	for _, l := range listeners {
+		l(block, receipts)
+	}
+}
+
+// OnReorg регистрирует обработчик реорганизаций цепи.
+func (bc *Blockchain) OnReorg(l ReorgListener) {
+	bc.listenersMu.Lock()
+	defer bc.listenersMu.Unlock()
+	bc.reorgListeners = append(bc.reorgListeners, l)
+}
+
+func (bc *Blockchain) notifyReorg(ev *ReorgEvent) {
+	bc.listenersMu.RLock()
+	listeners := bc.reorgListeners
+	bc.listenersMu.RUnlock()
+	for _, l := range listeners {
+		l(ev)
+	}
+}
+
+// OnTx регистрирует обработчик новых транзакций мемпула.
+func (m *Mempool) OnTx(l TxListener) {
+	m.listenersMu.Lock()
+	defer m.listenersMu.Unlock()
+	m.txListeners = append(m.txListeners, l)
+}
+
+func (m *Mempool) notifyTx(tx *Transaction) {
+	m.listenersMu.RLock()
+	listeners := m.txListeners
+	m.listenersMu.RUnlock()
+	for _, l := range listeners {
+		l(tx)
+	}
+}
+
+// OnDrop регистрирует обработчик удаления транзакций из мемпула.
+func (m *Mempool) OnDrop(l TxDropListener) {
+	m.listenersMu.Lock()
+	defer m.listenersMu.Unlock()
+	m.dropListeners = append(m.dropListeners, l)
+}
+
+func (m *Mempool) notifyDrop(dropped []droppedTx) {
+	if len(dropped) == 0 {
+		return
+	}
+	m.listenersMu.RLock()
+	listeners := m.dropListeners
+	m.listenersMu.RUnlock()
+	for _, d := range dropped {
+		for _, l := range listeners {
+			l(d.tx, d.reason)
+		}
+	}
+}
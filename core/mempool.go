@@ -1,89 +1,422 @@
 // | KB @CerberRus00 - Nexus Invest Team
+// core/mempool.go — мемпул: очереди отправителей по nonce (pending/queued), приоритет по цене газа при сборке блока.
 package core
 
 import (
+	"container/heap"
 	"errors"
+	"fmt"
 	"log"
+	"math/big"
+	"sort"
 	"sync"
 	"time"
+
+	"GND/types"
+)
+
+// Причины удаления транзакции из мемпула без включения в блок — записываются в transactions.status.
+const (
+	TxStatusReplaced = "replaced" // заменена транзакцией с тем же nonce: с большей ценой газа или уже включённой в блок
+	TxStatusEvicted  = "evicted"  // вытеснена при переполнении мемпула или удалена администратором
+	TxStatusExpired  = "expired"  // пролежала в мемпуле дольше TTL
 )
 
+// Значения MempoolConfig по умолчанию
+const (
+	DefaultMempoolMaxSize       = 10000
+	DefaultMempoolMaxPerAccount = 64
+	DefaultMempoolPriceBump     = 10
+	DefaultMempoolTTL           = 3 * time.Hour
+)
+
+// NonceSource — источник текущего nonce аккаунта (состояние цепи); State реализует этот интерфейс.
+type NonceSource interface {
+	GetNonce(address types.Address) int64
+}
+
+// senderTxs — транзакции одного отправителя.
+// pending — непрерывная цепочка nonce от текущего nonce аккаунта (готовы к включению в блок),
+// queued — транзакции с будущим nonce, ожидающие заполнения пропуска.
+type senderTxs struct {
+	pending []*Transaction
+	queued  map[int64]*Transaction
+}
+
+// nextNonce возвращает nonce, следующий за последней транзакцией pending (base — nonce аккаунта в состоянии).
+func (s *senderTxs) nextNonce(base int64) int64 {
+	if n := len(s.pending); n > 0 {
+		return s.pending[n-1].Nonce + 1
+	}
+	return base
+}
+
+// get возвращает транзакцию отправителя с данным nonce или nil.
+func (s *senderTxs) get(nonce int64) *Transaction {
+	if tx, ok := s.queued[nonce]; ok {
+		return tx
+	}
+	for _, tx := range s.pending {
+		if tx.Nonce == nonce {
+			return tx
+		}
+	}
+	return nil
+}
+
+// getOrNil — get для отправителя, которого может не быть в мемпуле.
+func (s *senderTxs) getOrNil(nonce int64) *Transaction {
+	if s == nil {
+		return nil
+	}
+	return s.get(nonce)
+}
+
+// replace ставит tx на место old (тот же nonce) в pending или queued.
+func (s *senderTxs) replace(old, tx *Transaction) {
+	if _, ok := s.queued[old.Nonce]; ok {
+		s.queued[old.Nonce] = tx
+		return
+	}
+	for i := range s.pending {
+		if s.pending[i] == old {
+			s.pending[i] = tx
+			return
+		}
+	}
+}
+
+// last возвращает транзакцию отправителя с наибольшим nonce — её удаление не разрывает цепочку pending.
+func (s *senderTxs) last() *Transaction {
+	var last *Transaction
+	for _, tx := range s.queued {
+		if last == nil || tx.Nonce > last.Nonce {
+			last = tx
+		}
+	}
+	if last == nil && len(s.pending) > 0 {
+		last = s.pending[len(s.pending)-1]
+	}
+	return last
+}
+
+func (s *senderTxs) len() int {
+	return len(s.pending) + len(s.queued)
+}
+
+// promote переносит из queued в pending транзакции, продолжающие непрерывную цепочку nonce.
+func (s *senderTxs) promote(base int64) int {
+	promoted := 0
+	for next := s.nextNonce(base); ; next++ {
+		tx, ok := s.queued[next]
+		if !ok {
+			return promoted
+		}
+		delete(s.queued, next)
+		s.pending = append(s.pending, tx)
+		promoted++
+	}
+}
+
+// droppedTx — транзакция, удалённая из мемпула, и причина удаления (для уведомления после снятия блокировки).
+type droppedTx struct {
+	tx     *Transaction
+	reason string
+}
+
+// MempoolEntry — транзакция мемпула с её положением в очереди отправителя (для админки).
+type MempoolEntry struct {
+	Tx        *Transaction
+	Queued    bool      // true — ждёт пропущенный nonce, false — готова к включению в блок
+	AddedAt   time.Time // время поступления в мемпул этой ноды (не tx.Timestamp отправителя), от него отсчитывается TTL
+	ExpiresAt time.Time
+}
+
 type Mempool struct {
-	txChan chan *Transaction
-	mu     sync.RWMutex
-	txMap  map[string]*Transaction
-	logger *log.Logger
+	mu      sync.RWMutex
+	txMap   map[string]*Transaction
+	addedAt map[string]time.Time // локальное время поступления: TTL и очерёдность при равной цене газа
+	senders map[types.Address]*senderTxs
+	nonces  NonceSource // nil — nonce всех аккаунтов считается нулевым
+	logger  *log.Logger
+
+	maxSize       int
+	maxPerAccount int
+	priceBump     int
+	ttl           time.Duration
+
+	listenersMu   sync.RWMutex
+	txListeners   []TxListener     // подписчики на новые транзакции (WebSocket и т.п.)
+	dropListeners []TxDropListener // подписчики на удаление транзакций (обновление статуса в БД)
 }
 
+// NewMempool создаёт мемпул с лимитами по умолчанию.
 func NewMempool() *Mempool {
-	return &Mempool{
-		txChan: make(chan *Transaction, 10000), // буфер на 10 000 транзакций
-		txMap:  make(map[string]*Transaction),
-		logger: log.New(log.Writer(), "[Mempool] ", log.LstdFlags),
+	return NewMempoolWithConfig(MempoolConfig{})
+}
+
+// NewMempoolWithConfig создаёт мемпул с лимитами из конфига; незаданные поля получают значения по умолчанию.
+func NewMempoolWithConfig(cfg MempoolConfig) *Mempool {
+	m := &Mempool{
+		txMap:         make(map[string]*Transaction),
+		addedAt:       make(map[string]time.Time),
+		senders:       make(map[types.Address]*senderTxs),
+		logger:        log.New(log.Writer(), "[Mempool] ", log.LstdFlags),
+		maxSize:       DefaultMempoolMaxSize,
+		maxPerAccount: DefaultMempoolMaxPerAccount,
+		priceBump:     DefaultMempoolPriceBump,
+		ttl:           DefaultMempoolTTL,
+	}
+	if cfg.MaxSize > 0 {
+		m.maxSize = cfg.MaxSize
+	}
+	if cfg.MaxPerAccount > 0 {
+		m.maxPerAccount = cfg.MaxPerAccount
+	}
+	if cfg.PriceBumpPercent > 0 {
+		m.priceBump = cfg.PriceBumpPercent
 	}
+	if cfg.TTL != "" {
+		if ttl, err := time.ParseDuration(cfg.TTL); err == nil && ttl > 0 {
+			m.ttl = ttl
+		} else {
+			m.logger.Printf("Неверный mempool.ttl %q, используется %v", cfg.TTL, m.ttl)
+		}
+	}
+	return m
+}
+
+// SetNonceSource задаёт источник nonce аккаунтов и перераспределяет транзакции между pending и queued.
+func (m *Mempool) SetNonceSource(src NonceSource) {
+	m.mu.Lock()
+	m.nonces = src
+	m.mu.Unlock()
+	m.Reset()
 }
 
+// accountNonce возвращает текущий nonce аккаунта (вызывается под m.mu).
+func (m *Mempool) accountNonce(addr types.Address) int64 {
+	if m.nonces == nil {
+		return 0
+	}
+	return m.nonces.GetNonce(addr)
+}
+
+// Add добавляет транзакцию: в pending, если её nonce продолжает цепочку отправителя, иначе в queued.
+// Транзакция с уже занятым nonce заменяет прежнюю, если цена газа выше минимум на PriceBumpPercent.
+// При переполнении мемпула вытесняется самая дешёвая транзакция, если новая дороже её.
 func (m *Mempool) Add(tx *Transaction) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 
 	// Проверяем, не существует ли уже такая транзакция
 	if _, exists := m.txMap[tx.Hash]; exists {
+		m.mu.Unlock()
 		return errors.New("transaction already exists")
 	}
+	base := m.accountNonce(tx.Sender)
+	if tx.Nonce < base {
+		m.mu.Unlock()
+		return fmt.Errorf("nonce too low: account nonce %d, tx nonce %d", base, tx.Nonce)
+	}
 
-	select {
-	case m.txChan <- tx:
-		m.txMap[tx.Hash] = tx
-		m.logger.Printf("Transaction %s added to mempool", tx.Hash)
-		return nil
-	default:
-		// Канал полон — всё равно кладём в txMap, чтобы TakePending (блок-продюсер) мог забрать транзакцию
+	var dropped []droppedTx
+	s := m.senders[tx.Sender]
+	if old := s.getOrNil(tx.Nonce); old != nil {
+		if !m.priceBumped(old, tx) {
+			m.mu.Unlock()
+			return fmt.Errorf("replacement transaction underpriced: need gas price >= %s (+%d%%), got %s",
+				m.minReplacementPrice(old), m.priceBump, tx.EffectiveGasPrice())
+		}
+		s.replace(old, tx)
+		delete(m.txMap, old.Hash)
+		delete(m.addedAt, old.Hash)
 		m.txMap[tx.Hash] = tx
-		m.logger.Printf("Transaction %s added to mempool (map only, channel full)", tx.Hash)
+		m.addedAt[tx.Hash] = time.Now()
+		dropped = append(dropped, droppedTx{old, TxStatusReplaced})
+		m.logger.Printf("Transaction %s replaced %s (nonce %d, gas price %s)", tx.Hash, old.Hash, tx.Nonce, tx.EffectiveGasPrice())
+		m.mu.Unlock()
+
+		m.notifyDrop(dropped)
+		m.notifyTx(tx)
 		return nil
 	}
+	if s != nil && s.len() >= m.maxPerAccount {
+		m.mu.Unlock()
+		return fmt.Errorf("account %s has %d transactions in mempool (limit %d)", tx.Sender, s.len(), m.maxPerAccount)
+	}
+	if len(m.txMap) >= m.maxSize {
+		victim := m.cheapestLocked()
+		if victim == nil || victim.EffectiveGasPrice().Cmp(tx.EffectiveGasPrice()) >= 0 {
+			m.mu.Unlock()
+			return fmt.Errorf("mempool is full (%d transactions), gas price %s too low", len(m.txMap), tx.EffectiveGasPrice())
+		}
+		m.removeLocked(victim)
+		dropped = append(dropped, droppedTx{victim, TxStatusEvicted})
+		m.logger.Printf("Transaction %s evicted: mempool full", victim.Hash)
+	}
+
+	s = m.senders[tx.Sender]
+	if s == nil {
+		s = &senderTxs{queued: make(map[int64]*Transaction)}
+		m.senders[tx.Sender] = s
+	}
+	m.txMap[tx.Hash] = tx
+	m.addedAt[tx.Hash] = time.Now()
+	if tx.Nonce == s.nextNonce(base) {
+		s.pending = append(s.pending, tx)
+		promoted := s.promote(base)
+		m.logger.Printf("Transaction %s added to mempool (pending, nonce %d, promoted %d)", tx.Hash, tx.Nonce, promoted)
+	} else {
+		s.queued[tx.Nonce] = tx
+		m.logger.Printf("Transaction %s added to mempool (queued, nonce %d, expected %d)", tx.Hash, tx.Nonce, s.nextNonce(base))
+	}
+	m.mu.Unlock()
+
+	m.notifyDrop(dropped)
+	m.notifyTx(tx)
+	return nil
 }
 
-// ErrSkip возвращается из Pop(), когда транзакция оставлена в мемпуле (contract_call забирает блок-продюсер).
-var ErrSkip = errors.New("skip")
+// minReplacementPrice возвращает минимальную цену газа для замены old.
+func (m *Mempool) minReplacementPrice(old *Transaction) *big.Int {
+	p := new(big.Int).Mul(old.EffectiveGasPrice(), big.NewInt(int64(100+m.priceBump)))
+	return p.Div(p.Add(p, big.NewInt(99)), big.NewInt(100))
+}
 
-// PutBack возвращает транзакцию в мемпул после Pop() (например, для повторной постановки).
-func (m *Mempool) PutBack(tx *Transaction) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.txMap[tx.Hash] = tx
-	select {
-	case m.txChan <- tx:
-	default:
-		// канал полон — транзакция только в txMap
-	}
-}
-
-func (m *Mempool) Pop() (*Transaction, error) {
-	select {
-	case tx := <-m.txChan:
-		// contract_call не забираем — оставляем в мемпуле для блок-продюсера
-		if tx.IsContractCall() {
-			m.mu.Lock()
-			_, stillInMap := m.txMap[tx.Hash]
-			if stillInMap {
-				select {
-				case m.txChan <- tx:
-				default:
+// priceBumped сообщает, достаточно ли цена газа tx превышает цену old для замены.
+func (m *Mempool) priceBumped(old, tx *Transaction) bool {
+	price := tx.EffectiveGasPrice()
+	return price.Cmp(old.EffectiveGasPrice()) > 0 && price.Cmp(m.minReplacementPrice(old)) >= 0
+}
+
+// cheapestLocked выбирает кандидата на вытеснение: среди последних (по nonce) транзакций отправителей — с наименьшей ценой газа.
+func (m *Mempool) cheapestLocked() *Transaction {
+	var victim *Transaction
+	for _, s := range m.senders {
+		tx := s.last()
+		if tx == nil {
+			continue
+		}
+		if victim == nil || tx.EffectiveGasPrice().Cmp(victim.EffectiveGasPrice()) < 0 {
+			victim = tx
+		}
+	}
+	return victim
+}
+
+// removeLocked удаляет транзакцию из мемпула; следующие за ней pending-транзакции отправителя переходят в queued.
+func (m *Mempool) removeLocked(tx *Transaction) {
+	delete(m.txMap, tx.Hash)
+	delete(m.addedAt, tx.Hash)
+	s := m.senders[tx.Sender]
+	if s == nil {
+		return
+	}
+	if q, ok := s.queued[tx.Nonce]; ok && q == tx {
+		delete(s.queued, tx.Nonce)
+	} else {
+		for i, p := range s.pending {
+			if p == tx {
+				for _, rest := range s.pending[i+1:] {
+					s.queued[rest.Nonce] = rest
 				}
+				s.pending = s.pending[:i]
+				break
 			}
-			m.mu.Unlock()
-			return nil, ErrSkip
 		}
-		m.mu.Lock()
-		delete(m.txMap, tx.Hash)
-		m.mu.Unlock()
-		m.logger.Printf("Transaction %s popped from mempool", tx.Hash)
-		return tx, nil
-	case <-time.After(100 * time.Millisecond):
-		return nil, errors.New("timeout")
 	}
+	if s.len() == 0 {
+		delete(m.senders, tx.Sender)
+	}
+}
+
+// Remove удаляет транзакцию из мемпула по хешу (например, по запросу администратора); reason записывается в статус.
+func (m *Mempool) Remove(txHash, reason string) (*Transaction, bool) {
+	m.mu.Lock()
+	tx, ok := m.txMap[txHash]
+	if ok {
+		m.removeLocked(tx)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+	m.logger.Printf("Transaction %s removed from mempool: %s", txHash, reason)
+	m.notifyDrop([]droppedTx{{tx, reason}})
+	return tx, true
+}
+
+// dropTaken сообщает подписчикам об удалении транзакции, взятой из мемпула (TakePending), но не включённой в блок.
+func (m *Mempool) dropTaken(tx *Transaction, reason string) {
+	m.logger.Printf("Transaction %s removed from mempool: %s", tx.Hash, reason)
+	m.notifyDrop([]droppedTx{{tx, reason}})
+}
+
+// RemoveExpired удаляет транзакции, пролежавшие в мемпуле дольше TTL на момент now. Возвращает число удалённых.
+func (m *Mempool) RemoveExpired(now time.Time) int {
+	m.mu.Lock()
+	dropped := m.removeExpiredLocked(now)
+	m.mu.Unlock()
+
+	m.notifyDrop(dropped)
+	return len(dropped)
+}
+
+func (m *Mempool) removeExpiredLocked(now time.Time) []droppedTx {
+	var dropped []droppedTx
+	for hash, at := range m.addedAt {
+		if now.Sub(at) > m.ttl {
+			tx := m.txMap[hash]
+			m.removeLocked(tx)
+			dropped = append(dropped, droppedTx{tx, TxStatusExpired})
+		}
+	}
+	if len(dropped) > 0 {
+		m.logger.Printf("Удалено %d транзакций по TTL (%v)", len(dropped), m.ttl)
+	}
+	return dropped
+}
+
+// Reset сверяет мемпул с состоянием после нового блока: удаляет транзакции с истёкшим TTL (expired) и уже
+// использованным nonce (replaced), заново делит оставшиеся на pending (непрерывно от nonce аккаунта) и queued.
+func (m *Mempool) Reset() {
+	m.mu.Lock()
+	expired := m.removeExpiredLocked(time.Now())
+
+	var stale []droppedTx
+	for addr, s := range m.senders {
+		base := m.accountNonce(addr)
+		all := make([]*Transaction, 0, s.len())
+		all = append(all, s.pending...)
+		for _, tx := range s.queued {
+			all = append(all, tx)
+		}
+		s.pending = nil
+		s.queued = make(map[int64]*Transaction)
+		for _, tx := range all {
+			// nonce уже использован — транзакция или другая с тем же nonce включена в блок (здесь или у другого валидатора);
+			// статус в БД меняется только у не включённой (markDroppedTx обновляет лишь pending)
+			if tx.Nonce < base {
+				delete(m.txMap, tx.Hash)
+				delete(m.addedAt, tx.Hash)
+				stale = append(stale, droppedTx{tx, TxStatusReplaced})
+				continue
+			}
+			s.queued[tx.Nonce] = tx
+		}
+		s.promote(base)
+		if s.len() == 0 {
+			delete(m.senders, addr)
+		}
+	}
+	if len(stale) > 0 {
+		m.logger.Printf("Reset: удалено %d транзакций с использованным nonce", len(stale))
+	}
+	m.mu.Unlock()
+
+	m.notifyDrop(expired)
+	m.notifyDrop(stale)
 }
 
 func (m *Mempool) Size() int {
@@ -92,6 +425,28 @@ func (m *Mempool) Size() int {
 	return len(m.txMap)
 }
 
+// Stats возвращает число транзакций, готовых к включению в блок (pending), и ожидающих пропущенный nonce (queued).
+func (m *Mempool) Stats() (pending, queued int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, s := range m.senders {
+		pending += len(s.pending)
+		queued += len(s.queued)
+	}
+	return pending, queued
+}
+
+// NextNonce возвращает следующий nonce отправителя с учётом его pending-транзакций в мемпуле.
+func (m *Mempool) NextNonce(addr types.Address) int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	base := m.accountNonce(addr)
+	if s := m.senders[addr]; s != nil {
+		return s.nextNonce(base)
+	}
+	return base
+}
+
 func (m *Mempool) Exists(txHash string) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -115,17 +470,13 @@ func (m *Mempool) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Очищаем канал
-	for len(m.txChan) > 0 {
-		<-m.txChan
-	}
-
-	// Очищаем карту
 	m.txMap = make(map[string]*Transaction)
+	m.addedAt = make(map[string]time.Time)
+	m.senders = make(map[types.Address]*senderTxs)
 	m.logger.Println("Mempool cleared")
 }
 
-// GetPendingTransactions возвращает список всех ожидающих транзакций
+// GetPendingTransactions возвращает список всех ожидающих транзакций (pending и queued)
 func (m *Mempool) GetPendingTransactions() []*Transaction {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -137,45 +488,99 @@ func (m *Mempool) GetPendingTransactions() []*Transaction {
 	return txs
 }
 
-// TakePending забирает до max транзакций из мемпула и удаляет их из очереди (для включения в блок).
-// Возвращённые транзакции больше не будут в мемпуле и не попадут в Pop().
-func (m *Mempool) TakePending(max int) []*Transaction {
+// GetQueuedTransactions возвращает транзакции с будущим nonce, отсортированные по отправителю и nonce.
+func (m *Mempool) GetQueuedTransactions() []*Transaction {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var txs []*Transaction
+	for _, s := range m.senders {
+		for _, tx := range s.queued {
+			txs = append(txs, tx)
+		}
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].Sender != txs[j].Sender {
+			return txs[i].Sender < txs[j].Sender
+		}
+		return txs[i].Nonce < txs[j].Nonce
+	})
+	return txs
+}
+
+// Entries возвращает все транзакции мемпула с признаком queued и сроком жизни, по отправителю и nonce.
+func (m *Mempool) Entries() []MempoolEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := make([]MempoolEntry, 0, len(m.txMap))
+	for _, s := range m.senders {
+		for _, tx := range s.pending {
+			entries = append(entries, MempoolEntry{Tx: tx, AddedAt: m.addedAt[tx.Hash], ExpiresAt: m.addedAt[tx.Hash].Add(m.ttl)})
+		}
+		for _, tx := range s.queued {
+			entries = append(entries, MempoolEntry{Tx: tx, Queued: true, AddedAt: m.addedAt[tx.Hash], ExpiresAt: m.addedAt[tx.Hash].Add(m.ttl)})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].Tx, entries[j].Tx
+		if a.Sender != b.Sender {
+			return a.Sender < b.Sender
+		}
+		return a.Nonce < b.Nonce
+	})
+	return entries
+}
+
+// TakePending забирает до max транзакций из pending для включения в блок и удаляет их из мемпула.
+// Между отправителями приоритет у большей цены газа при base fee блока baseFee (при равной — у более ранней),
+// внутри отправителя — строго по nonce. Транзакция, не помещающаяся в gasLimit блока или с наибольшей ценой газа
+// ниже baseFee, и все следующие за ней от того же отправителя остаются в мемпуле до следующего блока.
+func (m *Mempool) TakePending(max int, gasLimit uint64, baseFee *big.Int) []*Transaction {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if max <= 0 || len(m.txMap) == 0 {
+	if max <= 0 || len(m.senders) == 0 {
 		return nil
 	}
+	h := &priceHeap{cursors: make([]*senderCursor, 0, len(m.senders)), baseFee: baseFee, addedAt: m.addedAt}
+	for addr, s := range m.senders {
+		if len(s.pending) > 0 {
+			h.cursors = append(h.cursors, &senderCursor{addr: addr, txs: s.pending})
+		}
+	}
+	heap.Init(h)
+
 	taken := make([]*Transaction, 0, max)
-	takenSet := make(map[string]bool)
-	for hash, tx := range m.txMap {
-		if len(taken) >= max {
-			break
+	takenBy := make(map[types.Address]int)
+	var blockGas uint64
+	for h.Len() > 0 && len(taken) < max {
+		c := h.cursors[0]
+		tx := c.txs[c.next]
+		gas := tx.EffectiveGasLimit()
+		if blockGas+gas > gasLimit || (baseFee != nil && tx.EffectiveGasPrice().Cmp(baseFee) < 0) {
+			heap.Pop(h)
+			continue
 		}
+		blockGas += gas
 		taken = append(taken, tx)
-		takenSet[hash] = true
-	}
-	for h := range takenSet {
-		delete(m.txMap, h)
-	}
-	// Очищаем канал от взятых: вычитываем всё, обратно кладём только те, что не взяты
-	var toKeep []*Transaction
-	for {
-		select {
-		case tx := <-m.txChan:
-			if !takenSet[tx.Hash] {
-				toKeep = append(toKeep, tx)
-			}
-		default:
-			goto done
+		c.next++
+		takenBy[c.addr] = c.next
+		if c.next < len(c.txs) {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
 		}
 	}
-done:
-	for _, tx := range toKeep {
-		select {
-		case m.txChan <- tx:
-		default:
-			m.txMap[tx.Hash] = tx
+	for addr, n := range takenBy {
+		s := m.senders[addr]
+		for _, tx := range s.pending[:n] {
+			delete(m.txMap, tx.Hash)
+			delete(m.addedAt, tx.Hash)
+		}
+		s.pending = s.pending[n:]
+		if len(s.pending) == 0 && len(s.queued) == 0 {
+			delete(m.senders, addr)
 		}
 	}
 	if len(taken) > 0 {
@@ -183,3 +588,42 @@ done:
 	}
 	return taken
 }
+
+// senderCursor — позиция в pending отправителя при сборке блока.
+type senderCursor struct {
+	addr types.Address
+	txs  []*Transaction
+	next int
+}
+
+// priceHeap — очередь отправителей по цене газа их очередной транзакции при base fee блока (max-heap);
+// при равной цене раньше идёт транзакция, раньше поступившая в мемпул этой ноды.
+type priceHeap struct {
+	cursors []*senderCursor
+	baseFee *big.Int
+	addedAt map[string]time.Time // время поступления в мемпул (Mempool.addedAt)
+}
+
+func (h *priceHeap) Len() int { return len(h.cursors) }
+
+func (h *priceHeap) Less(i, j int) bool {
+	a, b := h.cursors[i].txs[h.cursors[i].next], h.cursors[j].txs[h.cursors[j].next]
+	if c := a.GasPriceAt(h.baseFee).Cmp(b.GasPriceAt(h.baseFee)); c != 0 {
+		return c > 0
+	}
+	if at, bt := h.addedAt[a.Hash], h.addedAt[b.Hash]; !at.Equal(bt) {
+		return at.Before(bt)
+	}
+	return a.Hash < b.Hash
+}
+
+func (h *priceHeap) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+
+func (h *priceHeap) Push(x interface{}) { h.cursors = append(h.cursors, x.(*senderCursor)) }
+
+func (h *priceHeap) Pop() interface{} {
+	n := len(h.cursors)
+	c := h.cursors[n-1]
+	h.cursors = h.cursors[:n-1]
+	return c
+}
@@ -0,0 +1,311 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"GND/types"
+)
+
+func TestProduceNextBlock_IncludesOutOfOrderNonces(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+	st := bc.State.(*State)
+	SetState(st)
+	defer SetState(nil)
+
+	wallet := newTestWallet(t)
+	sender := types.Address(wallet.Address)
+	if err := st.AddBalance(sender, GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	newTx := func(nonce, value int64) *Transaction {
+		return signTestTx(t, wallet, &Transaction{Recipient: "GN_recipient", Value: big.NewInt(value), Nonce: nonce, GasLimit: TxGas, Symbol: GasSymbol})
+	}
+
+	mp := bc.Mempool
+	if err := mp.Add(newTx(2, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if pending, queued := mp.Stats(); pending != 0 || queued != 1 {
+		t.Fatalf("nonce 2 при nonce аккаунта 0 должен попасть в queued: pending=%d queued=%d", pending, queued)
+	}
+	if err := mp.Add(newTx(0, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mp.Add(newTx(1, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if pending, queued := mp.Stats(); pending != 3 || queued != 0 {
+		t.Fatalf("после заполнения пропуска все транзакции должны быть в pending: pending=%d queued=%d", pending, queued)
+	}
+	if err := mp.Add(newTx(1, 11)); err == nil {
+		t.Error("повтор nonce должен отклоняться")
+	}
+	if got := mp.NextNonce(sender); got != 3 {
+		t.Errorf("NextNonce: ожидалось 3, получено %d", got)
+	}
+
+	if err := bc.ProduceNextBlock(mp, "miner", 100); err != nil {
+		t.Fatal(err)
+	}
+	block, _ := bc.LatestBlock()
+	if len(block.Transactions) != 3 {
+		t.Fatalf("в блок должны войти все 3 транзакции, получено %d", len(block.Transactions))
+	}
+	for i, tx := range block.Transactions {
+		if tx.Nonce != int64(i) || tx.Status != TxStatusConfirmed {
+			t.Errorf("транзакция %d: nonce %d, статус %s", i, tx.Nonce, tx.Status)
+		}
+	}
+	if st.GetNonce(sender) != 3 || mp.Size() != 0 {
+		t.Errorf("после блока: nonce %d, размер мемпула %d", st.GetNonce(sender), mp.Size())
+	}
+	if err := mp.Add(newTx(2, 12)); err == nil {
+		t.Error("транзакция с использованным nonce должна отклоняться")
+	}
+}
+
+func TestProduceNextBlock_DropsRejectedTxAndKeepsSenderQueue(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+	wallet := newTestWallet(t)
+	if err := bc.State.AddBalance(types.Address(wallet.Address), GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	unsigned := signTestTx(t, wallet, &Transaction{Recipient: "GN_recipient", Value: big.NewInt(1), Nonce: 0, GasLimit: TxGas, Symbol: GasSymbol})
+	unsigned.Signature = nil
+	next := signTestTx(t, wallet, &Transaction{Recipient: "GN_recipient", Value: big.NewInt(1), Nonce: 1, GasLimit: TxGas, Symbol: GasSymbol})
+
+	mp := bc.Mempool
+	dropped := make(map[string]string)
+	mp.OnDrop(func(tx *Transaction, reason string) { dropped[tx.Hash] = reason })
+	for _, tx := range []*Transaction{unsigned, next} {
+		if err := mp.Add(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bc.ProduceNextBlock(mp, "miner", 100); err != nil {
+		t.Fatal(err)
+	}
+	if block, _ := bc.LatestBlock(); len(block.Transactions) != 0 {
+		t.Errorf("после отклонённой транзакции следующие транзакции отправителя не включаются: %d", len(block.Transactions))
+	}
+	if dropped[unsigned.Hash] != TxStatusEvicted || unsigned.Status != TxStatusEvicted {
+		t.Errorf("отклонённая транзакция должна удаляться со статусом evicted: %q", dropped[unsigned.Hash])
+	}
+	if _, ok := dropped[next.Hash]; ok || !mp.Exists(next.Hash) {
+		t.Error("следующая транзакция отправителя должна вернуться в мемпул")
+	}
+	if pending, queued := mp.Stats(); pending != 0 || queued != 1 {
+		t.Errorf("nonce 1 без nonce 0 ожидает в queued: pending=%d queued=%d", pending, queued)
+	}
+}
+
+// sealFailEngine — движок консенсуса, не подписывающий блоки.
+type sealFailEngine struct{ acceptAllEngine }
+
+func (sealFailEngine) Seal(*Block) error { return errors.New("нет ключа подписи") }
+
+func TestProduceNextBlock_ReturnsTakenTxsOnFailure(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+	bc.Engine = sealFailEngine{}
+	wallet := newTestWallet(t)
+	sender := types.Address(wallet.Address)
+	if err := bc.State.AddBalance(sender, GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	mp := bc.Mempool
+	dropped := make(map[string]string)
+	mp.OnDrop(func(tx *Transaction, reason string) { dropped[tx.Hash] = reason })
+	var txs []*Transaction
+	for nonce := int64(0); nonce < 2; nonce++ {
+		tx := signTestTx(t, wallet, &Transaction{Recipient: "GN_recipient", Value: big.NewInt(1), Nonce: nonce, GasLimit: TxGas, Symbol: GasSymbol})
+		if err := mp.Add(tx); err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+
+	if err := bc.ProduceNextBlock(mp, "miner", 100); err == nil {
+		t.Fatal("блок без подписи не должен добавляться")
+	}
+	if pending, queued := mp.Stats(); pending != 2 || queued != 0 || bc.Height() != 0 {
+		t.Fatalf("транзакции неподписанного блока должны вернуться в мемпул: pending=%d queued=%d", pending, queued)
+	}
+	for _, tx := range txs {
+		if tx.Status != "pending" || tx.GasUsed != 0 {
+			t.Errorf("транзакция %s: статус %q, газ %d", tx.Hash, tx.Status, tx.GasUsed)
+		}
+	}
+
+	// nonce 0 использован транзакцией, пришедшей в блоке от другого валидатора: Reset сообщает об удалении
+	bc.State.(*State).SetNonce(sender, 1)
+	mp.Reset()
+	if dropped[txs[0].Hash] != TxStatusReplaced || mp.Exists(txs[0].Hash) {
+		t.Errorf("транзакция с использованным nonce должна удаляться со статусом replaced: %q", dropped[txs[0].Hash])
+	}
+	if _, ok := dropped[txs[1].Hash]; ok {
+		t.Error("транзакция со следующим nonce остаётся в мемпуле")
+	}
+}
+
+func TestMempool_TakePendingByGasPrice(t *testing.T) {
+	mp := NewMempool()
+	base := time.Now()
+	add := func(sender types.Address, nonce int64, price int64, hash string) {
+		t.Helper()
+		tx := &Transaction{Sender: sender, Recipient: "GN_recipient", Value: big.NewInt(0), Nonce: nonce, GasLimit: TxGas,
+			GasPrice: big.NewInt(price), Timestamp: base.Add(time.Duration(len(hash)+int(nonce)) * time.Second), Hash: hash}
+		if err := mp.Add(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("GN_a", 0, 1, "a0")
+	add("GN_a", 1, 9, "a1") // высокая цена не обгоняет a0 того же отправителя
+	add("GN_b", 0, 5, "b0")
+	add("GN_b", 1, 1, "b1")
+	add("GN_c", 1, 100, "c1") // пропущен nonce 0 — остаётся в queued
+
+	got := mp.TakePending(10, DefaultBlockGasLimit, nil)
+	want := []string{"b0", "a0", "a1", "b1"}
+	if len(got) != len(want) {
+		t.Fatalf("ожидалось %d транзакций, получено %d", len(want), len(got))
+	}
+	for i, tx := range got {
+		if tx.Hash != want[i] {
+			t.Errorf("позиция %d: ожидалось %s, получено %s", i, want[i], tx.Hash)
+		}
+	}
+	if pending, queued := mp.Stats(); pending != 0 || queued != 1 || !mp.Exists("c1") {
+		t.Errorf("в мемпуле должна остаться только queued c1: pending=%d queued=%d", pending, queued)
+	}
+
+	add("GN_d", 0, 1, "d0")
+	add("GN_d", 1, 1, "d1")
+	if got := mp.TakePending(10, TxGas, nil); len(got) != 1 || got[0].Hash != "d0" || !mp.Exists("d1") {
+		t.Errorf("лимит газа блока: ожидалась только d0, получено %d", len(got))
+	}
+}
+
+func TestMempool_TakePendingAtBaseFee(t *testing.T) {
+	mp := NewMempool()
+	add := func(tx *Transaction) {
+		t.Helper()
+		tx.Recipient, tx.Value, tx.GasLimit = "GN_recipient", big.NewInt(0), TxGas
+		if err := mp.Add(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(&Transaction{Sender: "GN_legacy", GasPrice: big.NewInt(12), Hash: "legacy"})
+	add(&Transaction{Sender: "GN_cheap", GasPrice: big.NewInt(5), Hash: "cheap"}) // ниже base fee — ждёт следующего блока
+	add(&Transaction{Sender: "GN_tip1", MaxFeePerGas: big.NewInt(100), MaxPriorityFeePerGas: big.NewInt(1), Hash: "tip1"})
+	add(&Transaction{Sender: "GN_tip5", MaxFeePerGas: big.NewInt(30), MaxPriorityFeePerGas: big.NewInt(5), Hash: "tip5"})
+
+	// при base fee 10 цены газа: tip5 — 15, legacy — 12, tip1 — 11 (max_fee_per_gas 100 не важен)
+	got := mp.TakePending(10, DefaultBlockGasLimit, big.NewInt(10))
+	want := []string{"tip5", "legacy", "tip1"}
+	if len(got) != len(want) {
+		t.Fatalf("ожидалось %d транзакций, получено %d", len(want), len(got))
+	}
+	for i, tx := range got {
+		if tx.Hash != want[i] {
+			t.Errorf("позиция %d: ожидалось %s, получено %s", i, want[i], tx.Hash)
+		}
+	}
+	if !mp.Exists("cheap") {
+		t.Error("транзакция с ценой газа ниже base fee должна остаться в мемпуле")
+	}
+}
+
+func TestMempool_ReplaceByFeeAndLimits(t *testing.T) {
+	mp := NewMempoolWithConfig(MempoolConfig{MaxSize: 3, MaxPerAccount: 2, PriceBumpPercent: 10, TTL: "1h"})
+	dropped := map[string]string{}
+	mp.OnDrop(func(tx *Transaction, reason string) { dropped[tx.Hash] = reason })
+	newTx := func(sender types.Address, nonce, price int64, hash string) *Transaction {
+		return &Transaction{Sender: sender, Recipient: "GN_recipient", Value: big.NewInt(0), Nonce: nonce, GasLimit: TxGas, GasPrice: big.NewInt(price), Hash: hash}
+	}
+
+	if err := mp.Add(newTx("GN_a", 0, 100, "a0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := mp.Add(newTx("GN_a", 0, 105, "a0_cheap")); err == nil {
+		t.Error("замена с повышением цены меньше 10% должна отклоняться")
+	}
+	if err := mp.Add(newTx("GN_a", 0, 110, "a0_bump")); err != nil {
+		t.Fatalf("замена с повышением на 10%% должна проходить: %v", err)
+	}
+	if mp.Exists("a0") || !mp.Exists("a0_bump") || dropped["a0"] != TxStatusReplaced {
+		t.Errorf("a0 должна быть заменена: dropped=%v", dropped)
+	}
+	if err := mp.Add(newTx("GN_a", 1, 100, "a1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := mp.Add(newTx("GN_a", 2, 100, "a2")); err == nil {
+		t.Error("превышение лимита транзакций отправителя должно отклоняться")
+	}
+
+	// мемпул заполнен (3): дешёвая транзакция отклоняется, дорогая вытесняет самую дешёвую из хвостов очередей
+	if err := mp.Add(newTx("GN_b", 0, 50, "b0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := mp.Add(newTx("GN_c", 0, 50, "c0")); err == nil {
+		t.Error("при переполнении транзакция не дороже самой дешёвой должна отклоняться")
+	}
+	if err := mp.Add(newTx("GN_c", 0, 200, "c0_rich")); err != nil {
+		t.Fatal(err)
+	}
+	if mp.Exists("b0") || dropped["b0"] != TxStatusEvicted || mp.Size() != 3 {
+		t.Errorf("b0 должна быть вытеснена: size=%d dropped=%v", mp.Size(), dropped)
+	}
+
+	// TTL: удаляются все транзакции старше часа, статус — expired
+	if n := mp.RemoveExpired(time.Now().Add(2 * time.Hour)); n != 3 || mp.Size() != 0 {
+		t.Errorf("по TTL должны удалиться все 3 транзакции, удалено %d, осталось %d", n, mp.Size())
+	}
+	if dropped["a1"] != TxStatusExpired {
+		t.Errorf("a1: ожидался статус %s, получено %q", TxStatusExpired, dropped["a1"])
+	}
+
+	if err := mp.Add(newTx("GN_d", 0, 1, "d0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := mp.Add(newTx("GN_d", 1, 1, "d1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := mp.Remove("d0", TxStatusEvicted); !ok {
+		t.Fatal("Remove: d0 не найдена")
+	}
+	if pending, queued := mp.Stats(); pending != 0 || queued != 1 {
+		t.Errorf("после удаления d0 транзакция d1 должна перейти в queued: pending=%d queued=%d", pending, queued)
+	}
+}
+
+func TestMempool_UsesLocalArrivalTime(t *testing.T) {
+	mp := NewMempoolWithConfig(MempoolConfig{TTL: "1h"})
+	now := time.Now()
+	first := &Transaction{Sender: "GN_a", Recipient: "GN_recipient", Value: big.NewInt(0), GasLimit: TxGas,
+		GasPrice: big.NewInt(1), Timestamp: now, Hash: "a0"}
+	// Транзакция от пира с отметкой времени в прошлом: не истекает сразу и не обгоняет поступившую раньше
+	backdated := &Transaction{Sender: "GN_b", Recipient: "GN_recipient", Value: big.NewInt(0), GasLimit: TxGas,
+		GasPrice: big.NewInt(1), Timestamp: now.Add(-10 * time.Hour), Hash: "00"}
+	for _, tx := range []*Transaction{first, backdated} {
+		if err := mp.Add(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := mp.RemoveExpired(time.Now()); n != 0 {
+		t.Fatalf("TTL отсчитывается от поступления в мемпул, удалено %d", n)
+	}
+	got := mp.TakePending(10, DefaultBlockGasLimit, nil)
+	if len(got) != 2 || got[0].Hash != "a0" || got[1].Hash != "00" {
+		t.Errorf("при равной цене первой должна идти a0, поступившая раньше")
+	}
+}
@@ -49,6 +49,7 @@ type Metrics struct {
 		MinFee                *big.Int
 		MaxFee                *big.Int
 		TotalFee              *big.Int
+		TotalGasUsed          uint64 // суммарный газ транзакций, включённых в блоки
 		LastMinuteCount       uint64
 		LastHourCount         uint64
 		TypeMetrics           map[string]*TransactionTypeMetrics // Метрики по типам транзакций
@@ -80,7 +81,9 @@ type Metrics struct {
 		ActiveValidators uint64
 		ConsensusLatency time.Duration
 		MissedBlocks     uint64
-		ForkCount        uint64
+		ForkCount        uint64 // число реорганизаций цепи (переключений на более тяжёлую ветку)
+		FinalizedHeight  uint64 // высота последнего финализированного блока
+		FinalityLag      uint64 // на сколько блоков вершина цепи опережает финализированный блок
 	}
 
 	// Алерты
@@ -141,6 +144,7 @@ func ResetMetrics() {
 			MinFee                *big.Int
 			MaxFee                *big.Int
 			TotalFee              *big.Int
+			TotalGasUsed          uint64
 			LastMinuteCount       uint64
 			LastHourCount         uint64
 			TypeMetrics           map[string]*TransactionTypeMetrics
@@ -173,6 +177,8 @@ func (m *Metrics) UpdateBlockMetrics(block *Block) {
 	}
 
 	m.BlockMetrics.LastBlockTime = now
+	m.BlockMetrics.GasUsed = block.GasUsed
+	m.BlockMetrics.GasLimit = block.GasLimit
 	elapsed := time.Since(startTime).Minutes()
 	if elapsed < 1.0 {
 		elapsed = 1.0
@@ -180,6 +186,50 @@ func (m *Metrics) UpdateBlockMetrics(block *Block) {
 	m.BlockMetrics.BlocksPerMinute = float64(m.BlockMetrics.TotalBlocks) / elapsed
 }
 
+// UpdateConsensusMetrics обновляет метрики консенсуса: размер набора валидаторов, число не забаненных и пропущенные блоки (прибавляется missed).
+func (m *Metrics) UpdateConsensusMetrics(validators, active, missed uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ConsensusMetrics.ValidatorsCount = validators
+	m.ConsensusMetrics.ActiveValidators = active
+	m.ConsensusMetrics.MissedBlocks += missed
+}
+
+// UpdateFinalityMetrics обновляет высоту финализированного блока и отставание финальности
+func (m *Metrics) UpdateFinalityMetrics(finalized, lag uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ConsensusMetrics.FinalizedHeight = finalized
+	m.ConsensusMetrics.FinalityLag = lag
+}
+
+// RecordReorg увеличивает счётчик реорганизаций цепи (переключений на другую ветку)
+func (m *Metrics) RecordReorg() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ConsensusMetrics.ForkCount++
+}
+
+// UpdatePeerMetrics обновляет число подключённых пиров P2P
+func (m *Metrics) UpdatePeerMetrics(active uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.NetworkMetrics.ActivePeers = active
+}
+
+// AddNetworkTraffic прибавляет отправленные и полученные по P2P байты
+func (m *Metrics) AddNetworkTraffic(sent, received uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.NetworkMetrics.BytesSent += sent
+	m.NetworkMetrics.BytesReceived += received
+}
+
 // UpdateTransactionMetrics обновляет метрики транзакций
 func (m *Metrics) UpdateTransactionMetrics(tx *Transaction, status string) {
 	m.mu.Lock()
@@ -201,20 +251,23 @@ func (m *Metrics) UpdateTransactionMetrics(tx *Transaction, status string) {
 
 	// Обновляем метрики по типу транзакции
 	if tx != nil {
-		if tx.Type == "" {
-			tx.Type = "unknown"
+		m.TransactionMetrics.TotalGasUsed += tx.GasUsed
+		// Тип входит в хеш подписи транзакции, поэтому саму транзакцию не меняем
+		txType := tx.Type
+		if txType == "" {
+			txType = "unknown"
 		}
 		// Инициализируем метрики для типа транзакции, если их еще нет
-		if _, exists := m.TransactionMetrics.TypeMetrics[tx.Type]; !exists {
+		if _, exists := m.TransactionMetrics.TypeMetrics[txType]; !exists {
 			typeMetrics := &TransactionTypeMetrics{}
 			if tx.Fee != nil {
 				typeMetrics.MinFee = new(big.Int).Set(tx.Fee)
 				typeMetrics.MaxFee = new(big.Int).Set(tx.Fee)
 			}
-			m.TransactionMetrics.TypeMetrics[tx.Type] = typeMetrics
+			m.TransactionMetrics.TypeMetrics[txType] = typeMetrics
 		}
 
-		typeMetrics := m.TransactionMetrics.TypeMetrics[tx.Type]
+		typeMetrics := m.TransactionMetrics.TypeMetrics[txType]
 		typeMetrics.Count++
 		if status == "success" || status == "confirmed" {
 			typeMetrics.SuccessCount++
@@ -374,7 +427,8 @@ func InitTransactionMetricsFromDB(ctx context.Context, pool *pgxpool.Pool, pendi
 	}
 
 	var totalFeeStr, minFeeStr, maxFeeStr string
-	if err := pool.QueryRow(ctx, `SELECT COALESCE(SUM(fee)::text, '0'), COALESCE(MIN(fee)::text, '0'), COALESCE(MAX(fee)::text, '0') FROM transactions`).Scan(&totalFeeStr, &minFeeStr, &maxFeeStr); err == nil {
+	// Комиссии — фактические (fee записывается при включении транзакции в блок), ожидающие транзакции не учитываются
+	if err := pool.QueryRow(ctx, `SELECT COALESCE(SUM(fee)::text, '0'), COALESCE(MIN(fee)::text, '0'), COALESCE(MAX(fee)::text, '0'), COALESCE(SUM(gas_used), 0)::bigint FROM transactions WHERE block_id IS NOT NULL`).Scan(&totalFeeStr, &minFeeStr, &maxFeeStr, &metrics.TransactionMetrics.TotalGasUsed); err == nil {
 		if totalFeeStr != "" {
 			metrics.TransactionMetrics.TotalFee = new(big.Int)
 			metrics.TransactionMetrics.TotalFee.SetString(totalFeeStr, 10)
@@ -393,7 +447,7 @@ func InitTransactionMetricsFromDB(ctx context.Context, pool *pgxpool.Pool, pendi
 	}
 
 	// FeeDistribution по диапазонам (low/medium/high/very_high)
-	rows3, err := pool.Query(ctx, `SELECT fee::text FROM transactions WHERE fee IS NOT NULL`)
+	rows3, err := pool.Query(ctx, `SELECT fee::text FROM transactions WHERE fee IS NOT NULL AND block_id IS NOT NULL`)
 	if err == nil {
 		defer rows3.Close()
 		for rows3.Next() {
@@ -0,0 +1,339 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/proof.go — доказательства Меркла для проверки без доверия к ноде: аккаунт (балансы и nonce), слот storage,
+// включение транзакции в блок. Проверка — пакет core/proof.
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"GND/core/trie"
+	"GND/types"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrStateUnavailable — состояния после блока нет: копии в памяти хранятся для последних MaxReorgDepth блоков,
+// доказательства для более ранних строятся по истории в БД, если она полная (account_states с балансами, миграция 030).
+var ErrStateUnavailable = errors.New("состояние после блока недоступно")
+
+// ProofHeader — заголовок блока в доказательстве: поля, из которых вычисляется хеш блока (Block.CalculateHash).
+type ProofHeader struct {
+	Hash       string    `json:"hash"`
+	Index      uint64    `json:"index"`
+	PrevHash   string    `json:"prev_hash"`
+	MerkleRoot string    `json:"merkle_root"`
+	StateRoot  string    `json:"state_root"`
+	Timestamp  time.Time `json:"timestamp"`
+	Height     uint64    `json:"height"`
+	Version    uint32    `json:"version"`
+	TxCount    uint32    `json:"tx_count"`
+	GasUsed    uint64    `json:"gas_used"`
+	GasLimit   uint64    `json:"gas_limit"`
+	Difficulty uint64    `json:"difficulty"`
+	Nonce      uint64    `json:"nonce"`
+	Miner      string    `json:"miner"`
+	Reward     *big.Int  `json:"reward"`
+	ExtraData  []byte    `json:"extra_data,omitempty"`
+}
+
+// NewProofHeader возвращает заголовок блока для доказательства.
+func NewProofHeader(b *Block) ProofHeader {
+	return ProofHeader{Hash: b.Hash, Index: b.Index, PrevHash: b.PrevHash, MerkleRoot: b.MerkleRoot, StateRoot: b.StateRoot,
+		Timestamp: b.Timestamp, Height: b.Height, Version: b.Version, TxCount: b.TxCount, GasUsed: b.GasUsed, GasLimit: b.GasLimit,
+		Difficulty: b.Difficulty, Nonce: b.Nonce, Miner: b.Miner, Reward: b.Reward, ExtraData: b.ExtraData}
+}
+
+// CalculateHash вычисляет хеш блока по полям заголовка.
+func (h *ProofHeader) CalculateHash() string {
+	b := Block{PrevHash: h.PrevHash, MerkleRoot: h.MerkleRoot, StateRoot: h.StateRoot, Timestamp: h.Timestamp, Height: h.Height,
+		Version: h.Version, TxCount: h.TxCount, GasUsed: h.GasUsed, GasLimit: h.GasLimit, Difficulty: h.Difficulty,
+		Nonce: h.Nonce, Miner: h.Miner, Reward: h.Reward, ExtraData: h.ExtraData}
+	return b.CalculateHash()
+}
+
+// AccountProof — доказательство состояния аккаунта после блока: лист дерева состояния (nonce, ненулевые балансы,
+// хеш кода, корень storage) и путь к state_root заголовка. Для аккаунта без состояния Proof доказывает отсутствие листа.
+type AccountProof struct {
+	Header      ProofHeader         `json:"header"`
+	Address     string              `json:"address"`
+	Nonce       uint64              `json:"nonce"`
+	Balances    map[string]*big.Int `json:"balances"`
+	CodeHash    trie.Hash           `json:"code_hash"`
+	StorageRoot trie.Hash           `json:"storage_root"`
+	Proof       *trie.Proof         `json:"proof"`
+}
+
+// Leaf возвращает лист аккаунта из полей доказательства.
+func (p *AccountProof) Leaf() *AccountLeaf {
+	return &AccountLeaf{Nonce: p.Nonce, Balances: p.Balances, CodeHash: p.CodeHash, StorageRoot: p.StorageRoot}
+}
+
+// StorageProof — доказательство значения слота storage контракта: доказательство аккаунта (корень storage)
+// и путь слота в дереве storage. Value — hex значения, пустой — слот не записан или нулевой.
+type StorageProof struct {
+	Account *AccountProof `json:"account"`
+	Slot    string        `json:"slot"`
+	Value   string        `json:"value"`
+	Proof   *trie.Proof   `json:"proof"`
+}
+
+// TransactionProof — доказательство включения транзакции: хеши транзакций блока по порядку, из которых
+// ComputeMerkleRoot даёт merkle_root заголовка, и позиция транзакции.
+type TransactionProof struct {
+	Header   ProofHeader `json:"header"`
+	Hash     string      `json:"hash"`
+	Index    int         `json:"index"`
+	TxHashes []string    `json:"tx_hashes"`
+}
+
+// AccountProof строит доказательство состояния аккаунта после блока number цепи.
+func (bc *Blockchain) AccountProof(address string, number uint64) (*AccountProof, error) {
+	ps, err := bc.proofState(number)
+	if err != nil {
+		return nil, err
+	}
+	return ps.accountProof(address), nil
+}
+
+// StorageProof строит доказательство значения слота storage контракта после блока number цепи (slot — 32 байта).
+func (bc *Blockchain) StorageProof(address string, slot []byte, number uint64) (*StorageProof, error) {
+	if len(slot) != 32 {
+		return nil, fmt.Errorf("ключ слота должен быть 32 байта, получено %d", len(slot))
+	}
+	ps, err := bc.proofState(number)
+	if err != nil {
+		return nil, err
+	}
+	p := &StorageProof{Account: ps.accountProof(address), Slot: fmt.Sprintf("%x", slot)}
+	storage := ps.trie.storage[address]
+	if storage == nil {
+		storage = trie.New()
+	}
+	p.Proof = storage.Prove(StorageKey(slot))
+	if v := ps.storage[address][string(slot)]; !isZeroSlot(v) {
+		p.Value = fmt.Sprintf("%x", v)
+	}
+	return p, nil
+}
+
+// TransactionProof строит доказательство включения транзакции в блок: из блоков цепи в памяти,
+// иначе по квитанциям в БД (порядок транзакций — receipts.tx_index).
+func (bc *Blockchain) TransactionProof(ctx context.Context, hash string) (*TransactionProof, error) {
+	var block *Block
+	var hashes []string
+	bc.mutex.Lock()
+	for i := len(bc.Blocks) - 1; i >= 0 && block == nil; i-- {
+		for _, tx := range bc.Blocks[i].Transactions {
+			if tx != nil && tx.Hash == hash {
+				block = bc.Blocks[i]
+				break
+			}
+		}
+	}
+	if block != nil {
+		for _, tx := range block.Transactions {
+			if tx != nil {
+				hashes = append(hashes, tx.Hash)
+			}
+		}
+	}
+	bc.mutex.Unlock()
+
+	if block == nil {
+		if bc.Pool == nil {
+			return nil, errors.New("транзакция не найдена в блоках")
+		}
+		r, err := LoadReceipt(ctx, bc.Pool, hash)
+		if err != nil || r == nil {
+			return nil, errors.New("транзакция не найдена в блоках")
+		}
+		if block, err = GetBlockByHash(bc.Pool, r.BlockHash); err != nil {
+			return nil, fmt.Errorf("блок транзакции: %w", err)
+		}
+		rows, err := bc.Pool.Query(ctx, `SELECT tx_hash FROM receipts WHERE block_hash = $1 ORDER BY tx_index`, r.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var h string
+			if err := rows.Scan(&h); err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, h)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &TransactionProof{Header: NewProofHeader(block), Hash: hash, Index: -1, TxHashes: hashes}
+	for i, h := range hashes {
+		if h == hash {
+			p.Index = i
+		}
+	}
+	if p.Index < 0 || block.MerkleRoot == "" || merkleRootOfHashes(hashes) != block.MerkleRoot {
+		return nil, fmt.Errorf("транзакции блока %d не сходятся с merkle_root", block.Index)
+	}
+	return p, nil
+}
+
+// proofState — состояние после блока для доказательств: дерево состояния и полные слоты storage, по которым оно построено.
+type proofState struct {
+	block   *Block
+	trie    *stateTrie
+	storage map[string]map[string][]byte
+}
+
+// proofState возвращает состояние после блока number цепи, корень которого совпадает со state_root блока:
+// для последних MaxReorgDepth блоков — по контрольной точке в памяти, для более ранних — по истории в БД
+// (account_states и contract_storage блоков цепи до number включительно).
+func (bc *Blockchain) proofState(number uint64) (*proofState, error) {
+	bc.mutex.Lock()
+	var block *Block
+	for i := len(bc.Blocks) - 1; i >= 0; i-- {
+		if bc.Blocks[i].Index == number {
+			block = bc.Blocks[i]
+			break
+		}
+	}
+	var ps *proofState
+	if block != nil && block.StateRoot != "" {
+		if cp := bc.checkpoints[block.Hash]; cp != nil {
+			ps = &proofState{block: block, trie: buildStateTrie(cp.nonces, cp.balances, cp.codes, cp.storage), storage: cp.storage}
+		}
+	}
+	bc.mutex.Unlock()
+
+	if ps == nil {
+		if block == nil && bc.Pool != nil {
+			block, _ = GetBlockByNumber(bc.Pool, number)
+		}
+		switch {
+		case block == nil:
+			return nil, fmt.Errorf("блок %d не найден в цепи", number)
+		case block.StateRoot == "":
+			return nil, fmt.Errorf("у блока %d нет state_root", number)
+		case bc.Pool == nil:
+			return nil, fmt.Errorf("блок %d: %w", number, ErrStateUnavailable)
+		}
+		var err error
+		if ps, err = loadProofState(context.Background(), bc.Pool, block); err != nil {
+			return nil, err
+		}
+	}
+	if root := ps.trie.accounts.Root().String(); root != ps.block.StateRoot {
+		return nil, fmt.Errorf("блок %d: корень состояния ноды %s не совпадает со state_root %s", number, root, ps.block.StateRoot)
+	}
+	return ps, nil
+}
+
+// loadProofState восстанавливает состояние после блока по истории в БД: последние строки account_states и contract_storage
+// каждого аккаунта и слота среди неотменённых блоков не выше block. Строки без балансов (записанные до миграции 030)
+// означают неполную историю — ErrStateUnavailable.
+func loadProofState(ctx context.Context, pool *pgxpool.Pool, block *Block) (*proofState, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT DISTINCT ON (s.address) s.address, s.nonce, s.balances, s.code_hash
+		FROM account_states s JOIN blocks b ON b.id = s.block_id
+		WHERE b.index <= $1 AND NOT b.is_orphaned
+		ORDER BY s.address, b.index DESC`, block.Index)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	nonces := make(map[types.Address]uint64)
+	balances := make(map[types.Address]map[string]*big.Int)
+	codeHashes := make(map[string]trie.Hash)
+	for rows.Next() {
+		var address string
+		var nonce int64
+		var balancesJSON, codeHash []byte
+		if err := rows.Scan(&address, &nonce, &balancesJSON, &codeHash); err != nil {
+			return nil, err
+		}
+		if balancesJSON == nil {
+			return nil, fmt.Errorf("блок %d: история аккаунта %s без балансов: %w", block.Index, address, ErrStateUnavailable)
+		}
+		var amounts map[string]string
+		if err := json.Unmarshal(balancesJSON, &amounts); err != nil {
+			return nil, fmt.Errorf("балансы аккаунта %s: %w", address, err)
+		}
+		addr := types.Address(address)
+		if nonce > 0 {
+			nonces[addr] = uint64(nonce)
+		}
+		for sym, v := range amounts {
+			amount, ok := new(big.Int).SetString(v, 10)
+			if !ok {
+				return nil, fmt.Errorf("баланс %s аккаунта %s: %q", sym, address, v)
+			}
+			if balances[addr] == nil {
+				balances[addr] = make(map[string]*big.Int)
+			}
+			balances[addr][sym] = amount
+		}
+		if len(codeHash) == len(trie.Empty) {
+			codeHashes[address] = trie.Hash(codeHash)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slotRows, err := pool.Query(ctx, `
+		SELECT DISTINCT ON (cs.address, cs.slot_key) cs.address, cs.slot_key, cs.slot_value
+		FROM contract_storage cs JOIN blocks b ON b.id = cs.block_id
+		WHERE b.index <= $1 AND NOT b.is_orphaned
+		ORDER BY cs.address, cs.slot_key, b.index DESC`, block.Index)
+	if err != nil {
+		return nil, err
+	}
+	defer slotRows.Close()
+	storage := make(map[string]map[string][]byte)
+	for slotRows.Next() {
+		var address string
+		var key, value []byte
+		if err := slotRows.Scan(&address, &key, &value); err != nil {
+			return nil, err
+		}
+		if storage[address] == nil {
+			storage[address] = make(map[string][]byte)
+		}
+		storage[address][string(key)] = value
+	}
+	if err := slotRows.Err(); err != nil {
+		return nil, err
+	}
+	return &proofState{block: block, trie: buildStateTrieFromCodeHashes(nonces, balances, codeHashes, storage), storage: storage}, nil
+}
+
+// accountProof строит доказательство аккаунта по листу дерева состояния.
+func (ps *proofState) accountProof(address string) *AccountProof {
+	p := &AccountProof{Header: NewProofHeader(ps.block), Address: address, Balances: make(map[string]*big.Int),
+		Proof: ps.trie.accounts.Prove(AccountKey(address))}
+	if leaf := ps.trie.leaves[address]; leaf != nil {
+		p.Nonce, p.CodeHash, p.StorageRoot = leaf.Nonce, leaf.CodeHash, leaf.StorageRoot
+		for sym, b := range leaf.Balances {
+			if b != nil && b.Sign() != 0 {
+				p.Balances[sym] = new(big.Int).Set(b)
+			}
+		}
+	}
+	return p
+}
+
+// merkleRootOfHashes — ComputeMerkleRoot по хешам транзакций.
+func merkleRootOfHashes(hashes []string) string {
+	txs := make([]*Transaction, len(hashes))
+	for i, h := range hashes {
+		txs[i] = &Transaction{Hash: h}
+	}
+	return ComputeMerkleRoot(txs)
+}
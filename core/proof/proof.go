@@ -0,0 +1,98 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/proof/proof.go — проверка доказательств ноды (core.AccountProof, StorageProof, TransactionProof) относительно
+// заголовка блока с известным хешем: для аудита балансов и мостов без доверия к ноде.
+
+package proof
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"GND/core"
+	"GND/core/trie"
+)
+
+// VerifyHeader проверяет, что поля заголовка дают blockHash — хеш блока, полученный из доверенного источника
+// (собственная нода, финализированный блок от нескольких валидаторов).
+func VerifyHeader(h *core.ProofHeader, blockHash string) error {
+	if blockHash == "" {
+		return errors.New("не задан хеш блока")
+	}
+	if h.Hash != blockHash {
+		return fmt.Errorf("доказательство для блока %s, ожидался %s", h.Hash, blockHash)
+	}
+	if got := h.CalculateHash(); got != blockHash {
+		return fmt.Errorf("поля заголовка дают хеш %s, ожидался %s", got, blockHash)
+	}
+	return nil
+}
+
+// VerifyAccount проверяет nonce, балансы, хеш кода и корень storage аккаунта по state_root блока blockHash.
+func VerifyAccount(p *core.AccountProof, blockHash string) error {
+	if p == nil || p.Proof == nil {
+		return errors.New("пустое доказательство аккаунта")
+	}
+	if err := VerifyHeader(&p.Header, blockHash); err != nil {
+		return err
+	}
+	root, err := parseRoot(p.Header.StateRoot)
+	if err != nil {
+		return fmt.Errorf("state_root: %w", err)
+	}
+	if !p.Proof.Verify(root, core.AccountKey(p.Address), p.Leaf().Encode()) {
+		return fmt.Errorf("состояние аккаунта %s не подтверждается state_root блока %d", p.Address, p.Header.Index)
+	}
+	return nil
+}
+
+// VerifyStorage проверяет значение слота storage контракта: аккаунт по state_root, слот — по корню storage аккаунта.
+func VerifyStorage(p *core.StorageProof, blockHash string) error {
+	if p == nil || p.Proof == nil {
+		return errors.New("пустое доказательство слота")
+	}
+	if err := VerifyAccount(p.Account, blockHash); err != nil {
+		return err
+	}
+	slot, err := hex.DecodeString(p.Slot)
+	if err != nil || len(slot) != 32 {
+		return fmt.Errorf("неверный ключ слота %q", p.Slot)
+	}
+	var value []byte
+	if p.Value != "" {
+		if value, err = hex.DecodeString(p.Value); err != nil || len(value) == 0 {
+			return fmt.Errorf("неверное значение слота %q", p.Value)
+		}
+	}
+	if !p.Proof.Verify(p.Account.StorageRoot, core.StorageKey(slot), value) {
+		return fmt.Errorf("слот %s контракта %s не подтверждается корнем storage", p.Slot, p.Account.Address)
+	}
+	return nil
+}
+
+// VerifyTransaction проверяет, что транзакция стоит на позиции Index среди транзакций блока blockHash.
+func VerifyTransaction(p *core.TransactionProof, blockHash string) error {
+	if p == nil {
+		return errors.New("пустое доказательство транзакции")
+	}
+	if err := VerifyHeader(&p.Header, blockHash); err != nil {
+		return err
+	}
+	if p.Index < 0 || p.Index >= len(p.TxHashes) || p.TxHashes[p.Index] != p.Hash {
+		return fmt.Errorf("транзакции %s нет на позиции %d", p.Hash, p.Index)
+	}
+	txs := make([]*core.Transaction, len(p.TxHashes))
+	for i, h := range p.TxHashes {
+		txs[i] = &core.Transaction{Hash: h}
+	}
+	if p.Header.MerkleRoot == "" || core.ComputeMerkleRoot(txs) != p.Header.MerkleRoot {
+		return fmt.Errorf("транзакции не сходятся с merkle_root блока %d", p.Header.Index)
+	}
+	return nil
+}
+
+func parseRoot(s string) (trie.Hash, error) {
+	var h trie.Hash
+	err := h.UnmarshalText([]byte(s))
+	return h, err
+}
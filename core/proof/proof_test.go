@@ -0,0 +1,145 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package proof
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"GND/core"
+	"GND/types"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// roundTrip кодирует доказательство в JSON и обратно, как его получает клиент API.
+func roundTrip[T any](t *testing.T, v *T) *T {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out T
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return &out
+}
+
+func TestVerify_AccountStorageAndTransactionProofs(t *testing.T) {
+	genesis := &core.Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: core.DefaultBlockGasLimit, Consensus: "poa", Status: core.BlockStatusFinalized}
+	genesis.Hash = genesis.CalculateHash()
+	bc := core.NewBlockchain(genesis, nil)
+	st := bc.State.(*core.State)
+	core.SetState(st)
+	defer core.SetState(nil)
+
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := core.AddressFromPublicKey(key.PubKey().SerializeCompressed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := &core.Wallet{PrivateKey: key, Address: core.Address(address)}
+	holder, contract := types.Address(address), "GN_proof_contract"
+	if err := st.AddBalance(holder, core.GasSymbol, big.NewInt(1_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.AddBalance(holder, "GANI", big.NewInt(7)); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetContractCode(contract, []byte{0x60, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	slot, value := make([]byte, 32), make([]byte, 32)
+	slot[31], value[31] = 1, 42
+	change := types.StateChange{Type: types.ChangeTypeStorage, Address: contract, Key: slot, Value: value}
+	if err := st.ApplyExecutionResult(&core.Transaction{Sender: holder}, &types.ExecutionResult{StateChanges: []*types.StateChange{&change}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := &core.Transaction{Sender: holder, Recipient: "GN_proof_recipient", Value: big.NewInt(100), GasLimit: core.TxGas, Symbol: core.GasSymbol}
+	if err := wallet.SignTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	block := &core.Block{Index: 1, PrevHash: genesis.Hash, Timestamp: time.Now(), Miner: "miner", GasLimit: core.DefaultBlockGasLimit,
+		Consensus: "poa", Transactions: []*core.Transaction{tx}, MerkleRoot: core.ComputeMerkleRoot([]*core.Transaction{tx})}
+	if err := bc.ExecuteBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := bc.AccountProof(string(holder), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account = roundTrip(t, account)
+	if err := VerifyAccount(account, block.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if account.Nonce != 1 || account.Balances["GANI"].Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("аккаунт: nonce %d, балансы %v", account.Nonce, account.Balances)
+	}
+	if err := VerifyAccount(account, genesis.Hash); err == nil {
+		t.Error("доказательство не должно подходить к другому блоку")
+	}
+	account.Balances["GANI"] = big.NewInt(8)
+	if err := VerifyAccount(account, block.Hash); err == nil {
+		t.Error("изменённый баланс GANI должен отклоняться")
+	}
+
+	absent, err := bc.AccountProof("GN_proof_nobody", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyAccount(absent, block.Hash); err != nil {
+		t.Errorf("доказательство отсутствия аккаунта: %v", err)
+	}
+	absent.Balances[core.GasSymbol] = big.NewInt(1)
+	if err := VerifyAccount(absent, block.Hash); err == nil {
+		t.Error("баланс отсутствующего аккаунта не должен подтверждаться")
+	}
+
+	storage, err := bc.StorageProof(contract, slot, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage = roundTrip(t, storage)
+	if err := VerifyStorage(storage, block.Hash); err != nil || storage.Value != "000000000000000000000000000000000000000000000000000000000000002a" {
+		t.Fatalf("слот storage %q: %v", storage.Value, err)
+	}
+	storage.Value = "00000000000000000000000000000000000000000000000000000000000000ff"
+	if err := VerifyStorage(storage, block.Hash); err == nil {
+		t.Error("изменённое значение слота должно отклоняться")
+	}
+	empty, err := bc.StorageProof(contract, make([]byte, 32), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyStorage(empty, block.Hash); err != nil || empty.Value != "" {
+		t.Errorf("пустой слот %q: %v", empty.Value, err)
+	}
+
+	inclusion, err := bc.TransactionProof(context.Background(), tx.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inclusion = roundTrip(t, inclusion)
+	if err := VerifyTransaction(inclusion, block.Hash); err != nil {
+		t.Fatal(err)
+	}
+	inclusion.Hash = "tx_other"
+	if err := VerifyTransaction(inclusion, block.Hash); err == nil {
+		t.Error("доказательство не должно подходить к другой транзакции")
+	}
+
+	if _, err := bc.AccountProof(string(holder), 0); err == nil {
+		t.Error("у генезиса без state_root доказательства нет")
+	}
+}
@@ -0,0 +1,191 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/receipt.go — квитанции транзакций: статус, газ, события контрактов, адрес созданного контракта, причина ошибки.
+
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"GND/types"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Статусы квитанции и транзакции после включения в блок
+const (
+	ReceiptStatusSuccess = "success"
+	ReceiptStatusFailed  = "failed"
+
+	TxStatusConfirmed = "confirmed" // транзакция в блоке, исполнена успешно
+	TxStatusFailed    = "failed"    // транзакция в блоке, исполнение не прошло (revert, nonce, баланс)
+)
+
+// ReceiptLog — событие контракта в квитанции (адрес, темы и данные в hex с 0x).
+type ReceiptLog struct {
+	Address  string   `json:"address"`
+	Topics   []string `json:"topics"`
+	Data     string   `json:"data"`
+	LogIndex uint     `json:"log_index"` // порядковый номер события в блоке
+}
+
+// Receipt — квитанция транзакции, включённой в блок.
+type Receipt struct {
+	TxHash            string        `json:"transaction_hash"`
+	TxIndex           int           `json:"transaction_index"`
+	BlockID           int64         `json:"block_id"`
+	BlockNumber       uint64        `json:"block_number"`
+	BlockHash         string        `json:"block_hash"`
+	From              string        `json:"from"`
+	To                string        `json:"to"`
+	Status            string        `json:"status"`
+	GasUsed           uint64        `json:"gas_used"`
+	CumulativeGasUsed uint64        `json:"cumulative_gas_used"`
+	Fee               string        `json:"fee"`
+	ContractAddress   string        `json:"contract_address,omitempty"`
+	Logs              []*ReceiptLog `json:"logs"`
+	Error             string        `json:"error,omitempty"`
+	RevertReason      string        `json:"revert_reason,omitempty"`
+	CreatedAt         time.Time     `json:"created_at"`
+}
+
+// Succeeded сообщает, исполнена ли транзакция успешно.
+func (r *Receipt) Succeeded() bool {
+	return r.Status == ReceiptStatusSuccess
+}
+
+// newReceipt формирует квитанцию по результату применения транзакции tx (index — позиция в блоке).
+// applyErr — ошибка применения (nonce, баланс), result — результат исполнения контракта (может быть nil для перевода).
+func newReceipt(tx *Transaction, block *Block, index int, cumulativeGas uint64, result *types.ExecutionResult, applyErr error) *Receipt {
+	r := &Receipt{
+		TxHash:            tx.Hash,
+		TxIndex:           index,
+		BlockNumber:       block.Index,
+		From:              tx.Sender.String(),
+		To:                tx.Recipient.String(),
+		Status:            ReceiptStatusSuccess,
+		GasUsed:           tx.GasUsed,
+		CumulativeGasUsed: cumulativeGas,
+		Fee:               "0",
+		Logs:              []*ReceiptLog{},
+		CreatedAt:         block.Timestamp,
+	}
+	if tx.Fee != nil {
+		r.Fee = tx.Fee.String()
+	}
+	if tx.Type == "contract_deploy" || tx.IsDeploy() {
+		r.ContractAddress = tx.Recipient.String()
+	}
+	switch {
+	case applyErr != nil:
+		r.Status = ReceiptStatusFailed
+		r.Error = applyErr.Error()
+	case result != nil && result.Error != nil:
+		r.Status = ReceiptStatusFailed
+		r.Error = result.Error.Error()
+		r.RevertReason = result.RevertReason
+	case result != nil:
+		for _, l := range result.Logs {
+			r.Logs = append(r.Logs, newReceiptLog(l))
+		}
+	}
+	return r
+}
+
+// newReceiptLog переводит событие исполнения в формат квитанции.
+func newReceiptLog(l *types.Log) *ReceiptLog {
+	topics := make([]string, len(l.Topics))
+	for i, t := range l.Topics {
+		topics[i] = "0x" + hex.EncodeToString(t)
+	}
+	return &ReceiptLog{Address: l.Address, Topics: topics, Data: "0x" + hex.EncodeToString(l.Data)}
+}
+
+// ReceiptFromTransaction строит квитанцию для транзакции из БД, для которой квитанция не сохранялась
+// (системные транзакции и транзакции, подтверждённые до появления квитанций).
+func ReceiptFromTransaction(tx *Transaction, blockNumber uint64) *Receipt {
+	r := &Receipt{
+		TxHash:            tx.Hash,
+		BlockID:           int64(tx.BlockID),
+		BlockNumber:       blockNumber,
+		From:              tx.Sender.String(),
+		To:                tx.Recipient.String(),
+		Status:            ReceiptStatusSuccess,
+		GasUsed:           tx.GasUsed,
+		CumulativeGasUsed: tx.GasUsed,
+		Fee:               "0",
+		Logs:              []*ReceiptLog{},
+		CreatedAt:         tx.Timestamp,
+	}
+	if tx.Fee != nil {
+		r.Fee = tx.Fee.String()
+	}
+	if tx.Status == TxStatusFailed {
+		r.Status = ReceiptStatusFailed
+	}
+	if tx.Type == "contract_deploy" || tx.IsDeploy() {
+		r.ContractAddress = tx.Recipient.String()
+	}
+	return r
+}
+
+// SaveToDB сохраняет квитанцию в таблицу receipts (повторное сохранение перезаписывает запись).
+func (r *Receipt) SaveToDB(ctx context.Context, pool *pgxpool.Pool) error {
+	if pool == nil {
+		return nil
+	}
+	logs, err := json.Marshal(r.Logs)
+	if err != nil {
+		return err
+	}
+	_, err = pool.Exec(ctx, `
+		INSERT INTO receipts (
+			tx_hash, tx_index, block_id, block_number, block_hash, sender, recipient, status,
+			gas_used, cumulative_gas_used, fee, contract_address, logs, error, revert_reason, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13, NULLIF($14, ''), NULLIF($15, ''), $16)
+		ON CONFLICT (tx_hash) DO UPDATE SET
+			tx_index = EXCLUDED.tx_index, block_id = EXCLUDED.block_id, block_number = EXCLUDED.block_number,
+			block_hash = EXCLUDED.block_hash, status = EXCLUDED.status, gas_used = EXCLUDED.gas_used,
+			cumulative_gas_used = EXCLUDED.cumulative_gas_used, fee = EXCLUDED.fee,
+			contract_address = EXCLUDED.contract_address, logs = EXCLUDED.logs,
+			error = EXCLUDED.error, revert_reason = EXCLUDED.revert_reason`,
+		r.TxHash, r.TxIndex, r.BlockID, r.BlockNumber, r.BlockHash, r.From, r.To, r.Status,
+		r.GasUsed, r.CumulativeGasUsed, r.Fee, r.ContractAddress, logs, r.Error, r.RevertReason, r.CreatedAt,
+	)
+	return err
+}
+
+// LoadReceipt загружает квитанцию транзакции из таблицы receipts. Возвращает (nil, nil), если квитанции нет.
+func LoadReceipt(ctx context.Context, pool *pgxpool.Pool, txHash string) (*Receipt, error) {
+	if pool == nil || txHash == "" {
+		return nil, errors.New("pool or hash empty")
+	}
+	var r Receipt
+	var fee string
+	var logs []byte
+	err := pool.QueryRow(ctx, `
+		SELECT tx_hash, tx_index, COALESCE(block_id, 0), block_number, block_hash, sender, recipient, status,
+			gas_used, cumulative_gas_used, COALESCE(fee::text, '0'), COALESCE(contract_address, ''),
+			COALESCE(logs, '[]'::jsonb), COALESCE(error, ''), COALESCE(revert_reason, ''), created_at
+		FROM receipts
+		WHERE tx_hash = $1`, txHash).Scan(
+		&r.TxHash, &r.TxIndex, &r.BlockID, &r.BlockNumber, &r.BlockHash, &r.From, &r.To, &r.Status,
+		&r.GasUsed, &r.CumulativeGasUsed, &fee, &r.ContractAddress,
+		&logs, &r.Error, &r.RevertReason, &r.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.Fee = fee
+	if err := json.Unmarshal(logs, &r.Logs); err != nil || r.Logs == nil {
+		r.Logs = []*ReceiptLog{}
+	}
+	return &r, nil
+}
@@ -0,0 +1,86 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/replay.go — повторное применение транзакций к отдельной копии состояния (трассировка исполнения, debug_trace*).
+
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"GND/types"
+)
+
+// Replay — повторное применение транзакций к копии состояния тем же кодом, что применение блока (applyTx).
+// Копия не связана с БД и цепью; транзакции применяются по порядку, State после ApplyTx — состояние после транзакции.
+type Replay struct {
+	Block   *Block // контекст исполнения: номер, время, лимит газа, base fee и предлагающий блок
+	State   *State
+	chain   *Blockchain // облегчённая цепь над копией: State, Executor и реестр стейкинга
+	index   int
+	gasUsed uint64
+}
+
+// ReplayBlock готовит повторное применение блока number цепи: копия состояния после его родителя, комиссии — как при
+// применении блока (base fee блока, чаевые — предлагающему). Состояние хранится для последних MaxReorgDepth блоков.
+func (bc *Blockchain) ReplayBlock(number uint64) (*Replay, error) {
+	if number == 0 {
+		return nil, errors.New("генезис-блок не исполняется")
+	}
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	var block *Block
+	for i := len(bc.Blocks) - 1; i >= 0; i-- {
+		if bc.Blocks[i].Index == number {
+			block = bc.Blocks[i]
+			break
+		}
+	}
+	if block == nil {
+		return nil, fmt.Errorf("блок %d не найден в цепи", number)
+	}
+	parent := number - 1
+	st, _, stakes, err := bc.stateAtLocked(&parent)
+	if err != nil {
+		return nil, err
+	}
+	st.SetFeeContext(block.BaseFee, block.Miner)
+	return &Replay{Block: block, State: st, chain: bc.replayChain(st, stakes)}, nil
+}
+
+// ReplayAt готовит применение пробных транзакций к копии состояния после блока number (nil — текущее состояние ноды)
+// в контексте этого блока; газ списывается по base fee блока-потомка.
+func (bc *Blockchain) ReplayAt(number *uint64) (*Replay, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+	st, block, stakes, err := bc.stateAtLocked(number)
+	if err != nil {
+		return nil, err
+	}
+	return &Replay{Block: block, State: st, chain: bc.replayChain(st, stakes)}, nil
+}
+
+// replayChain создаёт цепь над копией состояния с отдельным реестром стейкинга из снимка.
+func (bc *Blockchain) replayChain(st *State, stakes *StakeSnapshot) *Blockchain {
+	chain := &Blockchain{State: st}
+	if bc.Stakes != nil && stakes != nil {
+		bc.Stakes.mu.RLock()
+		cfg := bc.Stakes.cfg
+		bc.Stakes.mu.RUnlock()
+		chain.Stakes = NewStakeLedger(cfg)
+		chain.Stakes.restore(stakes)
+	}
+	return chain
+}
+
+// ApplyTx применяет копию транзакции tx (поля tx не меняются) и возвращает её квитанцию и результат исполнения контракта.
+// Вызовы контрактов исполняет executor (nil — без байткода, как у цепи без Executor).
+func (r *Replay) ApplyTx(tx *Transaction, executor ContractExecutor) (*Receipt, *types.ExecutionResult) {
+	applied := *tx
+	applied.GasUsed = 0
+	r.chain.Executor = executor
+	result, err := r.chain.applyTx(&applied, r.Block)
+	r.gasUsed += applied.GasUsed
+	receipt := newReceipt(&applied, r.Block, r.index, r.gasUsed, result, err)
+	r.index++
+	return receipt, result
+}
@@ -0,0 +1,563 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/staking.go — реестр стейкинга PoS: валидаторы, стейки делегаторов, вывод с периодом анбондинга, награды за блок.
+
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"GND/types"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Параметры стейкинга по умолчанию (переопределяются записью pos в consensus.json).
+const (
+	DefaultUnbondingBlocks = 100
+	MaxCommissionPercent   = 100
+)
+
+// DefaultBlockReward — награда за блок PoS по умолчанию: 1 GND (18 знаков).
+var DefaultBlockReward = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// StakingConfig — параметры стейкинга: период анбондинга в блоках и награда за блок PoS.
+type StakingConfig struct {
+	UnbondingBlocks uint64
+	BlockReward     *big.Int
+}
+
+// StakeValidator — валидатор PoS в реестре стейкинга.
+type StakeValidator struct {
+	Address           string   `json:"address"`
+	PubKey            string   `json:"pubkey"` // secp256k1, hex; ключ подписи блоков
+	CommissionPercent int      `json:"commission_percent"`
+	SelfStake         *big.Int `json:"self_stake"`
+	DelegatedStake    *big.Int `json:"delegated_stake"`
+	TotalStake        *big.Int `json:"total_stake"`
+	Rewards           *big.Int `json:"rewards"` // награды, полученные валидатором (комиссия и доля собственного стейка)
+}
+
+// Unbonding — выведенный из стейка объём, который вернётся на баланс делегатора на высоте ReleaseHeight.
+type Unbonding struct {
+	Validator     string   `json:"validator"`
+	Delegator     string   `json:"delegator"`
+	Amount        *big.Int `json:"amount"`
+	ReleaseHeight uint64   `json:"release_height"`
+}
+
+// Delegation — стейк делегатора у валидатора (self-stake — делегатор совпадает с валидатором).
+type Delegation struct {
+	Validator string   `json:"validator"`
+	Delegator string   `json:"delegator"`
+	Amount    *big.Int `json:"amount"`
+}
+
+// validatorPayload — data транзакции validator: ключ подписи блоков и комиссия с наград делегаторов.
+type validatorPayload struct {
+	PubKey            string `json:"pubkey"`
+	CommissionPercent int    `json:"commission_percent"`
+}
+
+type stakeValidator struct {
+	pubKey     string
+	commission int
+	rewards    *big.Int
+}
+
+// StakeLedger — реестр стейкинга. Меняется только при применении блока (транзакции stake, unstake, validator
+// и EndBlock), поэтому одинаков на всех нодах с одной цепью.
+type StakeLedger struct {
+	mu         sync.RWMutex
+	cfg        StakingConfig
+	validators map[string]*stakeValidator
+	stakes     map[string]map[string]*big.Int // валидатор → делегатор → объём
+	unbonding  []*Unbonding
+	rewards    map[string]*big.Int // все полученные награды по адресу (валидаторы и делегаторы)
+	dirty      bool
+}
+
+// NewStakeLedger создаёт пустой реестр; нулевые поля cfg заменяются значениями по умолчанию.
+func NewStakeLedger(cfg StakingConfig) *StakeLedger {
+	l := &StakeLedger{
+		validators: make(map[string]*stakeValidator),
+		stakes:     make(map[string]map[string]*big.Int),
+		rewards:    make(map[string]*big.Int),
+	}
+	l.SetConfig(cfg)
+	return l
+}
+
+// SetConfig задаёт параметры стейкинга.
+func (l *StakeLedger) SetConfig(cfg StakingConfig) {
+	if cfg.UnbondingBlocks == 0 {
+		cfg.UnbondingBlocks = DefaultUnbondingBlocks
+	}
+	if cfg.BlockReward == nil {
+		cfg.BlockReward = new(big.Int).Set(DefaultBlockReward)
+	}
+	l.mu.Lock()
+	l.cfg = cfg
+	l.mu.Unlock()
+}
+
+// BlockReward возвращает награду за блок PoS.
+func (l *StakeLedger) BlockReward() *big.Int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return new(big.Int).Set(l.cfg.BlockReward)
+}
+
+// IsStakingTx сообщает, относится ли транзакция к стейкингу (stake, unstake, validator).
+func IsStakingTx(tx *Transaction) bool {
+	switch TxType(tx.Type) {
+	case TxTypeStake, TxTypeUnstake, TxTypeValidator:
+		return true
+	}
+	return false
+}
+
+// Validator возвращает валидатора реестра.
+func (l *StakeLedger) Validator(addr string) (*StakeValidator, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if _, ok := l.validators[addr]; !ok {
+		return nil, false
+	}
+	return l.validatorLocked(addr), true
+}
+
+// Validators возвращает всех валидаторов реестра по адресу.
+func (l *StakeLedger) Validators() []*StakeValidator {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make([]*StakeValidator, 0, len(l.validators))
+	for _, addr := range sortedKeys(l.validators) {
+		out = append(out, l.validatorLocked(addr))
+	}
+	return out
+}
+
+// TotalStake возвращает суммарный стейк валидатора (собственный и делегированный).
+func (l *StakeLedger) TotalStake(validator string) *big.Int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.totalStakeLocked(validator)
+}
+
+// Delegations возвращает стейки делегатора у всех валидаторов.
+func (l *StakeLedger) Delegations(delegator string) []Delegation {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var out []Delegation
+	for _, v := range sortedKeys(l.stakes) {
+		if amt, ok := l.stakes[v][delegator]; ok {
+			out = append(out, Delegation{Validator: v, Delegator: delegator, Amount: new(big.Int).Set(amt)})
+		}
+	}
+	return out
+}
+
+// Unbondings возвращает незавершённые выводы делегатора (пусто — все).
+func (l *StakeLedger) Unbondings(delegator string) []Unbonding {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var out []Unbonding
+	for _, u := range l.unbonding {
+		if delegator == "" || u.Delegator == delegator {
+			out = append(out, Unbonding{Validator: u.Validator, Delegator: u.Delegator, Amount: new(big.Int).Set(u.Amount), ReleaseHeight: u.ReleaseHeight})
+		}
+	}
+	return out
+}
+
+// Rewards возвращает все награды, полученные адресом.
+func (l *StakeLedger) Rewards(addr string) *big.Int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if r, ok := l.rewards[addr]; ok {
+		return new(big.Int).Set(r)
+	}
+	return big.NewInt(0)
+}
+
+func (l *StakeLedger) validatorLocked(addr string) *StakeValidator {
+	v := l.validators[addr]
+	self := big.NewInt(0)
+	if amt, ok := l.stakes[addr][addr]; ok {
+		self.Set(amt)
+	}
+	total := l.totalStakeLocked(addr)
+	return &StakeValidator{
+		Address:           addr,
+		PubKey:            v.pubKey,
+		CommissionPercent: v.commission,
+		SelfStake:         self,
+		DelegatedStake:    new(big.Int).Sub(total, self),
+		TotalStake:        total,
+		Rewards:           new(big.Int).Set(v.rewards),
+	}
+}
+
+func (l *StakeLedger) totalStakeLocked(validator string) *big.Int {
+	total := big.NewInt(0)
+	for _, amt := range l.stakes[validator] {
+		total.Add(total, amt)
+	}
+	return total
+}
+
+// checkStakingTx проверяет транзакцию стейкинга до списания комиссии и изменения состояния.
+func (l *StakeLedger) checkStakingTx(tx *Transaction) (*validatorPayload, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	sender, validator := tx.Sender.String(), tx.Recipient.String()
+	switch TxType(tx.Type) {
+	case TxTypeValidator:
+		var p validatorPayload
+		if err := json.Unmarshal(tx.Data, &p); err != nil {
+			return nil, fmt.Errorf("data транзакции validator: %w", err)
+		}
+		if b, err := hex.DecodeString(p.PubKey); err != nil || (len(b) != 33 && len(b) != 65) {
+			return nil, errors.New("pubkey: ожидается ключ secp256k1 в hex (33 или 65 байт)")
+		}
+		if p.CommissionPercent < 0 || p.CommissionPercent > MaxCommissionPercent {
+			return nil, fmt.Errorf("commission_percent должен быть от 0 до %d", MaxCommissionPercent)
+		}
+		return &p, nil
+	case TxTypeStake, TxTypeUnstake:
+		if tx.Value == nil || tx.Value.Sign() <= 0 {
+			return nil, errors.New("объём стейка должен быть больше нуля")
+		}
+		if _, ok := l.validators[validator]; !ok {
+			return nil, fmt.Errorf("валидатор %s не зарегистрирован", validator)
+		}
+		if TxType(tx.Type) == TxTypeUnstake {
+			staked, ok := l.stakes[validator][sender]
+			if !ok || staked.Cmp(tx.Value) < 0 {
+				return nil, fmt.Errorf("стейк %s у валидатора %s меньше %s", sender, validator, tx.Value)
+			}
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("тип %s не относится к стейкингу", tx.Type)
+}
+
+// applyStakingTx применяет транзакцию стейкинга: проверка nonce и газа, комиссия, затем
+// validator — регистрация (повторная меняет ключ и комиссию), stake — GND отправителя блокируется у валидатора tx.Recipient,
+// unstake — объём выводится и вернётся на баланс через UnbondingBlocks блоков.
+func (bc *Blockchain) applyStakingTx(st *State, tx *Transaction, block *Block) error {
+	if expected := st.GetNonce(tx.Sender); tx.Nonce != expected {
+		return fmt.Errorf("неверный nonce (expected %d, got %d)", expected, tx.Nonce)
+	}
+	gasUsed := IntrinsicGas(tx.Data, false)
+	if gasUsed > tx.EffectiveGasLimit() {
+		return fmt.Errorf("intrinsic gas too low: have %d, want %d", tx.EffectiveGasLimit(), gasUsed)
+	}
+	payload, err := bc.Stakes.checkStakingTx(tx)
+	if err != nil {
+		return err
+	}
+	charge, err := st.ChargeGas(tx, gasUsed)
+	if err != nil {
+		return err
+	}
+	l := bc.Stakes
+	switch TxType(tx.Type) {
+	case TxTypeValidator:
+		l.register(tx.Sender.String(), payload.PubKey, payload.CommissionPercent)
+	case TxTypeStake:
+		if err := st.SubBalance(tx.Sender, GasSymbol, tx.Value); err != nil {
+			st.refundGas(charge)
+			return err
+		}
+		l.stake(tx.Recipient.String(), tx.Sender.String(), tx.Value)
+	case TxTypeUnstake:
+		l.unstake(tx.Recipient.String(), tx.Sender.String(), tx.Value, block.Index)
+	}
+	tx.GasUsed = gasUsed
+	tx.Fee = charge.Total
+	st.IncrementNonce(tx.Sender)
+	st.MarkTouched(tx.Sender)
+	return nil
+}
+
+func (l *StakeLedger) register(addr, pubKey string, commission int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	v, ok := l.validators[addr]
+	if !ok {
+		v = &stakeValidator{rewards: big.NewInt(0)}
+		l.validators[addr] = v
+	}
+	v.pubKey = pubKey
+	v.commission = commission
+	l.dirty = true
+}
+
+func (l *StakeLedger) stake(validator, delegator string, amount *big.Int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stakes[validator] == nil {
+		l.stakes[validator] = make(map[string]*big.Int)
+	}
+	if cur, ok := l.stakes[validator][delegator]; ok {
+		cur.Add(cur, amount)
+	} else {
+		l.stakes[validator][delegator] = new(big.Int).Set(amount)
+	}
+	l.dirty = true
+}
+
+func (l *StakeLedger) unstake(validator, delegator string, amount *big.Int, height uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur := l.stakes[validator][delegator]
+	cur.Sub(cur, amount)
+	if cur.Sign() == 0 {
+		delete(l.stakes[validator], delegator)
+	}
+	l.unbonding = append(l.unbonding, &Unbonding{Validator: validator, Delegator: delegator,
+		Amount: new(big.Int).Set(amount), ReleaseHeight: height + l.cfg.UnbondingBlocks})
+	l.dirty = true
+}
+
+// EndBlock завершает применение блока: возвращает на балансы выводы с наступившей высотой разблокировки
+// и для блока PoS начисляет block.Reward валидатору block.Miner и его делегаторам.
+func (l *StakeLedger) EndBlock(st *State, block *Block) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	kept := l.unbonding[:0]
+	for _, u := range l.unbonding {
+		if u.ReleaseHeight > block.Index {
+			kept = append(kept, u)
+			continue
+		}
+		if err := st.AddBalance(types.Address(u.Delegator), GasSymbol, u.Amount); err != nil {
+			fmt.Printf("[Staking] Не удалось вернуть %s GND на %s: %v\n", u.Amount, u.Delegator, err)
+		}
+		st.MarkTouched(types.Address(u.Delegator))
+		l.dirty = true
+	}
+	l.unbonding = kept
+
+	if block.Consensus == "pos" && block.Reward != nil && block.Reward.Sign() > 0 {
+		l.distributeRewardLocked(st, block.Miner, block.Reward)
+	}
+}
+
+// distributeRewardLocked делит награду: комиссия — валидатору, остаток — пропорционально стейкам
+// (включая собственный), остаток от деления — валидатору. Без стейка вся награда — валидатору.
+func (l *StakeLedger) distributeRewardLocked(st *State, validator string, reward *big.Int) {
+	shares := make(map[string]*big.Int)
+	total := l.totalStakeLocked(validator)
+	rest := new(big.Int).Set(reward)
+	if v, ok := l.validators[validator]; ok && total.Sign() > 0 {
+		commission := new(big.Int).Div(new(big.Int).Mul(reward, big.NewInt(int64(v.commission))), big.NewInt(100))
+		toStakers := new(big.Int).Sub(reward, commission)
+		for _, d := range sortedKeys(l.stakes[validator]) {
+			share := new(big.Int).Div(new(big.Int).Mul(toStakers, l.stakes[validator][d]), total)
+			shares[d] = share
+			rest.Sub(rest, share)
+		}
+	}
+	if cur, ok := shares[validator]; ok {
+		cur.Add(cur, rest)
+	} else {
+		shares[validator] = rest
+	}
+	for _, addr := range sortedKeys(shares) {
+		amt := shares[addr]
+		if amt.Sign() == 0 {
+			continue
+		}
+		if err := st.AddBalance(types.Address(addr), GasSymbol, amt); err != nil {
+			fmt.Printf("[Staking] Не удалось начислить награду %s на %s: %v\n", amt, addr, err)
+			continue
+		}
+		st.MarkTouched(types.Address(addr))
+		if l.rewards[addr] == nil {
+			l.rewards[addr] = big.NewInt(0)
+		}
+		l.rewards[addr].Add(l.rewards[addr], amt)
+		if addr == validator {
+			if v, ok := l.validators[validator]; ok {
+				v.rewards.Add(v.rewards, amt)
+			}
+		}
+	}
+	l.dirty = true
+}
+
+// SaveToDB сохраняет реестр, если он изменился: validators и pos_validators (стейк, комиссия, награды),
+// pos_stakes, pos_unbonding и pos_rewards перезаписываются целиком.
+func (l *StakeLedger) SaveToDB(ctx context.Context, pool *pgxpool.Pool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if pool == nil || !l.dirty {
+		return nil
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, addr := range sortedKeys(l.validators) {
+		v := l.validatorLocked(addr)
+		var id int64
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO validators (address, pubkey, stake, status, last_active, consensus_type)
+			VALUES ($1, $2, $3, 'active', NOW(), 'pos')
+			ON CONFLICT (address) DO UPDATE SET pubkey = EXCLUDED.pubkey, stake = EXCLUDED.stake, last_active = NOW()
+			RETURNING id`, addr, v.PubKey, v.TotalStake.String()).Scan(&id); err != nil {
+			return fmt.Errorf("validators %s: %w", addr, err)
+		}
+		commission := fmt.Sprintf("%.2f", float64(v.CommissionPercent)/100)
+		ct, err := tx.Exec(ctx, `
+			UPDATE pos_validators SET total_stake = $2, delegated_stake = $3, commission_rate = $4, rewards = $5
+			WHERE validator_id = $1`, id, v.TotalStake.String(), v.DelegatedStake.String(), commission, v.Rewards.String())
+		if err != nil {
+			return fmt.Errorf("pos_validators %s: %w", addr, err)
+		}
+		if ct.RowsAffected() == 0 {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO pos_validators (validator_id, total_stake, delegated_stake, commission_rate, rewards, slashing_events)
+				VALUES ($1, $2, $3, $4, $5, 0)`, id, v.TotalStake.String(), v.DelegatedStake.String(), commission, v.Rewards.String()); err != nil {
+				return fmt.Errorf("pos_validators %s: %w", addr, err)
+			}
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM pos_stakes`); err != nil {
+		return err
+	}
+	for _, v := range sortedKeys(l.stakes) {
+		for _, d := range sortedKeys(l.stakes[v]) {
+			if _, err := tx.Exec(ctx, `INSERT INTO pos_stakes (validator, delegator, amount) VALUES ($1, $2, $3)`,
+				v, d, l.stakes[v][d].String()); err != nil {
+				return fmt.Errorf("pos_stakes: %w", err)
+			}
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM pos_unbonding`); err != nil {
+		return err
+	}
+	for _, u := range l.unbonding {
+		if _, err := tx.Exec(ctx, `INSERT INTO pos_unbonding (validator, delegator, amount, release_height) VALUES ($1, $2, $3, $4)`,
+			u.Validator, u.Delegator, u.Amount.String(), u.ReleaseHeight); err != nil {
+			return fmt.Errorf("pos_unbonding: %w", err)
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM pos_rewards`); err != nil {
+		return err
+	}
+	for _, addr := range sortedKeys(l.rewards) {
+		if _, err := tx.Exec(ctx, `INSERT INTO pos_rewards (address, amount) VALUES ($1, $2)`, addr, l.rewards[addr].String()); err != nil {
+			return fmt.Errorf("pos_rewards: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+// LoadFromDB загружает реестр из validators/pos_validators, pos_stakes, pos_unbonding и pos_rewards.
+func (l *StakeLedger) LoadFromDB(ctx context.Context, pool *pgxpool.Pool) error {
+	if pool == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rows, err := pool.Query(ctx, `
+		SELECT v.address, COALESCE(v.pubkey, ''), COALESCE(ROUND(pv.commission_rate * 100), 0)::int, COALESCE(pv.rewards, 0)::text
+		FROM validators v
+		JOIN pos_validators pv ON pv.validator_id = v.id`)
+	if err != nil {
+		return fmt.Errorf("загрузка pos_validators: %w", err)
+	}
+	for rows.Next() {
+		var addr, pubKey, rewards string
+		var commission int
+		if err := rows.Scan(&addr, &pubKey, &commission, &rewards); err != nil {
+			rows.Close()
+			return err
+		}
+		r, _ := new(big.Int).SetString(rewards, 10)
+		if r == nil {
+			r = big.NewInt(0)
+		}
+		l.validators[addr] = &stakeValidator{pubKey: pubKey, commission: commission, rewards: r}
+	}
+	rows.Close()
+
+	rows, err = pool.Query(ctx, `SELECT validator, delegator, amount::text FROM pos_stakes`)
+	if err != nil {
+		return fmt.Errorf("загрузка pos_stakes: %w", err)
+	}
+	for rows.Next() {
+		var v, d, amount string
+		if err := rows.Scan(&v, &d, &amount); err != nil {
+			rows.Close()
+			return err
+		}
+		if l.stakes[v] == nil {
+			l.stakes[v] = make(map[string]*big.Int)
+		}
+		if amt, ok := new(big.Int).SetString(amount, 10); ok {
+			l.stakes[v][d] = amt
+		}
+	}
+	rows.Close()
+
+	rows, err = pool.Query(ctx, `SELECT address, amount::text FROM pos_rewards`)
+	if err != nil {
+		return fmt.Errorf("загрузка pos_rewards: %w", err)
+	}
+	for rows.Next() {
+		var addr, amount string
+		if err := rows.Scan(&addr, &amount); err != nil {
+			rows.Close()
+			return err
+		}
+		if r, ok := new(big.Int).SetString(amount, 10); ok {
+			l.rewards[addr] = r
+		}
+	}
+	rows.Close()
+
+	rows, err = pool.Query(ctx, `SELECT validator, delegator, amount::text, release_height FROM pos_unbonding ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("загрузка pos_unbonding: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		u := &Unbonding{}
+		var amount string
+		if err := rows.Scan(&u.Validator, &u.Delegator, &amount, &u.ReleaseHeight); err != nil {
+			return err
+		}
+		u.Amount, _ = new(big.Int).SetString(amount, 10)
+		if u.Amount != nil {
+			l.unbonding = append(l.unbonding, u)
+		}
+	}
+	return rows.Err()
+}
+
+// sortedKeys возвращает ключи карты по возрастанию (детерминированный порядок обхода).
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,104 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package core
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"GND/types"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+func TestStaking_StakeRewardsAndUnbonding(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now().Add(-time.Hour), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "pos", Status: "finalized"}
+	genesis.Hash = genesis.CalculateHash()
+	bc := NewBlockchain(genesis, nil)
+	bc.Stakes.SetConfig(StakingConfig{UnbondingBlocks: 2, BlockReward: big.NewInt(1000)})
+	st := bc.State.(*State)
+	SetState(st)
+	defer SetState(nil)
+
+	validatorWallet, delegatorWallet := newTestWallet(t), newTestWallet(t)
+	validator, delegator := types.Address(validatorWallet.Address), types.Address(delegatorWallet.Address)
+	wallets := map[types.Address]*Wallet{validator: validatorWallet, delegator: delegatorWallet}
+	for _, addr := range []types.Address{validator, delegator} {
+		if err := st.AddBalance(addr, GasSymbol, big.NewInt(1e15)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte(`{"pubkey":"` + hex.EncodeToString(key.PubKey().SerializeCompressed()) + `","commission_percent":10}`)
+
+	prev := genesis
+	addBlock := func(miner string, txs ...*Transaction) {
+		t.Helper()
+		b := &Block{Index: prev.Index + 1, PrevHash: prev.Hash, Timestamp: prev.Timestamp.Add(time.Second), Miner: miner,
+			GasLimit: DefaultBlockGasLimit, Consensus: "pos", Status: "finalized", Reward: bc.Stakes.BlockReward(), Transactions: txs,
+			MerkleRoot: ComputeMerkleRoot(txs)}
+		if err := bc.AddBlock(executeTestBlock(t, bc, b)); err != nil {
+			t.Fatal(err)
+		}
+		prev = b
+	}
+	stakingTx := func(txType TxType, from types.Address, value int64, nonce int64, data []byte) *Transaction {
+		return signTestTx(t, wallets[from], &Transaction{Type: string(txType), Recipient: validator, Value: big.NewInt(value), Nonce: nonce,
+			Data: data, GasLimit: 100000, Symbol: GasSymbol})
+	}
+
+	// stake до регистрации валидатора не проходит
+	addBlock("miner", stakingTx(TxTypeStake, delegator, 100, 0, nil))
+	if bc.Stakes.TotalStake(validator.String()).Sign() != 0 {
+		t.Fatal("стейк у незарегистрированного валидатора не должен приниматься")
+	}
+
+	addBlock("miner",
+		stakingTx(TxTypeValidator, validator, 0, 0, payload),
+		stakingTx(TxTypeStake, validator, 300, 1, nil),
+		stakingTx(TxTypeStake, delegator, 600, 0, nil))
+	v, ok := bc.Stakes.Validator(validator.String())
+	if !ok || v.SelfStake.Int64() != 300 || v.DelegatedStake.Int64() != 600 || v.CommissionPercent != 10 {
+		t.Fatalf("валидатор после stake: %+v", v)
+	}
+
+	// награда 1000: комиссия 100 валидатору, 900 делятся 1:2 — 300 валидатору, 600 делегатору
+	balanceBefore := st.GetBalance(delegator, GasSymbol)
+	addBlock(validator.String())
+	if got := bc.Stakes.Rewards(validator.String()).Int64(); got != 400 {
+		t.Errorf("награда валидатора: ожидалось 400, получено %d", got)
+	}
+	if got := bc.Stakes.Rewards(delegator.String()).Int64(); got != 600 {
+		t.Errorf("награда делегатора: ожидалось 600, получено %d", got)
+	}
+	if got := new(big.Int).Sub(st.GetBalance(delegator, GasSymbol), balanceBefore); got.Int64() != 600 {
+		t.Errorf("баланс делегатора: ожидалось +600, получено +%s", got)
+	}
+
+	// unstake сверх стейка не проходит; 200 возвращаются через 2 блока
+	addBlock("miner", stakingTx(TxTypeUnstake, delegator, 700, 1, nil))
+	if got := bc.Stakes.TotalStake(validator.String()).Int64(); got != 900 {
+		t.Fatalf("unstake сверх стейка не должен применяться, стейк %d", got)
+	}
+	addBlock("miner", stakingTx(TxTypeUnstake, delegator, 200, 1, nil))
+	unbondings := bc.Stakes.Unbondings(delegator.String())
+	if len(unbondings) != 1 || unbondings[0].ReleaseHeight != prev.Index+2 || bc.Stakes.TotalStake(validator.String()).Int64() != 700 {
+		t.Fatalf("unstake: разблокировки %+v, стейк %s", unbondings, bc.Stakes.TotalStake(validator.String()))
+	}
+	balanceBefore = st.GetBalance(delegator, GasSymbol)
+	addBlock("miner")
+	if len(bc.Stakes.Unbondings(delegator.String())) != 1 {
+		t.Error("стейк не должен возвращаться до release_height")
+	}
+	addBlock("miner")
+	if len(bc.Stakes.Unbondings(delegator.String())) != 0 {
+		t.Error("разблокировка должна завершиться на release_height")
+	}
+	if got := new(big.Int).Sub(st.GetBalance(delegator, GasSymbol), balanceBefore); got.Int64() != 200 {
+		t.Errorf("баланс делегатора после разблокировки: ожидалось +200, получено +%s", got)
+	}
+}
@@ -3,17 +3,17 @@ package core
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"GND/core/trie"
 	"GND/types"
 
 	"github.com/jackc/pgx/v5"
@@ -218,10 +218,20 @@ type State struct {
 	gndContractAddr     string // если задан — GND берётся из token_balances по token_id
 	ganiContractAddr    string
 	gndselfAddress      string // системный владелец; при owner == gndself комиссии не взимаются
-	feeCollectorAddress string // адрес сборщика комиссий; газ зачисляется на него при списании с отправителя
+	feeCollectorAddress string // адрес сборщика комиссий; чаевые транзакций вне блока зачисляются на него
+	treasuryAddress     string // казна: base fee зачисляется на неё (пустая — base fee сжигается)
+	baseFee             *big.Int
+	feeRecipient        string // предлагающий блок, транзакции которого применяются: получает чаевые
 	// Для записи снимков по блоку и слотов контрактов
 	touchedInBlock map[types.Address]struct{}
 	storageChanges []ContractStorageChange
+	// Хеши листов аккаунтов, записанных в account_states; nil — следующий блок записывает все аккаунты
+	savedLeaves map[string]trie.Hash
+	// Кэш runtime-кода и слотов storage контрактов для исполнения байткода (vm)
+	codes   map[string][]byte
+	storage map[string]map[string][]byte
+	// Адреса контрактов, код которых изменён после последней записи в contracts (SaveToDB)
+	codeChanges map[string]struct{}
 }
 
 // NewState создает новое состояние
@@ -231,6 +241,8 @@ func NewState() *State {
 		nonces:         make(map[types.Address]uint64),
 		touchedInBlock: make(map[types.Address]struct{}),
 		storageChanges: nil,
+		codes:          make(map[string][]byte),
+		storage:        make(map[string]map[string][]byte),
 	}
 }
 
@@ -257,20 +269,48 @@ func (s *State) SetGndselfAddress(addr string) {
 	s.gndselfAddress = strings.TrimSpace(addr)
 }
 
-// SetFeeCollectorAddress задаёт адрес сборщика комиссий; газ при списании с отправителя зачисляется на этот адрес (из config/native_contracts.json).
+// SetFeeCollectorAddress задаёт адрес сборщика комиссий (из config/native_contracts.json): на него зачисляются чаевые
+// за газ, списанный вне блока (деплой через API), когда предлагающего нет.
 func (s *State) SetFeeCollectorAddress(addr string) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.feeCollectorAddress = strings.TrimSpace(addr)
 }
 
+// SetTreasuryAddress задаёт казну, на которую зачисляется base fee (treasury_address из config/native_contracts.json);
+// без казны base fee сжигается.
+func (s *State) SetTreasuryAddress(addr string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.treasuryAddress = strings.TrimSpace(addr)
+}
+
+// SetFeeContext задаёт base fee и предлагающего блока, транзакции которого применяются. Вне блока proposer пустой,
+// а baseFee — base fee следующего блока.
+func (s *State) SetFeeContext(baseFee *big.Int, proposer string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.baseFee = baseFee
+	s.feeRecipient = proposer
+}
+
+// BaseFee возвращает base fee, по которой списывается газ (nil — не задана: вся комиссия считается чаевыми).
+func (s *State) BaseFee() *big.Int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.baseFee == nil {
+		return nil
+	}
+	return new(big.Int).Set(s.baseFee)
+}
+
 // WillSkipGasForTx возвращает true, если для данной транзакции газ не будет списан (вызов контракта, владелец которого = gndself).
 // Используется при валидации: отправителю не требуется баланс GND на газ.
 func (s *State) WillSkipGasForTx(tx *Transaction) bool {
 	s.mutex.RLock()
 	gndself, pool := s.gndselfAddress, s.pool
 	s.mutex.RUnlock()
-	if gndself == "" || pool == nil || strings.TrimSpace(tx.Recipient.String()) == "" {
+	if gndself == "" || pool == nil || strings.TrimSpace(tx.Recipient.String()) == "" || tx.IsDeploy() {
 		return false
 	}
 	var owner string
@@ -298,39 +338,25 @@ func (s *State) ClearTouched() {
 	s.storageChanges = nil
 }
 
-// RootHash возвращает детерминированный хеш текущего состояния (для state_root блока).
-// Учитываются адрес, nonce и баланс GND по каждому аккаунту (адреса в сортированном порядке).
+// RootHash возвращает корень дерева состояния (state_root блока): nonce и балансы всех символов аккаунтов,
+// runtime-код и слоты storage контрактов. Состояние в памяти — полный образ БД (LoadFromDB загружает все аккаунты,
+// token_balances, код и storage), чтения его не дополняют, поэтому корень зависит только от применённых изменений.
 func (s *State) RootHash() string {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	addresses := make([]string, 0, len(s.nonces)+len(s.balances))
-	seen := make(map[types.Address]struct{})
-	for a := range s.nonces {
-		seen[a] = struct{}{}
-	}
-	for a := range s.balances {
-		seen[a] = struct{}{}
-	}
-	for a := range seen {
-		addresses = append(addresses, string(a))
-	}
-	sort.Strings(addresses)
-	h := sha256.New()
-	for _, addr := range addresses {
-		a := types.Address(addr)
-		nonce := s.nonces[a]
-		balanceGnd := "0"
-		if bm, ok := s.balances[a]; ok && bm != nil {
-			if b, ok := bm[GasSymbol]; ok && b != nil {
-				balanceGnd = b.String()
-			}
-		}
-		h.Write([]byte(addr))
-		h.Write([]byte(fmt.Sprintf("%d", nonce)))
-		h.Write([]byte(balanceGnd))
+	return stateRoot(s.nonces, s.balances, s.codes, s.storage)
+}
+
+// tokenBalanceContractLocked сообщает, хранится ли баланс символа в token_balances, и возвращает адрес контракта токена,
+// которому должна принадлежать запись ("" — любой токен с этим символом). GND/GANI хранятся там только в режиме контрактов.
+func (s *State) tokenBalanceContractLocked(symbol string) (string, bool) {
+	switch symbol {
+	case GasSymbol:
+		return s.gndContractAddr, s.gndContractAddr != ""
+	case "GANI":
+		return s.ganiContractAddr, s.ganiContractAddr != ""
 	}
-	sum := h.Sum(nil)
-	return hex.EncodeToString(sum)
+	return "", true
 }
 
 // getTokenIDForNativeContractLocked возвращает token_id по символу и адресу контракта (tokens JOIN contracts).
@@ -450,7 +476,11 @@ func (s *State) AddBalance(address types.Address, symbol string, amount *big.Int
 				INSERT INTO token_balances (token_id, address, balance) VALUES ($1, $2, $3)
 				ON CONFLICT (token_id, address) DO UPDATE SET balance = token_balances.balance + $3`,
 				tokenID, string(address), amount.String())
-			return err
+			if err != nil {
+				return err
+			}
+			s.adjustBalanceLocked(address, symbol, amount)
+			return nil
 		}
 	}
 	if symbol == "GANI" && s.ganiContractAddr != "" && s.pool != nil {
@@ -460,7 +490,11 @@ func (s *State) AddBalance(address types.Address, symbol string, amount *big.Int
 				INSERT INTO token_balances (token_id, address, balance) VALUES ($1, $2, $3)
 				ON CONFLICT (token_id, address) DO UPDATE SET balance = token_balances.balance + $3`,
 				tokenID, string(address), amount.String())
-			return err
+			if err != nil {
+				return err
+			}
+			s.adjustBalanceLocked(address, symbol, amount)
+			return nil
 		}
 	}
 
@@ -507,6 +541,7 @@ func (s *State) SubBalance(address types.Address, symbol string, amount *big.Int
 			if res.RowsAffected() == 0 {
 				return errors.New("insufficient balance")
 			}
+			s.adjustBalanceLocked(address, symbol, new(big.Int).Neg(amount))
 			return nil
 		}
 	}
@@ -522,6 +557,7 @@ func (s *State) SubBalance(address types.Address, symbol string, amount *big.Int
 			if res.RowsAffected() == 0 {
 				return errors.New("insufficient balance")
 			}
+			s.adjustBalanceLocked(address, symbol, new(big.Int).Neg(amount))
 			return nil
 		}
 	}
@@ -539,6 +575,18 @@ func (s *State) SubBalance(address types.Address, symbol string, amount *big.Int
 	return nil
 }
 
+// adjustBalanceLocked меняет баланс адреса в памяти на delta. В режиме контрактов GND/GANI списываются и зачисляются
+// в token_balances, а память повторяет их, чтобы state_root включал эти балансы. Вызывать при удержанном s.mutex (Lock).
+func (s *State) adjustBalanceLocked(address types.Address, symbol string, delta *big.Int) {
+	if s.balances[address] == nil {
+		s.balances[address] = make(map[string]*big.Int)
+	}
+	if s.balances[address][symbol] == nil {
+		s.balances[address][symbol] = big.NewInt(0)
+	}
+	s.balances[address][symbol].Add(s.balances[address][symbol], delta)
+}
+
 // GetNonce возвращает nonce адреса
 func (s *State) GetNonce(address types.Address) int64 {
 	s.mutex.RLock()
@@ -553,6 +601,13 @@ func (s *State) IncrementNonce(address types.Address) {
 	s.nonces[address]++
 }
 
+// SetNonce задаёт nonce адреса (nonce контракта, изменённый при исполнении байткода, — CREATE)
+func (s *State) SetNonce(address types.Address, nonce uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nonces[address] = nonce
+}
+
 // ApplyTransaction применяет транзакцию к состоянию. Используется tx.Symbol (GND или GANI); при пустом — GND.
 func (s *State) ApplyTransaction(tx *Transaction) error {
 	if tx.Value == nil {
@@ -576,52 +631,58 @@ func (s *State) ApplyTransaction(tx *Transaction) error {
 		return errors.New("symbol must be native (GND or GANI)")
 	}
 
+	// Газ перевода — базовая стоимость (системные транзакции газ не платят)
+	gasUsed := uint64(0)
+	if !IsSystemTransaction(tx) {
+		gasUsed = IntrinsicGas(tx.Data, false)
+		if gasUsed > tx.EffectiveGasLimit() {
+			return fmt.Errorf("intrinsic gas too low: have %d, want %d", tx.EffectiveGasLimit(), gasUsed)
+		}
+	}
+	charge, err := s.ChargeGas(tx, gasUsed)
+	if err != nil {
+		return err
+	}
+
 	// Списываем баланс отправителя по символу транзакции
 	if err := s.SubBalance(types.Address(tx.Sender), symbol, tx.Value); err != nil {
+		s.refundGas(charge)
 		return err
 	}
 
 	// Начисляем баланс получателю
 	if err := s.AddBalance(types.Address(tx.Recipient), symbol, tx.Value); err != nil {
 		s.AddBalance(types.Address(tx.Sender), symbol, tx.Value)
+		s.refundGas(charge)
 		return err
 	}
 
+	tx.GasUsed = gasUsed
+	tx.Fee = charge.Total
 	s.IncrementNonce(types.Address(tx.Sender))
 	s.MarkTouched(types.Address(tx.Sender))
 	s.MarkTouched(types.Address(tx.Recipient))
 	return nil
 }
 
-// SaveToDB сохраняет состояние в БД. Нативные балансы — в native_balances; текущее состояние (nonce, balance_gnd) — в accounts.
-// При blockID > 0 дополнительно пишет снимки в account_states и слоты в contract_storage.
+// SaveToDB сохраняет состояние в БД. Нативные балансы — в native_balances, балансы токенов (и GND/GANI в режиме контрактов) —
+// в token_balances; текущее состояние (nonce, balance_gnd) — в accounts.
+// При blockID > 0 дополнительно пишет в account_states листы аккаунтов, изменившиеся с прошлого блока, и слоты в contract_storage.
 func (s *State) SaveToDB(blockID int64) error {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
 	if s.pool == nil {
+		s.codeChanges = nil
 		return nil
 	}
 	ctx := context.Background()
 
-	// 1. Нативные балансы (GND, GANI) в native_balances, если не режим контрактов
+	// 1. Балансы: GND/GANI — в native_balances, балансы токенов (и GND/GANI в режиме контрактов) — в token_balances:
+	// state_root считается по балансам в памяти, и после перезапуска LoadFromDB должен восстановить те же значения
 	for address, balances := range s.balances {
 		for symbol, balance := range balances {
-			if !IsNativeSymbol(symbol) {
-				continue
-			}
-			if symbol == GasSymbol && s.gndContractAddr != "" {
-				continue
-			}
-			if symbol == "GANI" && s.ganiContractAddr != "" {
-				continue
-			}
-			_, err := s.pool.Exec(ctx, `
-				INSERT INTO native_balances (address, symbol, balance, updated_at)
-				VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
-				ON CONFLICT (address, symbol) DO UPDATE
-				SET balance = $3, updated_at = CURRENT_TIMESTAMP`, address, symbol, balance.String())
-			if err != nil {
+			if err := s.saveBalanceLocked(ctx, address, symbol, balance); err != nil {
 				return err
 			}
 		}
@@ -636,44 +697,20 @@ func (s *State) SaveToDB(blockID int64) error {
 		seen[address] = struct{}{}
 	}
 	for address := range seen {
-		nonce := s.nonces[address]
-		balanceGnd := "0"
-		if bm, ok := s.balances[address]; ok && bm != nil {
-			if b, ok := bm[GasSymbol]; ok && b != nil {
-				balanceGnd = b.String()
-			}
-		}
-		_, err := s.pool.Exec(ctx, `
-			INSERT INTO accounts (address, nonce, balance_gnd, is_contract)
-			VALUES ($1, $2, $3, FALSE)
-			ON CONFLICT (address) DO UPDATE SET
-				nonce = EXCLUDED.nonce,
-				balance_gnd = EXCLUDED.balance_gnd`,
-			string(address), nonce, balanceGnd)
-		if err != nil {
+		if err := s.saveAccountLocked(ctx, address); err != nil {
 			return err
 		}
 	}
 
-	// 3. Снимки по блоку и слоты storage (только при blockID > 0)
+	// 3. Runtime-код, изменённый с прошлой записи: исполнение блока меняет код только в памяти
+	if err := s.saveContractCodesLocked(ctx); err != nil {
+		return err
+	}
+
+	// 4. Снимки по блоку и слоты storage (только при blockID > 0)
 	if blockID > 0 {
-		for addr := range s.touchedInBlock {
-			balanceWei := "0"
-			if bm, ok := s.balances[addr]; ok && bm != nil {
-				if b, ok := bm[GasSymbol]; ok && b != nil {
-					balanceWei = b.String()
-				}
-			}
-			nonce := s.nonces[addr]
-			_, err := s.pool.Exec(ctx, `
-				INSERT INTO account_states (block_id, address, nonce, balance_gnd, storage_root)
-				VALUES ($1, $2, $3, $4, NULL)
-				ON CONFLICT (block_id, address) DO UPDATE SET
-					nonce = $3, balance_gnd = $4`,
-				blockID, string(addr), nonce, balanceWei)
-			if err != nil {
-				return err
-			}
+		if err := s.saveAccountStatesLocked(ctx, blockID); err != nil {
+			return err
 		}
 		for _, sc := range s.storageChanges {
 			if len(sc.Key) == 0 || len(sc.Value) == 0 {
@@ -693,7 +730,113 @@ func (s *State) SaveToDB(blockID int64) error {
 	return nil
 }
 
-// LoadFromDB загружает состояние из БД. Сначала нативные балансы (native_balances), затем контрактные токены (token_balances JOIN tokens).
+// saveAccountStatesLocked пишет в account_states блока полные листы дерева состояния (nonce, балансы всех символов,
+// хеш кода, корень storage) для аккаунтов, лист которых изменился с прошлой записи или которые затронуты в блоке;
+// опустевший аккаунт записывается пустой строкой. По последним строкам на блок N и contract_storage восстанавливается
+// состояние после блока N для доказательств. Вызывать при удержанном s.mutex (Lock).
+func (s *State) saveAccountStatesLocked(ctx context.Context, blockID int64) error {
+	st := buildStateTrie(s.nonces, s.balances, s.codes, s.storage)
+	saved := make(map[string]trie.Hash, len(st.leaves))
+	addresses := make(map[string]struct{}, len(st.leaves))
+	for addr := range st.leaves {
+		addresses[addr] = struct{}{}
+	}
+	for addr := range s.savedLeaves {
+		addresses[addr] = struct{}{}
+	}
+	for addr := range s.touchedInBlock {
+		addresses[string(addr)] = struct{}{}
+	}
+	for addr := range addresses {
+		leaf := st.leaves[addr]
+		if leaf == nil {
+			leaf = &AccountLeaf{}
+		}
+		hash := trie.Key(leaf.Encode())
+		if st.leaves[addr] != nil {
+			saved[addr] = hash
+		}
+		prev, ok := s.savedLeaves[addr]
+		if !ok {
+			prev = trie.Key(nil)
+		}
+		if _, touched := s.touchedInBlock[types.Address(addr)]; s.savedLeaves != nil && !touched && prev == hash {
+			continue
+		}
+		balanceGnd := "0"
+		balances := make(map[string]string, len(leaf.Balances))
+		for sym, b := range leaf.Balances {
+			if b != nil && b.Sign() != 0 {
+				balances[sym] = b.String()
+			}
+		}
+		if b, ok := balances[GasSymbol]; ok {
+			balanceGnd = b
+		}
+		balancesJSON, err := json.Marshal(balances)
+		if err != nil {
+			return err
+		}
+		var codeHash, storageRoot []byte
+		if leaf.CodeHash != trie.Empty {
+			codeHash = leaf.CodeHash[:]
+		}
+		if leaf.StorageRoot != trie.Empty {
+			storageRoot = leaf.StorageRoot[:]
+		}
+		if _, err := s.pool.Exec(ctx, `
+			INSERT INTO account_states (block_id, address, nonce, balance_gnd, balances, code_hash, storage_root)
+			VALUES ($1, $2, $3, $4, $5::jsonb, $6, $7)
+			ON CONFLICT (block_id, address) DO UPDATE SET
+				nonce = EXCLUDED.nonce, balance_gnd = EXCLUDED.balance_gnd, balances = EXCLUDED.balances,
+				code_hash = EXCLUDED.code_hash, storage_root = EXCLUDED.storage_root`,
+			blockID, addr, leaf.Nonce, balanceGnd, string(balancesJSON), codeHash, storageRoot); err != nil {
+			s.savedLeaves = nil
+			return err
+		}
+	}
+	s.savedLeaves = saved
+	return nil
+}
+
+// saveBalanceLocked записывает баланс адреса по символу: GND/GANI — в native_balances, балансы токенов
+// (и GND/GANI в режиме контрактов) — в token_balances. Вызывать при удержанном s.mutex.
+func (s *State) saveBalanceLocked(ctx context.Context, address types.Address, symbol string, balance *big.Int) error {
+	if contractAddr, ok := s.tokenBalanceContractLocked(symbol); ok {
+		_, err := s.pool.Exec(ctx, `
+			INSERT INTO token_balances (token_id, address, balance)
+			SELECT t.id, $2, $3 FROM tokens t LEFT JOIN contracts c ON c.id = t.contract_id
+			WHERE t.symbol = $1 AND ($4::text = '' OR c.address = $4)
+			ON CONFLICT (token_id, address) DO UPDATE SET balance = EXCLUDED.balance`,
+			symbol, string(address), balance.String(), contractAddr)
+		return err
+	}
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO native_balances (address, symbol, balance, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (address, symbol) DO UPDATE
+		SET balance = $3, updated_at = CURRENT_TIMESTAMP`, address, symbol, balance.String())
+	return err
+}
+
+// saveAccountLocked записывает в accounts nonce и баланс GND адреса. Вызывать при удержанном s.mutex.
+func (s *State) saveAccountLocked(ctx context.Context, address types.Address) error {
+	balanceGnd := "0"
+	if b := s.balances[address][GasSymbol]; b != nil {
+		balanceGnd = b.String()
+	}
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO accounts (address, nonce, balance_gnd, is_contract)
+		VALUES ($1, $2, $3, FALSE)
+		ON CONFLICT (address) DO UPDATE SET
+			nonce = EXCLUDED.nonce,
+			balance_gnd = EXCLUDED.balance_gnd`,
+		string(address), s.nonces[address], balanceGnd)
+	return err
+}
+
+// LoadFromDB загружает полное состояние из БД: нативные балансы (native_balances), балансы токенов (token_balances JOIN tokens),
+// nonce, runtime-код и storage контрактов. Всё это входит в state_root, поэтому загружается целиком, а не по обращению.
 func (s *State) LoadFromDB(ctx context.Context) error {
 	if s.pool == nil {
 		return errors.New("database pool not set")
@@ -729,11 +872,13 @@ func (s *State) LoadFromDB(ctx context.Context) error {
 	}
 	rowsNative.Close()
 
-	// 2. Загружаем балансы контрактных токенов (token_balances JOIN tokens); нативные не перезаписываем
+	// 2. Загружаем балансы контрактных токенов (token_balances JOIN tokens); GND/GANI — только в режиме контрактов
+	// и только токена контракта из конфигурации, иначе они уже загружены из native_balances
 	rows, err := s.pool.Query(ctx, `
-		SELECT tb.address, t.symbol, tb.balance
+		SELECT tb.address, t.symbol, tb.balance, COALESCE(c.address, '')
 		FROM token_balances tb
-		JOIN tokens t ON t.id = tb.token_id`)
+		JOIN tokens t ON t.id = tb.token_id
+		LEFT JOIN contracts c ON c.id = t.contract_id`)
 	if err != nil {
 		return err
 	}
@@ -743,11 +888,12 @@ func (s *State) LoadFromDB(ctx context.Context) error {
 		var address types.Address
 		var symbol string
 		var balanceStr string
-		if err := rows.Scan(&address, &symbol, &balanceStr); err != nil {
+		var tokenContract string
+		if err := rows.Scan(&address, &symbol, &balanceStr, &tokenContract); err != nil {
 			return err
 		}
-		if IsNativeSymbol(symbol) {
-			continue // уже загружены из native_balances
+		if contractAddr, ok := s.tokenBalanceContractLocked(symbol); !ok || (contractAddr != "" && contractAddr != tokenContract) {
+			continue
 		}
 		balance := new(big.Int)
 		balance.SetString(balanceStr, 10)
@@ -775,68 +921,130 @@ func (s *State) LoadFromDB(ctx context.Context) error {
 		s.nonces[address] = nonce
 	}
 
+	// 4. Загружаем runtime-код и storage контрактов: они входят в state_root
+	if err := s.loadContractsState(ctx); err != nil {
+		return err
+	}
+
 	// accounts.balance не синхронизируем из token_balances (отключено по требованию)
 
 	return nil
 }
 
-// ApplyExecutionResult применяет результат выполнения контракта. Газ списывается в GND, кроме случая системного владельца контракта.
-// Если задан fee_collector_address, списанный газ зачисляется на него.
+// ApplyExecutionResult применяет результат выполнения контракта. Комиссия (газ × цена газа) списывается в GND (ChargeGas),
+// кроме случая системного владельца контракта.
+// Фактические газ и комиссия записываются в tx.GasUsed и tx.Fee. Если изменение не применяется, результат откатывается целиком.
 func (s *State) ApplyExecutionResult(tx *Transaction, result *types.ExecutionResult) error {
 	var skipGas bool
-	var feeCollector string
 	s.mutex.RLock()
 	gndself, pool := s.gndselfAddress, s.pool
-	feeCollector = s.feeCollectorAddress
 	s.mutex.RUnlock()
-	if gndself != "" && pool != nil && tx.Recipient != "" {
+	// Владелец создаваемого контракта известен только ноде, принявшей деплой: газ деплоя списывается всегда
+	if gndself != "" && pool != nil && tx.Recipient != "" && !tx.IsDeploy() {
 		var owner string
 		if err := pool.QueryRow(context.Background(), `SELECT owner FROM contracts WHERE address = $1`, tx.Recipient).Scan(&owner); err == nil {
 			skipGas = strings.TrimSpace(owner) == gndself
 		}
 	}
 
-	gasUsed := new(big.Int).SetUint64(result.GasUsed)
-	if !skipGas && gasUsed.Sign() > 0 {
-		if err := s.SubBalance(types.Address(tx.Sender), GasSymbol, gasUsed); err != nil {
-			return err
-		}
-		if feeCollector != "" {
-			if err := s.AddBalance(types.Address(feeCollector), GasSymbol, gasUsed); err != nil {
-				s.AddBalance(types.Address(tx.Sender), GasSymbol, gasUsed)
-				return err
-			}
-			s.MarkTouched(types.Address(feeCollector))
-		}
+	gasCharged := result.GasUsed
+	if skipGas {
+		gasCharged = 0
+	}
+	charge, err := s.ChargeGas(tx, gasCharged)
+	if err != nil {
+		return err
 	}
 
+	// Изменения применяются по одному; при ошибке применённые откатываются в обратном порядке и комиссия возвращается,
+	// чтобы в состоянии не осталось частично применённого результата
+	var undo []func()
+	fail := func(err error) error {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+		s.refundGas(charge)
+		return err
+	}
 	for _, change := range result.StateChanges {
+		address := change.Address
 		switch change.Type {
 		case types.ChangeTypeBalance:
-			if err := s.AddBalance(types.Address(change.Address), change.Symbol, change.Amount); err != nil {
-				if !skipGas && gasUsed.Sign() > 0 {
-					s.AddBalance(types.Address(tx.Sender), GasSymbol, gasUsed)
-					if feeCollector != "" {
-						s.SubBalance(types.Address(feeCollector), GasSymbol, gasUsed)
-					}
-				}
-				return err
+			// Отрицательная сумма — списание (перевод value внутри исполнения контракта)
+			apply, revert := s.AddBalance, s.SubBalance
+			amount := change.Amount
+			if amount != nil && amount.Sign() < 0 {
+				apply, revert = s.SubBalance, s.AddBalance
+				amount = new(big.Int).Neg(amount)
+			}
+			symbol := change.Symbol
+			if err := apply(types.Address(address), symbol, amount); err != nil {
+				return fail(err)
 			}
+			undo = append(undo, func() { revert(types.Address(address), symbol, amount) })
 		case types.ChangeTypeStorage:
+			key := string(change.Key)
 			s.mutex.Lock()
 			if s.storageChanges == nil {
 				s.storageChanges = make([]ContractStorageChange, 0, 8)
 			}
+			recorded := len(s.storageChanges)
 			s.storageChanges = append(s.storageChanges, ContractStorageChange{
-				Address: change.Address,
+				Address: address,
 				Key:     change.Key,
 				Value:   change.Value,
 			})
+			slots := s.contractStorageLocked(address)
+			prev, had := slots[key]
+			slots[key] = change.Value
 			s.mutex.Unlock()
-			s.MarkTouched(types.Address(change.Address))
+			s.MarkTouched(types.Address(address))
+			undo = append(undo, func() {
+				s.mutex.Lock()
+				defer s.mutex.Unlock()
+				if had {
+					s.contractStorageLocked(address)[key] = prev
+				} else {
+					delete(s.contractStorageLocked(address), key)
+				}
+				s.storageChanges = s.storageChanges[:recorded]
+			})
+		case types.ChangeTypeNonce:
+			s.mutex.RLock()
+			prev, had := s.nonces[types.Address(address)]
+			s.mutex.RUnlock()
+			s.SetNonce(types.Address(address), change.Amount.Uint64())
+			s.MarkTouched(types.Address(address))
+			undo = append(undo, func() {
+				s.mutex.Lock()
+				defer s.mutex.Unlock()
+				if had {
+					s.nonces[types.Address(address)] = prev
+				} else {
+					delete(s.nonces, types.Address(address))
+				}
+			})
+		case types.ChangeTypeCode:
+			s.mutex.RLock()
+			prev, had := s.codes[address]
+			s.mutex.RUnlock()
+			if err := s.SetContractCode(address, change.Value); err != nil {
+				return fail(err)
+			}
+			undo = append(undo, func() {
+				if had {
+					s.SetContractCode(address, prev)
+					return
+				}
+				s.mutex.Lock()
+				delete(s.codes, address)
+				s.mutex.Unlock()
+			})
 		}
 	}
 
+	tx.GasUsed = result.GasUsed
+	tx.Fee = charge.Total
 	s.IncrementNonce(types.Address(tx.Sender))
 	s.MarkTouched(types.Address(tx.Sender))
 	if tx.Recipient != "" {
@@ -845,6 +1053,76 @@ func (s *State) ApplyExecutionResult(tx *Transaction, result *types.ExecutionRes
 	return nil
 }
 
+// FeeCharge — комиссия за газ, списанная с отправителя, и её распределение.
+type FeeCharge struct {
+	Sender   types.Address
+	Total    *big.Int // газ × цена газа
+	Burned   *big.Int // газ × base fee: в казну или сжигается
+	Tip      *big.Int // чаевые: предлагающему блок, вне блока — сборщику комиссий
+	Treasury string   // получатель Burned (пусто — сожжено)
+	TipTo    string   // получатель Tip (пусто — сожжено)
+}
+
+// ChargeGas списывает в GND с отправителя gasUsed × цену газа при текущей base fee (Transaction.GasPriceAt):
+// gasUsed × base fee зачисляется в казну (без казны сжигается), остаток — чаевые предлагающему блок.
+// Цена газа ниже base fee — ошибка.
+func (s *State) ChargeGas(tx *Transaction, gasUsed uint64) (*FeeCharge, error) {
+	s.mutex.RLock()
+	baseFee, treasury, tipTo := s.baseFee, s.treasuryAddress, s.feeRecipient
+	if tipTo == "" {
+		tipTo = s.feeCollectorAddress
+	}
+	s.mutex.RUnlock()
+
+	gas := new(big.Int).SetUint64(gasUsed)
+	price := tx.GasPriceAt(baseFee)
+	charge := &FeeCharge{Sender: tx.Sender, Total: new(big.Int).Mul(price, gas), Burned: new(big.Int), Treasury: treasury, TipTo: tipTo}
+	if gasUsed == 0 {
+		charge.Tip = new(big.Int)
+		return charge, nil
+	}
+	if baseFee != nil {
+		if price.Cmp(baseFee) < 0 {
+			return nil, fmt.Errorf("цена газа %s ниже base fee %s", price, baseFee)
+		}
+		charge.Burned.Mul(baseFee, gas)
+	}
+	charge.Tip = new(big.Int).Sub(charge.Total, charge.Burned)
+	if err := s.SubBalance(tx.Sender, GasSymbol, charge.Total); err != nil {
+		return nil, err
+	}
+	if treasury != "" && charge.Burned.Sign() > 0 {
+		if err := s.AddBalance(types.Address(treasury), GasSymbol, charge.Burned); err != nil {
+			s.AddBalance(tx.Sender, GasSymbol, charge.Total)
+			return nil, err
+		}
+		s.MarkTouched(types.Address(treasury))
+	}
+	if tipTo != "" && charge.Tip.Sign() > 0 {
+		if err := s.AddBalance(types.Address(tipTo), GasSymbol, charge.Tip); err != nil {
+			charge.Tip, charge.TipTo = new(big.Int), ""
+			s.refundGas(charge)
+			return nil, err
+		}
+		s.MarkTouched(types.Address(tipTo))
+	}
+	return charge, nil
+}
+
+// refundGas возвращает отправителю комиссию, списанную ChargeGas (при ошибке применения транзакции).
+func (s *State) refundGas(charge *FeeCharge) {
+	if charge == nil || charge.Total.Sign() <= 0 {
+		return
+	}
+	s.AddBalance(charge.Sender, GasSymbol, charge.Total)
+	if charge.Treasury != "" && charge.Burned.Sign() > 0 {
+		s.SubBalance(types.Address(charge.Treasury), GasSymbol, charge.Burned)
+	}
+	if charge.TipTo != "" && charge.Tip.Sign() > 0 {
+		s.SubBalance(types.Address(charge.TipTo), GasSymbol, charge.Tip)
+	}
+}
+
 // LoadTokenBalances loads token balances for a given address
 func (s *State) LoadTokenBalances(address types.Address) map[string]*big.Int {
 	rows, err := s.pool.Query(
@@ -0,0 +1,132 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/state_trie.go — дерево состояния для state_root: аккаунты (nonce, балансы по символам, код) и storage контрактов.
+
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"sort"
+
+	"GND/core/trie"
+	"GND/types"
+)
+
+// AccountLeaf — значение листа аккаунта в дереве состояния. StorageRoot — корень дерева слотов storage контракта.
+type AccountLeaf struct {
+	Nonce       uint64
+	Balances    map[string]*big.Int
+	CodeHash    trie.Hash
+	StorageRoot trie.Hash
+}
+
+// AccountKey — ключ аккаунта в дереве состояния (sha256 адреса).
+func AccountKey(address string) trie.Hash { return trie.Key([]byte(address)) }
+
+// StorageKey — ключ слота в дереве storage контракта (sha256 ключа слота).
+func StorageKey(slot []byte) trie.Hash { return trie.Key(slot) }
+
+// Encode кодирует аккаунт для листа: nonce, число ненулевых балансов, пары (символ, сумма) по возрастанию символа,
+// хеш кода и корень storage. Пустой аккаунт (без nonce, балансов, кода и storage) кодируется в nil — листа нет.
+func (a *AccountLeaf) Encode() []byte {
+	symbols := make([]string, 0, len(a.Balances))
+	for sym, b := range a.Balances {
+		if b != nil && b.Sign() != 0 {
+			symbols = append(symbols, sym)
+		}
+	}
+	if a.Nonce == 0 && len(symbols) == 0 && a.CodeHash == trie.Empty && a.StorageRoot == trie.Empty {
+		return nil
+	}
+	sort.Strings(symbols)
+	var buf bytes.Buffer
+	buf.Write(binary.BigEndian.AppendUint64(nil, a.Nonce))
+	buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(symbols))))
+	for _, sym := range symbols {
+		amount := a.Balances[sym].Bytes()
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(len(sym))))
+		buf.WriteString(sym)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(len(amount))))
+		buf.Write(amount)
+	}
+	buf.Write(a.CodeHash[:])
+	buf.Write(a.StorageRoot[:])
+	return buf.Bytes()
+}
+
+// stateTrie — дерево аккаунтов, деревья storage контрактов и листы аккаунтов по адресу.
+type stateTrie struct {
+	accounts *trie.Tree
+	storage  map[string]*trie.Tree
+	leaves   map[string]*AccountLeaf
+}
+
+// buildStateTrie строит дерево состояния по nonce, балансам, runtime-коду и слотам storage (нулевые слоты не входят).
+func buildStateTrie(nonces map[types.Address]uint64, balances map[types.Address]map[string]*big.Int,
+	codes map[string][]byte, storage map[string]map[string][]byte) *stateTrie {
+	codeHashes := make(map[string]trie.Hash, len(codes))
+	for addr, code := range codes {
+		if len(code) > 0 {
+			codeHashes[addr] = sha256.Sum256(code)
+		}
+	}
+	return buildStateTrieFromCodeHashes(nonces, balances, codeHashes, storage)
+}
+
+// buildStateTrieFromCodeHashes — buildStateTrie по хешам runtime-кода: история аккаунтов в account_states хранит хеш, а не код.
+func buildStateTrieFromCodeHashes(nonces map[types.Address]uint64, balances map[types.Address]map[string]*big.Int,
+	codeHashes map[string]trie.Hash, storage map[string]map[string][]byte) *stateTrie {
+	st := &stateTrie{accounts: trie.New(), storage: make(map[string]*trie.Tree, len(storage)), leaves: make(map[string]*AccountLeaf)}
+	for addr, slots := range storage {
+		t := trie.New()
+		for k, v := range slots {
+			if !isZeroSlot(v) {
+				t.Set(StorageKey([]byte(k)), v)
+			}
+		}
+		if t.Len() > 0 {
+			st.storage[addr] = t
+		}
+	}
+
+	addresses := make(map[string]struct{}, len(nonces)+len(balances)+len(codeHashes))
+	for a := range nonces {
+		addresses[string(a)] = struct{}{}
+	}
+	for a := range balances {
+		addresses[string(a)] = struct{}{}
+	}
+	for a := range codeHashes {
+		addresses[a] = struct{}{}
+	}
+	for a := range st.storage {
+		addresses[a] = struct{}{}
+	}
+	for addr := range addresses {
+		leaf := &AccountLeaf{Nonce: nonces[types.Address(addr)], Balances: balances[types.Address(addr)], CodeHash: codeHashes[addr]}
+		if t := st.storage[addr]; t != nil {
+			leaf.StorageRoot = t.Root()
+		}
+		if value := leaf.Encode(); value != nil {
+			st.leaves[addr] = leaf
+			st.accounts.Set(AccountKey(addr), value)
+		}
+	}
+	return st
+}
+
+func stateRoot(nonces map[types.Address]uint64, balances map[types.Address]map[string]*big.Int,
+	codes map[string][]byte, storage map[string]map[string][]byte) string {
+	return buildStateTrie(nonces, balances, codes, storage).accounts.Root().String()
+}
+
+func isZeroSlot(v []byte) bool {
+	for _, b := range v {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"GND/core/trie"
+	"GND/types"
+)
+
+func TestStateRoot_CoversTokensCodeAndStorage(t *testing.T) {
+	st := NewState()
+	holder := types.Address("GN_root_holder")
+	if err := st.AddBalance(holder, GasSymbol, big.NewInt(10)); err != nil {
+		t.Fatal(err)
+	}
+	root := st.RootHash()
+
+	if err := st.AddBalance(holder, "GANI", big.NewInt(5)); err != nil {
+		t.Fatal(err)
+	}
+	if st.RootHash() == root {
+		t.Fatal("баланс GANI должен входить в state_root")
+	}
+	root = st.RootHash()
+
+	if err := st.SetContractCode("GN_root_contract", []byte{0x60, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if st.RootHash() == root {
+		t.Fatal("код контракта должен входить в state_root")
+	}
+	root = st.RootHash()
+
+	slot := make([]byte, 32)
+	st.mutex.Lock()
+	st.contractStorageLocked("GN_root_contract")[string(slot)] = []byte{0x01}
+	st.mutex.Unlock()
+	if st.RootHash() == root {
+		t.Fatal("слот storage должен входить в state_root")
+	}
+	root = st.RootHash()
+
+	// чтения (в том числе view-вызовы) состояние не дополняют
+	st.GetStorageSlot("GN_root_other", slot)
+	st.GetContractCode("GN_root_other")
+	st.GetBalance(holder, "GANI")
+	if st.RootHash() != root {
+		t.Fatal("чтение кода, слотов и балансов не должно менять state_root")
+	}
+
+	// снимок состояния даёт тот же корень, что и состояние
+	bc := NewBlockchain(&Block{Index: 0, Timestamp: time.Now(), Miner: "miner"}, nil)
+	bc.State = st
+	snap, err := bc.ExportSnapshot(bc.Genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.StateRoot != st.RootHash() {
+		t.Errorf("корень снимка %s, состояния %s", snap.StateRoot, st.RootHash())
+	}
+}
+
+// История в account_states хранит листы с хешем кода, а не сам код: корень по ним должен совпадать с корнем состояния.
+func TestStateTrie_RebuildsFromStoredLeaves(t *testing.T) {
+	st := NewState()
+	if err := st.AddBalance("GN_leaf_holder", "GANI", big.NewInt(3)); err != nil {
+		t.Fatal(err)
+	}
+	st.SetNonce("GN_leaf_holder", 2)
+	if err := st.SetContractCode("GN_leaf_contract", []byte{0x60, 0x01}); err != nil {
+		t.Fatal(err)
+	}
+	slot := make([]byte, 32)
+	st.mutex.Lock()
+	st.contractStorageLocked("GN_leaf_contract")[string(slot)] = []byte{0x07}
+	built := buildStateTrie(st.nonces, st.balances, st.codes, st.storage)
+	storage := copyStorage(st.storage)
+	st.mutex.Unlock()
+
+	nonces := make(map[types.Address]uint64)
+	balances := make(map[types.Address]map[string]*big.Int)
+	codeHashes := make(map[string]trie.Hash)
+	for addr, leaf := range built.leaves {
+		nonces[types.Address(addr)] = leaf.Nonce
+		balances[types.Address(addr)] = leaf.Balances
+		codeHashes[addr] = leaf.CodeHash
+	}
+	rebuilt := buildStateTrieFromCodeHashes(nonces, balances, codeHashes, storage)
+	if got := rebuilt.accounts.Root().String(); got != st.RootHash() {
+		t.Fatalf("корень по листам %s, состояния %s", got, st.RootHash())
+	}
+	if leaf := rebuilt.leaves["GN_leaf_contract"]; leaf == nil || leaf.StorageRoot == trie.Empty {
+		t.Error("лист контракта должен содержать корень storage")
+	}
+}
+
+func TestAddBlock_VerifiesImportedStateRoot(t *testing.T) {
+	genesis := &Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: DefaultBlockGasLimit, Consensus: "poa", Status: BlockStatusFinalized}
+	genesis.Hash = genesis.CalculateHash()
+	wallet := newTestWallet(t)
+	sender, recipient := types.Address(wallet.Address), types.Address("GN_root_recipient")
+	newTx := func() *Transaction {
+		return signTestTx(t, wallet, &Transaction{Recipient: recipient, Value: big.NewInt(100), GasLimit: TxGas, Symbol: GasSymbol})
+	}
+	newChain := func() *Blockchain {
+		g := *genesis
+		bc := NewBlockchain(&g, nil)
+		if err := bc.State.AddBalance(sender, GasSymbol, big.NewInt(1_000_000)); err != nil {
+			t.Fatal(err)
+		}
+		return bc
+	}
+
+	// собственный блок исполняется до подписи: state_root входит в заголовок и хеш
+	producer := newChain()
+	SetState(producer.State.(*State))
+	defer SetState(nil)
+	produced := executeTestBlock(t, producer, childBlock(producer.Genesis, "miner", newTx()))
+	if err := producer.AddBlock(produced); err != nil {
+		t.Fatal(err)
+	}
+	if produced.StateRoot != producer.State.(*State).RootHash() || produced.Hash != produced.CalculateHash() {
+		t.Fatalf("state_root %q не зафиксирован в заголовке блока", produced.StateRoot)
+	}
+
+	importer := newChain()
+	SetState(importer.State.(*State))
+	forged := *produced
+	forged.ID, forged.Transactions = 0, []*Transaction{newTx()}
+	forged.StateRoot = importer.State.(*State).RootHash() // корень до блока
+	forged.Hash = forged.CalculateHash()
+	if err := importer.AddBlock(&forged); err == nil {
+		t.Fatal("блок со state_root, не совпадающим с исполнением, должен отклоняться")
+	}
+	missing := forged
+	missing.StateRoot = ""
+	missing.Hash = missing.CalculateHash()
+	if err := importer.AddBlock(&missing); err == nil {
+		t.Fatal("блок без state_root должен отклоняться")
+	}
+	if importer.Height() != 0 || importer.State.GetBalance(recipient, GasSymbol).Sign() != 0 || importer.State.GetNonce(sender) != 0 {
+		t.Fatalf("отклонённый блок не должен менять состояние: высота %d, баланс получателя %s",
+			importer.Height(), importer.State.GetBalance(recipient, GasSymbol))
+	}
+
+	imported := *produced
+	imported.ID, imported.Transactions = 0, []*Transaction{newTx()}
+	if err := importer.AddBlock(&imported); err != nil {
+		t.Fatal(err)
+	}
+	if imported.Hash != produced.Hash || importer.State.(*State).RootHash() != produced.StateRoot {
+		t.Errorf("импортированный блок %s, ожидался %s", imported.Hash, produced.Hash)
+	}
+}
@@ -0,0 +1,419 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/sync.go — синхронизация цепи с пирами: статус загрузки блоков и снимок состояния для быстрой синхронизации.
+
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"GND/types"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Режимы синхронизации (p2p.sync_mode в config.json).
+const (
+	SyncModeFull = "full" // все блоки от вершины цепи с исполнением транзакций
+	SyncModeFast = "fast" // снимок состояния на финализированной высоте пира, затем блоки после неё
+)
+
+// SyncStatus — состояние синхронизации ноды с пирами (для /api/v1/health).
+type SyncStatus struct {
+	Mode          string `json:"mode"`
+	Syncing       bool   `json:"syncing"`
+	Stage         string `json:"stage"` // idle, snapshot, headers, bodies
+	StartingBlock uint64 `json:"starting_block"`
+	CurrentBlock  uint64 `json:"current_block"`
+	HighestBlock  uint64 `json:"highest_block"`         // наибольшая высота среди пиров
+	PivotBlock    uint64 `json:"pivot_block,omitempty"` // высота загруженного снимка состояния (fast)
+	Peer          string `json:"peer,omitempty"`        // пир, с которого идёт загрузка
+	LastError     string `json:"last_error,omitempty"`  // последняя ошибка загрузки
+}
+
+// SyncReporter — источник статуса синхронизации (p2p.Node).
+type SyncReporter interface {
+	SyncStatus() SyncStatus
+}
+
+// SnapshotAccount — аккаунт снимка: nonce и балансы по символам.
+type SnapshotAccount struct {
+	Address  string              `json:"address"`
+	Nonce    uint64              `json:"nonce"`
+	Balances map[string]*big.Int `json:"balances,omitempty"`
+}
+
+// SnapshotContract — контракт снимка: runtime-код и слоты storage (hex).
+type SnapshotContract struct {
+	Address string            `json:"address"`
+	Code    string            `json:"code"`
+	Storage map[string]string `json:"storage,omitempty"`
+}
+
+// StakeSnapshot — реестр стейкинга в снимке.
+type StakeSnapshot struct {
+	Validators  []*StakeValidator   `json:"validators"`
+	Delegations []Delegation        `json:"delegations"`
+	Unbonding   []Unbonding         `json:"unbonding"`
+	Rewards     map[string]*big.Int `json:"rewards"`
+}
+
+// TotalStake возвращает суммарный стейк валидатора в снимке.
+func (s *StakeSnapshot) TotalStake(validator string) *big.Int {
+	total := big.NewInt(0)
+	for _, d := range s.Delegations {
+		if d.Validator == validator && d.Amount != nil {
+			total.Add(total, d.Amount)
+		}
+	}
+	return total
+}
+
+// StateSnapshot — состояние цепи после блока Height: аккаунты, контракты и реестр стейкинга.
+// StateRoot — корень дерева состояния снимка (аккаунты и контракты), проверяется при загрузке.
+type StateSnapshot struct {
+	Height    uint64             `json:"height"`
+	BlockHash string             `json:"block_hash"`
+	StateRoot string             `json:"state_root"`
+	Accounts  []SnapshotAccount  `json:"accounts"`
+	Contracts []SnapshotContract `json:"contracts"`
+	Stakes    *StakeSnapshot     `json:"stakes,omitempty"`
+}
+
+// Root вычисляет корень дерева состояния по аккаунтам и контрактам снимка (как State.RootHash).
+// Код и слоты, не разбираемые как hex, в корень не входят — такой снимок не совпадёт с StateRoot.
+func (snap *StateSnapshot) Root() string {
+	nonces := make(map[types.Address]uint64, len(snap.Accounts))
+	balances := make(map[types.Address]map[string]*big.Int, len(snap.Accounts))
+	for _, a := range snap.Accounts {
+		nonces[types.Address(a.Address)] = a.Nonce
+		balances[types.Address(a.Address)] = a.Balances
+	}
+	codes := make(map[string][]byte, len(snap.Contracts))
+	storage := make(map[string]map[string][]byte, len(snap.Contracts))
+	for _, c := range snap.Contracts {
+		if code, err := hex.DecodeString(c.Code); err == nil {
+			codes[c.Address] = code
+		}
+		slots := make(map[string][]byte, len(c.Storage))
+		for k, v := range c.Storage {
+			key, errKey := hex.DecodeString(k)
+			value, errValue := hex.DecodeString(v)
+			if errKey == nil && errValue == nil {
+				slots[string(key)] = value
+			}
+		}
+		storage[c.Address] = slots
+	}
+	return stateRoot(nonces, balances, codes, storage)
+}
+
+// ExportSnapshot снимает состояние цепи после блока block — вершины цепи. Вызывается из BlockListener
+// (под блокировкой цепи), чтобы между блоком и снимком состояние не менялось блоками.
+func (bc *Blockchain) ExportSnapshot(block *Block) (*StateSnapshot, error) {
+	st, ok := bc.State.(*State)
+	if !ok {
+		return nil, errors.New("снимок поддерживается только для core.State")
+	}
+	accounts, contracts, err := st.snapshotData()
+	if err != nil {
+		return nil, err
+	}
+	snap := &StateSnapshot{Height: block.Index, BlockHash: block.Hash, Accounts: accounts, Contracts: contracts}
+	snap.StateRoot = snap.Root()
+	if bc.Stakes != nil {
+		snap.Stakes = bc.Stakes.snapshot()
+	}
+	return snap, nil
+}
+
+// ImportSnapshot загружает снимок состояния в новую цепь (только генезис) и добавляет блок pivot, на котором снят снимок,
+// как финализированный. Блоки между генезисом и pivot не загружаются. Проверяются хеш pivot, корень транзакций,
+// StateRoot снимка и совпадение его со state_root блока. Снимок сохраняется в state_snapshots: из него после перезапуска
+// берётся runtime-код контрактов, которых нет в таблице contracts.
+func (bc *Blockchain) ImportSnapshot(snap *StateSnapshot, pivot *Block) error {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	st, ok := bc.State.(*State)
+	if !ok {
+		return errors.New("снимок поддерживается только для core.State")
+	}
+	if len(bc.Blocks) != 1 {
+		return fmt.Errorf("снимок загружается только в цепь из генезиса, высота %d", bc.Height())
+	}
+	switch {
+	case snap == nil || pivot == nil:
+		return errors.New("пустой снимок или блок")
+	case pivot.Index != snap.Height || pivot.Hash != snap.BlockHash:
+		return fmt.Errorf("снимок высоты %d (%s) не соответствует блоку %d (%s)", snap.Height, snap.BlockHash, pivot.Index, pivot.Hash)
+	case pivot.Index <= bc.Genesis.Index:
+		return errors.New("снимок на высоте генезиса")
+	case pivot.Hash != pivot.CalculateHash():
+		return errors.New("хеш блока снимка не совпадает")
+	case pivot.MerkleRoot != "" && pivot.MerkleRoot != ComputeMerkleRoot(pivot.Transactions):
+		return errors.New("корень транзакций блока снимка не совпадает")
+	case snap.Root() != snap.StateRoot:
+		return errors.New("state_root снимка не совпадает с его аккаунтами")
+	case pivot.StateRoot != snap.StateRoot:
+		return fmt.Errorf("state_root снимка %s не совпадает с блоком %s", snap.StateRoot, pivot.StateRoot)
+	}
+	if err := st.restoreSnapshot(snap); err != nil {
+		return err
+	}
+	if bc.Stakes != nil && snap.Stakes != nil {
+		bc.Stakes.restore(snap.Stakes)
+	}
+
+	pivot.ID, pivot.ParentID, pivot.IsOrphaned = 0, nil, false
+	pivot.Status, pivot.IsFinalized = BlockStatusFinalized, true
+	if err := bc.storeBlock(pivot); err != nil {
+		return fmt.Errorf("failed to store block: %v", err)
+	}
+	bc.storeBlockTransactions(pivot)
+	if bc.Pool != nil {
+		ctx := context.Background()
+		if err := st.SaveToDB(int64(pivot.ID)); err != nil {
+			return fmt.Errorf("сохранение состояния снимка: %w", err)
+		}
+		if bc.Stakes != nil {
+			if err := bc.Stakes.SaveToDB(ctx, bc.Pool); err != nil {
+				return fmt.Errorf("сохранение реестра стейкинга снимка: %w", err)
+			}
+		}
+		data, err := json.Marshal(snap)
+		if err != nil {
+			return err
+		}
+		if _, err := bc.Pool.Exec(ctx, `
+			INSERT INTO state_snapshots (height, block_hash, state_root, data)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (height) DO UPDATE SET block_hash = EXCLUDED.block_hash, state_root = EXCLUDED.state_root, data = EXCLUDED.data`,
+			snap.Height, snap.BlockHash, snap.StateRoot, data); err != nil {
+			return fmt.Errorf("сохранение снимка в state_snapshots: %w", err)
+		}
+	}
+	st.ClearTouched()
+	bc.saveCheckpointLocked(pivot)
+
+	bc.Blocks = append(bc.Blocks, pivot)
+	if bc.Mempool != nil {
+		bc.Mempool.Reset()
+	}
+	return nil
+}
+
+// loadSnapshotCodes кладёт в кэш runtime-код контрактов из последнего загруженного снимка (state_snapshots),
+// если кода нет в contracts.runtime_code — контракт создан до высоты снимка на другой ноде.
+func (s *State) loadSnapshotCodes(ctx context.Context) error {
+	if s.pool == nil {
+		return nil
+	}
+	var data []byte
+	err := s.pool.QueryRow(ctx, `SELECT data FROM state_snapshots ORDER BY height DESC LIMIT 1`).Scan(&data)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	var snap StateSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("снимок в state_snapshots: %w", err)
+	}
+	for _, c := range snap.Contracts {
+		if code, _ := s.GetContractCode(c.Address); len(code) > 0 {
+			continue
+		}
+		if code, err := hex.DecodeString(c.Code); err == nil && len(code) > 0 {
+			s.mutex.Lock()
+			s.codes[c.Address] = code
+			s.mutex.Unlock()
+		}
+	}
+	return nil
+}
+
+// snapshotData возвращает аккаунты (по адресу) и контракты состояния. Контракты — из кэша кода и contracts.runtime_code,
+// storage — последние значения слотов (кэш и contract_storage).
+func (s *State) snapshotData() ([]SnapshotAccount, []SnapshotContract, error) {
+	s.mutex.RLock()
+	pool := s.pool
+	s.mutex.RUnlock()
+	dbCodes := make(map[string][]byte)
+	if pool != nil {
+		rows, err := pool.Query(context.Background(), `SELECT address, runtime_code FROM contracts WHERE runtime_code IS NOT NULL`)
+		if err != nil {
+			return nil, nil, fmt.Errorf("загрузка кода контрактов: %w", err)
+		}
+		for rows.Next() {
+			var addr string
+			var code []byte
+			if err := rows.Scan(&addr, &code); err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+			if len(code) > 0 {
+				dbCodes[addr] = code
+			}
+		}
+		rows.Close()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	seen := make(map[types.Address]struct{}, len(s.nonces)+len(s.balances))
+	for a := range s.nonces {
+		seen[a] = struct{}{}
+	}
+	for a := range s.balances {
+		seen[a] = struct{}{}
+	}
+	addresses := make([]string, 0, len(seen))
+	for a := range seen {
+		addresses = append(addresses, string(a))
+	}
+	sort.Strings(addresses)
+	accounts := make([]SnapshotAccount, 0, len(addresses))
+	for _, addr := range addresses {
+		acc := SnapshotAccount{Address: addr, Nonce: s.nonces[types.Address(addr)]}
+		if bm := s.balances[types.Address(addr)]; len(bm) > 0 {
+			acc.Balances = make(map[string]*big.Int, len(bm))
+			for sym, b := range bm {
+				if b != nil {
+					acc.Balances[sym] = new(big.Int).Set(b)
+				}
+			}
+		}
+		accounts = append(accounts, acc)
+	}
+
+	for addr, code := range s.codes {
+		dbCodes[addr] = code
+	}
+	contracts := make([]SnapshotContract, 0, len(dbCodes))
+	for _, addr := range sortedKeys(dbCodes) {
+		c := SnapshotContract{Address: addr, Code: hex.EncodeToString(dbCodes[addr])}
+		if slots := s.storage[addr]; len(slots) > 0 {
+			c.Storage = make(map[string]string, len(slots))
+			for k, v := range slots {
+				c.Storage[hex.EncodeToString([]byte(k))] = hex.EncodeToString(v)
+			}
+		}
+		contracts = append(contracts, c)
+	}
+	return accounts, contracts, nil
+}
+
+// restoreSnapshot заменяет состояние снимком. Все аккаунты помечаются затронутыми, а слоты — изменёнными,
+// чтобы SaveToDB записал их в account_states и contract_storage блока снимка.
+func (s *State) restoreSnapshot(snap *StateSnapshot) error {
+	codes := make(map[string][]byte, len(snap.Contracts))
+	storage := make(map[string]map[string][]byte, len(snap.Contracts))
+	var changes []ContractStorageChange
+	for _, c := range snap.Contracts {
+		code, err := hex.DecodeString(c.Code)
+		if err != nil {
+			return fmt.Errorf("код контракта %s: %w", c.Address, err)
+		}
+		codes[c.Address] = code
+		slots := make(map[string][]byte, len(c.Storage))
+		for k, v := range c.Storage {
+			key, errKey := hex.DecodeString(k)
+			value, errValue := hex.DecodeString(v)
+			if errKey != nil || errValue != nil || len(key) != 32 {
+				return fmt.Errorf("слот storage контракта %s: %q", c.Address, k)
+			}
+			slots[string(key)] = value
+			changes = append(changes, ContractStorageChange{Address: c.Address, Key: key, Value: value})
+		}
+		storage[c.Address] = slots
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.balances = make(map[types.Address]map[string]*big.Int, len(snap.Accounts))
+	s.nonces = make(map[types.Address]uint64, len(snap.Accounts))
+	s.touchedInBlock = make(map[types.Address]struct{}, len(snap.Accounts))
+	for _, a := range snap.Accounts {
+		addr := types.Address(a.Address)
+		s.nonces[addr] = a.Nonce
+		if len(a.Balances) > 0 {
+			s.balances[addr] = make(map[string]*big.Int, len(a.Balances))
+			for sym, b := range a.Balances {
+				if b != nil {
+					s.balances[addr][sym] = new(big.Int).Set(b)
+				}
+			}
+		}
+		s.touchedInBlock[addr] = struct{}{}
+	}
+	s.codes = codes
+	s.storage = storage
+	s.storageChanges = changes
+	s.savedLeaves = nil
+	s.codeChanges = nil
+	return nil
+}
+
+// snapshot возвращает копию реестра стейкинга.
+func (l *StakeLedger) snapshot() *StakeSnapshot {
+	validators := l.Validators()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	snap := &StakeSnapshot{Validators: validators, Rewards: make(map[string]*big.Int, len(l.rewards))}
+	for _, v := range sortedKeys(l.stakes) {
+		for _, d := range sortedKeys(l.stakes[v]) {
+			snap.Delegations = append(snap.Delegations, Delegation{Validator: v, Delegator: d, Amount: new(big.Int).Set(l.stakes[v][d])})
+		}
+	}
+	for _, u := range l.unbonding {
+		snap.Unbonding = append(snap.Unbonding, Unbonding{Validator: u.Validator, Delegator: u.Delegator, Amount: new(big.Int).Set(u.Amount), ReleaseHeight: u.ReleaseHeight})
+	}
+	for addr, r := range l.rewards {
+		snap.Rewards[addr] = new(big.Int).Set(r)
+	}
+	return snap
+}
+
+// restore заменяет реестр снимком; реестр сохраняется при следующем SaveToDB.
+func (l *StakeLedger) restore(snap *StakeSnapshot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.validators = make(map[string]*stakeValidator, len(snap.Validators))
+	for _, v := range snap.Validators {
+		rewards := big.NewInt(0)
+		if v.Rewards != nil {
+			rewards.Set(v.Rewards)
+		}
+		l.validators[v.Address] = &stakeValidator{pubKey: v.PubKey, commission: v.CommissionPercent, rewards: rewards}
+	}
+	l.stakes = make(map[string]map[string]*big.Int)
+	for _, d := range snap.Delegations {
+		if d.Amount == nil {
+			continue
+		}
+		if l.stakes[d.Validator] == nil {
+			l.stakes[d.Validator] = make(map[string]*big.Int)
+		}
+		l.stakes[d.Validator][d.Delegator] = new(big.Int).Set(d.Amount)
+	}
+	l.unbonding = nil
+	for _, u := range snap.Unbonding {
+		if u.Amount != nil {
+			l.unbonding = append(l.unbonding, &Unbonding{Validator: u.Validator, Delegator: u.Delegator, Amount: new(big.Int).Set(u.Amount), ReleaseHeight: u.ReleaseHeight})
+		}
+	}
+	l.rewards = make(map[string]*big.Int, len(snap.Rewards))
+	for addr, r := range snap.Rewards {
+		if r != nil {
+			l.rewards[addr] = new(big.Int).Set(r)
+		}
+	}
+	l.dirty = true
+}
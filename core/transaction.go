@@ -9,6 +9,7 @@ import (
 	"crypto/elliptic"
 	"crypto/sha256"
 	"database/sql"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -21,6 +22,7 @@ import (
 	"GND/core/crypto"
 	"GND/types"
 
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -41,9 +43,14 @@ const (
 	TxTypeTokenUnpause TxType = "token_unpause"
 )
 
+// ErrWrongChain — транзакция подписана для другой сети: chain_id или subnet_id не совпадают с сетью ноды.
+var ErrWrongChain = errors.New("транзакция подписана для другой сети")
+
 // Transaction represents a blockchain transaction
 type Transaction struct {
 	ID         string        `json:"id"`
+	ChainID    int64         `json:"chain_id"`            // chain_id сети, для которой подписана транзакция (входит в SigningHash)
+	SubnetID   string        `json:"subnet_id,omitempty"` // subnet_id подсети, для которой подписана транзакция (входит в SigningHash)
 	Sender     types.Address `json:"sender"`
 	Recipient  types.Address `json:"recipient"`
 	Value      *big.Int      `json:"value"`
@@ -51,6 +58,7 @@ type Transaction struct {
 	Nonce      int64         `json:"nonce"`
 	GasLimit   uint64        `json:"gas_limit"`
 	GasPrice   *big.Int      `json:"gas_price"`
+	GasUsed    uint64        `json:"gas_used"` // фактически использованный газ (заполняется при применении в блоке)
 	Signature  []byte        `json:"signature"`
 	Hash       string        `json:"hash"`
 	Fee        *big.Int      `json:"fee"`
@@ -63,8 +71,16 @@ type Transaction struct {
 	Symbol     string        `json:"symbol"`
 	IsVerified bool          `json:"is_verified"` // true для системных/генезисных транзакций
 
-	// SenderPublicKeyHex — опционально: hex публичного ключа P-256 отправителя для проверки подписи (не сохраняется в БД).
+	// SenderPublicKeyHex — hex публичного ключа отправителя для проверки подписи (не сохраняется в БД): обязателен для P-256,
+	// для secp256k1 с восстанавливаемой подписью не нужен.
 	SenderPublicKeyHex string `json:"sender_public_key,omitempty"`
+	// KeyType — схема подписи (p256, secp256k1); пустая — по формату адреса отправителя.
+	KeyType crypto.KeyType `json:"key_type,omitempty"`
+
+	// MaxFeePerGas и MaxPriorityFeePerGas — транзакция рынка комиссий (EIP-1559): цена газа в блоке —
+	// min(max_fee_per_gas, base fee блока + max_priority_fee_per_gas), gas_price не задаётся. nil — обычная транзакция с gas_price.
+	MaxFeePerGas         *big.Int `json:"max_fee_per_gas,omitempty"`
+	MaxPriorityFeePerGas *big.Int `json:"max_priority_fee_per_gas,omitempty"`
 }
 
 // Validate checks if the transaction is valid
@@ -81,7 +97,14 @@ func (tx *Transaction) Validate() error {
 	if tx.GasLimit == 0 {
 		return errors.New("gas limit must be greater than 0")
 	}
-	if tx.GasPrice == nil || tx.GasPrice.Sign() <= 0 {
+	if tx.IsDynamicFee() {
+		if tx.MaxFeePerGas.Sign() <= 0 || tx.MaxPriorityFeePerGas == nil || tx.MaxPriorityFeePerGas.Sign() < 0 {
+			return errors.New("invalid max fee per gas")
+		}
+		if tx.MaxPriorityFeePerGas.Cmp(tx.MaxFeePerGas) > 0 {
+			return errors.New("max priority fee per gas higher than max fee per gas")
+		}
+	} else if tx.GasPrice == nil || tx.GasPrice.Sign() <= 0 {
 		return errors.New("invalid gas price")
 	}
 	return nil
@@ -105,8 +128,8 @@ func (tx *Transaction) HasSufficientBalance() bool {
 		return false
 	}
 
-	// Газ оплачивается только в GND
-	gasCost := new(big.Int).Mul(tx.GasPrice, big.NewInt(int64(tx.GasLimit)))
+	// Газ оплачивается только в GND (резервируется весь лимит, списывается использованный)
+	gasCost := tx.CalculateTxFee(tx.EffectiveGasLimit())
 	balanceGND := state.GetBalance(tx.Sender, GasSymbol)
 	if balanceGND.Cmp(gasCost) < 0 {
 		return false
@@ -126,11 +149,15 @@ func (tx *Transaction) IsContractCall() bool {
 	return len(tx.Data) > 0
 }
 
+// IsDeploy сообщает, является ли транзакция деплоем контракта (тип deploy): Data — init-код, Recipient — адрес контракта.
+func (tx *Transaction) IsDeploy() bool {
+	return tx.Type == string(TxTypeDeploy)
+}
+
 // GetTotalCost calculates the total cost of the transaction including gas
 func (tx *Transaction) GetTotalCost() *big.Int {
 	total := new(big.Int).Set(tx.Value)
-	gasCost := new(big.Int).Mul(tx.GasPrice, big.NewInt(int64(tx.GasLimit)))
-	return total.Add(total, gasCost)
+	return total.Add(total, tx.CalculateTxFee(tx.EffectiveGasLimit()))
 }
 
 // NewTransaction creates a new transaction
@@ -160,33 +187,24 @@ func generateTransactionID() string {
 	return "tx_" + time.Now().Format("20060102150405")
 }
 
-// CalculateHash вычисляет хеш транзакции
+// CalculateHash вычисляет хеш транзакции — hex SigningHash. В хеш служебной записи без подписи (IsVerified)
+// добавляются время и payload: nonce у таких записей нулевой, и повторные записи одного действия иначе совпали бы.
 func (tx *Transaction) CalculateHash() string {
-	var sb string
-	sb += tx.Sender.String()
-	sb += tx.Recipient.String()
-	if tx.Value != nil {
-		sb += tx.Value.String()
-	}
-	if tx.Fee != nil {
-		sb += tx.Fee.String()
-	}
-	sb += fmt.Sprintf("%d", tx.Nonce)
-	sb += tx.Type
-	if tx.ContractID.Valid {
-		sb += fmt.Sprintf("%d", tx.ContractID.Int64)
-	}
-	sb += string(tx.Payload)
-	sb += tx.Status
-	sb += tx.Timestamp.Format(time.RFC3339Nano)
-	sb += string(tx.Data)
-	sb += tx.Symbol
-
-	hashArr := sha256.Sum256([]byte(sb))
-	return hex.EncodeToString(hashArr[:])
+	// Транзакция кошелька Ethereum идентифицируется хешем raw-транзакции, как в go-ethereum (keccak256)
+	if tx.IsEthereumSigned() {
+		return hex.EncodeToString(gethcrypto.Keccak256(tx.Signature))
+	}
+	if !tx.IsVerified || len(tx.Signature) > 0 {
+		return hex.EncodeToString(tx.SigningHash())
+	}
+	h := sha256.New()
+	h.Write(tx.appendSigningFields(nil))
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(tx.Timestamp.UnixNano())))
+	h.Write(tx.Payload)
+	return hex.EncodeToString(h.Sum(nil))
 }
 
-// Sign подписывает транзакцию
+// Sign подписывает транзакцию ключом P-256 (hex с префиксом 0x). Ключом secp256k1 подписывает Wallet.SignTransaction.
 func (tx *Transaction) Sign(privateKey string) error {
 	// Преобразование приватного ключа
 	key, err := crypto.HexToPrivateKey(privateKey)
@@ -194,7 +212,9 @@ func (tx *Transaction) Sign(privateKey string) error {
 		return fmt.Errorf("invalid private key: %v", err)
 	}
 
-	// Подпись хеша транзакции
+	// Подписывается hex хеша подписи (CalculateHash)
+	tx.KeyType = crypto.KeyTypeP256
+	tx.Hash = tx.CalculateHash()
 	signature, err := crypto.Sign([]byte(tx.Hash), key)
 	if err != nil {
 		return fmt.Errorf("failed to sign transaction: %v", err)
@@ -204,17 +224,44 @@ func (tx *Transaction) Sign(privateKey string) error {
 	return nil
 }
 
-// VerifyTransactionSignature проверяет подпись транзакции по хешу и публичному ключу P-256.
-// Хеш для проверки — tx.Hash (должен быть заполнен до вызова). Возвращает false при пустой подписи или неверной длине.
-func VerifyTransactionSignature(tx *Transaction, pubKey *ecdsa.PublicKey) bool {
-	if pubKey == nil || tx.Signature == nil || len(tx.Signature) != 64 {
-		return false
+// VerifyTransactionSignature проверяет подпись пользовательской транзакции — единый путь для REST, RPC, мемпула и пиров
+// (через ValidateTransaction). Схема — по формату адреса отправителя (tx.KeyType, если задан, должен с ней совпадать);
+// публичный ключ — sender_public_key или восстановленный из подписи secp256k1. Подписанные данные — hex хеша,
+// вычисленного по полям транзакции (CalculateHash), а не переданный tx.Hash. Адрес отправителя должен принадлежать ключу.
+func VerifyTransactionSignature(tx *Transaction) error {
+	if len(tx.Signature) == 0 {
+		return errors.New("транзакция должна быть подписана (signature обязателен)")
+	}
+	if tx.IsEthereumSigned() {
+		if err := verifyEthereumTransaction(tx); err != nil {
+			return err
+		}
+		tx.KeyType = crypto.KeyTypeSecp256k1
+		return nil
+	}
+	keyType := crypto.KeyTypeOfAddress(tx.Sender.String())
+	if keyType == "" {
+		return fmt.Errorf("адрес отправителя %s не принадлежит ключу p256 или secp256k1", tx.Sender)
+	}
+	if tx.KeyType != "" && tx.KeyType != keyType {
+		return fmt.Errorf("тип ключа %s не соответствует адресу отправителя (%s)", tx.KeyType, keyType)
+	}
+	var pub []byte
+	if s := strings.TrimPrefix(strings.TrimSpace(tx.SenderPublicKeyHex), "0x"); s != "" {
+		var err error
+		if pub, err = hex.DecodeString(s); err != nil {
+			return fmt.Errorf("неверный sender_public_key: %w", err)
+		}
+	}
+	pub, err := keyType.VerifyMessage([]byte(tx.CalculateHash()), tx.Signature, pub)
+	if err != nil {
+		return err
 	}
-	hashData := tx.Hash
-	if hashData == "" {
-		hashData = tx.CalculateHash()
+	if !keyType.AddressMatches(tx.Sender.String(), pub) {
+		return errors.New("адрес отправителя не соответствует публичному ключу")
 	}
-	return crypto.Verify([]byte(hashData), tx.Signature, pubKey)
+	tx.KeyType = keyType
+	return nil
 }
 
 // IsSystemTransaction возвращает true для генезисных и служебных транзакций, для которых подпись не проверяется.
@@ -282,12 +329,16 @@ func (tx *Transaction) SaveToDB(ctx context.Context, pool *pgxpool.Pool) error {
 	err := pool.QueryRow(ctx, `
 		INSERT INTO transactions (
 			id, block_id, hash, sender, recipient, value, fee, nonce,
-			type, contract_id, payload, status, timestamp, signature, is_verified
-		) VALUES (nextval('transactions_id_seq'), $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			type, contract_id, payload, status, timestamp, signature, is_verified,
+			gas_limit, gas_price, gas_used, max_fee_per_gas, max_priority_fee_per_gas
+		) VALUES (nextval('transactions_id_seq'), $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+			NULLIF($18, '')::numeric, NULLIF($19, '')::numeric)
 		RETURNING id`,
 		blockIDArg, tx.Hash, tx.Sender.String(), tx.Recipient.String(), tx.Value.String(),
 		feeStr, tx.Nonce, tx.Type, contractID, payloadArg,
 		tx.Status, tx.Timestamp, signatureArg, tx.IsVerified,
+		tx.GasLimit, tx.EffectiveGasPrice().String(), tx.GasUsed,
+		bigIntString(tx.MaxFeePerGas), bigIntString(tx.MaxPriorityFeePerGas),
 	).Scan(&dbID)
 	if err == nil {
 		tx.ID = strconv.FormatInt(dbID, 10)
@@ -300,15 +351,27 @@ func (tx *Transaction) SaveToDB(ctx context.Context, pool *pgxpool.Pool) error {
 	return nil
 }
 
-// CalculateTxFee вычисляет комиссию за транзакцию
-func (tx *Transaction) CalculateTxFee(gasUsed uint64) *big.Int {
-	fee := new(big.Int).Mul(tx.Fee, big.NewInt(int64(gasUsed)))
-	return fee
+// setGasPriceColumns заполняет цену газа из колонок transactions: при заданном max_fee_per_gas транзакция — рынка комиссий
+// (gas_price в БД хранит max_fee_per_gas и в поля не переносится), иначе — gas_price.
+func (tx *Transaction) setGasPriceColumns(gasPrice string, maxFee, maxPriorityFee sql.NullString) {
+	if maxFee.Valid {
+		tx.MaxFeePerGas, _ = new(big.Int).SetString(maxFee.String, 10)
+	}
+	if tx.MaxFeePerGas == nil {
+		tx.GasPrice, _ = new(big.Int).SetString(gasPrice, 10)
+		return
+	}
+	tx.MaxPriorityFeePerGas = big.NewInt(0)
+	if maxPriorityFee.Valid {
+		if tip, ok := new(big.Int).SetString(maxPriorityFee.String, 10); ok {
+			tx.MaxPriorityFeePerGas = tip
+		}
+	}
 }
 
-func DecodeRawTransaction(data []byte) (*Transaction, error) {
-	// Реализуйте декодирование транзакции из байтов (например, через gob, json, hex)
-	return nil, errors.New("DecodeRawTransaction не реализован")
+// CalculateTxFee вычисляет комиссию за транзакцию: gasUsed × цена газа (EffectiveGasPrice)
+func (tx *Transaction) CalculateTxFee(gasUsed uint64) *big.Int {
+	return new(big.Int).Mul(tx.EffectiveGasPrice(), new(big.Int).SetUint64(gasUsed))
 }
 
 // RecoverPublicKey восстанавливает публичный ключ из адреса
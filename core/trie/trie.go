@@ -0,0 +1,193 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/trie/trie.go — разреженное дерево Меркла (sparse Merkle trie) с 256-битными ключами и доказательствами.
+
+package trie
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// Hash — 32-байтный хеш узла, ключа или значения (sha256).
+type Hash [32]byte
+
+// Empty — хеш пустого поддерева и корень пустого дерева.
+var Empty Hash
+
+// Префиксы хешей листа и внутреннего узла: лист нельзя выдать за узел и наоборот.
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// Key возвращает ключ дерева — sha256 от конкатенации частей.
+func Key(parts ...[]byte) Hash {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	var k Hash
+	copy(k[:], h.Sum(nil))
+	return k
+}
+
+// String возвращает хеш в hex.
+func (h Hash) String() string { return hex.EncodeToString(h[:]) }
+
+// MarshalText кодирует хеш в hex (для JSON).
+func (h Hash) MarshalText() ([]byte, error) { return []byte(h.String()), nil }
+
+// UnmarshalText разбирает хеш из hex.
+func (h *Hash) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil || len(b) != len(h) {
+		return fmt.Errorf("неверный хеш %q", text)
+	}
+	copy(h[:], b)
+	return nil
+}
+
+// Tree — разреженное дерево Меркла глубины 256: путь к листу задают биты ключа (старший бит первым).
+// Поддерево ровно с одним листом сворачивается в хеш этого листа, пустое поддерево — Empty,
+// поэтому построение корня по n листьям стоит O(n·log n) хешей. Листья хранят хеш значения.
+type Tree struct {
+	leaves map[Hash]Hash
+}
+
+// New создаёт пустое дерево.
+func New() *Tree {
+	return &Tree{leaves: make(map[Hash]Hash)}
+}
+
+// Set записывает значение по ключу; пустое значение удаляет лист.
+func (t *Tree) Set(key Hash, value []byte) {
+	if len(value) == 0 {
+		delete(t.leaves, key)
+		return
+	}
+	t.leaves[key] = sha256.Sum256(value)
+}
+
+// Len возвращает число листьев.
+func (t *Tree) Len() int { return len(t.leaves) }
+
+// Root возвращает корень дерева.
+func (t *Tree) Root() Hash {
+	return t.subtreeRoot(t.sortedKeys(), 0)
+}
+
+// Proof — доказательство включения или отсутствия ключа. Siblings — хеши соседних поддеревьев от корня вниз
+// до глубины, на которой поддерево ключа содержит не больше одного листа. Для отсутствующего ключа
+// LeafKey и LeafValue — единственный лист этого поддерева (другой ключ с тем же префиксом), если он есть.
+type Proof struct {
+	Siblings  []Hash `json:"siblings"`
+	LeafKey   *Hash  `json:"leaf_key,omitempty"`
+	LeafValue *Hash  `json:"leaf_value,omitempty"`
+}
+
+// Prove строит доказательство для ключа: включения, если ключ записан, иначе отсутствия.
+func (t *Tree) Prove(key Hash) *Proof {
+	keys := t.sortedKeys()
+	p := &Proof{}
+	for depth := 0; len(keys) > 1; depth++ {
+		split := splitIndex(keys, depth)
+		if bit(key, depth) == 0 {
+			p.Siblings = append(p.Siblings, t.subtreeRoot(keys[split:], depth+1))
+			keys = keys[:split]
+		} else {
+			p.Siblings = append(p.Siblings, t.subtreeRoot(keys[:split], depth+1))
+			keys = keys[split:]
+		}
+	}
+	if len(keys) == 1 && keys[0] != key {
+		leafKey, leafValue := keys[0], t.leaves[keys[0]]
+		p.LeafKey, p.LeafValue = &leafKey, &leafValue
+	}
+	return p
+}
+
+// Verify проверяет доказательство относительно корня: value != nil — ключ записан с этим значением,
+// value == nil — ключа в дереве нет.
+func (p *Proof) Verify(root, key Hash, value []byte) bool {
+	if len(p.Siblings) > 256 {
+		return false
+	}
+	var h Hash
+	switch {
+	case value != nil:
+		if p.LeafKey != nil || len(value) == 0 {
+			return false
+		}
+		h = leafHash(key, sha256.Sum256(value))
+	case p.LeafKey != nil:
+		// другой лист должен лежать на том же пути, иначе он не доказывает отсутствие key
+		if p.LeafValue == nil || *p.LeafKey == key {
+			return false
+		}
+		for i := range p.Siblings {
+			if bit(*p.LeafKey, i) != bit(key, i) {
+				return false
+			}
+		}
+		h = leafHash(*p.LeafKey, *p.LeafValue)
+	default:
+		h = Empty
+	}
+	for i := len(p.Siblings) - 1; i >= 0; i-- {
+		if bit(key, i) == 0 {
+			h = nodeHash(h, p.Siblings[i])
+		} else {
+			h = nodeHash(p.Siblings[i], h)
+		}
+	}
+	return h == root
+}
+
+// subtreeRoot — корень поддерева на глубине depth по отсортированным ключам с общим префиксом длины depth.
+func (t *Tree) subtreeRoot(keys []Hash, depth int) Hash {
+	switch len(keys) {
+	case 0:
+		return Empty
+	case 1:
+		return leafHash(keys[0], t.leaves[keys[0]])
+	}
+	split := splitIndex(keys, depth)
+	return nodeHash(t.subtreeRoot(keys[:split], depth+1), t.subtreeRoot(keys[split:], depth+1))
+}
+
+func (t *Tree) sortedKeys() []Hash {
+	keys := make([]Hash, 0, len(t.leaves))
+	for k := range t.leaves {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	return keys
+}
+
+// splitIndex — индекс первого ключа с единичным битом depth (ключи отсортированы и совпадают до depth).
+func splitIndex(keys []Hash, depth int) int {
+	return sort.Search(len(keys), func(i int) bool { return bit(keys[i], depth) == 1 })
+}
+
+func bit(k Hash, i int) byte {
+	return (k[i/8] >> (7 - uint(i%8))) & 1
+}
+
+func leafHash(key, valueHash Hash) Hash {
+	var buf [1 + 64]byte
+	buf[0] = leafPrefix
+	copy(buf[1:], key[:])
+	copy(buf[33:], valueHash[:])
+	return sha256.Sum256(buf[:])
+}
+
+func nodeHash(left, right Hash) Hash {
+	var buf [1 + 64]byte
+	buf[0] = nodePrefix
+	copy(buf[1:], left[:])
+	copy(buf[33:], right[:])
+	return sha256.Sum256(buf[:])
+}
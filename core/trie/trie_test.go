@@ -0,0 +1,69 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package trie
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTree_RootIndependentOfInsertOrder(t *testing.T) {
+	a, b := New(), New()
+	if a.Root() != Empty {
+		t.Fatal("корень пустого дерева должен быть Empty")
+	}
+	for i := 0; i < 50; i++ {
+		a.Set(Key([]byte(fmt.Sprint(i))), []byte{byte(i + 1)})
+		b.Set(Key([]byte(fmt.Sprint(49-i))), []byte{byte(50 - i)})
+	}
+	if a.Root() != b.Root() {
+		t.Fatal("корень не должен зависеть от порядка записи")
+	}
+	before := a.Root()
+	a.Set(Key([]byte("7")), []byte{0xff})
+	if a.Root() == before {
+		t.Error("изменение значения должно менять корень")
+	}
+	a.Set(Key([]byte("7")), []byte{8})
+	if a.Root() != before {
+		t.Error("возврат значения должен возвращать корень")
+	}
+	a.Set(Key([]byte("7")), nil)
+	if a.Root() == before || a.Len() != 49 {
+		t.Error("пустое значение должно удалять лист")
+	}
+}
+
+func TestTree_Proofs(t *testing.T) {
+	tr := New()
+	for i := 0; i < 20; i++ {
+		tr.Set(Key([]byte(fmt.Sprint(i))), []byte(fmt.Sprint("value-", i)))
+	}
+	root := tr.Root()
+
+	key := Key([]byte("3"))
+	p := tr.Prove(key)
+	if !p.Verify(root, key, []byte("value-3")) {
+		t.Fatal("доказательство включения должно проходить")
+	}
+	if p.Verify(root, key, []byte("value-4")) || p.Verify(root, key, nil) {
+		t.Error("доказательство не должно подтверждать другое значение или отсутствие")
+	}
+	if p.Verify(root, Key([]byte("4")), []byte("value-3")) {
+		t.Error("доказательство не должно подходить к другому ключу")
+	}
+	p.Siblings[0][0] ^= 1
+	if p.Verify(root, key, []byte("value-3")) {
+		t.Error("доказательство с изменённым соседом должно отклоняться")
+	}
+
+	for i := 100; i < 120; i++ {
+		missing := Key([]byte(fmt.Sprint(i)))
+		p := tr.Prove(missing)
+		if !p.Verify(root, missing, nil) {
+			t.Fatalf("доказательство отсутствия ключа %d должно проходить", i)
+		}
+		if p.Verify(root, missing, []byte("value-1")) {
+			t.Errorf("отсутствующий ключ %d не должен подтверждаться со значением", i)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// core/tx_encoding.go — каноническое бинарное кодирование транзакции (версии 1 и 2): хеш подписи по неизменяемым полям
+// и сети (chain_id, subnet_id), raw-транзакция для eth_sendRawTransaction и её декодер.
+
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"GND/core/crypto"
+	"GND/types"
+)
+
+// TxEncodingVersion — версия канонического кодирования транзакции (первый байт кодирования).
+const TxEncodingVersion byte = 1
+
+// TxEncodingVersionDynamicFee — версия кодирования транзакции рынка комиссий (max_fee_per_gas задан).
+const TxEncodingVersionDynamicFee byte = 2
+
+// Кодирование версии 1 — поля подряд, переменной длины с префиксом длины u32 big-endian:
+//
+//	version(1) | chain_id u64 | subnet_id | type | sender | recipient | value | nonce u64 | gas_limit u64 | gas_price | symbol | data
+//
+// Версия 2 (транзакция рынка комиссий) вместо gas_price содержит две суммы:
+//
+//	version(2) | chain_id u64 | subnet_id | type | sender | recipient | value | nonce u64 | gas_limit u64 | max_priority_fee | max_fee | symbol | data
+//
+// Суммы — big-endian без ведущих нулей (ноль — пустые байты). Raw-транзакция дополнительно содержит
+// signature, тип ключа (p256, secp256k1 или пустой) и публичный ключ отправителя (может быть пустым).
+
+// signedType возвращает тип транзакции для подписи: contract_call нода проставляет сама вызову без типа,
+// поэтому в подписи он равен пустому.
+func signedType(t string) string {
+	if t == "contract_call" {
+		return ""
+	}
+	return t
+}
+
+// appendSigningFields дописывает к buf неизменяемые поля транзакции, chain_id и subnet_id.
+func (tx *Transaction) appendSigningFields(buf []byte) []byte {
+	if tx.IsDynamicFee() {
+		buf = append(buf, TxEncodingVersionDynamicFee)
+	} else {
+		buf = append(buf, TxEncodingVersion)
+	}
+	buf = binary.BigEndian.AppendUint64(buf, uint64(tx.ChainID))
+	buf = appendBytes(buf, []byte(tx.SubnetID))
+	buf = appendBytes(buf, []byte(signedType(tx.Type)))
+	buf = appendBytes(buf, []byte(tx.Sender))
+	buf = appendBytes(buf, []byte(tx.Recipient))
+	buf = appendBytes(buf, bigBytes(tx.Value))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(tx.Nonce))
+	buf = binary.BigEndian.AppendUint64(buf, tx.GasLimit)
+	if tx.IsDynamicFee() {
+		buf = appendBytes(buf, bigBytes(tx.MaxPriorityFeePerGas))
+		buf = appendBytes(buf, bigBytes(tx.MaxFeePerGas))
+	} else {
+		buf = appendBytes(buf, bigBytes(tx.GasPrice))
+	}
+	buf = appendBytes(buf, []byte(tx.Symbol))
+	return appendBytes(buf, tx.Data)
+}
+
+// SigningHash — sha256 канонического кодирования неизменяемых полей, chain_id и subnet_id. Статус, время, комиссия, gas_used
+// и payload в него не входят: хеш не меняется при исполнении транзакции.
+func (tx *Transaction) SigningHash() []byte {
+	h := sha256.Sum256(tx.appendSigningFields(nil))
+	return h[:]
+}
+
+// EncodeRawTransaction кодирует подписанную транзакцию: поля подписи, signature, тип ключа и публичный ключ отправителя.
+func EncodeRawTransaction(tx *Transaction) ([]byte, error) {
+	if isNegative(tx.Value) || isNegative(tx.GasPrice) || isNegative(tx.MaxFeePerGas) || isNegative(tx.MaxPriorityFeePerGas) {
+		return nil, errors.New("отрицательные суммы не кодируются")
+	}
+	var pub []byte
+	if s := strings.TrimPrefix(strings.TrimSpace(tx.SenderPublicKeyHex), "0x"); s != "" {
+		var err error
+		if pub, err = hex.DecodeString(s); err != nil {
+			return nil, fmt.Errorf("неверный sender_public_key: %w", err)
+		}
+	}
+	keyType, err := crypto.ParseKeyType(string(tx.KeyType))
+	if err != nil || keyType != tx.KeyType {
+		return nil, fmt.Errorf("неверный тип ключа %q", tx.KeyType)
+	}
+	buf := tx.appendSigningFields(nil)
+	buf = appendBytes(buf, tx.Signature)
+	buf = appendBytes(buf, []byte(tx.KeyType))
+	return appendBytes(buf, pub), nil
+}
+
+// DecodeRawTransaction декодирует raw-транзакцию (EncodeRawTransaction). Принимается только каноническое кодирование:
+// известная версия, числа без ведущих нулей, без лишних байт. Служебные транзакции (IsSystemTransaction) отклоняются.
+// Hash вычисляется по полям, статус — pending.
+func DecodeRawTransaction(data []byte) (*Transaction, error) {
+	d := txDecoder{data: data}
+	version := d.byte()
+	if d.err == nil && version != TxEncodingVersion && version != TxEncodingVersionDynamicFee {
+		return nil, fmt.Errorf("неизвестная версия кодирования транзакции %d", version)
+	}
+	tx := &Transaction{ChainID: int64(d.uint64())}
+	tx.SubnetID = string(d.bytes())
+	tx.Type = string(d.bytes())
+	tx.Sender = types.Address(d.bytes())
+	tx.Recipient = types.Address(d.bytes())
+	tx.Value = d.big()
+	tx.Nonce = int64(d.uint64())
+	tx.GasLimit = d.uint64()
+	if version == TxEncodingVersionDynamicFee {
+		tx.MaxPriorityFeePerGas = d.big()
+		tx.MaxFeePerGas = d.big()
+	} else {
+		tx.GasPrice = d.big()
+	}
+	tx.Symbol = string(d.bytes())
+	tx.Data = d.bytes()
+	tx.Signature = d.bytes()
+	tx.KeyType = crypto.KeyType(d.bytes())
+	if pub := d.bytes(); len(pub) > 0 {
+		tx.SenderPublicKeyHex = hex.EncodeToString(pub)
+	}
+	if d.err == nil && len(d.data) > 0 {
+		d.err = fmt.Errorf("лишние %d байт после транзакции", len(d.data))
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("декодирование транзакции: %w", d.err)
+	}
+	if keyType, err := crypto.ParseKeyType(string(tx.KeyType)); err != nil || keyType != tx.KeyType {
+		return nil, fmt.Errorf("неверный тип ключа %q", tx.KeyType)
+	}
+	if tx.Type == "contract_call" {
+		return nil, errors.New("тип contract_call в raw-транзакции не указывается: вызов определяется по data")
+	}
+	if IsSystemTransaction(tx) {
+		return nil, errors.New("служебные транзакции не принимаются в raw-виде")
+	}
+	tx.Hash = tx.CalculateHash()
+	tx.Status = "pending"
+	tx.Timestamp = BlockchainNow()
+	return tx, nil
+}
+
+// txDecoder читает поля кодирования по порядку; после первой ошибки чтения возвращает нулевые значения.
+type txDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *txDecoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.data) {
+		d.err = fmt.Errorf("кодирование обрывается: нужно %d байт, осталось %d", n, len(d.data))
+		return nil
+	}
+	b := d.data[:n:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *txDecoder) byte() byte {
+	if b := d.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *txDecoder) uint64() uint64 {
+	if b := d.take(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *txDecoder) bytes() []byte {
+	b := d.take(4)
+	if b == nil {
+		return nil
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint64(n) > uint64(len(d.data)) {
+		d.err = fmt.Errorf("длина поля %d больше остатка %d", n, len(d.data))
+		return nil
+	}
+	if n == 0 {
+		return nil
+	}
+	return d.take(int(n))
+}
+
+func (d *txDecoder) big() *big.Int {
+	b := d.bytes()
+	if len(b) > 0 && b[0] == 0 && d.err == nil {
+		d.err = errors.New("число с ведущими нулями")
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+func appendBytes(buf, b []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(b)))
+	return append(buf, b...)
+}
+
+func isNegative(v *big.Int) bool {
+	return v != nil && v.Sign() < 0
+}
+
+func bigBytes(v *big.Int) []byte {
+	if v == nil {
+		return nil
+	}
+	return v.Bytes()
+}
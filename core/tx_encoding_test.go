@@ -0,0 +1,235 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"GND/core/crypto"
+	"GND/types"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func signedTestTx(t testing.TB) *Transaction {
+	t.Helper()
+	key, err := crypto.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := &Transaction{ChainID: 7, Sender: types.Address(crypto.PublicKeyToAddressP256(&key.PublicKey)), Recipient: "GN_encoding_recipient",
+		Value: big.NewInt(1000), Nonce: 3, GasLimit: TxGas, GasPrice: big.NewInt(2), Symbol: GasSymbol, Data: []byte{0xa9, 0x05},
+		SenderPublicKeyHex: hex.EncodeToString(crypto.PublicKeyUncompressedBytes(&key.PublicKey))}
+	if err := tx.Sign(crypto.PrivateKeyToHex(key)); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestTransactionHash_CoversOnlyImmutableFieldsAndChainID(t *testing.T) {
+	tx := &Transaction{Sender: "GN_a", Recipient: "GN_b", Value: big.NewInt(5), Nonce: 1, GasLimit: TxGas, GasPrice: big.NewInt(1), Symbol: GasSymbol}
+	hash := tx.CalculateHash()
+
+	tx.Status, tx.Timestamp, tx.Fee, tx.GasUsed, tx.BlockID = "confirmed", time.Now(), big.NewInt(21000), TxGas, 9
+	if tx.CalculateHash() != hash {
+		t.Fatal("статус, время и результат исполнения не должны менять хеш")
+	}
+	tx.ChainID = 2
+	if tx.CalculateHash() == hash {
+		t.Fatal("chain_id должен входить в хеш подписи")
+	}
+
+	// раньше строки склеивались без разделителей: "GN_a"+"GN_b1" == "GN_aGN_b"+"1"
+	a := &Transaction{Sender: "GN_a", Recipient: "GN_b1", Value: big.NewInt(5)}
+	b := &Transaction{Sender: "GN_aGN_b", Recipient: "1", Value: big.NewInt(5)}
+	if a.CalculateHash() == b.CalculateHash() {
+		t.Fatal("разные транзакции не должны давать один хеш")
+	}
+}
+
+func TestRawTransaction_RoundTripKeepsSignature(t *testing.T) {
+	tx := signedTestTx(t)
+	raw, err := EncodeRawTransaction(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeRawTransaction(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Hash != tx.Hash || decoded.ChainID != 7 || decoded.Nonce != 3 || decoded.Value.Cmp(tx.Value) != 0 ||
+		!bytes.Equal(decoded.Data, tx.Data) || decoded.SenderPublicKeyHex != tx.SenderPublicKeyHex {
+		t.Fatalf("декодировано %+v, ожидалось %+v", decoded, tx)
+	}
+	if err := VerifyTransactionSignature(decoded); err != nil {
+		t.Fatalf("подпись декодированной транзакции должна проверяться: %v", err)
+	}
+	decoded.ChainID = 8
+	if VerifyTransactionSignature(decoded) == nil {
+		t.Fatal("подпись не должна подходить к транзакции с другим chain_id")
+	}
+
+	if _, err := DecodeRawTransaction(append(raw, 0)); err == nil {
+		t.Error("лишние байты должны отклоняться")
+	}
+	if _, err := DecodeRawTransaction(raw[:len(raw)-1]); err == nil {
+		t.Error("обрезанное кодирование должно отклоняться")
+	}
+	system := &Transaction{Sender: "GND_GENESIS", Recipient: "GN_b", Value: big.NewInt(1)}
+	if raw, _ := EncodeRawTransaction(system); raw != nil {
+		if _, err := DecodeRawTransaction(raw); err == nil {
+			t.Error("служебная транзакция в raw-виде должна отклоняться")
+		}
+	}
+}
+
+func TestRawTransaction_DynamicFeeVersion2(t *testing.T) {
+	legacy := signedTestTx(t)
+	tx := *legacy
+	tx.GasPrice, tx.MaxFeePerGas, tx.MaxPriorityFeePerGas = nil, big.NewInt(20), big.NewInt(3)
+	if tx.CalculateHash() == legacy.Hash {
+		t.Fatal("транзакция рынка комиссий должна иметь другой хеш")
+	}
+	raw, err := EncodeRawTransaction(&tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw[0] != TxEncodingVersionDynamicFee {
+		t.Fatalf("версия кодирования %d, ожидалась %d", raw[0], TxEncodingVersionDynamicFee)
+	}
+	decoded, err := DecodeRawTransaction(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.IsDynamicFee() || decoded.MaxFeePerGas.Int64() != 20 || decoded.MaxPriorityFeePerGas.Int64() != 3 || decoded.GasPrice != nil {
+		t.Fatalf("поля рынка комиссий: %v %v %v", decoded.MaxFeePerGas, decoded.MaxPriorityFeePerGas, decoded.GasPrice)
+	}
+	decoded.MaxPriorityFeePerGas = big.NewInt(4)
+	if decoded.CalculateHash() == decoded.Hash {
+		t.Fatal("чаевые должны входить в хеш подписи")
+	}
+}
+
+func TestVerifyTransactionSignature_Secp256k1WalletWithoutPublicKey(t *testing.T) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := AddressFromPublicKey(key.PubKey().SerializeCompressed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := &Wallet{PrivateKey: key, Address: Address(address)}
+	tx := &Transaction{Sender: types.Address(address), Recipient: "GN_encoding_recipient", Value: big.NewInt(1), GasLimit: TxGas, GasPrice: big.NewInt(1)}
+	if err := wallet.SignTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := EncodeRawTransaction(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeRawTransaction(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTransactionSignature(decoded); err != nil || decoded.KeyType != crypto.KeyTypeSecp256k1 {
+		t.Fatalf("подпись secp256k1 без публичного ключа: %v", err)
+	}
+
+	// подпись другим ключом восстанавливает другой адрес
+	other, _ := secp256k1.GeneratePrivateKey()
+	decoded.Signature = crypto.SignSecp256k1([]byte(decoded.Hash), other)
+	if err := VerifyTransactionSignature(decoded); err == nil {
+		t.Fatal("подпись чужим ключом должна отклоняться")
+	}
+	// схема p256 не подходит к адресу secp256k1
+	decoded.Signature, decoded.KeyType = tx.Signature, crypto.KeyTypeP256
+	if err := VerifyTransactionSignature(decoded); err == nil {
+		t.Fatal("тип ключа, не совпадающий с адресом, должен отклоняться")
+	}
+}
+
+func FuzzDecodeRawTransaction(f *testing.F) {
+	raw, err := EncodeRawTransaction(signedTestTx(f))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(raw)
+	dynamic := signedTestTx(f)
+	dynamic.GasPrice, dynamic.MaxFeePerGas, dynamic.MaxPriorityFeePerGas = nil, big.NewInt(20), big.NewInt(3)
+	dynamic.Hash = dynamic.CalculateHash()
+	if raw, err = EncodeRawTransaction(dynamic); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(raw)
+	f.Add([]byte{TxEncodingVersion})
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tx, err := DecodeRawTransaction(data)
+		if err != nil {
+			return
+		}
+		// кодирование каноническое: декодированная транзакция кодируется в те же байты
+		again, err := EncodeRawTransaction(tx)
+		if err != nil {
+			t.Fatalf("повторное кодирование: %v", err)
+		}
+		if !bytes.Equal(again, data) {
+			t.Fatalf("кодирование не каноническое: %x -> %x", data, again)
+		}
+		if tx.Hash != hex.EncodeToString(tx.SigningHash()) {
+			t.Fatalf("хеш %s не совпадает с хешем подписи", tx.Hash)
+		}
+	})
+}
+
+func TestEthereumTransaction_LegacySignedByGeth(t *testing.T) {
+	key, err := gethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient := types.Address("GN_encoding_recipient")
+	to := EVMAddress(recipient.String())
+	etx, err := gethtypes.SignNewTx(key, gethtypes.LatestSignerForChainID(big.NewInt(7)), &gethtypes.LegacyTx{
+		Nonce: 2, GasPrice: big.NewInt(3), Gas: TxGas, To: &to, Value: big.NewInt(40)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := etx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := DecodeEthereumTransaction(raw, func(common.Address) string { return recipient.String() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Hash != hex.EncodeToString(etx.Hash().Bytes()) || tx.Nonce != 2 || tx.GasPrice.Cmp(big.NewInt(3)) != 0 || crypto.KeyTypeOfAddress(tx.Sender.String()) != crypto.KeyTypeSecp256k1 {
+		t.Fatalf("неверно разобрана транзакция Ethereum: %+v", tx)
+	}
+	if err := tx.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTransactionSignature(tx); err != nil {
+		t.Fatalf("транзакция Ethereum не прошла проверку: %v", err)
+	}
+
+	// поля, не совпадающие с подписанной raw-транзакцией, отклоняются
+	for name, tamper := range map[string]func(*Transaction){
+		"value":     func(tx *Transaction) { tx.Value = big.NewInt(41) },
+		"recipient": func(tx *Transaction) { tx.Recipient = "GN_other_recipient" },
+		"sender":    func(tx *Transaction) { tx.Sender = "GN_other_sender" },
+		"symbol":    func(tx *Transaction) { tx.Symbol = "USDT" },
+	} {
+		bad := *tx
+		tamper(&bad)
+		if err := VerifyTransactionSignature(&bad); err == nil {
+			t.Errorf("%s: подмена поля транзакции Ethereum не обнаружена", name)
+		}
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"crypto/rand" // Импорт для rand.Int
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big" // Добавляем импорт math/big
 	"strings"
 	"time"
 
+	"GND/core/crypto"
 	"GND/types"
 
 	"github.com/btcsuite/btcutil/base58"
@@ -350,6 +352,18 @@ func bytesEqual(a, b []byte) bool {
 	return true
 }
 
+// SignTransaction подписывает транзакцию ключом secp256k1 кошелька: восстанавливаемая подпись hex хеша (CalculateHash),
+// поэтому sender_public_key не нужен. Для кошельков signing_service ключа в памяти нет — подписывает SignerService.
+func (w *Wallet) SignTransaction(tx *Transaction) error {
+	if w.PrivateKey == nil {
+		return errors.New("у кошелька нет приватного ключа в памяти")
+	}
+	tx.KeyType = crypto.KeyTypeSecp256k1
+	tx.Hash = tx.CalculateHash()
+	tx.Signature = crypto.SignSecp256k1([]byte(tx.Hash), w.PrivateKey)
+	return nil
+}
+
 func (w *Wallet) PrivateKeyHex() string {
 	if w.PrivateKey != nil {
 		return hex.EncodeToString(w.PrivateKey.Serialize())
@@ -0,0 +1,8 @@
+-- Runtime-код контрактов для исполнения байткода (vm, интерпретатор go-ethereum).
+-- code/bytecode хранят init-код (с аргументами конструктора), runtime_code — код, возвращённый конструктором.
+-- Для контрактов, задеплоенных до миграции, runtime_code заполняется нодой при первом вызове.
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+ALTER TABLE public.contracts ADD COLUMN IF NOT EXISTS runtime_code BYTEA;
+
+COMMENT ON COLUMN public.contracts.runtime_code IS 'Runtime-код контракта (результат исполнения конструктора); исполняется EVM при вызовах.';
@@ -0,0 +1,11 @@
+-- Учёт газа транзакций: лимит и цена газа из транзакции, фактически использованный газ после применения в блоке.
+-- fee после включения в блок — фактическая комиссия (gas_used × gas_price) в GND.
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS gas_limit BIGINT;
+ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS gas_price NUMERIC(78, 0);
+ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS gas_used BIGINT;
+
+COMMENT ON COLUMN public.transactions.gas_limit IS 'Лимит газа транзакции.';
+COMMENT ON COLUMN public.transactions.gas_price IS 'Цена газа в GND (минимальные единицы).';
+COMMENT ON COLUMN public.transactions.gas_used IS 'Фактически использованный газ (заполняется при включении в блок).';
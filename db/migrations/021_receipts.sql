@@ -0,0 +1,29 @@
+-- Квитанции транзакций: статус исполнения (success/failed), газ, события контрактов (logs), адрес созданного контракта, причина ошибки.
+-- Заполняется нодой при включении транзакции в блок (core.Blockchain.AddBlock).
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+CREATE TABLE IF NOT EXISTS public.receipts (
+    tx_hash             VARCHAR(128) PRIMARY KEY,
+    tx_index            INTEGER      NOT NULL DEFAULT 0,
+    block_id            INTEGER      REFERENCES public.blocks(id) ON DELETE CASCADE,
+    block_number        BIGINT       NOT NULL,
+    block_hash          VARCHAR(128) NOT NULL,
+    sender              VARCHAR(128) NOT NULL,
+    recipient           VARCHAR(128) NOT NULL,
+    status              VARCHAR(16)  NOT NULL,
+    gas_used            BIGINT       NOT NULL DEFAULT 0,
+    cumulative_gas_used BIGINT       NOT NULL DEFAULT 0,
+    fee                 NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    contract_address    VARCHAR(128),
+    logs                JSONB        NOT NULL DEFAULT '[]'::jsonb,
+    error               TEXT,
+    revert_reason       TEXT,
+    created_at          TIMESTAMP    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_receipts_block_id ON public.receipts (block_id);
+
+COMMENT ON TABLE public.receipts IS 'Квитанции транзакций, включённых в блок.';
+COMMENT ON COLUMN public.receipts.status IS 'success — исполнена; failed — откат (revert), неверный nonce или недостаточно средств.';
+COMMENT ON COLUMN public.receipts.cumulative_gas_used IS 'Газ, использованный транзакциями блока до этой включительно.';
+COMMENT ON COLUMN public.receipts.logs IS 'События контракта: [{address, topics[], data, log_index}], hex с 0x.';
@@ -0,0 +1,7 @@
+-- Подпись блока PoA: DER-подпись secp256k1 хеша заголовка (Block.SealHash) ключом предлагающего валидатора (blocks.miner), hex.
+-- Заполняется нодой при создании блока движком PoA (consensus.PoA.Seal); проверяется в core.Blockchain.AddBlock.
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+ALTER TABLE public.blocks ADD COLUMN IF NOT EXISTS signature VARCHAR;
+
+COMMENT ON COLUMN public.blocks.signature IS 'DER-подпись SealHash ключом валидатора blocks.miner (hex); NULL — блок создан без движка PoA.';
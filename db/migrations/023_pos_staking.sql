@@ -0,0 +1,34 @@
+-- Реестр стейкинга PoS: стейки делегаторов у валидаторов, ожидающие разблокировки после unstake и полученные награды.
+-- Валидаторы PoS — validators + pos_validators (total_stake, delegated_stake, commission_rate, rewards).
+-- Пишется нодой после каждого блока (core.StakeLedger.SaveToDB), читается при старте (core.StakeLedger.LoadFromDB).
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+CREATE TABLE IF NOT EXISTS public.pos_stakes (
+    validator VARCHAR NOT NULL,
+    delegator VARCHAR NOT NULL,
+    amount    NUMERIC NOT NULL,
+    PRIMARY KEY (validator, delegator)
+);
+
+COMMENT ON TABLE public.pos_stakes IS 'Заблокированный стейк: delegator = validator — собственный стейк валидатора, иначе делегирование.';
+
+CREATE INDEX IF NOT EXISTS idx_pos_stakes_delegator ON public.pos_stakes (delegator);
+
+CREATE TABLE IF NOT EXISTS public.pos_unbonding (
+    id             SERIAL PRIMARY KEY,
+    validator      VARCHAR NOT NULL,
+    delegator      VARCHAR NOT NULL,
+    amount         NUMERIC NOT NULL,
+    release_height BIGINT NOT NULL
+);
+
+COMMENT ON TABLE public.pos_unbonding IS 'Выведенный unstake стейк: возвращается на баланс delegator в блоке release_height.';
+
+CREATE INDEX IF NOT EXISTS idx_pos_unbonding_delegator ON public.pos_unbonding (delegator);
+
+CREATE TABLE IF NOT EXISTS public.pos_rewards (
+    address VARCHAR PRIMARY KEY,
+    amount  NUMERIC NOT NULL
+);
+
+COMMENT ON TABLE public.pos_rewards IS 'Награды за блоки PoS, начисленные адресу (валидатору и делегаторам), всего.';
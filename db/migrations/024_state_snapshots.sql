@@ -0,0 +1,14 @@
+-- Снимки состояния, загруженные быстрой синхронизацией (p2p.sync_mode = "fast"): аккаунты, код и storage контрактов,
+-- реестр стейкинга на высоте height. Пишется нодой в core.Blockchain.ImportSnapshot; при старте из последнего снимка
+-- берётся runtime-код контрактов, созданных до снимка (их нет в contracts).
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+CREATE TABLE IF NOT EXISTS public.state_snapshots (
+    height      BIGINT PRIMARY KEY,
+    block_hash  VARCHAR NOT NULL,
+    state_root  VARCHAR NOT NULL,
+    data        JSONB NOT NULL,
+    imported_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE public.state_snapshots IS 'Снимки состояния, загруженные от пиров при быстрой синхронизации (core.StateSnapshot в JSON).';
@@ -0,0 +1,13 @@
+-- Блоки, отменённые реорганизацией цепи (core/forkchoice.go): остаются в blocks с is_orphaned = TRUE, поэтому на одной
+-- высоте может быть несколько записей — уникален только номер блока цепи. parent_id — id родительского блока.
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+UPDATE public.blocks SET is_orphaned = FALSE WHERE is_orphaned IS NULL;
+ALTER TABLE public.blocks ALTER COLUMN is_orphaned SET DEFAULT FALSE;
+ALTER TABLE public.blocks ALTER COLUMN is_orphaned SET NOT NULL;
+
+ALTER TABLE public.blocks DROP CONSTRAINT IF EXISTS unique_block_index;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_block_index ON public.blocks (index) WHERE NOT is_orphaned;
+CREATE INDEX IF NOT EXISTS idx_blocks_parent_id ON public.blocks (parent_id);
+
+COMMENT ON COLUMN public.blocks.is_orphaned IS 'TRUE — блок отменён реорганизацией цепи (ветка с более тяжёлым форком), в цепь не входит.';
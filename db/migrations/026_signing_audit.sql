@@ -0,0 +1,23 @@
+-- Журнал подписи транзакций нодой через signing_service (api/signing.go): каждая попытка подписи кастодиальным
+-- кошельком — POST /api/v1/wallet/:address/sign-and-send по API-ключу и подпись из админки (X-Admin-Token).
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+CREATE TABLE IF NOT EXISTS public.signing_audit (
+    id               BIGSERIAL PRIMARY KEY,
+    wallet_address   VARCHAR NOT NULL,
+    signer_wallet_id UUID,
+    source           VARCHAR(16) NOT NULL,
+    api_key_id       INTEGER,
+    tx_hash          VARCHAR,
+    status           VARCHAR(16) NOT NULL,
+    error            TEXT,
+    client_ip        VARCHAR(64),
+    created_at       TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_signing_audit_wallet ON public.signing_audit (wallet_address, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_signing_audit_api_key ON public.signing_audit (api_key_id, created_at DESC);
+
+COMMENT ON TABLE public.signing_audit IS 'Попытки подписи транзакций ключами signer_wallets: кто (API-ключ или админка), каким кошельком, результат.';
+COMMENT ON COLUMN public.signing_audit.source IS 'api_key — sign-and-send по X-API-Key, admin — подпись из админки по X-Admin-Token';
+COMMENT ON COLUMN public.signing_audit.status IS 'denied — нет права на кошелёк, failed — ошибка подписи, rejected — нода отклонила транзакцию, sent — транзакция в мемпуле';
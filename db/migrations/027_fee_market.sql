@@ -0,0 +1,11 @@
+-- Рынок комиссий (core/fees.go): base fee блока выводится из заполненности родителя и зачисляется в казну
+-- (treasury_address) или сжигается, сверх неё отправитель платит чаевые предлагающему блок.
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+ALTER TABLE public.blocks ADD COLUMN IF NOT EXISTS base_fee NUMERIC(78, 0);
+ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS max_fee_per_gas NUMERIC(78, 0);
+ALTER TABLE public.transactions ADD COLUMN IF NOT EXISTS max_priority_fee_per_gas NUMERIC(78, 0);
+
+COMMENT ON COLUMN public.blocks.base_fee IS 'Base fee блока в GND за единицу газа (из gas_used и base_fee родителя); NULL — блок до рынка комиссий.';
+COMMENT ON COLUMN public.transactions.max_fee_per_gas IS 'Наибольшая цена газа транзакции рынка комиссий; NULL — обычная транзакция с gas_price. Для транзакции рынка комиссий gas_price = max_fee_per_gas.';
+COMMENT ON COLUMN public.transactions.max_priority_fee_per_gas IS 'Наибольшие чаевые предлагающему блок за единицу газа сверх base fee.';
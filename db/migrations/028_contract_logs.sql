@@ -0,0 +1,36 @@
+-- События контрактов из исполнения транзакций (core/contract_logs.go): место в цепи (блок, транзакция, log_index)
+-- и темы topic0–topic3 с индексами для выборки GET /api/v1/logs; расшифровка — по contracts.abi при выборке.
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+CREATE TABLE IF NOT EXISTS public.contract_logs (
+    id           BIGSERIAL PRIMARY KEY,
+    block_id     BIGINT,
+    block_number BIGINT NOT NULL,
+    block_hash   VARCHAR NOT NULL,
+    tx_hash      VARCHAR NOT NULL,
+    tx_index     INTEGER NOT NULL,
+    log_index    INTEGER NOT NULL,
+    address      VARCHAR NOT NULL,
+    topic0       VARCHAR(66),
+    topic1       VARCHAR(66),
+    topic2       VARCHAR(66),
+    topic3       VARCHAR(66),
+    topics_count SMALLINT NOT NULL DEFAULT 0,
+    data         BYTEA,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_contract_log UNIQUE (block_hash, log_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_contract_logs_address ON public.contract_logs (address, block_number);
+CREATE INDEX IF NOT EXISTS idx_contract_logs_topic0 ON public.contract_logs (topic0, block_number);
+CREATE INDEX IF NOT EXISTS idx_contract_logs_topic1 ON public.contract_logs (topic1, block_number);
+CREATE INDEX IF NOT EXISTS idx_contract_logs_topic2 ON public.contract_logs (topic2, block_number);
+CREATE INDEX IF NOT EXISTS idx_contract_logs_topic3 ON public.contract_logs (topic3, block_number);
+CREATE INDEX IF NOT EXISTS idx_contract_logs_block ON public.contract_logs (block_number, log_index);
+CREATE INDEX IF NOT EXISTS idx_contract_logs_tx_hash ON public.contract_logs (tx_hash);
+CREATE INDEX IF NOT EXISTS idx_contract_logs_block_id ON public.contract_logs (block_id);
+
+COMMENT ON TABLE public.contract_logs IS 'События контрактов (LOG0–LOG4) успешных транзакций блоков цепи; события блоков, отменённых реорганизацией, удаляются.';
+COMMENT ON COLUMN public.contract_logs.log_index IS 'Порядковый номер события в блоке (как logIndex квитанции).';
+COMMENT ON COLUMN public.contract_logs.topic0 IS 'Первая тема — keccak256 сигнатуры события (hex с 0x, нижний регистр); NULL — событие без тем.';
+COMMENT ON COLUMN public.contract_logs.topics_count IS 'Число тем события (0–4).';
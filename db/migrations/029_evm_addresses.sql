@@ -0,0 +1,9 @@
+-- Соответствие адресов EVM кошелькам ГАНИМЕД (vm/address.go): адрес кошелька в EVM — последние 20 байт keccak256
+-- от строки адреса, обратное преобразование возможно только по этой таблице (в памяти ноды — ограниченный кэш).
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+CREATE TABLE IF NOT EXISTS public.evm_addresses (
+    evm_address VARCHAR(42) PRIMARY KEY,
+    address     VARCHAR     NOT NULL,
+    created_at  TIMESTAMP   NOT NULL DEFAULT NOW()
+);
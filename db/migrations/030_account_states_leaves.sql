@@ -0,0 +1,12 @@
+-- Полные листы дерева состояния в account_states: балансы всех символов и хеш runtime-кода (storage_root заполняется).
+-- Строка пишется в State.SaveToDB для аккаунтов, лист которых изменился в блоке; по последним строкам на блок N
+-- и contract_storage нода восстанавливает состояние после блока N для доказательств (core.Blockchain.AccountProof, StorageProof).
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+ALTER TABLE public.account_states ADD COLUMN IF NOT EXISTS balances JSONB;
+ALTER TABLE public.account_states ADD COLUMN IF NOT EXISTS code_hash BYTEA;
+
+CREATE INDEX IF NOT EXISTS idx_account_states_address_block ON public.account_states (address, block_id);
+
+COMMENT ON COLUMN public.account_states.balances IS 'Ненулевые балансы аккаунта после блока: символ → сумма (десятичная строка). NULL — строка записана до миграции 030.';
+COMMENT ON COLUMN public.account_states.code_hash IS 'sha256 runtime-кода контракта после блока (NULL — кода нет).';
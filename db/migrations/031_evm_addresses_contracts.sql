@@ -0,0 +1,10 @@
+-- Соответствие адресов EVM контрактам GNDct в evm_addresses: vm.FromEVMAddress восстанавливает контракт по записанному
+-- соответствию, а не по нулевым старшим байтам адреса. Новые контракты записываются нодой после принятия блока,
+-- здесь — уже задеплоенные (GNDct + 32 hex → 0x + 8 нулей + 32 hex).
+-- | KB @CerberRus00 - Nexus Invest Team 2026
+
+INSERT INTO public.evm_addresses (evm_address, address)
+SELECT '0x00000000' || lower(substring(address FROM 6)), address
+FROM public.contracts
+WHERE address ~ '^GNDct[0-9a-fA-F]{32}$'
+ON CONFLICT (evm_address) DO NOTHING;
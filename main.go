@@ -5,6 +5,7 @@ import (
 	"GND/api"
 	"GND/consensus"
 	"GND/core"
+	"GND/p2p"
 	"GND/signing_service/crypto"
 	"GND/signing_service/service"
 	"GND/signing_service/storage"
@@ -12,9 +13,9 @@ import (
 	"GND/vm"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
+	"math/big"
 	"net"
 	_ "net/http/pprof"
 	"os"
@@ -54,7 +55,7 @@ func main() {
 				"type":                "pos",
 				"average_block_delay": "60s",
 				"initial_base_target": 153722867,
-				"initial_balance":     "10000000",
+				"initial_balance":     "1000000000",
 			},
 		}
 	}
@@ -72,6 +73,20 @@ func main() {
 		log.Fatal("Конфигурация PoA не найдена")
 	}
 	consensus.InitPoaConsensus(&poaConfig)
+
+	// Извлечение настройки PoS
+	var posConfig core.ConsensusPosConfig
+	for _, c := range cfg.Consensus {
+		if c["type"] == "pos" {
+			data, _ := json.Marshal(c)
+			json.Unmarshal(data, &posConfig)
+			break
+		}
+	}
+	if posConfig.Type == "" {
+		log.Fatal("Конфигурация PoS не найдена")
+	}
+	consensus.InitPosConsensus(&posConfig)
 	consensus.LoadSelectionRules("config/consensus.json")
 
 	// 3. Инициализация пула соединений
@@ -139,9 +154,17 @@ func main() {
 			log.Fatalf("Ошибка дополнения системных транзакций: %v", err)
 		}
 	}
+	// Сеть ноды для защиты от повтора транзакций: chain_id (0 — как eth_chainId, vm.DefaultChainID) и subnet_id
+	blockchain.ChainID, blockchain.SubnetID = cfg.ChainID, cfg.SubnetID
+	if blockchain.ChainID == 0 {
+		blockchain.ChainID = vm.DefaultChainID
+	}
 	// Глобальное состояние для processTransactions и HasSufficientBalance
 	if st, ok := blockchain.State.(*core.State); ok {
 		core.SetState(st)
+		// Соответствие адресов EVM кошелькам ГАНИМЕД хранится в evm_addresses; записывается после принятия блока
+		vm.SetAddressStore(st)
+		blockchain.OnBlock(func(*core.Block, []*core.Receipt) { vm.FlushAddresses() })
 	}
 
 	// 11. EVM
@@ -159,7 +182,10 @@ func main() {
 		GasLimit:   gasLimit,
 		Coins:      convertCoinsToInterface(cfg.Coins),
 		SolcPath:   solcPath,
+		ChainID:    cfg.ChainID,
 	})
+	// Вызовы контрактов в блоках исполняются байткодом через EVM
+	blockchain.Executor = evmInstance
 
 	// 8. Первый запуск: деплой монет из config (если ещё нет в БД), генезис, начисление балансов
 	if !genesisExists {
@@ -176,21 +202,19 @@ func main() {
 		fmt.Printf("%s: %s  %s. Знаков: %d\n", coin.Name, balance.String(), coin.Symbol, coin.Decimals)
 	}
 
-	// 10. Мемпул и привязка к блокчейну (API добавляет в bc.Mempool, блок-продюсер забирает из того же мемпула)
-	mempool := core.NewMempool()
-	blockchain.Mempool = mempool
+	// 10. Мемпул и привязка к блокчейну (API добавляет в bc.Mempool, блок-продюсер забирает из того же мемпула; лимиты — config.json, секция mempool)
+	mempool := core.NewMempoolWithConfig(cfg.Mempool)
+	blockchain.SetMempool(mempool)
 	// Загружаем ожидающие транзакции из БД в мемпул, чтобы после перезапуска они попали в следующий блок.
-	// Проверяем nonce — если состояние изменилось (другая tx от того же отправителя уже применена), не добавляем.
+	// Транзакции с уже использованным nonce мемпул отклоняет, с будущим nonce — кладёт в queued до заполнения пропуска.
 	if pool != nil {
 		if pending, err := core.LoadPendingTransactionsFromDB(ctx, pool); err == nil && len(pending) > 0 {
 			added := 0
 			for _, tx := range pending {
-				expected := blockchain.State.GetNonce(types.Address(tx.Sender))
-				if int64(tx.Nonce) != expected {
-					fmt.Printf("[Mempool] Транзакция %s пропущена при загрузке: неверный nonce (ожидается %d, в tx %d)\n", tx.Hash, expected, tx.Nonce)
+				if err := mempool.Add(tx); err != nil {
+					fmt.Printf("[Mempool] Транзакция %s пропущена при загрузке: %v\n", tx.Hash, err)
 					continue
 				}
-				_ = mempool.Add(tx)
 				added++
 			}
 			if added > 0 {
@@ -218,7 +242,7 @@ func main() {
 
 	// 13. Серверы
 	go func() {
-		err := api.StartRPCServer(evmInstance, cfg.Server.RPC.RPCAddr)
+		err := api.StartRPCServer(evmInstance, blockchain, mempool, cfg.ChainID, cfg.Server.RPC.RPCAddr)
 		if err != nil {
 			fmt.Printf("Ошибка запуска RPCServer %s:\n", err)
 		}
@@ -226,15 +250,64 @@ func main() {
 	go api.StartRESTServer(blockchain, mempool, cfg, pool, evmInstance, signerCreator)
 	go api.StartWebSocketServer(blockchain, mempool, cfg)
 
-	// 14. Обработка транзакций
-	go processTransactions(mempool, cfg.MaxWorkers)
+	// 13a. P2P: статические пиры из config.json (секция p2p), рассылка транзакций мемпула, блоков и голосов финальности
+	p2pNode, err := p2p.NewNode(cfg, blockchain)
+	if err != nil {
+		log.Fatalf("Ошибка инициализации P2P: %v", err)
+	}
+	if err := p2pNode.Start(); err != nil {
+		log.Fatalf("Ошибка запуска P2P: %v", err)
+	}
+	defer p2pNode.Stop()
 
-	// 14a. Производство блоков: по таймеру создаём новый блок и включаем транзакции из мемпула
+	// 14. Производство блоков (единственный потребитель мемпула). consensus_type из config.json выбирает движок:
+	// poa — предлагающий по очереди из poa_validators на каждый слот round_duration;
+	// pos — предлагающий с весом по стейку из реестра стейкинга (pos_validators). Блоки подписываются и проверяются в AddBlock,
+	// финализируются голосами валидаторов (prevote/precommit).
+	stakingCfg := core.StakingConfig{UnbondingBlocks: posConfig.UnbondingBlocks}
+	if reward, ok := new(big.Int).SetString(posConfig.BlockReward, 10); ok {
+		stakingCfg.BlockReward = reward
+	}
+	blockchain.Stakes.SetConfig(stakingCfg)
 	blockInterval, err := time.ParseDuration(poaConfig.RoundDuration)
 	if err != nil || blockInterval <= 0 {
 		blockInterval = 17 * time.Second
 	}
-	go runBlockProducer(blockchain, mempool, string(minerWallet.Address), blockInterval, 100)
+	var engine consensus.Consensus
+	var voters consensus.VoterSet
+	switch {
+	case cfg.ConsensusType == "pos":
+		pos, err := consensus.NewPoS(&posConfig, pool, minerWallet)
+		if err != nil {
+			log.Fatalf("Ошибка инициализации PoS: %v", err)
+		}
+		pos.Attach(blockchain)
+		engine, voters = pos, pos
+	case minerWallet.PrivateKey != nil || len(cfg.P2P.Peers) > 0:
+		// Без ключа нода с пирами не создаёт блоки, а проверяет блоки валидаторов, полученные по сети
+		poaCfg := poaConfig
+		poaCfg.RoundDuration = blockInterval.String()
+		poa, err := consensus.NewPoA(&poaCfg, pool, blockchain.Genesis.Timestamp, minerWallet)
+		if err != nil {
+			log.Fatalf("Ошибка инициализации PoA: %v", err)
+		}
+		if err := poa.LoadValidators(ctx); err != nil {
+			log.Fatalf("Ошибка загрузки валидаторов PoA: %v", err)
+		}
+		poa.Attach(blockchain)
+		engine, voters = poa, poa
+	default:
+		log.Printf("Ключ валидатора не найден: блоки создаются по таймеру без подписи PoA и финализируются сразу")
+		go runBlockProducer(blockchain, mempool, string(minerWallet.Address), blockInterval, 100)
+	}
+	if engine != nil {
+		// Слой финальности: блок становится finalized только после precommit более 2/3 веса валидаторов
+		finality := consensus.NewFinality(voters, minerWallet)
+		finality.Attach(blockchain)
+		finality.OnVote(p2pNode.BroadcastVote)
+		engine.Start(blockchain, mempool)
+		defer engine.Stop()
+	}
 
 	// 15. Грейсфул-шатдаун
 	sigs := make(chan os.Signal, 1)
@@ -267,178 +340,6 @@ func runBlockProducer(blockchain *core.Blockchain, mempool *core.Mempool, miner
 	}
 }
 
-// Горутины обработки транзакций
-func processTransactions(mempool *core.Mempool, maxWorkers int) {
-	sem := make(chan struct{}, maxWorkers)
-	logger := log.New(os.Stdout, "[TX Processor] ", log.LstdFlags)
-
-	for {
-		sem <- struct{}{}
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					logger.Printf("Panic recovered in transaction processor: %v", r)
-				}
-				<-sem
-			}()
-
-			tx, err := mempool.Pop()
-			if err != nil {
-				if err.Error() == "timeout" {
-					return
-				}
-				if errors.Is(err, core.ErrSkip) {
-					// contract_call оставлен в мемпуле для блок-продюсера
-					return
-				}
-				logger.Printf("Error popping transaction from mempool: %v", err)
-				return
-			}
-
-			consType := consensus.SelectConsensus(tx.Type, string(tx.Recipient))
-			logger.Printf("Processing transaction %s (type=%s) through %s consensus", tx.ID, tx.Type, consType)
-
-			// Process transaction based on consensus type
-			switch consType {
-			case consensus.ConsensusPoS:
-				if err := processPoSTransaction(tx); err != nil {
-					logger.Printf("Error processing PoS transaction %s: %v", tx.ID, err)
-					return
-				}
-				logger.Printf("Successfully processed PoS transaction %s", tx.ID)
-			case consensus.ConsensusPoA:
-				if err := processPoATransaction(tx); err != nil {
-					logger.Printf("Error processing PoA transaction %s: %v", tx.ID, err)
-					return
-				}
-				logger.Printf("Successfully processed PoA transaction %s", tx.ID)
-			default:
-				logger.Printf("Transaction %s: unknown consensus type", tx.ID)
-			}
-		}()
-	}
-}
-
-func processPoSTransaction(tx *core.Transaction) error {
-	// Validate transaction
-	if err := tx.Validate(); err != nil {
-		return fmt.Errorf("transaction validation failed: %v", err)
-	}
-
-	// Check sender's balance
-	if !tx.HasSufficientBalance() {
-		return fmt.Errorf("insufficient balance for transaction")
-	}
-
-	// Process transaction through EVM if it's a contract call
-	if tx.IsContractCall() {
-		return processContractTransaction(tx)
-	}
-
-	// Process regular transfer
-	return processTransferTransaction(tx)
-}
-
-func processPoATransaction(tx *core.Transaction) error {
-	// Validate transaction
-	if err := tx.Validate(); err != nil {
-		return fmt.Errorf("transaction validation failed: %v", err)
-	}
-
-	// Check sender's balance
-	if !tx.HasSufficientBalance() {
-		return fmt.Errorf("insufficient balance for transaction")
-	}
-
-	// Process transaction through EVM if it's a contract call
-	if tx.IsContractCall() {
-		return processContractTransaction(tx)
-	}
-
-	// Process regular transfer
-	return processTransferTransaction(tx)
-}
-
-func processContractTransaction(tx *core.Transaction) error {
-	// Get state
-	state := core.GetState()
-	if state == nil {
-		return fmt.Errorf("state not available")
-	}
-
-	// Create EVM instance
-	evm := vm.NewEVM(vm.EVMConfig{
-		State:    state,
-		GasLimit: 1000000, // Default gas limit
-	})
-
-	// Execute transaction in EVM
-	result, err := evm.CallContract(
-		string(tx.Sender),
-		string(tx.Recipient),
-		tx.Data,
-		tx.GasLimit,
-		tx.GasPrice.Uint64(),
-		tx.Value.Uint64(),
-	)
-	if err != nil {
-		return fmt.Errorf("EVM execution failed: %v", err)
-	}
-
-	// Update state with result
-	if err := updateStateWithResult(tx, result); err != nil {
-		return fmt.Errorf("failed to update state: %v", err)
-	}
-
-	return nil
-}
-
-func processTransferTransaction(tx *core.Transaction) error {
-	// Update balances
-	if err := updateBalances(tx); err != nil {
-		return fmt.Errorf("failed to update balances: %v", err)
-	}
-
-	return nil
-}
-
-func updateBalances(tx *core.Transaction) error {
-	// Get state
-	state := core.GetState()
-	if state == nil {
-		return fmt.Errorf("state not available")
-	}
-
-	// Update sender's balance
-	if err := state.SubBalance(tx.Sender, "GND", tx.Value); err != nil {
-		return err
-	}
-
-	// Update recipient's balance
-	if err := state.AddBalance(tx.Recipient, "GND", tx.Value); err != nil {
-		// Revert sender's balance if recipient update fails
-		state.AddBalance(tx.Sender, "GND", tx.Value)
-		return err
-	}
-
-	return nil
-}
-
-func updateStateWithResult(tx *core.Transaction, result *types.ExecutionResult) error {
-	// Get state
-	state := core.GetState()
-	if state == nil {
-		return fmt.Errorf("state not available")
-	}
-
-	// Update state with execution result
-	if err := state.ApplyExecutionResult(tx, result); err != nil {
-		return fmt.Errorf("failed to apply execution result: %v", err)
-	}
-
-	return nil
-}
-
 // Функция для конвертации []core.CoinConfig в []vm.CoinConfig
 func convertCoinsToInterface(coins []core.CoinConfig) []vm.CoinConfig {
 	result := make([]vm.CoinConfig, len(coins))
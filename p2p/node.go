@@ -0,0 +1,782 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// p2p/node.go — P2P-нода: TCP-соединения со статическими и входящими пирами, рассылка транзакций, блоков и голосов.
+// Загрузка цепи от пиров — p2p/sync.go.
+package p2p
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"GND/core"
+)
+
+const (
+	defaultListenPort   = 30303
+	defaultMaxPeers     = 25
+	defaultDialInterval = 10 * time.Second
+	handshakeTimeout    = 5 * time.Second
+	writeTimeout        = 10 * time.Second
+	peerSendQueue       = 256   // сообщений в очереди отправки пира; при переполнении новые пропускаются
+	seenCacheSize       = 16384 // последних хешей транзакций, блоков и голосов для отсева повторов
+	maxFutureBlocks     = 64    // блоков, ожидающих родителя
+	maxPendingVotes     = 1024  // голосов за высоты, которых ещё нет в цепи
+)
+
+// PeerInfo — сведения о подключённом пире.
+type PeerInfo struct {
+	NodeID     string `json:"node_id"`
+	NodeName   string `json:"node_name"`
+	RemoteAddr string `json:"remote_addr"`
+	ListenAddr string `json:"listen_addr"`
+	Height     uint64 `json:"height"` // высота из рукопожатия или последнего полученного блока
+	Inbound    bool   `json:"inbound"`
+}
+
+type peer struct {
+	conn     net.Conn
+	info     PeerInfo
+	dialAddr string // адрес из списка статических пиров; пусто — входящее соединение
+	send     chan []byte
+	closed   chan struct{}
+	once     sync.Once
+
+	pendingMu sync.Mutex
+	pending   map[uint64]chan message // ожидающие ответа запросы синхронизации по id
+}
+
+func (p *peer) close() {
+	p.once.Do(func() {
+		close(p.closed)
+		p.conn.Close()
+	})
+}
+
+// inbound — сообщение пира для обработчика.
+type inbound struct {
+	peer *peer
+	msg  message
+}
+
+// Node — P2P-нода. Подключается к статическим пирам из config.json и принимает входящие соединения; после рукопожатия
+// (версия протокола, chain_id, subnet_id) рассылает пирам новые транзакции мемпула, блоки цепи и голоса финальности.
+// Полученные сообщения обрабатываются по одному: транзакции — в мемпул (AddPeerTransaction), блоки — в AddBlock,
+// голоса — в слой финальности. Каждое сообщение пересылается дальше один раз, повторы отсеиваются по хешу.
+// Отставшая нода догоняет пира с наибольшей высотой запросами синхронизации (p2p/sync.go).
+type Node struct {
+	bc               *core.Blockchain
+	local            Hello
+	listenAddr       string
+	staticPeers      []string
+	maxPeers         int
+	dialInterval     time.Duration
+	syncMode         string
+	snapshotInterval uint64
+
+	mu       sync.Mutex
+	listener net.Listener
+	peers    map[string]*peer  // по NodeID
+	dialing  map[string]bool   // статические адреса, к которым идёт подключение
+	addrIDs  map[string]string // статический адрес → NodeID ноды по этому адресу
+
+	seen  *seenCache
+	inbox chan inbound
+	// только для обработчика сообщений
+	futureBlocks map[string]*core.Block  // блоки выше вершины цепи по PrevHash
+	pendingVotes map[uint64][]*core.Vote // голоса за высоты выше вершины цепи
+	pendingCount int
+
+	reqID    atomic.Uint64
+	syncCh   chan struct{}
+	syncing  atomic.Bool
+	fastDone bool // только для загрузчика: быстрая синхронизация уже выполнялась или не нужна
+
+	statusMu sync.Mutex
+	status   core.SyncStatus
+
+	snapMu    sync.Mutex
+	snapshots []*core.StateSnapshot // последние снимки состояния, от старого к новому
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+	logger *log.Logger
+}
+
+// NewNode создаёт P2P-ноду по секции p2p и идентификаторам сети (chain_id, subnet_id, network_id) из cfg.
+func NewNode(cfg *core.Config, bc *core.Blockchain) (*Node, error) {
+	if cfg == nil || bc == nil {
+		return nil, errors.New("p2p: не заданы конфиг или цепь")
+	}
+	listen := strings.TrimSpace(cfg.P2P.ListenAddr)
+	if listen == "" {
+		port := cfg.Port
+		if port == 0 {
+			port = defaultListenPort
+		}
+		listen = ":" + strconv.Itoa(port)
+	}
+	dialInterval := defaultDialInterval
+	if cfg.P2P.DialInterval != "" {
+		d, err := time.ParseDuration(cfg.P2P.DialInterval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("p2p: неверный dial_interval %q", cfg.P2P.DialInterval)
+		}
+		dialInterval = d
+	}
+	maxPeers := cfg.P2P.MaxPeers
+	if maxPeers <= 0 {
+		maxPeers = defaultMaxPeers
+	}
+	syncMode := strings.TrimSpace(cfg.P2P.SyncMode)
+	switch syncMode {
+	case "":
+		syncMode = core.SyncModeFull
+	case core.SyncModeFull, core.SyncModeFast:
+	default:
+		return nil, fmt.Errorf("p2p: неверный sync_mode %q (full или fast)", cfg.P2P.SyncMode)
+	}
+	snapshotInterval := cfg.P2P.SnapshotInterval
+	if snapshotInterval == 0 {
+		snapshotInterval = defaultSnapshotInterval
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("p2p: идентификатор ноды: %w", err)
+	}
+	var peers []string
+	for _, addr := range cfg.P2P.Peers {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			peers = append(peers, addr)
+		}
+	}
+	return &Node{
+		bc: bc,
+		local: Hello{
+			Version:   ProtocolVersion,
+			ChainID:   cfg.ChainID,
+			SubnetID:  cfg.SubnetID,
+			NetworkID: cfg.NetworkID,
+			NodeID:    hex.EncodeToString(id),
+			NodeName:  cfg.NodeName,
+		},
+		listenAddr:       listen,
+		staticPeers:      peers,
+		maxPeers:         maxPeers,
+		dialInterval:     dialInterval,
+		syncMode:         syncMode,
+		snapshotInterval: snapshotInterval,
+		peers:            make(map[string]*peer),
+		dialing:          make(map[string]bool),
+		addrIDs:          make(map[string]string),
+		seen:             newSeenCache(seenCacheSize),
+		inbox:            make(chan inbound, peerSendQueue),
+		futureBlocks:     make(map[string]*core.Block),
+		pendingVotes:     make(map[uint64][]*core.Vote),
+		syncCh:           make(chan struct{}, 1),
+		status:           core.SyncStatus{Mode: syncMode, Stage: syncStageIdle},
+		stopCh:           make(chan struct{}),
+		logger:           log.New(os.Stdout, "[P2P] ", log.LstdFlags),
+	}, nil
+}
+
+// Start открывает порт входящих соединений, подписывается на новые блоки и транзакции мемпула,
+// запускает подключение к статическим пирам и загрузку цепи от пиров.
+func (n *Node) Start() error {
+	l, err := net.Listen("tcp", n.listenAddr)
+	if err != nil {
+		return fmt.Errorf("p2p: прослушивание %s: %w", n.listenAddr, err)
+	}
+	n.mu.Lock()
+	n.listener = l
+	n.local.ListenAddr = l.Addr().String()
+	n.mu.Unlock()
+
+	n.bc.OnBlock(n.onBlock)
+	if n.bc.Mempool != nil {
+		n.bc.Mempool.OnTx(n.onTx)
+	}
+	n.bc.Sync = n
+	n.wg.Add(4)
+	go n.acceptLoop()
+	go n.dialLoop()
+	go n.handleLoop()
+	go n.syncLoop()
+	n.logger.Printf("Запущена: %s, node_id %s, chain_id %d, статических пиров %d", l.Addr(), n.local.NodeID, n.local.ChainID, len(n.staticPeers))
+	return nil
+}
+
+// Stop закрывает порт и соединения с пирами.
+func (n *Node) Stop() {
+	n.mu.Lock()
+	select {
+	case <-n.stopCh:
+		n.mu.Unlock()
+		return
+	default:
+	}
+	close(n.stopCh)
+	if n.listener != nil {
+		n.listener.Close()
+	}
+	for _, p := range n.peers {
+		p.close()
+	}
+	n.mu.Unlock()
+	n.wg.Wait()
+}
+
+// Addr возвращает адрес, на котором нода принимает соединения (после Start).
+func (n *Node) Addr() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.listener == nil {
+		return ""
+	}
+	return n.listener.Addr().String()
+}
+
+// ID возвращает идентификатор ноды в сети.
+func (n *Node) ID() string {
+	return n.local.NodeID
+}
+
+// PeerCount возвращает число подключённых пиров.
+func (n *Node) PeerCount() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.peers)
+}
+
+// Peers возвращает сведения о подключённых пирах.
+func (n *Node) Peers() []PeerInfo {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	out := make([]PeerInfo, 0, len(n.peers))
+	for _, p := range n.peers {
+		out = append(out, p.info)
+	}
+	return out
+}
+
+// BroadcastVote рассылает голос валидатора пирам; подключается к Finality.OnVote.
+func (n *Node) BroadcastVote(v *core.Vote) {
+	n.seen.add(voteKey(v))
+	n.broadcast(msgVote, v)
+}
+
+// onBlock рассылает пирам блок, добавленный в цепь (свой или полученный от пира), и каждые snapshot_interval блоков
+// снимает состояние для быстрой синхронизации пиров. Вызывается под блокировкой цепи.
+// Блоки, загружаемые при синхронизации, не рассылаются: они уже есть у пиров.
+func (n *Node) onBlock(block *core.Block, _ []*core.Receipt) {
+	n.seen.add(blockKey(block.Hash))
+	if block.Index%n.snapshotInterval == 0 {
+		n.captureSnapshot(block)
+	}
+	if n.syncing.Load() {
+		return
+	}
+	n.broadcast(msgBlock, block)
+}
+
+// onTx рассылает пирам транзакцию, принятую в мемпул.
+func (n *Node) onTx(tx *core.Transaction) {
+	n.seen.add(txKey(tx.Hash))
+	n.broadcast(msgTx, tx)
+}
+
+// broadcast кодирует сообщение сразу (данные могут измениться после возврата) и ставит его в очереди отправки пиров.
+func (n *Node) broadcast(msgType string, data interface{}) {
+	payload, err := encodeMessage(msgType, data)
+	if err != nil {
+		n.logger.Printf("Сообщение %s не закодировано: %v", msgType, err)
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, p := range n.peers {
+		select {
+		case p.send <- payload:
+		default:
+			n.logger.Printf("Очередь отправки пира %s переполнена, сообщение %s пропущено", p.info.NodeName, msgType)
+		}
+	}
+}
+
+func (n *Node) acceptLoop() {
+	defer n.wg.Done()
+	for {
+		conn, err := n.listener.Accept()
+		if err != nil {
+			select {
+			case <-n.stopCh:
+				return
+			default:
+			}
+			n.logger.Printf("Ошибка приёма соединения: %v", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			n.serveConn(conn, "")
+		}()
+	}
+}
+
+// dialLoop подключается к статическим пирам при запуске и каждые dial_interval, если соединения с ними нет.
+func (n *Node) dialLoop() {
+	defer n.wg.Done()
+	ticker := time.NewTicker(n.dialInterval)
+	defer ticker.Stop()
+	for {
+		n.dialStatic()
+		select {
+		case <-n.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *Node) dialStatic() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, addr := range n.staticPeers {
+		if n.dialing[addr] {
+			continue
+		}
+		if id, ok := n.addrIDs[addr]; ok {
+			if _, connected := n.peers[id]; connected || id == n.local.NodeID {
+				continue
+			}
+		}
+		if len(n.peers) >= n.maxPeers {
+			return
+		}
+		n.dialing[addr] = true
+		n.wg.Add(1)
+		go n.dial(addr)
+	}
+}
+
+func (n *Node) dial(addr string) {
+	defer n.wg.Done()
+	defer func() {
+		n.mu.Lock()
+		delete(n.dialing, addr)
+		n.mu.Unlock()
+	}()
+	conn, err := (&net.Dialer{Timeout: handshakeTimeout}).Dial("tcp", addr)
+	if err != nil {
+		n.logger.Printf("Пир %s недоступен: %v", addr, err)
+		return
+	}
+	n.serveConn(conn, addr)
+}
+
+// serveConn выполняет рукопожатие, регистрирует пира и читает его сообщения до разрыва соединения.
+func (n *Node) serveConn(conn net.Conn, dialAddr string) {
+	remote, sc, err := n.handshake(conn)
+	if remote != nil && dialAddr != "" {
+		n.mu.Lock()
+		n.addrIDs[dialAddr] = remote.NodeID
+		n.mu.Unlock()
+	}
+	if err != nil {
+		if !errors.Is(err, errSelfConnection) {
+			n.logger.Printf("Рукопожатие с %s не выполнено: %v", conn.RemoteAddr(), err)
+		}
+		reject(conn, err)
+		return
+	}
+	p := &peer{
+		conn: conn,
+		info: PeerInfo{
+			NodeID:     remote.NodeID,
+			NodeName:   remote.NodeName,
+			RemoteAddr: conn.RemoteAddr().String(),
+			ListenAddr: remote.ListenAddr,
+			Height:     remote.Height,
+			Inbound:    dialAddr == "",
+		},
+		dialAddr: dialAddr,
+		send:     make(chan []byte, peerSendQueue),
+		closed:   make(chan struct{}),
+		pending:  make(map[uint64]chan message),
+	}
+	if err := n.addPeer(p); err != nil {
+		reject(conn, err)
+		return
+	}
+	n.logger.Printf("Подключён пир %s (%s, %s), высота %d", p.info.NodeName, p.info.NodeID, p.info.RemoteAddr, p.info.Height)
+	n.requestSync()
+	n.wg.Add(1)
+	go n.writeLoop(p)
+	n.readLoop(p, sc)
+	n.removePeer(p)
+}
+
+// handshake обменивается сообщениями hello и проверяет совместимость. Возвращает hello пира, если оно получено.
+func (n *Node) handshake(conn net.Conn) (*Hello, *bufio.Scanner, error) {
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	n.mu.Lock()
+	local := n.local
+	n.mu.Unlock()
+	local.Height = n.bc.Height()
+	data, err := encodeMessage(msgHello, local)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := conn.Write(data); err != nil {
+		return nil, nil, err
+	}
+	sc := bufio.NewScanner(conn)
+	sc.Buffer(make([]byte, 64*1024), maxMessageSize)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("соединение закрыто до рукопожатия")
+	}
+	var msg message
+	if err := json.Unmarshal(sc.Bytes(), &msg); err != nil {
+		return nil, nil, fmt.Errorf("неверное сообщение: %w", err)
+	}
+	switch msg.Type {
+	case msgHello:
+	case msgDisconnect:
+		var reason disconnectReason
+		_ = json.Unmarshal(msg.Data, &reason)
+		return nil, nil, fmt.Errorf("пир отклонил соединение: %s", reason.Reason)
+	default:
+		return nil, nil, fmt.Errorf("ожидалось hello, получено %q", msg.Type)
+	}
+	var remote Hello
+	if err := json.Unmarshal(msg.Data, &remote); err != nil {
+		return nil, nil, fmt.Errorf("неверное hello: %w", err)
+	}
+	if err := checkHello(&local, &remote); err != nil {
+		return &remote, nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return &remote, sc, nil
+}
+
+// reject сообщает пиру причину отказа и закрывает соединение.
+func reject(conn net.Conn, reason error) {
+	if data, err := encodeMessage(msgDisconnect, disconnectReason{Reason: reason.Error()}); err == nil {
+		_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+		_, _ = conn.Write(data)
+	}
+	conn.Close()
+}
+
+// addPeer регистрирует пира. При встречных соединениях двух нод обе стороны оставляют соединение,
+// открытое нодой с меньшим NodeID, второе закрывается.
+func (n *Node) addPeer(p *peer) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	select {
+	case <-n.stopCh:
+		return errors.New("нода остановлена")
+	default:
+	}
+	id := p.info.NodeID
+	if old, ok := n.peers[id]; ok {
+		if !n.preferred(p) {
+			return errors.New("соединение с нодой уже установлено")
+		}
+		old.close()
+	} else if len(n.peers) >= n.maxPeers {
+		return fmt.Errorf("достигнут предел пиров (%d)", n.maxPeers)
+	}
+	n.peers[id] = p
+	core.GetMetrics().UpdatePeerMetrics(uint64(len(n.peers)))
+	return nil
+}
+
+func (n *Node) preferred(p *peer) bool {
+	if p.info.Inbound {
+		return p.info.NodeID < n.local.NodeID
+	}
+	return n.local.NodeID < p.info.NodeID
+}
+
+func (n *Node) removePeer(p *peer) {
+	p.close()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.peers[p.info.NodeID] == p {
+		delete(n.peers, p.info.NodeID)
+		core.GetMetrics().UpdatePeerMetrics(uint64(len(n.peers)))
+		n.logger.Printf("Пир %s (%s) отключён", p.info.NodeName, p.info.RemoteAddr)
+	}
+}
+
+func (n *Node) readLoop(p *peer, sc *bufio.Scanner) {
+	for sc.Scan() {
+		line := sc.Bytes()
+		core.GetMetrics().AddNetworkTraffic(0, uint64(len(line)+1))
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			n.logger.Printf("Пир %s: неверное сообщение: %v", p.info.NodeName, err)
+			return
+		}
+		switch msg.Type {
+		case msgDisconnect:
+			var reason disconnectReason
+			_ = json.Unmarshal(msg.Data, &reason)
+			n.logger.Printf("Пир %s разорвал соединение: %s", p.info.NodeName, reason.Reason)
+			return
+		case msgGetHeaders, msgGetBodies, msgGetSnapshot:
+			// Запросы синхронизации только читают цепь — отвечаем сразу, не ожидая обработчика
+			n.serveRequest(p, msg)
+			continue
+		case msgHeaders, msgBodies, msgSnapshot:
+			p.deliver(msg)
+			continue
+		}
+		select {
+		case n.inbox <- inbound{peer: p, msg: msg}:
+		case <-p.closed:
+			return
+		case <-n.stopCh:
+			return
+		}
+	}
+	select {
+	case <-p.closed:
+	default:
+		if err := sc.Err(); err != nil {
+			n.logger.Printf("Пир %s: ошибка чтения: %v", p.info.NodeName, err)
+		}
+	}
+}
+
+func (n *Node) writeLoop(p *peer) {
+	defer n.wg.Done()
+	for {
+		select {
+		case data := <-p.send:
+			_ = p.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if _, err := p.conn.Write(data); err != nil {
+				p.close()
+				return
+			}
+			core.GetMetrics().AddNetworkTraffic(uint64(len(data)), 0)
+		case <-p.closed:
+			return
+		}
+	}
+}
+
+// handleLoop обрабатывает сообщения пиров по одному: порядок блоков и голосов сохраняется.
+func (n *Node) handleLoop() {
+	defer n.wg.Done()
+	for {
+		select {
+		case in := <-n.inbox:
+			n.handle(in)
+		case <-n.stopCh:
+			return
+		}
+	}
+}
+
+func (n *Node) handle(in inbound) {
+	switch in.msg.Type {
+	case msgTx:
+		var tx core.Transaction
+		if err := json.Unmarshal(in.msg.Data, &tx); err != nil || tx.Hash == "" {
+			n.logger.Printf("Пир %s: неверная транзакция", in.peer.info.NodeName)
+			return
+		}
+		n.handleTx(in.peer, &tx)
+	case msgBlock:
+		var block core.Block
+		if err := json.Unmarshal(in.msg.Data, &block); err != nil || block.Hash == "" {
+			n.logger.Printf("Пир %s: неверный блок", in.peer.info.NodeName)
+			return
+		}
+		n.handleBlock(in.peer, &block)
+	case msgVote:
+		var v core.Vote
+		if err := json.Unmarshal(in.msg.Data, &v); err != nil {
+			n.logger.Printf("Пир %s: неверный голос", in.peer.info.NodeName)
+			return
+		}
+		n.handleVote(in.peer, &v)
+	default:
+		n.logger.Printf("Пир %s: неизвестное сообщение %q", in.peer.info.NodeName, in.msg.Type)
+	}
+}
+
+// handleTx ставит транзакцию пира в мемпул; принятая транзакция рассылается дальше через Mempool.OnTx.
+func (n *Node) handleTx(p *peer, tx *core.Transaction) {
+	if !n.seen.add(txKey(tx.Hash)) {
+		return
+	}
+	if n.bc.Mempool != nil && n.bc.Mempool.Exists(tx.Hash) {
+		return
+	}
+	if err := n.bc.AddPeerTransaction(tx); err != nil {
+		n.logger.Printf("Транзакция %s от пира %s отклонена: %v", tx.Hash, p.info.NodeName, err)
+	}
+}
+
+// handleBlock добавляет блок пира, родитель которого известен (вершина, более ранний блок цепи или боковая ветка —
+// выбор ветки и реорганизацию выполняет AddBlock); блок выше вершины ждёт родителя, а при большом отставании
+// запускается синхронизация. Блоки не выше финализированного и с неизвестным родителем отбрасываются.
+func (n *Node) handleBlock(p *peer, block *core.Block) {
+	n.updatePeerHeight(p, block.Index)
+	key := blockKey(block.Hash)
+	if n.seen.has(key) {
+		return
+	}
+	tip, err := n.bc.LatestBlock()
+	if err != nil {
+		return
+	}
+	switch {
+	case block.Index <= n.bc.FinalizedBlock().Index || n.bc.HasBlock(block.Hash):
+		n.seen.add(key)
+		return
+	case n.bc.HasBlock(block.PrevHash):
+		// продолжает вершину или боковую ветку: выбор ветки и реорганизацию выполняет AddBlock
+	case block.Index > tip.Index+1:
+		if len(n.futureBlocks) >= maxFutureBlocks || block.Index > tip.Index+maxFutureBlocks {
+			n.requestSync()
+			return
+		}
+		n.futureBlocks[block.PrevHash] = block
+		return
+	default:
+		n.seen.add(key)
+		n.logger.Printf("Блок %d от пира %s продолжает неизвестную ветку (родитель %s), отброшен", block.Index, p.info.NodeName, block.PrevHash)
+		return
+	}
+	for block != nil {
+		if err := n.importBlock(block); err != nil {
+			n.logger.Printf("Блок %d от пира %s отклонён: %v", block.Index, p.info.NodeName, err)
+			return
+		}
+		n.retryVotes(block.Index)
+		next := n.futureBlocks[block.Hash]
+		delete(n.futureBlocks, block.Hash)
+		block = next
+	}
+	for prev, b := range n.futureBlocks {
+		if b.Index <= n.bc.FinalizedBlock().Index {
+			delete(n.futureBlocks, prev)
+		}
+	}
+}
+
+// handleVote передаёт голос слою финальности; голос за высоту выше вершины цепи ждёт блока.
+func (n *Node) handleVote(p *peer, v *core.Vote) {
+	if n.bc.Finality == nil || !n.seen.add(voteKey(v)) {
+		return
+	}
+	if v.Height > n.bc.Height() {
+		if n.pendingCount < maxPendingVotes {
+			n.pendingVotes[v.Height] = append(n.pendingVotes[v.Height], v)
+			n.pendingCount++
+		}
+		return
+	}
+	if err := n.bc.Finality.AddVote(v); err != nil {
+		n.logger.Printf("Голос %s %s за блок %d от пира %s отклонён: %v", v.Type, v.Validator, v.Height, p.info.NodeName, err)
+	}
+}
+
+// importBlock добавляет блок пира в цепь (полная проверка и исполнение в AddBlock).
+func (n *Node) importBlock(block *core.Block) error {
+	n.seen.add(blockKey(block.Hash))
+	// Служебные поля — локальные для каждой ноды; статус задаёт AddBlock (с движком консенсуса) или финален сразу
+	block.ID, block.ParentID, block.IsOrphaned = 0, nil, false
+	if n.bc.Engine == nil {
+		block.Status, block.IsFinalized = core.BlockStatusFinalized, true
+	}
+	return n.bc.AddBlock(block)
+}
+
+// updatePeerHeight запоминает высоту цепи пира, если она выросла.
+func (n *Node) updatePeerHeight(p *peer, height uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if height > p.info.Height {
+		p.info.Height = height
+	}
+}
+
+// retryVotes передаёт слою финальности голоса, ожидавшие блока высоты height, и удаляет устаревшие.
+func (n *Node) retryVotes(height uint64) {
+	for h, votes := range n.pendingVotes {
+		if h > height {
+			continue
+		}
+		delete(n.pendingVotes, h)
+		n.pendingCount -= len(votes)
+		if h < height || n.bc.Finality == nil {
+			continue
+		}
+		for _, v := range votes {
+			if err := n.bc.Finality.AddVote(v); err != nil {
+				n.logger.Printf("Голос %s %s за блок %d отклонён: %v", v.Type, v.Validator, v.Height, err)
+			}
+		}
+	}
+}
+
+func txKey(hash string) string    { return "tx:" + hash }
+func blockKey(hash string) string { return "block:" + hash }
+func voteKey(v *core.Vote) string {
+	return fmt.Sprintf("vote:%s:%d:%s:%s", v.Type, v.Height, v.Validator, v.BlockHash)
+}
+
+// seenCache — множество последних ключей сообщений фиксированного размера (старые вытесняются по кругу).
+type seenCache struct {
+	mu    sync.Mutex
+	set   map[string]struct{}
+	order []string
+	next  int
+}
+
+func newSeenCache(size int) *seenCache {
+	return &seenCache{set: make(map[string]struct{}, size), order: make([]string, size)}
+}
+
+// add отмечает ключ; возвращает false, если он уже встречался.
+func (c *seenCache) add(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.set[key]; ok {
+		return false
+	}
+	if old := c.order[c.next]; old != "" {
+		delete(c.set, old)
+	}
+	c.order[c.next] = key
+	c.next = (c.next + 1) % len(c.order)
+	c.set[key] = struct{}{}
+	return true
+}
+
+func (c *seenCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.set[key]
+	return ok
+}
@@ -0,0 +1,163 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package p2p
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"GND/core"
+	"GND/core/crypto"
+	"GND/types"
+)
+
+func newTestGenesis() *core.Block {
+	genesis := &core.Block{Index: 0, Timestamp: time.Now().Add(-time.Minute), Miner: "miner", GasLimit: core.DefaultBlockGasLimit, Consensus: "poa", Status: core.BlockStatusFinalized}
+	genesis.Hash = genesis.CalculateHash()
+	return genesis
+}
+
+// startTestNode запускает ноду с собственной цепью от общего генезиса на случайном порту loopback.
+func startTestNode(t *testing.T, genesis *core.Block, name string, chainID int64, subnetID string, peers ...string) *Node {
+	t.Helper()
+	return startNodeWithConfig(t, genesis, &core.Config{NodeName: name, ChainID: chainID, SubnetID: subnetID,
+		P2P: core.P2PConfig{Peers: peers}})
+}
+
+// startNodeWithConfig запускает ноду с конфигом cfg; адрес — случайный порт loopback, переподключение — каждые 100 мс.
+func startNodeWithConfig(t *testing.T, genesis *core.Block, cfg *core.Config) *Node {
+	t.Helper()
+	g := *genesis
+	bc := core.NewBlockchain(&g, nil)
+	cfg.P2P.ListenAddr, cfg.P2P.DialInterval = "127.0.0.1:0", "100ms"
+	n, err := NewNode(cfg, bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(n.Stop)
+	return n
+}
+
+// signedContractCall возвращает подписанный ключом key вызов контракта с nonce.
+func signedContractCall(t *testing.T, key *ecdsa.PrivateKey, nonce int64) *core.Transaction {
+	t.Helper()
+	return signTestTx(t, key, &core.Transaction{Recipient: "GN_contract_1", Value: big.NewInt(0), Data: []byte{0xa9, 0x05, 0x9c, 0xbb},
+		Nonce: nonce, GasLimit: 100000})
+}
+
+// signTestTx заполняет отправителя, цену газа и публичный ключ по ключу key и подписывает транзакцию.
+func signTestTx(t *testing.T, key *ecdsa.PrivateKey, tx *core.Transaction) *core.Transaction {
+	t.Helper()
+	tx.Sender = types.Address(crypto.PublicKeyToAddressP256(&key.PublicKey))
+	tx.GasPrice, tx.Symbol, tx.Timestamp, tx.Status = big.NewInt(1), core.GasSymbol, time.Now(), "pending"
+	tx.SenderPublicKeyHex = hex.EncodeToString(crypto.PublicKeyUncompressedBytes(&key.PublicKey))
+	tx.Hash = tx.CalculateHash()
+	var err error
+	if tx.Signature, err = crypto.Sign([]byte(tx.Hash), key); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("не дождались: %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestNode_HandshakeChecksChainAndSubnet(t *testing.T) {
+	genesis := newTestGenesis()
+	a := startTestNode(t, genesis, "A", 1, "")
+	otherChain := startTestNode(t, genesis, "B", 2, "", a.Addr())
+	otherSubnet := startTestNode(t, genesis, "C", 1, "subnet-1", a.Addr())
+	d := startTestNode(t, genesis, "D", 1, "", a.Addr())
+
+	waitFor(t, "подключение ноды D с тем же chain_id и subnet_id", func() bool {
+		return a.PeerCount() == 1 && d.PeerCount() == 1
+	})
+	time.Sleep(300 * time.Millisecond) // несколько попыток переподключения
+	if a.PeerCount() != 1 || otherChain.PeerCount() != 0 || otherSubnet.PeerCount() != 0 {
+		t.Fatalf("ноды с другим chain_id или subnet_id не должны подключаться: A %d, B %d, C %d",
+			a.PeerCount(), otherChain.PeerCount(), otherSubnet.PeerCount())
+	}
+	if peers := a.Peers(); peers[0].NodeID != d.ID() || !peers[0].Inbound || peers[0].NodeName != "D" {
+		t.Errorf("пир ноды A: %+v", peers[0])
+	}
+}
+
+func TestNode_GossipTransactionsAndBlocks(t *testing.T) {
+	genesis := newTestGenesis()
+	// цепочка A ← B ← C: C узнаёт о блоках A и A о транзакциях C только через B
+	a := startTestNode(t, genesis, "A", 1, "")
+	b := startTestNode(t, genesis, "B", 1, "", a.Addr())
+	c := startTestNode(t, genesis, "C", 1, "", b.Addr())
+	waitFor(t, "соединения A–B и B–C", func() bool {
+		return a.PeerCount() == 1 && b.PeerCount() == 2 && c.PeerCount() == 1
+	})
+
+	key, err := crypto.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := types.Address(crypto.PublicKeyToAddressP256(&key.PublicKey))
+	for _, n := range []*Node{a, b, c} {
+		if err := n.bc.State.AddBalance(sender, core.GasSymbol, big.NewInt(1e15)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	core.SetState(a.bc.State.(*core.State))
+	defer core.SetState(nil)
+
+	tx := signedContractCall(t, key, 0)
+	if _, err := c.bc.SendTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	// перевод нативной монеты тоже идёт через мемпул и рассылается пирам
+	transfer := signTestTx(t, key, &core.Transaction{Recipient: "GN_gossip_recipient", Value: big.NewInt(7), Nonce: 1, GasLimit: core.TxGas})
+	if _, err := c.bc.SendTransaction(transfer); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.bc.State.GetBalance("GN_gossip_recipient", core.GasSymbol); got.Sign() != 0 {
+		t.Fatalf("перевод не должен применяться до блока, баланс получателя %s", got)
+	}
+	waitFor(t, "транзакции C в мемпуле A", func() bool {
+		return a.bc.Mempool.Exists(tx.Hash) && b.bc.Mempool.Exists(tx.Hash) && a.bc.Mempool.Exists(transfer.Hash)
+	})
+
+	forged := *tx
+	forged.Hash, forged.Nonce, forged.Signature = "forged", 1, tx.Signature
+	if err := c.bc.AddPeerTransaction(&forged); err == nil {
+		t.Error("транзакция с чужой подписью не должна приниматься от пира")
+	}
+
+	if err := a.bc.ProduceNextBlock(a.bc.Mempool, "miner", 10); err != nil {
+		t.Fatal(err)
+	}
+	produced, _ := a.bc.LatestBlock()
+	for _, n := range []*Node{b, c} {
+		waitFor(t, "блок A у ноды "+n.local.NodeName, func() bool { return n.bc.Height() == 1 })
+		got, _ := n.bc.LatestBlock()
+		if got.Hash != produced.Hash || len(got.Transactions) != 2 || got.Transactions[0].Hash != tx.Hash || got.Transactions[1].Hash != transfer.Hash {
+			t.Errorf("нода %s: блок %s с %d транзакциями, ожидался %s", n.local.NodeName, got.Hash, len(got.Transactions), produced.Hash)
+		}
+		if got.Status != core.BlockStatusFinalized {
+			t.Errorf("без движка консенсуса блок пира финален сразу, статус %q", got.Status)
+		}
+		if bal := n.bc.State.GetBalance("GN_gossip_recipient", core.GasSymbol); bal.Int64() != 7 {
+			t.Errorf("нода %s: перевод блока должен исполниться, баланс получателя %s", n.local.NodeName, bal)
+		}
+	}
+	if peers := c.Peers(); peers[0].Height != 1 {
+		t.Errorf("высота пира B у ноды C должна обновиться до 1, получено %d", peers[0].Height)
+	}
+}
@@ -0,0 +1,127 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// p2p/protocol.go — протокол обмена между нодами: рукопожатие, сообщения сплетен и запросы синхронизации (JSON, одно сообщение на строку).
+package p2p
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"GND/core"
+)
+
+// ProtocolVersion — версия протокола; ноды с другой версией отклоняются при рукопожатии.
+const ProtocolVersion = 1
+
+// Типы сообщений.
+const (
+	msgHello      = "hello"      // рукопожатие: первое сообщение каждой стороны
+	msgDisconnect = "disconnect" // причина разрыва перед закрытием соединения
+	msgTx         = "tx"         // транзакция мемпула
+	msgBlock      = "block"      // новый блок цепи
+	msgVote       = "vote"       // голос валидатора слоя финальности
+
+	// Запросы синхронизации и ответы на них (с тем же id)
+	msgGetHeaders  = "get_headers"  // заголовки блоков начиная с высоты from
+	msgHeaders     = "headers"      // блоки без транзакций
+	msgGetBodies   = "get_bodies"   // транзакции блоков по хешам
+	msgBodies      = "bodies"       // транзакции найденных блоков
+	msgGetSnapshot = "get_snapshot" // последний снимок состояния на финализированной высоте
+	msgSnapshot    = "snapshot"     // снимок и блок, на котором он снят (пусто — снимка нет)
+)
+
+// maxMessageSize — предельный размер одного сообщения (блок с транзакциями, снимок состояния).
+const maxMessageSize = 64 << 20
+
+// Пределы ответов на запросы синхронизации.
+const (
+	maxHeadersPerRequest = 192
+	maxBodiesPerRequest  = 64
+)
+
+// message — конверт сообщения: тип, id запроса (для запросов синхронизации и ответов на них) и данные.
+type message struct {
+	Type string          `json:"type"`
+	ID   uint64          `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// getHeadersRequest — данные get_headers: count заголовков начиная с высоты from.
+type getHeadersRequest struct {
+	From  uint64 `json:"from"`
+	Count int    `json:"count"`
+}
+
+// getBodiesRequest — данные get_bodies: хеши блоков.
+type getBodiesRequest struct {
+	Hashes []string `json:"hashes"`
+}
+
+// blockBody — транзакции блока Hash.
+type blockBody struct {
+	Hash         string              `json:"hash"`
+	Transactions []*core.Transaction `json:"transactions"`
+}
+
+// snapshotResponse — данные snapshot: снимок состояния и блок снимка с транзакциями.
+type snapshotResponse struct {
+	Snapshot *core.StateSnapshot `json:"snapshot,omitempty"`
+	Block    *core.Block         `json:"block,omitempty"`
+}
+
+// Hello — данные рукопожатия. Ноды соединяются, только если совпадают версия протокола, chain_id и subnet_id.
+type Hello struct {
+	Version    int    `json:"version"`
+	ChainID    int64  `json:"chain_id"`
+	SubnetID   string `json:"subnet_id"`
+	NetworkID  string `json:"network_id"`
+	NodeID     string `json:"node_id"`   // случайный идентификатор ноды, выбирается при запуске
+	NodeName   string `json:"node_name"` // node_name из config.json
+	ListenAddr string `json:"listen_addr"`
+	Height     uint64 `json:"height"` // высота цепи на момент рукопожатия
+}
+
+// disconnectReason — данные сообщения disconnect.
+type disconnectReason struct {
+	Reason string `json:"reason"`
+}
+
+var errSelfConnection = errors.New("соединение с самим собой")
+
+// checkHello проверяет, что удалённая нода remote совместима с локальной local.
+func checkHello(local, remote *Hello) error {
+	if remote.Version != ProtocolVersion {
+		return fmt.Errorf("версия протокола %d, ожидается %d", remote.Version, ProtocolVersion)
+	}
+	if remote.ChainID != local.ChainID {
+		return fmt.Errorf("chain_id %d не совпадает с %d", remote.ChainID, local.ChainID)
+	}
+	if remote.SubnetID != local.SubnetID {
+		return fmt.Errorf("subnet_id %q не совпадает с %q", remote.SubnetID, local.SubnetID)
+	}
+	if remote.NodeID == "" {
+		return errors.New("пустой node_id")
+	}
+	if remote.NodeID == local.NodeID {
+		return errSelfConnection
+	}
+	return nil
+}
+
+// encodeMessage кодирует сообщение в строку протокола (JSON с переводом строки в конце).
+func encodeMessage(msgType string, data interface{}) ([]byte, error) {
+	return encodeRequest(msgType, 0, data)
+}
+
+// encodeRequest кодирует запрос синхронизации или ответ на него с id запроса.
+func encodeRequest(msgType string, id uint64, data interface{}) ([]byte, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.Marshal(message{Type: msgType, ID: id, Data: raw})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,509 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// p2p/sync.go — загрузка цепи от пиров: сначала заголовки, затем транзакции блоков, исполнение через AddBlock;
+// быстрая синхронизация — снимок состояния на финализированной высоте пира и блоки после неё.
+package p2p
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"GND/core"
+)
+
+const (
+	syncInterval            = time.Second      // проверка отставания от пиров
+	requestTimeout          = 10 * time.Second // ожидание ответа на запрос синхронизации
+	defaultSnapshotInterval = 100              // блоков между снимками состояния
+	snapshotsKept           = 2                // снимков в памяти: последний может быть ещё не финализирован
+)
+
+// Этапы синхронизации (SyncStatus.Stage).
+const (
+	syncStageIdle     = "idle"
+	syncStageSnapshot = "snapshot" // заголовки до снимка и снимок состояния (fast)
+	syncStageHeaders  = "headers"
+	syncStageBodies   = "bodies"
+)
+
+var (
+	// errRequestFailed — запрос синхронизации не выполнен (пир отключился, не ответил, очередь переполнена); повторяется позже.
+	errRequestFailed = errors.New("запрос к пиру не выполнен")
+	// errForked — первый заголовок пира не продолжает запрошенный блок: ветка пира расходится с цепью.
+	errForked = errors.New("ветка пира расходится с цепью")
+)
+
+// SyncStatus возвращает состояние синхронизации: текущую высоту цепи и наибольшую высоту среди пиров.
+func (n *Node) SyncStatus() core.SyncStatus {
+	n.statusMu.Lock()
+	st := n.status
+	n.statusMu.Unlock()
+	st.CurrentBlock = n.bc.Height()
+	if best := n.bestPeerHeight(); best > st.HighestBlock {
+		st.HighestBlock = best
+	}
+	if st.CurrentBlock > st.HighestBlock {
+		st.HighestBlock = st.CurrentBlock
+	}
+	return st
+}
+
+func (n *Node) updateStatus(update func(st *core.SyncStatus)) {
+	n.statusMu.Lock()
+	defer n.statusMu.Unlock()
+	update(&n.status)
+}
+
+// requestSync запускает проверку отставания, не дожидаясь очередного тика.
+func (n *Node) requestSync() {
+	select {
+	case n.syncCh <- struct{}{}:
+	default:
+	}
+}
+
+// syncLoop загружает блоки, пока пир с наибольшей высотой впереди. После перезапуска загрузка продолжается
+// с вершины цепи из БД: каждый загруженный блок сохраняется в AddBlock.
+func (n *Node) syncLoop() {
+	defer n.wg.Done()
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		n.synchronise()
+		select {
+		case <-n.stopCh:
+			return
+		case <-ticker.C:
+		case <-n.syncCh:
+		}
+	}
+}
+
+func (n *Node) synchronise() {
+	p := n.bestPeer()
+	if p == nil {
+		return
+	}
+	n.syncing.Store(true)
+	defer n.syncing.Store(false)
+	n.updateStatus(func(st *core.SyncStatus) {
+		st.Syncing, st.StartingBlock, st.Peer, st.LastError = true, n.bc.Height(), p.info.NodeName, ""
+	})
+	n.logger.Printf("Синхронизация с пиром %s: высота %d, у пира %d", p.info.NodeName, n.bc.Height(), n.peerHeight(p))
+
+	err := n.fastSync(p)
+	if err != nil && !errors.Is(err, errRequestFailed) {
+		n.logger.Printf("Быстрая синхронизация с пиром %s не выполнена, загрузка всех блоков: %v", p.info.NodeName, err)
+		err = nil
+	}
+	if err == nil {
+		err = n.fullSync(p)
+	}
+	n.updateStatus(func(st *core.SyncStatus) {
+		st.Syncing, st.Stage = false, syncStageIdle
+		if err != nil {
+			st.LastError = err.Error()
+		}
+	})
+	if err != nil {
+		n.logger.Printf("Синхронизация с пиром %s прервана на высоте %d: %v", p.info.NodeName, n.bc.Height(), err)
+		return
+	}
+	n.logger.Printf("Синхронизация с пиром %s завершена, высота %d", p.info.NodeName, n.bc.Height())
+}
+
+// fullSync загружает заголовки блоков выше вершины цепи, проверяет их связность и хеши, затем запрашивает
+// транзакции блоков и добавляет блоки через AddBlock (validateBlock, проверка движком консенсуса, applyBlock).
+// Если ветка пира расходится с цепью, заголовки загружаются от финализированного блока: блоки ветки пира
+// попадают в боковую ветку, и AddBlock реорганизует цепь, если она тяжелее.
+func (n *Node) fullSync(p *peer) error {
+	from, err := n.bc.LatestBlock()
+	if err != nil {
+		return err
+	}
+	for {
+		target := n.peerHeight(p)
+		if from.Index >= target {
+			return nil
+		}
+		count := target - from.Index
+		if count > maxHeadersPerRequest {
+			count = maxHeadersPerRequest
+		}
+		n.updateStatus(func(st *core.SyncStatus) { st.Stage = syncStageHeaders })
+		headers, err := n.fetchHeaders(p, from, int(count))
+		if errors.Is(err, errForked) {
+			finalized := n.bc.FinalizedBlock()
+			if from.Index <= finalized.Index {
+				return err
+			}
+			from = finalized
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		n.updateStatus(func(st *core.SyncStatus) { st.Stage = syncStageBodies })
+		for start := 0; start < len(headers); start += maxBodiesPerRequest {
+			end := start + maxBodiesPerRequest
+			if end > len(headers) {
+				end = len(headers)
+			}
+			blocks, err := n.fetchBodies(p, headers[start:end])
+			if err != nil {
+				return err
+			}
+			for _, block := range blocks {
+				if n.bc.HasBlock(block.Hash) {
+					continue // блок уже получен рассылкой
+				}
+				if err := n.importBlock(block); err != nil {
+					return fmt.Errorf("блок %d: %w", block.Index, err)
+				}
+			}
+		}
+		from = headers[len(headers)-1]
+	}
+}
+
+// fastSync загружает снимок состояния пира, если нода новая (только генезис) и включён режим fast.
+// Блок снимка должен быть финализирован (justified или finalized), а при движке консенсуса — иметь не меньше
+// core.MaxReorgDepth потомков. Заголовки от генезиса до блока снимка и его потомки проверяются на связность
+// и движком консенсуса (подпись предлагающего), затем снимок загружается через ImportSnapshot. При PoS быстрая синхронизация недоступна: проверка блоков
+// до снимка требует истории стейка. Выполняется один раз за запуск ноды; при сбое запроса повторяется.
+func (n *Node) fastSync(p *peer) (err error) {
+	if n.fastDone || n.syncMode != core.SyncModeFast {
+		return nil
+	}
+	if n.bc.Height() != 0 {
+		n.fastDone = true
+		return nil
+	}
+	if n.bc.Engine != nil && n.bc.Engine.Type() == "pos" {
+		n.fastDone = true
+		return errors.New("быстрая синхронизация недоступна для PoS")
+	}
+	n.updateStatus(func(st *core.SyncStatus) { st.Stage = syncStageSnapshot })
+	defer func() { n.fastDone = err == nil || !errors.Is(err, errRequestFailed) }()
+	data, err := n.request(p, msgGetSnapshot, struct{}{})
+	if err != nil {
+		return err
+	}
+	var resp snapshotResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("неверный снимок: %w", err)
+	}
+	if resp.Snapshot == nil || resp.Block == nil {
+		return errors.New("у пира нет снимка состояния на финализированной высоте")
+	}
+	pivot := resp.Block
+	if pivot.Index != resp.Snapshot.Height {
+		return fmt.Errorf("блок снимка %d не соответствует высоте снимка %d", pivot.Index, resp.Snapshot.Height)
+	}
+	if pivot.Status != core.BlockStatusFinalized && pivot.Status != core.BlockStatusJustified {
+		return fmt.Errorf("блок снимка %d не финализирован (статус %q)", pivot.Index, pivot.Status)
+	}
+	// Статус сообщает сам пир, поэтому при движке консенсуса блок снимка должен быть ещё и глубже MaxReorgDepth
+	// проверенных заголовков: более глубокую реорганизацию цепь не принимает, и снимок не окажется на отменённой ветке
+	target := pivot.Index
+	if n.bc.Engine != nil {
+		target += core.MaxReorgDepth
+		if height := n.peerHeight(p); height < target {
+			return fmt.Errorf("блок снимка %d ближе %d блоков к вершине пира %d", pivot.Index, core.MaxReorgDepth, height)
+		}
+	}
+
+	// Цепочка заголовков от генезиса до блока снимка и после него: подтверждает, что снимок снят на блоке этой цепи
+	parent, pivotHeader := n.bc.Genesis, n.bc.Genesis
+	for parent.Index < target {
+		count := target - parent.Index
+		if count > maxHeadersPerRequest {
+			count = maxHeadersPerRequest
+		}
+		headers, err := n.fetchHeaders(p, parent, int(count))
+		if err != nil {
+			return err
+		}
+		for _, h := range headers {
+			if n.bc.Engine != nil {
+				if err := n.bc.Engine.VerifyBlock(h, parent); err != nil {
+					return fmt.Errorf("заголовок %d: %w", h.Index, err)
+				}
+			}
+			if h.Index == pivot.Index {
+				pivotHeader = h
+			}
+			parent = h
+		}
+	}
+	if pivotHeader.Index != pivot.Index || pivotHeader.Hash != pivot.Hash {
+		return fmt.Errorf("блок снимка %s не входит в цепь пира", pivot.Hash)
+	}
+	if err := n.bc.ImportSnapshot(resp.Snapshot, pivot); err != nil {
+		return err
+	}
+	n.seen.add(blockKey(pivot.Hash))
+	n.updateStatus(func(st *core.SyncStatus) { st.PivotBlock = pivot.Index })
+	n.logger.Printf("Загружен снимок состояния пира %s на высоте %d: аккаунтов %d, контрактов %d",
+		p.info.NodeName, pivot.Index, len(resp.Snapshot.Accounts), len(resp.Snapshot.Contracts))
+	return nil
+}
+
+// fetchHeaders запрашивает до count заголовков после блока parent и проверяет хеши, номера и связность с parent.
+func (n *Node) fetchHeaders(p *peer, parent *core.Block, count int) ([]*core.Block, error) {
+	data, err := n.request(p, msgGetHeaders, getHeadersRequest{From: parent.Index + 1, Count: count})
+	if err != nil {
+		return nil, err
+	}
+	var headers []*core.Block
+	if err := json.Unmarshal(data, &headers); err != nil {
+		return nil, fmt.Errorf("неверные заголовки: %w", err)
+	}
+	if len(headers) == 0 || len(headers) > count {
+		return nil, fmt.Errorf("пир вернул %d заголовков, запрошено %d", len(headers), count)
+	}
+	prev := parent
+	for _, h := range headers {
+		if h == nil || h.Index != prev.Index+1 {
+			return nil, fmt.Errorf("заголовки пира не по порядку после высоты %d", prev.Index)
+		}
+		if h.Hash != h.CalculateHash() {
+			return nil, fmt.Errorf("хеш заголовка %d не совпадает", h.Index)
+		}
+		if h.PrevHash != prev.Hash {
+			if prev == parent {
+				return nil, fmt.Errorf("заголовок %d не продолжает блок %d (%s): %w", h.Index, prev.Index, prev.Hash, errForked)
+			}
+			return nil, fmt.Errorf("заголовок %d не продолжает заголовок %d", h.Index, prev.Index)
+		}
+		prev = h
+	}
+	n.updatePeerHeight(p, prev.Index)
+	return headers, nil
+}
+
+// fetchBodies запрашивает транзакции блоков по заголовкам и проверяет корень транзакций каждого блока.
+func (n *Node) fetchBodies(p *peer, headers []*core.Block) ([]*core.Block, error) {
+	req := getBodiesRequest{Hashes: make([]string, len(headers))}
+	for i, h := range headers {
+		req.Hashes[i] = h.Hash
+	}
+	data, err := n.request(p, msgGetBodies, req)
+	if err != nil {
+		return nil, err
+	}
+	var bodies []blockBody
+	if err := json.Unmarshal(data, &bodies); err != nil {
+		return nil, fmt.Errorf("неверные транзакции блоков: %w", err)
+	}
+	byHash := make(map[string][]*core.Transaction, len(bodies))
+	for _, b := range bodies {
+		byHash[b.Hash] = b.Transactions
+	}
+	blocks := make([]*core.Block, 0, len(headers))
+	for _, h := range headers {
+		txs, ok := byHash[h.Hash]
+		if !ok {
+			return nil, fmt.Errorf("пир не вернул транзакции блока %d", h.Index)
+		}
+		if h.MerkleRoot != "" && core.ComputeMerkleRoot(txs) != h.MerkleRoot {
+			return nil, fmt.Errorf("транзакции блока %d не соответствуют корню %s", h.Index, h.MerkleRoot)
+		}
+		block := *h
+		block.Transactions = txs
+		blocks = append(blocks, &block)
+	}
+	return blocks, nil
+}
+
+// request отправляет пиру запрос синхронизации и ждёт ответа с тем же id.
+func (n *Node) request(p *peer, msgType string, data interface{}) (json.RawMessage, error) {
+	id := n.reqID.Add(1)
+	payload, err := encodeRequest(msgType, id, data)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan message, 1)
+	p.pendingMu.Lock()
+	p.pending[id] = ch
+	p.pendingMu.Unlock()
+	defer func() {
+		p.pendingMu.Lock()
+		delete(p.pending, id)
+		p.pendingMu.Unlock()
+	}()
+	select {
+	case p.send <- payload:
+	case <-p.closed:
+		return nil, fmt.Errorf("%w: пир %s отключился", errRequestFailed, p.info.NodeName)
+	default:
+		return nil, fmt.Errorf("%w: очередь отправки пира %s переполнена", errRequestFailed, p.info.NodeName)
+	}
+	timer := time.NewTimer(requestTimeout)
+	defer timer.Stop()
+	select {
+	case msg := <-ch:
+		return msg.Data, nil
+	case <-p.closed:
+		return nil, fmt.Errorf("%w: пир %s отключился", errRequestFailed, p.info.NodeName)
+	case <-n.stopCh:
+		return nil, fmt.Errorf("%w: нода остановлена", errRequestFailed)
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: пир %s не ответил на %s за %s", errRequestFailed, p.info.NodeName, msgType, requestTimeout)
+	}
+}
+
+// deliver передаёт ответ ожидающему запросу; ответ без запроса отбрасывается.
+func (p *peer) deliver(msg message) {
+	p.pendingMu.Lock()
+	ch, ok := p.pending[msg.ID]
+	p.pendingMu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case ch <- msg:
+	default:
+	}
+}
+
+// serveRequest отвечает на запрос синхронизации пира блоками цепи или снимком состояния.
+func (n *Node) serveRequest(p *peer, msg message) {
+	var (
+		respType string
+		resp     interface{}
+	)
+	switch msg.Type {
+	case msgGetHeaders:
+		var req getHeadersRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			return
+		}
+		respType, resp = msgHeaders, n.headersFrom(req.From, req.Count)
+	case msgGetBodies:
+		var req getBodiesRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			return
+		}
+		respType, resp = msgBodies, n.bodies(req.Hashes)
+	case msgGetSnapshot:
+		snap, block := n.servableSnapshot()
+		respType, resp = msgSnapshot, snapshotResponse{Snapshot: snap, Block: block}
+	}
+	payload, err := encodeRequest(respType, msg.ID, resp)
+	if err != nil {
+		n.logger.Printf("Ответ %s пиру %s не закодирован: %v", respType, p.info.NodeName, err)
+		return
+	}
+	select {
+	case p.send <- payload:
+	case <-p.closed:
+	default:
+		n.logger.Printf("Очередь отправки пира %s переполнена, ответ %s пропущен", p.info.NodeName, respType)
+	}
+}
+
+// headersFrom возвращает до count заголовков (блоков без транзакций) начиная с высоты from.
+func (n *Node) headersFrom(from uint64, count int) []*core.Block {
+	if count <= 0 || count > maxHeadersPerRequest {
+		count = maxHeadersPerRequest
+	}
+	blocks := n.bc.AllBlocks()
+	i := sort.Search(len(blocks), func(i int) bool { return blocks[i].Index >= from })
+	headers := make([]*core.Block, 0, count)
+	for ; i < len(blocks) && len(headers) < count; i++ {
+		h := *blocks[i]
+		h.Transactions = nil
+		headers = append(headers, &h)
+	}
+	return headers
+}
+
+// bodies возвращает транзакции блоков цепи по хешам (не найденные пропускаются).
+func (n *Node) bodies(hashes []string) []blockBody {
+	if len(hashes) > maxBodiesPerRequest {
+		hashes = hashes[:maxBodiesPerRequest]
+	}
+	want := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		want[h] = true
+	}
+	out := make([]blockBody, 0, len(hashes))
+	for _, b := range n.bc.AllBlocks() {
+		if want[b.Hash] {
+			out = append(out, blockBody{Hash: b.Hash, Transactions: b.Transactions})
+		}
+	}
+	return out
+}
+
+// captureSnapshot снимает состояние после блока block. Вызывается из onBlock под блокировкой цепи.
+func (n *Node) captureSnapshot(block *core.Block) {
+	snap, err := n.bc.ExportSnapshot(block)
+	if err != nil {
+		n.logger.Printf("Снимок состояния на высоте %d не снят: %v", block.Index, err)
+		return
+	}
+	n.snapMu.Lock()
+	defer n.snapMu.Unlock()
+	n.snapshots = append(n.snapshots, snap)
+	// снимки последних MaxReorgDepth блоков ещё не отдаются пирам (servableSnapshot), поэтому хранятся сверх snapshotsKept
+	kept := snapshotsKept + int(core.MaxReorgDepth/n.snapshotInterval)
+	if len(n.snapshots) > kept {
+		n.snapshots = n.snapshots[len(n.snapshots)-kept:]
+	}
+}
+
+// servableSnapshot возвращает последний снимок, блок которого финализирован (при движке консенсуса — не ближе
+// core.MaxReorgDepth блоков к вершине), и этот блок.
+func (n *Node) servableSnapshot() (*core.StateSnapshot, *core.Block) {
+	n.snapMu.Lock()
+	snapshots := append([]*core.StateSnapshot(nil), n.snapshots...)
+	n.snapMu.Unlock()
+	blocks := n.bc.AllBlocks()
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		snap := snapshots[i]
+		if n.bc.Engine != nil && snap.Height+core.MaxReorgDepth > n.bc.Height() {
+			continue // fastSync принимает снимок не ближе MaxReorgDepth блоков к вершине
+		}
+		j := sort.Search(len(blocks), func(j int) bool { return blocks[j].Index >= snap.Height })
+		if j < len(blocks) && blocks[j].Hash == snap.BlockHash && blocks[j].Status == core.BlockStatusFinalized {
+			return snap, blocks[j]
+		}
+	}
+	return nil, nil
+}
+
+// bestPeer возвращает пира с наибольшей высотой, если она больше высоты цепи ноды.
+func (n *Node) bestPeer() *peer {
+	height := n.bc.Height()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var best *peer
+	for _, p := range n.peers {
+		if p.info.Height > height && (best == nil || p.info.Height > best.info.Height) {
+			best = p
+		}
+	}
+	return best
+}
+
+func (n *Node) bestPeerHeight() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var best uint64
+	for _, p := range n.peers {
+		if p.info.Height > best {
+			best = p.info.Height
+		}
+	}
+	return best
+}
+
+func (n *Node) peerHeight(p *peer) uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return p.info.Height
+}
@@ -0,0 +1,141 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package p2p
+
+import (
+	"math/big"
+	"testing"
+
+	"GND/core"
+	"GND/core/crypto"
+	"GND/types"
+)
+
+func produceBlocks(t *testing.T, n *Node, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		if err := n.bc.ProduceNextBlock(n.bc.Mempool, "miner", 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSync_FullSyncOfNewNode(t *testing.T) {
+	genesis := newTestGenesis()
+	a := startTestNode(t, genesis, "A", 1, "")
+
+	key, err := crypto.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := types.Address(crypto.PublicKeyToAddressP256(&key.PublicKey))
+	if err := a.bc.State.AddBalance(sender, core.GasSymbol, big.NewInt(1e15)); err != nil {
+		t.Fatal(err)
+	}
+	core.SetState(a.bc.State.(*core.State))
+	defer core.SetState(nil)
+	tx := signedContractCall(t, key, 0)
+	if _, err := a.bc.SendTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	// больше одного запроса заголовков не нужно, но транзакции блоков запрашиваются частями по maxBodiesPerRequest
+	produceBlocks(t, a, maxBodiesPerRequest+6)
+
+	// B подключается к A позже: блоки A не придут рассылкой, только синхронизацией
+	b := startNodeWithConfig(t, genesis, &core.Config{NodeName: "B", ChainID: 1, P2P: core.P2PConfig{Peers: []string{a.Addr()}}})
+	if err := b.bc.State.AddBalance(sender, core.GasSymbol, big.NewInt(1e15)); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "синхронизация B до высоты A", func() bool { return b.bc.Height() == a.bc.Height() })
+
+	aBlocks, bBlocks := a.bc.AllBlocks(), b.bc.AllBlocks()
+	if len(bBlocks) != len(aBlocks) {
+		t.Fatalf("у B %d блоков, у A %d", len(bBlocks), len(aBlocks))
+	}
+	for i := range aBlocks {
+		if bBlocks[i].Hash != aBlocks[i].Hash {
+			t.Fatalf("блок %d: хеш %s, ожидался %s", i, bBlocks[i].Hash, aBlocks[i].Hash)
+		}
+	}
+	if got := bBlocks[1].Transactions; len(got) != 1 || got[0].Hash != tx.Hash || got[0].Status != core.TxStatusConfirmed {
+		t.Errorf("транзакция блока 1 должна загрузиться и исполниться, получено %+v", got)
+	}
+	if b.bc.State.GetNonce(sender) != a.bc.State.GetNonce(sender) {
+		t.Errorf("nonce отправителя у B %d, у A %d", b.bc.State.GetNonce(sender), a.bc.State.GetNonce(sender))
+	}
+	waitFor(t, "завершение синхронизации", func() bool { return !b.SyncStatus().Syncing })
+	st := b.SyncStatus()
+	if st.Mode != core.SyncModeFull || st.CurrentBlock != a.bc.Height() || st.HighestBlock != a.bc.Height() || st.LastError != "" {
+		t.Errorf("статус синхронизации: %+v", st)
+	}
+}
+
+func TestSync_FastSyncFromSnapshot(t *testing.T) {
+	genesis := newTestGenesis()
+	a := startNodeWithConfig(t, genesis, &core.Config{NodeName: "A", ChainID: 1, P2P: core.P2PConfig{SnapshotInterval: 10}})
+	rich := types.Address("GN_rich")
+	if err := a.bc.State.AddBalance(rich, core.GasSymbol, big.NewInt(500)); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.bc.State.(*core.State).SetContractCode("GN_contract_1", []byte{0x60, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	produceBlocks(t, a, 25)
+
+	b := startNodeWithConfig(t, genesis, &core.Config{NodeName: "B", ChainID: 1,
+		P2P: core.P2PConfig{Peers: []string{a.Addr()}, SyncMode: core.SyncModeFast}})
+	waitFor(t, "быстрая синхронизация B", func() bool { return b.bc.Height() == 25 && !b.SyncStatus().Syncing })
+
+	// снимки сняты на высотах 10 и 20; загружается последний финализированный, затем блоки 21–25
+	blocks := b.bc.AllBlocks()
+	if len(blocks) != 7 || blocks[1].Index != 20 || blocks[1].Status != core.BlockStatusFinalized {
+		t.Fatalf("ожидались генезис, блок снимка 20 и блоки 21–25, получено %d блоков (второй %d)", len(blocks), blocks[1].Index)
+	}
+	head, _ := a.bc.LatestBlock()
+	if tip, _ := b.bc.LatestBlock(); tip.Hash != head.Hash {
+		t.Errorf("вершина B %s, у A %s", tip.Hash, head.Hash)
+	}
+	if got := b.bc.GetBalance(string(rich), core.GasSymbol); got.Cmp(big.NewInt(500)) != 0 {
+		t.Errorf("баланс из снимка: %s, ожидалось 500", got)
+	}
+	if code, _ := b.bc.State.(*core.State).GetContractCode("GN_contract_1"); len(code) != 2 {
+		t.Errorf("код контракта из снимка: %x", code)
+	}
+	if st := b.SyncStatus(); st.Mode != core.SyncModeFast || st.PivotBlock != 20 || st.CurrentBlock != 25 {
+		t.Errorf("статус синхронизации: %+v", st)
+	}
+
+	// снимок с изменённым балансом не сходится с state_root и отклоняется
+	snap, pivot := a.servableSnapshot()
+	if snap == nil {
+		t.Fatal("у A нет снимка на финализированной высоте")
+	}
+	c := startTestNode(t, genesis, "C", 1, "")
+	for i := range snap.Accounts {
+		if snap.Accounts[i].Address == string(rich) {
+			snap.Accounts[i].Balances = map[string]*big.Int{core.GasSymbol: big.NewInt(1e9)}
+		}
+	}
+	if err := c.bc.ImportSnapshot(snap, pivot); err == nil {
+		t.Error("снимок с подменённым балансом должен отклоняться")
+	}
+}
+
+// proposingEngine — движок без подписей: блоки остаются proposed до голосов финальности.
+type proposingEngine struct{}
+
+func (proposingEngine) Type() string                       { return "poa" }
+func (proposingEngine) Seal(*core.Block) error             { return nil }
+func (proposingEngine) VerifyBlock(_, _ *core.Block) error { return nil }
+
+func TestSync_ServableSnapshotBelowReorgDepth(t *testing.T) {
+	a := startNodeWithConfig(t, newTestGenesis(), &core.Config{NodeName: "A", ChainID: 1, P2P: core.P2PConfig{SnapshotInterval: 10}})
+	produceBlocks(t, a, 25)
+	a.bc.Engine = proposingEngine{}
+	if snap, _ := a.servableSnapshot(); snap != nil {
+		t.Fatalf("снимок %d ближе %d блоков к вершине не должен отдаваться пирам", snap.Height, core.MaxReorgDepth)
+	}
+	produceBlocks(t, a, 20+core.MaxReorgDepth-25)
+	if snap, block := a.servableSnapshot(); snap == nil || snap.Height != 20 || block.Status != core.BlockStatusFinalized {
+		t.Fatalf("ожидался финализированный снимок 20, получено %+v", snap)
+	}
+}
@@ -32,13 +32,13 @@ func PrivKeyToBytes(priv *secp256k1.PrivateKey) []byte {
 	return priv.Serialize()
 }
 
-// SignDigest подписывает digest по secp256k1 ECDSA. Возвращает сериализованную подпись (DER).
+// SignDigest подписывает digest по secp256k1 ECDSA. Возвращает восстанавливаемую подпись v‖r‖s (65 байт):
+// нода восстанавливает из неё публичный ключ и сверяет его с адресом отправителя.
 func SignDigest(priv *secp256k1.PrivateKey, digest []byte) ([]byte, error) {
 	if len(digest) == 0 {
 		return nil, fmt.Errorf("empty digest")
 	}
-	sig := ecdsa.Sign(priv, digest)
-	return sig.Serialize(), nil
+	return ecdsa.SignCompact(priv, digest, true), nil
 }
 
 // HexToBytes декодирует hex-строку в байты.
@@ -4,6 +4,8 @@ package crypto
 import (
 	"crypto/sha256"
 	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
 )
 
 func TestNewSecp256k1Key(t *testing.T) {
@@ -47,8 +49,9 @@ func TestSignDigest(t *testing.T) {
 	if err != nil {
 		t.Fatalf("SignDigest: %v", err)
 	}
-	if len(sig) == 0 {
-		t.Error("подпись пустая")
+	pub, _, err := ecdsa.RecoverCompact(sig, digest[:])
+	if err != nil || !bytesEqual(pub.SerializeCompressed(), PublicKeyBytes(priv)) {
+		t.Errorf("из подписи должен восстанавливаться публичный ключ: %v", err)
 	}
 	_, err = SignDigest(priv, nil)
 	if err == nil {
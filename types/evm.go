@@ -67,7 +67,16 @@ type ExecutionResult struct {
 	GasUsed      uint64
 	StateChanges []*StateChange
 	ReturnData   []byte
+	Logs         []*Log // события контракта (только при успешном исполнении)
 	Error        error
+	RevertReason string // причина revert из Error(string), если передана
+}
+
+// Log represents an event emitted by a contract (LOG0..LOG4)
+type Log struct {
+	Address string   // адрес контракта ГАНИМЕД
+	Topics  [][]byte // темы события, по 32 байта
+	Data    []byte
 }
 
 // StateChange represents a change to the blockchain state
@@ -86,6 +95,8 @@ type ChangeType uint8
 const (
 	ChangeTypeBalance ChangeType = iota
 	ChangeTypeStorage
+	ChangeTypeCode  // runtime-код контракта, созданного при исполнении (Value — код)
+	ChangeTypeNonce // nonce аккаунта, изменённый при исполнении (CREATE; Amount — новое значение)
 )
 
 // NewStateChange creates a new state change
@@ -98,6 +109,24 @@ func NewStateChange(changeType ChangeType, address Address, symbol string, amoun
 	}
 }
 
+// NewCodeChange creates a new code change (runtime code of a contract created during execution)
+func NewCodeChange(address Address, code []byte) *StateChange {
+	return &StateChange{
+		Type:    ChangeTypeCode,
+		Address: address.String(),
+		Value:   code,
+	}
+}
+
+// NewNonceChange creates a new nonce change (nonce of an account after execution)
+func NewNonceChange(address Address, nonce uint64) *StateChange {
+	return &StateChange{
+		Type:    ChangeTypeNonce,
+		Address: address.String(),
+		Amount:  new(big.Int).SetUint64(nonce),
+	}
+}
+
 // NewStorageChange creates a new storage change
 func NewStorageChange(address Address, key, value []byte) *StateChange {
 	return &StateChange{
@@ -0,0 +1,125 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// vm/address.go — соответствие адресов ГАНИМЕД и 20-байтных адресов EVM.
+
+package vm
+
+import (
+	"encoding/hex"
+	"log"
+	"strings"
+	"sync"
+
+	"GND/core"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+)
+
+// addressBookSize — число соответствий адресов кошельков, которые держатся в памяти (остальные читаются из AddressStore).
+const addressBookSize = 65536
+
+// AddressStore — постоянное хранилище обратного соответствия адресов EVM кошелькам ГАНИМЕД (таблица evm_addresses, core.State).
+type AddressStore interface {
+	SaveEVMAddress(evmAddress, address string) error
+	LoadEVMAddress(evmAddress string) (string, bool)
+}
+
+var (
+	// addressBook — кэш обратного соответствия адресов EVM адресам ГАНИМЕД (кошельки и контракты GNDct).
+	addressBook = lru.NewCache[common.Address, string](addressBookSize)
+
+	addressStoreMu sync.RWMutex
+	addressStore   AddressStore
+	// pendingAddresses — соответствия, ещё не записанные в AddressStore: запись в БД не делается на пути исполнения
+	// (eth_call, трассировка, блок), а выполняется FlushAddresses после принятия блока.
+	pendingAddresses = make(map[common.Address]string)
+)
+
+// SetAddressStore задаёт хранилище, в которое записываются соответствия адресов (nil — только кэш в памяти).
+func SetAddressStore(store AddressStore) {
+	addressStoreMu.Lock()
+	defer addressStoreMu.Unlock()
+	addressStore = store
+}
+
+func currentAddressStore() AddressStore {
+	addressStoreMu.RLock()
+	defer addressStoreMu.RUnlock()
+	return addressStore
+}
+
+// FlushAddresses записывает в AddressStore соответствия, накопленные с прошлой записи. Вызывается после принятия блока
+// (Blockchain.OnBlock); соответствие, которое не удалось записать, остаётся в очереди до следующего блока.
+func FlushAddresses() {
+	addressStoreMu.Lock()
+	defer addressStoreMu.Unlock()
+	if addressStore == nil {
+		return
+	}
+	for a, addr := range pendingAddresses {
+		if err := addressStore.SaveEVMAddress(strings.ToLower(a.Hex()), addr); err != nil {
+			log.Printf("[EVM] запись соответствия адреса %s: %v", addr, err)
+			continue
+		}
+		delete(pendingAddresses, a)
+	}
+}
+
+// remember добавляет соответствие в кэш и, если оно новое, в очередь записи в AddressStore
+// (очередь ограничена addressBookSize; сверх неё соответствие остаётся только в кэше).
+func remember(a common.Address, addr string) {
+	if known, ok := addressBook.Get(a); ok && known == addr {
+		return
+	}
+	addressBook.Add(a, addr)
+	addressStoreMu.Lock()
+	defer addressStoreMu.Unlock()
+	if addressStore != nil && len(pendingAddresses) < addressBookSize {
+		pendingAddresses[a] = addr
+	}
+}
+
+// ToEVMAddress переводит адрес ГАНИМЕД в 20-байтный адрес EVM (core.EVMAddress): 0x+40 hex и 40 hex — как есть;
+// GNDct+32 hex — 16 байт, дополненные слева нулями; остальные (кошельки) — последние 20 байт keccak256 от строки адреса.
+// Соответствие кошельков и контрактов GNDct запоминается для FromEVMAddress и записывается в AddressStore при FlushAddresses.
+func ToEVMAddress(addr string) common.Address {
+	s := strings.TrimSpace(addr)
+	a := core.EVMAddress(s)
+	if hexPart := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"); len(hexPart) == 40 {
+		if _, err := hex.DecodeString(hexPart); err == nil {
+			return a // адрес EVM как есть: соответствие не нужно
+		}
+	}
+	remember(a, s)
+	return a
+}
+
+// MapEVMAddress запоминает, что адрес EVM a принадлежит адресу ГАНИМЕД addr (например, адрес кошелька Ethereum —
+// GND-адресу того же ключа после eth_sendRawTransaction).
+func MapEVMAddress(a common.Address, addr string) {
+	remember(a, addr)
+}
+
+// FromEVMAddress переводит адрес EVM обратно в адрес ГАНИМЕД по известному соответствию (кэш, очередь записи,
+// AddressStore) — так восстанавливаются кошельки и контракты GNDct; прочие возвращаются в виде 0x+40 hex.
+func FromEVMAddress(a common.Address) string {
+	if v, ok := addressBook.Get(a); ok {
+		return v
+	}
+	addressStoreMu.RLock()
+	pending, ok := pendingAddresses[a]
+	store := addressStore
+	addressStoreMu.RUnlock()
+	if ok {
+		addressBook.Add(a, pending)
+		return pending
+	}
+	hexAddr := strings.ToLower(a.Hex())
+	if store != nil {
+		if addr, ok := store.LoadEVMAddress(hexAddr); ok {
+			addressBook.Add(a, addr)
+			return addr
+		}
+	}
+	return hexAddr
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -28,11 +29,12 @@ type ContractMetadata struct {
 
 // CompileResult результат компиляции
 type CompileResult struct {
-	Bytecode string           // hex-код байткода
-	ABI      string           // JSON ABI
-	Metadata ContractMetadata // метаданные
-	Warnings []string
-	Errors   []string
+	Bytecode        string           // hex-код байткода
+	RuntimeBytecode string           // hex-код runtime-байткода (код, возвращаемый конструктором)
+	ABI             string           // JSON ABI
+	Metadata        ContractMetadata // метаданные
+	Warnings        []string
+	Errors          []string
 }
 
 // SolidityCompiler интерфейс для компиляции Solidity-контрактов
@@ -42,7 +44,28 @@ type SolidityCompiler interface {
 
 // DefaultSolidityCompiler реализует SolidityCompiler через внешний solc
 type DefaultSolidityCompiler struct {
-	SolcPath string // путь к solc (например, "solc" если в $PATH)
+	SolcPath  string             // путь к solc (например, "solc" если в $PATH)
+	Optimizer *OptimizerSettings // настройки оптимизатора (nil — --optimize с числом запусков solc по умолчанию)
+}
+
+// OptimizerSettings — настройки оптимизатора solc
+type OptimizerSettings struct {
+	Enabled bool `json:"enabled"`
+	Runs    int  `json:"runs"` // 0 — по умолчанию solc (200)
+}
+
+// args возвращает флаги оптимизатора для командной строки solc.
+func (o *OptimizerSettings) args() []string {
+	if o == nil {
+		return []string{"--optimize"}
+	}
+	if !o.Enabled {
+		return nil
+	}
+	if o.Runs > 0 {
+		return []string{"--optimize", "--optimize-runs", strconv.Itoa(o.Runs)}
+	}
+	return []string{"--optimize"}
 }
 
 // Compile компилирует исходник Solidity и возвращает байткод, ABI и метаданные
@@ -80,11 +103,8 @@ func (c *DefaultSolidityCompiler) Compile(source []byte, metadata ContractMetada
 	}
 
 	// Запускаем solc для получения байткода и ABI
-	cmd := exec.Command(c.SolcPath,
-		"--optimize",
-		"--combined-json", "abi,bin",
-		srcFile,
-	)
+	args := append(c.Optimizer.args(), "--combined-json", "abi,bin,bin-runtime", srcFile)
+	cmd := exec.Command(c.SolcPath, args...)
 	var out bytes.Buffer
 	var stderr bytes.Buffer
 	cmd.Stdout = &out
@@ -148,15 +168,32 @@ func (c *DefaultSolidityCompiler) Compile(source []byte, metadata ContractMetada
 		return nil, errors.New("empty bytecode")
 	}
 
+	binRuntime, _ := contractData["bin-runtime"].(string)
+
 	compileResult := &CompileResult{
-		Bytecode: bin,
-		ABI:      abiStr,
-		Metadata: metadata,
+		Bytecode:        bin,
+		RuntimeBytecode: binRuntime,
+		ABI:             abiStr,
+		Metadata:        metadata,
 	}
 
 	return compileResult, nil
 }
 
+// Version возвращает версию solc из вывода solc --version (например, "0.8.20+commit.a1b79de6.Linux.g++").
+func (c *DefaultSolidityCompiler) Version() (string, error) {
+	out, err := exec.Command(c.SolcPath, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("solc error: %v", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "Version:"); ok {
+			return strings.TrimSpace(v), nil
+		}
+	}
+	return "", errors.New("solc version not found")
+}
+
 // removeDir безопасно удаляет временную директорию
 func removeDir(dir string) error {
 	return exec.Command("rm", "-rf", dir).Run()
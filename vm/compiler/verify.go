@@ -0,0 +1,63 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// vm/compiler/verify.go — сверка байткода, скомпилированного из исходника, с байткодом задеплоенного контракта
+// без учёта хеша метаданных solc (CBOR в конце кода) для верификации контрактов.
+
+package compiler
+
+import (
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// metadataPattern — CBOR-метаданные, которые solc дописывает в конец runtime-кода: ipfs (0.6+), bzzr1 (0.5.12+), bzzr0.
+// Хеш в них зависит от исходника вплоть до пробелов и комментариев, поэтому при сверке не учитывается.
+var metadataPattern = regexp.MustCompile(
+	`a2646970667358221220[0-9a-f]{64}64736f6c6343[0-9a-f]{6}0033` +
+		`|a265627a7a72315820[0-9a-f]{64}64736f6c6343[0-9a-f]{6}0032` +
+		`|a165627a7a72305820[0-9a-f]{64}0029`)
+
+// StripMetadata приводит hex-код к нижнему регистру без 0x и заменяет метаданные solc нулями той же длины
+// (смещения в коде сохраняются).
+func StripMetadata(code string) string {
+	code = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(code), "0x"), "0X"))
+	return metadataPattern.ReplaceAllStringFunc(code, func(m string) string {
+		return strings.Repeat("0", len(m))
+	})
+}
+
+// MatchBytecode сверяет результат компиляции с кодом контракта: runtime-код — с RuntimeBytecode, init-код — с Bytecode,
+// за которым допускаются аргументы конструктора (возвращаются hex без 0x). Пустой код из БД не сверяется.
+func MatchBytecode(result *CompileResult, initCode, runtimeCode []byte) (constructorArgs string, ok bool) {
+	if result == nil {
+		return "", false
+	}
+	if len(runtimeCode) > 0 && result.RuntimeBytecode != "" &&
+		StripMetadata(hex.EncodeToString(runtimeCode)) == StripMetadata(result.RuntimeBytecode) {
+		return "", true
+	}
+	compiled := StripMetadata(result.Bytecode)
+	if len(initCode) == 0 || compiled == "" {
+		return "", false
+	}
+	deployed := StripMetadata(hex.EncodeToString(initCode))
+	if !strings.HasPrefix(deployed, compiled) {
+		return "", false
+	}
+	return deployed[len(compiled):], true
+}
+
+// VersionMatches проверяет, что версия solc installed (из solc --version) соответствует запрошенной requested:
+// "0.8.20" совпадает с любой сборкой 0.8.20, "v0.8.20+commit.a1b79de6" — только со сборкой этого коммита.
+func VersionMatches(installed, requested string) bool {
+	installed = strings.TrimPrefix(strings.TrimSpace(installed), "v")
+	requested = strings.TrimPrefix(strings.TrimSpace(requested), "v")
+	if requested == "" || installed == "" {
+		return false
+	}
+	if !strings.Contains(requested, "+") {
+		base, _, _ := strings.Cut(installed, "+")
+		return base == requested
+	}
+	return installed == requested || strings.HasPrefix(installed, requested+".")
+}
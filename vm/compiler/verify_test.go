@@ -0,0 +1,53 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package compiler
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// solcMetadata собирает ipfs-метаданные solc 0.8.20 с хешем, заполненным байтом b.
+func solcMetadata(b string) string {
+	return "a2646970667358221220" + strings.Repeat(b, 32) + "64736f6c6343" + "080014" + "0033"
+}
+
+func TestMatchBytecode_IgnoresMetadata(t *testing.T) {
+	runtime := "6080604052600080fd" + solcMetadata("ab")
+	creation := "608060405234801561001057600080fd5b50" + runtime
+	result := &CompileResult{Bytecode: creation, RuntimeBytecode: runtime}
+
+	deployedRuntime, _ := hex.DecodeString("6080604052600080fd" + solcMetadata("cd"))
+	if _, ok := MatchBytecode(result, nil, deployedRuntime); !ok {
+		t.Error("runtime-код с другим хешем метаданных должен совпадать")
+	}
+
+	args := strings.Repeat("0", 62) + "2a"
+	deployedInit, _ := hex.DecodeString(strings.Replace(creation, solcMetadata("ab"), solcMetadata("cd"), 1) + args)
+	got, ok := MatchBytecode(result, deployedInit, nil)
+	if !ok || got != args {
+		t.Errorf("init-код: ok=%v, аргументы конструктора %q", ok, got)
+	}
+
+	otherRuntime, _ := hex.DecodeString("6080604052600180fd" + solcMetadata("ab"))
+	if _, ok := MatchBytecode(result, nil, otherRuntime); ok {
+		t.Error("другой код не должен совпадать")
+	}
+}
+
+func TestVersionMatches(t *testing.T) {
+	installed := "0.8.20+commit.a1b79de6.Linux.g++"
+	cases := map[string]bool{
+		"0.8.20":                  true,
+		"v0.8.20":                 true,
+		"v0.8.20+commit.a1b79de6": true,
+		"0.8.20+commit.00000000":  false,
+		"0.8.2":                   false,
+		"":                        false,
+	}
+	for requested, want := range cases {
+		if got := VersionMatches(installed, requested); got != want {
+			t.Errorf("%q: ожидалось %v, получено %v", requested, want, got)
+		}
+	}
+}
@@ -56,6 +56,7 @@ type EVMConfig struct {
 	GasLimit   uint64
 	Coins      []CoinConfig
 	SolcPath   string // путь к solc (пусто — "solc")
+	ChainID    int64  // chain_id сети для опкода CHAINID (0 — DefaultChainID)
 }
 
 // EVM represents the Ethereum Virtual Machine
@@ -64,6 +65,7 @@ type EVM struct {
 	mutex        sync.RWMutex
 	eventManager types.EventManager
 	ctx          context.Context
+	trace        *traceSession // трассировка исполнения (TraceTx); nil — без трассировщика
 }
 
 // NewEVM creates a new EVM instance
@@ -87,7 +89,9 @@ func (e *EVM) GetSender() string {
 	return e.config.Coins[0].ContractAddress
 }
 
-// DeployContract deploys a contract bytecode, registers it, and deducts the fee in GND (соответствует types.EVMInterface)
+// DeployContract регистрирует токен-контракт в реестре ноды (ContractRegistry, соответствует types.EVMInterface).
+// Реестр не входит в состояние цепи, поэтому комиссия не списывается: баланс GND отправителя лишь должен покрывать
+// лимит газа. Деплой EVM-контракта в состоянии цепи — транзакцией деплоя (core.Blockchain.NewDeployTransaction).
 func (e *EVM) DeployContract(
 	from types.Address,
 	bytecode []byte,
@@ -113,14 +117,12 @@ func (e *EVM) DeployContract(
 	defer e.mutex.Unlock()
 
 	fromAddr := from
-	gp := uint64(0)
-	if gasPrice != nil {
-		gp = gasPrice.Uint64()
+	// Баланс должен покрывать весь лимит газа
+	deployTx := &core.Transaction{Sender: fromAddr, GasPrice: gasPrice}
+	if intrinsic := core.IntrinsicGas(bytecode, true); intrinsic > gasLimit {
+		return "", fmt.Errorf("intrinsic gas too low: have %d, want %d", gasLimit, intrinsic)
 	}
-	requiredFee := new(big.Int).Mul(
-		new(big.Int).SetUint64(gasLimit),
-		new(big.Int).SetUint64(gp),
-	)
+	requiredFee := deployTx.CalculateTxFee(gasLimit)
 
 	if len(e.config.Coins) == 0 {
 		return "", errors.New("coin configuration is required")
@@ -166,11 +168,6 @@ func (e *EVM) DeployContract(
 	// Register contract
 	ContractRegistry[contract.address] = contract
 
-	// Deduct fee
-	if err := e.config.State.SubBalance(fromAddr, primarySymbol, requiredFee); err != nil {
-		return "", fmt.Errorf("error deducting fee: %v", err)
-	}
-
 	return addr, nil
 }
 
@@ -193,6 +190,8 @@ func (e *EVM) CallContract(from string, to string, data []byte, gasLimit uint64,
 
 // CallContractStatic выполняет read-only вызов контракта (view/constant) без добавления в mempool.
 // Используется для balanceOf, totalSupply и т.п. — не создаёт транзакцию и не меняет nonce.
+// Байткод исполняется интерпретатором EVM, изменения состояния не применяются (возвращаются в StateChanges для предпросмотра).
+// Если кода контракта нет (или состояние не поддерживает исполнение) — чтение слота storage через State.CallStatic.
 func (e *EVM) CallContractStatic(from string, to string, data []byte, gasLimit uint64, value uint64) (*types.ExecutionResult, error) {
 	tx := &core.Transaction{
 		Sender:    types.Address(from),
@@ -200,9 +199,34 @@ func (e *EVM) CallContractStatic(from string, to string, data []byte, gasLimit u
 		Data:      data,
 		GasLimit:  gasLimit,
 		GasPrice:  big.NewInt(0),
-		Value:     big.NewInt(int64(value)),
+		Value:     new(big.Int).SetUint64(value),
+	}
+	if !e.hasCode(to) {
+		return e.config.State.CallStatic(tx)
+	}
+	return e.execute(tx, nil)
+}
+
+// SimulateTransaction исполняет транзакцию вызова контракта без применения к состоянию (предпросмотр для /contract/:address/send).
+func (e *EVM) SimulateTransaction(tx *core.Transaction) (*types.ExecutionResult, error) {
+	if !e.hasCode(tx.Recipient.String()) {
+		return e.config.State.CallStatic(tx)
+	}
+	return e.execute(tx, nil)
+}
+
+// hasCode сообщает, есть ли по адресу код контракта, который можно исполнить. Runtime-код, полученный из init-кода,
+// здесь не сохраняется: он попадает в состояние изменением кода при применении транзакции в блоке.
+func (e *EVM) hasCode(address string) bool {
+	cs, err := e.contractState()
+	if err != nil {
+		return false
+	}
+	code, isInitCode := cs.GetContractCode(address)
+	if isInitCode {
+		code = e.resolveRuntimeCode(ToEVMAddress(address), code)
 	}
-	return e.config.State.CallStatic(tx)
+	return len(code) > 0
 }
 
 // GetBalance возвращает баланс GND для адреса
@@ -224,16 +248,14 @@ func (e *EVM) BlockByNumber(number uint64) (*core.Block, error) {
 	return e.config.Blockchain.GetBlockByNumber(number)
 }
 
-// SendRawTransaction sends a raw transaction
+// SendRawTransaction декодирует raw-транзакцию (core.DecodeRawTransaction) и отправляет её с полной проверкой
+// (подпись, chain_id и subnet_id, баланс, nonce) — как POST /api/v1/transaction.
 func (e *EVM) SendRawTransaction(rawTx []byte) (string, error) {
 	tx, err := core.DecodeRawTransaction(rawTx)
 	if err != nil {
 		return "", err
 	}
-	if err := e.config.Blockchain.AddTx(tx); err != nil {
-		return "", err
-	}
-	return tx.Hash, nil
+	return e.config.Blockchain.SendTransaction(tx)
 }
 
 // GetTxStatus returns the transaction status
@@ -255,25 +277,14 @@ func (e *EVM) ExecuteTransaction(tx *core.Transaction) (*types.ExecutionResult,
 		return nil, errors.New("insufficient balance")
 	}
 
-	// Вычитаем баланс отправителя
-	if err := e.config.State.SubBalance(types.Address(tx.Sender), e.config.Coins[0].Symbol, tx.Value); err != nil {
-		return nil, err
-	}
-
-	// Если это вызов контракта
+	// Если это вызов контракта: предварительно исполняем байткод (value и storage применятся при включении в блок)
 	if tx.IsContractCall() {
-		// Выполняем статический вызов
-		result, err := e.config.State.CallStatic(tx)
+		result, err := e.SimulateTransaction(tx)
 		if err != nil {
-			// Возвращаем баланс в случае ошибки
-			e.config.State.AddBalance(types.Address(tx.Sender), e.config.Coins[0].Symbol, tx.Value)
 			return nil, err
 		}
-
-		// Проверяем баланс получателя
-		recipientBalance := e.config.State.GetBalance(types.Address(tx.Recipient), e.config.Coins[0].Symbol)
-		if recipientBalance == nil {
-			return nil, errors.New("failed to get recipient balance")
+		if result.Error != nil {
+			return result, nil
 		}
 
 		// Добавляем транзакцию в блокчейн
@@ -284,6 +295,11 @@ func (e *EVM) ExecuteTransaction(tx *core.Transaction) (*types.ExecutionResult,
 		return result, nil
 	}
 
+	// Вычитаем баланс отправителя
+	if err := e.config.State.SubBalance(types.Address(tx.Sender), e.config.Coins[0].Symbol, tx.Value); err != nil {
+		return nil, err
+	}
+
 	// Для обычного перевода просто добавляем баланс получателю
 	if err := e.config.State.AddBalance(types.Address(tx.Recipient), e.config.Coins[0].Symbol, tx.Value); err != nil {
 		// Возвращаем баланс отправителю в случае ошибки
@@ -292,7 +308,7 @@ func (e *EVM) ExecuteTransaction(tx *core.Transaction) (*types.ExecutionResult,
 	}
 
 	return &types.ExecutionResult{
-		GasUsed: 0, // TODO: Implement gas calculation
+		GasUsed: core.IntrinsicGas(tx.Data, false),
 		Error:   nil,
 	}, nil
 }
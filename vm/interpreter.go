@@ -0,0 +1,324 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// vm/interpreter.go — исполнение байткода контрактов интерпретатором go-ethereum поверх stateDB.
+
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"GND/core"
+	"GND/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethvm "github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// DefaultChainID — chain_id по умолчанию для опкода CHAINID, если в конфиге не задан.
+const DefaultChainID = 1
+
+// ErrStateNotSupported — состояние не даёт доступа к коду и storage контрактов (нужен core.ContractStateIface).
+var ErrStateNotSupported = errors.New("state does not support contract execution")
+
+// chainConfig возвращает правила EVM: все форки до Cancun включительно (PUSH0, MCOPY, TSTORE), chain_id сети.
+func (e *EVM) chainConfig() *params.ChainConfig {
+	chainID := e.config.ChainID
+	if chainID == 0 {
+		chainID = DefaultChainID
+	}
+	zero := uint64(0)
+	return &params.ChainConfig{
+		ChainID:                 big.NewInt(chainID),
+		HomesteadBlock:          big.NewInt(0),
+		EIP150Block:             big.NewInt(0),
+		EIP155Block:             big.NewInt(0),
+		EIP158Block:             big.NewInt(0),
+		ByzantiumBlock:          big.NewInt(0),
+		ConstantinopleBlock:     big.NewInt(0),
+		PetersburgBlock:         big.NewInt(0),
+		IstanbulBlock:           big.NewInt(0),
+		MuirGlacierBlock:        big.NewInt(0),
+		BerlinBlock:             big.NewInt(0),
+		LondonBlock:             big.NewInt(0),
+		ArrowGlacierBlock:       big.NewInt(0),
+		GrayGlacierBlock:        big.NewInt(0),
+		MergeNetsplitBlock:      big.NewInt(0),
+		ShanghaiTime:            &zero,
+		CancunTime:              &zero,
+		TerminalTotalDifficulty: big.NewInt(0),
+	}
+}
+
+// contractState возвращает состояние с доступом к коду и storage контрактов.
+func (e *EVM) contractState() (core.ContractStateIface, error) {
+	cs, ok := e.config.State.(core.ContractStateIface)
+	if !ok || cs == nil {
+		return nil, ErrStateNotSupported
+	}
+	return cs, nil
+}
+
+// blockContext формирует контекст блока: номер, время, coinbase (валидатор) и хеши предыдущих блоков для BLOCKHASH.
+func (e *EVM) blockContext(block *core.Block) gethvm.BlockContext {
+	if block == nil && e.config.Blockchain != nil {
+		block, _ = e.config.Blockchain.LatestBlock()
+	}
+	ctx := gethvm.BlockContext{
+		CanTransfer: func(db gethvm.StateDB, addr common.Address, amount *uint256.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db gethvm.StateDB, sender, recipient common.Address, amount *uint256.Int) {
+			db.SubBalance(sender, amount, tracing.BalanceChangeTransfer)
+			db.AddBalance(recipient, amount, tracing.BalanceChangeTransfer)
+		},
+		GetHash: func(n uint64) common.Hash {
+			if e.config.Blockchain == nil {
+				return common.Hash{}
+			}
+			b, err := e.config.Blockchain.GetBlockByNumber(n)
+			if err != nil || b == nil {
+				return common.Hash{}
+			}
+			return common.HexToHash(b.Hash)
+		},
+		GasLimit:    e.config.GasLimit,
+		BlockNumber: new(big.Int),
+		Difficulty:  new(big.Int),
+		BaseFee:     new(big.Int),
+		BlobBaseFee: new(big.Int),
+		Random:      &common.Hash{},
+	}
+	if block != nil {
+		ctx.Coinbase = ToEVMAddress(block.Miner)
+		ctx.BlockNumber = new(big.Int).SetUint64(block.Index)
+		ctx.Time = uint64(block.Timestamp.Unix())
+		if block.GasLimit > 0 {
+			ctx.GasLimit = block.GasLimit
+		}
+		random := common.HexToHash(block.PrevHash)
+		ctx.Random = &random
+		if block.BaseFee != nil {
+			ctx.BaseFee = new(big.Int).Set(block.BaseFee) // опкод BASEFEE
+		}
+	}
+	if ctx.GasLimit == 0 {
+		ctx.GasLimit = 10_000_000
+	}
+	return ctx
+}
+
+// newInterpreter создаёт интерпретатор go-ethereum и stateDB для одного исполнения.
+func (e *EVM) newInterpreter(block *core.Block, origin string, gasPrice *big.Int) (*gethvm.EVM, *stateDB, error) {
+	cs, err := e.contractState()
+	if err != nil {
+		return nil, nil, err
+	}
+	db := newStateDB(cs)
+	db.resolveCode = e.resolveRuntimeCode
+	if gasPrice == nil {
+		gasPrice = new(big.Int)
+	}
+	txCtx := gethvm.TxContext{Origin: db.register(origin), GasPrice: new(big.Int).Set(gasPrice)}
+	config := gethvm.Config{NoBaseFee: true}
+	if e.trace != nil {
+		config.Tracer = e.trace.hooks
+		db.onLog = e.trace.hooks.OnLog
+		e.trace.state.db = db
+	}
+	return gethvm.NewEVM(e.blockContext(block), txCtx, db, e.chainConfig(), config), db, nil
+}
+
+// execute исполняет вызов контракта tx. Результат содержит return data, использованный газ и изменения состояния
+// (только при успешном исполнении); revert и ошибки EVM возвращаются в result.Error, а не во втором значении.
+func (e *EVM) execute(tx *core.Transaction, block *core.Block) (*types.ExecutionResult, error) {
+	if tx == nil || tx.Recipient == "" {
+		return nil, errors.New("invalid contract call")
+	}
+	var baseFee *big.Int
+	if block != nil {
+		baseFee = block.BaseFee
+	}
+	interp, db, err := e.newInterpreter(block, tx.Sender.String(), tx.GasPriceAt(baseFee))
+	if err != nil {
+		return nil, err
+	}
+	from := db.register(tx.Sender.String())
+	to := db.register(tx.Recipient.String())
+	rules := interp.ChainConfig().Rules(interp.Context.BlockNumber, true, interp.Context.Time)
+	db.Prepare(rules, from, interp.Context.Coinbase, &to, gethvm.ActivePrecompiles(rules), nil)
+
+	gas := tx.GasLimit
+	if gas == 0 {
+		gas = e.config.GasLimit
+	}
+	intrinsic := core.IntrinsicGas(tx.Data, false)
+	if gas < intrinsic {
+		return nil, fmt.Errorf("intrinsic gas too low: have %d, want %d", gas, intrinsic)
+	}
+	// Value в GND переводится внутри EVM (CALLVALUE, откат при revert); в другой нативной монете — отдельным изменением баланса.
+	value := new(uint256.Int)
+	var symbolTransfer []*types.StateChange
+	if tx.Value != nil && tx.Value.Sign() > 0 {
+		if tx.Symbol == "" || tx.Symbol == core.GasSymbol {
+			v, overflow := uint256.FromBig(tx.Value)
+			if overflow {
+				return nil, errors.New("value overflows uint256")
+			}
+			value = v
+		} else {
+			symbolTransfer = []*types.StateChange{
+				types.NewStateChange(types.ChangeTypeBalance, tx.Sender, tx.Symbol, new(big.Int).Neg(tx.Value)),
+				types.NewStateChange(types.ChangeTypeBalance, tx.Recipient, tx.Symbol, new(big.Int).Set(tx.Value)),
+			}
+		}
+	}
+
+	ret, leftOver, vmErr := interp.Call(gethvm.AccountRef(from), to, tx.Data, gas-intrinsic, value)
+	result := &types.ExecutionResult{
+		GasUsed:    refundGas(db, gas-leftOver),
+		ReturnData: ret,
+	}
+	if vmErr != nil {
+		result.Error = executionError(vmErr, ret)
+		result.RevertReason = RevertReason(result.Error)
+		return result, nil
+	}
+	result.StateChanges = append(symbolTransfer, db.changes()...)
+	result.Logs = db.eventLogs()
+	return result, nil
+}
+
+// ExecuteContractCall исполняет вызов контракта в контексте блока (реализует core.ContractExecutor).
+func (e *EVM) ExecuteContractCall(tx *core.Transaction, block *core.Block) (*types.ExecutionResult, error) {
+	return e.execute(tx, block)
+}
+
+// ExecuteContractDeploy исполняет init-код транзакции деплоя в контексте блока (реализует core.ContractExecutor).
+func (e *EVM) ExecuteContractDeploy(tx *core.Transaction, block *core.Block) (*types.ExecutionResult, []byte, error) {
+	var baseFee *big.Int
+	if block != nil {
+		baseFee = block.BaseFee
+	}
+	return e.createAt(block, tx.Sender, tx.Recipient.String(), tx.Data, tx.GasLimit, tx.GasPriceAt(baseFee))
+}
+
+// ExecuteContractCreate исполняет init-код по заданному адресу контракта ГАНИМЕД (реализует core.ContractExecutor).
+// Адрес назначается нодой (GNDct…), поэтому init-код кладётся в код аккаунта и вызывается напрямую; возвращённые данные — runtime-код.
+func (e *EVM) ExecuteContractCreate(from types.Address, contractAddress string, initCode []byte, gasLimit uint64) (*types.ExecutionResult, []byte, error) {
+	return e.createAt(nil, from, contractAddress, initCode, gasLimit, nil)
+}
+
+// createAt исполняет init-код по адресу контракта в контексте блока block (nil — вершина цепи) с ценой газа gasPrice.
+func (e *EVM) createAt(block *core.Block, from types.Address, contractAddress string, initCode []byte, gasLimit uint64, gasPrice *big.Int) (*types.ExecutionResult, []byte, error) {
+	if len(initCode) == 0 {
+		return nil, nil, errors.New("empty contract bytecode")
+	}
+	interp, db, err := e.newInterpreter(block, from.String(), gasPrice)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gasLimit == 0 {
+		gasLimit = e.config.GasLimit
+	}
+	intrinsic := core.IntrinsicGas(initCode, true)
+	if gasLimit < intrinsic {
+		return nil, nil, fmt.Errorf("intrinsic gas too low: have %d, want %d", gasLimit, intrinsic)
+	}
+	result, code := e.create(interp, db, from.String(), contractAddress, initCode, gasLimit-intrinsic)
+	result.GasUsed = refundGas(db, result.GasUsed+intrinsic)
+	return result, code, nil
+}
+
+// create исполняет init-код в уже подготовленных interp/db и проверяет ограничения на runtime-код (размер, газ за хранение кода).
+func (e *EVM) create(interp *gethvm.EVM, db *stateDB, from, contractAddress string, initCode []byte, gas uint64) (*types.ExecutionResult, []byte) {
+	caller := db.register(from)
+	target := db.register(contractAddress)
+	rules := interp.ChainConfig().Rules(interp.Context.BlockNumber, true, interp.Context.Time)
+	db.Prepare(rules, caller, interp.Context.Coinbase, &target, gethvm.ActivePrecompiles(rules), nil)
+	db.CreateContract(target)
+	db.SetNonce(target, 1)
+	db.SetCode(target, initCode)
+
+	ret, leftOver, vmErr := interp.Call(gethvm.AccountRef(caller), target, nil, gas, new(uint256.Int))
+	result := &types.ExecutionResult{GasUsed: gas - leftOver, ReturnData: ret}
+	if vmErr != nil {
+		result.Error = executionError(vmErr, ret)
+		result.RevertReason = RevertReason(result.Error)
+		return result, nil
+	}
+	if len(ret) > params.MaxCodeSize {
+		result.Error = gethvm.ErrMaxCodeSizeExceeded
+		result.GasUsed = gas
+		return result, nil
+	}
+	depositGas := uint64(len(ret)) * params.CreateDataGas
+	if leftOver < depositGas {
+		result.Error = gethvm.ErrCodeStoreOutOfGas
+		result.GasUsed = gas
+		return result, nil
+	}
+	result.GasUsed += depositGas
+	db.SetCode(target, ret)
+	result.ReturnData = nil
+	for _, change := range db.changes() {
+		// Код создаваемого контракта возвращается отдельно (его сохраняет вызывающая сторона)
+		if change.Type == types.ChangeTypeCode && change.Address == contractAddress {
+			continue
+		}
+		result.StateChanges = append(result.StateChanges, change)
+	}
+	result.Logs = db.eventLogs()
+	return result, ret
+}
+
+// resolveRuntimeCode получает runtime-код контракта, для которого в БД сохранён только init-код (задеплоен до исполнения байткода):
+// конструктор исполняется в отдельном stateDB, изменения storage отбрасываются.
+func (e *EVM) resolveRuntimeCode(addr common.Address, initCode []byte) []byte {
+	if e.trace != nil {
+		return e.withState(e.config.State).resolveRuntimeCode(addr, initCode) // конструктор не трассируется
+	}
+	interp, db, err := e.newInterpreter(nil, "", nil)
+	if err != nil {
+		return nil
+	}
+	db.resolveCode = nil
+	result, code := e.create(interp, db, "", FromEVMAddress(addr), initCode, e.config.GasLimit)
+	if result == nil || result.Error != nil {
+		return nil
+	}
+	return code
+}
+
+// refundGas уменьшает использованный газ на накопленный возврат (очистка storage), не более gasUsed/5 (EIP-3529).
+func refundGas(db *stateDB, gasUsed uint64) uint64 {
+	refund := db.GetRefund()
+	if limit := gasUsed / params.RefundQuotientEIP3529; refund > limit {
+		refund = limit
+	}
+	return gasUsed - refund
+}
+
+// executionError приводит ошибку EVM к ошибке исполнения; для revert добавляет причину из Error(string), если она есть.
+func executionError(vmErr error, ret []byte) error {
+	if errors.Is(vmErr, gethvm.ErrExecutionReverted) {
+		if reason, err := abi.UnpackRevert(ret); err == nil {
+			return fmt.Errorf("%w: %s", gethvm.ErrExecutionReverted, reason)
+		}
+		return gethvm.ErrExecutionReverted
+	}
+	return vmErr
+}
+
+// RevertReason возвращает причину revert из ошибки исполнения (пустая строка, если это не revert или причина не передана).
+func RevertReason(err error) string {
+	if err == nil || !errors.Is(err, gethvm.ErrExecutionReverted) {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(err.Error(), gethvm.ErrExecutionReverted.Error()), ": ")
+}
@@ -0,0 +1,462 @@
+// | KB @CerberRus00 - Nexus Invest Team
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"GND/core"
+	"GND/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	testSender   = "GN_BSP32eTfEeRq4V9ZXVVsCmHEW2ZFNo8Ab"
+	testContract = "GNDct00112233445566778899aabbccddeeff"
+)
+
+// storeRuntime: calldata[0:32] != 0 — записать в слот 0; иначе вернуть слот 0.
+var storeRuntime = []byte{
+	0x60, 0x00, 0x35, // CALLDATALOAD(0)
+	0x80, 0x15, 0x60, 0x0c, 0x57, // DUP1 ISZERO PUSH1 0x0c JUMPI
+	0x60, 0x00, 0x55, 0x00, // SSTORE(0, x) STOP
+	0x5b, 0x50, // JUMPDEST POP
+	0x60, 0x00, 0x54, 0x60, 0x00, 0x52, // MSTORE(0, SLOAD(0))
+	0x60, 0x20, 0x60, 0x00, 0xf3, // RETURN(0, 32)
+}
+
+// storeInitCode: конструктор записывает 42 в слот 0 и возвращает storeRuntime.
+func storeInitCode() []byte {
+	n := byte(len(storeRuntime))
+	code := []byte{
+		0x60, 0x2a, 0x60, 0x00, 0x55, // SSTORE(0, 42)
+		0x60, n, 0x60, 17, 0x60, 0x00, 0x39, // CODECOPY(0, 17, n)
+		0x60, n, 0x60, 0x00, 0xf3, // RETURN(0, n)
+	}
+	return append(code, storeRuntime...)
+}
+
+// revertRuntime возвращает код, который всегда делает revert с Error(reason).
+func revertRuntime(t *testing.T, reason string) []byte {
+	t.Helper()
+	strType, _ := abi.NewType("string", "", nil)
+	packed, err := abi.Arguments{{Type: strType}}.Pack(reason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := append([]byte{0x08, 0xc3, 0x79, 0xa0}, packed...)
+	n := byte(len(payload))
+	code := []byte{
+		0x60, n, 0x60, 12, 0x60, 0x00, 0x39, // CODECOPY(0, 12, n)
+		0x60, n, 0x60, 0x00, 0xfd, // REVERT(0, n)
+	}
+	return append(code, payload...)
+}
+
+func uint256Word(v int64) []byte {
+	word := make([]byte, 32)
+	big.NewInt(v).FillBytes(word)
+	return word
+}
+
+func newTestEVM(t *testing.T) (*EVM, *core.State) {
+	t.Helper()
+	st := core.NewState()
+	if err := st.AddBalance(types.Address(testSender), core.GasSymbol, big.NewInt(1_000_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	return NewEVM(EVMConfig{State: st, GasLimit: 1_000_000}), st
+}
+
+func TestExecuteContractCreate_RunsConstructor(t *testing.T) {
+	e, st := newTestEVM(t)
+	result, code, err := e.ExecuteContractCreate(types.Address(testSender), testContract, storeInitCode(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != nil {
+		t.Fatalf("конструктор не должен откатываться: %v", result.Error)
+	}
+	if !bytes.Equal(code, storeRuntime) {
+		t.Fatalf("runtime-код не совпадает: %x", code)
+	}
+	if len(result.StateChanges) != 2 || result.StateChanges[0].Type != types.ChangeTypeNonce ||
+		result.StateChanges[1].Type != types.ChangeTypeStorage {
+		t.Fatalf("ожидались nonce контракта и одна запись storage, получено %d изменений", len(result.StateChanges))
+	}
+	if err := st.SetContractCode(testContract, code); err != nil {
+		t.Fatal(err)
+	}
+	tx := &core.Transaction{Sender: types.Address(testSender), Recipient: types.Address(testContract)}
+	if err := st.ApplyExecutionResult(tx, result); err != nil {
+		t.Fatal(err)
+	}
+
+	call, err := e.CallContractStatic(testSender, testContract, make([]byte, 32), 100_000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := new(big.Int).SetBytes(call.ReturnData); got.Int64() != 42 {
+		t.Errorf("ожидалось 42 из storage конструктора, получено %s", got)
+	}
+}
+
+func TestExecuteContractCall_WritesStorage(t *testing.T) {
+	e, st := newTestEVM(t)
+	if err := st.SetContractCode(testContract, storeRuntime); err != nil {
+		t.Fatal(err)
+	}
+	tx := &core.Transaction{
+		Sender:    types.Address(testSender),
+		Recipient: types.Address(testContract),
+		Data:      uint256Word(7),
+		GasLimit:  100_000,
+		GasPrice:  big.NewInt(1),
+		Value:     big.NewInt(0),
+	}
+	result, err := e.ExecuteContractCall(tx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != nil {
+		t.Fatalf("вызов не должен откатываться: %v", result.Error)
+	}
+	if result.GasUsed == 0 {
+		t.Error("GasUsed должен быть > 0")
+	}
+	if err := st.ApplyExecutionResult(tx, result); err != nil {
+		t.Fatal(err)
+	}
+	if got := new(big.Int).SetBytes(st.GetStorageSlot(testContract, make([]byte, 32))); got.Int64() != 7 {
+		t.Errorf("слот 0 после вызова: ожидалось 7, получено %s", got)
+	}
+	call, err := e.CallContractStatic(testSender, testContract, make([]byte, 32), 100_000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := new(big.Int).SetBytes(call.ReturnData); got.Int64() != 7 {
+		t.Errorf("view после вызова: ожидалось 7, получено %s", got)
+	}
+}
+
+// initCodeState — состояние, в котором для testContract известен только init-код (контракт задеплоен до исполнения байткода).
+type initCodeState struct {
+	*core.State
+}
+
+func (s initCodeState) GetContractCode(address string) ([]byte, bool) {
+	if code, _ := s.State.GetContractCode(address); len(code) > 0 || address != testContract {
+		return code, false
+	}
+	return storeInitCode(), true
+}
+
+func TestExecuteContractCall_ResolvedCodeStoredOnlyByBlock(t *testing.T) {
+	_, st := newTestEVM(t)
+	e := NewEVM(EVMConfig{State: initCodeState{st}, GasLimit: 1_000_000})
+	root := st.RootHash()
+
+	if _, err := e.CallContractStatic(testSender, testContract, make([]byte, 32), 100_000, 0); err != nil {
+		t.Fatal(err)
+	}
+	if st.RootHash() != root {
+		t.Fatal("view-вызов не должен сохранять runtime-код в состоянии")
+	}
+
+	tx := &core.Transaction{
+		Sender:    types.Address(testSender),
+		Recipient: types.Address(testContract),
+		Data:      uint256Word(7),
+		GasLimit:  100_000,
+		GasPrice:  big.NewInt(1),
+		Value:     big.NewInt(0),
+	}
+	result, err := e.ExecuteContractCall(tx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != nil {
+		t.Fatalf("вызов не должен откатываться: %v", result.Error)
+	}
+	if err := st.ApplyExecutionResult(tx, result); err != nil {
+		t.Fatal(err)
+	}
+	if code, isInitCode := st.GetContractCode(testContract); isInitCode || !bytes.Equal(code, storeRuntime) {
+		t.Errorf("после применения транзакции ожидался runtime-код, получено %x", code)
+	}
+}
+
+// factoryRuntime: CREATE с пустым init-кодом, возвращает адрес созданного контракта.
+var factoryRuntime = []byte{
+	0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0xf0, // CREATE(0, 0, 0)
+	0x60, 0x00, 0x52, // MSTORE(0, addr)
+	0x60, 0x20, 0x60, 0x00, 0xf3, // RETURN(0, 32)
+}
+
+func TestExecuteContractCall_CreatePersistsFactoryNonce(t *testing.T) {
+	e, st := newTestEVM(t)
+	if err := st.SetContractCode(testContract, factoryRuntime); err != nil {
+		t.Fatal(err)
+	}
+	var created []common.Address
+	for i := 0; i < 2; i++ {
+		tx := &core.Transaction{
+			Sender:    types.Address(testSender),
+			Recipient: types.Address(testContract),
+			Nonce:     int64(i),
+			GasLimit:  200_000,
+			GasPrice:  big.NewInt(1),
+			Value:     big.NewInt(0),
+		}
+		result, err := e.ExecuteContractCall(tx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Error != nil {
+			t.Fatalf("вызов %d не должен откатываться: %v", i, result.Error)
+		}
+		if err := st.ApplyExecutionResult(tx, result); err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, common.BytesToAddress(result.ReturnData))
+	}
+	if created[0] == (common.Address{}) || created[1] == (common.Address{}) || created[0] == created[1] {
+		t.Fatalf("второй CREATE должен получить новый адрес: %x, %x", created[0], created[1])
+	}
+	if got := st.GetNonce(types.Address(testContract)); got != 2 {
+		t.Errorf("nonce фабрики: ожидалось 2, получено %d", got)
+	}
+}
+
+func TestStateDBChanges_SkipsSelfDestructedAccount(t *testing.T) {
+	st := core.NewState()
+	if err := st.AddBalance(types.Address(testContract), core.GasSymbol, big.NewInt(100)); err != nil {
+		t.Fatal(err)
+	}
+	db := newStateDB(st)
+	a := db.register(testContract)
+	db.CreateContract(a)
+	db.SetNonce(a, 1)
+	db.SetCode(a, storeRuntime)
+	db.SetState(a, common.Hash{}, common.BigToHash(big.NewInt(42)))
+	if _, destructed := db.SelfDestruct6780(a); !destructed {
+		t.Fatal("контракт, созданный в исполнении, должен уничтожаться")
+	}
+	changes := db.changes()
+	if len(changes) != 1 || changes[0].Type != types.ChangeTypeBalance || changes[0].Amount.Int64() != -100 {
+		t.Fatalf("у уничтоженного аккаунта ожидалось только списание баланса, получено %d изменений", len(changes))
+	}
+}
+
+func TestCallContractStatic_RevertReason(t *testing.T) {
+	e, st := newTestEVM(t)
+	if err := st.SetContractCode(testContract, revertRuntime(t, "bad value")); err != nil {
+		t.Fatal(err)
+	}
+	result, err := e.CallContractStatic(testSender, testContract, []byte{0x01, 0x02, 0x03, 0x04}, 100_000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error == nil {
+		t.Fatal("ожидался revert")
+	}
+	if reason := RevertReason(result.Error); reason != "bad value" {
+		t.Errorf("причина revert: ожидалось %q, получено %q", "bad value", reason)
+	}
+	if len(result.StateChanges) != 0 {
+		t.Error("при revert изменения состояния не возвращаются")
+	}
+}
+
+func TestCallContractStatic_NoCodeFallsBackToStorageRead(t *testing.T) {
+	e, _ := newTestEVM(t)
+	result, err := e.CallContractStatic(testSender, testContract, []byte{0x18, 0x16, 0x0d, 0xdd}, 100_000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.ReturnData) != 32 || new(big.Int).SetBytes(result.ReturnData).Sign() != 0 {
+		t.Errorf("без кода ожидался uint256(0) из State.CallStatic, получено %x", result.ReturnData)
+	}
+}
+
+func TestEVMAddressMapping(t *testing.T) {
+	a := ToEVMAddress(testContract)
+	if got := FromEVMAddress(a); got != testContract {
+		t.Errorf("GNDct: ожидалось %s, получено %s", testContract, got)
+	}
+	w := ToEVMAddress(testSender)
+	if got := FromEVMAddress(w); got != testSender {
+		t.Errorf("кошелёк: ожидалось %s, получено %s", testSender, got)
+	}
+	hexAddr := "0x00000000000000000000000000000000000000ff"
+	if ToEVMAddress(hexAddr) != ToEVMAddress(hexAddr[2:]) {
+		t.Error("0x-адрес и 40 hex должны давать один адрес EVM")
+	}
+	// адрес с нулевыми старшими байтами без известного соответствия — не контракт GNDct
+	if got := FromEVMAddress(common.HexToAddress(hexAddr)); got != hexAddr {
+		t.Errorf("неизвестный адрес: ожидалось %s, получено %s", hexAddr, got)
+	}
+}
+
+// mapAddressStore — AddressStore в памяти для тестов.
+type mapAddressStore map[string]string
+
+func (m mapAddressStore) SaveEVMAddress(evmAddress, address string) error {
+	m[evmAddress] = address
+	return nil
+}
+
+func (m mapAddressStore) LoadEVMAddress(evmAddress string) (string, bool) {
+	address, ok := m[evmAddress]
+	return address, ok
+}
+
+func TestEVMAddressMapping_PersistedInStore(t *testing.T) {
+	store := mapAddressStore{}
+	SetAddressStore(store)
+	t.Cleanup(func() { SetAddressStore(nil) })
+
+	const wallet = "GN_3nQ8zvKcP1mRwT5yXaB7dF2gH4jL6sU9e"
+	a := ToEVMAddress(wallet)
+	if _, ok := store[strings.ToLower(a.Hex())]; ok {
+		t.Fatal("соответствие не должно записываться в хранилище до принятия блока")
+	}
+	addressBook.Remove(a)
+	if got := FromEVMAddress(a); got != wallet {
+		t.Errorf("до записи соответствие берётся из очереди: ожидалось %s, получено %s", wallet, got)
+	}
+	FlushAddresses()
+	if got := store[strings.ToLower(a.Hex())]; got != wallet {
+		t.Fatalf("соответствие должно быть записано в хранилище, получено %q", got)
+	}
+	addressBook.Remove(a) // вытеснено из кэша или нода перезапущена
+	if got := FromEVMAddress(a); got != wallet {
+		t.Errorf("ожидалось %s из хранилища, получено %s", wallet, got)
+	}
+}
+
+func TestExecuteContractCall_IntrinsicGas(t *testing.T) {
+	e, st := newTestEVM(t)
+	if err := st.SetContractCode(testContract, storeRuntime); err != nil {
+		t.Fatal(err)
+	}
+	data := uint256Word(7)
+	tx := &core.Transaction{Sender: types.Address(testSender), Recipient: types.Address(testContract), Data: data, GasLimit: 100_000}
+	result, err := e.ExecuteContractCall(tx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intrinsic := core.IntrinsicGas(data, false); result.GasUsed <= intrinsic {
+		t.Errorf("GasUsed %d должен включать базовый газ %d и газ опкодов", result.GasUsed, intrinsic)
+	}
+
+	tx.GasLimit = core.TxGas
+	if _, err := e.ExecuteContractCall(tx, nil); err == nil {
+		t.Error("лимит газа ниже базового должен возвращать ошибку")
+	}
+}
+
+func TestExecuteContractCall_CollectsLogs(t *testing.T) {
+	e, st := newTestEVM(t)
+	// LOG1(0, 32, topic=0x01) после MSTORE(0, 7)
+	code := []byte{
+		0x60, 0x07, 0x60, 0x00, 0x52, // MSTORE(0, 7)
+		0x60, 0x01, 0x60, 0x20, 0x60, 0x00, 0xa1, // LOG1(0, 32, 1)
+		0x00, // STOP
+	}
+	if err := st.SetContractCode(testContract, code); err != nil {
+		t.Fatal(err)
+	}
+	tx := &core.Transaction{Sender: types.Address(testSender), Recipient: types.Address(testContract), GasLimit: 100_000}
+	result, err := e.ExecuteContractCall(tx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != nil {
+		t.Fatalf("вызов не должен откатываться: %v", result.Error)
+	}
+	if len(result.Logs) != 1 {
+		t.Fatalf("ожидалось одно событие, получено %d", len(result.Logs))
+	}
+	l := result.Logs[0]
+	if l.Address != testContract || len(l.Topics) != 1 || !bytes.Equal(l.Topics[0], uint256Word(1)) || !bytes.Equal(l.Data, uint256Word(7)) {
+		t.Errorf("событие не совпадает: %+v", l)
+	}
+}
+
+func TestTraceTx_PrestateDiffAndRevertReason(t *testing.T) {
+	genesis := &core.Block{Index: 0, Timestamp: time.Now(), Miner: "miner", GasLimit: core.DefaultBlockGasLimit}
+	genesis.Hash = genesis.CalculateHash()
+	bc := core.NewBlockchain(genesis, nil)
+	st := bc.State.(*core.State)
+	if err := st.AddBalance(types.Address(testSender), core.GasSymbol, big.NewInt(1_000_000_000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetContractCode(testContract, storeRuntime); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEVM(EVMConfig{Blockchain: bc, State: st, GasLimit: 1_000_000})
+	tx := &core.Transaction{Sender: types.Address(testSender), Recipient: types.Address(testContract), Data: uint256Word(7), GasLimit: 100_000, Symbol: core.GasSymbol}
+
+	replay, err := bc.ReplayAt(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer, err := NewTracer("prestateTracer", json.RawMessage(`{"diffMode":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := e.TraceTx(replay, tx, tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var diff struct {
+		Pre  map[common.Address]PrestateAccount `json:"pre"`
+		Post map[common.Address]PrestateAccount `json:"post"`
+	}
+	if err := json.Unmarshal(out, &diff); err != nil {
+		t.Fatal(err)
+	}
+	contract, slot := ToEVMAddress(testContract), common.Hash{}
+	if got := diff.Post[contract].Storage[slot]; got != common.BytesToHash(uint256Word(7)) {
+		t.Errorf("post: слот 0 должен стать 7, получено %s (%s)", got, out)
+	}
+	if pre, ok := diff.Pre[contract].Storage[slot]; !ok || pre != (common.Hash{}) {
+		t.Errorf("pre: слот 0 до вызова должен быть 0 (%s)", out)
+	}
+	if diff.Post[ToEVMAddress(testSender)].Nonce != 1 {
+		t.Errorf("post: nonce отправителя должен стать 1 (%s)", out)
+	}
+	if got := st.GetStorageSlot(testContract, make([]byte, 32)); new(big.Int).SetBytes(got).Sign() != 0 {
+		t.Error("трассировка не должна менять состояние ноды")
+	}
+
+	if err := st.SetContractCode(testContract, revertRuntime(t, "bad value")); err != nil {
+		t.Fatal(err)
+	}
+	if replay, err = bc.ReplayAt(nil); err != nil {
+		t.Fatal(err)
+	}
+	if tracer, err = NewTracer("callTracer", nil); err != nil {
+		t.Fatal(err)
+	}
+	if out, err = e.TraceTx(replay, tx, tracer); err != nil {
+		t.Fatal(err)
+	}
+	var frame CallFrame
+	if err := json.Unmarshal(out, &frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != "CALL" || frame.To != contract || frame.RevertReason != "bad value" || frame.GasUsed == 0 {
+		t.Errorf("callTracer: %s", out)
+	}
+	if _, err := NewTracer("unknownTracer", nil); err == nil {
+		t.Error("неизвестный трассировщик должен возвращать ошибку")
+	}
+}
@@ -0,0 +1,154 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// vm/simulate.go — пробное исполнение транзакции без применения к состоянию и оценка газа (POST /transaction/simulate, eth_estimateGas).
+
+package vm
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"GND/core"
+	"GND/types"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// estimateGasDivisor — точность бинарного поиска газа: поиск останавливается, когда интервал не больше 1/64 найденного значения.
+const estimateGasDivisor = 64
+
+// Simulation — результат пробного исполнения транзакции. Изменения состояния и события не применяются.
+type Simulation struct {
+	GasUsed      uint64 // газ при исполнении с лимитом транзакции (или блока)
+	GasEstimate  uint64 // минимальный лимит газа, при котором транзакция исполняется успешно (0 — транзакция не исполняется)
+	ReturnData   []byte
+	StateChanges []*types.StateChange
+	Logs         []*types.Log
+	Error        error  // причина неуспеха: revert, ошибка EVM или нехватка баланса
+	RevertReason string // причина revert из Error(string), если передана
+}
+
+// Simulate исполняет транзакцию tx над состоянием st (nil — состояние EVM) в контексте блока block (nil — вершина цепи),
+// не применяя результат. Вызов контракта и деплой (пустой получатель) исполняются интерпретатором, вызов адреса контракта
+// без кода — через State.CallStatic, перевод — базовым газом. Лимит газа — tx.GasLimit, при нуле — лимит блока;
+// оценка газа — бинарный поиск между фактическим газом и этим лимитом. Ошибка возвращается, если транзакцию нельзя
+// исполнить ни при каком газе (например, лимит ниже базового газа); revert и нехватка баланса — в Simulation.Error.
+func (e *EVM) Simulate(tx *core.Transaction, block *core.Block, st core.StateIface) (*Simulation, error) {
+	if tx == nil {
+		return nil, errors.New("empty transaction")
+	}
+	sim := e
+	if st != nil {
+		sim = e.withState(st)
+	}
+	limit := tx.GasLimit
+	if limit == 0 {
+		limit = core.DefaultBlockGasLimit
+		if block != nil && block.GasLimit > 0 {
+			limit = block.GasLimit
+		}
+	}
+	run := func(gas uint64) (*types.ExecutionResult, error) {
+		call := *tx
+		call.GasLimit = gas
+		return sim.simulateOnce(&call, block)
+	}
+	result, err := run(limit)
+	if err != nil {
+		return nil, err
+	}
+	out := &Simulation{
+		GasUsed:      result.GasUsed,
+		ReturnData:   result.ReturnData,
+		StateChanges: result.StateChanges,
+		Logs:         result.Logs,
+		Error:        result.Error,
+		RevertReason: result.RevertReason,
+	}
+	if result.Error != nil {
+		return out, nil
+	}
+	// Чаще всего хватает фактического газа; иначе (возврат газа, правило 63/64 для вложенных вызовов) — бинарный поиск
+	hi := limit
+	if result.GasUsed < hi {
+		if res, err := run(result.GasUsed); err == nil && res.Error == nil {
+			hi = result.GasUsed
+		}
+	}
+	lo := result.GasUsed
+	for lo+1 < hi && hi-lo > hi/estimateGasDivisor {
+		mid := lo + (hi-lo)/2
+		res, err := run(mid)
+		if err == nil && res.Error == nil {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	out.GasEstimate = hi
+	return out, nil
+}
+
+// simulateOnce исполняет tx с её лимитом газа, не применяя изменения.
+func (e *EVM) simulateOnce(tx *core.Transaction, block *core.Block) (*types.ExecutionResult, error) {
+	var baseFee *big.Int
+	if block != nil {
+		baseFee = block.BaseFee
+	}
+	switch {
+	case tx.Recipient == "":
+		result, _, err := e.createAt(block, tx.Sender, simulationContractAddress(tx.Sender, tx.Data), tx.Data, tx.GasLimit, tx.GasPriceAt(baseFee))
+		return result, err
+	case e.hasCode(tx.Recipient.String()):
+		return e.execute(tx, block)
+	}
+	intrinsic := core.IntrinsicGas(tx.Data, false)
+	if tx.GasLimit < intrinsic {
+		return nil, fmt.Errorf("intrinsic gas too low: have %d, want %d", tx.GasLimit, intrinsic)
+	}
+	if len(tx.Data) > 0 && types.IsContractAddress(tx.Recipient.String()) {
+		result, err := e.config.State.CallStatic(tx)
+		if err != nil {
+			return nil, err
+		}
+		if result.GasUsed < intrinsic {
+			result.GasUsed = intrinsic
+		}
+		return result, nil
+	}
+	result := &types.ExecutionResult{GasUsed: intrinsic}
+	if tx.Value == nil || tx.Value.Sign() <= 0 {
+		return result, nil
+	}
+	symbol := tx.Symbol
+	if symbol == "" {
+		symbol = core.GasSymbol
+	}
+	balance := e.config.State.GetBalance(tx.Sender, symbol)
+	if balance == nil {
+		balance = new(big.Int)
+	}
+	if balance.Cmp(tx.Value) < 0 {
+		result.Error = fmt.Errorf("insufficient balance: have %s %s, want %s", balance, symbol, tx.Value)
+		return result, nil
+	}
+	result.StateChanges = []*types.StateChange{
+		types.NewStateChange(types.ChangeTypeBalance, tx.Sender, symbol, new(big.Int).Neg(tx.Value)),
+		types.NewStateChange(types.ChangeTypeBalance, tx.Recipient, symbol, new(big.Int).Set(tx.Value)),
+	}
+	return result, nil
+}
+
+// withState возвращает EVM с той же конфигурацией над состоянием st (например, копией состояния после блока).
+func (e *EVM) withState(st core.StateIface) *EVM {
+	config := e.config
+	config.State = st
+	return NewEVM(config)
+}
+
+// simulationContractAddress возвращает временный адрес контракта ГАНИМЕД для пробного деплоя (адрес реального деплоя назначает нода).
+func simulationContractAddress(from types.Address, initCode []byte) string {
+	seed := crypto.Keccak256([]byte(from), initCode)
+	return types.ContractAddressPrefix + hex.EncodeToString(seed[:types.ContractAddressSuffixLen/2])
+}
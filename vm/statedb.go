@@ -0,0 +1,520 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// vm/statedb.go — адаптер vm.StateDB (go-ethereum) поверх core.State и contract_storage.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"GND/core"
+	"GND/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie/utils"
+	"github.com/holiman/uint256"
+)
+
+// overlayAccount — аккаунт в рамках одного исполнения: значения из core.State подгружаются лениво, изменения копятся в памяти.
+type overlayAccount struct {
+	address        string
+	balance        *uint256.Int
+	origBalance    *uint256.Int
+	nonce          uint64
+	origNonce      uint64
+	nonceLoaded    bool
+	code           []byte
+	codeLoaded     bool
+	codeDirty      bool
+	codeResolved   bool                        // runtime-код получен исполнением init-кода и ещё не записан в состояние
+	committed      map[common.Hash]common.Hash // значения storage до исполнения
+	dirty          map[common.Hash]common.Hash // значения storage, записанные при исполнении
+	created        bool
+	selfDestructed bool
+}
+
+// stateDB реализует vm.StateDB: чтение из core.State (балансы GND, код, слоты storage), запись — в overlay с журналом для откатов.
+// Изменения не применяются к core.State напрямую: changes() возвращает их как types.StateChange для State.ApplyExecutionResult.
+type stateDB struct {
+	backend     core.ContractStateIface
+	accounts    map[common.Address]*overlayAccount
+	journal     []func()
+	refund      uint64
+	logs        []*gethtypes.Log
+	transient   map[common.Address]map[common.Hash]common.Hash
+	accessList  map[common.Address]map[common.Hash]struct{}
+	resolveCode func(addr common.Address, initCode []byte) []byte // получение runtime-кода из init-кода (контракты до исполнения байткода)
+	onLog       tracing.LogHook                                   // трассировщик событий (nil — без трассировки)
+}
+
+// newStateDB создаёт адаптер поверх состояния ноды.
+func newStateDB(backend core.ContractStateIface) *stateDB {
+	return &stateDB{
+		backend:    backend,
+		accounts:   make(map[common.Address]*overlayAccount),
+		transient:  make(map[common.Address]map[common.Hash]common.Hash),
+		accessList: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// register сопоставляет адрес ГАНИМЕД адресу EVM и закрепляет его в overlay (для обратного преобразования при записи).
+func (db *stateDB) register(addr string) common.Address {
+	a := ToEVMAddress(addr)
+	if _, ok := db.accounts[a]; !ok {
+		db.accounts[a] = &overlayAccount{address: addr}
+	}
+	return a
+}
+
+func (db *stateDB) account(a common.Address) *overlayAccount {
+	acc, ok := db.accounts[a]
+	if !ok {
+		acc = &overlayAccount{address: FromEVMAddress(a)}
+		db.accounts[a] = acc
+	}
+	return acc
+}
+
+func (db *stateDB) loadBalance(acc *overlayAccount) {
+	if acc.balance != nil {
+		return
+	}
+	b := db.backend.GetBalance(types.Address(acc.address), core.GasSymbol)
+	v, overflow := uint256.FromBig(b)
+	if overflow || v == nil {
+		v = new(uint256.Int)
+	}
+	acc.balance = v
+	acc.origBalance = v.Clone()
+}
+
+func (db *stateDB) loadCode(a common.Address, acc *overlayAccount) {
+	if acc.codeLoaded {
+		return
+	}
+	acc.codeLoaded = true
+	code, isInitCode := db.backend.GetContractCode(acc.address)
+	if isInitCode {
+		if db.resolveCode == nil {
+			code = nil
+		} else {
+			code = db.resolveCode(a, code)
+			acc.codeResolved = len(code) > 0
+		}
+	}
+	acc.code = code
+}
+
+// CreateAccount создаёт аккаунт (при переводе на несуществующий адрес).
+func (db *stateDB) CreateAccount(a common.Address) {
+	db.account(a)
+}
+
+// CreateContract помечает аккаунт как созданный в текущем исполнении.
+func (db *stateDB) CreateContract(a common.Address) {
+	acc := db.account(a)
+	if !acc.created {
+		acc.created = true
+		db.journal = append(db.journal, func() { acc.created = false })
+	}
+}
+
+// SubBalance списывает баланс GND.
+func (db *stateDB) SubBalance(a common.Address, amount *uint256.Int, _ tracing.BalanceChangeReason) uint256.Int {
+	acc := db.account(a)
+	db.loadBalance(acc)
+	prev := *acc.balance
+	if amount.IsZero() {
+		return prev
+	}
+	acc.balance = new(uint256.Int).Sub(acc.balance, amount)
+	db.journal = append(db.journal, func() { acc.balance = &prev })
+	return prev
+}
+
+// AddBalance зачисляет баланс GND.
+func (db *stateDB) AddBalance(a common.Address, amount *uint256.Int, _ tracing.BalanceChangeReason) uint256.Int {
+	acc := db.account(a)
+	db.loadBalance(acc)
+	prev := *acc.balance
+	if amount.IsZero() {
+		return prev
+	}
+	acc.balance = new(uint256.Int).Add(acc.balance, amount)
+	db.journal = append(db.journal, func() { acc.balance = &prev })
+	return prev
+}
+
+// GetBalance возвращает баланс GND.
+func (db *stateDB) GetBalance(a common.Address) *uint256.Int {
+	acc := db.account(a)
+	db.loadBalance(acc)
+	return acc.balance.Clone()
+}
+
+// GetNonce возвращает nonce аккаунта.
+func (db *stateDB) GetNonce(a common.Address) uint64 {
+	acc := db.account(a)
+	if !acc.nonceLoaded {
+		acc.nonceLoaded = true
+		acc.nonce = uint64(db.backend.GetNonce(types.Address(acc.address)))
+		acc.origNonce = acc.nonce
+	}
+	return acc.nonce
+}
+
+// SetNonce задаёт nonce (CREATE внутри исполнения); изменение переносится в состояние ноды через changes(),
+// иначе следующий CREATE того же контракта получил бы занятый адрес.
+func (db *stateDB) SetNonce(a common.Address, nonce uint64) {
+	prev := db.GetNonce(a)
+	acc := db.account(a)
+	acc.nonce = nonce
+	db.journal = append(db.journal, func() { acc.nonce = prev })
+}
+
+// GetCodeHash возвращает keccak256 кода; для несуществующего аккаунта — нулевой хеш.
+func (db *stateDB) GetCodeHash(a common.Address) common.Hash {
+	if !db.Exist(a) {
+		return common.Hash{}
+	}
+	code := db.GetCode(a)
+	if len(code) == 0 {
+		return gethtypes.EmptyCodeHash
+	}
+	return crypto.Keccak256Hash(code)
+}
+
+// GetCode возвращает runtime-код контракта.
+func (db *stateDB) GetCode(a common.Address) []byte {
+	acc := db.account(a)
+	db.loadCode(a, acc)
+	return acc.code
+}
+
+// SetCode задаёт код аккаунта.
+func (db *stateDB) SetCode(a common.Address, code []byte) {
+	acc := db.account(a)
+	db.loadCode(a, acc)
+	prevCode, prevDirty := acc.code, acc.codeDirty
+	acc.code, acc.codeDirty = code, true
+	db.journal = append(db.journal, func() { acc.code, acc.codeDirty = prevCode, prevDirty })
+}
+
+// GetCodeSize возвращает размер кода.
+func (db *stateDB) GetCodeSize(a common.Address) int {
+	return len(db.GetCode(a))
+}
+
+// AddRefund увеличивает счётчик возврата газа.
+func (db *stateDB) AddRefund(gas uint64) {
+	prev := db.refund
+	db.refund += gas
+	db.journal = append(db.journal, func() { db.refund = prev })
+}
+
+// SubRefund уменьшает счётчик возврата газа.
+func (db *stateDB) SubRefund(gas uint64) {
+	prev := db.refund
+	if gas > db.refund {
+		db.refund = 0
+	} else {
+		db.refund -= gas
+	}
+	db.journal = append(db.journal, func() { db.refund = prev })
+}
+
+// GetRefund возвращает накопленный возврат газа.
+func (db *stateDB) GetRefund() uint64 {
+	return db.refund
+}
+
+// GetCommittedState возвращает значение слота до начала исполнения (из core.State / contract_storage).
+func (db *stateDB) GetCommittedState(a common.Address, key common.Hash) common.Hash {
+	acc := db.account(a)
+	if acc.committed == nil {
+		acc.committed = make(map[common.Hash]common.Hash)
+	}
+	if v, ok := acc.committed[key]; ok {
+		return v
+	}
+	var v common.Hash
+	if !acc.created {
+		v = common.BytesToHash(db.backend.GetStorageSlot(acc.address, key.Bytes()))
+	}
+	acc.committed[key] = v
+	return v
+}
+
+// GetState возвращает текущее значение слота с учётом записей исполнения.
+func (db *stateDB) GetState(a common.Address, key common.Hash) common.Hash {
+	acc := db.account(a)
+	if v, ok := acc.dirty[key]; ok {
+		return v
+	}
+	return db.GetCommittedState(a, key)
+}
+
+// SetState записывает слот storage.
+func (db *stateDB) SetState(a common.Address, key, value common.Hash) common.Hash {
+	prev := db.GetState(a, key)
+	if prev == value {
+		return prev
+	}
+	acc := db.account(a)
+	if acc.dirty == nil {
+		acc.dirty = make(map[common.Hash]common.Hash)
+	}
+	old, had := acc.dirty[key]
+	acc.dirty[key] = value
+	db.journal = append(db.journal, func() {
+		if had {
+			acc.dirty[key] = old
+		} else {
+			delete(acc.dirty, key)
+		}
+	})
+	return prev
+}
+
+// GetStorageRoot не поддерживается (корень storage не ведётся) — нулевой хеш, как у аккаунта без storage.
+func (db *stateDB) GetStorageRoot(common.Address) common.Hash {
+	return common.Hash{}
+}
+
+// GetTransientState возвращает значение transient storage (EIP-1153).
+func (db *stateDB) GetTransientState(a common.Address, key common.Hash) common.Hash {
+	return db.transient[a][key]
+}
+
+// SetTransientState записывает transient storage (EIP-1153).
+func (db *stateDB) SetTransientState(a common.Address, key, value common.Hash) {
+	prev := db.GetTransientState(a, key)
+	if prev == value {
+		return
+	}
+	db.setTransient(a, key, value)
+	db.journal = append(db.journal, func() { db.setTransient(a, key, prev) })
+}
+
+func (db *stateDB) setTransient(a common.Address, key, value common.Hash) {
+	if db.transient[a] == nil {
+		db.transient[a] = make(map[common.Hash]common.Hash)
+	}
+	db.transient[a][key] = value
+}
+
+// SelfDestruct помечает аккаунт уничтоженным и обнуляет его баланс.
+func (db *stateDB) SelfDestruct(a common.Address) uint256.Int {
+	acc := db.account(a)
+	db.loadBalance(acc)
+	prev := *acc.balance
+	prevDestructed := acc.selfDestructed
+	acc.selfDestructed = true
+	acc.balance = new(uint256.Int)
+	db.journal = append(db.journal, func() { acc.balance, acc.selfDestructed = &prev, prevDestructed })
+	return prev
+}
+
+// HasSelfDestructed сообщает, был ли аккаунт уничтожен в текущем исполнении.
+func (db *stateDB) HasSelfDestructed(a common.Address) bool {
+	return db.account(a).selfDestructed
+}
+
+// SelfDestruct6780 уничтожает аккаунт только если он создан в текущем исполнении (EIP-6780).
+func (db *stateDB) SelfDestruct6780(a common.Address) (uint256.Int, bool) {
+	acc := db.account(a)
+	if acc.created {
+		return db.SelfDestruct(a), true
+	}
+	db.loadBalance(acc)
+	return *acc.balance, false
+}
+
+// Exist сообщает, существует ли аккаунт (есть баланс, nonce или код, либо создан в исполнении).
+func (db *stateDB) Exist(a common.Address) bool {
+	acc := db.account(a)
+	if acc.created || acc.selfDestructed {
+		return true
+	}
+	return !db.Empty(a)
+}
+
+// Empty сообщает, пуст ли аккаунт по EIP-161 (баланс, nonce и код нулевые).
+func (db *stateDB) Empty(a common.Address) bool {
+	return db.GetBalance(a).IsZero() && db.GetNonce(a) == 0 && db.GetCodeSize(a) == 0
+}
+
+// AddressInAccessList сообщает, «тёплый» ли адрес (EIP-2929).
+func (db *stateDB) AddressInAccessList(a common.Address) bool {
+	_, ok := db.accessList[a]
+	return ok
+}
+
+// SlotInAccessList сообщает, «тёплые» ли адрес и слот (EIP-2929).
+func (db *stateDB) SlotInAccessList(a common.Address, slot common.Hash) (bool, bool) {
+	slots, ok := db.accessList[a]
+	if !ok {
+		return false, false
+	}
+	_, slotOk := slots[slot]
+	return true, slotOk
+}
+
+// AddAddressToAccessList добавляет адрес в access list.
+func (db *stateDB) AddAddressToAccessList(a common.Address) {
+	if _, ok := db.accessList[a]; ok {
+		return
+	}
+	db.accessList[a] = make(map[common.Hash]struct{})
+	db.journal = append(db.journal, func() { delete(db.accessList, a) })
+}
+
+// AddSlotToAccessList добавляет слот (и адрес) в access list.
+func (db *stateDB) AddSlotToAccessList(a common.Address, slot common.Hash) {
+	db.AddAddressToAccessList(a)
+	if _, ok := db.accessList[a][slot]; ok {
+		return
+	}
+	db.accessList[a][slot] = struct{}{}
+	db.journal = append(db.journal, func() { delete(db.accessList[a], slot) })
+}
+
+// PointCache не используется (verkle отключён).
+func (db *stateDB) PointCache() *utils.PointCache {
+	return nil
+}
+
+// Prepare заполняет access list перед исполнением (отправитель, получатель, прекомпайлы, coinbase).
+func (db *stateDB) Prepare(rules params.Rules, sender, coinbase common.Address, dest *common.Address, precompiles []common.Address, txAccesses gethtypes.AccessList) {
+	if !rules.IsBerlin {
+		return
+	}
+	db.AddAddressToAccessList(sender)
+	if dest != nil {
+		db.AddAddressToAccessList(*dest)
+	}
+	for _, p := range precompiles {
+		db.AddAddressToAccessList(p)
+	}
+	for _, el := range txAccesses {
+		db.AddAddressToAccessList(el.Address)
+		for _, key := range el.StorageKeys {
+			db.AddSlotToAccessList(el.Address, key)
+		}
+	}
+	if rules.IsShanghai {
+		db.AddAddressToAccessList(coinbase)
+	}
+	db.transient = make(map[common.Address]map[common.Hash]common.Hash)
+}
+
+// Snapshot возвращает идентификатор точки отката.
+func (db *stateDB) Snapshot() int {
+	return len(db.journal)
+}
+
+// RevertToSnapshot откатывает изменения до точки id.
+func (db *stateDB) RevertToSnapshot(id int) {
+	for i := len(db.journal) - 1; i >= id; i-- {
+		db.journal[i]()
+	}
+	db.journal = db.journal[:id]
+}
+
+// AddLog сохраняет событие (LOG0..LOG4).
+func (db *stateDB) AddLog(l *gethtypes.Log) {
+	n := len(db.logs)
+	l.Index = uint(n)
+	db.logs = append(db.logs, l)
+	db.journal = append(db.journal, func() { db.logs = db.logs[:n] })
+	if db.onLog != nil {
+		db.onLog(l)
+	}
+}
+
+// AddPreimage не используется.
+func (db *stateDB) AddPreimage(common.Hash, []byte) {}
+
+// Witness не используется (stateless-режим отключён).
+func (db *stateDB) Witness() *stateless.Witness {
+	return nil
+}
+
+// Finalise вызывается в конце транзакции; изменения остаются в overlay до changes().
+func (db *stateDB) Finalise(bool) {}
+
+// eventLogs возвращает события исполнения (LOG0..LOG4) в порядке генерации с адресами ГАНИМЕД.
+func (db *stateDB) eventLogs() []*types.Log {
+	if len(db.logs) == 0 {
+		return nil
+	}
+	out := make([]*types.Log, 0, len(db.logs))
+	for _, l := range db.logs {
+		topics := make([][]byte, len(l.Topics))
+		for i, t := range l.Topics {
+			topics[i] = t.Bytes()
+		}
+		out = append(out, &types.Log{Address: FromEVMAddress(l.Address), Topics: topics, Data: l.Data})
+	}
+	return out
+}
+
+// changes возвращает изменения исполнения в виде types.StateChange в детерминированном порядке:
+// сначала списания GND, затем зачисления, затем nonce, код созданных контрактов (и runtime-код, полученный из init-кода)
+// и слоты storage. Уничтоженный аккаунт (SELFDESTRUCT, EIP-6780 — только созданный в этом исполнении) удаляется
+// целиком, как в go-ethereum: его баланс обнуляется, а nonce, код и storage не применяются.
+func (db *stateDB) changes() []*types.StateChange {
+	addrs := make([]common.Address, 0, len(db.accounts))
+	for a := range db.accounts {
+		addrs = append(addrs, a)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	var debits, credits, nonces, codes, storage []*types.StateChange
+	for _, a := range addrs {
+		acc := db.accounts[a]
+		if acc.selfDestructed {
+			if acc.balance != nil && !acc.origBalance.IsZero() {
+				debits = append(debits, types.NewStateChange(types.ChangeTypeBalance, types.Address(acc.address), core.GasSymbol,
+					new(big.Int).Neg(acc.origBalance.ToBig())))
+			}
+			continue
+		}
+		if acc.balance != nil && !acc.balance.Eq(acc.origBalance) {
+			delta := new(big.Int).Sub(acc.balance.ToBig(), acc.origBalance.ToBig())
+			change := types.NewStateChange(types.ChangeTypeBalance, types.Address(acc.address), core.GasSymbol, delta)
+			if delta.Sign() < 0 {
+				debits = append(debits, change)
+			} else {
+				credits = append(credits, change)
+			}
+		}
+		if acc.nonceLoaded && acc.nonce != acc.origNonce {
+			nonces = append(nonces, types.NewNonceChange(types.Address(acc.address), acc.nonce))
+		}
+		if (acc.codeDirty && acc.created) || acc.codeResolved {
+			codes = append(codes, types.NewCodeChange(types.Address(acc.address), acc.code))
+		}
+		keys := make([]common.Hash, 0, len(acc.dirty))
+		for k, v := range acc.dirty {
+			if v != db.GetCommittedState(a, k) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+		for _, k := range keys {
+			v := acc.dirty[k]
+			storage = append(storage, types.NewStorageChange(types.Address(acc.address), k.Bytes(), v.Bytes()))
+		}
+	}
+	out := append(debits, credits...)
+	out = append(out, nonces...)
+	out = append(out, codes...)
+	return append(out, storage...)
+}
@@ -0,0 +1,212 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// vm/tracer.go — трассировка исполнения: интерфейс Tracer, реестр трассировщиков и повторное исполнение транзакций
+// над копией состояния (debug_traceTransaction, debug_traceCall, debug_traceBlockByNumber).
+
+package vm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"GND/core"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	gethvm "github.com/ethereum/go-ethereum/core/vm"
+	"github.com/holiman/uint256"
+)
+
+// Tracer — трассировщик одного исполнения: обработчики событий интерпретатора go-ethereum и итог трассировки.
+// Транзакция начинается с OnTxStart (в VMContext.StateDB — состояние до неё) и заканчивается OnTxEnd (после применения
+// транзакции StateDB отдаёт итоговое состояние); между ними — OnEnter/OnExit вызовов, OnOpcode, OnLog.
+type Tracer interface {
+	Hooks() *tracing.Hooks
+	Result() (json.RawMessage, error)
+}
+
+// TracerConstructor создаёт трассировщик по JSON-настройкам (пустые настройки — по умолчанию).
+type TracerConstructor func(config json.RawMessage) (Tracer, error)
+
+// DefaultTracer — трассировщик по умолчанию (пустое имя): пошаговый журнал опкодов.
+const DefaultTracer = "structLogger"
+
+var (
+	tracersMu sync.RWMutex
+	tracers   = map[string]TracerConstructor{
+		DefaultTracer:    newStructLogger,
+		"callTracer":     newCallTracer,
+		"prestateTracer": newPrestateTracer,
+	}
+)
+
+// RegisterTracer добавляет трассировщик под именем name (заменяет существующий с тем же именем).
+func RegisterTracer(name string, constructor TracerConstructor) {
+	tracersMu.Lock()
+	defer tracersMu.Unlock()
+	tracers[name] = constructor
+}
+
+// Tracers возвращает имена зарегистрированных трассировщиков по алфавиту.
+func Tracers() []string {
+	tracersMu.RLock()
+	defer tracersMu.RUnlock()
+	names := make([]string, 0, len(tracers))
+	for name := range tracers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewTracer создаёт трассировщик по имени (пустое — DefaultTracer) и настройкам.
+func NewTracer(name string, config json.RawMessage) (Tracer, error) {
+	if name == "" {
+		name = DefaultTracer
+	}
+	tracersMu.RLock()
+	constructor, ok := tracers[name]
+	tracersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown tracer %q", name)
+	}
+	return constructor(config)
+}
+
+// traceSession — трассировка в рамках TraceTx: обработчики трассировщика и состояние для них.
+type traceSession struct {
+	hooks *tracing.Hooks
+	state *traceState
+}
+
+// traceState — состояние для трассировщиков (tracing.StateDB): во время исполнения байткода — overlay интерпретатора
+// (видны изменения текущей транзакции), вне исполнения — копия состояния ноды.
+type traceState struct {
+	backend core.ContractStateIface
+	db      *stateDB
+}
+
+func (s *traceState) current() *stateDB {
+	if s.db != nil {
+		return s.db
+	}
+	return newStateDB(s.backend)
+}
+
+func (s *traceState) GetBalance(a common.Address) *uint256.Int { return s.current().GetBalance(a) }
+func (s *traceState) GetNonce(a common.Address) uint64         { return s.current().GetNonce(a) }
+func (s *traceState) GetCode(a common.Address) []byte          { return s.current().GetCode(a) }
+func (s *traceState) Exist(a common.Address) bool              { return s.current().Exist(a) }
+func (s *traceState) GetRefund() uint64                        { return s.current().GetRefund() }
+func (s *traceState) GetState(a common.Address, key common.Hash) common.Hash {
+	return s.current().GetState(a, key)
+}
+func (s *traceState) GetTransientState(a common.Address, key common.Hash) common.Hash {
+	return s.current().GetTransientState(a, key)
+}
+
+// ReplayTx применяет транзакцию к копии состояния replay без трассировки (транзакции блока до трассируемой).
+func (e *EVM) ReplayTx(replay *core.Replay, tx *core.Transaction) *core.Receipt {
+	receipt, _ := replay.ApplyTx(tx, e.withState(replay.State))
+	return receipt
+}
+
+// TraceTx применяет транзакцию к копии состояния replay тем же кодом, что применение блока, с трассировщиком tracer
+// и возвращает итог трассировки. Перевод и транзакции стейкинга исполняются без интерпретатора: для них трассировщик
+// получает один вызов CALL от отправителя к получателю без опкодов.
+func (e *EVM) TraceTx(replay *core.Replay, tx *core.Transaction, tracer Tracer) (json.RawMessage, error) {
+	if replay == nil || tx == nil || tracer == nil {
+		return nil, errors.New("nothing to trace")
+	}
+	hooks := tracer.Hooks()
+	traced := e.withState(replay.State)
+	state := &traceState{backend: replay.State}
+	entered := false
+	session := *hooks
+	session.OnEnter = func(depth int, typ byte, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+		if depth == 0 {
+			entered = true
+		}
+		if hooks.OnEnter != nil {
+			hooks.OnEnter(depth, typ, from, to, input, gas, value)
+		}
+	}
+	traced.trace = &traceSession{hooks: &session, state: state}
+
+	block := replay.Block
+	blockCtx := traced.blockContext(block)
+	from := ToEVMAddress(tx.Sender.String())
+	if hooks.OnTxStart != nil {
+		hooks.OnTxStart(&tracing.VMContext{
+			Coinbase:    blockCtx.Coinbase,
+			BlockNumber: blockCtx.BlockNumber,
+			Time:        blockCtx.Time,
+			Random:      blockCtx.Random,
+			GasPrice:    tx.GasPriceAt(block.BaseFee),
+			StateDB:     state,
+		}, gethTransaction(tx), from)
+	}
+	receipt, result := replay.ApplyTx(tx, traced)
+	state.db = nil // дальше трассировщик видит состояние после применения транзакции
+
+	var err error
+	if receipt.Error != "" {
+		err = errors.New(receipt.Error)
+	}
+	if !entered {
+		to := ToEVMAddress(tx.Recipient.String())
+		if hooks.OnEnter != nil {
+			hooks.OnEnter(0, byte(gethvm.CALL), from, to, tx.Data, tx.EffectiveGasLimit(), tx.Value)
+		}
+		if hooks.OnExit != nil {
+			hooks.OnExit(0, nil, receipt.GasUsed, err, false)
+		}
+	}
+	if hooks.OnTxEnd != nil {
+		status := gethtypes.ReceiptStatusSuccessful
+		if !receipt.Succeeded() {
+			status = gethtypes.ReceiptStatusFailed
+		}
+		var logs []*gethtypes.Log
+		if result != nil {
+			for _, l := range result.Logs {
+				logs = append(logs, &gethtypes.Log{Address: ToEVMAddress(l.Address), Topics: bytesToHashes(l.Topics), Data: l.Data})
+			}
+		}
+		hooks.OnTxEnd(&gethtypes.Receipt{Status: status, GasUsed: receipt.GasUsed, CumulativeGasUsed: receipt.CumulativeGasUsed, Logs: logs}, err)
+	}
+	return tracer.Result()
+}
+
+// gethTransaction представляет транзакцию ГАНИМЕД транзакцией go-ethereum для OnTxStart (пустой получатель — деплой).
+func gethTransaction(tx *core.Transaction) *gethtypes.Transaction {
+	var to *common.Address
+	if tx.Recipient != "" {
+		addr := ToEVMAddress(tx.Recipient.String())
+		to = &addr
+	}
+	value := new(big.Int)
+	if tx.Value != nil {
+		value.Set(tx.Value)
+	}
+	return gethtypes.NewTx(&gethtypes.LegacyTx{
+		Nonce:    uint64(tx.Nonce),
+		GasPrice: tx.GasPriceAt(nil),
+		Gas:      tx.EffectiveGasLimit(),
+		To:       to,
+		Value:    value,
+		Data:     tx.Data,
+	})
+}
+
+func bytesToHashes(topics [][]byte) []common.Hash {
+	hashes := make([]common.Hash, len(topics))
+	for i, t := range topics {
+		hashes[i] = common.BytesToHash(t)
+	}
+	return hashes
+}
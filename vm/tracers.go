@@ -0,0 +1,437 @@
+// | KB @CerberRus00 - Nexus Invest Team
+// vm/tracers.go — встроенные трассировщики: structLogger (журнал опкодов), callTracer (дерево вызовов),
+// prestateTracer (состояние затронутых аккаунтов до транзакции и его изменения).
+
+package vm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	gethvm "github.com/ethereum/go-ethereum/core/vm"
+)
+
+// parseTracerConfig разбирает настройки трассировщика (пустые или null — значения по умолчанию).
+func parseTracerConfig(config json.RawMessage, v interface{}) error {
+	if len(config) == 0 || string(config) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(config, v); err != nil {
+		return fmt.Errorf("invalid tracer config: %w", err)
+	}
+	return nil
+}
+
+// --- structLogger ---
+
+// StructLoggerConfig — настройки structLogger.
+type StructLoggerConfig struct {
+	EnableMemory     bool `json:"enableMemory"`
+	DisableStack     bool `json:"disableStack"`
+	DisableStorage   bool `json:"disableStorage"`
+	EnableReturnData bool `json:"enableReturnData"`
+	Limit            int  `json:"limit"` // максимум записей журнала (0 — без ограничения)
+}
+
+// StructLog — шаг исполнения: опкод, газ, стек, память и прочитанные/записанные слоты хранилища контракта.
+type StructLog struct {
+	Pc         uint64            `json:"pc"`
+	Op         string            `json:"op"`
+	Gas        uint64            `json:"gas"`
+	GasCost    uint64            `json:"gasCost"`
+	Depth      int               `json:"depth"`
+	Error      string            `json:"error,omitempty"`
+	Stack      []string          `json:"stack,omitempty"`
+	Memory     []string          `json:"memory,omitempty"`
+	Storage    map[string]string `json:"storage,omitempty"`
+	ReturnData string            `json:"returnData,omitempty"`
+	Refund     uint64            `json:"refund,omitempty"`
+}
+
+type structLogger struct {
+	config  StructLoggerConfig
+	env     *tracing.VMContext
+	storage map[common.Address]map[common.Hash]common.Hash
+	logs    []StructLog
+	output  []byte
+	gasUsed uint64
+	err     error
+}
+
+func newStructLogger(config json.RawMessage) (Tracer, error) {
+	l := &structLogger{storage: make(map[common.Address]map[common.Hash]common.Hash)}
+	if err := parseTracerConfig(config, &l.config); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *structLogger) Hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnTxStart: func(env *tracing.VMContext, _ *gethtypes.Transaction, _ common.Address) { l.env = env },
+		OnOpcode:  l.onOpcode,
+		OnExit: func(depth int, output []byte, _ uint64, _ error, _ bool) {
+			if depth == 0 {
+				l.output = common.CopyBytes(output)
+			}
+		},
+		OnTxEnd: func(receipt *gethtypes.Receipt, err error) {
+			if receipt != nil {
+				l.gasUsed = receipt.GasUsed
+			}
+			l.err = err
+		},
+	}
+}
+
+func (l *structLogger) onOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	if l.config.Limit > 0 && len(l.logs) >= l.config.Limit {
+		return
+	}
+	opcode := gethvm.OpCode(op)
+	entry := StructLog{Pc: pc, Op: opcode.String(), Gas: gas, GasCost: cost, Depth: depth}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	stack := scope.StackData()
+	if !l.config.DisableStack {
+		entry.Stack = make([]string, len(stack))
+		for i := range stack {
+			entry.Stack[i] = stack[i].Hex()
+		}
+	}
+	if l.config.EnableMemory {
+		memory := scope.MemoryData()
+		for i := 0; i+32 <= len(memory); i += 32 {
+			entry.Memory = append(entry.Memory, hex.EncodeToString(memory[i:i+32]))
+		}
+	}
+	if !l.config.DisableStorage && (opcode == gethvm.SLOAD || opcode == gethvm.SSTORE) && len(stack) > 0 {
+		contract := scope.Address()
+		slots := l.storage[contract]
+		if slots == nil {
+			slots = make(map[common.Hash]common.Hash)
+			l.storage[contract] = slots
+		}
+		key := common.Hash(stack[len(stack)-1].Bytes32())
+		if opcode == gethvm.SSTORE && len(stack) > 1 {
+			slots[key] = common.Hash(stack[len(stack)-2].Bytes32())
+		} else if l.env != nil && l.env.StateDB != nil {
+			slots[key] = l.env.StateDB.GetState(contract, key)
+		}
+		entry.Storage = make(map[string]string, len(slots))
+		for k, v := range slots {
+			entry.Storage[hex.EncodeToString(k[:])] = hex.EncodeToString(v[:])
+		}
+	}
+	if l.config.EnableReturnData && len(rData) > 0 {
+		entry.ReturnData = hexutil.Encode(rData)
+	}
+	if l.env != nil && l.env.StateDB != nil {
+		entry.Refund = l.env.StateDB.GetRefund()
+	}
+	l.logs = append(l.logs, entry)
+}
+
+func (l *structLogger) Result() (json.RawMessage, error) {
+	logs := l.logs
+	if logs == nil {
+		logs = []StructLog{}
+	}
+	return json.Marshal(struct {
+		Gas         uint64      `json:"gas"`
+		Failed      bool        `json:"failed"`
+		ReturnValue string      `json:"returnValue"`
+		StructLogs  []StructLog `json:"structLogs"`
+	}{l.gasUsed, l.err != nil, hex.EncodeToString(l.output), logs})
+}
+
+// --- callTracer ---
+
+// CallTracerConfig — настройки callTracer.
+type CallTracerConfig struct {
+	OnlyTopCall bool `json:"onlyTopCall"` // только вызов верхнего уровня, без вложенных
+	WithLog     bool `json:"withLog"`     // события (LOG0–LOG4) в кадрах вызовов
+}
+
+// CallFrame — кадр дерева вызовов callTracer.
+type CallFrame struct {
+	Type         string          `json:"type"`
+	From         common.Address  `json:"from"`
+	To           common.Address  `json:"to"`
+	Value        *hexutil.Big    `json:"value,omitempty"`
+	Gas          hexutil.Uint64  `json:"gas"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	Input        hexutil.Bytes   `json:"input"`
+	Output       hexutil.Bytes   `json:"output,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	RevertReason string          `json:"revertReason,omitempty"`
+	Calls        []*CallFrame    `json:"calls,omitempty"`
+	Logs         []*CallFrameLog `json:"logs,omitempty"`
+}
+
+// CallFrameLog — событие, выпущенное в кадре вызова.
+type CallFrameLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+type callTracer struct {
+	config CallTracerConfig
+	stack  []*CallFrame
+	root   *CallFrame
+	gas    uint64
+}
+
+func newCallTracer(config json.RawMessage) (Tracer, error) {
+	t := &callTracer{}
+	if err := parseTracerConfig(config, &t.config); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *callTracer) Hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnTxStart: func(_ *tracing.VMContext, tx *gethtypes.Transaction, _ common.Address) { t.gas = tx.Gas() },
+		OnEnter:   t.onEnter,
+		OnExit:    t.onExit,
+		OnLog:     t.onLog,
+		OnTxEnd: func(receipt *gethtypes.Receipt, _ error) {
+			if t.root != nil && receipt != nil {
+				t.root.Gas = hexutil.Uint64(t.gas)
+				t.root.GasUsed = hexutil.Uint64(receipt.GasUsed)
+			}
+		},
+	}
+}
+
+func (t *callTracer) onEnter(depth int, typ byte, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.config.OnlyTopCall && depth > 0 {
+		return
+	}
+	frame := &CallFrame{
+		Type:  gethvm.OpCode(typ).String(),
+		From:  from,
+		To:    to,
+		Gas:   hexutil.Uint64(gas),
+		Input: common.CopyBytes(input),
+	}
+	if value != nil {
+		frame.Value = (*hexutil.Big)(new(big.Int).Set(value))
+	}
+	t.stack = append(t.stack, frame)
+}
+
+func (t *callTracer) onExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if (t.config.OnlyTopCall && depth > 0) || len(t.stack) == 0 {
+		return
+	}
+	frame := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	frame.GasUsed = hexutil.Uint64(gasUsed)
+	frame.Output = common.CopyBytes(output)
+	if err != nil {
+		frame.Error = err.Error()
+		frame.Logs = nil // события неуспешного вызова отменяются
+		if errors.Is(err, gethvm.ErrExecutionReverted) {
+			if reason, unpackErr := abi.UnpackRevert(output); unpackErr == nil {
+				frame.RevertReason = reason
+			}
+		}
+	}
+	if len(t.stack) == 0 {
+		t.root = frame
+		return
+	}
+	parent := t.stack[len(t.stack)-1]
+	parent.Calls = append(parent.Calls, frame)
+}
+
+func (t *callTracer) onLog(l *gethtypes.Log) {
+	if !t.config.WithLog || len(t.stack) == 0 {
+		return
+	}
+	frame := t.stack[len(t.stack)-1]
+	frame.Logs = append(frame.Logs, &CallFrameLog{Address: l.Address, Topics: l.Topics, Data: common.CopyBytes(l.Data)})
+}
+
+func (t *callTracer) Result() (json.RawMessage, error) {
+	if t.root == nil {
+		return nil, errors.New("callTracer: no call captured")
+	}
+	return json.Marshal(t.root)
+}
+
+// --- prestateTracer ---
+
+// PrestateTracerConfig — настройки prestateTracer.
+type PrestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // pre и post только с изменившимися полями вместо полного состояния до транзакции
+}
+
+// PrestateAccount — состояние аккаунта: баланс GND, nonce, код и затронутые слоты хранилища.
+type PrestateAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+func (a *PrestateAccount) empty() bool {
+	return (a.Balance == nil || a.Balance.ToInt().Sign() == 0) && a.Nonce == 0 && len(a.Code) == 0 && len(a.Storage) == 0
+}
+
+type prestateTracer struct {
+	config PrestateTracerConfig
+	env    *tracing.VMContext
+	pre    map[common.Address]*PrestateAccount
+	post   map[common.Address]*PrestateAccount
+}
+
+func newPrestateTracer(config json.RawMessage) (Tracer, error) {
+	t := &prestateTracer{pre: make(map[common.Address]*PrestateAccount)}
+	if err := parseTracerConfig(config, &t.config); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *prestateTracer) Hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnTxStart: t.onTxStart,
+		OnEnter: func(_ int, _ byte, _, to common.Address, _ []byte, _ uint64, _ *big.Int) {
+			t.lookupAccount(to)
+		},
+		OnOpcode: t.onOpcode,
+		OnTxEnd: func(_ *gethtypes.Receipt, _ error) {
+			if t.config.DiffMode {
+				t.diff()
+			}
+		},
+	}
+}
+
+func (t *prestateTracer) onTxStart(env *tracing.VMContext, tx *gethtypes.Transaction, from common.Address) {
+	t.env = env
+	t.lookupAccount(from)
+	t.lookupAccount(env.Coinbase)
+	if to := tx.To(); to != nil {
+		t.lookupAccount(*to)
+	}
+}
+
+func (t *prestateTracer) onOpcode(_ uint64, op byte, _, _ uint64, scope tracing.OpContext, _ []byte, _ int, err error) {
+	if err != nil {
+		return
+	}
+	stack := scope.StackData()
+	if len(stack) == 0 {
+		return
+	}
+	top := stack[len(stack)-1]
+	switch gethvm.OpCode(op) {
+	case gethvm.SLOAD, gethvm.SSTORE:
+		t.lookupStorage(scope.Address(), common.Hash(top.Bytes32()))
+	case gethvm.BALANCE, gethvm.EXTCODESIZE, gethvm.EXTCODECOPY, gethvm.EXTCODEHASH, gethvm.SELFDESTRUCT:
+		t.lookupAccount(common.Address(top.Bytes20()))
+	}
+}
+
+// lookupAccount запоминает состояние аккаунта при первом обращении к нему в транзакции.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.pre[addr]; ok || t.env == nil {
+		return
+	}
+	t.pre[addr] = t.readAccount(addr)
+}
+
+// lookupStorage запоминает значение слота при первом обращении к нему в транзакции.
+func (t *prestateTracer) lookupStorage(addr common.Address, slot common.Hash) {
+	t.lookupAccount(addr)
+	account := t.pre[addr]
+	if account == nil {
+		return
+	}
+	if account.Storage == nil {
+		account.Storage = make(map[common.Hash]common.Hash)
+	}
+	if _, ok := account.Storage[slot]; !ok {
+		account.Storage[slot] = t.env.StateDB.GetState(addr, slot)
+	}
+}
+
+func (t *prestateTracer) readAccount(addr common.Address) *PrestateAccount {
+	db := t.env.StateDB
+	return &PrestateAccount{
+		Balance: (*hexutil.Big)(db.GetBalance(addr).ToBig()),
+		Nonce:   db.GetNonce(addr),
+		Code:    common.CopyBytes(db.GetCode(addr)),
+	}
+}
+
+// diff сравнивает запомненное состояние с состоянием после транзакции: в pre остаются изменившиеся аккаунты и слоты,
+// в post — их новые значения.
+func (t *prestateTracer) diff() {
+	t.post = make(map[common.Address]*PrestateAccount)
+	for addr, before := range t.pre {
+		after := t.readAccount(addr)
+		changed := &PrestateAccount{}
+		modified := false
+		if before.Balance.ToInt().Cmp(after.Balance.ToInt()) != 0 {
+			changed.Balance = after.Balance
+			modified = true
+		}
+		if before.Nonce != after.Nonce {
+			changed.Nonce = after.Nonce
+			modified = true
+		}
+		if !bytes.Equal(before.Code, after.Code) {
+			changed.Code = after.Code
+			modified = true
+		}
+		for slot, value := range before.Storage {
+			current := t.env.StateDB.GetState(addr, slot)
+			if current == value {
+				delete(before.Storage, slot)
+				continue
+			}
+			if changed.Storage == nil {
+				changed.Storage = make(map[common.Hash]common.Hash)
+			}
+			changed.Storage[slot] = current
+			modified = true
+		}
+		if !modified {
+			delete(t.pre, addr)
+			continue
+		}
+		t.post[addr] = changed
+	}
+}
+
+func (t *prestateTracer) Result() (json.RawMessage, error) {
+	pre := make(map[common.Address]*PrestateAccount, len(t.pre))
+	for addr, account := range t.pre {
+		if !account.empty() || t.post[addr] != nil {
+			pre[addr] = account
+		}
+	}
+	if !t.config.DiffMode {
+		return json.Marshal(pre)
+	}
+	return json.Marshal(struct {
+		Pre  map[common.Address]*PrestateAccount `json:"pre"`
+		Post map[common.Address]*PrestateAccount `json:"post"`
+	}{pre, t.post})
+}